@@ -0,0 +1,190 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrConcurrencyLimited is returned when a call could not get a slot from
+// an AdaptiveLimiter before its context was done.
+var ErrConcurrencyLimited = errors.New("concurrency limit reached")
+
+// AdaptivePolicy configures an AdaptiveLimiter.
+type AdaptivePolicy struct {
+	// MinLimit and MaxLimit bound the concurrency limit. They default to 1
+	// and 100.
+	MinLimit int `json:"min_limit"`
+	MaxLimit int `json:"max_limit"`
+	// InitialLimit is the starting limit. Defaults to MinLimit.
+	InitialLimit int `json:"initial_limit"`
+	// LatencyThreshold marks calls slower than it as a sign of overload.
+	// When zero calls taking more than twice the fastest call seen are.
+	LatencyThreshold Duration `json:"latency_threshold"`
+	// Backoff multiplies the limit after a failed or slow call. Defaults to
+	// 0.9.
+	Backoff float64 `json:"backoff"`
+}
+
+// AdaptiveLimiter limits concurrent calls to an upstream with additive
+// increase, multiplicative decrease (AIMD). The limit grows by one for
+// every limit's worth of healthy calls while the upstream is kept busy and
+// shrinks by Backoff on every error or slow call, so it settles around the
+// concurrency the upstream can handle. It is safe for concurrent use.
+type AdaptiveLimiter struct {
+	policy AdaptivePolicy
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	minRTT   time.Duration
+	waiters  []waiter
+}
+
+type waiter struct {
+	ch      chan struct{}
+	urgency int
+}
+
+// NewAdaptiveLimiter returns an AdaptiveLimiter at its initial limit.
+func NewAdaptiveLimiter(policy AdaptivePolicy) *AdaptiveLimiter {
+	if policy.MinLimit <= 0 {
+		policy.MinLimit = 1
+	}
+	if policy.MaxLimit <= 0 {
+		policy.MaxLimit = 100
+	}
+	policy.MaxLimit = max(policy.MaxLimit, policy.MinLimit)
+	if policy.InitialLimit <= 0 {
+		policy.InitialLimit = policy.MinLimit
+	}
+	if policy.Backoff <= 0 || policy.Backoff >= 1 {
+		policy.Backoff = 0.9
+	}
+	initial := min(max(policy.InitialLimit, policy.MinLimit), policy.MaxLimit)
+	return &AdaptiveLimiter{policy: policy, limit: float64(initial)}
+}
+
+// Limit returns the current concurrency limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Do calls fn once a slot is free, waiting until ctx is done in which case
+// ErrConcurrencyLimited is returned. Waiting calls get slots by the urgency
+// of their PriorityFromContext, then in arrival order. Permanent errors do
+// not reduce the limit as they indicate a bad request rather than an
+// overloaded upstream. A panic in fn releases the slot as a failure and is
+// propagated.
+func (l *AdaptiveLimiter) Do(ctx context.Context, fn func(context.Context) error) (err error) {
+	busy, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	panicked := true
+	defer func() {
+		l.release(time.Since(start), panicked || (err != nil && !IsPermanent(err)), busy)
+	}()
+	err = fn(ctx)
+	panicked = false
+	return err
+}
+
+// acquire takes a slot and reports whether the limiter was at least half
+// used, only then is a healthy call evidence that the limit can grow.
+func (l *AdaptiveLimiter) acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	if l.inflight < int(l.limit) {
+		l.inflight++
+		busy := float64(l.inflight) >= l.limit/2
+		l.mu.Unlock()
+		return busy, nil
+	}
+	ch := make(chan struct{})
+	urgency := PriorityFromContext(ctx).Urgency
+	i := len(l.waiters)
+	for i > 0 && l.waiters[i-1].urgency > urgency {
+		i--
+	}
+	l.waiters = append(l.waiters[:i], append([]waiter{{ch: ch, urgency: urgency}}, l.waiters[i:]...)...)
+	l.mu.Unlock()
+
+	select {
+	case <-ch:
+		return true, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, w := range l.waiters {
+			if w.ch == ch {
+				l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+				return false, ErrConcurrencyLimited
+			}
+		}
+		// the slot was handed over as ctx finished, give it back
+		l.inflight--
+		l.wake()
+		return false, ErrConcurrencyLimited
+	}
+}
+
+func (l *AdaptiveLimiter) release(latency time.Duration, failed, busy bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+
+	if l.minRTT == 0 || latency < l.minRTT {
+		l.minRTT = latency
+	}
+	threshold := time.Duration(l.policy.LatencyThreshold)
+	if threshold == 0 {
+		threshold = 2 * l.minRTT
+	}
+
+	switch {
+	case failed || latency > threshold:
+		l.limit = max(l.limit*l.policy.Backoff, float64(l.policy.MinLimit))
+	case busy:
+		l.limit = min(l.limit+1/l.limit, float64(l.policy.MaxLimit))
+	}
+	l.wake()
+}
+
+// wake hands free slots to waiters, which are kept ordered by urgency and
+// arrival. l.mu must be held.
+func (l *AdaptiveLimiter) wake() {
+	for l.inflight < int(l.limit) && len(l.waiters) > 0 {
+		w := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.inflight++
+		close(w.ch)
+	}
+}
+
+// Middleware limits the concurrency of the wrapped handler, so a function
+// sheds load instead of overloading its dependencies. Responses with a 5xx
+// status or slower than the latency threshold reduce the limit. Requests
+// which get no slot before their context is done are rejected with 503.
+// Use RequestPriority before it to serve urgent requests first.
+func (l *AdaptiveLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		err := l.Do(r.Context(), func(context.Context) error {
+			next.ServeHTTP(sw, r)
+			if sw.status >= 500 {
+				return errUpstreamStatus
+			}
+			return nil
+		})
+		if errors.Is(err, ErrConcurrencyLimited) {
+			_ = writeError(w, E(CodeUnavailable, "too many concurrent requests", err))
+		}
+	})
+}
+
+var errUpstreamStatus = errors.New("handler responded with a server error")
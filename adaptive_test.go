@@ -0,0 +1,113 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiterAIMD(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptivePolicy{MinLimit: 2, MaxLimit: 10, LatencyThreshold: Duration(time.Second)})
+	ctx := context.Background()
+	ok := func(context.Context) error { return nil }
+
+	// sequential calls never use the limit so give no reason to grow it
+	for i := 0; i < 50; i++ {
+		_ = l.Do(ctx, ok)
+	}
+	if l.Limit() != 2 {
+		t.Fatalf("expected the limit to stay at 2 while underused, got %d", l.Limit())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = l.Do(ctx, func(context.Context) error { time.Sleep(time.Millisecond); return nil })
+			}
+		}()
+	}
+	wg.Wait()
+	grown := l.Limit()
+	if grown <= 2 {
+		t.Fatalf("expected the limit to grow with busy healthy calls, got %d", grown)
+	}
+
+	for i := 0; i < 5; i++ {
+		_ = l.Do(ctx, func(context.Context) error { return errors.New("overloaded") })
+	}
+	if l.Limit() >= grown {
+		t.Errorf("expected errors to shrink the limit below %d, got %d", grown, l.Limit())
+	}
+
+	for i := 0; i < 50; i++ {
+		_ = l.Do(ctx, func(context.Context) error { return Permanent(errors.New("bad request")) })
+	}
+	if l.Limit() < 2 {
+		t.Errorf("expected the limit to stay above its minimum, got %d", l.Limit())
+	}
+}
+
+func TestAdaptiveLimiterConcurrency(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptivePolicy{MinLimit: 2, MaxLimit: 2})
+	var inflight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = l.Do(context.Background(), func(context.Context) error {
+				n := inflight.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				inflight.Add(-1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 concurrent calls, got %d", peak.Load())
+	}
+
+	// a waiting call gives up when its context is done
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() { _ = l.Do(context.Background(), func(context.Context) error { <-release; return nil }) }()
+	}
+	time.Sleep(5 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := l.Do(ctx, func(context.Context) error { return nil }); err != ErrConcurrencyLimited {
+		t.Errorf("expected ErrConcurrencyLimited, got %v", err)
+	}
+	close(release)
+}
+
+func TestAdaptiveLimiterPanic(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptivePolicy{MinLimit: 1, MaxLimit: 1})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to propagate")
+			}
+		}()
+		_ = l.Do(context.Background(), func(context.Context) error { panic("boom") })
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := l.Do(ctx, func(context.Context) error { return nil }); err != nil {
+		t.Errorf("expected the slot to be released after a panic, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package faas
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// AffinityHint is a routing hint sent back by clients on follow-up requests.
+type AffinityHint struct {
+	// Key is the application key, e.g. a tenant ID, whose state the replica
+	// has warm.
+	Key string
+	// Replica is the hostname of the replica which set the hint.
+	Replica string
+}
+
+// Local reports whether the request was routed to the replica which set the
+// hint, i.e. whether state cached in memory for Key is likely present.
+func (h AffinityHint) Local() bool {
+	return h.Replica != "" && h.Replica == replicaID()
+}
+
+// Affinity sets and reads session affinity hints. Ingress controllers and
+// gateways which support cookie or header based affinity, such as
+// ingress-nginx with "affinity: cookie" or a consistent hash on a header,
+// can be pointed at Cookie or Header to route follow-ups to the same
+// replica. Where stickiness is unsupported the hint is harmless and Local
+// tells the handler whether its cache is likely warm.
+type Affinity struct {
+	// Cookie is the cookie name. Defaults to "faas-affinity".
+	Cookie string
+	// Header is sent in responses and accepted in requests for clients
+	// which do not keep cookies. Defaults to "X-Affinity".
+	Header string
+	// MaxAge of the cookie. Defaults to one hour.
+	MaxAge time.Duration
+}
+
+// DefaultAffinity uses the default cookie and header names.
+var DefaultAffinity = &Affinity{}
+
+// SetAffinity sets a hint for key using DefaultAffinity.
+func SetAffinity(w http.ResponseWriter, key string) {
+	DefaultAffinity.Set(w, key)
+}
+
+// GetAffinity reads the hint of r using DefaultAffinity.
+func GetAffinity(r *http.Request) (AffinityHint, bool) {
+	return DefaultAffinity.Get(r)
+}
+
+// Set adds the hint for key to the response as both a cookie and a header.
+// The key is query escaped so any key makes a valid cookie value.
+func (a *Affinity) Set(w http.ResponseWriter, key string) {
+	value := url.QueryEscape(key) + "." + replicaID()
+	w.Header().Set(a.header(), value)
+	maxAge := a.MaxAge
+	if maxAge <= 0 {
+		maxAge = time.Hour
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.cookie(),
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Get returns the hint sent with r in the header, or failing that the
+// cookie.
+func (a *Affinity) Get(r *http.Request) (AffinityHint, bool) {
+	value := r.Header.Get(a.header())
+	if value == "" {
+		c, err := r.Cookie(a.cookie())
+		if err != nil {
+			return AffinityHint{}, false
+		}
+		value = c.Value
+	}
+	// the key may contain dots, replica hostnames do not
+	i := strings.LastIndex(value, ".")
+	if i <= 0 {
+		return AffinityHint{}, false
+	}
+	key, err := url.QueryUnescape(value[:i])
+	if err != nil {
+		return AffinityHint{}, false
+	}
+	return AffinityHint{Key: key, Replica: value[i+1:]}, true
+}
+
+func (a *Affinity) cookie() string {
+	if a.Cookie == "" {
+		return "faas-affinity"
+	}
+	return a.Cookie
+}
+
+func (a *Affinity) header() string {
+	if a.Header == "" {
+		return "X-Affinity"
+	}
+	return a.Header
+}
+
+// replicaID is the hostname, which Kubernetes sets to the pod name.
+func replicaID() string {
+	host, _ := os.Hostname()
+	return strings.ReplaceAll(host, ".", "-")
+}
@@ -0,0 +1,56 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAffinity(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SetAffinity(rec, "tenant.acme")
+	value := rec.Header().Get("X-Affinity")
+	cookies := rec.Result().Cookies()
+	if value == "" || len(cookies) != 1 || cookies[0].Value != value || cookies[0].MaxAge != 3600 {
+		t.Fatalf("unexpected hint %q %+v", value, cookies)
+	}
+
+	tests := []struct {
+		name      string
+		req       func(*http.Request)
+		key       string
+		local     bool
+		wantFound bool
+	}{
+		{name: "cookie", req: func(r *http.Request) { r.AddCookie(cookies[0]) }, key: "tenant.acme", local: true, wantFound: true},
+		{name: "header", req: func(r *http.Request) { r.Header.Set("X-Affinity", value) }, key: "tenant.acme", local: true, wantFound: true},
+		{name: "other replica", req: func(r *http.Request) { r.Header.Set("X-Affinity", "tenant.acme.fn-7d9f-x2") }, key: "tenant.acme", wantFound: true},
+		{name: "missing", req: func(*http.Request) {}},
+		{name: "malformed", req: func(r *http.Request) { r.Header.Set("X-Affinity", "nodot") }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			tc.req(req)
+			hint, ok := GetAffinity(req)
+			if ok != tc.wantFound || hint.Key != tc.key || hint.Local() != tc.local {
+				t.Errorf("GetAffinity() = %+v, %v", hint, ok)
+			}
+		})
+	}
+}
+
+func TestAffinityEscapesKey(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SetAffinity(rec, `a b;c="d"`)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || strings.ContainsAny(cookies[0].Value, ` ;"`) {
+		t.Fatalf("unexpected cookie %+v", cookies)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	if hint, ok := GetAffinity(req); !ok || hint.Key != `a b;c="d"` || !hint.Local() {
+		t.Errorf("GetAffinity() = %+v, %v", hint, ok)
+	}
+}
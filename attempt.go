@@ -0,0 +1,41 @@
+package faas
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Attempt describes which delivery of an invocation a request is.
+type Attempt struct {
+	// Number is 1 for the first delivery and increases with every retry.
+	Number int
+	// CallID is the X-Call-Id of the first delivery, which stays the same
+	// across retries. It is empty when the caller did not send one.
+	CallID string
+}
+
+// Retry reports whether the request is a redelivery of an earlier attempt.
+func (a Attempt) Retry() bool {
+	return a.Number > 1
+}
+
+// AttemptInfo returns the attempt of an invocation, so handlers can skip
+// side effects which are not idempotent, such as sending an email, when the
+// gateway or queue-worker retries a call. The attempt number is read from
+// X-Attempt, from X-Retry-Count, which counts the retries only, or from
+// Nats-Num-Delivered set by JetStream consumers, and is 1 when none is sent.
+// The call ID is read from X-Original-Call-Id, falling back to X-Call-Id.
+func AttemptInfo(r *http.Request) Attempt {
+	a := Attempt{Number: 1, CallID: r.Header.Get("X-Original-Call-Id")}
+	if a.CallID == "" {
+		a.CallID = r.Header.Get("X-Call-Id")
+	}
+	if n, err := strconv.Atoi(r.Header.Get("X-Attempt")); err == nil && n > 0 {
+		a.Number = n
+	} else if n, err := strconv.Atoi(r.Header.Get("X-Retry-Count")); err == nil && n >= 0 {
+		a.Number = n + 1
+	} else if n, err := strconv.Atoi(r.Header.Get("Nats-Num-Delivered")); err == nil && n > 0 {
+		a.Number = n
+	}
+	return a
+}
@@ -0,0 +1,36 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAttemptInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    Attempt
+	}{
+		{name: "first delivery", headers: map[string]string{"X-Call-Id": "abc"}, want: Attempt{Number: 1, CallID: "abc"}},
+		{name: "attempt header", headers: map[string]string{"X-Attempt": "3", "X-Call-Id": "def", "X-Original-Call-Id": "abc"}, want: Attempt{Number: 3, CallID: "abc"}},
+		{name: "retry count", headers: map[string]string{"X-Retry-Count": "1"}, want: Attempt{Number: 2}},
+		{name: "jetstream", headers: map[string]string{"Nats-Num-Delivered": "4"}, want: Attempt{Number: 4}},
+		{name: "invalid", headers: map[string]string{"X-Attempt": "zero"}, want: Attempt{Number: 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			got := AttemptInfo(req)
+			if got != tc.want {
+				t.Errorf("AttemptInfo() = %+v, want %+v", got, tc.want)
+			}
+			if got.Retry() != (tc.want.Number > 1) {
+				t.Errorf("Retry() = %v", got.Retry())
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditEvent records who did what to which subject.
+type AuditEvent struct {
+	Type      string         `json:"type"`
+	ID        string         `json:"id"`
+	Time      time.Time      `json:"time"`
+	Action    string         `json:"action"`
+	Subject   string         `json:"subject"`
+	Actor     string         `json:"actor,omitempty"`
+	IP        string         `json:"ip,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Function  string         `json:"function,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
+}
+
+// AuditSink stores audit events.
+type AuditSink interface {
+	WriteAudit(ctx context.Context, event AuditEvent) error
+}
+
+// WriterAuditSink writes audit events as JSON lines, e.g. to stdout where
+// the log collector picks them up. Events have "type": "audit" so they can
+// be routed separately from other logs.
+type WriterAuditSink struct {
+	W io.Writer
+
+	mu sync.Mutex
+}
+
+// WriteAudit implements AuditSink.
+func (s *WriterAuditSink) WriteAudit(_ context.Context, event AuditEvent) error {
+	js, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.W.Write(append(js, '\n'))
+	return err
+}
+
+// HTTPAuditSink posts each audit event as JSON to URL, such as another
+// function which owns the audit store.
+type HTTPAuditSink struct {
+	URL string
+	// Client defaults to SharedHTTPClient.
+	Client *http.Client
+}
+
+// WriteAudit implements AuditSink.
+func (s *HTTPAuditSink) WriteAudit(ctx context.Context, event AuditEvent) error {
+	return doJSON(ctx, s.Client, http.MethodPost, s.URL, event, nil)
+}
+
+// DefaultAuditSink receives the events emitted by Audit. It writes to
+// stdout unless replaced, e.g. with AuditSinkFromEnv.
+var DefaultAuditSink AuditSink = &WriterAuditSink{W: os.Stdout}
+
+// AuditSinkFromEnv returns an HTTPAuditSink for the AUDIT_SINK_URL
+// environment variable, or a stdout sink when it is unset.
+func AuditSinkFromEnv() AuditSink {
+	if u := os.Getenv("AUDIT_SINK_URL"); u != "" {
+		return &HTTPAuditSink{URL: u}
+	}
+	return &WriterAuditSink{W: os.Stdout}
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx naming the actor recorded by Audit, for
+// authentication schemes other than BasicAuth and APIKeyAuth.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the authenticated caller of the request: the actor set with
+// WithActor, the BasicAuth user as "user:<name>" or the APIKeyAuth key as
+// "api_key:<id>". It is empty for unauthenticated requests.
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok {
+		return actor
+	}
+	if user, ok := BasicAuthUser(ctx); ok {
+		return "user:" + user
+	}
+	if id, ok := APIKeyID(ctx); ok {
+		return "api_key:" + id
+	}
+	return ""
+}
+
+// Audit emits an audit event to DefaultAuditSink recording that the actor of
+// ctx performed action, such as "order.refund", on subject, such as the
+// order ID. The IP address and request ID are included when the request
+// passed through RequestLogger. Callers should treat errors as fatal to the
+// operation when the audit trail is mandatory.
+func Audit(ctx context.Context, action, subject string, fields map[string]any) error {
+	event := AuditEvent{
+		Type:      "audit",
+		ID:        randomHex(16),
+		Time:      time.Now().UTC(),
+		Action:    action,
+		Subject:   subject,
+		Actor:     Actor(ctx),
+		RequestID: RequestID(ctx),
+		Function:  os.Getenv("function_name"),
+		Fields:    fields,
+	}
+	event.IP, _ = FromContext(ctx, RealIPKey)
+	if err := DefaultAuditSink.WriteAudit(ctx, event); err != nil {
+		return fmt.Errorf("writing audit event %s: %w", action, err)
+	}
+	return nil
+}
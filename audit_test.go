@@ -0,0 +1,93 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	withSecrets(t, map[string]string{"user": "admin", "pass": "pw", "keys": "k1"})
+
+	tests := []struct {
+		name  string
+		mw    func(http.Handler) http.Handler
+		auth  func(*http.Request)
+		actor string
+	}{
+		{
+			name:  "basic auth",
+			mw:    BasicAuth("user", "pass"),
+			auth:  func(r *http.Request) { r.SetBasicAuth("admin", "pw") },
+			actor: "user:admin",
+		},
+		{
+			name:  "api key",
+			mw:    APIKeyAuth(APIKeyOptions{Secrets: []string{"keys"}}),
+			auth:  func(r *http.Request) { r.Header.Set("X-API-Key", "k1") },
+			actor: "api_key:keys",
+		},
+		{
+			name: "custom actor",
+			mw: func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r.WithContext(WithActor(r.Context(), "svc:billing")))
+				})
+			},
+			auth:  func(*http.Request) {},
+			actor: "svc:billing",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			prev := DefaultAuditSink
+			DefaultAuditSink = &WriterAuditSink{W: &buf}
+			t.Cleanup(func() { DefaultAuditSink = prev })
+
+			h := RequestLogger(tc.mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := Audit(r.Context(), "order.refund", "o_1", map[string]any{"amount": "9.99"}); err != nil {
+					t.Error(err)
+				}
+			})))
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("X-Call-Id", "call-1")
+			req.Header.Set("X-Forwarded-For", "198.51.100.4, 10.0.0.2")
+			tc.auth(req)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			var event AuditEvent
+			if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+				t.Fatalf("invalid audit line %q: %v", buf.String(), err)
+			}
+			if event.Type != "audit" || event.Action != "order.refund" || event.Subject != "o_1" || event.Actor != tc.actor {
+				t.Errorf("unexpected event %+v", event)
+			}
+			if event.RequestID != "call-1" || event.IP != "198.51.100.4" || event.Fields["amount"] != "9.99" {
+				t.Errorf("missing request metadata %+v", event)
+			}
+		})
+	}
+}
+
+func TestHTTPAuditSink(t *testing.T) {
+	var got AuditEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	t.Setenv("AUDIT_SINK_URL", srv.URL)
+	sink := AuditSinkFromEnv().(*HTTPAuditSink)
+	sink.Client = srv.Client()
+	if err := sink.WriteAudit(context.Background(), AuditEvent{Action: "user.delete", Subject: "u_1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Action != "user.delete" {
+		t.Errorf("unexpected event %+v", got)
+	}
+}
@@ -0,0 +1,200 @@
+package faas
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// BasicAuth is middleware requiring HTTP basic auth credentials matching the
+// OpenFaaS secrets named userSecret and passSecret. The secrets are read on
+// every request so rotated credentials take effect without a restart.
+// Failed or missing credentials get a 401 JSON error with a
+// WWW-Authenticate challenge. The user name is available to the handler
+// through BasicAuthUser.
+func BasicAuth(userSecret, passSecret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wantUser, wantPass, err := loadBasicAuth(userSecret, passSecret)
+			if err != nil {
+				slog.Error("basic auth credentials unavailable", "error", err)
+				_ = writeJSONError(w, Error{
+					Status: http.StatusText(http.StatusInternalServerError),
+					Code:   http.StatusInternalServerError,
+				})
+				return
+			}
+
+			user, pass, ok := r.BasicAuth()
+			// both are compared so the timing does not reveal a valid user
+			userOK := credentialsMatch(user, wantUser)
+			passOK := credentialsMatch(pass, wantPass)
+			if !ok || !userOK || !passOK {
+				w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+				_ = writeJSONError(w, Error{
+					Status: http.StatusText(http.StatusUnauthorized),
+					Reason: "invalid or missing credentials",
+					Code:   http.StatusUnauthorized,
+				})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), basicAuthUserKey{}, user)))
+		})
+	}
+}
+
+type basicAuthUserKey struct{}
+
+// BasicAuthUser returns the user which authenticated the request.
+func BasicAuthUser(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(basicAuthUserKey{}).(string)
+	return user, ok
+}
+
+func loadBasicAuth(userSecret, passSecret string) (string, string, error) {
+	user, err := getSecretString(userSecret)
+	if err != nil {
+		return "", "", err
+	}
+	pass, err := getSecretString(passSecret)
+	if err != nil {
+		return "", "", err
+	}
+	if user == "" || pass == "" {
+		return "", "", errors.New("basic auth secrets must not be empty")
+	}
+	return user, pass, nil
+}
+
+// authorizeBearer checks that r sends "Authorization: Bearer <token>" with
+// the OpenFaaS secret named tokenSecret, writing the error response when it
+// does not. Tokens are only read from the header, never the query, so they
+// do not end up in access logs. name describes the endpoint in logs.
+func authorizeBearer(w http.ResponseWriter, r *http.Request, tokenSecret, name string) bool {
+	token, err := getSecretString(tokenSecret)
+	if err != nil || token == "" {
+		slog.Error(name+" token unavailable", "error", err)
+		_ = writeJSONError(w, Error{Status: http.StatusText(http.StatusInternalServerError), Code: http.StatusInternalServerError})
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !credentialsMatch(got, token) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		_ = writeJSONError(w, Error{
+			Status: http.StatusText(http.StatusUnauthorized),
+			Reason: "invalid or missing token",
+			Code:   http.StatusUnauthorized,
+		})
+		return false
+	}
+	return true
+}
+
+// credentialsMatch compares in constant time. Both values are hashed first
+// so the comparison does not leak the length of the expected value.
+func credentialsMatch(got, want string) bool {
+	g := sha256.Sum256([]byte(got))
+	w := sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(g[:], w[:]) == 1
+}
+
+// APIKeyOptions configures APIKeyAuth.
+type APIKeyOptions struct {
+	// Header carrying the key. Defaults to X-API-Key.
+	Header string
+	// QueryParam optionally accepts the key as a query parameter, for clients
+	// such as webhooks which cannot set headers. Disabled when empty.
+	QueryParam string
+	// Secrets are the OpenFaaS secrets holding accepted keys, one per line.
+	// Keys can be rotated by adding the new key, deploying clients with it
+	// and then removing the old one.
+	Secrets []string
+}
+
+type apiKeyContextKey struct{}
+
+// APIKeyAuth is middleware requiring a key from one of opts.Secrets. The ID
+// of the matched key, its secret name followed by the line number when the
+// secret holds more than one key, e.g. "api-keys:2", is available to the
+// handler through APIKeyID for auditing. The key itself is never exposed.
+func APIKeyAuth(opts APIKeyOptions) func(http.Handler) http.Handler {
+	if opts.Header == "" {
+		opts.Header = "X-API-Key"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys, err := loadAPIKeys(opts.Secrets)
+			if err != nil {
+				slog.Error("api keys unavailable", "error", err)
+				_ = writeJSONError(w, Error{
+					Status: http.StatusText(http.StatusInternalServerError),
+					Code:   http.StatusInternalServerError,
+				})
+				return
+			}
+
+			got := r.Header.Get(opts.Header)
+			if got == "" && opts.QueryParam != "" {
+				got = r.URL.Query().Get(opts.QueryParam)
+			}
+			// check every key so the time taken does not reveal which matched
+			var id string
+			for _, k := range keys {
+				if credentialsMatch(got, k.key) && id == "" {
+					id = k.id
+				}
+			}
+			if got == "" || id == "" {
+				_ = writeJSONError(w, Error{
+					Status: http.StatusText(http.StatusUnauthorized),
+					Reason: "invalid or missing API key",
+					Code:   http.StatusUnauthorized,
+				})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, id)))
+		})
+	}
+}
+
+// APIKeyID returns the ID of the key which authenticated the request.
+func APIKeyID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(apiKeyContextKey{}).(string)
+	return id, ok
+}
+
+type apiKey struct {
+	id  string
+	key string
+}
+
+func loadAPIKeys(secrets []string) ([]apiKey, error) {
+	var keys []apiKey
+	for _, name := range secrets {
+		secret, err := getSecretString(name)
+		if err != nil {
+			return nil, err
+		}
+		lines := strings.Split(secret, "\n")
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			id := name
+			if len(lines) > 1 {
+				id = fmt.Sprintf("%s:%d", name, i+1)
+			}
+			keys = append(keys, apiKey{id: id, key: line})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no API keys configured")
+	}
+	return keys, nil
+}
@@ -0,0 +1,110 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	withSecrets(t, map[string]string{"admin-user": "admin\n", "admin-pass": "s3cret\n"})
+
+	tests := []struct {
+		name       string
+		userSecret string
+		user, pass string
+		noAuth     bool
+		status     int
+	}{
+		{name: "valid", userSecret: "admin-user", user: "admin", pass: "s3cret", status: http.StatusOK},
+		{name: "wrong password", userSecret: "admin-user", user: "admin", pass: "nope", status: http.StatusUnauthorized},
+		{name: "missing credentials", userSecret: "admin-user", noAuth: true, status: http.StatusUnauthorized},
+		{name: "missing secret", userSecret: "missing", user: "admin", pass: "s3cret", status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := BasicAuth(tc.userSecret, "admin-pass")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if !tc.noAuth {
+				r.SetBasicAuth(tc.user, tc.pass)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, r)
+
+			if rr.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, rr.Code)
+			}
+			if tc.status == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	withSecrets(t, map[string]string{"api-key": "old-key\n", "api-keys-next": "new-key\nother-key\n"})
+
+	tests := []struct {
+		name   string
+		opts   APIKeyOptions
+		header string
+		query  string
+		status int
+		id     string
+	}{
+		{name: "old key", header: "old-key", status: http.StatusOK, id: "api-key"},
+		{name: "rotated key", header: "other-key", status: http.StatusOK, id: "api-keys-next:2"},
+		{name: "query param", opts: APIKeyOptions{QueryParam: "key"}, query: "new-key", status: http.StatusOK, id: "api-keys-next:1"},
+		{name: "query param disabled", query: "new-key", status: http.StatusUnauthorized},
+		{name: "custom header", opts: APIKeyOptions{Header: "X-Token"}, header: "old-key", status: http.StatusOK, id: "api-key"},
+		{name: "wrong key", header: "nope", status: http.StatusUnauthorized},
+		{name: "missing secret", opts: APIKeyOptions{Secrets: []string{"missing"}}, header: "old-key", status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.opts.Secrets == nil {
+				tc.opts.Secrets = []string{"api-key", "api-keys-next"}
+			}
+			var id string
+			h := APIKeyAuth(tc.opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, _ = APIKeyID(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/?key="+tc.query, nil)
+			header := tc.opts.Header
+			if header == "" {
+				header = "X-API-Key"
+			}
+			r.Header.Set(header, tc.header)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, r)
+
+			if rr.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, rr.Code)
+			}
+			if id != tc.id {
+				t.Errorf("expected key id %q, got %q", tc.id, id)
+			}
+		})
+	}
+}
+
+// withSecrets points secret lookups at a temporary directory holding secrets
+// for the duration of the test.
+func withSecrets(t *testing.T, secrets map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, value := range secrets {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := secretsPath
+	secretsPath = dir
+	t.Cleanup(func() { secretsPath = old })
+}
@@ -0,0 +1,91 @@
+package faas
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// WriteBlob writes data as the response body. An empty contentType is
+// detected from the data. Content-Length is always set and the optional
+// headers, such as those from Attachment, are added before writing.
+func WriteBlob(w http.ResponseWriter, status int, contentType string, data []byte, headers ...http.Header) error {
+	return writeBlob(w, status, contentType, data, headers...)
+}
+func writeBlob(w http.ResponseWriter, status int, contentType string, data []byte, headers ...http.Header) error {
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	setBlobHeaders(w, contentType, int64(len(data)), headers)
+	w.WriteHeader(status)
+	_, err := w.Write(data)
+	return err
+}
+
+// WriteReader streams r as the response body. An empty contentType is
+// detected from the first 512 bytes. size sets Content-Length and may be -1
+// when unknown, in which case the body is sent chunked and each chunk is
+// flushed as it is read. The optional headers are added as by WriteBlob.
+func WriteReader(w http.ResponseWriter, status int, contentType string, r io.Reader, size int64, headers ...http.Header) error {
+	return writeReader(w, status, contentType, r, size, headers...)
+}
+func writeReader(w http.ResponseWriter, status int, contentType string, r io.Reader, size int64, headers ...http.Header) error {
+	if contentType == "" {
+		br := bufio.NewReaderSize(r, sniffLen)
+		head, err := br.Peek(sniffLen)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return err
+		}
+		contentType = http.DetectContentType(head)
+		r = br
+	}
+	setBlobHeaders(w, contentType, size, headers)
+	w.WriteHeader(status)
+	// bodies of unknown size are usually produced as they are sent, so
+	// pass each chunk on instead of waiting for the buffer to fill
+	_, err := copyContext(context.Background(), w, r, CopyOptions{Flush: size < 0})
+	return err
+}
+
+func setBlobHeaders(w http.ResponseWriter, contentType string, size int64, headers []http.Header) {
+	for _, h := range headers {
+		for k, v := range h {
+			w.Header()[k] = v
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	// the type is known, so browsers must not second guess it
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+}
+
+// Attachment returns headers telling browsers to download the response as
+// filename instead of displaying it.
+func Attachment(filename string) http.Header {
+	return disposition("attachment", filename)
+}
+
+// Inline returns headers telling browsers to display the response, saving
+// it as filename if the user chooses to.
+func Inline(filename string) http.Header {
+	return disposition("inline", filename)
+}
+
+func disposition(kind, filename string) http.Header {
+	v := kind
+	if filename != "" {
+		// FormatMediaType uses RFC 2231 encoding for non-ASCII names
+		if d := mime.FormatMediaType(kind, map[string]string{"filename": filename}); d != "" {
+			v = d
+		}
+	}
+	return http.Header{"Content-Disposition": {v}}
+}
@@ -0,0 +1,68 @@
+package faas
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWriteBlob(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+		headers     http.Header
+		wantType    string
+		disposition string
+	}{
+		{name: "sniffed", data: png, wantType: "image/png"},
+		{name: "explicit", contentType: "text/csv", data: []byte("a,b\n"), wantType: "text/csv"},
+		{
+			name: "attachment", contentType: "application/pdf", data: []byte("%PDF-1.7"),
+			headers: Attachment("report.pdf"), wantType: "application/pdf", disposition: "attachment; filename=report.pdf",
+		},
+		{
+			name: "unicode filename", data: []byte("x"), headers: Inline("résumé.txt"),
+			wantType: "text/plain; charset=utf-8", disposition: "inline; filename*=utf-8''r%C3%A9sum%C3%A9.txt",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if err := WriteBlob(rec, http.StatusOK, tc.contentType, tc.data, tc.headers); err != nil {
+				t.Fatal(err)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tc.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tc.wantType)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != tc.disposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tc.disposition)
+			}
+			if rec.Header().Get("Content-Length") == "" || !bytes.Equal(rec.Body.Bytes(), tc.data) {
+				t.Errorf("unexpected body or length %q", rec.Header().Get("Content-Length"))
+			}
+		})
+	}
+}
+
+func TestWriteReader(t *testing.T) {
+	body := "<html><body>" + strings.Repeat("x", 1000) + "</body></html>"
+	rec := httptest.NewRecorder()
+	// a one byte reader checks sniffing does not lose data across reads
+	if err := WriteReader(rec, http.StatusCreated, "", iotest.OneByteReader(strings.NewReader(body)), -1); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated || rec.Body.String() != body {
+		t.Fatalf("unexpected response %d %d bytes", rec.Code, rec.Body.Len())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Error("Content-Length must not be set for unknown sizes")
+	}
+}
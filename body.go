@@ -0,0 +1,69 @@
+package faas
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// defaultMaxBodyBytes is the request body limit when MAX_BODY_BYTES is
+// unset.
+const defaultMaxBodyBytes = 1_048_576 // 1MB
+
+// BodyOptions overrides how ReadJSON and ReadBody read a request body.
+type BodyOptions struct {
+	// MaxBytes is the largest body accepted. Defaults to the size in the
+	// MAX_BODY_BYTES environment variable, such as "10MB", or 1MB.
+	MaxBytes int64
+}
+
+// maxBodyBytesFromEnv reads MAX_BODY_BYTES, a size parsed by ParseSize.
+func maxBodyBytesFromEnv() (int64, error) {
+	v := os.Getenv("MAX_BODY_BYTES")
+	if v == "" {
+		return defaultMaxBodyBytes, nil
+	}
+	n, err := parseSize(v)
+	if err != nil {
+		return defaultMaxBodyBytes, fmt.Errorf("invalid MAX_BODY_BYTES: %w", err)
+	}
+	if n <= 0 {
+		return defaultMaxBodyBytes, fmt.Errorf("invalid MAX_BODY_BYTES %q, expected a positive size", v)
+	}
+	return n, nil
+}
+
+// maxBodyBytes is the process wide body limit, read once.
+var maxBodyBytes = sync.OnceValue(func() int64 {
+	n, err := maxBodyBytesFromEnv()
+	if err != nil {
+		slog.Warn("ignoring body size limit", "error", err)
+	}
+	return n
+})
+
+// bodyLimit returns the limit set by opts, or the process wide one.
+func bodyLimit(opts []BodyOptions) int64 {
+	for _, o := range opts {
+		if o.MaxBytes > 0 {
+			return o.MaxBytes
+		}
+	}
+	return maxBodyBytes()
+}
+
+// ReadBody reads the whole request body, rejecting bodies larger than the
+// limit with a CodeTooLarge AppError.
+func ReadBody(w http.ResponseWriter, r *http.Request, opts ...BodyOptions) ([]byte, error) {
+	limit := bodyLimit(opts)
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return nil, E(CodeTooLarge, fmt.Sprintf("body must not be larger than %d bytes", limit), err)
+	}
+	return data, err
+}
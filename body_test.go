@@ -0,0 +1,75 @@
+package faas
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodyBytesFromEnv(t *testing.T) {
+	tests := []struct {
+		env  string
+		want int64
+		err  bool
+	}{
+		{"", defaultMaxBodyBytes, false},
+		{"10MB", 10_000_000, false},
+		{"16MiB", 16 << 20, false},
+		{"2048", 2048, false},
+		{"lots", defaultMaxBodyBytes, true},
+		{"0", defaultMaxBodyBytes, true},
+	}
+	for _, tc := range tests {
+		t.Setenv("MAX_BODY_BYTES", tc.env)
+		got, err := maxBodyBytesFromEnv()
+		if tc.err != (err != nil) {
+			t.Errorf("%q: unexpected error %v", tc.env, err)
+		}
+		if got != tc.want {
+			t.Errorf("%q: expected %d, got %d", tc.env, tc.want, got)
+		}
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	data, err := ReadBody(httptest.NewRecorder(), r)
+	if err != nil || string(data) != body {
+		t.Fatalf("ReadBody() = %d bytes, %v", len(data), err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	_, err = ReadBody(httptest.NewRecorder(), r, BodyOptions{MaxBytes: 1024})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != CodeTooLarge {
+		t.Fatalf("expected a CodeTooLarge error, got %v", err)
+	}
+	if appErr.Message != "body must not be larger than 1024 bytes" {
+		t.Errorf("message = %q", appErr.Message)
+	}
+}
+
+func TestReadJSONMaxBytes(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("a", 2*defaultMaxBodyBytes) + `"}`
+	var dst struct {
+		Name string `json:"name"`
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	err := ReadJSON(httptest.NewRecorder(), r, &dst)
+	if err == nil || err.Error() != "body must not be larger than 1048576 bytes" {
+		t.Fatalf("expected the default limit, got %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if err := ReadJSON(httptest.NewRecorder(), r, &dst, BodyOptions{MaxBytes: 10 << 20}); err != nil {
+		t.Fatalf("expected a larger limit to accept the body, got %v", err)
+	}
+	if len(dst.Name) != 2*defaultMaxBodyBytes {
+		t.Errorf("decoded %d bytes", len(dst.Name))
+	}
+}
@@ -0,0 +1,280 @@
+package faas
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces the values removed by LogBodies.
+const redacted = "[REDACTED]"
+
+// BodyLogOptions configure LogBodies.
+type BodyLogOptions struct {
+	// RedactHeaders are header names whose values are never logged, in
+	// addition to Authorization, Cookie, Set-Cookie, Proxy-Authorization
+	// and X-Api-Key.
+	RedactHeaders []string
+	// RedactPaths are JSON paths whose values are replaced, such as
+	// "$.password", "$.card.number" or "$.items[*].token". A "*" segment
+	// matches every field of an object. Form fields are matched by the top
+	// level paths, e.g. "$.password".
+	RedactPaths []string
+	// MaxBytes caps how much of each body is logged. Defaults to 4KB.
+	// JSON and form bodies over the cap are not logged at all as they
+	// cannot be redacted.
+	MaxBytes int
+	// LogOtherBodies logs bodies which are neither JSON nor forms as they
+	// are. By default only their size and type are logged, as secrets in
+	// them cannot be redacted.
+	LogOtherBodies bool
+	// Level is the level of the log records. Defaults to debug, so bodies
+	// are only logged while debugging, see DefaultLogControl and Debug.
+	Level slog.Leveler
+}
+
+var defaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// LogBodies is middleware logging request and response bodies and headers
+// with secrets redacted, for troubleshooting integrations such as webhooks.
+// Records are written with LoggerFromContext and nothing is buffered unless
+// the logger is enabled at the configured level.
+func LogBodies(opts BodyLogOptions) func(http.Handler) http.Handler {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 4 << 10
+	}
+	if opts.Level == nil {
+		opts.Level = slog.LevelDebug
+	}
+	headers := append(append([]string(nil), defaultRedactHeaders...), opts.RedactHeaders...)
+	paths := make([][]string, 0, len(opts.RedactPaths))
+	for _, p := range opts.RedactPaths {
+		paths = append(paths, parseRedactPath(p))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			logger := LoggerFromContext(ctx)
+			if !logger.Enabled(ctx, opts.Level.Level()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqBody := &cappedBuffer{max: opts.MaxBytes}
+			if r.Body != nil {
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.TeeReader(r.Body, reqBody), r.Body}
+			}
+			bw := &bodyLogWriter{ResponseWriter: w, body: cappedBuffer{max: opts.MaxBytes}}
+			next.ServeHTTP(bw, r)
+
+			status := bw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			logger.Log(ctx, opts.Level.Level(), "request bodies",
+				slog.Group("request",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"headers", redactHeaders(r.Header, headers),
+					"body", redactBody(reqBody, r.Header.Get("Content-Type"), paths, opts.LogOtherBodies),
+				),
+				slog.Group("response",
+					"status", status,
+					"headers", redactHeaders(w.Header(), headers),
+					"body", redactBody(&bw.body, w.Header().Get("Content-Type"), paths, opts.LogOtherBodies),
+				),
+			)
+		})
+	}
+}
+
+// cappedBuffer keeps the first max bytes written to it and counts the rest.
+type cappedBuffer struct {
+	bytes.Buffer
+	max   int
+	total int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) truncated() bool {
+	return b.total > b.Len()
+}
+
+type bodyLogWriter struct {
+	http.ResponseWriter
+	status int
+	body   cappedBuffer
+}
+
+func (w *bodyLogWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	_, _ = w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *bodyLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func redactHeaders(h http.Header, names []string) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		v := strings.Join(values, ", ")
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				v = redacted
+				break
+			}
+		}
+		out[name] = v
+	}
+	return out
+}
+
+// redactBody returns the body to log with the paths redacted. Bodies
+// without a content type or sent as text/plain are treated as JSON when
+// they look like it. Other bodies are replaced by a placeholder unless
+// other is set.
+func redactBody(b *cappedBuffer, contentType string, paths [][]string, other bool) string {
+	if b.total == 0 {
+		return ""
+	}
+	s, kind := redactStructured(b, contentType, paths)
+	if kind != bodyOther {
+		return s
+	}
+	if !other {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType == "" {
+			return "[" + FormatSize(int64(b.total)) + " body not logged]"
+		}
+		return "[" + FormatSize(int64(b.total)) + " " + mediaType + " body not logged]"
+	}
+	s = b.String()
+	if b.truncated() {
+		s += "...[truncated]"
+	}
+	return s
+}
+
+type bodyKind int
+
+const (
+	// bodyOther is a body which is neither JSON nor a form.
+	bodyOther bodyKind = iota
+	// bodyRedacted is a JSON or form body with the paths redacted.
+	bodyRedacted
+	// bodyUnredactable is a JSON or form body which was truncated or is
+	// invalid, replaced by a placeholder.
+	bodyUnredactable
+)
+
+// redactStructured redacts the paths of JSON and form bodies, and reports
+// which kind of body b holds.
+func redactStructured(b *cappedBuffer, contentType string, paths [][]string) (string, bodyKind) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	isForm := mediaType == "application/x-www-form-urlencoded"
+	if mediaType == "" || mediaType == "text/plain" {
+		isJSON = looksLikeJSON(b.Bytes())
+	}
+	if (isJSON || isForm) && b.truncated() {
+		return "[" + FormatSize(int64(b.total)) + " body not logged, larger than the size cap]", bodyUnredactable
+	}
+
+	switch {
+	case isJSON:
+		var v any
+		if err := json.Unmarshal(b.Bytes(), &v); err != nil {
+			return "[invalid JSON body not logged]", bodyUnredactable
+		}
+		for _, p := range paths {
+			v = redactPath(v, p)
+		}
+		js, _ := json.Marshal(v)
+		return string(js), bodyRedacted
+	case isForm:
+		form, err := url.ParseQuery(b.String())
+		if err != nil {
+			return "[invalid form body not logged]", bodyUnredactable
+		}
+		for _, p := range paths {
+			if len(p) == 1 && form.Has(p[0]) {
+				form.Set(p[0], redacted)
+			}
+		}
+		return form.Encode(), bodyRedacted
+	}
+	return "", bodyOther
+}
+
+// looksLikeJSON reports whether b starts like a JSON object or array, so
+// that JSON sent without its content type is still redacted even when it
+// was truncated.
+func looksLikeJSON(b []byte) bool {
+	b = bytes.TrimLeft(b, " \t\r\n")
+	return len(b) > 0 && (b[0] == '{' || b[0] == '[')
+}
+
+// parseRedactPath splits "$.items[*].token" into ["items", "[*]", "token"].
+func parseRedactPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(part, "[")
+		if name != "" {
+			segments = append(segments, name)
+		}
+		if rest != "" {
+			segments = append(segments, "["+rest)
+		}
+	}
+	return segments
+}
+
+func redactPath(v any, path []string) any {
+	if len(path) == 0 {
+		return redacted
+	}
+	switch node := v.(type) {
+	case map[string]any:
+		for k, child := range node {
+			if path[0] == "*" || path[0] == k {
+				node[k] = redactPath(child, path[1:])
+			}
+		}
+	case []any:
+		if path[0] == "[*]" {
+			for i, child := range node {
+				node[i] = redactPath(child, path[1:])
+			}
+		}
+	}
+	return v
+}
@@ -0,0 +1,130 @@
+package faas
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogBodies(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		maxBytes    int
+		other       bool
+		want        string
+		leaked      string
+	}{
+		{
+			name:        "json paths",
+			contentType: "application/json",
+			body:        `{"user":"jane","password":"hunter2","card":{"number":"4242"},"items":[{"token":"t1"},{"token":"t2"}]}`,
+			want:        `{"card":{"number":"[REDACTED]"},"items":[{"token":"[REDACTED]"},{"token":"[REDACTED]"}],"password":"[REDACTED]","user":"jane"}`,
+			leaked:      "hunter2",
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "password=hunter2&user=jane",
+			want:        "password=%5BREDACTED%5D&user=jane",
+			leaked:      "hunter2",
+		},
+		{
+			name:        "oversized json",
+			contentType: "application/json",
+			body:        `{"password":"hunter2","padding":"xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"}`,
+			maxBytes:    16,
+			want:        "[67B body not logged, larger than the size cap]",
+			leaked:      "hunter2",
+		},
+		{
+			name:   "json without content type",
+			body:   `{"password":"hunter2"}`,
+			want:   `{"password":"[REDACTED]"}`,
+			leaked: "hunter2",
+		},
+		{
+			name:        "json sent as text",
+			contentType: "text/plain; charset=utf-8",
+			body:        ` [{"password":"hunter2"}]`,
+			maxBytes:    8,
+			want:        "[25B body not logged, larger than the size cap]",
+			leaked:      "hunter2",
+		},
+		{
+			name:        "multipart",
+			contentType: "multipart/form-data; boundary=x",
+			body:        "--x\r\nContent-Disposition: form-data; name=\"password\"\r\n\r\nhunter2\r\n--x--",
+			want:        "[70B multipart/form-data body not logged]",
+			leaked:      "hunter2",
+		},
+		{
+			name:        "truncated text",
+			contentType: "text/plain",
+			body:        "hello world",
+			maxBytes:    5,
+			other:       true,
+			want:        "hello...[truncated]",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			mw := LogBodies(BodyLogOptions{RedactPaths: []string{"$.password", "$.card.number", "$.items[*].token"}, MaxBytes: tc.maxBytes, LogOtherBodies: tc.other})
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", tc.contentType)
+				w.Header().Set("Set-Cookie", "session=abc")
+				_, _ = w.Write(body)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			req.Header.Set("Authorization", "Bearer s3cret")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req.WithContext(WithLogger(req.Context(), logger)))
+
+			if rec.Body.String() != tc.body {
+				t.Fatalf("response was altered: %q", rec.Body)
+			}
+			var line struct {
+				Request  map[string]any `json:"request"`
+				Response map[string]any `json:"response"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("invalid log line %q: %v", buf.String(), err)
+			}
+			for side, got := range map[string]any{"request": line.Request["body"], "response": line.Response["body"]} {
+				if got != tc.want {
+					t.Errorf("%s body = %q, want %q", side, got, tc.want)
+				}
+			}
+			if tc.leaked != "" && strings.Contains(buf.String(), tc.leaked) {
+				t.Errorf("secret leaked into log: %s", buf.String())
+			}
+			if strings.Contains(buf.String(), "s3cret") || strings.Contains(buf.String(), "session=abc") {
+				t.Errorf("headers not redacted: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestLogBodiesDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := LogBodies(BodyLogOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x"))
+	h.ServeHTTP(httptest.NewRecorder(), req.WithContext(WithLogger(req.Context(), logger)))
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing logged at info level, got %s", buf.String())
+	}
+}
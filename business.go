@@ -0,0 +1,404 @@
+package faas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HolidayCalendar reports the days business is closed. date is in the
+// time zone of the BusinessCalendar using it, and only its year, month and
+// day matter.
+type HolidayCalendar interface {
+	Holiday(date time.Time) (name string, ok bool)
+}
+
+// HolidayFunc adapts a function to a HolidayCalendar, for holidays which
+// are computed rather than listed, such as Easter.
+type HolidayFunc func(date time.Time) (string, bool)
+
+// Holiday implements HolidayCalendar.
+func (f HolidayFunc) Holiday(date time.Time) (string, bool) {
+	return f(date)
+}
+
+// Holidays is a HolidayCalendar listing holiday names by date. Keys are
+// "2006-01-02" for a single day or "01-02" for a day of every year:
+//
+//	faas.Holidays{"12-25": "Christmas Day", "2025-04-18": "Good Friday"}
+type Holidays map[string]string
+
+// Holiday implements HolidayCalendar.
+func (h Holidays) Holiday(date time.Time) (string, bool) {
+	if name, ok := h[date.Format("2006-01-02")]; ok {
+		return name, true
+	}
+	name, ok := h[date.Format("01-02")]
+	return name, ok
+}
+
+// validate checks every key is a date.
+func (h Holidays) validate() error {
+	for key := range h {
+		date := key
+		if len(key) == len("01-02") {
+			// in a leap year so "02-29" is accepted
+			date = "2000-" + key
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("invalid holiday date %q, expected YYYY-MM-DD or MM-DD", key)
+		}
+	}
+	return nil
+}
+
+// HolidayCalendars combines calendars, such as national and company
+// holidays, into one closed on the holidays of any of them.
+type HolidayCalendars []HolidayCalendar
+
+// Holiday implements HolidayCalendar, naming the first match.
+func (cs HolidayCalendars) Holiday(date time.Time) (string, bool) {
+	for _, c := range cs {
+		if name, ok := c.Holiday(date); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// ClockRange is a period of the day, as offsets from midnight on the wall
+// clock. End may be 24 hours, the end of the day.
+type ClockRange struct {
+	Start, End time.Duration
+}
+
+// BusinessCalendar answers questions about business hours, such as
+// whether a support team is working now, when an SLA deadline falls or
+// when a deferred notification may be sent.
+//
+// A calendar file looks like:
+//
+//	{
+//	  "time_zone": "Europe/London",
+//	  "hours": {"mon-thu": "09:00-17:30", "fri": "09:00-12:00,13:00-16:00"},
+//	  "holidays": {"12-25": "Christmas Day", "2025-04-18": "Good Friday"}
+//	}
+//
+// Days missing from hours are closed.
+type BusinessCalendar struct {
+	// Location defaults to UTC.
+	Location *time.Location
+	// Hours are the opening periods of each day of the week, in order.
+	Hours map[time.Weekday][]ClockRange
+	// Holidays are closed all day. It may be nil.
+	Holidays HolidayCalendar
+}
+
+// businessSearchDays bounds the searches for an opening, for calendars
+// which are never open.
+const businessSearchDays = 3660
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseBusinessCalendar decodes a JSON calendar file, see BusinessCalendar.
+func ParseBusinessCalendar(data []byte) (*BusinessCalendar, error) {
+	var file struct {
+		TimeZone string            `json:"time_zone"`
+		Hours    map[string]string `json:"hours"`
+		Holidays Holidays          `json:"holidays"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid business calendar: %w", triageJSONError(err, len(data)))
+	}
+	c := &BusinessCalendar{Location: time.UTC, Hours: make(map[time.Weekday][]ClockRange)}
+	if file.TimeZone != "" {
+		loc, err := time.LoadLocation(file.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid business calendar: unknown time zone %q", file.TimeZone)
+		}
+		c.Location = loc
+	}
+	for days, hours := range file.Hours {
+		weekdays, err := parseWeekdays(days)
+		if err != nil {
+			return nil, fmt.Errorf("invalid business calendar: %w", err)
+		}
+		ranges, err := parseClockRanges(hours)
+		if err != nil {
+			return nil, fmt.Errorf("invalid business calendar: hours of %s: %w", days, err)
+		}
+		for _, d := range weekdays {
+			if _, ok := c.Hours[d]; ok {
+				return nil, fmt.Errorf("invalid business calendar: hours of %s given twice", weekdayNames[d])
+			}
+			c.Hours[d] = ranges
+		}
+	}
+	if len(file.Holidays) > 0 {
+		if err := file.Holidays.validate(); err != nil {
+			return nil, fmt.Errorf("invalid business calendar: %w", err)
+		}
+		c.Holidays = file.Holidays
+	}
+	return c, nil
+}
+
+// LoadBusinessCalendar reads a JSON calendar file from path.
+func LoadBusinessCalendar(path string) (*BusinessCalendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBusinessCalendar(data)
+}
+
+// LoadBusinessCalendarFromEnv reads the calendar file named by the
+// BUSINESS_CALENDAR_FILE environment variable. When it is not set the
+// calendar is open 09:00 to 17:00 UTC, Monday to Friday, without holidays.
+func LoadBusinessCalendarFromEnv() (*BusinessCalendar, error) {
+	path, err := getEnvOrError("BUSINESS_CALENDAR_FILE")
+	if err != nil {
+		c := &BusinessCalendar{Location: time.UTC, Hours: make(map[time.Weekday][]ClockRange)}
+		for d := time.Monday; d <= time.Friday; d++ {
+			c.Hours[d] = []ClockRange{{Start: 9 * time.Hour, End: 17 * time.Hour}}
+		}
+		return c, nil
+	}
+	return LoadBusinessCalendar(path)
+}
+
+// parseWeekdays parses "mon" or a range such as "mon-fri".
+func parseWeekdays(s string) ([]time.Weekday, error) {
+	day := func(name string) (time.Weekday, error) {
+		for i, n := range weekdayNames {
+			if strings.EqualFold(name, n) {
+				return time.Weekday(i), nil
+			}
+		}
+		return 0, fmt.Errorf("unknown day %q, expected mon, tue, wed, thu, fri, sat or sun", name)
+	}
+	first, last, isRange := strings.Cut(s, "-")
+	from, err := day(first)
+	if err != nil {
+		return nil, err
+	}
+	to := from
+	if isRange {
+		if to, err = day(last); err != nil {
+			return nil, err
+		}
+	}
+	var days []time.Weekday
+	// a range may wrap around the weekend, e.g. "sat-mon"
+	for d := from; ; d = (d + 1) % 7 {
+		days = append(days, d)
+		if d == to {
+			return days, nil
+		}
+	}
+}
+
+// parseClockRanges parses a list such as "09:00-12:00,13:00-17:00", or
+// "closed".
+func parseClockRanges(s string) ([]ClockRange, error) {
+	if s = strings.TrimSpace(s); s == "" || strings.EqualFold(s, "closed") {
+		return nil, nil
+	}
+	var ranges []ClockRange
+	for _, part := range strings.Split(s, ",") {
+		start, end, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid range %q, expected HH:MM-HH:MM", part)
+		}
+		r := ClockRange{}
+		var err error
+		if r.Start, err = parseClock(start); err != nil {
+			return nil, err
+		}
+		if r.End, err = parseClock(end); err != nil {
+			return nil, err
+		}
+		if r.End <= r.Start {
+			return nil, fmt.Errorf("range %q must end after it starts", part)
+		}
+		if len(ranges) > 0 && r.Start < ranges[len(ranges)-1].End {
+			return nil, fmt.Errorf("range %q overlaps or precedes the one before", part)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// parseClock parses HH:MM, up to 24:00.
+func parseClock(s string) (time.Duration, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hours, err1 := strconv.Atoi(h)
+	minutes, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+func (c *BusinessCalendar) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+// Holiday returns the name of the holiday on the day of t, if it is one.
+func (c *BusinessCalendar) Holiday(t time.Time) (string, bool) {
+	if c.Holidays == nil {
+		return "", false
+	}
+	return c.Holidays.Holiday(t.In(c.location()))
+}
+
+// IsBusinessDay reports whether the day of t has opening hours and is not
+// a holiday.
+func (c *BusinessCalendar) IsBusinessDay(t time.Time) bool {
+	t = t.In(c.location())
+	if len(c.Hours[t.Weekday()]) == 0 {
+		return false
+	}
+	_, holiday := c.Holiday(t)
+	return !holiday
+}
+
+// openPeriods returns the opening periods of the day of t.
+func (c *BusinessCalendar) openPeriods(t time.Time) [][2]time.Time {
+	if !c.IsBusinessDay(t) {
+		return nil
+	}
+	t = t.In(c.location())
+	y, m, d := t.Date()
+	at := func(offset time.Duration) time.Time {
+		// on the wall clock, so days with a daylight saving change work
+		return time.Date(y, m, d, 0, int(offset/time.Minute), 0, 0, c.location())
+	}
+	ranges := c.Hours[t.Weekday()]
+	periods := make([][2]time.Time, len(ranges))
+	for i, r := range ranges {
+		periods[i] = [2]time.Time{at(r.Start), at(r.End)}
+	}
+	return periods
+}
+
+// nextDay returns midnight of the day after t.
+func (c *BusinessCalendar) nextDay(t time.Time) time.Time {
+	t = t.In(c.location())
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, c.location())
+}
+
+// IsOpen reports whether t is within business hours.
+func (c *BusinessCalendar) IsOpen(t time.Time) bool {
+	for _, p := range c.openPeriods(t) {
+		if !t.Before(p[0]) && t.Before(p[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns t when it is within business hours, or else the start
+// of the next opening, such as when to send a notification held back
+// outside business hours. It returns the zero time when the calendar is
+// never open.
+func (c *BusinessCalendar) NextOpen(t time.Time) time.Time {
+	day := t
+	for i := 0; i < businessSearchDays; i++ {
+		for _, p := range c.openPeriods(day) {
+			if t.Before(p[1]) {
+				if t.Before(p[0]) {
+					return p[0]
+				}
+				return t
+			}
+		}
+		day = c.nextDay(day)
+	}
+	return time.Time{}
+}
+
+// AddBusinessTime returns when d of business hours have passed after t,
+// such as the deadline of an SLA measured in working hours. It returns the
+// zero time when the calendar is never open.
+func (c *BusinessCalendar) AddBusinessTime(t time.Time, d time.Duration) time.Time {
+	day := t
+	for i := 0; i < businessSearchDays; i++ {
+		for _, p := range c.openPeriods(day) {
+			if !t.Before(p[1]) {
+				continue
+			}
+			start := p[0]
+			if t.After(start) {
+				start = t
+			}
+			left := p[1].Sub(start)
+			if d <= left {
+				return start.Add(d)
+			}
+			d -= left
+		}
+		day = c.nextDay(day)
+	}
+	return time.Time{}
+}
+
+// BusinessDuration returns how much of the time from from to to is within
+// business hours, such as the working time an SLA has been open. It is
+// negative when to is before from.
+func (c *BusinessCalendar) BusinessDuration(from, to time.Time) time.Duration {
+	if to.Before(from) {
+		return -c.BusinessDuration(to, from)
+	}
+	var total time.Duration
+	for day := from; day.Before(to); day = c.nextDay(day) {
+		for _, p := range c.openPeriods(day) {
+			start, end := p[0], p[1]
+			if from.After(start) {
+				start = from
+			}
+			if to.Before(end) {
+				end = to
+			}
+			if end.After(start) {
+				total += end.Sub(start)
+			}
+		}
+	}
+	return total
+}
+
+// AddBusinessDays returns the same wall clock time n business days after
+// t, or before it when n is negative. Days which are not business days are
+// skipped, so one business day after a Friday is the Monday. It returns
+// the zero time when the calendar is never open.
+func (c *BusinessCalendar) AddBusinessDays(t time.Time, n int) time.Time {
+	t = t.In(c.location())
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	y, m, d := t.Date()
+	for closed := 0; n > 0; {
+		d += step
+		if !c.IsBusinessDay(time.Date(y, m, d, 12, 0, 0, 0, c.location())) {
+			if closed++; closed >= businessSearchDays {
+				return time.Time{}
+			}
+			continue
+		}
+		closed = 0
+		n--
+	}
+	return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), c.location())
+}
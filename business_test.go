@@ -0,0 +1,162 @@
+package faas
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCalendar = `{
+	"time_zone": "Europe/London",
+	"hours": {"mon-thu": "09:00-17:30", "fri": "09:00-12:00,13:00-16:00", "sat": "closed"},
+	"holidays": {"12-25": "Christmas Day", "2024-03-29": "Good Friday"}
+}`
+
+func parseTestCalendar(t *testing.T) *BusinessCalendar {
+	t.Helper()
+	c, err := ParseBusinessCalendar([]byte(testCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestBusinessCalendarIsOpen(t *testing.T) {
+	c := parseTestCalendar(t)
+	london := c.Location
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		// Tuesday 2 July 2024, British Summer Time
+		{time.Date(2024, 7, 2, 8, 59, 0, 0, london), false},
+		{time.Date(2024, 7, 2, 9, 0, 0, 0, london), true},
+		{time.Date(2024, 7, 2, 17, 29, 0, 0, london), true},
+		{time.Date(2024, 7, 2, 17, 30, 0, 0, london), false},
+		// 08:30 UTC is 09:30 in London
+		{time.Date(2024, 7, 2, 8, 30, 0, 0, time.UTC), true},
+		// the Friday lunch break
+		{time.Date(2024, 7, 5, 12, 30, 0, 0, london), false},
+		{time.Date(2024, 7, 5, 13, 0, 0, 0, london), true},
+		{time.Date(2024, 7, 6, 10, 0, 0, 0, london), false},
+		{time.Date(2024, 7, 7, 10, 0, 0, 0, london), false},
+		{time.Date(2024, 12, 25, 10, 0, 0, 0, london), false},
+		{time.Date(2024, 3, 29, 10, 0, 0, 0, london), false},
+	}
+	for _, tt := range tests {
+		if got := c.IsOpen(tt.at); got != tt.want {
+			t.Errorf("IsOpen(%v) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+	if name, ok := c.Holiday(time.Date(2030, 12, 25, 0, 0, 0, 0, london)); !ok || name != "Christmas Day" {
+		t.Errorf("Holiday() = %q, %v", name, ok)
+	}
+}
+
+func TestBusinessCalendarArithmetic(t *testing.T) {
+	c := parseTestCalendar(t)
+	london := c.Location
+	friday := time.Date(2024, 7, 5, 15, 0, 0, 0, london)
+
+	if got, want := c.NextOpen(friday), friday; !got.Equal(want) {
+		t.Errorf("NextOpen(open) = %v", got)
+	}
+	if got, want := c.NextOpen(time.Date(2024, 7, 5, 18, 0, 0, 0, london)), time.Date(2024, 7, 8, 9, 0, 0, 0, london); !got.Equal(want) {
+		t.Errorf("NextOpen(friday evening) = %v, want %v", got, want)
+	}
+	if got, want := c.NextOpen(time.Date(2024, 7, 5, 12, 15, 0, 0, london)), time.Date(2024, 7, 5, 13, 0, 0, 0, london); !got.Equal(want) {
+		t.Errorf("NextOpen(lunch) = %v, want %v", got, want)
+	}
+
+	// an hour on Friday and three on Monday
+	if got, want := c.AddBusinessTime(friday, 4*time.Hour), time.Date(2024, 7, 8, 12, 0, 0, 0, london); !got.Equal(want) {
+		t.Errorf("AddBusinessTime() = %v, want %v", got, want)
+	}
+	if got := c.BusinessDuration(friday, time.Date(2024, 7, 8, 12, 0, 0, 0, london)); got != 4*time.Hour {
+		t.Errorf("BusinessDuration() = %v", got)
+	}
+	if got := c.BusinessDuration(time.Date(2024, 7, 8, 12, 0, 0, 0, london), friday); got != -4*time.Hour {
+		t.Errorf("reversed BusinessDuration() = %v", got)
+	}
+
+	// Thursday before Good Friday, over the Easter weekend
+	thursday := time.Date(2024, 3, 28, 10, 0, 0, 0, london)
+	if got, want := c.AddBusinessDays(thursday, 1), time.Date(2024, 4, 1, 10, 0, 0, 0, london); !got.Equal(want) {
+		t.Errorf("AddBusinessDays(1) = %v, want %v", got, want)
+	}
+	if got, want := c.AddBusinessDays(time.Date(2024, 4, 1, 10, 0, 0, 0, london), -1), thursday; !got.Equal(want) {
+		t.Errorf("AddBusinessDays(-1) = %v, want %v", got, want)
+	}
+
+	never := &BusinessCalendar{}
+	if got := never.NextOpen(friday); !got.IsZero() {
+		t.Errorf("NextOpen() of a closed calendar = %v", got)
+	}
+	if got := never.AddBusinessDays(friday, 3); !got.IsZero() {
+		t.Errorf("AddBusinessDays() of a closed calendar = %v", got)
+	}
+}
+
+func TestHolidayCalendars(t *testing.T) {
+	easterMonday := HolidayFunc(func(d time.Time) (string, bool) {
+		return "Easter Monday", d.Year() == 2024 && d.Month() == time.April && d.Day() == 1
+	})
+	c := parseTestCalendar(t)
+	c.Holidays = HolidayCalendars{c.Holidays, easterMonday}
+	if c.IsBusinessDay(time.Date(2024, 4, 1, 10, 0, 0, 0, c.Location)) {
+		t.Error("Easter Monday is a business day")
+	}
+	if !c.IsBusinessDay(time.Date(2024, 4, 2, 10, 0, 0, 0, c.Location)) {
+		t.Error("the Tuesday after Easter is not a business day")
+	}
+}
+
+func TestParseBusinessCalendarErrors(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{`{"hours": {"mon": "9-17"}}`, `invalid time "9"`},
+		{`{"hours": {"funday": "09:00-17:00"}}`, `unknown day "funday"`},
+		{`{"hours": {"mon": "17:00-09:00"}}`, "must end after it starts"},
+		{`{"hours": {"mon": "09:00-13:00,12:00-17:00"}}`, "overlaps"},
+		{`{"hours": {"mon-fri": "09:00-17:00", "fri": "10:00-12:00"}}`, "hours of fri given twice"},
+		{`{"time_zone": "Nowhere/Land"}`, "unknown time zone"},
+		{`{"holidays": {"12-32": "x"}}`, `invalid holiday date "12-32"`},
+		{`{"opening": {}}`, `unknown key "opening"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseBusinessCalendar([]byte(tt.doc))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseBusinessCalendar(%s) err = %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+	c, err := ParseBusinessCalendar([]byte(`{"hours": {"sat-mon": "00:00-24:00"}, "holidays": {"02-29": "Leap day"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Hours) != 3 || !c.IsOpen(time.Date(2024, 7, 7, 23, 59, 0, 0, time.UTC)) {
+		t.Errorf("hours = %v", c.Hours)
+	}
+}
+
+func TestLoadBusinessCalendarFromEnv(t *testing.T) {
+	c, err := LoadBusinessCalendarFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.IsOpen(time.Date(2024, 7, 2, 9, 0, 0, 0, time.UTC)) || c.IsOpen(time.Date(2024, 7, 6, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("default calendar = %+v", c)
+	}
+
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	if err := os.WriteFile(path, []byte(testCalendar), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BUSINESS_CALENDAR_FILE", path)
+	if c, err = LoadBusinessCalendarFromEnv(); err != nil || c.Location.String() != "Europe/London" {
+		t.Errorf("LoadBusinessCalendarFromEnv() = %+v, %v", c, err)
+	}
+}
@@ -0,0 +1,65 @@
+package faas
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync/atomic"
+)
+
+// JSONOutput controls the bodies written by WriteJSON.
+type JSONOutput struct {
+	// Canonical writes objects with their keys sorted, struct fields
+	// included, and without escaping <, > and &, so equal values always give
+	// the same bytes, as needed to sign responses, key caches or compare
+	// against golden files.
+	Canonical bool
+	// Indent indents canonical output by this string per level, e.g. two
+	// spaces. Compact when empty.
+	Indent string
+}
+
+var jsonOutput atomic.Pointer[JSONOutput]
+
+// SetJSONOutput replaces how WriteJSON writes bodies. It is normally
+// called once at startup.
+func SetJSONOutput(o JSONOutput) {
+	jsonOutput.Store(&o)
+}
+
+func currentJSONOutput() JSONOutput {
+	if o := jsonOutput.Load(); o != nil {
+		return *o
+	}
+	return JSONOutput{}
+}
+
+// MarshalCanonical encodes v as canonical JSON, see JSONOutput. Numbers
+// are kept as encoded by the codec rather than normalised, so the output
+// is stable for a given program but is not RFC 8785.
+func MarshalCanonical(v any, indent string) ([]byte, error) {
+	data, err := jsonCodec().Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalize(data, indent)
+}
+
+// canonicalize re-encodes the JSON document data with sorted keys and
+// without HTML escaping.
+func canonicalize(data []byte, indent string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	// encoding/json writes map keys sorted
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(tree); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,59 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type canonicalOrder struct {
+	Zone  string         `json:"zone"`
+	Total Decimal        `json:"total"`
+	Note  string         `json:"note"`
+	Meta  map[string]int `json:"meta"`
+	Big   int64          `json:"big"`
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	v := canonicalOrder{Zone: "b", Total: MustParseDecimal("10.50"), Note: "a<b & c>d", Meta: map[string]int{"y": 2, "x": 1}, Big: 1 << 60}
+	got, err := MarshalCanonical(v, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"big":1152921504606846976,"meta":{"x":1,"y":2},"note":"a<b & c>d","total":"10.50","zone":"b"}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	indented, err := MarshalCanonical(map[string]any{"b": []int{1}, "a": true}, "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"a\": true,\n  \"b\": [\n    1\n  ]\n}"; string(indented) != want {
+		t.Errorf("indented:\n%s\nwant\n%s", indented, want)
+	}
+}
+
+func TestWriteJSONCanonical(t *testing.T) {
+	defer SetJSONOutput(JSONOutput{})
+	v := canonicalOrder{Zone: "b", Note: "<x>"}
+
+	SetJSONOutput(JSONOutput{Canonical: true})
+	first := httptest.NewRecorder()
+	second := httptest.NewRecorder()
+	_ = WriteJSON(first, http.StatusOK, v, nil)
+	_ = WriteJSON(second, http.StatusOK, map[string]any{"zone": "b", "note": "<x>", "total": "0", "meta": nil, "big": 0}, nil)
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("struct and map bodies differ:\n%s\n%s", first.Body, second.Body)
+	}
+	if want := `{"big":0,"meta":null,"note":"<x>","total":"0","zone":"b"}`; first.Body.String() != want {
+		t.Errorf("got %s, want %s", first.Body, want)
+	}
+
+	SetJSONOutput(JSONOutput{})
+	w := httptest.NewRecorder()
+	_ = WriteJSON(w, http.StatusOK, v, nil)
+	if want := `{"zone":"b","total":"0","note":"\u003cx\u003e","meta":null,"big":0}`; w.Body.String() != want {
+		t.Errorf("default output changed: %s", w.Body)
+	}
+}
@@ -0,0 +1,210 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SurrogateKeys tags a cacheable response with keys, such as "product-42",
+// which a Purger can later invalidate. They are sent in both the
+// Surrogate-Key header read by Fastly and the Cache-Tag header read by
+// Cloudflare. Call it before the response is written.
+func SurrogateKeys(w http.ResponseWriter, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	h := w.Header()
+	if v := h.Get("Surrogate-Key"); v != "" {
+		h.Set("Surrogate-Key", v+" "+strings.Join(keys, " "))
+	} else {
+		h.Set("Surrogate-Key", strings.Join(keys, " "))
+	}
+	if v := h.Get("Cache-Tag"); v != "" {
+		h.Set("Cache-Tag", v+","+strings.Join(keys, ","))
+	} else {
+		h.Set("Cache-Tag", strings.Join(keys, ","))
+	}
+}
+
+// Purger invalidates the CDN cached responses tagged with surrogate keys.
+type Purger interface {
+	Purge(ctx context.Context, keys ...string) error
+}
+
+// CloudflarePurger purges Cloudflare cache tags of a zone. The API token
+// needs the Cache Purge permission.
+type CloudflarePurger struct {
+	ZoneID string
+	// TokenSecret is the name of the secret holding the API token.
+	// Defaults to cloudflare-api-token.
+	TokenSecret string
+	// Client defaults to SharedHTTPClient.
+	Client *http.Client
+	// BaseURL defaults to https://api.cloudflare.com/client/v4.
+	BaseURL string
+}
+
+// Purge implements Purger, sending the keys in batches of 30, the API
+// limit.
+func (p *CloudflarePurger) Purge(ctx context.Context, keys ...string) error {
+	token, err := getSecretString(defaultString(p.TokenSecret, "cloudflare-api-token"))
+	if err != nil {
+		return fmt.Errorf("reading cloudflare token: %w", err)
+	}
+	url := strings.TrimSuffix(defaultString(p.BaseURL, "https://api.cloudflare.com/client/v4"), "/") + "/zones/" + p.ZoneID + "/purge_cache"
+	for _, batch := range batchKeys(keys, 30) {
+		body, _ := json.Marshal(Map{"tags": batch})
+		header := http.Header{"Authorization": {"Bearer " + token}, "Content-Type": {"application/json"}}
+		if err := sendPurge(ctx, p.Client, url, header, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FastlyPurger purges Fastly surrogate keys of a service.
+type FastlyPurger struct {
+	ServiceID string
+	// TokenSecret is the name of the secret holding the API token.
+	// Defaults to fastly-api-token.
+	TokenSecret string
+	// Soft marks the content as stale instead of removing it, so it can
+	// still be served while the origin is unavailable.
+	Soft bool
+	// Client defaults to SharedHTTPClient.
+	Client *http.Client
+	// BaseURL defaults to https://api.fastly.com.
+	BaseURL string
+}
+
+// Purge implements Purger, sending the keys in batches of 256, the API
+// limit.
+func (p *FastlyPurger) Purge(ctx context.Context, keys ...string) error {
+	token, err := getSecretString(defaultString(p.TokenSecret, "fastly-api-token"))
+	if err != nil {
+		return fmt.Errorf("reading fastly token: %w", err)
+	}
+	url := strings.TrimSuffix(defaultString(p.BaseURL, "https://api.fastly.com"), "/") + "/service/" + p.ServiceID + "/purge"
+	for _, batch := range batchKeys(keys, 256) {
+		header := http.Header{"Fastly-Key": {token}, "Surrogate-Key": {strings.Join(batch, " ")}}
+		if p.Soft {
+			header.Set("Fastly-Soft-Purge", "1")
+		}
+		if err := sendPurge(ctx, p.Client, url, header, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendPurge posts a purge request, retrying rate limited and failed
+// requests.
+func sendPurge(ctx context.Context, client *http.Client, url string, header http.Header, body []byte) error {
+	if client == nil {
+		client = SharedHTTPClient()
+	}
+	policy := RetryPolicy{Attempts: 3, Backoff: Duration(time.Second), Jitter: true}
+	return retry(ctx, policy, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return Permanent(err)
+		}
+		req.Header = header.Clone()
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySnippet))
+		err = &StatusError{Method: req.Method, URL: req.URL.Redacted(), StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(snippet))}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return err
+		}
+		return Permanent(err)
+	})
+}
+
+func batchKeys(keys []string, size int) [][]string {
+	var batches [][]string
+	for len(keys) > size {
+		batches = append(batches, keys[:size])
+		keys = keys[size:]
+	}
+	if len(keys) > 0 {
+		batches = append(batches, keys)
+	}
+	return batches
+}
+
+func defaultString(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+type purgeSet struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+var purgeKey = NewContextKey[*purgeSet]("purge")
+
+// PurgeKeys schedules keys to be purged once the request completes
+// successfully. It needs the PurgeAfter middleware and does nothing
+// without it.
+func PurgeKeys(ctx context.Context, keys ...string) {
+	if set, ok := FromContext(ctx, purgeKey); ok {
+		set.mu.Lock()
+		set.keys = append(set.keys, keys...)
+		set.mu.Unlock()
+	}
+}
+
+// PurgeAfter is middleware purging the keys scheduled with PurgeKeys after
+// the handler responds with a status below 400, so caches are invalidated
+// after every successful mutation. Failed purges are logged.
+func PurgeAfter(p Purger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			set := &purgeSet{}
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r.WithContext(WithValue(r.Context(), purgeKey, set)))
+
+			set.mu.Lock()
+			keys := dedupeStrings(set.keys)
+			set.mu.Unlock()
+			if len(keys) == 0 || sw.status >= 400 {
+				return
+			}
+			// the response is sent, the purge must not be cancelled with it
+			ctx := context.WithoutCancel(r.Context())
+			if err := p.Purge(ctx, keys...); err != nil {
+				LoggerFromContext(ctx).Error("purging cdn cache", "keys", keys, "error", err)
+			}
+		})
+	}
+}
+
+func dedupeStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := values[:0:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSurrogateKeys(t *testing.T) {
+	w := httptest.NewRecorder()
+	SurrogateKeys(w, "product-1", "products")
+	SurrogateKeys(w, "category-2")
+	SurrogateKeys(w)
+	if got := w.Header().Get("Surrogate-Key"); got != "product-1 products category-2" {
+		t.Errorf("Surrogate-Key = %q", got)
+	}
+	if got := w.Header().Get("Cache-Tag"); got != "product-1,products,category-2" {
+		t.Errorf("Cache-Tag = %q", got)
+	}
+}
+
+type purgeRequest struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+func purgeServer(t *testing.T, statuses ...int) (*httptest.Server, func() []purgeRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var reqs []purgeRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		n := len(reqs)
+		reqs = append(reqs, purgeRequest{path: r.URL.Path, header: r.Header, body: string(body)})
+		mu.Unlock()
+		if n < len(statuses) {
+			w.WriteHeader(statuses[n])
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []purgeRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]purgeRequest(nil), reqs...)
+	}
+}
+
+func TestCloudflarePurger(t *testing.T) {
+	withSecrets(t, map[string]string{"cloudflare-api-token": "cf-token\n"})
+	srv, requests := purgeServer(t)
+	keys := make([]string, 31)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+	}
+	p := &CloudflarePurger{ZoneID: "zone1", BaseURL: srv.URL}
+	if err := p.Purge(context.Background(), keys...); err != nil {
+		t.Fatal(err)
+	}
+	reqs := requests()
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %d, want 2 batches", len(reqs))
+	}
+	if reqs[0].path != "/zones/zone1/purge_cache" || reqs[0].header.Get("Authorization") != "Bearer cf-token" {
+		t.Errorf("request = %s %v", reqs[0].path, reqs[0].header)
+	}
+	var body struct{ Tags []string }
+	if err := json.Unmarshal([]byte(reqs[1].body), &body); err != nil || len(body.Tags) != 1 || body.Tags[0] != "k30" {
+		t.Errorf("second batch = %s", reqs[1].body)
+	}
+}
+
+func TestFastlyPurger(t *testing.T) {
+	withSecrets(t, map[string]string{"fastly-api-token": "fastly-token"})
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+		wantReqs int
+	}{
+		{name: "purged", wantReqs: 1},
+		{name: "rate limited is retried", statuses: []int{http.StatusTooManyRequests}, wantReqs: 2},
+		{name: "forbidden is not retried", statuses: []int{http.StatusForbidden}, wantErr: true, wantReqs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := purgeServer(t, tt.statuses...)
+			p := &FastlyPurger{ServiceID: "svc", Soft: true, BaseURL: srv.URL}
+			err := p.Purge(context.Background(), "a", "b")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Purge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			reqs := requests()
+			if len(reqs) != tt.wantReqs {
+				t.Fatalf("requests = %d, want %d", len(reqs), tt.wantReqs)
+			}
+			h := reqs[0].header
+			if reqs[0].path != "/service/svc/purge" || h.Get("Fastly-Key") != "fastly-token" || h.Get("Surrogate-Key") != "a b" || h.Get("Fastly-Soft-Purge") != "1" {
+				t.Errorf("request = %s %v", reqs[0].path, h)
+			}
+		})
+	}
+}
+
+type recordingPurger struct {
+	keys []string
+}
+
+func (p *recordingPurger) Purge(_ context.Context, keys ...string) error {
+	p.keys = append(p.keys, keys...)
+	return nil
+}
+
+func TestPurgeAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantKeys string
+	}{
+		{name: "success purges", status: http.StatusOK, wantKeys: "product-1,products"},
+		{name: "failure does not purge", status: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPurger{}
+			h := PurgeAfter(p)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				PurgeKeys(r.Context(), "product-1", "products")
+				PurgeKeys(r.Context(), "products")
+				w.WriteHeader(tt.status)
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/products/1", nil))
+			if got := strings.Join(p.keys, ","); got != tt.wantKeys {
+				t.Errorf("purged %q, want %q", got, tt.wantKeys)
+			}
+		})
+	}
+
+	// without the middleware PurgeKeys is a no-op
+	PurgeKeys(context.Background(), "x")
+}
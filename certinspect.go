@@ -0,0 +1,179 @@
+package faas
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// CertificateInfo describes a certificate of a chain returned by
+// InspectTLS.
+type CertificateInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	IPAddresses        []string  `json:"ip_addresses,omitempty"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	IsCA               bool      `json:"is_ca"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	PublicKeyAlgorithm string    `json:"public_key_algorithm"`
+	// SHA256 is the hex encoded fingerprint of the DER certificate.
+	SHA256 string `json:"sha256"`
+}
+
+// TLSInfo is the result of InspectTLS.
+type TLSInfo struct {
+	Address     string `json:"address"`
+	ServerName  string `json:"server_name"`
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipher_suite"`
+	ALPN        string `json:"alpn,omitempty"`
+	// Versions are the protocol versions the server accepts, only set when
+	// TLSInspectOptions.ProbeVersions is.
+	Versions []string `json:"versions,omitempty"`
+	// Chain is the chain sent by the server, leaf first.
+	Chain []CertificateInfo `json:"chain"`
+	// Verified reports whether the chain is valid for ServerName. The
+	// reason it is not, such as expiry, is in VerifyError.
+	Verified    bool   `json:"verified"`
+	VerifyError string `json:"verify_error,omitempty"`
+}
+
+// Expires returns the expiry time of the leaf certificate.
+func (i *TLSInfo) Expires() time.Time {
+	if len(i.Chain) == 0 {
+		return time.Time{}
+	}
+	return i.Chain[0].NotAfter
+}
+
+// ExpiresIn returns the time left before the leaf certificate expires,
+// negative once it has.
+func (i *TLSInfo) ExpiresIn() time.Duration {
+	return time.Until(i.Expires())
+}
+
+// TLSInspectOptions configure InspectTLS.
+type TLSInspectOptions struct {
+	// ServerName is sent with SNI and verified. Defaults to the host of the
+	// address.
+	ServerName string
+	// Timeout bounds each handshake. Defaults to 10 seconds.
+	Timeout time.Duration
+	// RootCAs verify the chain. Defaults to the system roots.
+	RootCAs *x509.CertPool
+	// Proxy picks the proxy to connect through, as http.Transport.Proxy
+	// does, e.g. ProxySelector.Proxy or http.ProxyFromEnvironment.
+	Proxy func(*http.Request) (*url.URL, error)
+	// ProbeVersions makes a handshake per TLS version from 1.0 to 1.3 to
+	// find which the server accepts.
+	ProbeVersions bool
+}
+
+var tlsVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// InspectTLS connects to addr, a "host:port", and returns the details of
+// the TLS connection and certificate chain, for monitoring certificate
+// expiry. Invalid chains are reported in the result rather than failing
+// the inspection, errors are only returned when no handshake is possible.
+func InspectTLS(ctx context.Context, addr string, opts TLSInspectOptions) (*TLSInfo, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	serverName := defaultString(opts.ServerName, host)
+	var proxy *url.URL
+	if opts.Proxy != nil {
+		if proxy, err = opts.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: addr}}); err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := tlsHandshake(ctx, addr, proxy, opts, &tls.Config{ServerName: serverName, NextProtos: []string{"h2", "http/1.1"}})
+	if err != nil {
+		return nil, err
+	}
+	info := &TLSInfo{
+		Address:     addr,
+		ServerName:  serverName,
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+	}
+	for _, cert := range state.PeerCertificates {
+		info.Chain = append(info.Chain, certificateInfo(cert))
+	}
+	if err := verifyChain(state.PeerCertificates, serverName, opts.RootCAs); err != nil {
+		info.VerifyError = err.Error()
+	} else {
+		info.Verified = true
+	}
+
+	if opts.ProbeVersions {
+		for _, v := range tlsVersions {
+			cfg := &tls.Config{ServerName: serverName, MinVersion: v, MaxVersion: v}
+			if _, err := tlsHandshake(ctx, addr, proxy, opts, cfg); err == nil {
+				info.Versions = append(info.Versions, tls.VersionName(v))
+			}
+		}
+	}
+	return info, nil
+}
+
+func tlsHandshake(ctx context.Context, addr string, proxy *url.URL, opts TLSInspectOptions, cfg *tls.Config) (tls.ConnectionState, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultDuration(opts.Timeout, 10*time.Second))
+	defer cancel()
+	conn, err := DialProxy(ctx, nil, proxy, addr)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	// the chain is verified separately so invalid certificates can still
+	// be inspected
+	cfg.InsecureSkipVerify = true
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	return tlsConn.ConnectionState(), nil
+}
+
+func verifyChain(certs []*x509.Certificate, serverName string, roots *x509.CertPool) error {
+	if len(certs) == 0 {
+		return x509.CertificateInvalidError{Reason: x509.NotAuthorizedToSign, Detail: "no certificates"}
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{DNSName: serverName, Roots: roots, Intermediates: intermediates})
+	return err
+}
+
+func certificateInfo(c *x509.Certificate) CertificateInfo {
+	sum := sha256.Sum256(c.Raw)
+	info := CertificateInfo{
+		Subject:            c.Subject.String(),
+		Issuer:             c.Issuer.String(),
+		SerialNumber:       c.SerialNumber.String(),
+		DNSNames:           c.DNSNames,
+		NotBefore:          c.NotBefore,
+		NotAfter:           c.NotAfter,
+		IsCA:               c.IsCA,
+		SignatureAlgorithm: c.SignatureAlgorithm.String(),
+		PublicKeyAlgorithm: c.PublicKeyAlgorithm.String(),
+		SHA256:             hex.EncodeToString(sum[:]),
+	}
+	for _, ip := range c.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info
+}
@@ -0,0 +1,65 @@
+package faas
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInspectTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "https://")
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	proxy, _ := connectProxy(t)
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	tests := []struct {
+		name         string
+		opts         TLSInspectOptions
+		wantVerified bool
+	}{
+		{name: "trusted", opts: TLSInspectOptions{RootCAs: roots}, wantVerified: true},
+		{name: "untrusted is still inspected", opts: TLSInspectOptions{}},
+		{name: "name mismatch", opts: TLSInspectOptions{RootCAs: roots, ServerName: "other.test"}},
+		{name: "through proxy", opts: TLSInspectOptions{RootCAs: roots, Proxy: http.ProxyURL(proxyURL)}, wantVerified: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := InspectTLS(context.Background(), addr, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Verified != tt.wantVerified || (info.VerifyError == "") != tt.wantVerified {
+				t.Errorf("Verified = %v, VerifyError = %q", info.Verified, info.VerifyError)
+			}
+			if len(info.Chain) == 0 || !info.Expires().Equal(srv.Certificate().NotAfter) || info.ExpiresIn() <= 0 {
+				t.Fatalf("chain = %+v", info.Chain)
+			}
+			if !slices.Contains(info.Chain[0].IPAddresses, "127.0.0.1") || info.Chain[0].SHA256 == "" {
+				t.Errorf("leaf = %+v", info.Chain[0])
+			}
+			if info.Version == "" || info.CipherSuite == "" {
+				t.Errorf("info = %+v", info)
+			}
+		})
+	}
+
+	info, err := InspectTLS(context.Background(), addr, TLSInspectOptions{ProbeVersions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(info.Versions, "TLS 1.3") || !slices.Contains(info.Versions, "TLS 1.2") {
+		t.Errorf("Versions = %v", info.Versions)
+	}
+
+	if _, err := InspectTLS(context.Background(), "no-port", TLSInspectOptions{}); err == nil {
+		t.Error("invalid address returned no error")
+	}
+}
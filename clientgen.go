@@ -0,0 +1,311 @@
+package faas
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Route describes an endpoint of a function for GenerateClient. Request and
+// Response are zero values of the body types, e.g. CreateOrder{}, and are
+// nil for endpoints without a body.
+type Route struct {
+	// Name is the name of the generated client method, e.g. "CreateOrder".
+	Name   string
+	Method string
+	// Path may contain {param} segments, which become string arguments of
+	// the client method.
+	Path     string
+	Request  any
+	Response any
+}
+
+var (
+	routesMu sync.Mutex
+	routes   []Route
+)
+
+// RegisterRoute records route for RegisteredRoutes, typically next to the
+// code registering its handler.
+func RegisterRoute(route Route) {
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	routes = append(routes, route)
+}
+
+// RegisteredRoutes returns the routes recorded by RegisterRoute.
+func RegisteredRoutes() []Route {
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	return append([]Route(nil), routes...)
+}
+
+// FunctionURL returns the URL of the function name behind the OpenFaaS
+// gateway, read from GATEWAY_URL and defaulting to the in-cluster address
+// http://gateway.openfaas:8080.
+func FunctionURL(name string) string {
+	return gatewayBaseURL() + "/function/" + url.PathEscape(name)
+}
+
+// ClientOptions configure GenerateClient.
+type ClientOptions struct {
+	// Package is the package name of the generated code.
+	Package string
+	// Function is the name of the function the client calls.
+	Function string
+	// Routes defaults to RegisteredRoutes.
+	Routes []Route
+}
+
+// GenerateClient writes a Go client package for the routes of a function,
+// with copies of the request and response types, so other functions can
+// call it through the gateway without sharing code. Types with their own
+// JSON or text marshalling, such as time.Time, are referenced rather than
+// copied and must live in an importable package. It is meant to be run
+// from a small generator program:
+//
+//	//go:generate go run ./gen
+//
+// where gen/main.go registers the routes and calls GenerateClient.
+func GenerateClient(w io.Writer, opts ClientOptions) error {
+	if opts.Package == "" || opts.Function == "" {
+		return fmt.Errorf("package and function names are required")
+	}
+	if opts.Routes == nil {
+		opts.Routes = RegisteredRoutes()
+	}
+	g := &clientGen{types: map[string]reflect.Type{}, imports: map[string]bool{"context": true, "net/http": true, faasImportPath: true}}
+
+	var methods bytes.Buffer
+	for _, route := range opts.Routes {
+		if err := g.method(&methods, route); err != nil {
+			return fmt.Errorf("route %s: %w", route.Name, err)
+		}
+	}
+	var decls bytes.Buffer
+	if err := g.declare(&decls); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by faas.GenerateClient. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "// Package %s is a client for the %s function.\n", opts.Package, opts.Function)
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", opts.Package)
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		if !strings.Contains(path, ".") {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+	}
+	fmt.Fprintf(&out, "\n\tfaas %q\n", faasImportPath)
+	for _, path := range imports {
+		if strings.Contains(path, ".") && path != faasImportPath {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+	}
+	fmt.Fprintf(&out, ")\n\n")
+	fmt.Fprintf(&out, "// Client calls the %s function.\ntype Client struct {\n", opts.Function)
+	fmt.Fprintf(&out, "\t// BaseURL defaults to faas.FunctionURL(%q).\n\tBaseURL string\n", opts.Function)
+	fmt.Fprintf(&out, "\t// HTTP defaults to faas.GatewayClient, which adds the gateway\n\t// credentials.\n\tHTTP *http.Client\n}\n\n")
+	fmt.Fprintf(&out, "// New returns a client calling the function through the gateway.\n")
+	fmt.Fprintf(&out, "func New() *Client {\n\treturn &Client{BaseURL: faas.FunctionURL(%q)}\n}\n\n", opts.Function)
+	fmt.Fprintf(&out, "func (c *Client) baseURL() string {\n\tif c.BaseURL == \"\" {\n\t\treturn New().BaseURL\n\t}\n\treturn c.BaseURL\n}\n\n")
+	fmt.Fprintf(&out, "func (c *Client) httpClient() *http.Client {\n\tif c.HTTP == nil {\n\t\treturn faas.GatewayClient()\n\t}\n\treturn c.HTTP\n}\n\n")
+	out.Write(methods.Bytes())
+	out.Write(decls.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated client: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+const faasImportPath = "github.com/danielmichaels/go-faas"
+
+var pathParam = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+type clientGen struct {
+	types   map[string]reflect.Type
+	order   []string
+	imports map[string]bool
+}
+
+func (g *clientGen) method(w io.Writer, route Route) error {
+	if !isExportedIdent(route.Name) {
+		return fmt.Errorf("name must be an exported Go identifier")
+	}
+	method := strings.ToUpper(route.Method)
+	if method == "" {
+		method = "GET"
+	}
+
+	// the URL expression, e.g. c.baseURL() + "/orders/" + url.PathEscape(id)
+	params := []string{"ctx context.Context"}
+	path := "c.baseURL()"
+	last := 0
+	for _, m := range pathParam.FindAllStringSubmatchIndex(route.Path, -1) {
+		name := route.Path[m[2]:m[3]]
+		params = append(params, name+" string")
+		path += " + " + strconv.Quote(route.Path[last:m[0]]) + " + url.PathEscape(" + name + ")"
+		last = m[1]
+		g.imports["net/url"] = true
+	}
+	if rest := route.Path[last:]; rest != "" {
+		path += " + " + strconv.Quote(rest)
+	}
+	body := "nil"
+	if route.Request != nil {
+		t, err := g.typeExpr(reflect.TypeOf(route.Request))
+		if err != nil {
+			return err
+		}
+		params = append(params, "in "+pointerTo(t))
+		body = "in"
+	}
+
+	fmt.Fprintf(w, "// %s calls %s %s.\n", route.Name, method, route.Path)
+	if route.Response == nil {
+		fmt.Fprintf(w, "func (c *Client) %s(%s) error {\n", route.Name, strings.Join(params, ", "))
+		fmt.Fprintf(w, "\treturn faas.DoJSON(ctx, c.httpClient(), %q, %s, %s, nil)\n}\n\n", method, path, body)
+		return nil
+	}
+	t, err := g.typeExpr(reflect.TypeOf(route.Response))
+	if err != nil {
+		return err
+	}
+	t = strings.TrimPrefix(t, "*")
+	fmt.Fprintf(w, "func (c *Client) %s(%s) (*%s, error) {\n", route.Name, strings.Join(params, ", "), t)
+	fmt.Fprintf(w, "\tvar out %s\n", t)
+	fmt.Fprintf(w, "\tif err := faas.DoJSON(ctx, c.httpClient(), %q, %s, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", method, path, body)
+	fmt.Fprintf(w, "\treturn &out, nil\n}\n\n")
+	return nil
+}
+
+func pointerTo(t string) string {
+	if strings.HasPrefix(t, "*") || strings.HasPrefix(t, "[]") || strings.HasPrefix(t, "map[") {
+		return t
+	}
+	return "*" + t
+}
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// typeExpr returns the Go expression for t, queueing named types for
+// declare.
+func (g *clientGen) typeExpr(t reflect.Type) (string, error) {
+	if t.Name() != "" && t.PkgPath() != "" {
+		if t.Implements(jsonMarshaler) || t.Implements(textMarshaler) ||
+			reflect.PointerTo(t).Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+			if t.PkgPath() == "main" {
+				return "", fmt.Errorf("type %s marshals itself and cannot be copied, move it to an importable package", t)
+			}
+			g.imports[t.PkgPath()] = true
+			return t.String(), nil
+		}
+		if prev, ok := g.types[t.Name()]; ok {
+			if prev != t {
+				return "", fmt.Errorf("types %s and %s share a name", prev, t)
+			}
+			return t.Name(), nil
+		}
+		g.types[t.Name()] = t
+		g.order = append(g.order, t.Name())
+		return t.Name(), nil
+	}
+	return g.underlying(t)
+}
+
+// underlying returns the type literal of t.
+func (g *clientGen) underlying(t reflect.Type) (string, error) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		e, err := g.typeExpr(t.Elem())
+		return "*" + e, err
+	case reflect.Slice:
+		e, err := g.typeExpr(t.Elem())
+		return "[]" + e, err
+	case reflect.Array:
+		e, err := g.typeExpr(t.Elem())
+		return fmt.Sprintf("[%d]%s", t.Len(), e), err
+	case reflect.Map:
+		k, err := g.typeExpr(t.Key())
+		if err != nil {
+			return "", err
+		}
+		e, err := g.typeExpr(t.Elem())
+		return "map[" + k + "]" + e, err
+	case reflect.Interface:
+		return "any", nil
+	case reflect.Struct:
+		var b strings.Builder
+		b.WriteString("struct {\n")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			ft, err := g.typeExpr(f.Type)
+			if err != nil {
+				return "", fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			if f.Anonymous {
+				b.WriteString("\t" + ft)
+			} else {
+				b.WriteString("\t" + f.Name + " " + ft)
+			}
+			if f.Tag != "" {
+				b.WriteString(" `" + string(f.Tag) + "`")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("}")
+		return b.String(), nil
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return "", fmt.Errorf("type %s cannot be sent as JSON", t)
+	}
+	// basic kinds, named or not
+	return t.Kind().String(), nil
+}
+
+// declare writes the named types collected by typeExpr, including those
+// found while writing others.
+func (g *clientGen) declare(w io.Writer) error {
+	for i := 0; i < len(g.order); i++ {
+		name := g.order[i]
+		def, err := g.underlying(g.types[name])
+		if err != nil {
+			return fmt.Errorf("type %s: %w", name, err)
+		}
+		fmt.Fprintf(w, "type %s %s\n\n", name, def)
+	}
+	return nil
+}
+
+func isExportedIdent(s string) bool {
+	for i, r := range s {
+		if !(unicode.IsLetter(r) || r == '_' || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return s != "" && unicode.IsUpper([]rune(s)[0])
+}
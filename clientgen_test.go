@@ -0,0 +1,84 @@
+package faas
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+	"time"
+)
+
+type genLine struct {
+	SKU string `json:"sku"`
+	Qty int    `json:"qty"`
+}
+
+type genStatus string
+
+type genOrder struct {
+	ID      string    `json:"id"`
+	Lines   []genLine `json:"lines"`
+	Status  genStatus `json:"status"`
+	Created time.Time `json:"created"`
+	Total   *Money    `json:"total,omitempty"`
+	secret  string
+}
+
+func TestGenerateClient(t *testing.T) {
+	var buf bytes.Buffer
+	err := GenerateClient(&buf, ClientOptions{Package: "orders", Function: "orders", Routes: []Route{
+		{Name: "CreateOrder", Method: "post", Path: "/orders", Request: genOrder{}, Response: genOrder{}},
+		{Name: "GetOrder", Path: "/orders/{id}", Response: &genOrder{}},
+		{Name: "DeleteLine", Method: "DELETE", Path: "/orders/{id}/lines/{sku}"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	if _, err := parser.ParseFile(token.NewFileSet(), "client.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+	for _, want := range []string{
+		`faas "github.com/danielmichaels/go-faas"`,
+		`func (c *Client) CreateOrder(ctx context.Context, in *genOrder) (*genOrder, error)`,
+		`c.baseURL()+"/orders/"+url.PathEscape(id)`,
+		`func (c *Client) DeleteLine(ctx context.Context, id string, sku string) error`,
+		"Created time.Time",
+		"Total   *faas.Money",
+		"type genLine struct",
+		"type genStatus string",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected generated code to contain %q\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, "secret") {
+		t.Error("expected unexported fields to be left out")
+	}
+}
+
+func TestGenerateClientErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		route Route
+	}{
+		{name: "unexported name", route: Route{Name: "create", Path: "/"}},
+		{name: "channel field", route: Route{Name: "Create", Path: "/", Request: struct{ C chan int }{}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := GenerateClient(&bytes.Buffer{}, ClientOptions{Package: "p", Function: "f", Routes: []Route{tc.route}})
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestFunctionURL(t *testing.T) {
+	t.Setenv("GATEWAY_URL", "http://127.0.0.1:8080/")
+	if got := FunctionURL("orders"); got != "http://127.0.0.1:8080/function/orders" {
+		t.Errorf("FunctionURL() = %q", got)
+	}
+}
@@ -0,0 +1,366 @@
+// Package cloudevents reads and writes CloudEvents over HTTP using both the
+// binary and structured content modes, as spoken by the OpenFaaS connectors
+// and Knative eventing.
+package cloudevents
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// SpecVersion is the CloudEvents specification version produced by this
+// package.
+const SpecVersion = "1.0"
+
+// ContentType is the media type of a structured mode event.
+const ContentType = "application/cloudevents+json"
+
+// Event is a CloudEvent. Data holds the raw payload, which is JSON when
+// DataContentType is empty or a JSON media type.
+type Event struct {
+	SpecVersion     string
+	ID              string
+	Source          string
+	Type            string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	DataSchema      string
+	Data            []byte
+	// Extensions holds any non-standard attributes, keyed by lower case name.
+	// Names must be lower case letters and digits and must not be those of
+	// the standard attributes.
+	Extensions map[string]string
+}
+
+// New returns an Event with the spec version and time set.
+func New(id, source, eventType string) Event {
+	return Event{
+		SpecVersion: SpecVersion,
+		ID:          id,
+		Source:      source,
+		Type:        eventType,
+		Time:        time.Now().UTC(),
+	}
+}
+
+// Validate checks the required attributes are present.
+func (e Event) Validate() error {
+	var missing []string
+	if e.SpecVersion == "" {
+		missing = append(missing, "specversion")
+	}
+	if e.ID == "" {
+		missing = append(missing, "id")
+	}
+	if e.Source == "" {
+		missing = append(missing, "source")
+	}
+	if e.Type == "" {
+		missing = append(missing, "type")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("event is missing required attributes: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// SetData marshals v to JSON and stores it as the event payload.
+func (e *Event) SetData(v any) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.Data = js
+	e.DataContentType = "application/json"
+	return nil
+}
+
+// DataAs unmarshals a JSON payload into dst.
+func (e Event) DataAs(dst any) error {
+	if !isJSON(e.DataContentType) {
+		return fmt.Errorf("event data is %s, not JSON", e.DataContentType)
+	}
+	return json.Unmarshal(e.Data, dst)
+}
+
+// Read parses a CloudEvent from r, detecting structured mode from the
+// Content-Type and falling back to binary mode ce- headers. The body is
+// limited as by faas.ReadBody.
+func Read(r *http.Request, opts ...faas.BodyOptions) (Event, error) {
+	body, err := faas.ReadBody(nil, r, opts...)
+	if err != nil {
+		return Event{}, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var evt Event
+	if mediaType == ContentType {
+		evt, err = readStructured(body)
+	} else {
+		evt = readBinary(r.Header, body)
+	}
+	if err != nil {
+		return Event{}, err
+	}
+	return evt, evt.Validate()
+}
+
+func readBinary(h http.Header, body []byte) Event {
+	get := func(name string) string { return decodeHeaderValue(h.Get(name)) }
+	evt := Event{
+		SpecVersion:     get("Ce-Specversion"),
+		ID:              get("Ce-Id"),
+		Source:          get("Ce-Source"),
+		Type:            get("Ce-Type"),
+		Subject:         get("Ce-Subject"),
+		DataSchema:      get("Ce-Dataschema"),
+		DataContentType: h.Get("Content-Type"),
+		Data:            body,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, get("Ce-Time")); err == nil {
+		evt.Time = t
+	}
+	for key, values := range h {
+		name := strings.ToLower(key)
+		if !strings.HasPrefix(name, "ce-") || len(values) == 0 {
+			continue
+		}
+		name = strings.TrimPrefix(name, "ce-")
+		if isStandard(name) {
+			continue
+		}
+		if evt.Extensions == nil {
+			evt.Extensions = make(map[string]string)
+		}
+		evt.Extensions[name] = decodeHeaderValue(values[0])
+	}
+	return evt
+}
+
+func readStructured(body []byte) (Event, error) {
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(body, &attrs); err != nil {
+		return Event{}, fmt.Errorf("body contains badly-formed JSON: %w", err)
+	}
+
+	str := func(name string) string {
+		var s string
+		_ = json.Unmarshal(attrs[name], &s)
+		return s
+	}
+	evt := Event{
+		SpecVersion:     str("specversion"),
+		ID:              str("id"),
+		Source:          str("source"),
+		Type:            str("type"),
+		Subject:         str("subject"),
+		DataContentType: str("datacontenttype"),
+		DataSchema:      str("dataschema"),
+	}
+	if t, err := time.Parse(time.RFC3339Nano, str("time")); err == nil {
+		evt.Time = t
+	}
+
+	if b64 := str("data_base64"); b64 != "" {
+		data, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return Event{}, errors.New("event contains invalid data_base64")
+		}
+		evt.Data = data
+	} else if raw, ok := attrs["data"]; ok {
+		if isJSON(evt.DataContentType) {
+			evt.Data = raw
+		} else {
+			// non JSON payloads are carried as a JSON string
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return Event{}, errors.New("event data must be a string for non JSON content types")
+			}
+			evt.Data = []byte(s)
+		}
+	}
+
+	for name, raw := range attrs {
+		if isStandard(name) || name == "data" || name == "data_base64" {
+			continue
+		}
+		if evt.Extensions == nil {
+			evt.Extensions = make(map[string]string)
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			s = string(raw)
+		}
+		evt.Extensions[name] = s
+	}
+	return evt, nil
+}
+
+// WriteBinary writes evt in binary mode: attributes as ce- headers and the
+// payload as the body.
+func WriteBinary(w http.ResponseWriter, status int, evt Event) error {
+	if err := evt.Validate(); err != nil {
+		return err
+	}
+	if err := validateExtensions(evt.Extensions); err != nil {
+		return err
+	}
+	h := w.Header()
+	set := func(name, value string) { h.Set(name, encodeHeaderValue(value)) }
+	set("Ce-Specversion", evt.SpecVersion)
+	set("Ce-Id", evt.ID)
+	set("Ce-Source", evt.Source)
+	set("Ce-Type", evt.Type)
+	if evt.Subject != "" {
+		set("Ce-Subject", evt.Subject)
+	}
+	if !evt.Time.IsZero() {
+		set("Ce-Time", evt.Time.Format(time.RFC3339Nano))
+	}
+	if evt.DataSchema != "" {
+		set("Ce-Dataschema", evt.DataSchema)
+	}
+	for name, value := range evt.Extensions {
+		set("Ce-"+name, value)
+	}
+	if evt.DataContentType != "" {
+		h.Set("Content-Type", evt.DataContentType)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(evt.Data)
+	return nil
+}
+
+// WriteStructured writes evt in structured mode as a single
+// application/cloudevents+json document.
+func WriteStructured(w http.ResponseWriter, status int, evt Event) error {
+	js, err := Marshal(evt)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(js)
+	return nil
+}
+
+// Marshal encodes evt in the structured JSON format.
+func Marshal(evt Event) ([]byte, error) {
+	if err := evt.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateExtensions(evt.Extensions); err != nil {
+		return nil, err
+	}
+	doc := map[string]any{
+		"specversion": evt.SpecVersion,
+		"id":          evt.ID,
+		"source":      evt.Source,
+		"type":        evt.Type,
+	}
+	for name, value := range evt.Extensions {
+		doc[name] = value
+	}
+	if evt.Subject != "" {
+		doc["subject"] = evt.Subject
+	}
+	if !evt.Time.IsZero() {
+		doc["time"] = evt.Time.Format(time.RFC3339Nano)
+	}
+	if evt.DataSchema != "" {
+		doc["dataschema"] = evt.DataSchema
+	}
+	if evt.DataContentType != "" {
+		doc["datacontenttype"] = evt.DataContentType
+	}
+	if len(evt.Data) > 0 {
+		switch {
+		case isJSON(evt.DataContentType) && json.Valid(evt.Data):
+			doc["data"] = json.RawMessage(evt.Data)
+		case strings.HasPrefix(evt.DataContentType, "text/"):
+			doc["data"] = string(evt.Data)
+		default:
+			doc["data_base64"] = base64.StdEncoding.EncodeToString(evt.Data)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func isStandard(name string) bool {
+	switch name {
+	case "specversion", "id", "source", "type", "subject", "time", "datacontenttype", "dataschema":
+		return true
+	}
+	return false
+}
+
+// validateExtensions rejects extension names which would overwrite the
+// standard attributes or are not lower case letters and digits, as the
+// specification requires.
+func validateExtensions(extensions map[string]string) error {
+	for name := range extensions {
+		if isStandard(name) || name == "data" || name == "data_base64" {
+			return fmt.Errorf("extension %q is a reserved attribute name", name)
+		}
+		if name == "" || strings.TrimFunc(name, func(r rune) bool { return r >= 'a' && r <= 'z' || r >= '0' && r <= '9' }) != "" {
+			return fmt.Errorf("extension %q must only contain lower case letters and digits", name)
+		}
+	}
+	return nil
+}
+
+// encodeHeaderValue percent-encodes a binary mode attribute value as the
+// HTTP binding requires: spaces, double quotes, percent signs and any byte
+// outside printable ASCII.
+func encodeHeaderValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c > '~' || c == '"' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// decodeHeaderValue reverses encodeHeaderValue. Values with invalid escapes
+// are returned as they are.
+func decodeHeaderValue(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	decoded, err := url.PathUnescape(s)
+	if err != nil || !utf8.ValidString(decoded) {
+		return s
+	}
+	return decoded
+}
+
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
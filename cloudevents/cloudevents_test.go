@@ -0,0 +1,167 @@
+package cloudevents
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		body      string
+		expectErr bool
+	}{
+		{
+			name: "binary mode",
+			headers: map[string]string{
+				"Content-Type":   "application/json",
+				"Ce-Specversion": "1.0",
+				"Ce-Id":          "1",
+				"Ce-Source":      "/orders",
+				"Ce-Type":        "order.created",
+				"Ce-Tenant":      "acme",
+			},
+			body: `{"order":42}`,
+		},
+		{
+			name:    "structured mode",
+			headers: map[string]string{"Content-Type": "application/cloudevents+json; charset=utf-8"},
+			body: `{"specversion":"1.0","id":"1","source":"/orders","type":"order.created",` +
+				`"tenant":"acme","datacontenttype":"application/json","data":{"order":42}}`,
+		},
+		{
+			name:      "missing attributes",
+			headers:   map[string]string{"Content-Type": "application/json", "Ce-Id": "1"},
+			body:      `{}`,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			evt, err := Read(req)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected an error but didn't get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var data struct {
+				Order int `json:"order"`
+			}
+			if err := evt.DataAs(&data); err != nil {
+				t.Fatal(err)
+			}
+			if evt.Type != "order.created" || data.Order != 42 || evt.Extensions["tenant"] != "acme" {
+				t.Errorf("unexpected event %+v", evt)
+			}
+		})
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Ce-Specversion", "1.0")
+	req.Header.Set("Ce-Id", "1")
+	req.Header.Set("Ce-Source", "/orders")
+	req.Header.Set("Ce-Type", "order.created")
+	var appErr *faas.AppError
+	if _, err := Read(req, faas.BodyOptions{MaxBytes: 4}); !errors.As(err, &appErr) || appErr.Code != faas.CodeTooLarge {
+		t.Errorf("expected CodeTooLarge over the configured limit, got %v", err)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	evt := New("1", "/orders", "order.created")
+	evt.Subject = "Zoë's \"order\" 100%"
+	evt.Extensions = map[string]string{"tenant": "acme"}
+	if err := evt.SetData(map[string]int{"order": 42}); err != nil {
+		t.Fatal(err)
+	}
+
+	writers := map[string]func(http.ResponseWriter, int, Event) error{
+		"binary":     WriteBinary,
+		"structured": WriteStructured,
+	}
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			if err := write(resp, http.StatusOK, evt); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(resp.Body.Bytes()))
+			for k, v := range resp.Header() {
+				req.Header[k] = v
+			}
+			got, err := Read(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != evt.ID || string(got.Data) != `{"order":42}` || got.Extensions["tenant"] != "acme" {
+				t.Errorf("unexpected event %+v", got)
+			}
+			if got.Subject != evt.Subject {
+				t.Errorf("subject = %q, want %q", got.Subject, evt.Subject)
+			}
+			if !got.Time.Equal(evt.Time) {
+				t.Errorf("expected time %v, got %v", evt.Time, got.Time)
+			}
+		})
+	}
+}
+
+func TestBinaryHeaderEncoding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	req.Header.Set("Ce-Specversion", "1.0")
+	req.Header.Set("Ce-Id", "1")
+	req.Header.Set("Ce-Source", "/orders")
+	req.Header.Set("Ce-Type", "order.created")
+	req.Header.Set("Ce-Subject", "Euro%20%E2%82%AC")
+	req.Header.Set("Ce-Note", "100%")
+	evt, err := Read(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.Subject != "Euro €" || evt.Extensions["note"] != "100%" {
+		t.Errorf("subject %q, note %q", evt.Subject, evt.Extensions["note"])
+	}
+
+	w := httptest.NewRecorder()
+	evt.Extensions = nil
+	if err := WriteBinary(w, http.StatusOK, evt); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Header().Get("Ce-Subject"); got != "Euro%20%E2%82%AC" {
+		t.Errorf("Ce-Subject = %q", got)
+	}
+}
+
+func TestReservedExtensions(t *testing.T) {
+	for _, name := range []string{"id", "type", "data", "data_base64", "Tenant", "tenant-id", ""} {
+		evt := New("1", "/orders", "order.created")
+		evt.Extensions = map[string]string{name: "x"}
+		if _, err := Marshal(evt); err == nil {
+			t.Errorf("Marshal accepted the extension %q", name)
+		}
+		if err := WriteBinary(httptest.NewRecorder(), http.StatusOK, evt); err == nil {
+			t.Errorf("WriteBinary accepted the extension %q", name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package faas
+
+import (
+	"encoding/json"
+	"io"
+	"sync/atomic"
+)
+
+// Codec encodes and decodes JSON for ReadJSON, ReadNDJSON, DecodeStream,
+// WriteJSON, WriteJSONStream, error responses and JSONVersionCodec. The
+// default, StdCodec, uses encoding/json; SetCodec swaps in a faster
+// implementation such as sonic or encoding/json/v2 with a small adapter.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	// NewEncoder returns an encoder writing each value passed to Encode to
+	// w.
+	NewEncoder(w io.Writer) Encoder
+	// NewDecoder returns a decoder of the JSON values read from r. When
+	// strict is set, object fields without a matching struct field are an
+	// error.
+	NewDecoder(r io.Reader, strict bool) Decoder
+}
+
+// Encoder writes JSON values, see Codec.
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads JSON values, see Codec. Decode returns io.EOF once the
+// input is exhausted.
+type Decoder interface {
+	Decode(v any) error
+}
+
+// StdCodec is the Codec backed by encoding/json.
+var StdCodec Codec = stdCodec{}
+
+type stdCodec struct{}
+
+func (stdCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (stdCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (stdCodec) NewEncoder(w io.Writer) Encoder     { return json.NewEncoder(w) }
+
+func (stdCodec) NewDecoder(r io.Reader, strict bool) Decoder {
+	dec := json.NewDecoder(r)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec
+}
+
+// codecBox wraps the current codec so implementations of different types
+// can be stored in the same atomic.Pointer.
+type codecBox struct {
+	Codec
+}
+
+var currentCodec atomic.Pointer[codecBox]
+
+// SetCodec makes c the codec of the package's JSON helpers. A nil c
+// restores StdCodec. Call it at startup, before serving requests.
+//
+// ReadJSON turns the syntax and type errors of encoding/json into friendly
+// messages; errors of other codecs are returned as they are, so prefer an
+// adapter which returns the encoding/json error types.
+func SetCodec(c Codec) {
+	if c == nil {
+		c = StdCodec
+	}
+	currentCodec.Store(&codecBox{c})
+}
+
+// jsonCodec returns the codec set with SetCodec.
+func jsonCodec() Codec {
+	if b := currentCodec.Load(); b != nil {
+		return b.Codec
+	}
+	return StdCodec
+}
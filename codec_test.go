@@ -0,0 +1,95 @@
+package faas
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// upperCodec wraps StdCodec, upper casing encoded output and counting
+// decodes, to check which helpers go through the codec.
+type upperCodec struct {
+	decodes *int
+}
+
+func (c upperCodec) Marshal(v any) ([]byte, error) {
+	js, err := json.Marshal(v)
+	return []byte(strings.ToUpper(string(js))), err
+}
+
+func (c upperCodec) Unmarshal(data []byte, v any) error {
+	*c.decodes++
+	return json.Unmarshal(data, v)
+}
+
+func (c upperCodec) NewEncoder(w io.Writer) Encoder {
+	return encoderFunc(func(v any) error {
+		js, err := c.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(js)
+		return err
+	})
+}
+
+func (c upperCodec) NewDecoder(r io.Reader, strict bool) Decoder {
+	*c.decodes++
+	return StdCodec.NewDecoder(r, strict)
+}
+
+type encoderFunc func(any) error
+
+func (f encoderFunc) Encode(v any) error { return f(v) }
+
+func TestSetCodec(t *testing.T) {
+	var decodes int
+	SetCodec(upperCodec{decodes: &decodes})
+	t.Cleanup(func() { SetCodec(nil) })
+
+	w := httptest.NewRecorder()
+	_ = WriteJSON(w, http.StatusOK, Map{"a": "b"}, nil)
+	if got := w.Body.String(); got != `{"A":"B"}` {
+		t.Errorf("WriteJSON() body = %s", got)
+	}
+	w = httptest.NewRecorder()
+	_ = WriteJSONStream(w, http.StatusOK, Map{"a": "b"}, nil)
+	if got := w.Body.String(); got != `{"A":"B"}` {
+		t.Errorf("WriteJSONStream() body = %s", got)
+	}
+
+	var dst struct{ Name string }
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"x"}`))
+	if err := ReadJSON(httptest.NewRecorder(), r, &dst); err != nil || dst.Name != "x" {
+		t.Errorf("ReadJSON() = %+v, %v", dst, err)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"x","extra":1}`))
+	if err := ReadJSON(httptest.NewRecorder(), r, &dst); err == nil {
+		t.Error("ReadJSON() accepted an unknown field, the decoder must be strict")
+	}
+	if decodes != 2 {
+		t.Errorf("codec decoded %d bodies, want 2", decodes)
+	}
+
+	decodes = 0
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{\"name\":\"x\"}\n{\"name\":\"y\"}\n"))
+	if err := ReadNDJSON(r, func(struct{ Name string }) error { return nil }); err != nil {
+		t.Errorf("ReadNDJSON() = %v", err)
+	}
+	if err := DecodeStream(strings.NewReader(`[{"name":"x"},{"name":"y"}]`), func(struct{ Name string }) error { return nil }); err != nil {
+		t.Errorf("DecodeStream() = %v", err)
+	}
+	if decodes != 4 {
+		t.Errorf("codec decoded %d records, want 4", decodes)
+	}
+
+	SetCodec(nil)
+	w = httptest.NewRecorder()
+	_ = WriteJSON(w, http.StatusOK, Map{"a": "b"}, nil)
+	if got := w.Body.String(); got != `{"a":"b"}` {
+		t.Errorf("WriteJSON() after reset = %s", got)
+	}
+}
@@ -0,0 +1,123 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// CopyOptions configure Copy.
+type CopyOptions struct {
+	// BytesPerSecond limits the throughput, e.g. to leave bandwidth for
+	// other requests. Zero means no limit.
+	BytesPerSecond int64
+	// Progress is called with the number of bytes copied so far after
+	// writes and once more when the copy ends.
+	Progress func(written int64)
+	// ProgressInterval is the minimum time between Progress calls. Zero
+	// calls it after every write.
+	ProgressInterval time.Duration
+	// Flush flushes dst after every write when it is an http.ResponseWriter
+	// or has a Flush method, so streamed data reaches the client as it is
+	// produced rather than when a buffer fills.
+	Flush bool
+	// BufferSize is the size of the copy buffer. Defaults to 32KB.
+	BufferSize int
+}
+
+// Copy copies from src to dst until EOF, an error or ctx is done, and
+// returns the number of bytes written. Writes are never buffered beyond
+// BufferSize, so a slow reader of dst slows reading from src.
+func Copy(ctx context.Context, dst io.Writer, src io.Reader, opts CopyOptions) (int64, error) {
+	return copyContext(ctx, dst, src, opts)
+}
+func copyContext(ctx context.Context, dst io.Writer, src io.Reader, opts CopyOptions) (written int64, err error) {
+	size := opts.BufferSize
+	if size <= 0 {
+		size = 32 << 10
+	}
+	if opts.BytesPerSecond > 0 && int64(size) > opts.BytesPerSecond {
+		// smaller chunks keep the rate smooth
+		size = int(opts.BytesPerSecond)
+	}
+	buf := make([]byte, size)
+	flush := flusher(dst, opts.Flush)
+
+	start := time.Now()
+	var reported time.Time
+	if opts.Progress != nil {
+		defer func() { opts.Progress(written) }()
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			w, werr := dst.Write(buf[:n])
+			written += int64(w)
+			if werr == nil && w < n {
+				werr = io.ErrShortWrite
+			}
+			if werr != nil {
+				return written, werr
+			}
+			if flush != nil {
+				if err := flush(); err != nil {
+					return written, err
+				}
+			}
+			if opts.Progress != nil && time.Since(reported) >= opts.ProgressInterval {
+				opts.Progress(written)
+				reported = time.Now()
+			}
+			if opts.BytesPerSecond > 0 {
+				due := start.Add(time.Duration(float64(written) / float64(opts.BytesPerSecond) * float64(time.Second)))
+				if err := sleepContext(ctx, time.Until(due)); err != nil {
+					return written, err
+				}
+			}
+		}
+		if errors.Is(rerr, io.EOF) {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+func flusher(w io.Writer, enabled bool) func() error {
+	if !enabled {
+		return nil
+	}
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rc := http.NewResponseController(rw)
+		return func() error {
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			return nil
+		}
+	}
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush
+	}
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,58 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCopy(t *testing.T) {
+	src := strings.Repeat("x", 100_000)
+	var progress []int64
+	var dst bytes.Buffer
+	n, err := Copy(context.Background(), &dst, strings.NewReader(src), CopyOptions{
+		BufferSize: 10_000,
+		Progress:   func(written int64) { progress = append(progress, written) },
+	})
+	if err != nil || n != int64(len(src)) || dst.String() != src {
+		t.Fatalf("Copy() = %d, %v", n, err)
+	}
+	if len(progress) != 11 || progress[0] != 10_000 || progress[len(progress)-1] != n {
+		t.Errorf("unexpected progress calls %v", progress)
+	}
+}
+
+func TestCopyRate(t *testing.T) {
+	start := time.Now()
+	n, err := Copy(context.Background(), io.Discard, strings.NewReader(strings.Repeat("x", 3000)), CopyOptions{BytesPerSecond: 20_000})
+	if err != nil || n != 3000 {
+		t.Fatalf("Copy() = %d, %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the copy to be rate limited, took %s", elapsed)
+	}
+}
+
+func TestCopyCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	n, err := Copy(ctx, io.Discard, strings.NewReader(strings.Repeat("x", 10_000)), CopyOptions{BytesPerSecond: 1000})
+	if !errors.Is(err, context.DeadlineExceeded) || n == 0 || n >= 10_000 {
+		t.Errorf("expected a partial copy cut short by the context, got %d, %v", n, err)
+	}
+}
+
+func TestCopyFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if _, err := Copy(context.Background(), rec, strings.NewReader("chunk"), CopyOptions{Flush: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+}
@@ -0,0 +1,142 @@
+package faas
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"os"
+	"time"
+)
+
+// CronInvocation describes a call made by the OpenFaaS cron-connector.
+type CronInvocation struct {
+	// Topic is the topic annotation the function subscribed with, normally
+	// "cron-function".
+	Topic string
+	// Schedule is the cron expression, taken from the X-Cron-Schedule
+	// header when the connector sends it or the CRON_SCHEDULE environment
+	// variable, which can be set to match the schedule annotation.
+	Schedule string
+}
+
+// ReadCronInvocation reports whether r was sent by the cron-connector,
+// identified by its X-Connector header.
+func ReadCronInvocation(r *http.Request) (CronInvocation, bool) {
+	if r.Header.Get("X-Connector") != "cron-connector" {
+		return CronInvocation{}, false
+	}
+	inv := CronInvocation{Topic: r.Header.Get("X-Topic"), Schedule: r.Header.Get("X-Cron-Schedule")}
+	if inv.Schedule == "" {
+		inv.Schedule = os.Getenv("CRON_SCHEDULE")
+	}
+	return inv, true
+}
+
+// CronGuard is middleware for periodic jobs written as functions. It delays
+// each run by a random jitter, so many jobs on the same schedule do not hit
+// their dependencies at once, and takes a lock in Store so a run is skipped
+// while the previous one is still going, even on another replica. Stores
+// implementing AtomicKV and CompareDeleteKV give a strict guarantee, others
+// are best effort.
+type CronGuard struct {
+	Store KV
+	// Name identifies the job's lock. Defaults to the function_name
+	// environment variable, runs fail with 500 when neither is set so
+	// unrelated jobs never share a lock.
+	Name string
+	// LockTTL bounds how long a crashed run can block the next ones. It
+	// should exceed the longest run. Defaults to 10 minutes.
+	LockTTL time.Duration
+	// Jitter is the maximum random delay before a run starts.
+	Jitter time.Duration
+	// Dashboard records the runs of the job. Defaults to DefaultDashboard.
+	Dashboard *Dashboard
+}
+
+// Wrap returns next guarded against overlapping runs. Skipped runs are
+// answered with 200 and {"status": "skipped"} so the connector does not
+// report them as failures.
+func (g *CronGuard) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if g.Jitter > 0 {
+			timer := time.NewTimer(time.Duration(rand.Int63n(int64(g.Jitter))))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				_ = writeError(w, E(CodeTimeout, "cancelled during jitter delay", ctx.Err()))
+				return
+			case <-timer.C:
+			}
+		}
+
+		name := g.Name
+		if name == "" {
+			name = os.Getenv("function_name")
+		}
+		if name == "" {
+			_ = writeError(w, E(CodeInternal, "cron guard has no job name", nil))
+			return
+		}
+		ttl := g.LockTTL
+		if ttl <= 0 {
+			ttl = 10 * time.Minute
+		}
+		key := "faas:cron:" + name
+		owner := []byte(randomHex(16))
+		locked, err := setNX(ctx, g.Store, key, owner, ttl)
+		if err != nil {
+			_ = writeError(w, E(CodeUnavailable, "cron lock unavailable", err))
+			return
+		}
+		dashboard := g.Dashboard
+		if dashboard == nil {
+			dashboard = DefaultDashboard
+		}
+		inv, _ := ReadCronInvocation(r)
+		if !locked {
+			LoggerFromContext(ctx).Info("cron run skipped, previous run still in progress", "job", name)
+			dashboard.job(name, func(j *CronStatus) { j.Skipped++ })
+			_ = writeJSON(w, http.StatusOK, Map{"status": "skipped"}, nil)
+			return
+		}
+		defer g.unlock(context.WithoutCancel(ctx), key, owner)
+
+		start := time.Now()
+		dashboard.job(name, func(j *CronStatus) {
+			j.Running, j.LastRun = true, &start
+			if inv.Schedule != "" {
+				j.Schedule = inv.Schedule
+				if sched, err := ParseCron(inv.Schedule); err == nil {
+					j.NextRun = nil
+					if next := sched.NextRun(start); !next.IsZero() {
+						j.NextRun = &next
+					}
+				}
+			}
+		})
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			dashboard.job(name, func(j *CronStatus) {
+				j.Running = false
+				j.Runs++
+				j.LastStatus, j.LastDuration = status, Duration(time.Since(start))
+				if status >= 500 {
+					j.Failed++
+				}
+			})
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// unlock releases the lock unless it expired and was taken by another run.
+func (g *CronGuard) unlock(ctx context.Context, key string, owner []byte) {
+	if _, err := deleteIfEqual(ctx, g.Store, key, owner); err != nil {
+		LoggerFromContext(ctx).Warn("cron unlock failed", "key", key, "error", err)
+	}
+}
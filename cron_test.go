@@ -0,0 +1,97 @@
+package faas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadCronInvocation(t *testing.T) {
+	t.Setenv("CRON_SCHEDULE", "*/5 * * * *")
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		ok       bool
+		schedule string
+	}{
+		{name: "connector", headers: map[string]string{"X-Connector": "cron-connector", "X-Topic": "cron-function"}, ok: true, schedule: "*/5 * * * *"},
+		{name: "schedule header", headers: map[string]string{"X-Connector": "cron-connector", "X-Cron-Schedule": "@hourly"}, ok: true, schedule: "@hourly"},
+		{name: "other caller", headers: map[string]string{"X-Topic": "orders"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			inv, ok := ReadCronInvocation(req)
+			if ok != tc.ok || inv.Schedule != tc.schedule {
+				t.Errorf("ReadCronInvocation() = %+v, %v", inv, ok)
+			}
+		})
+	}
+}
+
+func TestCronGuard(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	runs := 0
+	g := &CronGuard{Store: NewMemoryKV(), Name: "report", Jitter: time.Millisecond}
+	h := g.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		if runs == 1 {
+			close(started)
+			<-release
+		}
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "skipped") {
+		t.Errorf("expected an overlapping run to be skipped, got %d %s", rec.Code, rec.Body)
+	}
+
+	close(release)
+	wg.Wait()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if runs != 2 || strings.Contains(rec.Body.String(), "skipped") {
+		t.Errorf("expected the lock to be released after the first run, runs = %d", runs)
+	}
+}
+
+func TestCronGuardKeepsStolenLock(t *testing.T) {
+	store := NewMemoryKV()
+	g := &CronGuard{Store: store, Name: "report"}
+	h := g.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the lock expired during a long run and another run took it
+		_ = store.Set(r.Context(), "faas:cron:report", []byte("other"), time.Minute)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	if v, err := store.Get(context.Background(), "faas:cron:report"); err != nil || string(v) != "other" {
+		t.Errorf("lock = %q, %v, want the other run's lock kept", v, err)
+	}
+}
+
+func TestCronGuardRequiresName(t *testing.T) {
+	t.Setenv("function_name", "")
+	runs := 0
+	h := (&CronGuard{Store: NewMemoryKV()}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { runs++ }))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusInternalServerError || runs != 0 {
+		t.Errorf("got %d with %d runs, want 500 without running", rec.Code, runs)
+	}
+}
@@ -0,0 +1,102 @@
+package faas
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronNextRun(t *testing.T) {
+	// a Wednesday
+	after := time.Date(2024, 1, 31, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * * *", "2024-01-31T10:08:00Z"},
+		{"*/15 * * * *", "2024-01-31T10:15:00Z"},
+		{"5/20 * * * *", "2024-01-31T10:25:00Z"},
+		{"0 9-17 * * MON-FRI", "2024-01-31T11:00:00Z"},
+		{"30 8 * * sat,sun", "2024-02-03T08:30:00Z"},
+		{"0 0 29 2 *", "2024-02-29T00:00:00Z"},
+		{"0 0 31 * *", "2024-03-31T00:00:00Z"},
+		{"0 12 1 * 5", "2024-02-01T12:00:00Z"},
+		{"0 0 * * 7", "2024-02-04T00:00:00Z"},
+		{"@yearly", "2025-01-01T00:00:00Z"},
+		{"@hourly", "2024-01-31T11:00:00Z"},
+		{"0 0 1 JAN,jul ?", "2024-07-01T00:00:00Z"},
+		{"CRON_TZ=Asia/Kolkata 0 * * * *", "2024-01-31T10:30:00Z"},
+		{"TZ=America/New_York 0 9 * * *", "2024-01-31T14:00:00Z"},
+		{"0 0 30 2 *", "0001-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.NextRun(after).UTC().Format(time.RFC3339); got != tt.want {
+			t.Errorf("%q NextRun() = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCronDaylightSaving(t *testing.T) {
+	s, err := ParseCron("CRON_TZ=Europe/London 30 1 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Location().String() != "Europe/London" {
+		t.Errorf("Location() = %v", s.Location())
+	}
+	// 01:30 does not exist on 31 March 2024, so it runs on 1 April
+	next := s.NextRun(time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC))
+	if want := "2024-04-01T01:30:00+01:00"; next.Format(time.RFC3339) != want {
+		t.Errorf("NextRun() = %s, want %s", next.Format(time.RFC3339), want)
+	}
+	// 01:30 happens twice on 27 October 2024
+	first := s.NextRun(time.Date(2024, 10, 26, 12, 0, 0, 0, time.UTC))
+	second := s.NextRun(first)
+	if first.Format(time.RFC3339) != "2024-10-27T01:30:00+01:00" || second.Format(time.RFC3339) != "2024-10-27T01:30:00Z" {
+		t.Errorf("NextRun() = %s then %s", first.Format(time.RFC3339), second.Format(time.RFC3339))
+	}
+
+	local, err := ParseCronIn("0 9 * * *", time.FixedZone("X", 3600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := local.NextRun(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).UTC().Hour(); got != 8 {
+		t.Errorf("ParseCronIn NextRun hour = %d", got)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * *", "expected 5 fields, got 4"},
+		{"60 * * * *", "minute 60 out of range 0-59"},
+		{"* 5-2 * * *", `invalid hour range "5-2"`},
+		{"*/0 * * * *", `invalid minute step "0"`},
+		{"* * * foo *", `invalid month "foo"`},
+		{"? * * * *", `invalid minute "?"`},
+		{"CRON_TZ=Mars/Base * * * * *", `unknown time zone "Mars/Base"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseCron(tt.expr)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("ParseCron(%q) err = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+
+	type job struct {
+		Schedule string `json:"schedule" validate:"cron"`
+	}
+	if err := Validate(job{Schedule: "@daily"}); err != nil {
+		t.Errorf("valid schedule: %v", err)
+	}
+	if err := Validate(job{Schedule: "61 * * * *"}); err == nil || !strings.Contains(err.Error(), "must be a valid cron expression") {
+		t.Errorf("invalid schedule err = %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package faas
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ContextKey is a typed context key. Keys are compared by identity, so two
+// keys created with the same name never collide, and values can only be
+// stored and read with the key's type.
+type ContextKey[T any] struct {
+	name string
+}
+
+// NewContextKey returns a new key. The name is only used by String.
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+func (k *ContextKey[T]) String() string {
+	return "faas context key " + k.name
+}
+
+// Well-known keys shared by the middleware in this package and any written
+// by function authors.
+var (
+	// RequestIDKey holds the request ID set by RequestLogger.
+	RequestIDKey = NewContextKey[string]("request_id")
+	// RealIPKey holds the client address set by RequestLogger, see ClientIP.
+	RealIPKey = NewContextKey[string]("real_ip")
+	// LoggerKey holds the request scoped logger, see LoggerFromContext.
+	LoggerKey = NewContextKey[*slog.Logger]("logger")
+	// PriorityKey holds the request priority set by RequestPriority.
+	PriorityKey = NewContextKey[Priority]("priority")
+	// LanguageKey holds the response language set by Localize.
+	LanguageKey = NewContextKey[string]("language")
+)
+
+// WithValue returns a copy of ctx with v stored under key.
+func WithValue[T any](ctx context.Context, key *ContextKey[T], v T) context.Context {
+	return context.WithValue(ctx, key, v)
+}
+
+// FromContext returns the value stored under key and whether it was set.
+func FromContext[T any](ctx context.Context, key *ContextKey[T]) (T, bool) {
+	v, ok := ctx.Value(key).(T)
+	return v, ok
+}
@@ -0,0 +1,29 @@
+package faas
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextValues(t *testing.T) {
+	tenantKey := NewContextKey[map[string]any]("tenant")
+	ctx := WithValue(context.Background(), tenantKey, map[string]any{"id": "t_1"})
+	ctx = WithValue(ctx, RequestIDKey, "req-1")
+
+	tenant, ok := FromContext(ctx, tenantKey)
+	if !ok || tenant["id"] != "t_1" {
+		t.Errorf("unexpected tenant %v %v", tenant, ok)
+	}
+	if id := RequestID(ctx); id != "req-1" {
+		t.Errorf("RequestID() = %q", id)
+	}
+
+	// keys with the same name and type must not collide
+	other := NewContextKey[string]("request_id")
+	if v, ok := FromContext(ctx, other); ok {
+		t.Errorf("expected no value for a distinct key, got %q", v)
+	}
+	if _, ok := FromContext(ctx, RealIPKey); ok {
+		t.Error("expected unset keys to report false")
+	}
+}
@@ -0,0 +1,91 @@
+package faas
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCursor is returned when a cursor is malformed or its
+	// signature does not match.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrCursorExpired is returned when a cursor is past its expiry.
+	ErrCursorExpired = errors.New("cursor expired")
+)
+
+type cursorPayload struct {
+	Value   json.RawMessage `json:"v"`
+	Expires int64           `json:"e,omitempty"`
+}
+
+// EncodeCursor serialises v into an opaque, URL safe token signed with
+// secret. A ttl of zero creates a cursor that never expires.
+func EncodeCursor(secret []byte, v any, ttl time.Duration) (string, error) {
+	return encodeCursor(secret, v, ttl)
+}
+func encodeCursor(secret []byte, v any, ttl time.Duration) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("cursor secret must not be empty")
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	payload := cursorPayload{Value: js}
+	if ttl > 0 {
+		payload.Expires = time.Now().Add(ttl).UnixMilli()
+	}
+	byt, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(byt)
+	sig := base64.RawURLEncoding.EncodeToString(signCursor(secret, body))
+	return body + "." + sig, nil
+}
+
+// DecodeCursor verifies a token created by EncodeCursor and unmarshals its
+// value into dst.
+func DecodeCursor(secret []byte, token string, dst any) error {
+	return decodeCursor(secret, token, dst)
+}
+func decodeCursor(secret []byte, token string, dst any) error {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	want, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	// compare signatures before looking at the payload so tampered cursors
+	// are never unmarshalled
+	if !hmac.Equal(want, signCursor(secret, body)) {
+		return ErrInvalidCursor
+	}
+
+	byt, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(byt, &payload); err != nil {
+		return ErrInvalidCursor
+	}
+	if payload.Expires != 0 && time.Now().UnixMilli() > payload.Expires {
+		return ErrCursorExpired
+	}
+	return json.Unmarshal(payload.Value, dst)
+}
+
+func signCursor(secret []byte, body string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,64 @@
+package faas
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	type page struct {
+		Offset int    `json:"offset"`
+		Sort   string `json:"sort"`
+	}
+	secret := []byte("s3cret")
+
+	token, err := encodeCursor(secret, page{Offset: 40, Sort: "name"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsAny(token, "+/=") {
+		t.Errorf("expected url safe token, got %s", token)
+	}
+
+	var got page
+	if err := decodeCursor(secret, token, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Offset != 40 || got.Sort != "name" {
+		t.Errorf("unexpected cursor value %+v", got)
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+	secret := []byte("s3cret")
+	valid, _ := encodeCursor(secret, 10, time.Minute)
+	forever, _ := encodeCursor(secret, 10, 0)
+	past, _ := encodeCursor(secret, 10, time.Nanosecond)
+	time.Sleep(5 * time.Millisecond)
+
+	tests := []struct {
+		name   string
+		secret []byte
+		token  string
+		err    error
+	}{
+		{name: "valid", secret: secret, token: valid},
+		{name: "no expiry", secret: secret, token: forever},
+		{name: "expired", secret: secret, token: past, err: ErrCursorExpired},
+		{name: "wrong secret", secret: []byte("other"), token: valid, err: ErrInvalidCursor},
+		{name: "tampered", secret: secret, token: "e30" + valid, err: ErrInvalidCursor},
+		{name: "malformed", secret: secret, token: "nodot", err: ErrInvalidCursor},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var v int
+			err := decodeCursor(tc.secret, tc.token, &v)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package faas
+
+import (
+	"context"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDashboardFailures is how many recent failures a Dashboard keeps.
+const maxDashboardFailures = 50
+
+// DefaultDashboard collects the runs of every CronGuard by default.
+var DefaultDashboard = NewDashboard()
+
+// CronStatus describes the runs of a scheduled job.
+type CronStatus struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule,omitempty"`
+	Running  bool   `json:"running"`
+	Runs     int64  `json:"runs"`
+	Skipped  int64  `json:"skipped"`
+	Failed   int64  `json:"failed"`
+	// LastRun is nil until the job first runs.
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastStatus   int        `json:"last_status,omitempty"`
+	LastDuration Duration   `json:"last_duration,omitempty"`
+	// NextRun is when the schedule runs next, when it could be parsed and
+	// has a next run.
+	NextRun *time.Time `json:"next_run,omitempty"`
+}
+
+// Failure is a recent failure recorded by a Dashboard.
+type Failure struct {
+	Time   time.Time `json:"time"`
+	Status int       `json:"status"`
+	Error  string    `json:"error"`
+	Path   string    `json:"path,omitempty"`
+	Panic  bool      `json:"panic,omitempty"`
+}
+
+// DashboardSnapshot is the state shown by Dashboard.Handler.
+type DashboardSnapshot struct {
+	Pipelines   map[string][]StageStats `json:"pipelines"`
+	Jobs        []CronStatus            `json:"jobs"`
+	Failures    []Failure               `json:"failures"`
+	DeadLetters map[string]int64        `json:"dead_letters"`
+}
+
+// Dashboard collects the state of a function's background work: pipeline
+// workers, scheduled jobs, recent failures and dead-lettered events, for
+// inspecting a running function without exec'ing into it. It is safe for
+// concurrent use.
+type Dashboard struct {
+	mu          sync.Mutex
+	pipelines   map[string]*Pipeline
+	jobs        map[string]*CronStatus
+	failures    []Failure
+	deadLetters map[string]int64
+}
+
+// NewDashboard returns an empty Dashboard.
+func NewDashboard() *Dashboard {
+	return &Dashboard{
+		pipelines:   make(map[string]*Pipeline),
+		jobs:        make(map[string]*CronStatus),
+		deadLetters: make(map[string]int64),
+	}
+}
+
+// AddPipeline shows the stage stats of p under name, replacing any
+// pipeline previously added with the name.
+func (d *Dashboard) AddPipeline(name string, p *Pipeline) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pipelines[name] = p
+}
+
+// CountDeadLetter adds a dead-lettered message for topic. Events dead
+// lettered by a SchemaValidator using DefaultSchemaMetrics are included
+// without it.
+func (d *Dashboard) CountDeadLetter(topic string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deadLetters[topic]++
+}
+
+// Reporter returns an ErrorReporter recording failures on the dashboard
+// before passing them to next, which may be nil:
+//
+//	faas.DefaultErrorReporter = faas.DefaultDashboard.Reporter(sentryReporter)
+func (d *Dashboard) Reporter(next ErrorReporter) ErrorReporter {
+	return dashboardReporter{d: d, next: next}
+}
+
+type dashboardReporter struct {
+	d    *Dashboard
+	next ErrorReporter
+}
+
+func (r dashboardReporter) Report(ctx context.Context, report ErrorReport) {
+	f := Failure{Time: time.Now(), Status: report.Status, Panic: report.Panic}
+	if report.Err != nil {
+		f.Error = report.Err.Error()
+	}
+	if report.Request != nil {
+		f.Path = report.Request.URL.Path
+	}
+	r.d.mu.Lock()
+	r.d.failures = append(r.d.failures, f)
+	if len(r.d.failures) > maxDashboardFailures {
+		r.d.failures = r.d.failures[len(r.d.failures)-maxDashboardFailures:]
+	}
+	r.d.mu.Unlock()
+	if r.next != nil {
+		r.next.Report(ctx, report)
+	}
+}
+
+func (d *Dashboard) job(name string, fn func(*CronStatus)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j, ok := d.jobs[name]
+	if !ok {
+		j = &CronStatus{Name: name}
+		d.jobs[name] = j
+	}
+	fn(j)
+}
+
+// Snapshot returns the current state, with the most recent failure first.
+func (d *Dashboard) Snapshot() DashboardSnapshot {
+	d.mu.Lock()
+	snap := DashboardSnapshot{
+		Pipelines:   make(map[string][]StageStats, len(d.pipelines)),
+		Jobs:        make([]CronStatus, 0, len(d.jobs)),
+		Failures:    make([]Failure, 0, len(d.failures)),
+		DeadLetters: make(map[string]int64, len(d.deadLetters)),
+	}
+	pipelines := make(map[string]*Pipeline, len(d.pipelines))
+	for name, p := range d.pipelines {
+		pipelines[name] = p
+	}
+	for _, j := range d.jobs {
+		snap.Jobs = append(snap.Jobs, *j)
+	}
+	for i := len(d.failures) - 1; i >= 0; i-- {
+		snap.Failures = append(snap.Failures, d.failures[i])
+	}
+	for topic, n := range d.deadLetters {
+		snap.DeadLetters[topic] = n
+	}
+	d.mu.Unlock()
+
+	for name, p := range pipelines {
+		snap.Pipelines[name] = p.Stats()
+	}
+	sort.Slice(snap.Jobs, func(i, j int) bool { return snap.Jobs[i].Name < snap.Jobs[j].Name })
+	for _, s := range DefaultSchemaMetrics.Snapshot() {
+		if s.DeadLettered > 0 {
+			snap.DeadLetters[s.Subject] += s.DeadLettered
+		}
+	}
+	return snap
+}
+
+// Handler serves the snapshot as an HTML page to browsers and as JSON
+// otherwise. Callers must send "Authorization: Bearer <token>" where the
+// token is the OpenFaaS secret named tokenSecret, e.g. from a browser
+// extension setting the header. Failures are only listed once the
+// dashboard's Reporter wraps DefaultErrorReporter.
+func (d *Dashboard) Handler(tokenSecret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeBearer(w, r, tokenSecret, "dashboard") {
+			return
+		}
+
+		snap := d.Snapshot()
+		if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+			_ = writeJSON(w, http.StatusOK, snap, nil)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := dashboardTemplate.Execute(w, snap); err != nil {
+			slog.Error("rendering dashboard", "error", err)
+		}
+	})
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>Jobs</title>
+<style>body{font-family:sans-serif;margin:2em}table{border-collapse:collapse;margin-bottom:2em}td,th{border:1px solid #ccc;padding:.3em .6em;text-align:left}</style>
+</head><body>
+<h2>Pipelines</h2>
+{{range $name, $stages := .Pipelines}}<h3>{{$name}}</h3>
+<table><tr><th>Stage</th><th>In</th><th>Out</th><th>Errors</th><th>Busy</th></tr>
+{{range $stages}}<tr><td>{{.Name}}</td><td>{{.In}}</td><td>{{.Out}}</td><td>{{.Errors}}</td><td>{{.Busy}}</td></tr>{{end}}
+</table>{{else}}<p>None</p>{{end}}
+<h2>Scheduled jobs</h2>
+<table><tr><th>Job</th><th>Schedule</th><th>Running</th><th>Runs</th><th>Skipped</th><th>Failed</th><th>Last run</th><th>Status</th><th>Duration</th><th>Next run</th></tr>
+{{range .Jobs}}<tr><td>{{.Name}}</td><td>{{.Schedule}}</td><td>{{.Running}}</td><td>{{.Runs}}</td><td>{{.Skipped}}</td><td>{{.Failed}}</td><td>{{with .LastRun}}{{.Format "2006-01-02 15:04:05"}}{{end}}</td><td>{{.LastStatus}}</td><td>{{.LastDuration}}</td><td>{{with .NextRun}}{{.Format "2006-01-02 15:04:05"}}{{end}}</td></tr>{{end}}
+</table>
+<h2>Dead letters</h2>
+<table><tr><th>Topic</th><th>Count</th></tr>
+{{range $topic, $n := .DeadLetters}}<tr><td>{{$topic}}</td><td>{{$n}}</td></tr>{{end}}
+</table>
+<h2>Recent failures</h2>
+<table><tr><th>Time</th><th>Status</th><th>Path</th><th>Error</th></tr>
+{{range .Failures}}<tr><td>{{.Time.Format "2006-01-02 15:04:05"}}</td><td>{{.Status}}{{if .Panic}} (panic){{end}}</td><td>{{.Path}}</td><td>{{.Error}}</td></tr>{{end}}
+</table>
+</body></html>
+`))
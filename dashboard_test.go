@@ -0,0 +1,92 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+	withSecrets(t, map[string]string{"dashboard-token": "s3cret"})
+	d := NewDashboard()
+
+	g := &CronGuard{Store: NewMemoryKV(), Name: "nightly", Dashboard: d}
+	job := g.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-Connector", "cron-connector")
+	req.Header.Set("X-Cron-Schedule", "0 2 * * *")
+	job.ServeHTTP(httptest.NewRecorder(), req)
+
+	d.Reporter(nil).Report(context.Background(), ErrorReport{
+		Err:     errors.New("upstream down <b>"),
+		Status:  http.StatusBadGateway,
+		Request: httptest.NewRequest(http.MethodGet, "/orders", nil),
+	})
+	d.CountDeadLetter("orders.dlq")
+
+	h := d.Handler("dashboard-token")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var snap DashboardSnapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &snap); err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Jobs []map[string]any `json:"jobs"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &raw)
+	if _, ok := raw.Jobs[0]["last_duration"].(string); !ok {
+		t.Errorf("last_duration = %v, want a duration string", raw.Jobs[0]["last_duration"])
+	}
+	if len(snap.Jobs) != 1 || snap.Jobs[0].Runs != 1 || snap.Jobs[0].Failed != 1 || snap.Jobs[0].Schedule != "0 2 * * *" || snap.Jobs[0].NextRun.Hour() != 2 {
+		t.Errorf("unexpected jobs %+v", snap.Jobs)
+	}
+	if len(snap.Failures) != 1 || snap.Failures[0].Path != "/orders" || snap.DeadLetters["orders.dlq"] != 1 {
+		t.Errorf("unexpected snapshot %+v", snap)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?token=s3cret", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("query token: status %d, want 401", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	req.Header.Set("Accept", "text/html")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, "nightly") || !strings.Contains(body, "upstream down &lt;b&gt;") {
+		t.Errorf("expected an escaped HTML page, got %s", body)
+	}
+}
+
+func TestDashboardCronWithoutNextRun(t *testing.T) {
+	d := NewDashboard()
+	g := &CronGuard{Store: NewMemoryKV(), Name: "leap", Dashboard: d}
+	job := g.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-Connector", "cron-connector")
+	req.Header.Set("X-Cron-Schedule", "0 0 30 2 *")
+	job.ServeHTTP(httptest.NewRecorder(), req)
+
+	if jobs := d.Snapshot().Jobs; len(jobs) != 1 || jobs[0].NextRun != nil {
+		t.Errorf("expected no next run for a schedule which never runs, got %+v", jobs)
+	}
+}
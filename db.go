@@ -0,0 +1,121 @@
+package faas
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DBOptions configure OpenDB. The pool defaults suit functions which scale
+// to zero: few connections per replica, since replicas multiply them, and
+// idle connections closed quickly so a quiet replica holds none.
+type DBOptions struct {
+	// PasswordSecret names a secret holding the password, for DSNs kept
+	// without it. It is set as the password of URL DSNs such as
+	// postgres://app@db/orders and replaces "{password}" in others.
+	PasswordSecret string
+	// MaxOpenConns defaults to 4 and MaxIdleConns to 2.
+	MaxOpenConns int
+	MaxIdleConns int
+	// ConnMaxIdleTime defaults to 1 minute and ConnMaxLifetime to 30
+	// minutes.
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+	// PingTimeout bounds the connectivity check made by OpenDB. Defaults
+	// to 5s.
+	PingTimeout time.Duration
+	// HealthCheck is the name of the health check registered for the
+	// pool, see HealthHandler. Defaults to the driver name.
+	HealthCheck string
+}
+
+// OpenDB opens a database/sql pool for driver with the DSN read from the
+// dsnSecret secret and checks it is reachable. The pool is registered as a
+// health check and closed by Shutdown. The driver must be imported by the
+// function as usual.
+func OpenDB(driver, dsnSecret string, opts DBOptions) (*sql.DB, error) {
+	dsn, err := getSecretString(dsnSecret)
+	if err != nil {
+		return nil, fmt.Errorf("reading database DSN: %w", err)
+	}
+	if opts.PasswordSecret != "" {
+		password, err := getSecretString(opts.PasswordSecret)
+		if err != nil {
+			return nil, fmt.Errorf("reading database password: %w", err)
+		}
+		dsn = withDSNPassword(dsn, password)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(defaultInt(opts.MaxOpenConns, 4))
+	db.SetMaxIdleConns(defaultInt(opts.MaxIdleConns, 2))
+	db.SetConnMaxIdleTime(defaultDuration(opts.ConnMaxIdleTime, time.Minute))
+	db.SetConnMaxLifetime(defaultDuration(opts.ConnMaxLifetime, 30*time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDuration(opts.PingTimeout, 5*time.Second))
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("connecting to %s database: %w", driver, err)
+	}
+
+	name := opts.HealthCheck
+	if name == "" {
+		name = driver
+	}
+	RegisterHealthCheck(name, db.PingContext)
+	OnShutdown(func(context.Context) error { return db.Close() })
+	return db, nil
+}
+
+func withDSNPassword(dsn, password string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		u.User = url.UserPassword(u.User.Username(), password)
+		return u.String()
+	}
+	return strings.ReplaceAll(dsn, "{password}", password)
+}
+
+func defaultInt(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+func defaultDuration(v, def time.Duration) time.Duration {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// WithTx runs fn in a transaction, committing when it returns nil and
+// rolling back when it returns an error or panics. Panics are re-raised
+// after the rollback.
+func WithTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			_ = tx.Rollback()
+			panic(rec)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, fmt.Errorf("rolling back: %w", rbErr))
+		}
+		return err
+	}
+	return tx.Commit()
+}
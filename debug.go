@@ -0,0 +1,76 @@
+package faas
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const debugTokenPurpose = "debug"
+
+type debugKey struct{}
+
+// NewDebugToken returns a signed token which enables debugging of requests
+// sending it in the X-Debug-Token header until ttl has passed. The secret
+// must match the contents of the secret given to Debug.
+func NewDebugToken(secret []byte, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return "", errors.New("debug tokens must expire")
+	}
+	return encodeCursor(debugTokenKey(secret), debugTokenPurpose, ttl)
+}
+
+// debugTokenKey derives the key signing debug tokens from secret, so a
+// pagination cursor signed with the same secret is never a valid debug token
+// and the other way around.
+func debugTokenKey(secret []byte) []byte {
+	if len(secret) == 0 {
+		return nil
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("faas debug token"))
+	return mac.Sum(nil)
+}
+
+// Debug is middleware honouring X-Debug-Token headers created by
+// NewDebugToken with the OpenFaaS secret named secretName. For a request
+// with a valid token every log level is written regardless of
+// DefaultLogControl, when logging with the request context, and the
+// timings recorded with StartTiming, Timed and TimingTransport are returned
+// in an X-Debug-Timing trailer. Requests without a token are unaffected.
+func Debug(secretName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-Debug-Token")
+			if token == "" || !validDebugToken(secretName, token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, timings := WithTimings(context.WithValue(r.Context(), debugKey{}, true))
+			w.Header().Add("Trailer", "X-Debug-Timing")
+			stop := StartTiming(ctx, "total")
+			next.ServeHTTP(w, r.WithContext(ctx))
+			stop()
+			w.Header().Set("X-Debug-Timing", timings.String())
+		})
+	}
+}
+
+func validDebugToken(secretName, token string) bool {
+	secret, err := getSecretString(secretName)
+	if err != nil || secret == "" {
+		return false
+	}
+	var purpose string
+	return decodeCursor(debugTokenKey([]byte(secret)), token, &purpose) == nil && purpose == debugTokenPurpose
+}
+
+// IsDebug reports whether ctx belongs to a request with a valid debug token.
+func IsDebug(ctx context.Context) bool {
+	debug, _ := ctx.Value(debugKey{}).(bool)
+	return debug
+}
@@ -0,0 +1,64 @@
+package faas
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebug(t *testing.T) {
+	withSecrets(t, map[string]string{"debug-key": "debug-secret"})
+	valid, err := NewDebugToken([]byte("debug-secret"), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, _ := NewDebugToken([]byte("other-secret"), time.Minute)
+	cursor, _ := EncodeCursor([]byte("debug-secret"), 42, time.Minute)
+	swapped, _ := EncodeCursor([]byte("debug-secret"), "debug", time.Minute)
+
+	tests := []struct {
+		name  string
+		token string
+		debug bool
+	}{
+		{name: "valid token", token: valid, debug: true},
+		{name: "no token"},
+		{name: "wrong secret", token: forged},
+		{name: "token for another purpose", token: cursor},
+		{name: "cursor with the debug purpose", token: swapped},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(NewLogControl().Handler(slog.NewTextHandler(&logs, nil)))
+			h := Debug("debug-key")(Timed("handler")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger.DebugContext(r.Context(), "verbose detail")
+				_, _ = w.Write([]byte("ok"))
+			})))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.token != "" {
+				r.Header.Set("X-Debug-Token", tc.token)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, r)
+
+			timing := rr.Result().Trailer.Get("X-Debug-Timing")
+			if tc.debug != strings.Contains(timing, "handler;dur=") {
+				t.Errorf("unexpected timing trailer %q", timing)
+			}
+			if tc.debug != strings.Contains(logs.String(), "verbose detail") {
+				t.Errorf("unexpected logs %q", logs.String())
+			}
+		})
+	}
+
+	if _, err := NewDebugToken([]byte("debug-secret"), 0); err == nil {
+		t.Error("expected tokens without expiry to be rejected")
+	}
+}
@@ -0,0 +1,271 @@
+package faas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an arbitrary precision decimal number. It is stored as an
+// integer coefficient and a base 10 scale so values such as 0.1 are exact,
+// unlike float64. The zero value is 0.
+type Decimal struct {
+	coef  *big.Int
+	scale int32
+}
+
+var bigTen = big.NewInt(10)
+
+// maxDecimalScale bounds the scale and exponent accepted by ParseDecimal.
+const maxDecimalScale = 1000
+
+// NewDecimal returns unscaled * 10^-scale, e.g. NewDecimal(1234, 2) is 12.34.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	return Decimal{coef: big.NewInt(unscaled), scale: scale}
+}
+
+// ParseDecimal parses a decimal string such as "-12.340" or "1.5e3". The
+// scale of the input is preserved. Inputs whose scale or exponent is beyond
+// ±1000 are rejected.
+func ParseDecimal(s string) (Decimal, error) {
+	return parseDecimal(s)
+}
+func parseDecimal(s string) (Decimal, error) {
+	invalid := fmt.Errorf("invalid decimal %q", s)
+	s = strings.TrimSpace(s)
+
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, invalid
+		}
+		mantissa, exp = s[:i], e
+	}
+
+	whole, frac, _ := strings.Cut(mantissa, ".")
+	digits := strings.TrimLeft(whole, "+-") + frac
+	if digits == "" || strings.Trim(digits, "0123456789") != "" || strings.Count(whole, "-")+strings.Count(whole, "+") > 1 {
+		return Decimal{}, invalid
+	}
+	if len(whole) > 0 && strings.ContainsAny(whole[1:], "+-") {
+		return Decimal{}, invalid
+	}
+
+	coef, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, invalid
+	}
+	if strings.HasPrefix(whole, "-") {
+		coef.Neg(coef)
+	}
+	scale := int64(len(frac)) - exp
+	if scale > maxDecimalScale || scale < -maxDecimalScale {
+		// 10^scale is computed for arithmetic, so huge exponents would let
+		// a short input use unbounded CPU and memory
+		return Decimal{}, fmt.Errorf("invalid decimal %q: exponent out of range", s)
+	}
+	if scale < 0 {
+		coef.Mul(coef, new(big.Int).Exp(bigTen, big.NewInt(-scale), nil))
+		scale = 0
+	}
+	return Decimal{coef: coef, scale: int32(scale)}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on invalid input. It is
+// intended for constants.
+func MustParseDecimal(s string) Decimal {
+	d, err := parseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d Decimal) int() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// rescale returns the coefficient of d expressed with a larger scale.
+func (d Decimal) rescale(scale int32) *big.Int {
+	c := new(big.Int).Set(d.int())
+	if scale > d.scale {
+		c.Mul(c, new(big.Int).Exp(bigTen, big.NewInt(int64(scale-d.scale)), nil))
+	}
+	return c
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Add returns d + o.
+func (d Decimal) Add(o Decimal) Decimal {
+	scale := max(d.scale, o.scale)
+	return Decimal{coef: new(big.Int).Add(d.rescale(scale), o.rescale(scale)), scale: scale}
+}
+
+// Sub returns d - o.
+func (d Decimal) Sub(o Decimal) Decimal {
+	scale := max(d.scale, o.scale)
+	return Decimal{coef: new(big.Int).Sub(d.rescale(scale), o.rescale(scale)), scale: scale}
+}
+
+// Mul returns d * o.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.int(), o.int()), scale: d.scale + o.scale}
+}
+
+// Div returns d / o rounded half-even to scale digits.
+func (d Decimal) Div(o Decimal, scale int32) (Decimal, error) {
+	if o.Sign() == 0 {
+		return Decimal{}, errors.New("decimal division by zero")
+	}
+	shift := int64(scale) + int64(o.scale) - int64(d.scale)
+	num := new(big.Int).Set(d.int())
+	den := new(big.Int).Set(o.int())
+	if shift >= 0 {
+		num.Mul(num, new(big.Int).Exp(bigTen, big.NewInt(shift), nil))
+	} else {
+		den.Mul(den, new(big.Int).Exp(bigTen, big.NewInt(-shift), nil))
+	}
+
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if roundAway(q, r, den) {
+		if num.Sign()*den.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{coef: q, scale: scale}, nil
+}
+
+// roundAway reports whether a truncated quotient q with remainder r should
+// be rounded away from zero under half-even rounding.
+func roundAway(q, r, den *big.Int) bool {
+	if r.Sign() == 0 {
+		return false
+	}
+	cmp := new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(new(big.Int).Abs(den))
+	return cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Sign returns -1, 0 or 1.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// IsZero reports whether d is 0.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and o, returning -1, 0 or 1.
+func (d Decimal) Cmp(o Decimal) int {
+	scale := max(d.scale, o.scale)
+	return d.rescale(scale).Cmp(o.rescale(scale))
+}
+
+// Round returns d rounded half-even (banker's rounding) to scale digits.
+func (d Decimal) Round(scale int32) Decimal {
+	if scale >= d.scale {
+		return Decimal{coef: d.rescale(scale), scale: scale}
+	}
+	div := new(big.Int).Exp(bigTen, big.NewInt(int64(d.scale-scale)), nil)
+	q, r := new(big.Int).QuoRem(d.int(), div, new(big.Int))
+	if roundAway(q, r, div) {
+		if d.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{coef: q, scale: scale}
+}
+
+// Int64 returns the coefficient of d at the given scale, e.g. the amount in
+// cents for scale 2. It fails if d has more digits or does not fit.
+func (d Decimal) Int64(scale int32) (int64, error) {
+	if d.scale > scale && d.Round(scale).Cmp(d) != 0 {
+		return 0, fmt.Errorf("decimal %s has more than %d decimal places", d, scale)
+	}
+	c := d.Round(scale).int()
+	if !c.IsInt64() {
+		return 0, fmt.Errorf("decimal %s overflows int64", d)
+	}
+	return c.Int64(), nil
+}
+
+// String formats d without an exponent, keeping its scale.
+func (d Decimal) String() string {
+	c := d.int()
+	digits := new(big.Int).Abs(c).String()
+	sign := ""
+	if c.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale <= 0 {
+		return sign + digits + strings.Repeat("0", int(-d.scale))
+	}
+	if len(digits) <= int(d.scale) {
+		digits = strings.Repeat("0", int(d.scale)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// MarshalJSON encodes d as a JSON string so no precision is lost in clients
+// that parse numbers as floats.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts both JSON strings and numbers. Numbers are parsed
+// from their literal text and never pass through float64.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	parsed, err := parseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler so decimals can be bound
+// from query parameters and form values.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := parseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,115 @@
+package faas
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{input: "12.340", expected: "12.340"},
+		{input: "-0.05", expected: "-0.05"},
+		{input: ".5", expected: "0.5"},
+		{input: "1.5e3", expected: "1500"},
+		{input: "25e-4", expected: "0.0025"},
+		{input: "1.2.3", expectErr: true},
+		{input: "--1", expectErr: true},
+		{input: "1-2", expectErr: true},
+		{input: "abc", expectErr: true},
+		{input: "1e1000", expected: "1" + strings.Repeat("0", 1000)},
+		{input: "1e5000000", expectErr: true},
+		{input: "1e-2147483647", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			d, err := parseDecimal(tc.input)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if !tc.expectErr && d.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, d)
+			}
+		})
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	a := MustParseDecimal("0.1")
+	b := MustParseDecimal("0.2")
+	if got := a.Add(b); got.Cmp(MustParseDecimal("0.3")) != 0 {
+		t.Errorf("expected 0.1 + 0.2 to be exactly 0.3, got %s", got)
+	}
+	if got := a.Sub(b); got.String() != "-0.1" {
+		t.Errorf("expected -0.1, got %s", got)
+	}
+	if got := MustParseDecimal("1.5").Mul(MustParseDecimal("1.25")); got.String() != "1.875" {
+		t.Errorf("expected 1.875, got %s", got)
+	}
+
+	rounding := []struct {
+		input    string
+		expected string
+	}{
+		{input: "2.345", expected: "2.34"},
+		{input: "2.355", expected: "2.36"},
+		{input: "2.3451", expected: "2.35"},
+		{input: "-2.345", expected: "-2.34"},
+		{input: "-2.346", expected: "-2.35"},
+	}
+	for _, tc := range rounding {
+		if got := MustParseDecimal(tc.input).Round(2); got.String() != tc.expected {
+			t.Errorf("expected %s to round to %s, got %s", tc.input, tc.expected, got)
+		}
+	}
+
+	q, err := NewDecimal(10, 0).Div(NewDecimal(3, 0), 4)
+	if err != nil || q.String() != "3.3333" {
+		t.Errorf("expected 3.3333, got %s (%v)", q, err)
+	}
+	q, _ = NewDecimal(-1, 0).Div(NewDecimal(8, 0), 2)
+	if q.String() != "-0.12" {
+		t.Errorf("expected -0.12, got %s", q)
+	}
+	if _, err := a.Div(Decimal{}, 2); err == nil {
+		t.Error("expected division by zero error")
+	}
+}
+
+func TestDecimalHugeExponent(t *testing.T) {
+	start := time.Now()
+	var d Decimal
+	if err := json.Unmarshal([]byte(`"1e5000000"`), &d); err == nil {
+		t.Fatal("expected an error for a huge exponent")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("rejecting the exponent took %v", elapsed)
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var v struct {
+		Number Decimal `json:"number"`
+		String Decimal `json:"string"`
+	}
+	if err := json.Unmarshal([]byte(`{"number":0.1000000000000000055511,"string":"19.99"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Number.String() != "0.1000000000000000055511" {
+		t.Errorf("expected number precision to be kept, got %s", v.Number)
+	}
+
+	js, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(js) != `{"number":"0.1000000000000000055511","string":"19.99"}` {
+		t.Errorf("unexpected json %s", js)
+	}
+}
@@ -0,0 +1,134 @@
+package faas
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+var (
+	dedupeProcessing = []byte("processing")
+	dedupeDone       = []byte("done")
+)
+
+// MessageID is the default Dedupe key function. It identifies deliveries by
+// the CloudEvents id and source (Ce-Id and Ce-Source), the Kafka topic,
+// partition and offset, or the Nats-Msg-Id or Idempotency-Key headers, in
+// that order. An empty string is returned when none are present.
+func MessageID(r *http.Request) string {
+	h := r.Header
+	switch {
+	case h.Get("Ce-Id") != "":
+		return "ce:" + h.Get("Ce-Source") + ":" + h.Get("Ce-Id")
+	case h.Get("X-Kafka-Offset") != "":
+		return "kafka:" + h.Get("X-Topic") + ":" + h.Get("X-Kafka-Partition") + ":" + h.Get("X-Kafka-Offset")
+	case h.Get("Nats-Msg-Id") != "":
+		return "nats:" + h.Get("X-Topic") + ":" + h.Get("Nats-Msg-Id")
+	case h.Get("Idempotency-Key") != "":
+		return "key:" + h.Get("Idempotency-Key")
+	}
+	return ""
+}
+
+// Dedupe is middleware for functions fed by at-least-once event sources.
+// Deliveries whose key, as returned by keyFunc, was already handled
+// successfully within ttl get a 200 response without running the handler
+// again. Requests without a key are always handled. A nil keyFunc uses
+// MessageID.
+//
+// A delivery still being handled is answered with 409 so the source
+// redelivers it later. Failed deliveries, with a non-2xx status, are not
+// recorded so redeliveries run the handler. Deliveries are claimed with
+// SetNX when store implements AtomicKV, as MemoryKV and the redis and
+// sqlstore clients do, so only one of two copies arriving at the same
+// moment runs. Other stores fall back to a Get followed by a Set, where
+// both may run.
+func Dedupe(keyFunc func(*http.Request) string, store KV, ttl time.Duration) func(http.Handler) http.Handler {
+	if keyFunc == nil {
+		keyFunc = MessageID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key = "faas:dedupe:" + key
+			ctx := r.Context()
+
+			claimed, err := setNX(ctx, store, key, dedupeProcessing, ttl)
+			if err != nil {
+				// handling twice is better than dropping the delivery
+				slog.WarnContext(ctx, "dedupe claim failed", "key", key, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !claimed {
+				state, err := store.Get(ctx, key)
+				switch {
+				case err == nil && string(state) == string(dedupeDone):
+					_ = writeJSON(w, http.StatusOK, Map{"status": "duplicate"}, nil)
+				case err == nil || errors.Is(err, ErrNotFound):
+					// still processing, or finished and failed just now
+					_ = writeJSONError(w, Error{
+						Status: http.StatusText(http.StatusConflict),
+						Reason: "delivery is already being processed",
+						Code:   http.StatusConflict,
+					})
+				default:
+					slog.WarnContext(ctx, "dedupe lookup failed", "key", key, "error", err)
+					next.ServeHTTP(w, r)
+				}
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w}
+			completed := false
+			defer func() {
+				// record the outcome even when the handler panics, handlers
+				// which write nothing respond 200
+				status := sw.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				if completed && status >= 200 && status < 300 {
+					err = store.Set(ctx, key, dedupeDone, ttl)
+				} else {
+					err = store.Delete(ctx, key)
+				}
+				if err != nil {
+					slog.WarnContext(ctx, "dedupe update failed", "key", key, "error", err)
+				}
+			}()
+			next.ServeHTTP(sw, r)
+			completed = true
+		})
+	}
+}
+
+// statusWriter records the status code written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
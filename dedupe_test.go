@@ -0,0 +1,89 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDedupe(t *testing.T) {
+	kv := NewMemoryKV()
+	calls := 0
+	h := Dedupe(nil, kv, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	deliver := func(headers map[string]string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		return rr.Code
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		calls   int
+	}{
+		{name: "first cloudevent", headers: map[string]string{"Ce-Id": "1", "Ce-Source": "/orders"}, status: http.StatusAccepted, calls: 1},
+		{name: "redelivered cloudevent", headers: map[string]string{"Ce-Id": "1", "Ce-Source": "/orders"}, status: http.StatusOK, calls: 1},
+		{name: "same id other source", headers: map[string]string{"Ce-Id": "1", "Ce-Source": "/refunds"}, status: http.StatusAccepted, calls: 2},
+		{name: "kafka offset", headers: map[string]string{"X-Topic": "orders", "X-Kafka-Partition": "0", "X-Kafka-Offset": "7"}, status: http.StatusAccepted, calls: 3},
+		{name: "kafka redelivery", headers: map[string]string{"X-Topic": "orders", "X-Kafka-Partition": "0", "X-Kafka-Offset": "7"}, status: http.StatusOK, calls: 3},
+		{name: "failed delivery", headers: map[string]string{"Nats-Msg-Id": "a", "X-Fail": "1"}, status: http.StatusInternalServerError, calls: 4},
+		{name: "failed delivery is retried", headers: map[string]string{"Nats-Msg-Id": "a"}, status: http.StatusAccepted, calls: 5},
+		{name: "no key", status: http.StatusAccepted, calls: 6},
+		{name: "no key again", status: http.StatusAccepted, calls: 7},
+	}
+	for _, tc := range tests {
+		if got := deliver(tc.headers); got != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, got)
+		}
+		if calls != tc.calls {
+			t.Errorf("%s: expected %d handler calls, got %d", tc.name, tc.calls, calls)
+		}
+	}
+}
+
+func TestDedupeConcurrentCopies(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var calls atomic.Int32
+	h := Dedupe(nil, NewMemoryKV(), time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		close(started)
+		<-release
+	}))
+	deliver := func() int {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Idempotency-Key", "k")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		return rr.Code
+	}
+
+	first := make(chan int)
+	go func() { first <- deliver() }()
+	<-started
+	if got := deliver(); got != http.StatusConflict {
+		t.Errorf("second copy: status %d, want 409", got)
+	}
+	close(release)
+	if got := <-first; got != http.StatusOK {
+		t.Errorf("first copy: status %d, want 200", got)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+}
@@ -0,0 +1,176 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// IP family preferences for HTTPClientOptions.PreferIP.
+const (
+	PreferIPv4 = "ipv4"
+	PreferIPv6 = "ipv6"
+)
+
+// dualStackDialer connects to hosts with several addresses using Happy
+// Eyeballs (RFC 8305): addresses of the preferred family are tried first and
+// the other family is raced against them after a fallback delay.
+type dualStackDialer struct {
+	dialer *net.Dialer
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+	// prefer is PreferIPv4, PreferIPv6 or empty to prefer the family of the
+	// first resolved address.
+	prefer string
+	// fallbackDelay before racing the other family. Negative disables
+	// racing so the other family is only tried once the preferred one fails.
+	fallbackDelay time.Duration
+}
+
+// dialOptionsFromEnv reads DIAL_PREFER_IP ("ipv4" or "ipv6") and
+// DIAL_FALLBACK_DELAY, a duration or "off".
+func dialOptionsFromEnv() (string, time.Duration, error) {
+	prefer := strings.ToLower(os.Getenv("DIAL_PREFER_IP"))
+	if prefer != "" && prefer != PreferIPv4 && prefer != PreferIPv6 {
+		return "", 0, fmt.Errorf("invalid DIAL_PREFER_IP %q, expected ipv4 or ipv6", prefer)
+	}
+	var delay time.Duration
+	switch v := os.Getenv("DIAL_FALLBACK_DELAY"); v {
+	case "":
+	case "off":
+		delay = -1
+	default:
+		d, err := parseDuration(v)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid DIAL_FALLBACK_DELAY: %w", err)
+		}
+		delay = d
+	}
+	return prefer, delay, nil
+}
+
+func (d dualStackDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+	lookup := d.lookup
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupIPAddr
+	}
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var primary, fallback []string
+	for _, addr := range addrs {
+		if !matchesNetwork(network, addr.IP) {
+			continue
+		}
+		hostport := net.JoinHostPort(addr.String(), port)
+		var primaryFamily bool
+		switch d.prefer {
+		case PreferIPv4:
+			primaryFamily = addr.IP.To4() != nil
+		case PreferIPv6:
+			primaryFamily = addr.IP.To4() == nil
+		default:
+			primaryFamily = (addr.IP.To4() != nil) == (addrs[0].IP.To4() != nil)
+		}
+		if primaryFamily {
+			primary = append(primary, hostport)
+		} else {
+			fallback = append(fallback, hostport)
+		}
+	}
+	if len(primary) == 0 {
+		primary, fallback = fallback, nil
+	}
+	if len(primary) == 0 {
+		return nil, &net.DNSError{Err: "no suitable address found", Name: host}
+	}
+	if len(fallback) == 0 || d.fallbackDelay < 0 {
+		return d.dialSerial(ctx, network, append(primary, fallback...))
+	}
+	return d.dialParallel(ctx, network, primary, fallback)
+}
+
+// dialSerial tries each address in turn.
+func (d dualStackDialer) dialSerial(ctx context.Context, network string, addrs []string) (net.Conn, error) {
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// dialParallel starts the fallback addresses once the primary ones have
+// failed or the fallback delay has passed, returning the first connection.
+func (d dualStackDialer) dialParallel(ctx context.Context, network string, primary, fallback []string) (net.Conn, error) {
+	delay := d.fallbackDelay
+	if delay == 0 {
+		delay = 300 * time.Millisecond
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	results := make(chan result, 2)
+	start := func(addrs []string, primary bool) {
+		go func() {
+			conn, err := d.dialSerial(ctx, network, addrs)
+			results <- result{conn: conn, err: err, primary: primary}
+		}()
+	}
+	start(primary, true)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var errs []error
+	started, pending := false, 1
+	for {
+		select {
+		case <-timer.C:
+			if !started {
+				start(fallback, false)
+				started, pending = true, pending+1
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				if pending > 0 {
+					// close the loser once it finishes
+					go func() {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}()
+				}
+				return res.conn, nil
+			}
+			errs = append(errs, res.err)
+			if !started {
+				start(fallback, false)
+				started, pending = true, pending+1
+			}
+			if pending == 0 {
+				return nil, errors.Join(errs...)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package faas
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDualStackDialer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	// ::1 has nothing listening on the port so it fails over to IPv4
+	lookup := func(context.Context, string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("::1")}, {IP: net.ParseIP("127.0.0.1")}}, nil
+	}
+
+	tests := []struct {
+		name   string
+		prefer string
+		delay  time.Duration
+	}{
+		{name: "prefer ipv4", prefer: PreferIPv4},
+		{name: "resolver order with racing", delay: 10 * time.Millisecond},
+		{name: "resolver order without racing", delay: -1},
+		{name: "prefer ipv6", prefer: PreferIPv6},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := dualStackDialer{dialer: &net.Dialer{Timeout: time.Second}, lookup: lookup, prefer: tc.prefer, fallbackDelay: tc.delay}
+			conn, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("api.test", port))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if host, _, _ := net.SplitHostPort(conn.RemoteAddr().String()); host != "127.0.0.1" {
+				t.Errorf("expected to connect to 127.0.0.1, got %s", host)
+			}
+		})
+	}
+}
+
+func TestDialOptionsFromEnv(t *testing.T) {
+	tests := []struct {
+		prefer, delay string
+		wantPrefer    string
+		wantDelay     time.Duration
+		err           bool
+	}{
+		{prefer: "IPv4", delay: "50ms", wantPrefer: PreferIPv4, wantDelay: 50 * time.Millisecond},
+		{prefer: "ipv6", delay: "off", wantPrefer: PreferIPv6, wantDelay: -1},
+		{},
+		{prefer: "ipv5", err: true},
+		{delay: "soon", err: true},
+	}
+	for _, tc := range tests {
+		t.Setenv("DIAL_PREFER_IP", tc.prefer)
+		t.Setenv("DIAL_FALLBACK_DELAY", tc.delay)
+		prefer, delay, err := dialOptionsFromEnv()
+		if tc.err != (err != nil) {
+			t.Errorf("%q %q: unexpected error %v", tc.prefer, tc.delay, err)
+			continue
+		}
+		if prefer != tc.wantPrefer || delay != tc.wantDelay {
+			t.Errorf("%q %q: expected %q %v, got %q %v", tc.prefer, tc.delay, tc.wantPrefer, tc.wantDelay, prefer, delay)
+		}
+	}
+}
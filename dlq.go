@@ -0,0 +1,286 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSkipMessage can be returned by a Reprocessor Transform to drop a message
+// instead of re-injecting it.
+var ErrSkipMessage = errors.New("skip message")
+
+// DLQMessage is a message read from a dead-letter queue.
+type DLQMessage struct {
+	Topic string
+	Key   string
+	Data  []byte
+}
+
+// Publisher publishes a message to a subject or topic. NATSPublisher
+// implements it, other brokers can be adapted with a small wrapper.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// ReprocessStats counts what a Reprocessor did with the messages it read.
+type ReprocessStats struct {
+	Read      int `json:"read"`
+	Published int `json:"published"`
+	Skipped   int `json:"skipped"`
+	// WouldPublish counts the messages a DryRun would have published.
+	WouldPublish int `json:"would_publish"`
+}
+
+// ReprocessError is returned by Reprocessor.Run for a message which could
+// not be transformed or published. The message has already been taken from
+// the queue, so callers should dead-letter it again or keep it elsewhere.
+type ReprocessError struct {
+	// Message is the message as it was read from the queue.
+	Message DLQMessage
+	Err     error
+}
+
+func (e *ReprocessError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReprocessError) Unwrap() error {
+	return e.Err
+}
+
+// Reprocessor moves messages from a dead-letter queue back into the main
+// stream once the problem which dead-lettered them is fixed, optionally
+// transforming them on the way and rate limited so the consumers are not
+// flooded.
+type Reprocessor struct {
+	Publisher Publisher
+	// Target returns the topic a message is re-injected into. By default a
+	// ".dlq", "-dlq" or "_dlq" suffix is removed from the message topic.
+	Target func(DLQMessage) string
+	// Transform optionally rewrites messages, e.g. to fix a field which
+	// failed validation. Returning ErrSkipMessage drops the message.
+	Transform func(context.Context, DLQMessage) (DLQMessage, error)
+	// Rate limits how fast messages are re-injected. The zero Rate means no
+	// limit.
+	Rate Rate
+	// DryRun transforms messages without publishing them, counting them as
+	// WouldPublish.
+	DryRun bool
+
+	limiterOnce sync.Once
+	limiter     *RateLimiter
+}
+
+// Run re-injects every message of src. It stops at the first message which
+// cannot be transformed or published so nothing more is taken from the
+// queue, returning the stats so far and a *ReprocessError holding the
+// message.
+func (p *Reprocessor) Run(ctx context.Context, src Iterator[DLQMessage]) (ReprocessStats, error) {
+	var stats ReprocessStats
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if !src.Next() {
+			break
+		}
+		stats.Read++
+		msg := src.Value()
+		result, err := p.reprocess(ctx, msg)
+		if err != nil {
+			return stats, &ReprocessError{Message: msg, Err: err}
+		}
+		switch result {
+		case reprocessPublished:
+			stats.Published++
+		case reprocessWouldPublish:
+			stats.WouldPublish++
+		default:
+			stats.Skipped++
+		}
+	}
+	return stats, src.Err()
+}
+
+// ServeHTTP re-injects a single message delivered by the Kafka or NATS
+// connector, so the reprocessor can be deployed as a function subscribed to
+// the dead-letter topic. The topic is read from X-Topic and the key from
+// X-Kafka-Key.
+func (p *Reprocessor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	msg, err := readNATSMessage(r)
+	var appErr *AppError
+	if err != nil && !errors.As(err, &appErr) {
+		err = E(CodeInvalidArgument, err.Error(), err)
+	}
+	if err != nil {
+		_ = writeError(w, err)
+		return
+	}
+	result, err := p.reprocess(r.Context(), DLQMessage{Topic: msg.Topic, Key: r.Header.Get("X-Kafka-Key"), Data: msg.Data})
+	if err != nil {
+		_ = writeError(w, err)
+		return
+	}
+	_ = writeJSON(w, http.StatusOK, Map{"status": string(result)}, nil)
+}
+
+// reprocessResult is what reprocess did with a message, also used as the
+// status answered by ServeHTTP.
+type reprocessResult string
+
+const (
+	reprocessSkipped      reprocessResult = "skipped"
+	reprocessPublished    reprocessResult = "published"
+	reprocessWouldPublish reprocessResult = "would_publish"
+)
+
+func (p *Reprocessor) reprocess(ctx context.Context, msg DLQMessage) (reprocessResult, error) {
+	if p.Transform != nil {
+		var err error
+		msg, err = p.Transform(ctx, msg)
+		if errors.Is(err, ErrSkipMessage) {
+			return reprocessSkipped, nil
+		}
+		if err != nil {
+			return reprocessSkipped, fmt.Errorf("transforming %s message: %w", msg.Topic, err)
+		}
+	}
+	target := dlqTarget(msg)
+	if p.Target != nil {
+		target = p.Target(msg)
+	}
+	if p.DryRun {
+		return reprocessWouldPublish, nil
+	}
+
+	p.limiterOnce.Do(func() {
+		if p.Rate.Count > 0 {
+			p.limiter = NewRateLimiter(RateLimitPolicy{Rate: p.Rate, Burst: 1})
+		}
+	})
+	if p.limiter != nil {
+		if err := p.limiter.Wait(ctx); err != nil {
+			return reprocessSkipped, err
+		}
+	}
+	if err := p.Publisher.Publish(ctx, target, msg.Data); err != nil {
+		return reprocessSkipped, fmt.Errorf("publishing to %s: %w", target, err)
+	}
+	return reprocessPublished, nil
+}
+
+func dlqTarget(msg DLQMessage) string {
+	for _, suffix := range []string{".dlq", "-dlq", "_dlq"} {
+		if t, ok := strings.CutSuffix(msg.Topic, suffix); ok {
+			return t
+		}
+	}
+	return msg.Topic
+}
+
+// SubscribeNATS returns an Iterator over the messages published to subject,
+// for use with Reprocessor.Run. A non-empty queue joins a queue group so
+// several reprocessors share the work. The iterator ends, closing the
+// connection, when no message arrives for idle or ctx is done.
+func SubscribeNATS(ctx context.Context, addr string, opts NATSOptions, subject, queue string, idle time.Duration) Iterator[DLQMessage] {
+	return &natsIterator{ctx: ctx, p: NewNATSPublisher(addr, opts), subject: subject, queue: queue, idle: idle}
+}
+
+type natsIterator struct {
+	ctx     context.Context
+	p       *NATSPublisher
+	subject string
+	queue   string
+	idle    time.Duration
+
+	subscribed bool
+	done       bool
+	msg        DLQMessage
+	err        error
+}
+
+func (it *natsIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	msg, err := it.next()
+	if err != nil {
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() || it.ctx.Err() != nil {
+			it.err = errors.Join(err, it.ctx.Err())
+		}
+		it.done = true
+		_ = it.p.Close()
+		return false
+	}
+	it.msg = msg
+	return true
+}
+
+func (it *natsIterator) next() (DLQMessage, error) {
+	p := it.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !it.subscribed {
+		if err := p.connect(it.ctx); err != nil {
+			return DLQMessage{}, err
+		}
+		sub := it.subject
+		if it.queue != "" {
+			sub += " " + it.queue
+		}
+		if _, err := fmt.Fprintf(p.conn, "SUB %s 1\r\n", sub); err != nil {
+			return DLQMessage{}, err
+		}
+		it.subscribed = true
+	}
+
+	for {
+		deadline := time.Now().Add(it.idle)
+		if d, ok := it.ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		_ = p.conn.SetDeadline(deadline)
+		line, err := p.rd.ReadString('\n')
+		if err != nil {
+			return DLQMessage{}, err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <size>
+			fields := strings.Fields(line)
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 || len(fields) < 4 {
+				return DLQMessage{}, fmt.Errorf("invalid nats message header %q", line)
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(p.rd, data); err != nil {
+				return DLQMessage{}, err
+			}
+			return DLQMessage{Topic: fields[1], Data: data[:size]}, nil
+		case line == "PING":
+			if _, err := io.WriteString(p.conn, "PONG\r\n"); err != nil {
+				return DLQMessage{}, err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return DLQMessage{}, fmt.Errorf("nats: %s", strings.Trim(strings.TrimPrefix(line, "-ERR"), " '"))
+		}
+	}
+}
+
+func (it *natsIterator) Value() DLQMessage {
+	return it.msg
+}
+
+func (it *natsIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,178 @@
+package faas
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingPublisher struct {
+	published []string
+	err       error
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, subject string, data []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, subject+" "+string(data))
+	return nil
+}
+
+func TestReprocessorRun(t *testing.T) {
+	msgs := []DLQMessage{
+		{Topic: "orders.dlq", Data: []byte(`{"id":1}`)},
+		{Topic: "orders-dlq", Data: []byte(`{"id":2,"skip":true}`)},
+		{Topic: "orders_dlq", Data: []byte(`{"id":3}`)},
+	}
+	tests := []struct {
+		name      string
+		dryRun    bool
+		pubErr    error
+		want      ReprocessStats
+		published []string
+		expectErr bool
+	}{
+		{
+			name:      "republish",
+			want:      ReprocessStats{Read: 3, Published: 2, Skipped: 1},
+			published: []string{`orders {"id":1,"fixed":true}`, `orders {"id":3,"fixed":true}`},
+		},
+		{name: "dry run", dryRun: true, want: ReprocessStats{Read: 3, Skipped: 1, WouldPublish: 2}},
+		{name: "publish error", pubErr: errors.New("down"), want: ReprocessStats{Read: 1}, expectErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pub := &recordingPublisher{err: tc.pubErr}
+			p := &Reprocessor{
+				Publisher: pub,
+				DryRun:    tc.dryRun,
+				Rate:      Rate{Count: 1000, Period: time.Second},
+				Transform: func(_ context.Context, m DLQMessage) (DLQMessage, error) {
+					if strings.Contains(string(m.Data), "skip") {
+						return m, ErrSkipMessage
+					}
+					m.Data = []byte(strings.TrimSuffix(string(m.Data), "}") + `,"fixed":true}`)
+					return m, nil
+				},
+			}
+			stats, err := p.Run(context.Background(), SliceIterator(msgs))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Run() error = %v, expectErr %v", err, tc.expectErr)
+			}
+			if stats != tc.want {
+				t.Errorf("stats = %+v, want %+v", stats, tc.want)
+			}
+			var reprocessErr *ReprocessError
+			if tc.expectErr && (!errors.As(err, &reprocessErr) || string(reprocessErr.Message.Data) != `{"id":1}`) {
+				t.Errorf("expected the failed message in the error, got %v", err)
+			}
+			if strings.Join(pub.published, "\n") != strings.Join(tc.published, "\n") {
+				t.Errorf("published %q, want %q", pub.published, tc.published)
+			}
+		})
+	}
+}
+
+func TestReprocessorServeHTTP(t *testing.T) {
+	pub := &recordingPublisher{}
+	p := &Reprocessor{Publisher: pub}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":1}`))
+	req.Header.Set("X-Topic", "payments.dlq")
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || len(pub.published) != 1 || pub.published[0] != `payments {"id":1}` {
+		t.Fatalf("unexpected result %d %q", rec.Code, pub.published)
+	}
+
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without X-Topic, got %d", rec.Code)
+	}
+}
+
+func TestSubscribeNATS(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {}\r\n")
+		rd := bufio.NewReader(conn)
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "PING"):
+				fmt.Fprint(conn, "PONG\r\n")
+			case strings.HasPrefix(line, "SUB orders.dlq workers 1"):
+				fmt.Fprint(conn, "MSG orders.dlq 1 5\r\nfirst\r\nPING\r\nMSG orders.dlq 1 _INBOX.1 6\r\nsecond\r\n")
+			}
+		}
+	}()
+
+	it := SubscribeNATS(context.Background(), ln.Addr().String(), NATSOptions{}, "orders.dlq", "workers", 100*time.Millisecond)
+	var got []string
+	for it.Next() {
+		got = append(got, it.Value().Topic+" "+string(it.Value().Data))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("expected the idle timeout to end iteration cleanly, got %v", err)
+	}
+	if strings.Join(got, ",") != "orders.dlq first,orders.dlq second" {
+		t.Errorf("unexpected messages %q", got)
+	}
+}
+
+func TestSubscribeNATSInvalidSize(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {}\r\n")
+		rd := bufio.NewReader(conn)
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "PING"):
+				fmt.Fprint(conn, "PONG\r\n")
+			case strings.HasPrefix(line, "SUB "):
+				fmt.Fprint(conn, "MSG orders.dlq 1 -1\r\n")
+			}
+		}
+	}()
+
+	it := SubscribeNATS(context.Background(), ln.Addr().String(), NATSOptions{}, "orders.dlq", "", 100*time.Millisecond)
+	if it.Next() {
+		t.Fatal("expected no message with a negative size")
+	}
+	if err := it.Err(); err == nil || !strings.Contains(err.Error(), "invalid nats message header") {
+		t.Errorf("err = %v, want an invalid header error", err)
+	}
+}
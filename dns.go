@@ -0,0 +1,284 @@
+package faas
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// DNSCache caches DNS lookups for outbound calls, honouring the TTLs of the
+// records returned by the cluster's nameserver. Failed lookups for names
+// which do not exist are cached for NegativeTTL. Concurrent lookups for
+// the same host share a single query. It is safe for concurrent use.
+//
+// Plug it into an http.Transport with:
+//
+//	transport.DialContext = cache.DialContext(&net.Dialer{Timeout: 5 * time.Second})
+type DNSCache struct {
+	// MinTTL and MaxTTL clamp record TTLs. They default to one second and
+	// five minutes. MaxTTL is also used for names from /etc/hosts.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+	// NegativeTTL is how long names which do not exist are cached. Defaults
+	// to five seconds.
+	NegativeTTL time.Duration
+	// MaxEntries bounds how many hosts are cached. When it is reached
+	// expired entries are removed, then arbitrary ones. Defaults to 1024.
+	MaxEntries int
+
+	mu       sync.Mutex
+	entries  map[string]*dnsEntry
+	resolver *net.Resolver
+	// nameserver overrides the system nameserver in tests
+	nameserver string
+}
+
+type dnsEntry struct {
+	ready   chan struct{}
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// NewDNSCache returns an empty DNSCache using the system DNS configuration.
+func NewDNSCache() *DNSCache {
+	c := &DNSCache{entries: make(map[string]*dnsEntry)}
+	dialer := &net.Dialer{}
+	c.resolver = &net.Resolver{
+		PreferGo: true,
+		// record the TTLs in the responses read by the resolver
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if c.nameserver != "" {
+				address = c.nameserver
+			}
+			conn, err := dialer.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			obs, ok := ctx.Value(ttlObserverKey{}).(*ttlObserver)
+			if udp, isUDP := conn.(*net.UDPConn); ok && isUDP {
+				// the resolver relies on UDP conns implementing
+				// net.PacketConn so the wrapper must too
+				return &ttlSniffConn{UDPConn: udp, obs: obs}, nil
+			}
+			return conn, nil
+		},
+	}
+	return c
+}
+
+// LookupIPAddr returns the addresses of host from the cache, resolving it
+// when missing or expired.
+func (c *DNSCache) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	c.mu.Lock()
+	e, ok := c.entries[host]
+	if ok {
+		select {
+		case <-e.ready:
+			if time.Now().After(e.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if ok {
+		c.mu.Unlock()
+		select {
+		case <-e.ready:
+			return e.addrs, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if _, exists := c.entries[host]; !exists {
+		c.evictLocked()
+	}
+	e = &dnsEntry{ready: make(chan struct{})}
+	c.entries[host] = e
+	c.mu.Unlock()
+
+	addrs, ttl, err := c.resolve(ctx, host)
+	e.addrs, e.err, e.expires = addrs, err, time.Now().Add(ttl)
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		// only cache answers, not timeouts or cancelled lookups
+		c.mu.Lock()
+		if c.entries[host] == e {
+			delete(c.entries, host)
+		}
+		c.mu.Unlock()
+	}
+	close(e.ready)
+	return addrs, err
+}
+
+// evictLocked makes room for a new entry once the cache is full. Lookups in
+// progress are kept so their waiters share the answer.
+func (c *DNSCache) evictLocked() {
+	limit := c.MaxEntries
+	if limit <= 0 {
+		limit = 1024
+	}
+	if len(c.entries) < limit {
+		return
+	}
+	now := time.Now()
+	for host, e := range c.entries {
+		select {
+		case <-e.ready:
+			if now.After(e.expires) {
+				delete(c.entries, host)
+			}
+		default:
+		}
+	}
+	for host, e := range c.entries {
+		if len(c.entries) < limit {
+			return
+		}
+		select {
+		case <-e.ready:
+			delete(c.entries, host)
+		default:
+		}
+	}
+}
+
+func (c *DNSCache) resolve(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	minTTL, maxTTL, negTTL := c.MinTTL, c.MaxTTL, c.NegativeTTL
+	if minTTL <= 0 {
+		minTTL = time.Second
+	}
+	if maxTTL <= 0 {
+		maxTTL = 5 * time.Minute
+	}
+	if negTTL <= 0 {
+		negTTL = 5 * time.Second
+	}
+
+	obs := &ttlObserver{min: math.MaxUint32}
+	addrs, err := c.resolver.LookupIPAddr(context.WithValue(ctx, ttlObserverKey{}, obs), host)
+	if err != nil {
+		return nil, negTTL, err
+	}
+	ttl := maxTTL
+	if seen, ok := obs.ttl(); ok {
+		ttl = min(max(seen, minTTL), maxTTL)
+	}
+	return addrs, ttl, nil
+}
+
+// DialContext returns a dial function for http.Transport which resolves
+// hosts through the cache. Addresses are tried in turn, racing IPv4 and
+// IPv6 after dialer.FallbackDelay as net.Dialer does.
+func (c *DNSCache) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return dualStackDialer{dialer: dialer, lookup: c.LookupIPAddr, fallbackDelay: dialer.FallbackDelay}.DialContext
+}
+
+func matchesNetwork(network string, ip net.IP) bool {
+	switch network {
+	case "tcp4", "udp4":
+		return ip.To4() != nil
+	case "tcp6", "udp6":
+		return ip.To4() == nil
+	default:
+		return true
+	}
+}
+
+type ttlObserverKey struct{}
+
+// ttlObserver records the lowest TTL seen in the responses for a lookup.
+type ttlObserver struct {
+	mu   sync.Mutex
+	min  uint32
+	seen bool
+}
+
+func (o *ttlObserver) observe(ttl uint32) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.min = min(o.min, ttl)
+	o.seen = true
+}
+
+func (o *ttlObserver) ttl() (time.Duration, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return time.Duration(o.min) * time.Second, o.seen
+}
+
+type ttlSniffConn struct {
+	*net.UDPConn
+	obs *ttlObserver
+}
+
+func (c *ttlSniffConn) Read(b []byte) (int, error) {
+	n, err := c.UDPConn.Read(b)
+	if n > 0 {
+		if ttl, ok := minAnswerTTL(b[:n]); ok {
+			c.obs.observe(ttl)
+		}
+	}
+	return n, err
+}
+
+// minAnswerTTL returns the lowest TTL of the A, AAAA and CNAME records in
+// the answer section of a DNS response.
+func minAnswerTTL(msg []byte) (uint32, bool) {
+	if len(msg) < 12 {
+		return 0, false
+	}
+	qd := int(binary.BigEndian.Uint16(msg[4:]))
+	an := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+	for i := 0; i < qd; i++ {
+		if off = skipDNSName(msg, off); off < 0 || off+4 > len(msg) {
+			return 0, false
+		}
+		off += 4 // type and class
+	}
+
+	ttl, found := uint32(math.MaxUint32), false
+	for i := 0; i < an; i++ {
+		if off = skipDNSName(msg, off); off < 0 || off+10 > len(msg) {
+			return 0, false
+		}
+		typ := binary.BigEndian.Uint16(msg[off:])
+		rrTTL := binary.BigEndian.Uint32(msg[off+4:])
+		off += 10 + int(binary.BigEndian.Uint16(msg[off+8:]))
+		if off > len(msg) {
+			return 0, false
+		}
+		// A, CNAME and AAAA
+		if typ == 1 || typ == 5 || typ == 28 {
+			ttl, found = min(ttl, rrTTL), true
+		}
+	}
+	return ttl, found
+}
+
+// skipDNSName returns the offset after the name at off, or -1 if the
+// message is malformed.
+func skipDNSName(msg []byte, off int) int {
+	for off < len(msg) {
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			return off + 1
+		case l&0xC0 == 0xC0:
+			// compression pointer ends the name
+			if off+2 > len(msg) {
+				return -1
+			}
+			return off + 2
+		default:
+			off += 1 + l
+		}
+	}
+	return -1
+}
@@ -0,0 +1,151 @@
+package faas
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDNS answers A queries for api.test. with 127.0.0.1 and the given TTL
+// and NXDOMAIN for everything else.
+func fakeDNS(t *testing.T, ttl uint32) (string, *atomic.Int32) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var queries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			queries.Add(1)
+			q := buf[:n]
+			end := skipDNSName(q, 12)
+			name := string(q[12:end])
+			qtype := binary.BigEndian.Uint16(q[end:])
+
+			resp := append([]byte(nil), q[:end+4]...)
+			binary.BigEndian.PutUint16(resp[2:], 0x8180)
+			binary.BigEndian.PutUint16(resp[10:], 0) // no additional records
+			switch {
+			case name != "\x03api\x04test\x00":
+				resp[3] |= 3 // NXDOMAIN
+			case qtype == 1:
+				binary.BigEndian.PutUint16(resp[6:], 1)
+				rr := []byte{0xC0, 12, 0, 1, 0, 1, 0, 0, 0, 0, 0, 4, 127, 0, 0, 1}
+				binary.BigEndian.PutUint32(rr[6:], ttl)
+				resp = append(resp, rr...)
+			}
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), &queries
+}
+
+func TestDNSCache(t *testing.T) {
+	addr, queries := fakeDNS(t, 1)
+	c := NewDNSCache()
+	c.nameserver = addr
+	c.MinTTL = 10 * time.Millisecond
+	ctx := context.Background()
+
+	addrs, err := c.LookupIPAddr(ctx, "api.test.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0].IP.String() != "127.0.0.1" {
+		t.Fatalf("unexpected addresses %v", addrs)
+	}
+	sent := queries.Load()
+	if _, err := c.LookupIPAddr(ctx, "api.test."); err != nil {
+		t.Fatal(err)
+	}
+	if queries.Load() != sent {
+		t.Error("expected the second lookup to be served from the cache")
+	}
+
+	// the record TTL of one second is respected
+	c.mu.Lock()
+	expires := time.Until(c.entries["api.test."].expires)
+	c.mu.Unlock()
+	if expires <= 0 || expires > time.Second {
+		t.Errorf("expected the entry to expire within the record TTL, got %v", expires)
+	}
+
+	if _, err := c.LookupIPAddr(ctx, "missing.test."); err == nil {
+		t.Fatal("expected NXDOMAIN to fail")
+	}
+	sent = queries.Load()
+	if _, err := c.LookupIPAddr(ctx, "missing.test."); err == nil {
+		t.Fatal("expected cached NXDOMAIN to fail")
+	}
+	if queries.Load() != sent {
+		t.Error("expected the negative answer to be cached")
+	}
+}
+
+func TestDNSCacheMaxEntries(t *testing.T) {
+	addr, _ := fakeDNS(t, 60)
+	c := NewDNSCache()
+	c.nameserver = addr
+	c.MaxEntries = 2
+	ctx := context.Background()
+
+	for _, host := range []string{"a.test.", "b.test.", "c.test.", "api.test."} {
+		_, _ = c.LookupIPAddr(ctx, host)
+	}
+	c.mu.Lock()
+	n, ok := len(c.entries), c.entries["api.test."] != nil
+	c.mu.Unlock()
+	if n > 2 || !ok {
+		t.Errorf("got %d entries, latest cached %v, want at most 2 including the latest", n, ok)
+	}
+}
+
+func TestDNSCacheDialContext(t *testing.T) {
+	addr, _ := fakeDNS(t, 60)
+	c := NewDNSCache()
+	c.nameserver = addr
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	dial := c.DialContext(&net.Dialer{Timeout: time.Second})
+	conn, err := dial(context.Background(), "tcp", net.JoinHostPort("api.test.", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if _, err := dial(context.Background(), "tcp6", net.JoinHostPort("api.test.", port)); err == nil {
+		t.Error("expected no IPv6 address to be found")
+	}
+}
+
+func TestMinAnswerTTL(t *testing.T) {
+	msg := []byte{0, 1, 0x81, 0x80, 0, 1, 0, 2, 0, 0, 0, 0}
+	msg = append(msg, 3, 'w', 'w', 'w', 0, 0, 1, 0, 1)
+	// CNAME with TTL 300 then A with TTL 60
+	msg = append(msg, 0xC0, 12, 0, 5, 0, 1, 0, 0, 1, 44, 0, 2, 0xC0, 12)
+	msg = append(msg, 0xC0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 1, 2, 3, 4)
+
+	if ttl, ok := minAnswerTTL(msg); !ok || ttl != 60 {
+		t.Errorf("expected TTL 60, got %d %v", ttl, ok)
+	}
+	if _, ok := minAnswerTTL(msg[:20]); ok {
+		t.Error("expected truncated message to be rejected")
+	}
+}
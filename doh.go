@@ -0,0 +1,215 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DNS record types supported by DoHResolver.Lookup.
+const (
+	DNSTypeA     = "A"
+	DNSTypeAAAA  = "AAAA"
+	DNSTypeCNAME = "CNAME"
+	DNSTypeMX    = "MX"
+	DNSTypeTXT   = "TXT"
+	DNSTypeNS    = "NS"
+)
+
+var dnsTypeCodes = map[string]int{
+	DNSTypeA:     1,
+	DNSTypeNS:    2,
+	DNSTypeCNAME: 5,
+	DNSTypeMX:    15,
+	DNSTypeTXT:   16,
+	DNSTypeAAAA:  28,
+}
+
+// ErrDNSSECUnvalidated is returned by a DoHResolver with RequireDNSSEC when
+// the answer was not validated, typically because the zone is not signed.
+var ErrDNSSECUnvalidated = errors.New("dns answer is not dnssec validated")
+
+// DNSRecord is a record returned by DoHResolver.Lookup. Data is in
+// presentation format, e.g. "10 mail.example.com." for MX records.
+type DNSRecord struct {
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	TTL  Duration `json:"ttl"`
+	Data string   `json:"data"`
+}
+
+// DoHResolver looks up names with DNS over HTTPS using the JSON API of
+// public resolvers, for lookups of user supplied domains which should not
+// depend on, or be visible to, the cluster's resolver. It is safe for
+// concurrent use.
+type DoHResolver struct {
+	// URL defaults to https://cloudflare-dns.com/dns-query. Google's
+	// https://dns.google/resolve works too.
+	URL string
+	// Client defaults to SharedHTTPClient.
+	Client *http.Client
+	// RequireDNSSEC fails lookups with ErrDNSSECUnvalidated unless the
+	// resolver validated the answer with DNSSEC.
+	RequireDNSSEC bool
+}
+
+type dohResponse struct {
+	Status int  `json:"Status"`
+	AD     bool `json:"AD"`
+	Answer []struct {
+		Name string `json:"name"`
+		Type int    `json:"type"`
+		TTL  int    `json:"TTL"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// Lookup returns the records of the given type for name, such as
+// DNSTypeTXT. Records of other types in the answer, such as the CNAMEs
+// which were followed, are left out. Names which do not exist return a
+// *net.DNSError with IsNotFound set.
+func (r *DoHResolver) Lookup(ctx context.Context, name, recordType string) ([]DNSRecord, error) {
+	code, ok := dnsTypeCodes[recordType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported dns record type %q", recordType)
+	}
+	u := defaultString(r.URL, "https://cloudflare-dns.com/dns-query") + "?" + url.Values{
+		"name": {name},
+		"type": {recordType},
+		"do":   {strconv.FormatBool(r.RequireDNSSEC)},
+	}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+	client := r.Client
+	if client == nil {
+		client = SharedHTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: name, IsTemporary: true}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySnippet))
+		return nil, &StatusError{Method: req.Method, URL: req.URL.Redacted(), StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(snippet))}
+	}
+	var answer dohResponse
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("decoding dns answer: %w", err)
+	}
+
+	switch answer.Status {
+	case 0:
+	case 3:
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	case 2:
+		// SERVFAIL, which validating resolvers also return for bogus
+		// DNSSEC signatures
+		return nil, &net.DNSError{Err: "server failure", Name: name, IsTemporary: true}
+	default:
+		return nil, &net.DNSError{Err: "rcode " + strconv.Itoa(answer.Status), Name: name}
+	}
+	if r.RequireDNSSEC && !answer.AD {
+		return nil, fmt.Errorf("%s: %w", name, ErrDNSSECUnvalidated)
+	}
+	var records []DNSRecord
+	for _, a := range answer.Answer {
+		if a.Type == code {
+			records = append(records, DNSRecord{Name: a.Name, Type: recordType, TTL: Duration(time.Duration(a.TTL) * time.Second), Data: a.Data})
+		}
+	}
+	return records, nil
+}
+
+// LookupIP returns the IPv4 and IPv6 addresses of host, looked up
+// concurrently. It fails only when both lookups fail.
+func (r *DoHResolver) LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	type result struct {
+		records []DNSRecord
+		err     error
+	}
+	v6 := make(chan result, 1)
+	go func() {
+		records, err := r.Lookup(ctx, host, DNSTypeAAAA)
+		v6 <- result{records, err}
+	}()
+	v4Records, v4Err := r.Lookup(ctx, host, DNSTypeA)
+	res := <-v6
+	if v4Err != nil && res.err != nil {
+		return nil, v4Err
+	}
+	var ips []net.IP
+	for _, rec := range append(v4Records, res.records...) {
+		if ip := net.ParseIP(rec.Data); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return ips, nil
+}
+
+// LookupTXT returns the TXT records of name, joining the strings of each
+// record as net.LookupTXT does.
+func (r *DoHResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, err := r.Lookup(ctx, name, DNSTypeTXT)
+	if err != nil {
+		return nil, err
+	}
+	txts := make([]string, 0, len(records))
+	for _, rec := range records {
+		txts = append(txts, joinTXT(rec.Data))
+	}
+	return txts, nil
+}
+
+// joinTXT joins the quoted strings of a TXT record, e.g. `"v=spf1 " "-all"`.
+func joinTXT(data string) string {
+	if !strings.HasPrefix(data, `"`) {
+		return data
+	}
+	var b strings.Builder
+	for rest := strings.TrimSpace(data); rest != ""; rest = strings.TrimSpace(rest) {
+		s, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			b.WriteString(rest)
+			break
+		}
+		unquoted, _ := strconv.Unquote(s)
+		b.WriteString(unquoted)
+		rest = rest[len(s):]
+	}
+	return b.String()
+}
+
+// LookupMX returns the MX records of name sorted by preference.
+func (r *DoHResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	records, err := r.Lookup(ctx, name, DNSTypeMX)
+	if err != nil {
+		return nil, err
+	}
+	mxs := make([]*net.MX, 0, len(records))
+	for _, rec := range records {
+		pref, host, ok := strings.Cut(rec.Data, " ")
+		n, err := strconv.ParseUint(pref, 10, 16)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid mx record %q", rec.Data)
+		}
+		mxs = append(mxs, &net.MX{Host: host, Pref: uint16(n)})
+	}
+	sort.SliceStable(mxs, func(i, j int) bool { return mxs[i].Pref < mxs[j].Pref })
+	return mxs, nil
+}
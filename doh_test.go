@@ -0,0 +1,100 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func dohServer(t *testing.T, answers map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/dns-json" {
+			http.Error(w, "bad accept", http.StatusBadRequest)
+			return
+		}
+		q := r.URL.Query()
+		body, ok := answers[q.Get("name")+" "+q.Get("type")]
+		if !ok {
+			body = `{"Status":3}`
+		}
+		w.Header().Set("Content-Type", "application/dns-json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDoHResolver(t *testing.T) {
+	srv := dohServer(t, map[string]string{
+		"example.com A":    `{"Status":0,"AD":true,"Answer":[{"name":"www.example.com","type":5,"TTL":60,"data":"example.com."},{"name":"example.com","type":1,"TTL":300,"data":"93.184.216.34"}]}`,
+		"example.com AAAA": `{"Status":0,"AD":true,"Answer":[{"name":"example.com","type":28,"TTL":300,"data":"2606:2800:220:1::"}]}`,
+		"example.com TXT":  `{"Status":0,"AD":true,"Answer":[{"name":"example.com","type":16,"TTL":300,"data":"\"v=spf1 \" \"-all\""},{"name":"example.com","type":16,"TTL":300,"data":"\"verify=abc\""}]}`,
+		"example.com MX":   `{"Status":0,"AD":true,"Answer":[{"name":"example.com","type":15,"TTL":300,"data":"20 backup.example.com."},{"name":"example.com","type":15,"TTL":300,"data":"10 mail.example.com."}]}`,
+		"unsigned.com A":   `{"Status":0,"AD":false,"Answer":[{"name":"unsigned.com","type":1,"TTL":300,"data":"1.2.3.4"}]}`,
+		"bogus.com A":      `{"Status":2}`,
+	})
+	r := &DoHResolver{URL: srv.URL}
+	ctx := context.Background()
+
+	ips, err := r.LookupIP(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 2 || ips[0].String() != "93.184.216.34" || ips[1].String() != "2606:2800:220:1::" {
+		t.Errorf("LookupIP() = %v", ips)
+	}
+
+	records, err := r.Lookup(ctx, "example.com", DNSTypeA)
+	if err != nil || len(records) != 1 || records[0].TTL.String() != "5m" {
+		t.Errorf("Lookup(A) = %+v, %v, want only the A record", records, err)
+	}
+
+	txts, err := r.LookupTXT(ctx, "example.com")
+	if err != nil || strings.Join(txts, "|") != "v=spf1 -all|verify=abc" {
+		t.Errorf("LookupTXT() = %q, %v", txts, err)
+	}
+
+	mxs, err := r.LookupMX(ctx, "example.com")
+	if err != nil || len(mxs) != 2 || mxs[0].Host != "mail.example.com." || mxs[0].Pref != 10 {
+		t.Errorf("LookupMX() = %+v, %v", mxs, err)
+	}
+
+	tests := []struct {
+		name     string
+		resolver *DoHResolver
+		host     string
+		check    func(error) bool
+	}{
+		{name: "not found", resolver: r, host: "missing.com", check: func(err error) bool {
+			var dnsErr *net.DNSError
+			return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+		}},
+		{name: "server failure", resolver: r, host: "bogus.com", check: func(err error) bool {
+			var dnsErr *net.DNSError
+			return errors.As(err, &dnsErr) && dnsErr.IsTemporary
+		}},
+		{name: "dnssec required", resolver: &DoHResolver{URL: srv.URL, RequireDNSSEC: true}, host: "unsigned.com", check: func(err error) bool {
+			return errors.Is(err, ErrDNSSECUnvalidated)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.resolver.Lookup(ctx, tt.host, DNSTypeA)
+			if !tt.check(err) {
+				t.Errorf("Lookup() error = %v", err)
+			}
+		})
+	}
+
+	if _, err := r.Lookup(ctx, "example.com", "SRV"); err == nil {
+		t.Error("unsupported type returned no error")
+	}
+	if _, err := (&DoHResolver{URL: srv.URL, RequireDNSSEC: true}).Lookup(ctx, "example.com", DNSTypeA); err != nil {
+		t.Errorf("validated answer with RequireDNSSEC = %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySnippet is how much of a non-2xx response body is kept in a
+// StatusError.
+const maxErrorBodySnippet = 1024
+
+// StatusError is returned by DoJSON for non-2xx responses.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Body is the start of the response body, which usually explains the
+	// failure.
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// DoJSON sends reqBody, when not nil, as JSON and decodes a successful JSON
+// response into respBody, when not nil. Non-2xx responses are returned as a
+// *StatusError. A nil client uses SharedHTTPClient.
+func DoJSON(ctx context.Context, client *http.Client, method, url string, reqBody, respBody any) error {
+	return doJSON(ctx, client, method, url, reqBody, respBody)
+}
+func doJSON(ctx context.Context, client *http.Client, method, url string, reqBody, respBody any) error {
+	if client == nil {
+		client = SharedHTTPClient()
+	}
+	var body io.Reader
+	if reqBody != nil {
+		js, err := json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("encoding request body: %w", err)
+		}
+		body = bytes.NewReader(js)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySnippet))
+		return &StatusError{
+			Method:     method,
+			URL:        req.URL.Redacted(),
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(snippet)),
+		}
+	}
+	if respBody == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+		if errors.Is(err, io.EOF) {
+			// an empty body, e.g. 204 No Content, leaves respBody unchanged
+			return nil
+		}
+		return fmt.Errorf("decoding %s %s response: %w", method, req.URL.Redacted(), err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDoJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			if r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			var in map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&in)
+			_ = WriteJSON(w, http.StatusOK, Map{"echo": in["name"]}, nil)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/invalid":
+			_, _ = w.Write([]byte("<html>"))
+		default:
+			_ = writeJSONError(w, Error{Status: "Not Found", Reason: "no such widget", Code: http.StatusNotFound})
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	var out struct {
+		Echo string `json:"echo"`
+	}
+	if err := DoJSON(ctx, srv.Client(), http.MethodPost, srv.URL+"/echo", Map{"name": "gopher"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Echo != "gopher" {
+		t.Errorf("expected echo gopher, got %q", out.Echo)
+	}
+
+	if err := DoJSON(ctx, srv.Client(), http.MethodDelete, srv.URL+"/empty", nil, &out); err != nil {
+		t.Errorf("expected empty response to succeed, got %v", err)
+	}
+	if err := DoJSON(ctx, srv.Client(), http.MethodGet, srv.URL+"/invalid", nil, &out); err == nil {
+		t.Error("expected invalid JSON to fail")
+	}
+
+	err := DoJSON(ctx, srv.Client(), http.MethodGet, srv.URL+"/missing", nil, &out)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected a StatusError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusNotFound || statusErr.Body != `{"status":"Not Found","reason":"no such widget","code":404}` {
+		t.Errorf("unexpected error %+v", statusErr)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := DoJSON(cancelled, srv.Client(), http.MethodGet, srv.URL+"/echo", nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
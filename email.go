@@ -0,0 +1,226 @@
+package faas
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// maxEmailBytes is the largest raw email ParseEmail will read.
+const maxEmailBytes = 25 << 20 // 25MB, the common provider limit
+
+// Email is a parsed inbound email.
+type Email struct {
+	From        []*mail.Address
+	To          []*mail.Address
+	Cc          []*mail.Address
+	ReplyTo     []*mail.Address
+	Subject     string
+	MessageID   string
+	Date        time.Time
+	Header      mail.Header
+	Text        string
+	HTML        string
+	Attachments []EmailAttachment
+	// Raw is the original message, kept for signature verification.
+	Raw []byte
+}
+
+// EmailAttachment is a decoded attachment or inline part.
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Inline      bool
+	Data        []byte
+}
+
+// DKIMVerifier verifies the DKIM signatures of a raw message. The standard
+// library has no DKIM support so an implementation, such as one wrapping
+// github.com/emersion/go-msgauth, is supplied by the caller.
+type DKIMVerifier func(raw []byte) error
+
+// ParseEmail parses a raw RFC 5322 message, decoding MIME parts, transfer
+// encodings and encoded header words.
+func ParseEmail(r io.Reader) (*Email, error) {
+	return parseEmail(r)
+}
+func parseEmail(r io.Reader) (*Email, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, maxEmailBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > maxEmailBytes {
+		return nil, fmt.Errorf("email must not be larger than %d bytes", maxEmailBytes)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email: %w", err)
+	}
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	email := &Email{
+		Subject:   subject,
+		MessageID: strings.Trim(msg.Header.Get("Message-Id"), "<>"),
+		Header:    msg.Header,
+		Raw:       raw,
+	}
+	email.From = addressList(msg.Header, "From")
+	email.To = addressList(msg.Header, "To")
+	email.Cc = addressList(msg.Header, "Cc")
+	email.ReplyTo = addressList(msg.Header, "Reply-To")
+	if date, err := msg.Header.Date(); err == nil {
+		email.Date = date
+	}
+
+	if err := email.parsePart(msg.Header, msg.Body); err != nil {
+		return nil, err
+	}
+	return email, nil
+}
+
+// VerifyDKIM runs verify against the raw message.
+func (e *Email) VerifyDKIM(verify DKIMVerifier) error {
+	if verify == nil {
+		return errors.New("no DKIM verifier configured")
+	}
+	return verify(e.Raw)
+}
+
+// ReadInboundEmail reads an email posted by an inbound mail webhook. Raw
+// message bodies are parsed directly, while form posts are searched for the
+// raw MIME fields used by common providers ("email" for SendGrid, "body-mime"
+// for Mailgun).
+func ReadInboundEmail(r *http.Request) (*Email, error) {
+	return readInboundEmail(r)
+}
+func readInboundEmail(r *http.Request) (*Email, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data", "application/x-www-form-urlencoded":
+		r.Body = http.MaxBytesReader(nil, r.Body, maxEmailBytes)
+		if err := r.ParseMultipartForm(1 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return nil, err
+		}
+		for _, field := range []string{"email", "body-mime"} {
+			if raw := r.FormValue(field); raw != "" {
+				return parseEmail(strings.NewReader(raw))
+			}
+		}
+		return nil, errors.New("body does not contain a raw email field")
+	default:
+		return parseEmail(r.Body)
+	}
+}
+
+func addressList(h mail.Header, key string) []*mail.Address {
+	addrs, err := h.AddressList(key)
+	if err != nil {
+		return nil
+	}
+	return addrs
+}
+
+// partHeader is satisfied by both mail.Header and textproto.MIMEHeader.
+type partHeader interface {
+	Get(key string) string
+}
+
+func (e *Email) parsePart(h partHeader, body io.Reader) error {
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid multipart email: %w", err)
+			}
+			if err := e.parsePart(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(transferDecoder(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("invalid email part: %w", err)
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if dec, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+		filename = dec
+	}
+
+	isBody := disposition != "attachment" && filename == ""
+	switch {
+	case isBody && mediaType == "text/plain" && e.Text == "":
+		e.Text = decodeCharset(params["charset"], data)
+	case isBody && mediaType == "text/html" && e.HTML == "":
+		e.HTML = decodeCharset(params["charset"], data)
+	default:
+		e.Attachments = append(e.Attachments, EmailAttachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			ContentID:   strings.Trim(h.Get("Content-Id"), "<>"),
+			Inline:      disposition == "inline",
+			Data:        data,
+		})
+	}
+	return nil
+}
+
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// decodeCharset converts latin-1 bodies to UTF-8. Other charsets are
+// returned unchanged as the standard library does not ship their tables.
+func decodeCharset(charset string, data []byte) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		return string(data)
+	}
+}
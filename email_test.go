@@ -0,0 +1,103 @@
+package faas
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testEmail = "From: \"Jane\" <jane@example.com>\r\n" +
+	"To: inbox@example.org\r\n" +
+	"Subject: =?UTF-8?B?SGVsbG8g8J+Riw==?=\r\n" +
+	"Message-ID: <abc@example.com>\r\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"caf=C3=A9\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<p>caf=E9</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/plain; name=\"notes.txt\"\r\n" +
+	"Content-Disposition: attachment; filename=\"notes.txt\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"aGVsbG8g\r\n" +
+	"d29ybGQ=\r\n" +
+	"--outer--\r\n"
+
+func TestParseEmail(t *testing.T) {
+	email, err := parseEmail(strings.NewReader(testEmail))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email.Subject != "Hello 👋" {
+		t.Errorf("unexpected subject %q", email.Subject)
+	}
+	if len(email.From) != 1 || email.From[0].Address != "jane@example.com" || email.From[0].Name != "Jane" {
+		t.Errorf("unexpected from %v", email.From)
+	}
+	if email.MessageID != "abc@example.com" || email.Date.Year() != 2006 {
+		t.Errorf("unexpected message id %q or date %v", email.MessageID, email.Date)
+	}
+	if email.Text != "café" {
+		t.Errorf("unexpected text %q", email.Text)
+	}
+	if email.HTML != "<p>café</p>" {
+		t.Errorf("unexpected html %q", email.HTML)
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Filename != "notes.txt" ||
+		string(email.Attachments[0].Data) != "hello world" {
+		t.Errorf("unexpected attachments %+v", email.Attachments)
+	}
+
+	verifyErr := errors.New("bad signature")
+	if err := email.VerifyDKIM(func([]byte) error { return verifyErr }); err != verifyErr {
+		t.Errorf("expected verifier error, got %v", err)
+	}
+}
+
+func TestReadInboundEmail(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "raw message", contentType: "message/rfc822", body: testEmail},
+		{
+			name:        "mailgun form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        url.Values{"body-mime": {testEmail}}.Encode(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+
+			email, err := readInboundEmail(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if email.Text != "café" {
+				t.Errorf("unexpected text %q", email.Text)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package faas
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// ErrorCode is a machine readable error code. The predefined codes map to
+// HTTP statuses, other codes map to 500 unless a status is set with
+// WithStatus.
+type ErrorCode string
+
+// Predefined error codes.
+const (
+	CodeInvalidArgument  ErrorCode = "invalid_argument"
+	CodeUnauthenticated  ErrorCode = "unauthenticated"
+	CodePermissionDenied ErrorCode = "permission_denied"
+	CodeNotFound         ErrorCode = "not_found"
+	CodeConflict         ErrorCode = "conflict"
+	CodeTooLarge         ErrorCode = "too_large"
+	CodeRateLimited      ErrorCode = "rate_limited"
+	CodeInternal         ErrorCode = "internal"
+	CodeUnavailable      ErrorCode = "unavailable"
+	CodeTimeout          ErrorCode = "timeout"
+)
+
+var codeStatus = map[ErrorCode]int{
+	CodeInvalidArgument:  http.StatusBadRequest,
+	CodeUnauthenticated:  http.StatusUnauthorized,
+	CodePermissionDenied: http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeConflict:         http.StatusConflict,
+	CodeTooLarge:         http.StatusRequestEntityTooLarge,
+	CodeRateLimited:      http.StatusTooManyRequests,
+	CodeInternal:         http.StatusInternalServerError,
+	CodeUnavailable:      http.StatusServiceUnavailable,
+	CodeTimeout:          http.StatusGatewayTimeout,
+}
+
+// AppError is an error carrying everything needed to respond to a request:
+// an HTTP status, a machine readable code, a message for the caller,
+// optional fields and the underlying cause. The stack where it was created
+// is captured for logging.
+type AppError struct {
+	Code    ErrorCode
+	Message string
+	Fields  map[string]any
+	Err     error
+
+	status int
+	stack  []uintptr
+}
+
+// E returns an AppError with the given code and message wrapping err, which
+// may be nil.
+func E(code ErrorCode, msg string, err error) *AppError {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &AppError{Code: code, Message: msg, Err: err, stack: pcs[:n]}
+}
+
+func (e *AppError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// WithStatus overrides the HTTP status derived from the code.
+func (e *AppError) WithStatus(status int) *AppError {
+	e.status = status
+	return e
+}
+
+// With adds a field returned to the caller, e.g. the ID of a missing item.
+func (e *AppError) With(key string, value any) *AppError {
+	if e.Fields == nil {
+		e.Fields = make(map[string]any)
+	}
+	e.Fields[key] = value
+	return e
+}
+
+// Status returns the HTTP status for the error.
+func (e *AppError) Status() int {
+	if e.status != 0 {
+		return e.status
+	}
+	if status, ok := codeStatus[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// StackTrace returns the stack captured by E, one "function file:line" per
+// line.
+func (e *AppError) StackTrace() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "%s %s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// WriteError writes err as a JSON error response. An AppError uses its
+// status, code, message and fields, and ValidationErrors become a 400
+// listing the invalid fields. For 5xx statuses and any other error only the
+// status text is returned so internals are not leaked; the full error is
+// logged instead and reported to DefaultErrorReporter.
+func WriteError(w http.ResponseWriter, err error) error {
+	return writeError(w, err)
+}
+func writeError(w http.ResponseWriter, err error) error {
+	var appErr *AppError
+	var validationErrs ValidationErrors
+	resp := Error{Code: http.StatusInternalServerError}
+	switch {
+	case errors.As(err, &appErr):
+		resp = Error{Code: appErr.Status(), Reason: appErr.Message, ErrorCode: string(appErr.Code), Fields: appErr.Fields}
+	case errors.As(err, &validationErrs):
+		fields := make(map[string]any, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields[fe.Field] = fe.Message
+		}
+		resp = Error{Code: http.StatusBadRequest, Reason: "validation failed", ErrorCode: string(CodeInvalidArgument), Fields: fields}
+	}
+
+	if resp.Code >= 500 {
+		attrs := []any{"error", err, "status", resp.Code}
+		if appErr != nil {
+			attrs = append(attrs, "stack", appErr.StackTrace())
+		}
+		slog.Error("request failed", attrs...)
+		reportError(w, resp.Code, err, appErr)
+		resp = Error{Code: resp.Code, ErrorCode: resp.ErrorCode}
+	}
+	resp.Status = http.StatusText(resp.Code)
+	return writeJSONError(w, resp)
+}
@@ -0,0 +1,81 @@
+package faas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAppError(t *testing.T) {
+	cause := errors.New("sql: no rows")
+	err := fmt.Errorf("loading order: %w", E(CodeNotFound, "order not found", cause).With("id", "o_1"))
+
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		t.Fatal("expected an AppError")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("expected the cause to be unwrapped")
+	}
+	if appErr.Status() != http.StatusNotFound || appErr.Error() != "order not found: sql: no rows" {
+		t.Errorf("unexpected error %d %q", appErr.Status(), appErr.Error())
+	}
+	if !strings.Contains(appErr.StackTrace(), "TestAppError") {
+		t.Errorf("expected the stack to include the caller, got %s", appErr.StackTrace())
+	}
+	if got := E("quota_exceeded", "quota exceeded", nil).WithStatus(http.StatusPaymentRequired).Status(); got != http.StatusPaymentRequired {
+		t.Errorf("expected custom status 402, got %d", got)
+	}
+	if got := E("quota_exceeded", "quota exceeded", nil).Status(); got != http.StatusInternalServerError {
+		t.Errorf("expected unknown codes to map to 500, got %d", got)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Error
+	}{
+		{
+			name: "client error",
+			err:  E(CodeNotFound, "order not found", nil).With("id", "o_1"),
+			want: Error{Status: "Not Found", Reason: "order not found", Code: 404, ErrorCode: "not_found", Fields: map[string]any{"id": "o_1"}},
+		},
+		{
+			name: "internal details hidden",
+			err:  E(CodeUnavailable, "db connection refused on 10.0.0.5", errors.New("dial tcp")).With("host", "10.0.0.5"),
+			want: Error{Status: "Service Unavailable", Code: 503, ErrorCode: "unavailable"},
+		},
+		{
+			name: "validation errors",
+			err:  ValidationErrors{{Field: "email", Message: "is required"}},
+			want: Error{Status: "Bad Request", Reason: "validation failed", Code: 400, ErrorCode: "invalid_argument", Fields: map[string]any{"email": "is required"}},
+		},
+		{
+			name: "plain error",
+			err:  errors.New("secret connection string"),
+			want: Error{Status: "Internal Server Error", Code: 500},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			if err := WriteError(rr, tc.err); err != nil {
+				t.Fatal(err)
+			}
+			var got Error
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if rr.Code != tc.want.Code || !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %d %+v, got %d %+v", tc.want.Code, tc.want, rr.Code, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package extract
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// extractDOCX reads the paragraphs of word/document.xml, starting a
+// section at each paragraph with a heading style.
+func extractDOCX(r io.ReaderAt, size int64, opts Options) (*Document, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid docx: %v", ErrUnsupported, err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if files["word/document.xml"] == nil {
+		return nil, fmt.Errorf("%w: zip is not a docx document", ErrUnsupported)
+	}
+	budget := opts.inflateBudget()
+	open := func(name string) (io.ReadCloser, error) {
+		f := files[name]
+		if f == nil {
+			return nil, nil
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{&limitReader{r: rc, n: budget}, rc}, nil
+	}
+
+	b := newBuilder(FormatDOCX, opts)
+	if err := readDOCXProperties(open, b.doc.Metadata); err != nil {
+		return nil, err
+	}
+	levels, err := readDOCXHeadingStyles(open)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := open("word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	var text strings.Builder
+	level := 0
+	inText := false
+	for !b.full() {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading docx document: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				text.Reset()
+				level = 0
+			case "pStyle":
+				level = levels[xmlAttr(t, "val")]
+			case "outlineLvl":
+				if n, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && n < 9 {
+					level = n + 1
+				}
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteByte('\t')
+			case "br", "cr":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if level > 0 {
+					b.section(Section{Heading: text.String(), Level: level})
+				} else {
+					b.paragraph(text.String())
+				}
+				text.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+	return b.done(), nil
+}
+
+// docxProperties maps the elements of docProps/core.xml and app.xml to
+// Metadata keys.
+var docxProperties = map[string]string{
+	"title": "title", "subject": "subject", "creator": "author", "keywords": "keywords",
+	"created": "created", "modified": "modified", "Application": "creator",
+}
+
+func readDOCXProperties(open func(string) (io.ReadCloser, error), metadata map[string]string) error {
+	for _, name := range []string{"docProps/core.xml", "docProps/app.xml"} {
+		rc, err := open(name)
+		if err != nil {
+			return err
+		}
+		if rc == nil {
+			continue
+		}
+		err = walkXML(rc, func(path []string, text string) {
+			if len(path) == 2 {
+				if key := docxProperties[path[1]]; key != "" && metadata[key] == "" {
+					metadata[key] = text
+				}
+			}
+		})
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+var headingStyleName = regexp.MustCompile(`(?i)^heading ([1-9])$`)
+
+// readDOCXHeadingStyles returns the heading level of the paragraph styles
+// of word/styles.xml, keyed by style ID. Styles are identified by their
+// name, which unlike the ID is not localised, or their outline level.
+func readDOCXHeadingStyles(open func(string) (io.ReadCloser, error)) (map[string]int, error) {
+	levels := map[string]int{"Title": 1}
+	for i := 1; i <= 9; i++ {
+		levels["Heading"+strconv.Itoa(i)] = i
+	}
+	rc, err := open("word/styles.xml")
+	if err != nil || rc == nil {
+		return levels, err
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	var id string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return levels, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading docx styles: %w", err)
+		}
+		t, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch t.Name.Local {
+		case "style":
+			id = ""
+			if xmlAttr(t, "type") == "paragraph" {
+				id = xmlAttr(t, "styleId")
+			}
+		case "name":
+			if m := headingStyleName.FindStringSubmatch(xmlAttr(t, "val")); m != nil && id != "" {
+				levels[id] = int(m[1][0] - '0')
+			} else if strings.EqualFold(xmlAttr(t, "val"), "title") && id != "" {
+				levels[id] = 1
+			}
+		case "outlineLvl":
+			if n, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && n < 9 && id != "" && levels[id] == 0 {
+				levels[id] = n + 1
+			}
+		}
+	}
+}
+
+// walkXML calls fn with the path of local element names and the text of
+// every element with text.
+func walkXML(r io.Reader, fn func(path []string, text string)) error {
+	dec := xml.NewDecoder(r)
+	var path []string
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if s := strings.TrimSpace(text.String()); s != "" {
+				fn(path, s)
+			}
+			text.Reset()
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
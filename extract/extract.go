@@ -0,0 +1,314 @@
+// Package extract pulls plain text out of PDF, DOCX and HTML documents, for
+// document processing and indexing functions:
+//
+//	doc, err := extract.Extract(r.Body, extract.Options{MaxBytes: 20 << 20})
+//	if errors.Is(err, extract.ErrTooLarge) {
+//		// reject the upload
+//	}
+//	for _, s := range doc.Sections {
+//		index(doc.Metadata["title"], s.Heading, s.Text)
+//	}
+//
+// The text is split into sections, at each heading for DOCX and HTML and
+// at each page for PDF. HTML is extracted while it is read, PDF and DOCX
+// need random access and are buffered up to Options.MaxBytes. Extraction is
+// best effort: layout, tables and images are dropped, and scanned PDFs
+// without a text layer yield no text.
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formats of the documents.
+const (
+	FormatPDF  = "pdf"
+	FormatDOCX = "docx"
+	FormatHTML = "html"
+	FormatText = "text"
+)
+
+var (
+	// ErrTooLarge is returned for documents larger than Options.MaxBytes,
+	// or whose compressed parts inflate past the limit.
+	ErrTooLarge = errors.New("document is too large")
+	// ErrUnsupported is returned for formats which cannot be extracted,
+	// including encrypted PDFs.
+	ErrUnsupported = errors.New("unsupported document format")
+)
+
+// Document is the text extracted from a document.
+type Document struct {
+	Format string `json:"format"`
+	// Metadata holds the document properties which are set, with the keys
+	// "title", "author", "subject", "keywords", "creator", "producer",
+	// "created", "modified" and, for HTML, "description" and "lang".
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Sections []Section         `json:"sections"`
+	// Pages is the page count of PDFs.
+	Pages int `json:"pages,omitempty"`
+	// Truncated is set when the text reached Options.MaxTextBytes and the
+	// rest of the document was skipped.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Section is a part of a document's text.
+type Section struct {
+	// Heading is the heading starting the section, empty for the text
+	// before the first heading and for PDF pages.
+	Heading string `json:"heading,omitempty"`
+	// Level is the heading level, 1 for the top level.
+	Level int `json:"level,omitempty"`
+	// Page is the 1-based page number of PDF sections.
+	Page int `json:"page,omitempty"`
+	// Text is the section's paragraphs separated by blank lines.
+	Text string `json:"text"`
+}
+
+// Text returns the whole text of the document, with the headings.
+func (d *Document) Text() string {
+	var b strings.Builder
+	for _, s := range d.Sections {
+		for _, part := range []string{s.Heading, s.Text} {
+			if part == "" {
+				continue
+			}
+			if b.Len() > 0 {
+				b.WriteString("\n\n")
+			}
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
+
+// Options limits the extraction.
+type Options struct {
+	// Format is one of the Format constants. Defaults to detecting it from
+	// the content.
+	Format string
+	// MaxBytes is the largest document accepted. Compressed parts may
+	// inflate to 10 times as much in total. Defaults to 32MB.
+	MaxBytes int64
+	// MaxTextBytes bounds the text extracted, the rest of the document is
+	// skipped and Truncated set. Defaults to 8MB.
+	MaxTextBytes int
+}
+
+func (o Options) maxBytes() int64 {
+	if o.MaxBytes <= 0 {
+		return 32 << 20
+	}
+	return o.MaxBytes
+}
+
+func (o Options) maxTextBytes() int {
+	if o.MaxTextBytes <= 0 {
+		return 8 << 20
+	}
+	return o.MaxTextBytes
+}
+
+// Extract reads a document from r and extracts its text. The format is
+// detected from the content unless set in opts.
+func Extract(r io.Reader, opts Options) (*Document, error) {
+	limit := opts.maxBytes()
+	br := bufio.NewReader(&limitReader{r: r, n: &limit})
+	format := opts.Format
+	if format == "" {
+		head, err := br.Peek(512)
+		if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+			return nil, err
+		}
+		format = detect(head)
+	}
+
+	switch format {
+	case FormatHTML:
+		return extractHTML(br, opts)
+	case FormatText:
+		return extractText(br, opts)
+	case FormatPDF, FormatDOCX:
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		if format == FormatPDF {
+			return extractPDF(data, opts)
+		}
+		return extractDOCX(bytes.NewReader(data), int64(len(data)), opts)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupported, format)
+}
+
+// detect returns the format of a document from its first bytes.
+func detect(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		// the zip may be another office format, which extractDOCX rejects
+		return FormatDOCX
+	}
+	switch ct := http.DetectContentType(head); {
+	case strings.HasPrefix(ct, "text/html"), strings.HasPrefix(ct, "text/xml"):
+		return FormatHTML
+	case strings.HasPrefix(ct, "text/plain"):
+		return FormatText
+	default:
+		return ct
+	}
+}
+
+// limitReader fails with ErrTooLarge once more than *n bytes are read,
+// unlike io.LimitReader which silently truncates. Readers sharing n share
+// the limit, which bounds the total inflated from a document's parts.
+type limitReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if *l.n < 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > *l.n+1 {
+		p = p[:*l.n+1]
+	}
+	n, err := l.r.Read(p)
+	*l.n -= int64(n)
+	if *l.n < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+// inflateBudget returns the limit on the bytes inflated from the
+// compressed parts of a document.
+func (o Options) inflateBudget() *int64 {
+	n := 10 * o.maxBytes()
+	return &n
+}
+
+// builder accumulates sections up to the text limit.
+type builder struct {
+	doc   *Document
+	max   int
+	size  int
+	cur   *Section
+	paras []string
+}
+
+func newBuilder(format string, opts Options) *builder {
+	return &builder{doc: &Document{Format: format, Metadata: map[string]string{}}, max: opts.maxTextBytes()}
+}
+
+// full reports whether the text limit is reached.
+func (b *builder) full() bool {
+	return b.doc.Truncated
+}
+
+// add counts s against the text limit, returning the part of it which
+// fits.
+func (b *builder) add(s string) string {
+	if b.doc.Truncated {
+		return ""
+	}
+	if b.size+len(s) > b.max {
+		s = truncateUTF8(s, b.max-b.size)
+		b.doc.Truncated = true
+	}
+	b.size += len(s)
+	return s
+}
+
+// paragraph adds a paragraph to the current section.
+func (b *builder) paragraph(text string) {
+	text = normalizeSpace(text)
+	if text == "" {
+		return
+	}
+	if text = b.add(text); text != "" {
+		b.paras = append(b.paras, text)
+	}
+}
+
+// section starts a new section, with a heading or for a page.
+func (b *builder) section(s Section) {
+	b.flush()
+	s.Heading = b.add(normalizeSpace(s.Heading))
+	b.cur = &s
+}
+
+func (b *builder) flush() {
+	if b.cur == nil && len(b.paras) == 0 {
+		return
+	}
+	s := Section{}
+	if b.cur != nil {
+		s = *b.cur
+	}
+	s.Text = strings.Join(b.paras, "\n\n")
+	b.doc.Sections = append(b.doc.Sections, s)
+	b.cur, b.paras = nil, nil
+}
+
+func (b *builder) done() *Document {
+	b.flush()
+	if b.doc.Sections == nil {
+		b.doc.Sections = []Section{}
+	}
+	for k, v := range b.doc.Metadata {
+		if v = normalizeSpace(v); v == "" {
+			delete(b.doc.Metadata, k)
+		} else {
+			b.doc.Metadata[k] = v
+		}
+	}
+	return b.doc
+}
+
+// normalizeSpace collapses runs of white space, including non-breaking
+// spaces, into single spaces.
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncateUTF8(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// extractText splits plain text into paragraphs at blank lines.
+func extractText(r io.Reader, opts Options) (*Document, error) {
+	b := newBuilder(FormatText, opts)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	var para []string
+	for sc.Scan() && !b.full() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			b.paragraph(strings.Join(para, " "))
+			para = para[:0]
+			continue
+		}
+		para = append(para, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	b.paragraph(strings.Join(para, " "))
+	return b.done(), nil
+}
@@ -0,0 +1,226 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExtractHTML(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<title>Release &amp; notes</title>
+	<meta name="description" content="What changed">
+	<style>h1 { color: red }</style>
+	<script>if (a < b && c > d) { document.write("<p>no</p>") }</script>
+</head>
+<body>
+	<!-- navigation <p>hidden</p> -->
+	<p>Intro   text with <b>bold</b> and&nbsp;entities &lt;ok&gt;.</p>
+	<h1>Version <em>2</em></h1>
+	<p>First<br>line</p>
+	<ul><li>one</li><li>two</li></ul>
+	<img src="a.png" alt="a diagram">
+	<h2 class='sub'>Fixes</h2>
+	<div>5 < 6 is true</div>
+	<noscript><p>enable js</p></noscript>
+</body>
+</html>`
+	doc, err := Extract(strings.NewReader(page), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format != FormatHTML {
+		t.Errorf("format = %q", doc.Format)
+	}
+	want := []Section{
+		{Text: "Intro text with bold and entities <ok>."},
+		{Heading: "Version 2", Level: 1, Text: "First\n\nline\n\none\n\ntwo\n\na diagram"},
+		{Heading: "Fixes", Level: 2, Text: "5 < 6 is true"},
+	}
+	assertSections(t, doc.Sections, want)
+	if doc.Metadata["title"] != "Release & notes" || doc.Metadata["description"] != "What changed" || doc.Metadata["lang"] != "en" {
+		t.Errorf("metadata = %v", doc.Metadata)
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	doc, err := Extract(strings.NewReader("first line\ncontinued\n\n\nsecond"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSections(t, doc.Sections, []Section{{Text: "first line continued\n\nsecond"}})
+}
+
+func TestExtractDOCX(t *testing.T) {
+	data := buildDOCX(t, map[string]string{
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:body>
+	<w:p><w:r><w:t>Preface</w:t></w:r></w:p>
+	<w:p><w:pPr><w:pStyle w:val="Berschrift1"/></w:pPr><w:r><w:t>Chapter</w:t></w:r><w:r><w:t xml:space="preserve"> one</w:t></w:r></w:p>
+	<w:p><w:r><w:t>Hello</w:t></w:r><w:r><w:tab/><w:t>world</w:t></w:r></w:p>
+	<w:tbl><w:tr><w:tc><w:p><w:r><w:t>cell</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+	<w:p><w:pPr><w:outlineLvl w:val="1"/></w:pPr><w:r><w:t>Details</w:t></w:r></w:p>
+	<w:p><w:r><w:t>End</w:t></w:r></w:p>
+</w:body>
+</w:document>`,
+		"word/styles.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+	<w:style w:type="paragraph" w:styleId="Berschrift1"><w:name w:val="heading 1"/></w:style>
+	<w:style w:type="character" w:styleId="Strong"><w:name w:val="Strong"/></w:style>
+</w:styles>`,
+		"docProps/core.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">
+	<dc:title>Handbook</dc:title>
+	<dc:creator>Ada</dc:creator>
+	<dcterms:created>2024-03-01T10:00:00Z</dcterms:created>
+</cp:coreProperties>`,
+	})
+	doc, err := Extract(bytes.NewReader(data), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format != FormatDOCX {
+		t.Errorf("format = %q", doc.Format)
+	}
+	assertSections(t, doc.Sections, []Section{
+		{Text: "Preface"},
+		{Heading: "Chapter one", Level: 1, Text: "Hello world\n\ncell"},
+		{Heading: "Details", Level: 2, Text: "End"},
+	})
+	if doc.Metadata["title"] != "Handbook" || doc.Metadata["author"] != "Ada" || doc.Metadata["created"] != "2024-03-01T10:00:00Z" {
+		t.Errorf("metadata = %v", doc.Metadata)
+	}
+
+	if _, err := Extract(bytes.NewReader(buildDOCX(t, map[string]string{"xl/workbook.xml": "<workbook/>"})), Options{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("xlsx err = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestExtractPDF(t *testing.T) {
+	page1 := "BT /F1 12 Tf 72 720 Td (Hello) Tj ( world) Tj 0 -14 Td (second \\(line\\)) Tj 0 -40 Td [(New) -300 (para) 20 (graph)] TJ ET"
+	// the second page uses a composite font with a ToUnicode map
+	page2 := "BT /F2 12 Tf 1 0 0 1 72 720 Tm <00010002> Tj ET BI /W 1 /H 1 ID \x00EI\xff EI q Q"
+	toUnicode := "1 begincodespacerange <0000> <ffff> endcodespacerange\n1 beginbfchar <0001> <00c9> endbfchar\n1 beginbfrange <0002> <0003> <0074> endbfrange"
+	data := buildPDF(t, []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [8 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type0 /Encoding /Identity-H /ToUnicode 9 0 R >>",
+		flateStream(t, page1),
+		flateStream(t, page2),
+		flateStream(t, toUnicode),
+		"<< /Title (Quarterly \\222report\\222) /Author <feff004a006f> /CreationDate (D:20240131120000+01'00') >>",
+	}, "/Info 10 0 R")
+
+	doc, err := Extract(bytes.NewReader(data), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format != FormatPDF || doc.Pages != 2 {
+		t.Errorf("format = %q, pages = %d", doc.Format, doc.Pages)
+	}
+	assertSections(t, doc.Sections, []Section{
+		{Page: 1, Text: "Hello world second (line)\n\nNew paragraph"},
+		{Page: 2, Text: "Ét"},
+	})
+	want := map[string]string{"title": "Quarterly ’report’", "author": "Jo", "created": "2024-01-31T12:00:00+01:00"}
+	for k, v := range want {
+		if doc.Metadata[k] != v {
+			t.Errorf("metadata %s = %q, want %q", k, doc.Metadata[k], v)
+		}
+	}
+
+	encrypted := buildPDF(t, []string{"<< /Type /Catalog >>", "<< /Filter /Standard >>"}, "/Encrypt 2 0 R")
+	if _, err := Extract(bytes.NewReader(encrypted), Options{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("encrypted err = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	large := "<p>" + strings.Repeat("word ", 1000) + "</p>"
+	if _, err := Extract(strings.NewReader(large), Options{MaxBytes: 1024}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+
+	doc, err := Extract(strings.NewReader(large+"<p>more</p>"), Options{MaxTextBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Truncated || len(doc.Text()) > 100 {
+		t.Errorf("truncated = %v, text is %d bytes", doc.Truncated, len(doc.Text()))
+	}
+
+	// a small file inflating to far more than its size
+	bomb := buildPDF(t, []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] >>",
+		"<< /Type /Page /Contents 4 0 R >>",
+		flateStream(t, strings.Repeat(" ", 1<<20)),
+	}, "")
+	if _, err := Extract(bytes.NewReader(bomb), Options{MaxBytes: int64(len(bomb))}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("bomb err = %v, want ErrTooLarge", err)
+	}
+
+	if _, err := Extract(bytes.NewReader([]byte("\x89PNG\r\n\x1a\n\x00\x00")), Options{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("png err = %v, want ErrUnsupported", err)
+	}
+}
+
+func assertSections(t *testing.T, got, want []Section) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d sections %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("section %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func buildDOCX(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildPDF numbers objects from 1, with the first as the catalog.
+func buildPDF(t *testing.T, objects []string, trailer string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	fmt.Fprintf(&buf, "xref\n0 1\n0000000000 65535 f \ntrailer\n<< /Size %d /Root 1 0 R %s >>\nstartxref\n0\n%%%%EOF\n", len(objects)+1, trailer)
+	return buf.Bytes()
+}
+
+func flateStream(t *testing.T, content string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(content))
+	zw.Close()
+	return fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", buf.Len(), buf.Bytes())
+}
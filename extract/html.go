@@ -0,0 +1,289 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"html"
+	"io"
+	"strings"
+)
+
+// htmlBlocks are the elements which end a paragraph.
+var htmlBlocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"dd": true, "details": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "header": true,
+	"hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"section": true, "summary": true, "table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// htmlSkipped are the elements whose content is not text.
+var htmlSkipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+	"iframe": true, "object": true, "canvas": true, "select": true, "textarea": true,
+}
+
+// htmlRaw are the elements whose content is not markup, which is read up
+// to their end tag.
+var htmlRaw = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+var htmlMeta = map[string]string{
+	"description": "description", "author": "author", "keywords": "keywords",
+	"generator": "creator", "dc.title": "title", "og:title": "title",
+}
+
+// extractHTML tokenizes HTML as it is read, starting a section at each
+// heading element.
+func extractHTML(r io.Reader, opts Options) (*Document, error) {
+	b := newBuilder(FormatHTML, opts)
+	br := bufio.NewReader(r)
+	var text strings.Builder
+	var heading *Section
+	skip := 0
+
+	endParagraph := func() {
+		if heading != nil {
+			return
+		}
+		b.paragraph(html.UnescapeString(text.String()))
+		text.Reset()
+	}
+	for !b.full() {
+		chunk, err := br.ReadString('<')
+		if skip == 0 {
+			text.WriteString(strings.TrimSuffix(chunk, "<"))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tag, err := readTag(br)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case tag.name == "":
+			// a comment, directive or stray "<"
+			if tag.text && skip == 0 {
+				text.WriteByte('<')
+			}
+			continue
+		case htmlRaw[tag.name] && !tag.end && !tag.selfClosing:
+			content, err := readRaw(br, tag.name)
+			if err != nil {
+				return nil, err
+			}
+			if tag.name == "title" {
+				b.doc.Metadata["title"] = html.UnescapeString(content)
+			}
+			continue
+		case htmlSkipped[tag.name]:
+			if tag.end {
+				skip = max(skip-1, 0)
+			} else if !tag.selfClosing {
+				skip++
+			}
+			continue
+		}
+		if skip > 0 {
+			continue
+		}
+
+		switch tag.name {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			if !tag.end {
+				endParagraph()
+				heading = &Section{Level: int(tag.name[1] - '0')}
+			} else if heading != nil {
+				heading.Heading = html.UnescapeString(text.String())
+				text.Reset()
+				b.section(*heading)
+				heading = nil
+			}
+		case "html":
+			if lang := tag.attrs["lang"]; lang != "" {
+				b.doc.Metadata["lang"] = lang
+			}
+		case "meta":
+			key := htmlMeta[strings.ToLower(tag.attrs["name"]+tag.attrs["property"])]
+			if key != "" && b.doc.Metadata[key] == "" {
+				b.doc.Metadata[key] = tag.attrs["content"]
+			}
+		case "img":
+			// alt text is the only text of images
+			if alt := tag.attrs["alt"]; alt != "" {
+				text.WriteString(" " + alt + " ")
+			}
+		default:
+			if htmlBlocks[tag.name] {
+				endParagraph()
+			}
+		}
+	}
+	endParagraph()
+	return b.done(), nil
+}
+
+type htmlTag struct {
+	name        string
+	end         bool
+	selfClosing bool
+	attrs       map[string]string
+	// text is set when the "<" did not start markup and is text.
+	text bool
+}
+
+// readTag reads the markup after a "<".
+func readTag(br *bufio.Reader) (htmlTag, error) {
+	c, err := br.Peek(1)
+	if err == io.EOF {
+		return htmlTag{text: true}, nil
+	}
+	if err != nil {
+		return htmlTag{}, err
+	}
+	switch {
+	case c[0] == '!':
+		if p, _ := br.Peek(3); bytes.Equal(p, []byte("!--")) {
+			return htmlTag{}, skipPast(br, "-->")
+		}
+		return htmlTag{}, skipPast(br, ">")
+	case c[0] == '?':
+		return htmlTag{}, skipPast(br, ">")
+	case c[0] == '/' || isASCIILetter(c[0]):
+	default:
+		return htmlTag{text: true}, nil
+	}
+
+	// read up to the closing ">", which may appear in quoted attributes
+	var raw strings.Builder
+	var quote byte
+	for {
+		ch, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return htmlTag{}, err
+		}
+		if quote != 0 {
+			if ch == quote {
+				quote = 0
+			}
+		} else if ch == '"' || ch == '\'' {
+			quote = ch
+		} else if ch == '>' {
+			break
+		}
+		raw.WriteByte(ch)
+	}
+	return parseTag(raw.String()), nil
+}
+
+func parseTag(raw string) htmlTag {
+	var tag htmlTag
+	if strings.HasPrefix(raw, "/") {
+		tag.end = true
+		raw = raw[1:]
+	}
+	if strings.HasSuffix(raw, "/") {
+		tag.selfClosing = true
+		raw = raw[:len(raw)-1]
+	}
+	i := strings.IndexAny(raw, " \t\r\n\f/")
+	if i < 0 {
+		i = len(raw)
+	}
+	tag.name = strings.ToLower(raw[:i])
+	tag.attrs = parseAttrs(raw[i:])
+	return tag
+}
+
+// parseAttrs parses `name="value" name='value' name=value name`.
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t\r\n\f/")
+		if s == "" {
+			return attrs
+		}
+		i := strings.IndexAny(s, "= \t\r\n\f")
+		if i < 0 {
+			attrs[strings.ToLower(s)] = ""
+			return attrs
+		}
+		name := strings.ToLower(s[:i])
+		s = strings.TrimLeft(s[i:], " \t\r\n\f")
+		if !strings.HasPrefix(s, "=") {
+			attrs[name] = ""
+			continue
+		}
+		s = strings.TrimLeft(s[1:], " \t\r\n\f")
+		var value string
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t\r\n\f")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		attrs[name] = html.UnescapeString(value)
+	}
+}
+
+// readRaw reads the content of a raw text element up to its end tag,
+// consuming the end tag.
+func readRaw(br *bufio.Reader, name string) (string, error) {
+	var content strings.Builder
+	for {
+		chunk, err := br.ReadString('<')
+		content.WriteString(strings.TrimSuffix(chunk, "<"))
+		if err == io.EOF {
+			return content.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		p, _ := br.Peek(len(name) + 1)
+		if len(p) == len(name)+1 && p[0] == '/' && strings.EqualFold(string(p[1:]), name) {
+			return content.String(), skipPast(br, ">")
+		}
+		content.WriteByte('<')
+	}
+}
+
+// skipPast discards input up to and including delim.
+func skipPast(br *bufio.Reader, delim string) error {
+	last := delim[len(delim)-1]
+	var tail []byte
+	for {
+		chunk, err := br.ReadSlice(last)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return err
+		}
+		tail = append(tail, chunk...)
+		if err == nil && bytes.HasSuffix(tail, []byte(delim)) {
+			return nil
+		}
+		if len(tail) > len(delim) {
+			tail = tail[len(tail)-len(delim):]
+		}
+	}
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,1077 @@
+package extract
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// The PDF object types. Numbers are float64, booleans bool and null nil.
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfString  string
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfLexer reads the tokens and objects of PDF files and content streams.
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// token returns the next name, string, number or keyword, with the
+// delimiters "<<", ">>", "[" and "]" returned as keywords.
+func (l *pdfLexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(unescapeName(l.data[start:l.pos])), nil
+	case c == '(':
+		return l.literalString()
+	case c == '<' && l.peek(1) == '<', c == '>' && l.peek(1) == '>':
+		l.pos += 2
+		return pdfKeyword(l.data[l.pos-2 : l.pos]), nil
+	case c == '<':
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			return nil, errors.New("unterminated hex string")
+		}
+		digits := bytes.Map(func(r rune) rune {
+			if isPDFSpace(byte(r)) {
+				return -1
+			}
+			return r
+		}, l.data[l.pos+1:l.pos+end])
+		l.pos += end + 1
+		if len(digits)%2 == 1 {
+			digits = append(digits, '0')
+		}
+		s := make([]byte, hex.DecodedLen(len(digits)))
+		if _, err := hex.Decode(s, digits); err != nil {
+			return nil, fmt.Errorf("invalid hex string: %w", err)
+		}
+		return pdfString(s), nil
+	case isPDFDelim(c):
+		l.pos++
+		return pdfKeyword([]byte{c}), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		start := l.pos
+		l.pos++
+		for l.pos < len(l.data) && (l.data[l.pos] == '.' || (l.data[l.pos] >= '0' && l.data[l.pos] <= '9')) {
+			l.pos++
+		}
+		n, err := strconv.ParseFloat(string(l.data[start:l.pos]), 64)
+		if err != nil {
+			// a lone sign or dot, which some writers emit for zero
+			return 0.0, nil
+		}
+		return n, nil
+	}
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	return pdfKeyword(l.data[start:l.pos]), nil
+}
+
+func (l *pdfLexer) peek(n int) byte {
+	if l.pos+n < len(l.data) {
+		return l.data[l.pos+n]
+	}
+	return 0
+}
+
+func unescapeName(b []byte) string {
+	if bytes.IndexByte(b, '#') < 0 {
+		return string(b)
+	}
+	var out []byte
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
+
+func (l *pdfLexer) literalString() (any, error) {
+	l.pos++ // (
+	var out []byte
+	nesting := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			nesting++
+		case ')':
+			if nesting--; nesting == 0 {
+				return pdfString(out), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				continue
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// a line continuation
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return nil, errors.New("unterminated string")
+}
+
+// value reads the next object, composing arrays, dictionaries and
+// references from tokens. Other keywords, such as content stream
+// operators, are returned as they are.
+func (l *pdfLexer) value() (any, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	return l.valueFrom(tok)
+}
+
+func (l *pdfLexer) valueFrom(tok any) (any, error) {
+	switch t := tok.(type) {
+	case pdfKeyword:
+		switch t {
+		case "<<", "[":
+			if l.depth++; l.depth > 64 {
+				return nil, errors.New("objects are nested too deeply")
+			}
+			defer func() { l.depth-- }()
+			if t == "[" {
+				return l.array()
+			}
+			return l.dict()
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	case float64:
+		// "num gen R" is a reference
+		if t >= 0 && t == math.Trunc(t) {
+			save := l.pos
+			if gen, err := l.token(); err == nil {
+				if g, ok := gen.(float64); ok && g >= 0 && g == math.Trunc(g) {
+					if r, err := l.token(); err == nil && r == pdfKeyword("R") {
+						return pdfRef{int(t), int(g)}, nil
+					}
+				}
+			}
+			l.pos = save
+		}
+	}
+	return tok, nil
+}
+
+func (l *pdfLexer) array() ([]any, error) {
+	var arr []any
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return nil, err
+		}
+		if tok == pdfKeyword("]") {
+			return arr, nil
+		}
+		v, err := l.valueFrom(tok)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+}
+
+func (l *pdfLexer) dict() (pdfDict, error) {
+	d := pdfDict{}
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return nil, err
+		}
+		if tok == pdfKeyword(">>") {
+			return d, nil
+		}
+		key, ok := tok.(pdfName)
+		if !ok {
+			// skip junk keys rather than failing the whole object
+			continue
+		}
+		v, err := l.value()
+		if err != nil {
+			return nil, err
+		}
+		d[key] = v
+	}
+}
+
+// pdfFile is the objects of a PDF.
+type pdfFile struct {
+	objects map[int]any
+	trailer pdfDict
+	budget  *int64
+}
+
+var pdfObjectHeader = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+
+// parsePDF reads every object of data in order, so objects redefined by
+// incremental updates take their latest value. The cross reference table
+// is not used, it is often wrong in files which viewers open fine.
+func parsePDF(data []byte, budget *int64) (*pdfFile, error) {
+	f := &pdfFile{objects: make(map[int]any), budget: budget}
+	var objStreams []*pdfStream
+	for pos := 0; pos < len(data); {
+		loc := pdfObjectHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		l := &pdfLexer{data: data, pos: pos + loc[1]}
+		v, err := l.value()
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		if d, ok := v.(pdfDict); ok {
+			if s := l.stream(d); s != nil {
+				v = s
+				switch d["Type"] {
+				case pdfName("ObjStm"):
+					objStreams = append(objStreams, s)
+				case pdfName("XRef"):
+					// cross reference streams double as the trailer
+					f.trailer = d
+				}
+			}
+		}
+		f.objects[num] = v
+		pos = l.pos
+	}
+	if i := bytes.LastIndex(data, []byte("trailer")); i >= 0 {
+		l := &pdfLexer{data: data, pos: i + len("trailer")}
+		if d, err := l.value(); err == nil {
+			if d, ok := d.(pdfDict); ok {
+				f.trailer = d
+			}
+		}
+	}
+	if f.trailer == nil {
+		return nil, errors.New("invalid pdf: no trailer")
+	}
+	if _, ok := f.trailer["Encrypt"]; ok {
+		return nil, fmt.Errorf("%w: the pdf is encrypted", ErrUnsupported)
+	}
+
+	for _, s := range objStreams {
+		if err := f.loadObjectStream(s); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// stream reads the stream following the dictionary d, if any.
+func (l *pdfLexer) stream(d pdfDict) *pdfStream {
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		return nil
+	}
+	start := l.pos + len("stream")
+	if bytes.HasPrefix(l.data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(l.data) && (l.data[start] == '\n' || l.data[start] == '\r') {
+		start++
+	}
+	end := -1
+	if n, ok := d["Length"].(float64); ok && n >= 0 && start+int(n) <= len(l.data) {
+		rest := bytes.TrimLeft(l.data[start+int(n):], " \r\n\t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			end = start + int(n)
+		}
+	}
+	if end < 0 {
+		// the length is indirect or wrong, look for the end instead
+		i := bytes.Index(l.data[start:], []byte("endstream"))
+		if i < 0 {
+			l.pos = len(l.data)
+			return &pdfStream{dict: d, raw: l.data[start:]}
+		}
+		end = start + i
+		for end > start && (l.data[end-1] == '\n' || l.data[end-1] == '\r') {
+			end--
+		}
+	}
+	l.pos = end
+	if i := bytes.Index(l.data[end:], []byte("endstream")); i >= 0 {
+		l.pos = end + i + len("endstream")
+	}
+	return &pdfStream{dict: d, raw: l.data[start:end]}
+}
+
+// loadObjectStream adds the objects compressed in an object stream, unless
+// they are also defined directly.
+func (f *pdfFile) loadObjectStream(s *pdfStream) error {
+	data, err := f.decode(s)
+	if err != nil {
+		return err
+	}
+	n, _ := s.dict["N"].(float64)
+	first, _ := s.dict["First"].(float64)
+	if first <= 0 || int(first) > len(data) {
+		return nil
+	}
+	header := &pdfLexer{data: data[:int(first)]}
+	for i := 0; i < int(n); i++ {
+		num, err1 := header.token()
+		off, err2 := header.token()
+		numF, ok1 := num.(float64)
+		offF, ok2 := off.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return nil
+		}
+		if _, ok := f.objects[int(numF)]; ok {
+			continue
+		}
+		l := &pdfLexer{data: data, pos: int(first) + int(offF)}
+		if l.pos >= len(data) {
+			continue
+		}
+		if v, err := l.value(); err == nil {
+			f.objects[int(numF)] = v
+		}
+	}
+	return nil
+}
+
+// resolve follows references.
+func (f *pdfFile) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = f.objects[ref.num]
+	}
+	return nil
+}
+
+func (f *pdfFile) dict(v any) pdfDict {
+	switch v := f.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode returns the decoded data of a stream. Streams with filters which
+// are not supported, such as image compressions, decode to nothing.
+func (f *pdfFile) decode(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch v := f.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{v}
+	case []any:
+		filters = v
+	}
+	data := s.raw
+	for _, filter := range filters {
+		var r io.Reader
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				// some writers omit the zlib header
+				r = flate.NewReader(bytes.NewReader(data))
+			} else {
+				r = zr
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			r = hex.NewDecoder(bytes.NewReader(bytes.Map(func(r rune) rune {
+				if r == '>' || isPDFSpace(byte(r)) {
+					return -1
+				}
+				return r
+			}, data)))
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			trimmed := bytes.TrimSpace(data)
+			trimmed = bytes.TrimPrefix(trimmed, []byte("<~"))
+			if i := bytes.Index(trimmed, []byte("~>")); i >= 0 {
+				trimmed = trimmed[:i]
+			}
+			r = ascii85.NewDecoder(bytes.NewReader(trimmed))
+		default:
+			return nil, nil
+		}
+		decoded, err := io.ReadAll(&limitReader{r: r, n: f.budget})
+		if errors.Is(err, ErrTooLarge) {
+			return nil, err
+		}
+		// keep what decoded of corrupt streams, which viewers also show
+		data = decoded
+	}
+	return data, nil
+}
+
+// extractPDF extracts the text of each page into a section.
+func extractPDF(data []byte, opts Options) (*Document, error) {
+	f, err := parsePDF(data, opts.inflateBudget())
+	if err != nil {
+		return nil, err
+	}
+	b := newBuilder(FormatPDF, opts)
+	readPDFInfo(f, b.doc.Metadata)
+
+	catalog := f.dict(f.trailer["Root"])
+	if catalog == nil {
+		// fall back to finding the catalog when the trailer is damaged
+		for _, v := range f.objects {
+			if d, ok := v.(pdfDict); ok && d["Type"] == pdfName("Catalog") {
+				catalog = d
+			}
+		}
+	}
+	if catalog == nil {
+		return nil, errors.New("invalid pdf: no catalog")
+	}
+	var pages []pdfPage
+	f.collectPages(catalog["Pages"], nil, map[pdfRef]bool{}, &pages)
+	b.doc.Pages = len(pages)
+
+	fonts := map[any]*pdfFont{}
+	for i, page := range pages {
+		if b.full() {
+			break
+		}
+		content, err := f.contents(page.dict["Contents"])
+		if err != nil {
+			return nil, err
+		}
+		t := &pdfText{f: f, fonts: fonts}
+		if err := t.run(content, page.resources, 0); err != nil {
+			return nil, err
+		}
+		b.section(Section{Page: i + 1})
+		for _, para := range strings.Split(t.out.String(), "\n\n") {
+			b.paragraph(para)
+		}
+	}
+	return b.done(), nil
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// collectPages walks the page tree in order, passing down the inherited
+// resources.
+func (f *pdfFile) collectPages(node any, resources pdfDict, seen map[pdfRef]bool, pages *[]pdfPage) {
+	if ref, ok := node.(pdfRef); ok {
+		if seen[ref] {
+			return
+		}
+		seen[ref] = true
+	}
+	d := f.dict(node)
+	if d == nil {
+		return
+	}
+	if res := f.dict(d["Resources"]); res != nil {
+		resources = res
+	}
+	kids, ok := f.resolve(d["Kids"]).([]any)
+	if !ok {
+		if d["Type"] == pdfName("Page") || d["Contents"] != nil {
+			*pages = append(*pages, pdfPage{dict: d, resources: resources})
+		}
+		return
+	}
+	for _, kid := range kids {
+		f.collectPages(kid, resources, seen, pages)
+	}
+}
+
+// contents returns the decoded content streams of a page, which may be
+// split over several streams.
+func (f *pdfFile) contents(v any) ([]byte, error) {
+	var streams []any
+	switch c := f.resolve(v).(type) {
+	case *pdfStream:
+		streams = []any{c}
+	case []any:
+		streams = c
+	}
+	var out []byte
+	for _, s := range streams {
+		if s, ok := f.resolve(s).(*pdfStream); ok {
+			data, err := f.decode(s)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, data...)
+			out = append(out, '\n')
+		}
+	}
+	return out, nil
+}
+
+var pdfInfoKeys = map[pdfName]string{
+	"Title": "title", "Author": "author", "Subject": "subject", "Keywords": "keywords",
+	"Creator": "creator", "Producer": "producer", "CreationDate": "created", "ModDate": "modified",
+}
+
+func readPDFInfo(f *pdfFile, metadata map[string]string) {
+	info := f.dict(f.trailer["Info"])
+	for name, key := range pdfInfoKeys {
+		s, ok := f.resolve(info[name]).(pdfString)
+		if !ok {
+			continue
+		}
+		v := decodeTextString(s)
+		if key == "created" || key == "modified" {
+			if t, ok := parsePDFDate(v); ok {
+				v = t.Format(time.RFC3339)
+			}
+		}
+		metadata[key] = v
+	}
+}
+
+// decodeTextString decodes a PDF text string, UTF-16 when it starts with
+// a byte order mark and otherwise in PDFDocEncoding, which is close to
+// Latin-1.
+func decodeTextString(s pdfString) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		return decodeUTF16BE([]byte(s[2:]))
+	}
+	if strings.HasPrefix(string(s), "\xef\xbb\xbf") {
+		return string(s[3:])
+	}
+	return decodeWinAnsi([]byte(s))
+}
+
+func decodeUTF16BE(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(u))
+}
+
+var pdfDate = regexp.MustCompile(`^(?:D:)?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?([Zz+-])?(\d{2})?'?(\d{2})?'?`)
+
+// parsePDFDate parses dates such as "D:20240131120000+01'00'".
+func parsePDFDate(s string) (time.Time, bool) {
+	m := pdfDate.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return time.Time{}, false
+	}
+	n := func(i, def int) int {
+		if m[i] == "" {
+			return def
+		}
+		v, _ := strconv.Atoi(m[i])
+		return v
+	}
+	loc := time.UTC
+	if m[7] == "+" || m[7] == "-" {
+		offset := n(8, 0)*3600 + n(9, 0)*60
+		if m[7] == "-" {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	return time.Date(n(1, 0), time.Month(n(2, 1)), n(3, 1), n(4, 0), n(5, 0), n(6, 0), 0, loc), true
+}
+
+// pdfText interprets content streams, keeping only the text.
+type pdfText struct {
+	f     *pdfFile
+	fonts map[any]*pdfFont
+	out   strings.Builder
+
+	font      *pdfFont
+	lineStart float64 // y of the current line start in the text object
+	leading   float64
+	y         float64 // y of the last text shown
+	gap       float64 // the last line spacing
+	shown     bool
+	moved     bool
+}
+
+// run interprets content, a content stream or form, with its resources.
+func (t *pdfText) run(content []byte, resources pdfDict, depth int) error {
+	l := &pdfLexer{data: content}
+	var operands []any
+	for {
+		v, err := l.value()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// stop at corrupt content, keeping the text read so far
+			return nil
+		}
+		op, ok := v.(pdfKeyword)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+		switch op {
+		case "BT":
+			t.lineStart = 0
+		case "Tf":
+			if len(operands) >= 1 {
+				name, _ := operands[0].(pdfName)
+				font, err := t.loadFont(resources, name)
+				if err != nil {
+					return err
+				}
+				t.font = font
+			}
+		case "TL":
+			t.leading = number(operands, 0)
+		case "Td":
+			t.moveTo(t.lineStart + number(operands, 1))
+		case "TD":
+			t.leading = -number(operands, 1)
+			t.moveTo(t.lineStart + number(operands, 1))
+		case "Tm":
+			t.moveTo(number(operands, 5))
+		case "T*":
+			t.moveTo(t.lineStart - t.leading)
+		case "Tj":
+			t.show(operands, 0)
+		case "'":
+			t.moveTo(t.lineStart - t.leading)
+			t.show(operands, 0)
+		case "\"":
+			t.moveTo(t.lineStart - t.leading)
+			t.show(operands, 2)
+		case "TJ":
+			if arr, ok := lastOperand(operands).([]any); ok {
+				for _, item := range arr {
+					switch item := item.(type) {
+					case pdfString:
+						t.show([]any{item}, 0)
+					case float64:
+						// a large negative adjustment is a word gap
+						if item < -200 {
+							t.space()
+						}
+					}
+				}
+			}
+		case "Do":
+			if depth < 4 && len(operands) >= 1 {
+				name, _ := operands[0].(pdfName)
+				xobjects := t.f.dict(resources["XObject"])
+				if form, ok := t.f.resolve(xobjects[name]).(*pdfStream); ok && form.dict["Subtype"] == pdfName("Form") {
+					data, err := t.f.decode(form)
+					if err != nil {
+						return err
+					}
+					res := t.f.dict(form.dict["Resources"])
+					if res == nil {
+						res = resources
+					}
+					if err := t.run(data, res, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+		case "BI":
+			// skip inline images, whose data is binary
+			if i := bytes.Index(content[l.pos:], []byte("EI")); i >= 0 {
+				for j := l.pos + i; j < len(content); j++ {
+					if content[j] == 'E' && j+2 <= len(content) && content[j+1] == 'I' &&
+						isPDFSpace(content[j-1]) && (j+2 == len(content) || isPDFSpace(content[j+2])) {
+						l.pos = j + 2
+						break
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func number(operands []any, i int) float64 {
+	if i < len(operands) {
+		if n, ok := operands[i].(float64); ok {
+			return n
+		}
+	}
+	return 0
+}
+
+func lastOperand(operands []any) any {
+	if len(operands) == 0 {
+		return nil
+	}
+	return operands[len(operands)-1]
+}
+
+// moveTo starts a new line at y.
+func (t *pdfText) moveTo(y float64) {
+	t.lineStart = y
+	t.moved = true
+}
+
+// show writes the string operand i in the current font, first breaking the
+// line, or the paragraph for gaps much larger than the line spacing, when
+// the text moved to another line.
+func (t *pdfText) show(operands []any, i int) {
+	if i >= len(operands) {
+		return
+	}
+	s, ok := operands[i].(pdfString)
+	if !ok || t.font == nil {
+		return
+	}
+	if t.moved {
+		dy := math.Abs(t.lineStart - t.y)
+		switch {
+		case !t.shown:
+		case dy < 0.5:
+			t.space()
+		case t.gap > 0 && dy > 1.6*t.gap:
+			t.out.WriteString("\n\n")
+		default:
+			t.out.WriteByte('\n')
+			t.gap = dy
+		}
+		t.y = t.lineStart
+		t.moved = false
+	}
+	t.out.WriteString(t.font.decode([]byte(s)))
+	t.shown = true
+}
+
+func (t *pdfText) space() {
+	if s := t.out.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		t.out.WriteByte(' ')
+	}
+}
+
+// pdfFont decodes the strings shown with a font into text.
+type pdfFont struct {
+	// toUnicode maps character codes to text, from the font's ToUnicode
+	// CMap.
+	toUnicode map[string]string
+	codeLen   int
+	// differences maps single byte codes to glyph names.
+	differences map[byte]string
+	// composite fonts without a ToUnicode map cannot be decoded.
+	composite bool
+}
+
+func (t *pdfText) loadFont(resources pdfDict, name pdfName) (*pdfFont, error) {
+	ref := t.f.dict(resources["Font"])[name]
+	key := any(ref)
+	if _, ok := ref.(pdfRef); !ok {
+		key = name
+	}
+	if font, ok := t.fonts[key]; ok {
+		return font, nil
+	}
+	d := t.f.dict(ref)
+	font := &pdfFont{codeLen: 1, composite: d["Subtype"] == pdfName("Type0")}
+	if font.composite {
+		font.codeLen = 2
+	}
+	if s, ok := t.f.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		data, err := t.f.decode(s)
+		if err != nil {
+			return nil, err
+		}
+		font.parseCMap(data)
+	}
+	if enc := t.f.dict(d["Encoding"]); enc != nil {
+		if diffs, ok := t.f.resolve(enc["Differences"]).([]any); ok {
+			font.differences = make(map[byte]string)
+			code := 0
+			for _, v := range diffs {
+				switch v := v.(type) {
+				case float64:
+					code = int(v)
+				case pdfName:
+					if code >= 0 && code < 256 {
+						font.differences[byte(code)] = string(v)
+					}
+					code++
+				}
+			}
+		}
+	}
+	t.fonts[key] = font
+	return font, nil
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap.
+func (font *pdfFont) parseCMap(data []byte) {
+	font.toUnicode = make(map[string]string)
+	l := &pdfLexer{data: data}
+	var operands []any
+	mode := ""
+	for {
+		v, err := l.value()
+		if err != nil {
+			return
+		}
+		if kw, ok := v.(pdfKeyword); ok {
+			switch kw {
+			case "begincodespacerange", "beginbfchar", "beginbfrange":
+				mode = string(kw)
+			case "endcodespacerange", "endbfchar", "endbfrange":
+				mode = ""
+			}
+			operands = operands[:0]
+			continue
+		}
+		operands = append(operands, v)
+		switch mode {
+		case "begincodespacerange":
+			if len(operands) == 2 {
+				if lo, ok := operands[0].(pdfString); ok && len(lo) > 0 {
+					font.codeLen = len(lo)
+				}
+				operands = operands[:0]
+			}
+		case "beginbfchar":
+			if len(operands) == 2 {
+				src, _ := operands[0].(pdfString)
+				if dst, ok := operands[1].(pdfString); ok {
+					font.toUnicode[string(src)] = decodeUTF16BE([]byte(dst))
+				}
+				operands = operands[:0]
+			}
+		case "beginbfrange":
+			if len(operands) == 3 {
+				font.mapRange(operands[0], operands[1], operands[2])
+				operands = operands[:0]
+			}
+		default:
+			operands = operands[:0]
+		}
+	}
+}
+
+func (font *pdfFont) mapRange(loV, hiV, dst any) {
+	lo, ok1 := loV.(pdfString)
+	hi, ok2 := hiV.(pdfString)
+	if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+		return
+	}
+	start, end := codeValue([]byte(lo)), codeValue([]byte(hi))
+	if end < start || end-start > 0xffff {
+		return
+	}
+	for code := start; code <= end; code++ {
+		key := codeBytes(code, len(lo))
+		switch d := dst.(type) {
+		case pdfString:
+			// the last byte of the destination is incremented along the range
+			b := []byte(d)
+			if len(b) == 0 {
+				return
+			}
+			b = append([]byte(nil), b...)
+			b[len(b)-1] += byte(code - start)
+			font.toUnicode[key] = decodeUTF16BE(b)
+		case []any:
+			if i := int(code - start); i < len(d) {
+				if s, ok := d[i].(pdfString); ok {
+					font.toUnicode[key] = decodeUTF16BE([]byte(s))
+				}
+			}
+		}
+	}
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+func codeBytes(v uint32, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return string(b)
+}
+
+func (font *pdfFont) decode(s []byte) string {
+	var out strings.Builder
+	for len(s) > 0 {
+		n := min(font.codeLen, len(s))
+		code := s[:n]
+		s = s[n:]
+		if text, ok := font.toUnicode[string(code)]; ok {
+			out.WriteString(text)
+			continue
+		}
+		if font.composite || n != 1 {
+			continue
+		}
+		if name, ok := font.differences[code[0]]; ok {
+			if r, ok := glyphRune(name); ok {
+				out.WriteRune(r)
+			}
+			continue
+		}
+		out.WriteString(decodeWinAnsi(code))
+	}
+	return out.String()
+}
+
+// glyphNames maps the glyph names used in font encodings which are not a
+// single letter or a uniXXXX name.
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$',
+	"percent": '%', "ampersand": '&', "quotesingle": '\'', "parenleft": '(',
+	"parenright": ')', "asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-',
+	"period": '.', "slash": '/', "zero": '0', "one": '1', "two": '2', "three": '3',
+	"four": '4', "five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\',
+	"bracketright": ']', "underscore": '_', "quoteleft": '‘', "quoteright": '’',
+	"quotedblleft": '“', "quotedblright": '”', "endash": '–', "emdash": '—',
+	"bullet": '•', "ellipsis": '…', "fi": 'ﬁ', "fl": 'ﬂ', "ff": 'ﬀ', "ffi": 'ﬃ',
+	"ffl": 'ﬄ', "minus": '−', "degree": '°', "copyright": '©', "registered": '®',
+	"trademark": '™', "Euro": '€', "section": '§', "paragraph": '¶',
+}
+
+func glyphRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	if hexCode, ok := strings.CutPrefix(name, "uni"); ok && len(hexCode) == 4 {
+		if v, err := strconv.ParseUint(hexCode, 16, 32); err == nil {
+			return rune(v), true
+		}
+	}
+	return 0, false
+}
+
+// winAnsi maps the bytes 0x80 to 0x9f of WinAnsiEncoding, which differ
+// from Latin-1.
+var winAnsi = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8a: 'Š', 0x8b: '‹', 0x8c: 'Œ', 0x8e: 'Ž', 0x91: '‘',
+	0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜',
+	0x99: '™', 0x9a: 'š', 0x9b: '›', 0x9c: 'œ', 0x9e: 'ž', 0x9f: 'Ÿ',
+}
+
+func decodeWinAnsi(b []byte) string {
+	var out strings.Builder
+	for _, c := range b {
+		if r, ok := winAnsi[c]; ok {
+			out.WriteRune(r)
+		} else if c >= 0x20 || c == '\t' || c == '\n' || c == '\r' {
+			out.WriteRune(rune(c))
+		}
+	}
+	return out.String()
+}
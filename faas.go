@@ -1,15 +1,18 @@
 package faas
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Map is an interface for generating a custom untyped JSON object.
@@ -20,6 +23,9 @@ type Error struct {
 	Status string `json:"status"`
 	Reason string `json:"reason,omitempty"`
 	Code   int    `json:"code,omitempty"`
+	// ErrorCode and Fields are set for errors written by WriteError.
+	ErrorCode string         `json:"error_code,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
 }
 
 // GetIpAddress retrieves the requests remote address.
@@ -36,6 +42,29 @@ func GetIpAddress(r *http.Request) string {
 	}
 }
 
+// ClientIP returns the address of the client which sent r: the first hop
+// of X-Forwarded-For, as set by the OpenFaaS gateway and most proxies, or
+// else the host of RemoteAddr without its port, which changes with every
+// connection. An empty string is returned when neither holds an IP address.
+// X-Forwarded-For is set by the client when the function is reached
+// without a proxy, so only use ClientIP for security decisions behind one.
+func ClientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		first, _, _ := strings.Cut(xff, ",")
+		if ip := net.ParseIP(strings.TrimSpace(first)); ip != nil {
+			return ip.String()
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
 // ValidateMethod checks the http.Request is either GET or POST. Everything else
 // returns an error.
 func ValidateMethod(r *http.Request) error {
@@ -84,12 +113,34 @@ func validateCORS(w http.ResponseWriter, r *http.Request, origins []string) erro
 	return nil
 }
 
+// secretsPath is where OpenFaaS mounts function secrets.
+var secretsPath = "/var/openfaas/secrets"
+
+// SecretProvider looks up secrets by name. A missing secret is an error
+// matching fs.ErrNotExist.
+type SecretProvider interface {
+	Secret(name string) ([]byte, error)
+}
+
+// MountedSecrets reads the secrets OpenFaaS mounts as files.
+type MountedSecrets struct{}
+
+// Secret implements SecretProvider.
+func (MountedSecrets) Secret(name string) ([]byte, error) {
+	return os.ReadFile(fmt.Sprintf("%s/%s", secretsPath, name))
+}
+
+// DefaultSecretProvider is used by GetSecret and every helper reading a
+// secret. Replace it to load secrets from elsewhere, or in tests with the
+// faastest sub-package.
+var DefaultSecretProvider SecretProvider = MountedSecrets{}
+
 // GetSecret is a helper to retrieve kubernetes/openfaas secrets from the cluster.
 func GetSecret(secretName string) ([]byte, error) {
 	return getSecret(secretName)
 }
 func getSecret(secretName string) ([]byte, error) {
-	secret, err := os.ReadFile(fmt.Sprintf("/var/openfaas/secrets/%s", secretName))
+	secret, err := DefaultSecretProvider.Secret(secretName)
 	if err != nil {
 		return nil, err
 	}
@@ -124,12 +175,16 @@ func getEnvOrError(env string) (string, error) {
 	return exists, nil
 }
 
-// WriteJSON will write a JSON response to the caller.
+// WriteJSON will write a JSON response to the caller. Time values are
+// written as configured by SetTimeConfig, and the body is canonical when set with
+// SetJSONOutput.
 func WriteJSON(w http.ResponseWriter, status int, data any, headers http.Header) error {
 	return writeJSON(w, status, data, headers)
 }
 func writeJSON(w http.ResponseWriter, status int, data any, headers http.Header) error {
-	js, err := json.Marshal(data)
+	buf := jsonBufPool.Get().(*bytes.Buffer)
+	defer putJSONBuf(buf)
+	body, err := encodeJSONBody(w, buf, data)
 	if err != nil {
 		return err
 	}
@@ -140,13 +195,83 @@ func writeJSON(w http.ResponseWriter, status int, data any, headers http.Header)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	_, _ = w.Write(js)
+	// the newline added by Encode is dropped so the body matches Marshal
+	_, _ = w.Write(body)
 	return nil
 }
 
+// encodeJSONBody encodes data into buf as writeJSON writes it, returning
+// the body without the newline added by Encode.
+func encodeJSONBody(w http.ResponseWriter, buf *bytes.Buffer, data any) ([]byte, error) {
+	start := time.Now()
+	buf.Reset()
+	if err := jsonCodec().NewEncoder(buf).Encode(data); err != nil {
+		return nil, err
+	}
+	body := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	if o := currentJSONOutput(); o.Canonical {
+		canonical, err := canonicalize(body, o.Indent)
+		if err != nil {
+			return nil, err
+		}
+		body = canonical
+	}
+	// report serialisation time when the response is wrapped by ServerTiming
+	if tw, ok := w.(interface{ timings() *Timings }); ok {
+		tw.timings().Add("serialize", time.Since(start))
+	}
+	return body, nil
+}
+
+// jsonBufPool holds the buffers writeJSON encodes into, so responses do not
+// allocate a new slice per call.
+var jsonBufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// putJSONBuf returns buf to the pool unless it grew over 1MB, so a single
+// large response does not pin its memory.
+func putJSONBuf(buf *bytes.Buffer) {
+	if buf.Cap() <= 1<<20 {
+		jsonBufPool.Put(buf)
+	}
+}
+
+// WriteJSONStream writes data as JSON with an encoder writing to the
+// response, skipping the copy into a pooled buffer, for large payloads.
+// Time values follow SetTimeConfig as with WriteJSON, but SetJSONOutput is
+// ignored since canonical output needs the whole body first. The body
+// keeps the trailing newline written by the encoder. The status and headers
+// are set with the first bytes written by the encoder. The default encoder
+// writes nothing when encoding fails, so the caller can respond with
+// WriteError instead, but a streaming codec set with SetCodec may fail
+// after part of the body was sent.
+func WriteJSONStream(w http.ResponseWriter, status int, data any, headers http.Header) error {
+	return jsonCodec().NewEncoder(&headerOnWrite{ResponseWriter: w, status: status, headers: headers}).Encode(data)
+}
+
+// headerOnWrite defers setting headers and WriteHeader until the first
+// Write.
+type headerOnWrite struct {
+	http.ResponseWriter
+	status  int
+	headers http.Header
+	wrote   bool
+}
+
+func (w *headerOnWrite) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.wrote = true
+		for k, v := range w.headers {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
 // writeJSONError returns a JSON response with a custom error type.
 func writeJSONError(w http.ResponseWriter, data Error) error {
-	js, err := json.Marshal(data)
+	js, err := jsonCodec().Marshal(data)
 	if err != nil {
 		return err
 	}
@@ -158,86 +283,26 @@ func writeJSONError(w http.ResponseWriter, data Error) error {
 }
 
 // ReadJSON is helper for trapping errors and return values for JSON related
-// handlers
-func ReadJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
-	return readJSON(w, r, dst)
+// handlers. Bodies are limited to 1MB, or the size set by the MAX_BODY_BYTES
+// environment variable, unless opts sets another limit.
+func ReadJSON(w http.ResponseWriter, r *http.Request, dst interface{}, opts ...BodyOptions) error {
+	return readJSON(w, r, dst, opts...)
 }
-func readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+func readJSON(w http.ResponseWriter, r *http.Request, dst interface{}, opts ...BodyOptions) error {
 	// Set a max body length. Without this it will accept unlimited size requests
-	maxBytes := 1_048_576 // 1MB
+	maxBytes := int(bodyLimit(opts))
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
 
-	// Init a Decoder and call DisallowUnknownFields() on it before decoding.
+	// Init a strict Decoder from the codec set by SetCodec before decoding.
 	// This means that JSON from the client will be rejected if it contains keys
 	// which do not match the target destination struct. If not implemented,
 	// the decoder will silently drop unknown fields - this will raise an error instead.
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
+	dec := jsonCodec().NewDecoder(r.Body, true)
 
 	// decode the request body into the target struct/destination
 	err := dec.Decode(dst)
 	if err != nil {
-		// start triaging the various JSON related errors
-		var syntaxError *json.SyntaxError
-		var unmarshallTypeError *json.UnmarshalTypeError
-		var invalidUnmarshallError *json.InvalidUnmarshalError
-
-		switch {
-		// Use the errors.As() function to check whether the error has the
-		// *json.SyntaxError. If it does, then return a user-readable error
-		// message including the location of the problem
-		case errors.As(err, &syntaxError):
-			return fmt.Errorf(
-				"body contains badly-formed JSON (at character %d)",
-				syntaxError.Offset,
-			)
-
-		// Decode() can also return an io.ErrUnexpectedEOF for JSON syntax errors. This is
-		// checked for with errors.Is() and returns a generic error message to the client.
-		case errors.Is(err, io.ErrUnexpectedEOF):
-			return errors.New("body contains badly-formed JSON")
-
-		// Wrong JSON types will return an error when they do not match the target destination
-		// struct.
-		case errors.As(err, &unmarshallTypeError):
-			if unmarshallTypeError.Field != "" {
-				return fmt.Errorf(
-					"body contains incorrect JSON type for field %q",
-					unmarshallTypeError.Field,
-				)
-			}
-			return fmt.Errorf(
-				"body contains incorrect JSON type (at character %d)",
-				unmarshallTypeError.Offset,
-			)
-
-		// An EOF error will be returned by Decode() if the request body is empty. Use errors.Is()
-		// to check for this and return a human-readable error message
-		case errors.Is(err, io.EOF):
-			return errors.New("body must not be empty")
-
-		// If JSON contains a field which cannot be mapped to the target destination
-		// then Decode will return an error message in the format "json: unknown field "<name>""
-		// We check for this, extract the field name and interpolate it into an error
-		// which is returned to the client
-		case strings.HasPrefix(err.Error(), "json: unknown field "):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
-			return fmt.Errorf("body contains unknown key %s", fieldName)
-
-		// If the request body exceeds maxBytes the decode will fail with a
-		// "http: request body too large".
-		case err.Error() == "http: request body too large":
-			return fmt.Errorf("body must not be larger than %d bytes", maxBytes)
-
-		// A json.InvalidUnmarshallError will be returned if we pass a non-nil pointer
-		// to Decode(). We catch and panic, rather than return an error.
-		case errors.As(err, &invalidUnmarshallError):
-			panic(err)
-
-		// All else fails, return an error as-is
-		default:
-			return err
-		}
+		return triageJSONError(err, maxBytes)
 	}
 
 	// Call Decode() again, using a pointer to anonymous empty struct as the
@@ -251,6 +316,72 @@ func readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 	return nil
 }
 
+// triageJSONError converts the errors returned by json.Decoder into
+// user-readable messages.
+func triageJSONError(err error, maxBytes int) error {
+	// start triaging the various JSON related errors
+	var syntaxError *json.SyntaxError
+	var unmarshallTypeError *json.UnmarshalTypeError
+	var invalidUnmarshallError *json.InvalidUnmarshalError
+
+	switch {
+	// Use the errors.As() function to check whether the error has the
+	// *json.SyntaxError. If it does, then return a user-readable error
+	// message including the location of the problem
+	case errors.As(err, &syntaxError):
+		return fmt.Errorf(
+			"body contains badly-formed JSON (at character %d)",
+			syntaxError.Offset,
+		)
+
+	// Decode() can also return an io.ErrUnexpectedEOF for JSON syntax errors. This is
+	// checked for with errors.Is() and returns a generic error message to the client.
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("body contains badly-formed JSON")
+
+	// Wrong JSON types will return an error when they do not match the target destination
+	// struct.
+	case errors.As(err, &unmarshallTypeError):
+		if unmarshallTypeError.Field != "" {
+			return fmt.Errorf(
+				"body contains incorrect JSON type for field %q",
+				unmarshallTypeError.Field,
+			)
+		}
+		return fmt.Errorf(
+			"body contains incorrect JSON type (at character %d)",
+			unmarshallTypeError.Offset,
+		)
+
+	// An EOF error will be returned by Decode() if the request body is empty. Use errors.Is()
+	// to check for this and return a human-readable error message
+	case errors.Is(err, io.EOF):
+		return errors.New("body must not be empty")
+
+	// If JSON contains a field which cannot be mapped to the target destination
+	// then Decode will return an error message in the format "json: unknown field "<name>""
+	// We check for this, extract the field name and interpolate it into an error
+	// which is returned to the client
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return fmt.Errorf("body contains unknown key %s", fieldName)
+
+	// If the request body exceeds maxBytes the decode will fail with a
+	// "http: request body too large".
+	case err.Error() == "http: request body too large":
+		return fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+
+	// A json.InvalidUnmarshallError will be returned if we pass a non-nil pointer
+	// to Decode(). We catch and panic, rather than return an error.
+	case errors.As(err, &invalidUnmarshallError):
+		panic(err)
+
+	// All else fails, return an error as-is
+	default:
+		return err
+	}
+}
+
 // Background helper accepts an arbitrary function as a parameter.
 func Background(fn func()) {
 	background(fn)
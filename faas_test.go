@@ -125,6 +125,30 @@ func TestValidateCORS(t *testing.T) {
 	}
 }
 
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{remoteAddr: "203.0.113.7:50001", want: "203.0.113.7"},
+		{remoteAddr: "[2001:db8::1]:443", want: "2001:db8::1"},
+		{remoteAddr: "10.0.0.2:8080", xff: "198.51.100.1, 10.0.0.9", want: "198.51.100.1"},
+		{remoteAddr: "10.0.0.2:8080", xff: "not an ip", want: "10.0.0.2"},
+		{remoteAddr: "pipe", want: ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if got := ClientIP(r); got != tt.want {
+			t.Errorf("ClientIP(%q, %q) = %q, want %q", tt.remoteAddr, tt.xff, got, tt.want)
+		}
+	}
+}
+
 func TestGetEnvOrError(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -161,3 +185,76 @@ func TestGetEnvOrError(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		write    func(http.ResponseWriter, int, any, http.Header) error
+		data     any
+		wantErr  bool
+		wantBody string
+	}{
+		{name: "buffered", write: WriteJSON, data: Map{"a": "<b>"}, wantBody: `{"a":"\u003cb\u003e"}`},
+		{name: "buffered error", write: WriteJSON, data: Map{"ch": make(chan int)}, wantErr: true},
+		{name: "stream", write: WriteJSONStream, data: Map{"a": 1}, wantBody: "{\"a\":1}\n"},
+		{name: "stream error", write: WriteJSONStream, data: Map{"ch": make(chan int)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			err := tt.write(w, http.StatusCreated, tt.data, http.Header{"X-Test": {"1"}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if w.Body.Len() != 0 || w.Code != http.StatusOK || w.Header().Get("X-Test") != "" {
+					t.Errorf("wrote %d %q %v on error", w.Code, w.Body.String(), w.Header())
+				}
+				return
+			}
+			if w.Code != http.StatusCreated || w.Body.String() != tt.wantBody || w.Header().Get("X-Test") != "1" {
+				t.Errorf("response = %d %q %v", w.Code, w.Body.String(), w.Header())
+			}
+		})
+	}
+}
+
+// discardWriter is a ResponseWriter with no recording overhead, for
+// benchmarks.
+type discardWriter struct{ h http.Header }
+
+func (w *discardWriter) Header() http.Header         { return w.h }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func benchmarkPayload() any {
+	type item struct {
+		ID    int      `json:"id"`
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Price float64  `json:"price"`
+	}
+	items := make([]item, 1000)
+	for i := range items {
+		items[i] = item{ID: i, Name: "item name", Tags: []string{"a", "b"}, Price: 9.99}
+	}
+	return Map{"items": items}
+}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	data := benchmarkPayload()
+	w := &discardWriter{h: make(http.Header)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = WriteJSON(w, http.StatusOK, data, nil)
+	}
+}
+
+func BenchmarkWriteJSONStream(b *testing.B) {
+	data := benchmarkPayload()
+	w := &discardWriter{h: make(http.Header)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = WriteJSONStream(w, http.StatusOK, data, nil)
+	}
+}
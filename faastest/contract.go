@@ -0,0 +1,334 @@
+package faastest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// maxRefDepth bounds how deeply $ref chains are followed, so recursive
+// schemas such as trees are only checked a few levels down.
+const maxRefDepth = 16
+
+// Contract checks responses against the operations of an OpenAPI 3
+// document, so the functions behind an API keep to its published
+// contract. A response breaks the contract when its status is not listed
+// for the operation, or when it is JSON and does not match the schema
+// given for its status.
+//
+// Install it for every Invoke of a test with UseContract, or wrap a
+// handler served by other means with Wrap.
+type Contract struct {
+	doc        map[string]any
+	operations []*contractOperation
+
+	mu      sync.Mutex
+	schemas map[string]*faas.JSONSchema
+}
+
+// contractOperation is an operation of the document.
+type contractOperation struct {
+	method    string
+	path      string
+	segments  []string
+	params    int
+	responses map[string]any
+}
+
+var contractMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// ParseContract parses an OpenAPI 3 document in JSON. Documents written in
+// YAML need converting first.
+func ParseContract(data []byte) (*Contract, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("faastest: contract must be an OpenAPI document in JSON: %w", err)
+	}
+	if v, _ := doc["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, errors.New("faastest: contract must be an OpenAPI 3 document")
+	}
+	c := &Contract{doc: doc, schemas: make(map[string]*faas.JSONSchema)}
+	paths, _ := doc["paths"].(map[string]any)
+	for path, item := range paths {
+		item, _ := item.(map[string]any)
+		for _, method := range contractMethods {
+			op, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			responses, _ := op["responses"].(map[string]any)
+			o := &contractOperation{method: strings.ToUpper(method), path: path, segments: strings.Split(strings.Trim(path, "/"), "/"), responses: responses}
+			for _, s := range o.segments {
+				if strings.HasPrefix(s, "{") {
+					o.params++
+				}
+			}
+			c.operations = append(c.operations, o)
+		}
+	}
+	// literal paths win over templated ones, so /orders/export is not
+	// taken for /orders/{id}
+	sort.SliceStable(c.operations, func(i, j int) bool { return c.operations[i].params < c.operations[j].params })
+	return c, nil
+}
+
+// LoadContract reads an OpenAPI document with ParseContract, failing the
+// test when it cannot.
+func LoadContract(t testing.TB, path string) *Contract {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseContract(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// Check returns an error describing how resp, the response to r, breaks
+// the contract, or nil when it keeps to it.
+func (c *Contract) Check(r *http.Request, resp *Response) error {
+	op := c.operation(r.Method, r.URL.Path)
+	if op == nil {
+		return fmt.Errorf("%s %s is not an operation of the contract", r.Method, r.URL.Path)
+	}
+	key, response := op.response(resp.Status)
+	if response == nil {
+		return fmt.Errorf("%s %s (%s %s) responded %d, expected one of %s\nbody:\n%s",
+			r.Method, r.URL.Path, op.method, op.path, resp.Status, strings.Join(op.statuses(), ", "), indentBody(resp.Body))
+	}
+	if !isJSON(resp.Header.Get("Content-Type")) {
+		return nil
+	}
+	schema, err := c.schema(op, key, response)
+	if err != nil || schema == nil {
+		return err
+	}
+	var verrs faas.ValidationErrors
+	if err := schema.Validate(resp.Body); errors.As(err, &verrs) {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %s (%s %s) responded %d not matching its schema:\n", r.Method, r.URL.Path, op.method, op.path, resp.Status)
+		for _, e := range verrs {
+			fmt.Fprintf(&b, "  - %s: %s\n", e.Field, e.Message)
+		}
+		fmt.Fprintf(&b, "body:\n%s", indentBody(resp.Body))
+		return errors.New(b.String())
+	}
+	return nil
+}
+
+// Assert fails the test when resp, the response to r, breaks the
+// contract.
+func (c *Contract) Assert(t testing.TB, r *http.Request, resp *Response) {
+	t.Helper()
+	if err := c.Check(r, resp); err != nil {
+		t.Errorf("response breaks the OpenAPI contract: %v", err)
+	}
+}
+
+// Wrap returns handler with every response checked against the contract,
+// failing the test when one breaks it.
+func (c *Contract) Wrap(t testing.TB, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := serve(handler, r)
+		c.Assert(t, r, resp)
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.Status)
+		_, _ = w.Write(resp.Body)
+	})
+}
+
+var (
+	contractMu sync.Mutex
+	contract   *Contract
+	contractT  testing.TB
+)
+
+// UseContract checks the response of every Invoke and InvokeRequest
+// against c until the test ends, failing the test when one breaks it.
+func UseContract(t testing.TB, c *Contract) {
+	t.Helper()
+	contractMu.Lock()
+	oldC, oldT := contract, contractT
+	contract, contractT = c, t
+	contractMu.Unlock()
+	t.Cleanup(func() {
+		contractMu.Lock()
+		contract, contractT = oldC, oldT
+		contractMu.Unlock()
+	})
+}
+
+// checkContract applies the contract installed by UseContract.
+func checkContract(r *http.Request, resp *Response) {
+	contractMu.Lock()
+	c, t := contract, contractT
+	contractMu.Unlock()
+	if c != nil {
+		t.Helper()
+		c.Assert(t, r, resp)
+	}
+}
+
+func (c *Contract) operation(method, path string) *contractOperation {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, op := range c.operations {
+		if op.method == method && op.matches(segments) {
+			return op
+		}
+	}
+	return nil
+}
+
+func (op *contractOperation) matches(segments []string) bool {
+	if len(segments) != len(op.segments) {
+		return false
+	}
+	for i, s := range op.segments {
+		if !strings.HasPrefix(s, "{") && s != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// response returns the response documented for status, trying the exact
+// code, then a range such as 2XX, then default.
+func (op *contractOperation) response(status int) (string, any) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if r, ok := op.responses[key]; ok {
+			return key, r
+		}
+	}
+	return "", nil
+}
+
+func (op *contractOperation) statuses() []string {
+	statuses := make([]string, 0, len(op.responses))
+	for k := range op.responses {
+		statuses = append(statuses, k)
+	}
+	sort.Strings(statuses)
+	return statuses
+}
+
+// schema returns the compiled JSON schema of a response, or nil when it
+// documents no JSON content.
+func (c *Contract) schema(op *contractOperation, key string, response any) (*faas.JSONSchema, error) {
+	cacheKey := op.method + " " + op.path + " " + key
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.schemas[cacheKey]; ok {
+		return s, nil
+	}
+	resolved, _ := c.resolve(response, 0).(map[string]any)
+	content, _ := resolved["content"].(map[string]any)
+	var raw any
+	for mediaType, media := range content {
+		if isJSON(mediaType) {
+			media, _ := media.(map[string]any)
+			raw = media["schema"]
+			break
+		}
+	}
+	if raw == nil {
+		c.schemas[cacheKey] = nil
+		return nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	s, err := faas.ParseJSONSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s response %s: %w", op.method, op.path, key, err)
+	}
+	c.schemas[cacheKey] = s
+	return s, nil
+}
+
+// resolve returns v with $refs to the document replaced by their target,
+// and the OpenAPI 3.0 nullable and boolean exclusiveMinimum and
+// exclusiveMaximum rewritten as JSON Schema.
+func (c *Contract) resolve(v any, depth int) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			if depth >= maxRefDepth {
+				return true
+			}
+			if target, ok := c.lookup(ref); ok {
+				return c.resolve(target, depth+1)
+			}
+		}
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			out[k] = c.resolve(child, depth)
+		}
+		if nullable, _ := out["nullable"].(bool); nullable {
+			if t, ok := out["type"].(string); ok {
+				out["type"] = []any{t, "null"}
+			}
+		}
+		for _, bound := range [][2]string{{"exclusiveMinimum", "minimum"}, {"exclusiveMaximum", "maximum"}} {
+			if exclusive, ok := out[bound[0]].(bool); ok {
+				delete(out, bound[0])
+				if exclusive {
+					out[bound[0]] = out[bound[1]]
+					delete(out, bound[1])
+				}
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			out[i] = c.resolve(child, depth)
+		}
+		return out
+	}
+	return v
+}
+
+// lookup follows a local JSON pointer such as #/components/schemas/Order.
+func (c *Contract) lookup(ref string) (any, bool) {
+	pointer, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, false
+	}
+	var node any = c.doc
+	for _, token := range strings.Split(pointer, "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if node, ok = m[token]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+func indentBody(body []byte) string {
+	var b bytes.Buffer
+	if json.Indent(&b, body, "", "  ") == nil {
+		return b.String()
+	}
+	return string(body)
+}
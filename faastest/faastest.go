@@ -0,0 +1,272 @@
+// Package faastest provides helpers for unit testing function handlers
+// without repeating httptest plumbing:
+//
+//	func TestHandle(t *testing.T) {
+//		faastest.UseSecrets(t, faastest.Secrets{"api-key": "test"})
+//		resp := faastest.Invoke(http.HandlerFunc(Handle), "POST", "/", faas.Map{"name": "ada"})
+//		if resp.Status != http.StatusOK {
+//			t.Fatalf("status = %d, error = %+v", resp.Status, resp.Err)
+//		}
+//		faastest.AssertGolden(t, resp, "testdata/handle.golden")
+//	}
+//
+// Helpers replacing process wide state, such as UseSecrets and KeepEnv,
+// must not be used by parallel tests.
+package faastest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// Options configures the request made by Invoke.
+type Options struct {
+	Header http.Header
+	// Query is added to the query string of the path.
+	Query url.Values
+	// Context defaults to context.Background.
+	Context context.Context
+	// RemoteAddr defaults to the httptest address, 192.0.2.1:1234.
+	RemoteAddr string
+}
+
+// Response is the response written by a handler called by Invoke.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// JSON is the decoded body of a JSON response, a faas.Map for an
+	// object. It is nil for other responses.
+	JSON any
+	// Err is the decoded body of an error written by faas.WriteError, for
+	// responses with a 4xx or 5xx status.
+	Err *faas.Error
+}
+
+// Decode unmarshals the body into v.
+func (r *Response) Decode(v any) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("faastest: decoding %d response %q: %w", r.Status, snippet(r.Body), err)
+	}
+	return nil
+}
+
+// Invoke calls handler with a request built from method, path and body,
+// returning the decoded response. body may be nil, a string, a []byte or
+// an io.Reader sent as is, or any other value sent as JSON with a
+// Content-Type of application/json. Invoke panics if body cannot be
+// encoded, as that is a mistake in the test.
+func Invoke(handler http.Handler, method, path string, body any, opts ...Options) *Response {
+	var o Options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	reader, isJSON := requestBody(body)
+	r := httptest.NewRequest(method, path, reader)
+	for k, v := range o.Header {
+		r.Header[k] = v
+	}
+	if isJSON && r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if len(o.Query) > 0 {
+		q := r.URL.Query()
+		for k, v := range o.Query {
+			q[k] = append(q[k], v...)
+		}
+		r.URL.RawQuery = q.Encode()
+		r.RequestURI = r.URL.RequestURI()
+	}
+	if o.Context != nil {
+		r = r.WithContext(o.Context)
+	}
+	if o.RemoteAddr != "" {
+		r.RemoteAddr = o.RemoteAddr
+	}
+
+	return InvokeRequest(handler, r)
+}
+
+// InvokeRequest calls handler with r, such as a request built by
+// GitHubWebhook, returning the decoded response.
+func InvokeRequest(handler http.Handler, r *http.Request) *Response {
+	resp := serve(handler, r)
+	checkContract(r, resp)
+	return resp
+}
+
+func serve(handler http.Handler, r *http.Request) *Response {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	return newResponse(rec.Result())
+}
+
+func requestBody(body any) (io.Reader, bool) {
+	switch b := body.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return strings.NewReader(b), false
+	case []byte:
+		return bytes.NewReader(b), false
+	case io.Reader:
+		return b, false
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		panic(fmt.Sprintf("faastest: encoding request body: %v", err))
+	}
+	return bytes.NewReader(data), true
+}
+
+func newResponse(res *http.Response) *Response {
+	body, _ := io.ReadAll(res.Body)
+	resp := &Response{Status: res.StatusCode, Header: res.Header, Body: body}
+	if !isJSON(res.Header.Get("Content-Type")) {
+		return resp
+	}
+	var v any
+	if json.Unmarshal(body, &v) == nil {
+		if m, ok := v.(map[string]any); ok {
+			v = faas.Map(m)
+		}
+		resp.JSON = v
+	}
+	if resp.Status >= 400 {
+		var e faas.Error
+		if json.Unmarshal(body, &e) == nil && e.Code != 0 {
+			resp.Err = &e
+		}
+	}
+	return resp
+}
+
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mt == "application/json" || strings.HasSuffix(mt, "+json"))
+}
+
+func snippet(b []byte) string {
+	if len(b) > 200 {
+		return string(b[:200]) + "..."
+	}
+	return string(b)
+}
+
+// Secrets is a faas.SecretProvider holding secrets in memory.
+type Secrets map[string]string
+
+// Secret implements faas.SecretProvider.
+func (s Secrets) Secret(name string) ([]byte, error) {
+	v, ok := s[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return []byte(v), nil
+}
+
+// FailingSecrets is a faas.SecretProvider failing every lookup with Err,
+// to test how a handler copes with an unavailable secret store.
+type FailingSecrets struct {
+	Err error
+}
+
+// Secret implements faas.SecretProvider.
+func (s FailingSecrets) Secret(string) ([]byte, error) {
+	return nil, s.Err
+}
+
+// UseSecrets replaces faas.DefaultSecretProvider with p until the test
+// ends.
+func UseSecrets(t testing.TB, p faas.SecretProvider) {
+	t.Helper()
+	old := faas.DefaultSecretProvider
+	faas.DefaultSecretProvider = p
+	t.Cleanup(func() { faas.DefaultSecretProvider = old })
+}
+
+// EnvSnapshot is a copy of the process environment.
+type EnvSnapshot []string
+
+// SnapshotEnv copies the current environment.
+func SnapshotEnv() EnvSnapshot {
+	return os.Environ()
+}
+
+// Restore replaces the environment with the snapshot, removing variables
+// set since it was taken.
+func (s EnvSnapshot) Restore() {
+	os.Clearenv()
+	for _, kv := range s {
+		// skip the first byte, as Windows names such as "=C:" start with "="
+		if i := strings.Index(kv[min(1, len(kv)):], "="); i >= 0 {
+			os.Setenv(kv[:i+1], kv[i+2:])
+		}
+	}
+}
+
+// KeepEnv restores the environment when the test ends, for code under test
+// calling os.Setenv. Unlike t.Setenv it also undoes changes made by that
+// code.
+func KeepEnv(t testing.TB) {
+	t.Helper()
+	t.Cleanup(SnapshotEnv().Restore)
+}
+
+// UpdateGoldenEnv is the environment variable which, when set to 1, makes
+// AssertGolden write the golden files instead of comparing against them.
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// AssertGolden fails the test unless the status, Content-Type and body of
+// resp match the golden file at path. JSON bodies are indented so the file
+// diffs well. Run the tests with UPDATE_GOLDEN=1 to create or update the
+// files.
+func AssertGolden(t testing.TB, resp *Response, path string) {
+	t.Helper()
+	got := golden(resp)
+	if os.Getenv(UpdateGoldenEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("response does not match %s (run with %s=1 to update it)\ngot:\n%s\nwant:\n%s", path, UpdateGoldenEnv, got, want)
+	}
+}
+
+func golden(resp *Response) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d %s\n", resp.Status, http.StatusText(resp.Status))
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		fmt.Fprintf(&b, "Content-Type: %s\n", ct)
+	}
+	b.WriteString("\n")
+	if isJSON(resp.Header.Get("Content-Type")) && json.Indent(&b, resp.Body, "", "  ") == nil {
+		b.WriteString("\n")
+		return b.Bytes()
+	}
+	b.Write(resp.Body)
+	return b.Bytes()
+}
@@ -0,0 +1,327 @@
+package faastest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+func greet(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := faas.ReadJSON(w, r, &in); err != nil {
+		faas.WriteError(w, faas.E(faas.CodeInvalidArgument, err.Error(), err))
+		return
+	}
+	greeting, err := faas.GetSecretString("greeting")
+	if err != nil {
+		faas.WriteError(w, err)
+		return
+	}
+	faas.WriteJSON(w, http.StatusOK, faas.Map{"message": greeting + " " + in.Name, "lang": r.URL.Query().Get("lang")}, nil)
+}
+
+func TestInvoke(t *testing.T) {
+	UseSecrets(t, Secrets{"greeting": " hello\n"})
+
+	resp := Invoke(http.HandlerFunc(greet), http.MethodPost, "/?x=1", faas.Map{"name": "ada"}, Options{Query: url.Values{"lang": {"en"}}})
+	if resp.Status != http.StatusOK || resp.Err != nil {
+		t.Fatalf("status = %d, err = %+v, body = %s", resp.Status, resp.Err, resp.Body)
+	}
+	m, ok := resp.JSON.(faas.Map)
+	if !ok || m["message"] != "hello ada" || m["lang"] != "en" {
+		t.Errorf("JSON = %#v", resp.JSON)
+	}
+	var out struct{ Message string }
+	if err := resp.Decode(&out); err != nil || out.Message != "hello ada" {
+		t.Errorf("Decode() = %+v, %v", out, err)
+	}
+	AssertGolden(t, resp, filepath.Join("testdata", "greet.golden"))
+
+	resp = Invoke(http.HandlerFunc(greet), http.MethodPost, "/", `{"name": 1}`)
+	if resp.Status != http.StatusBadRequest || resp.Err == nil || resp.Err.ErrorCode != string(faas.CodeInvalidArgument) {
+		t.Errorf("bad body response = %d %+v", resp.Status, resp.Err)
+	}
+	if err := resp.Decode(new(int)); err == nil {
+		t.Error("Decode() into int succeeded")
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	UseSecrets(t, Secrets{})
+	if _, err := faas.GetSecret("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing secret err = %v", err)
+	}
+
+	unavailable := errors.New("vault sealed")
+	UseSecrets(t, FailingSecrets{Err: unavailable})
+	if _, err := faas.GetSecret("any"); !errors.Is(err, unavailable) {
+		t.Errorf("err = %v", err)
+	}
+	resp := Invoke(http.HandlerFunc(greet), http.MethodPost, "/", []byte(`{"name":"ada"}`))
+	if resp.Status != http.StatusInternalServerError || resp.Err == nil {
+		t.Errorf("response = %d %s", resp.Status, resp.Body)
+	}
+}
+
+func TestEnvSnapshot(t *testing.T) {
+	t.Setenv("FAASTEST_KEPT", "before")
+	snap := SnapshotEnv()
+	os.Setenv("FAASTEST_KEPT", "after")
+	os.Setenv("FAASTEST_ADDED", "1")
+	snap.Restore()
+	if got := os.Getenv("FAASTEST_KEPT"); got != "before" {
+		t.Errorf("FAASTEST_KEPT = %q", got)
+	}
+	if _, ok := os.LookupEnv("FAASTEST_ADDED"); ok {
+		t.Error("FAASTEST_ADDED survived Restore")
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.golden")
+	resp := &Response{Status: http.StatusTeapot, Header: http.Header{"Content-Type": {"text/plain"}}, Body: []byte("short and stout")}
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	AssertGolden(t, resp, path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "418 I'm a teapot\nContent-Type: text/plain\n\nshort and stout"; string(data) != want {
+		t.Errorf("golden = %q, want %q", data, want)
+	}
+
+	t.Setenv(UpdateGoldenEnv, "")
+	AssertGolden(t, resp, path)
+}
+
+func TestGateway(t *testing.T) {
+	gw := NewGateway(t)
+	gw.Handle("pricing", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		faas.WriteJSON(w, http.StatusOK, faas.Map{"path": r.URL.Path, "call_id": r.Header.Get("X-Call-Id")}, nil)
+	}))
+	gw.Handle("receiver", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	var out struct {
+		Path   string `json:"path"`
+		CallID string `json:"call_id"`
+	}
+	if err := faas.DoJSON(context.Background(), nil, http.MethodPost, faas.FunctionURL("pricing")+"/quote", faas.Map{"sku": "a"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Path != "/quote" || out.CallID == "" {
+		t.Errorf("sync response = %+v", out)
+	}
+	if err := faas.DoJSON(context.Background(), nil, http.MethodGet, faas.FunctionURL("missing"), nil, nil); err == nil {
+		t.Error("calling an undeployed function succeeded")
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, gw.URL+"/async-function/pricing", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("X-Callback-Url", faas.FunctionURL("receiver"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("X-Call-Id") == "" {
+		t.Fatalf("async response = %d %v", resp.StatusCode, resp.Header)
+	}
+	gw.Wait()
+	calls := gw.Calls()
+	if len(calls) != 3 || calls[0].Path != "/quote" || !calls[1].Async || calls[2].Function != "receiver" {
+		t.Fatalf("calls = %+v", calls)
+	}
+	callback := calls[2]
+	if callback.Header.Get("X-Function-Status") != "200" || callback.Header.Get("X-Call-Id") != resp.Header.Get("X-Call-Id") || !bytes.Contains(callback.Body, []byte(`"path":"/"`)) {
+		t.Errorf("callback = %+v, body = %s", callback, callback.Body)
+	}
+}
+
+func TestGatewaySecrets(t *testing.T) {
+	gw := NewGateway(t)
+	UseSecrets(t, gw)
+	do := func(method, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, gw.URL+"/system/secrets", strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := do(http.MethodPost, `{"name": "api-key", "value": "one"}`); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create = %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, `{"name": "api-key", "value": "two"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("duplicate create = %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPut, `{"name": "api-key", "value": "two"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("update = %d", resp.StatusCode)
+	}
+	if got, err := faas.GetSecretString("api-key"); err != nil || got != "two" {
+		t.Errorf("GetSecretString() = %q, %v", got, err)
+	}
+
+	var list []struct{ Name, Value string }
+	if err := json.NewDecoder(do(http.MethodGet, "").Body).Decode(&list); err != nil || len(list) != 1 || list[0].Name != "api-key" || list[0].Value != "" {
+		t.Errorf("list = %+v, %v", list, err)
+	}
+
+	if resp := do(http.MethodDelete, `{"name": "api-key"}`); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("delete = %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodDelete, `{"name": "api-key"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second delete = %d", resp.StatusCode)
+	}
+	if _, err := faas.GetSecret("api-key"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("deleted secret err = %v", err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	// the examples from the GitHub and Slack documentation
+	r := GitHubWebhook("It's a Secret to Everybody", "push", "Hello, World!")
+	if got := r.Header.Get("X-Hub-Signature-256"); got != "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17" {
+		t.Errorf("GitHub signature = %q", got)
+	}
+	if r.Header.Get("X-GitHub-Event") != "push" || r.Header.Get("X-GitHub-Delivery") == "" {
+		t.Errorf("GitHub headers = %v", r.Header)
+	}
+
+	slackBody := "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+	r = SlackWebhook("8f742231b10e8888abcd99yyyzzz85a5", slackBody, WebhookOptions{Time: time.Unix(1531420618, 0)})
+	if got := r.Header.Get("X-Slack-Signature"); got != "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503" {
+		t.Errorf("Slack signature = %q", got)
+	}
+	if r = SlackWebhook("s", url.Values{"command": {"/deploy"}}); r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" || r.FormValue("command") != "/deploy" {
+		t.Errorf("Slack form request = %v", r.Header)
+	}
+
+	at := time.Unix(1700000000, 0)
+	r = StripeWebhook("whsec_test", faas.Map{"type": "charge.succeeded"}, WebhookOptions{Time: at, Path: "/stripe"})
+	body, _ := io.ReadAll(r.Body)
+	if want := "t=1700000000,v1=" + faas.SignWebhook([]byte("whsec_test"), "1700000000", body); r.Header.Get("Stripe-Signature") != want {
+		t.Errorf("Stripe-Signature = %q, want %q", r.Header.Get("Stripe-Signature"), want)
+	}
+	if r.URL.Path != "/stripe" || r.Header.Get("Content-Type") != "application/json" || string(body) != `{"type":"charge.succeeded"}` {
+		t.Errorf("Stripe request = %s %v %s", r.URL.Path, r.Header, body)
+	}
+
+	r = SenderWebhook("shh", "order.created", faas.Map{"id": 1}, WebhookOptions{Time: at})
+	body, _ = io.ReadAll(r.Body)
+	if r.Header.Get("X-Webhook-Signature") != "sha256="+faas.SignWebhook([]byte("shh"), "1700000000", body) || r.Header.Get("X-Webhook-Event") != "order.created" {
+		t.Errorf("sender headers = %v", r.Header)
+	}
+
+	r = HMACWebhook("key", "X-Signature", []byte("data"), WebhookOptions{Header: http.Header{"X-Extra": {"1"}}})
+	if r.Header.Get("X-Signature") != "5031fe3d989c6d1537a013fa6e739da23463fdaec3b70137d828e36ace221bd0" || r.Header.Get("X-Extra") != "1" {
+		t.Errorf("HMAC headers = %v", r.Header)
+	}
+
+	resp := InvokeRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}), GitHubWebhook("s", "ping", nil))
+	if resp.Status != http.StatusAccepted {
+		t.Errorf("InvokeRequest() status = %d", resp.Status)
+	}
+}
+
+// recordingT collects the failures reported through it.
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func TestContract(t *testing.T) {
+	c := LoadContract(t, filepath.Join("testdata", "openapi.json"))
+	orders := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders/1":
+			faas.WriteJSON(w, http.StatusOK, faas.Map{"id": "1", "total": 9.5, "note": nil, "parent": faas.Map{"id": "0", "total": 1}}, nil)
+		case "/orders/2":
+			faas.WriteJSON(w, http.StatusOK, faas.Map{"id": 2, "total": 0, "colour": "red"}, nil)
+		case "/orders/export":
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			faas.WriteError(w, faas.E(faas.CodeNotFound, "order not found", nil))
+		}
+	})
+
+	for _, path := range []string{"/orders/1", "/orders/404"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if err := c.Check(r, InvokeRequest(orders, r)); err != nil {
+			t.Errorf("GET %s: %v", path, err)
+		}
+	}
+	r := httptest.NewRequest(http.MethodPost, "/orders/export", nil)
+	if err := c.Check(r, InvokeRequest(orders, r)); err != nil {
+		t.Errorf("POST /orders/export: %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/orders/2", nil)
+	err := c.Check(r, InvokeRequest(orders, r))
+	for _, want := range []string{"GET /orders/{id}", "id: must be of type string", "total: must be greater than 0", "colour: is not allowed", `"colour": "red"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want it to mention %q", err, want)
+		}
+	}
+	r = httptest.NewRequest(http.MethodGet, "/customers", nil)
+	if err := c.Check(r, &Response{Status: http.StatusOK}); err == nil || !strings.Contains(err.Error(), "not an operation") {
+		t.Errorf("unknown operation err = %v", err)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/orders/export", nil)
+	if err := c.Check(r, &Response{Status: http.StatusOK}); err == nil || !strings.Contains(err.Error(), "expected one of 202") {
+		t.Errorf("undocumented status err = %v", err)
+	}
+
+	var rt *recordingT
+	t.Run("UseContract", func(t *testing.T) {
+		rt = &recordingT{TB: t}
+		UseContract(rt, c)
+		Invoke(orders, http.MethodGet, "/orders/1", nil)
+		Invoke(orders, http.MethodGet, "/orders/2", nil)
+	})
+	if len(rt.failures) != 1 || !strings.Contains(rt.failures[0], "/orders/2") {
+		t.Errorf("UseContract failures = %q", rt.failures)
+	}
+	Invoke(orders, http.MethodGet, "/orders/2", nil)
+
+	srv := httptest.NewServer(c.Wrap(rt, orders))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/orders/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(rt.failures) != 1 {
+		t.Errorf("wrapped status = %d, failures = %q", resp.StatusCode, rt.failures)
+	}
+
+	if _, err := ParseContract([]byte(`{"swagger": "2.0"}`)); err == nil {
+		t.Error("ParseContract accepted a Swagger 2 document")
+	}
+}
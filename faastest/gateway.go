@@ -0,0 +1,260 @@
+package faastest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Call is an invocation received by a Gateway.
+type Call struct {
+	Function string
+	Method   string
+	// Path is the path after the function name, "/" when there is none,
+	// with the query string.
+	Path   string
+	Header http.Header
+	Body   []byte
+	Async  bool
+	CallID string
+}
+
+// Gateway is a fake OpenFaaS gateway, for testing code which calls other
+// functions, through faas.FunctionURL or a client made by
+// faas.GenerateClient, without a cluster:
+//
+//	gw := faastest.NewGateway(t)
+//	gw.Handle("pricing", http.HandlerFunc(pricing.Handle))
+//	resp := faastest.Invoke(http.HandlerFunc(Handle), "POST", "/", order)
+//
+// It serves /function/<name> synchronously, and /async-function/<name> by
+// answering 202 Accepted and calling the function in the background,
+// posting its response to the X-Callback-Url header when there is one.
+// The /system/secrets endpoints manage secrets, which the gateway also
+// provides to functions when installed with UseSecrets.
+type Gateway struct {
+	// URL is the base URL of the gateway, e.g. http://127.0.0.1:34567.
+	URL string
+
+	t      testing.TB
+	server *httptest.Server
+	async  sync.WaitGroup
+
+	mu        sync.Mutex
+	functions map[string]http.Handler
+	secrets   map[string]string
+	calls     []Call
+	nextID    int
+}
+
+// NewGateway starts a Gateway and points GATEWAY_URL at it until the test
+// ends, when the gateway waits for asynchronous calls and shuts down.
+func NewGateway(t testing.TB) *Gateway {
+	t.Helper()
+	g := &Gateway{t: t, functions: make(map[string]http.Handler), secrets: make(map[string]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/function/", g.serveFunction)
+	mux.HandleFunc("/async-function/", g.serveFunction)
+	mux.HandleFunc("/system/secrets", g.serveSecrets)
+	g.server = httptest.NewServer(mux)
+	g.URL = g.server.URL
+	t.Setenv("GATEWAY_URL", g.URL)
+	t.Cleanup(func() {
+		g.Wait()
+		g.server.Close()
+	})
+	return g
+}
+
+// Handle deploys handler as the function name.
+func (g *Gateway) Handle(name string, handler http.Handler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.functions[name] = handler
+}
+
+// SetSecret creates or replaces a secret.
+func (g *Gateway) SetSecret(name, value string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.secrets[name] = value
+}
+
+// Secret implements faas.SecretProvider with the secrets of the gateway.
+func (g *Gateway) Secret(name string) ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	v, ok := g.secrets[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return []byte(v), nil
+}
+
+// Calls returns the invocations received so far, of every function.
+func (g *Gateway) Calls() []Call {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Call(nil), g.calls...)
+}
+
+// Wait blocks until the asynchronous calls received so far, and their
+// callbacks, have finished.
+func (g *Gateway) Wait() {
+	g.async.Wait()
+}
+
+func (g *Gateway) serveFunction(w http.ResponseWriter, r *http.Request) {
+	prefix, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	name, path, _ := strings.Cut(rest, "/")
+	path = "/" + path
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	g.mu.Lock()
+	handler, ok := g.functions[name]
+	g.nextID++
+	call := Call{
+		Function: name,
+		Method:   r.Method,
+		Path:     path,
+		Header:   r.Header.Clone(),
+		Body:     body,
+		Async:    prefix == "async-function",
+		CallID:   fmt.Sprintf("call-%d", g.nextID),
+	}
+	if ok {
+		g.calls = append(g.calls, call)
+	}
+	g.mu.Unlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("error finding function %s: not deployed", name), http.StatusNotFound)
+		return
+	}
+
+	if !call.Async {
+		w.Header().Set("X-Call-Id", call.CallID)
+		handler.ServeHTTP(w, g.functionRequest(call))
+		return
+	}
+	g.async.Add(1)
+	go func() {
+		defer g.async.Done()
+		g.runAsync(handler, call)
+	}()
+	w.Header().Set("X-Call-Id", call.CallID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// functionRequest is the request a function receives for call.
+func (g *Gateway) functionRequest(call Call) *http.Request {
+	r := httptest.NewRequest(call.Method, call.Path, bytes.NewReader(call.Body))
+	r.Header = call.Header.Clone()
+	r.Header.Set("X-Call-Id", call.CallID)
+	r.Header.Set("X-Start-Time", strconv.FormatInt(time.Now().UnixNano(), 10))
+	return r
+}
+
+// runAsync calls the function, then posts its response to the callback
+// URL, as the OpenFaaS queue worker does.
+func (g *Gateway) runAsync(handler http.Handler, call Call) {
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, g.functionRequest(call))
+	callback := call.Header.Get("X-Callback-Url")
+	if callback == "" {
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, callback, bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		g.t.Errorf("faastest: invalid callback URL %q: %v", callback, err)
+		return
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+	req.Header.Set("X-Call-Id", call.CallID)
+	req.Header.Set("X-Function-Name", call.Function)
+	req.Header.Set("X-Function-Status", strconv.Itoa(rec.Code))
+	req.Header.Set("X-Duration-Seconds", strconv.FormatFloat(time.Since(start).Seconds(), 'f', 6, 64))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		g.t.Errorf("faastest: posting callback of %s to %s: %v", call.Function, callback, err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// gatewaySecret is a secret in the body of the /system/secrets endpoints.
+type gatewaySecret struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Value     string `json:"value,omitempty"`
+}
+
+func (g *Gateway) serveSecrets(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		g.mu.Lock()
+		list := make([]gatewaySecret, 0, len(g.secrets))
+		for name := range g.secrets {
+			list = append(list, gatewaySecret{Name: name, Namespace: "openfaas-fn"})
+		}
+		g.mu.Unlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	var s gatewaySecret
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil || s.Name == "" {
+		http.Error(w, "invalid secret: a JSON object with a name is required", http.StatusBadRequest)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, exists := g.secrets[s.Name]
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			http.Error(w, fmt.Sprintf("secret %s already exists", s.Name), http.StatusConflict)
+			return
+		}
+		g.secrets[s.Name] = s.Value
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPut:
+		if !exists {
+			http.Error(w, fmt.Sprintf("secret %s not found", s.Name), http.StatusNotFound)
+			return
+		}
+		g.secrets[s.Name] = s.Value
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if !exists {
+			http.Error(w, fmt.Sprintf("secret %s not found", s.Name), http.StatusNotFound)
+			return
+		}
+		delete(g.secrets, s.Name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,152 @@
+package faastest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// WebhookOptions configures the requests built by the webhook helpers.
+type WebhookOptions struct {
+	// Path defaults to "/".
+	Path string
+	// Time is when the request is signed. Defaults to now. Set it in the
+	// past to test that stale deliveries are rejected.
+	Time time.Time
+	// Header is added to the request.
+	Header http.Header
+}
+
+// GitHubWebhook returns a GitHub webhook delivery of event, signed with
+// secret in X-Hub-Signature-256 as GitHub does.
+func GitHubWebhook(secret, event string, payload any, opts ...WebhookOptions) *http.Request {
+	body, contentType := webhookBody(payload)
+	r := newWebhookRequest(body, contentType, opts)
+	r.Header.Set("X-GitHub-Event", event)
+	r.Header.Set("X-GitHub-Delivery", randomID())
+	r.Header.Set("X-Hub-Signature-256", "sha256="+sign(secret, body))
+	return r
+}
+
+// StripeWebhook returns a Stripe event delivery signed with the endpoint
+// secret in the Stripe-Signature header, as Stripe does.
+func StripeWebhook(secret string, payload any, opts ...WebhookOptions) *http.Request {
+	body, contentType := webhookBody(payload)
+	r := newWebhookRequest(body, contentType, opts)
+	ts := strconv.FormatInt(webhookTime(opts).Unix(), 10)
+	r.Header.Set("Stripe-Signature", fmt.Sprintf("t=%s,v1=%s", ts, sign(secret, []byte(ts+"."), body)))
+	return r
+}
+
+// SlackWebhook returns a Slack request signed with the signing secret in
+// X-Slack-Signature, as Slack does. Pass url.Values for the form encoded
+// bodies of slash commands and interactions, or a value for the JSON of
+// the Events API.
+func SlackWebhook(secret string, payload any, opts ...WebhookOptions) *http.Request {
+	body, contentType := webhookBody(payload)
+	r := newWebhookRequest(body, contentType, opts)
+	ts := strconv.FormatInt(webhookTime(opts).Unix(), 10)
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+sign(secret, []byte("v0:"+ts+":"), body))
+	return r
+}
+
+// HMACWebhook returns a request with the hex HMAC-SHA256 of its body,
+// keyed with secret, in header, for providers signing the body alone.
+func HMACWebhook(secret, header string, payload any, opts ...WebhookOptions) *http.Request {
+	body, contentType := webhookBody(payload)
+	r := newWebhookRequest(body, contentType, opts)
+	r.Header.Set(header, sign(secret, body))
+	return r
+}
+
+// SenderWebhook returns a request as delivered by faas.WebhookSender with
+// secret, with its X-Webhook-Signature.
+func SenderWebhook(secret, event string, payload any, opts ...WebhookOptions) *http.Request {
+	body, contentType := webhookBody(payload)
+	r := newWebhookRequest(body, contentType, opts)
+	ts := strconv.FormatInt(webhookTime(opts).Unix(), 10)
+	r.Header.Set("X-Webhook-Id", randomID())
+	r.Header.Set("X-Webhook-Event", event)
+	r.Header.Set("X-Webhook-Timestamp", ts)
+	r.Header.Set("X-Webhook-Signature", "sha256="+faas.SignWebhook([]byte(secret), ts, body))
+	return r
+}
+
+// webhookBody encodes payload as Invoke does, with url.Values form encoded.
+func webhookBody(payload any) ([]byte, string) {
+	switch p := payload.(type) {
+	case nil:
+		return nil, ""
+	case string:
+		return []byte(p), ""
+	case []byte:
+		return p, ""
+	case url.Values:
+		return []byte(p.Encode()), "application/x-www-form-urlencoded"
+	case io.Reader:
+		data, err := io.ReadAll(p)
+		if err != nil {
+			panic(fmt.Sprintf("faastest: reading webhook body: %v", err))
+		}
+		return data, ""
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		panic(fmt.Sprintf("faastest: encoding webhook body: %v", err))
+	}
+	return data, "application/json"
+}
+
+func newWebhookRequest(body []byte, contentType string, opts []WebhookOptions) *http.Request {
+	var o WebhookOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	path := o.Path
+	if path == "" {
+		path = "/"
+	}
+	r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range o.Header {
+		r.Header[k] = v
+	}
+	return r
+}
+
+func webhookTime(opts []WebhookOptions) time.Time {
+	if len(opts) > 0 && !opts[0].Time.IsZero() {
+		return opts[0].Time
+	}
+	return time.Now()
+}
+
+// sign returns the hex HMAC-SHA256 of parts keyed with secret.
+func sign(secret string, parts ...[]byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, p := range parts {
+		mac.Write(p)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,215 @@
+package faas
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits s into lower case words of letters and digits, with
+// accents and other scripts transliterated by Transliterate, so "Crème
+// BRÛLÉE!" and "creme brulee" have the same tokens.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(Transliterate(s)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// NGrams returns the character n-grams of the words of s, with each word
+// padded by a space on both sides so its start and end count, e.g. the
+// 3-grams of "cat" are " ca", "cat" and "at ". Duplicates are kept.
+func NGrams(s string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	var grams []string
+	for _, tok := range Tokenize(s) {
+		runes := []rune(" " + tok + " ")
+		for i := 0; i+n <= len(runes); i++ {
+			grams = append(grams, string(runes[i:i+n]))
+		}
+	}
+	return grams
+}
+
+// WordNGrams returns the runs of n consecutive tokens joined by spaces,
+// also known as shingles, for near duplicate detection.
+func WordNGrams(tokens []string, n int) []string {
+	if n <= 0 || len(tokens) < n {
+		return nil
+	}
+	grams := make([]string, 0, len(tokens)-n+1)
+	for i := 0; i+n <= len(tokens); i++ {
+		grams = append(grams, strings.Join(tokens[i:i+n], " "))
+	}
+	return grams
+}
+
+// Levenshtein returns the edit distance between a and b, the least
+// insertions, deletions and substitutions of characters turning a into b.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	// a single row of the distance matrix, over the shorter string
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur := min(row[j]+1, row[j-1]+1, prev+cost)
+			prev, row[j] = row[j], cur
+		}
+	}
+	return row[len(rb)]
+}
+
+// LevenshteinSimilarity is the Levenshtein distance scaled to a
+// similarity from 0, nothing in common, to 1, equal.
+func LevenshteinSimilarity(a, b string) float64 {
+	n := max(len([]rune(a)), len([]rune(b)))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(Levenshtein(a, b))/float64(n)
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of a and b, from 0 to 1,
+// which favours strings sharing a prefix and suits short strings such as
+// names.
+func JaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	window := max(max(len(ra), len(rb))/2-1, 0)
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		for j := max(0, i-window); j < min(len(rb), i+window+1); j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+	transpositions, j := 0, 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// NGramSimilarity returns the Dice coefficient of the character n-grams
+// of a and b, from 0 to 1, which tolerates reordered words and typos in
+// longer strings.
+func NGramSimilarity(a, b string, n int) float64 {
+	ga, gb := NGrams(a, n), NGrams(b, n)
+	if len(ga) == 0 && len(gb) == 0 {
+		return 1
+	}
+	counts := make(map[string]int, len(ga))
+	for _, g := range ga {
+		counts[g]++
+	}
+	shared := 0
+	for _, g := range gb {
+		if counts[g] > 0 {
+			counts[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ga)+len(gb))
+}
+
+// FuzzyScore is the similarity of a and b from 0 to 1 used by FuzzyFind.
+// Both are tokenized, then the Jaro-Winkler similarity, of the tokens in
+// order or sorted so word order does not matter, is averaged with the
+// trigram similarity.
+func FuzzyScore(a, b string) float64 {
+	ta, tb := Tokenize(a), Tokenize(b)
+	na, nb := strings.Join(ta, " "), strings.Join(tb, " ")
+	if na == nb {
+		return 1
+	}
+	jw := JaroWinkler(na, nb)
+	sort.Strings(ta)
+	sort.Strings(tb)
+	jw = max(jw, JaroWinkler(strings.Join(ta, " "), strings.Join(tb, " ")))
+	return (jw + NGramSimilarity(na, nb, 3)) / 2
+}
+
+// FuzzyMatch is a candidate matched by FuzzyFind.
+type FuzzyMatch struct {
+	Value string  `json:"value"`
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+// FuzzyOptions configures FuzzyFind.
+type FuzzyOptions struct {
+	// MinScore drops candidates scoring below it. Defaults to 0.5.
+	MinScore float64
+	// Limit is the most matches returned. Zero means no limit.
+	Limit int
+	// Score scores a candidate against the query. Defaults to FuzzyScore.
+	Score func(query, candidate string) float64
+}
+
+// FuzzyFind scores every candidate against query, returning the matches
+// from best to worst, for search suggestions and duplicate detection:
+//
+//	matches := faas.FuzzyFind("jon smtih", names, faas.FuzzyOptions{Limit: 5})
+func FuzzyFind(query string, candidates []string, opts FuzzyOptions) []FuzzyMatch {
+	score := opts.Score
+	if score == nil {
+		score = FuzzyScore
+	}
+	minScore := opts.MinScore
+	if minScore == 0 {
+		minScore = 0.5
+	}
+	var matches []FuzzyMatch
+	for i, c := range candidates {
+		if s := score(query, c); s >= minScore {
+			matches = append(matches, FuzzyMatch{Value: c, Index: i, Score: s})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+	return matches
+}
@@ -0,0 +1,106 @@
+package faas
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Crème BRÛLÉE! 2x, don't-stop")
+	want := []string{"creme", "brulee", "2x", "don", "t", "stop"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %q, want %q", got, want)
+	}
+}
+
+func TestNGrams(t *testing.T) {
+	if got, want := NGrams("Cat", 3), []string{" ca", "cat", "at "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NGrams() = %q, want %q", got, want)
+	}
+	if got, want := WordNGrams([]string{"a", "b", "c"}, 2), []string{"a b", "b c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WordNGrams() = %q, want %q", got, want)
+	}
+	if got := WordNGrams([]string{"a"}, 2); got != nil {
+		t.Errorf("WordNGrams() = %q, want nil", got)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if got := LevenshteinSimilarity("kitten", "sitting"); !near(got, 1-3.0/7) {
+		t.Errorf("LevenshteinSimilarity() = %v", got)
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"abc", "", 0},
+		{"martha", "marhta", 0.9611},
+		{"dixon", "dicksonx", 0.8133},
+		{"abc", "xyz", 0},
+	}
+	for _, tt := range tests {
+		if got := JaroWinkler(tt.a, tt.b); math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("JaroWinkler(%q, %q) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyFind(t *testing.T) {
+	if got := FuzzyScore("John Smith", "smith, JOHN"); got < 0.9 {
+		t.Errorf("reordered FuzzyScore() = %v", got)
+	}
+	if got := NGramSimilarity("night", "nacht", 3); got >= 0.5 {
+		t.Errorf("NGramSimilarity() = %v", got)
+	}
+
+	names := []string{"Jane Doe", "John Smith", "Jon Smyth", "Johann Schmidt", "Zoe Quinn"}
+	matches := FuzzyFind("jon smith", names, FuzzyOptions{Limit: 2})
+	if len(matches) != 2 {
+		t.Fatalf("matches = %+v", matches)
+	}
+	for _, m := range matches {
+		if m.Value != "John Smith" && m.Value != "Jon Smyth" {
+			t.Errorf("unexpected match %+v", m)
+		}
+		if names[m.Index] != m.Value {
+			t.Errorf("index %d of %q", m.Index, m.Value)
+		}
+	}
+	if matches[0].Score < matches[1].Score {
+		t.Errorf("matches not sorted: %+v", matches)
+	}
+
+	exact := func(q, c string) float64 {
+		if q == c {
+			return 1
+		}
+		return 0
+	}
+	if got := FuzzyFind("Zoe Quinn", names, FuzzyOptions{Score: exact}); len(got) != 1 || got[0].Index != 4 {
+		t.Errorf("custom score matches = %+v", got)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,187 @@
+package faas
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// FunctionResources are the memory and CPU of a function, in Kubernetes
+// quantities such as "128Mi" and "100m".
+type FunctionResources struct {
+	Memory string `json:"memory,omitempty"`
+	CPU    string `json:"cpu,omitempty"`
+}
+
+// FunctionDeployment is the spec of a function, as sent to deploy or update
+// it through the OpenFaaS REST API.
+type FunctionDeployment struct {
+	Service                string             `json:"service"`
+	Image                  string             `json:"image"`
+	Namespace              string             `json:"namespace,omitempty"`
+	EnvProcess             string             `json:"envProcess,omitempty"`
+	EnvVars                map[string]string  `json:"envVars,omitempty"`
+	Constraints            []string           `json:"constraints,omitempty"`
+	Secrets                []string           `json:"secrets,omitempty"`
+	Labels                 map[string]string  `json:"labels,omitempty"`
+	Annotations            map[string]string  `json:"annotations,omitempty"`
+	Limits                 *FunctionResources `json:"limits,omitempty"`
+	Requests               *FunctionResources `json:"requests,omitempty"`
+	ReadOnlyRootFilesystem bool               `json:"readOnlyRootFilesystem,omitempty"`
+}
+
+// FunctionStatus is a deployed function, as listed by the OpenFaaS REST
+// API.
+type FunctionStatus struct {
+	Name                   string             `json:"name"`
+	Image                  string             `json:"image"`
+	Namespace              string             `json:"namespace,omitempty"`
+	EnvProcess             string             `json:"envProcess,omitempty"`
+	EnvVars                map[string]string  `json:"envVars,omitempty"`
+	Constraints            []string           `json:"constraints,omitempty"`
+	Secrets                []string           `json:"secrets,omitempty"`
+	Labels                 map[string]string  `json:"labels,omitempty"`
+	Annotations            map[string]string  `json:"annotations,omitempty"`
+	Limits                 *FunctionResources `json:"limits,omitempty"`
+	Requests               *FunctionResources `json:"requests,omitempty"`
+	ReadOnlyRootFilesystem bool               `json:"readOnlyRootFilesystem,omitempty"`
+	InvocationCount        float64            `json:"invocationCount"`
+	Replicas               uint64             `json:"replicas"`
+	AvailableReplicas      uint64             `json:"availableReplicas"`
+	CreatedAt              time.Time          `json:"createdAt"`
+}
+
+// Deployment returns the spec the function was deployed with.
+func (s *FunctionStatus) Deployment() FunctionDeployment {
+	return FunctionDeployment{
+		Service:                s.Name,
+		Image:                  s.Image,
+		Namespace:              s.Namespace,
+		EnvProcess:             s.EnvProcess,
+		EnvVars:                s.EnvVars,
+		Constraints:            s.Constraints,
+		Secrets:                s.Secrets,
+		Labels:                 s.Labels,
+		Annotations:            s.Annotations,
+		Limits:                 s.Limits,
+		Requests:               s.Requests,
+		ReadOnlyRootFilesystem: s.ReadOnlyRootFilesystem,
+	}
+}
+
+// RedeployAnnotation is set by GatewayAPI.Redeploy to the time of the
+// redeploy.
+const RedeployAnnotation = "com.openfaas.redeployed-at"
+
+// GatewayAPI calls the OpenFaaS REST API, for operator style functions
+// which list, deploy, scale or redeploy their sibling functions:
+//
+//	api := faas.NewGatewayAPI()
+//	err := api.Scale(ctx, "resize", 5)
+//
+// The function needs the gateway credentials, see GatewayCredentials.
+// Failed calls return a *StatusError, with StatusCode 404 for a function
+// which does not exist.
+type GatewayAPI struct {
+	// URL is the gateway URL. Defaults to GATEWAY_URL or the in-cluster
+	// address.
+	URL string
+	// HTTP defaults to GatewayClient, which only authenticates requests to
+	// GATEWAY_URL; set a client adding credentials when URL differs.
+	HTTP *http.Client
+	// Namespace of the functions. Defaults to the gateway's default
+	// namespace.
+	Namespace string
+}
+
+// NewGatewayAPI returns a GatewayAPI for the gateway at GATEWAY_URL.
+func NewGatewayAPI() *GatewayAPI {
+	return &GatewayAPI{URL: gatewayBaseURL(), HTTP: GatewayClient()}
+}
+
+func (g *GatewayAPI) url(path string) string {
+	base := g.URL
+	if base == "" {
+		base = gatewayBaseURL()
+	}
+	u := base + path
+	if g.Namespace != "" {
+		u += "?namespace=" + url.QueryEscape(g.Namespace)
+	}
+	return u
+}
+
+func (g *GatewayAPI) client() *http.Client {
+	if g.HTTP == nil {
+		return GatewayClient()
+	}
+	return g.HTTP
+}
+
+// ListFunctions returns the functions deployed in the namespace.
+func (g *GatewayAPI) ListFunctions(ctx context.Context) ([]FunctionStatus, error) {
+	var fns []FunctionStatus
+	if err := doJSON(ctx, g.client(), http.MethodGet, g.url("/system/functions"), nil, &fns); err != nil {
+		return nil, err
+	}
+	return fns, nil
+}
+
+// Function returns the function name.
+func (g *GatewayAPI) Function(ctx context.Context, name string) (*FunctionStatus, error) {
+	var fn FunctionStatus
+	if err := doJSON(ctx, g.client(), http.MethodGet, g.url("/system/function/"+url.PathEscape(name)), nil, &fn); err != nil {
+		return nil, err
+	}
+	return &fn, nil
+}
+
+// Deploy deploys a new function.
+func (g *GatewayAPI) Deploy(ctx context.Context, d FunctionDeployment) error {
+	return doJSON(ctx, g.client(), http.MethodPost, g.url("/system/functions"), g.withNamespace(d), nil)
+}
+
+// Update replaces the spec of a deployed function.
+func (g *GatewayAPI) Update(ctx context.Context, d FunctionDeployment) error {
+	return doJSON(ctx, g.client(), http.MethodPut, g.url("/system/functions"), g.withNamespace(d), nil)
+}
+
+func (g *GatewayAPI) withNamespace(d FunctionDeployment) FunctionDeployment {
+	if d.Namespace == "" {
+		d.Namespace = g.Namespace
+	}
+	return d
+}
+
+// Delete removes a function.
+func (g *GatewayAPI) Delete(ctx context.Context, name string) error {
+	body := map[string]string{"functionName": name, "namespace": g.Namespace}
+	return doJSON(ctx, g.client(), http.MethodDelete, g.url("/system/functions"), body, nil)
+}
+
+// Scale sets the number of replicas of a function. Scaling to zero needs
+// scale to zero to be enabled on the gateway.
+func (g *GatewayAPI) Scale(ctx context.Context, name string, replicas uint64) error {
+	body := map[string]any{"serviceName": name, "replicas": replicas, "namespace": g.Namespace}
+	return doJSON(ctx, g.client(), http.MethodPost, g.url("/system/scale-function/"+url.PathEscape(name)), body, nil)
+}
+
+// Redeploy replaces the pods of a function, e.g. to pick up a rotated
+// secret or a new image pushed under the same tag. The function is
+// updated with its current spec and RedeployAnnotation set to now, as a
+// spec without changes would not be rolled out.
+func (g *GatewayAPI) Redeploy(ctx context.Context, name string) error {
+	fn, err := g.Function(ctx, name)
+	if err != nil {
+		return err
+	}
+	d := fn.Deployment()
+	annotations := make(map[string]string, len(d.Annotations)+1)
+	for k, v := range d.Annotations {
+		annotations[k] = v
+	}
+	annotations[RedeployAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	d.Annotations = annotations
+	return g.Update(ctx, d)
+}
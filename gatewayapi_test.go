@@ -0,0 +1,86 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type gatewayRequest struct {
+	method, path, namespace, auth string
+	body                          map[string]any
+}
+
+func TestGatewayAPI(t *testing.T) {
+	var mu sync.Mutex
+	var reqs []gatewayRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		req := gatewayRequest{method: r.Method, path: r.URL.Path, namespace: r.URL.Query().Get("namespace"), auth: r.Header.Get("Authorization")}
+		_ = json.Unmarshal(data, &req.body)
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/system/functions" && r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`[{"name":"resize","image":"resize:1","replicas":2,"availableReplicas":2}]`))
+		case r.URL.Path == "/system/function/resize":
+			_, _ = w.Write([]byte(`{"name":"resize","image":"resize:1","namespace":"staging","secrets":["s3"],"annotations":{"topic":"images"}}`))
+		case r.URL.Path == "/system/function/missing":
+			http.Error(w, "not found", http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+	t.Setenv("GATEWAY_NAMESPACE", "")
+	withSecrets(t, map[string]string{GatewayTokenSecret: "tok"})
+
+	ctx := context.Background()
+	api := NewGatewayAPI()
+	api.Namespace = "staging"
+
+	fns, err := api.ListFunctions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fns) != 1 || fns[0].Name != "resize" || fns[0].Replicas != 2 {
+		t.Errorf("functions = %+v", fns)
+	}
+	if err := api.Scale(ctx, "resize", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Redeploy(ctx, "resize"); err != nil {
+		t.Fatal(err)
+	}
+	var statusErr *StatusError
+	if err := api.Redeploy(ctx, "missing"); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("err = %v, want a 404 StatusError", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reqs) != 5 {
+		t.Fatalf("requests = %+v", reqs)
+	}
+	for _, r := range reqs {
+		if r.auth != "Bearer tok" || r.namespace != "staging" {
+			t.Errorf("%s %s: auth %q, namespace %q", r.method, r.path, r.auth, r.namespace)
+		}
+	}
+	scale := reqs[1]
+	if scale.method != http.MethodPost || scale.path != "/system/scale-function/resize" || scale.body["replicas"] != 5.0 {
+		t.Errorf("scale request = %+v", scale)
+	}
+	update := reqs[3]
+	annotations, _ := update.body["annotations"].(map[string]any)
+	if update.method != http.MethodPut || update.body["service"] != "resize" || annotations["topic"] != "images" || annotations[RedeployAnnotation] == nil {
+		t.Errorf("redeploy request = %+v", update)
+	}
+}
@@ -0,0 +1,171 @@
+package faas
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The OpenFaaS secrets GatewayCredentials reads. The basic auth secrets are
+// those created by the OpenFaaS installation for the gateway admin user;
+// functions need them listed under secrets in stack.yml.
+const (
+	GatewayUserSecret     = "basic-auth-user"
+	GatewayPasswordSecret = "basic-auth-password"
+	// GatewayTokenSecret holds a bearer token for the gateway. A secret
+	// named after it and the function's namespace, e.g.
+	// "gateway-token-staging", is preferred when present.
+	GatewayTokenSecret = "gateway-token"
+)
+
+// namespacePath is where Kubernetes mounts the namespace of a pod's service
+// account.
+var namespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// GatewayAuth is the credentials for calling the OpenFaaS gateway. A Token,
+// sent as a bearer token, wins over User and Password.
+type GatewayAuth struct {
+	User     string
+	Password string
+	Token    string
+	// Namespace is the function's namespace, when known, used to pick
+	// a namespace scoped token.
+	Namespace string
+}
+
+// Empty reports whether a has no credentials, as for a gateway without
+// authentication.
+func (a GatewayAuth) Empty() bool {
+	return a.Token == "" && a.User == ""
+}
+
+// Apply sets the Authorization header of r.
+func (a GatewayAuth) Apply(r *http.Request) {
+	switch {
+	case a.Token != "":
+		r.Header.Set("Authorization", "Bearer "+a.Token)
+	case a.User != "":
+		r.SetBasicAuth(a.User, a.Password)
+	}
+}
+
+// GatewayCredentials resolves the gateway credentials from the OpenFaaS
+// secrets: a token from gateway-token-<namespace> or gateway-token, else
+// basic auth from basic-auth-user and basic-auth-password. The namespace is
+// read from GATEWAY_NAMESPACE, falling back to the service account
+// namespace Kubernetes mounts in the pod. Missing secrets are not an error,
+// and give empty credentials.
+func GatewayCredentials() (GatewayAuth, error) {
+	a := GatewayAuth{Namespace: gatewayNamespace()}
+
+	tokens := []string{GatewayTokenSecret}
+	if a.Namespace != "" {
+		tokens = []string{GatewayTokenSecret + "-" + a.Namespace, GatewayTokenSecret}
+	}
+	for _, name := range tokens {
+		token, err := optionalSecret(name)
+		if err != nil {
+			return GatewayAuth{}, err
+		}
+		if token != "" {
+			a.Token = token
+			return a, nil
+		}
+	}
+
+	user, err := optionalSecret(GatewayUserSecret)
+	if err != nil {
+		return GatewayAuth{}, err
+	}
+	pass, err := optionalSecret(GatewayPasswordSecret)
+	if err != nil {
+		return GatewayAuth{}, err
+	}
+	if (user == "") != (pass == "") {
+		return GatewayAuth{}, fmt.Errorf("gateway credentials need both %s and %s", GatewayUserSecret, GatewayPasswordSecret)
+	}
+	a.User, a.Password = user, pass
+	return a, nil
+}
+
+// optionalSecret reads a secret, returning "" when it does not exist.
+func optionalSecret(name string) (string, error) {
+	s, err := getSecretString(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return s, err
+}
+
+func gatewayNamespace() string {
+	if ns := os.Getenv("GATEWAY_NAMESPACE"); ns != "" {
+		return ns
+	}
+	ns, _ := os.ReadFile(namespacePath)
+	return strings.TrimSpace(string(ns))
+}
+
+// gatewayBaseURL is the gateway URL from GATEWAY_URL, defaulting to the
+// in-cluster address http://gateway.openfaas:8080.
+func gatewayBaseURL() string {
+	gateway := os.Getenv("GATEWAY_URL")
+	if gateway == "" {
+		gateway = "http://gateway.openfaas:8080"
+	}
+	return strings.TrimSuffix(gateway, "/")
+}
+
+var (
+	gatewayClientOnce sync.Once
+	gatewayClient     *http.Client
+)
+
+// GatewayClient returns a process wide client, sharing the connection pool
+// of SharedHTTPClient, which adds the GatewayCredentials to requests to the
+// gateway. Credentials are resolved on every request so rotated secrets
+// take effect without a restart, and are never sent to other hosts. Clients
+// made by GenerateClient use it by default.
+func GatewayClient() *http.Client {
+	gatewayClientOnce.Do(func() {
+		shared := SharedHTTPClient()
+		c := *shared
+		c.Transport = gatewayAuthTransport{base: shared.Transport}
+		gatewayClient = &c
+	})
+	return gatewayClient
+}
+
+type gatewayAuthTransport struct {
+	base http.RoundTripper
+}
+
+func (t gatewayAuthTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	gw, err := url.Parse(gatewayBaseURL())
+	if err != nil || r.URL.Host != gw.Host || r.Header.Get("Authorization") != "" {
+		return base.RoundTrip(r)
+	}
+	auth, err := GatewayCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("gateway credentials: %w", err)
+	}
+	if auth.Empty() {
+		return base.RoundTrip(r)
+	}
+	// a RoundTripper must not modify the caller's request
+	r = r.Clone(r.Context())
+	auth.Apply(r)
+	return base.RoundTrip(r)
+}
+
+func (t gatewayAuthTransport) CloseIdleConnections() {
+	closeIdleConnections(t.base)
+}
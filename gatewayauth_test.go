@@ -0,0 +1,82 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestGatewayCredentials(t *testing.T) {
+	old := namespacePath
+	namespacePath = filepath.Join(t.TempDir(), "namespace")
+	t.Cleanup(func() { namespacePath = old })
+
+	tests := []struct {
+		name      string
+		secrets   map[string]string
+		namespace string
+		want      GatewayAuth
+		wantErr   bool
+	}{
+		{name: "none", secrets: map[string]string{}},
+		{name: "basic auth", secrets: map[string]string{GatewayUserSecret: "admin\n", GatewayPasswordSecret: "s3cret\n"},
+			want: GatewayAuth{User: "admin", Password: "s3cret"}},
+		{name: "token wins", secrets: map[string]string{GatewayUserSecret: "admin", GatewayPasswordSecret: "s3cret", "gateway-token": "tok"},
+			want: GatewayAuth{Token: "tok"}},
+		{name: "namespace token", namespace: "staging",
+			secrets: map[string]string{"gateway-token": "tok", "gateway-token-staging": "staging-tok"},
+			want:    GatewayAuth{Token: "staging-tok", Namespace: "staging"}},
+		{name: "namespace without its own token", namespace: "dev",
+			secrets: map[string]string{"gateway-token": "tok", "gateway-token-staging": "staging-tok"},
+			want:    GatewayAuth{Token: "tok", Namespace: "dev"}},
+		{name: "user without password", secrets: map[string]string{GatewayUserSecret: "admin"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withSecrets(t, tt.secrets)
+			t.Setenv("GATEWAY_NAMESPACE", tt.namespace)
+			got, err := GatewayCredentials()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGatewayClient(t *testing.T) {
+	auth := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth <- r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth <- r.Header.Get("Authorization")
+	}))
+	defer other.Close()
+
+	t.Setenv("GATEWAY_URL", srv.URL)
+	t.Setenv("GATEWAY_NAMESPACE", "")
+	withSecrets(t, map[string]string{GatewayUserSecret: "admin", GatewayPasswordSecret: "s3cret"})
+
+	resp, err := GatewayClient().Get(FunctionURL("echo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := <-auth; got != "Basic YWRtaW46czNjcmV0" {
+		t.Errorf("gateway Authorization = %q", got)
+	}
+
+	resp, err = GatewayClient().Get(other.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := <-auth; got != "" {
+		t.Errorf("credentials sent to another host: %q", got)
+	}
+}
@@ -0,0 +1,274 @@
+package faas
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// EarthRadius is the mean radius of the Earth in meters, used by the
+// distance helpers which treat it as a sphere.
+const EarthRadius = 6_371_008.8
+
+// Point is a location in degrees of latitude and longitude.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Valid reports whether p is within the range of latitudes and longitudes.
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Distance returns the great circle distance between a and b in meters,
+// using the haversine formula. It is within about 0.5% of the distance on
+// the ellipsoid, which is plenty for "what is near me" lookups.
+func Distance(a, b Point) float64 {
+	dLat := radians(b.Lat - a.Lat)
+	dLon := radians(b.Lon - a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(a.Lat))*math.Cos(radians(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BBox is a bounding box in degrees. A box crossing the antimeridian has
+// MinLon greater than MaxLon.
+type BBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// BBoxAround returns the smallest box holding every point within radius
+// meters of p, so a lookup can filter candidates with a cheap range query
+// before checking their Distance. Near the poles the box spans every
+// longitude.
+func BBoxAround(p Point, radius float64) BBox {
+	dLat := degrees(radius / EarthRadius)
+	box := BBox{MinLat: p.Lat - dLat, MaxLat: p.Lat + dLat, MinLon: -180, MaxLon: 180}
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.MinLat, box.MaxLat = math.Max(box.MinLat, -90), math.Min(box.MaxLat, 90)
+		return box
+	}
+	// the widest longitude of a circle on the sphere
+	dLon := degrees(math.Asin(math.Sin(radius/EarthRadius) / math.Cos(radians(p.Lat))))
+	if dLon >= 180 {
+		return box
+	}
+	box.MinLon, box.MaxLon = wrapLon(p.Lon-dLon), wrapLon(p.Lon+dLon)
+	return box
+}
+
+// wrapLon brings a longitude back into [-180, 180].
+func wrapLon(lon float64) float64 {
+	switch {
+	case lon < -180:
+		return lon + 360
+	case lon > 180:
+		return lon - 360
+	}
+	return lon
+}
+
+// BBoxOf returns the smallest box holding points, which must not be empty.
+// It does not consider the antimeridian.
+func BBoxOf(points []Point) BBox {
+	box := BBox{MinLat: math.Inf(1), MinLon: math.Inf(1), MaxLat: math.Inf(-1), MaxLon: math.Inf(-1)}
+	for _, p := range points {
+		box = box.Extend(p)
+	}
+	return box
+}
+
+// Extend returns the box grown to hold p.
+func (b BBox) Extend(p Point) BBox {
+	return BBox{
+		MinLat: math.Min(b.MinLat, p.Lat), MinLon: math.Min(b.MinLon, p.Lon),
+		MaxLat: math.Max(b.MaxLat, p.Lat), MaxLon: math.Max(b.MaxLon, p.Lon),
+	}
+}
+
+// Contains reports whether p is inside the box or on its edge.
+func (b BBox) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon > b.MaxLon {
+		return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+}
+
+// Intersects reports whether the boxes overlap.
+func (b BBox) Intersects(o BBox) bool {
+	if b.MaxLat < o.MinLat || o.MaxLat < b.MinLat {
+		return false
+	}
+	// split boxes crossing the antimeridian into the two sides
+	for _, x := range b.lonRanges() {
+		for _, y := range o.lonRanges() {
+			if x[0] <= y[1] && y[0] <= x[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b BBox) lonRanges() [][2]float64 {
+	if b.MinLon > b.MaxLon {
+		return [][2]float64{{b.MinLon, 180}, {-180, b.MaxLon}}
+	}
+	return [][2]float64{{b.MinLon, b.MaxLon}}
+}
+
+// Center returns the middle of the box.
+func (b BBox) Center() Point {
+	lon := (b.MinLon + b.MaxLon) / 2
+	if b.MinLon > b.MaxLon {
+		lon = wrapLon(lon + 180)
+	}
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lon: lon}
+}
+
+// Polygon is a list of linear rings, the first being the outer boundary
+// and any others holes. Rings may or may not repeat their first point at
+// the end.
+type Polygon [][]Point
+
+// Contains reports whether p is inside the outer ring of the polygon and
+// not inside any of its holes, treating edges as straight lines in degrees,
+// which is accurate for polygons the size of a city or region. Points on an
+// edge may fall either way.
+func (poly Polygon) Contains(p Point) bool {
+	if len(poly) == 0 || !ringContains(poly[0], p) {
+		return false
+	}
+	for _, hole := range poly[1:] {
+		if ringContains(hole, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains casts a ray east of p, counting the edges it crosses.
+func ringContains(ring []Point, p Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// BBox returns the box around the outer ring.
+func (poly Polygon) BBox() BBox {
+	if len(poly) == 0 {
+		return BBox{}
+	}
+	return BBoxOf(poly[0])
+}
+
+// geohashAlphabet is the base 32 alphabet of geohashes, without a, i, l
+// and o.
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// ErrInvalidGeohash is returned by GeohashDecode for a hash which is empty
+// or has characters outside the geohash alphabet.
+var ErrInvalidGeohash = errors.New("invalid geohash")
+
+// GeohashEncode returns the geohash of p with precision characters,
+// between 1 and 12. Nearby points share a prefix, so a geohash column
+// indexes locations for prefix lookups. Each character narrows the cell,
+// from about 5000km at 1 to 4cm at 12.
+func GeohashEncode(p Point, precision int) string {
+	precision = min(max(precision, 1), 12)
+	lat, lon := [2]float64{-90, 90}, [2]float64{-180, 180}
+	var b strings.Builder
+	b.Grow(precision)
+	bit, ch, even := 0, 0, true
+	for b.Len() < precision {
+		// bits alternate between longitude and latitude, starting with
+		// longitude
+		r, v := &lat, p.Lat
+		if even {
+			r, v = &lon, p.Lon
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+		if bit++; bit == 5 {
+			b.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return b.String()
+}
+
+// GeohashDecode returns the cell of a geohash. Its Center is the point the
+// hash stands for.
+func GeohashDecode(hash string) (BBox, error) {
+	if hash == "" {
+		return BBox{}, ErrInvalidGeohash
+	}
+	lat, lon := [2]float64{-90, 90}, [2]float64{-180, 180}
+	even := true
+	for _, c := range strings.ToLower(hash) {
+		idx := strings.IndexRune(geohashAlphabet, c)
+		if idx < 0 {
+			return BBox{}, ErrInvalidGeohash
+		}
+		for i := 4; i >= 0; i-- {
+			r := &lat
+			if even {
+				r = &lon
+			}
+			mid := (r[0] + r[1]) / 2
+			if idx>>i&1 == 1 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+	}
+	return BBox{MinLat: lat[0], MinLon: lon[0], MaxLat: lat[1], MaxLon: lon[1]}, nil
+}
+
+// GeohashNeighbors returns the up to 8 cells of the same precision around
+// hash, clockwise from north. A lookup searching the cell of a point and
+// its neighbors finds everything within a cell's width of it. Cells beyond
+// the poles are left out.
+func GeohashNeighbors(hash string) ([]string, error) {
+	cell, err := GeohashDecode(hash)
+	if err != nil {
+		return nil, err
+	}
+	c := cell.Center()
+	h, w := cell.MaxLat-cell.MinLat, cell.MaxLon-cell.MinLon
+	var neighbors []string
+	for _, d := range [8][2]float64{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}} {
+		lat := c.Lat + d[0]*h
+		if lat > 90 || lat < -90 {
+			continue
+		}
+		neighbors = append(neighbors, GeohashEncode(Point{Lat: lat, Lon: wrapLon(c.Lon + d[1]*w)}, len(hash)))
+	}
+	return neighbors, nil
+}
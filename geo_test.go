@@ -0,0 +1,133 @@
+package faas
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	london := Point{Lat: 51.5074, Lon: -0.1278}
+	paris := Point{Lat: 48.8566, Lon: 2.3522}
+	if got := Distance(london, paris); math.Abs(got-343_560) > 500 {
+		t.Errorf("Distance(london, paris) = %.0f", got)
+	}
+	if got := Distance(london, london); got != 0 {
+		t.Errorf("Distance(london, london) = %v", got)
+	}
+	// half way around the world
+	if got := Distance(Point{0, 0}, Point{0, 180}); math.Abs(got-math.Pi*EarthRadius) > 1 {
+		t.Errorf("antipodal Distance() = %v", got)
+	}
+}
+
+func TestBBoxAround(t *testing.T) {
+	center := Point{Lat: 60, Lon: 10}
+	box := BBoxAround(center, 10_000)
+	for _, bearing := range []float64{0, 45, 90, 135, 180, 225, 270, 315} {
+		// a point 9.9km away along bearing
+		d := 9_900 / EarthRadius
+		b := radians(bearing)
+		lat1, lon1 := radians(center.Lat), radians(center.Lon)
+		lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+		lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+		p := Point{Lat: degrees(lat2), Lon: degrees(lon2)}
+		if !box.Contains(p) {
+			t.Errorf("box %+v does not contain %+v at bearing %v", box, p, bearing)
+		}
+	}
+	if box.Contains(Point{Lat: 60.2, Lon: 10}) {
+		t.Error("box contains a point 22km north")
+	}
+
+	wrapped := BBoxAround(Point{Lat: 0, Lon: 179.99}, 10_000)
+	if wrapped.MinLon <= wrapped.MaxLon || !wrapped.Contains(Point{Lat: 0, Lon: -179.99}) {
+		t.Errorf("antimeridian box = %+v", wrapped)
+	}
+	if !wrapped.Intersects(BBox{MinLat: -1, MinLon: -180, MaxLat: 1, MaxLon: -179}) {
+		t.Error("antimeridian box does not intersect its far side")
+	}
+	if c := wrapped.Center(); math.Abs(c.Lon-179.99) > 1e-9 {
+		t.Errorf("antimeridian Center() = %+v", c)
+	}
+
+	polar := BBoxAround(Point{Lat: 89.95, Lon: 0}, 10_000)
+	if polar.MaxLat != 90 || polar.MinLon != -180 || polar.MaxLon != 180 {
+		t.Errorf("polar box = %+v", polar)
+	}
+}
+
+func TestBBoxOf(t *testing.T) {
+	box := BBoxOf([]Point{{1, 5}, {-2, 3}, {4, -1}})
+	if want := (BBox{MinLat: -2, MinLon: -1, MaxLat: 4, MaxLon: 5}); box != want {
+		t.Errorf("BBoxOf() = %+v, want %+v", box, want)
+	}
+	if box.Intersects(BBox{MinLat: 5, MinLon: 0, MaxLat: 6, MaxLon: 1}) {
+		t.Error("disjoint boxes intersect")
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	square := []Point{{0, 0}, {0, 10}, {10, 10}, {10, 0}}
+	hole := []Point{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}}
+	poly := Polygon{square, hole}
+	tests := []struct {
+		p    Point
+		want bool
+	}{
+		{Point{1, 1}, true},
+		{Point{5, 5}, false},
+		{Point{9.9, 5}, true},
+		{Point{11, 5}, false},
+		{Point{-1, -1}, false},
+	}
+	for _, tt := range tests {
+		if got := poly.Contains(tt.p); got != tt.want {
+			t.Errorf("Contains(%+v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if (Polygon{}).Contains(Point{}) {
+		t.Error("empty polygon contains a point")
+	}
+	if got := poly.BBox(); got != (BBox{MaxLat: 10, MaxLon: 10}) {
+		t.Errorf("BBox() = %+v", got)
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	p := Point{Lat: 57.64911, Lon: 10.40744}
+	if got := GeohashEncode(p, 11); got != "u4pruydqqvj" {
+		t.Errorf("GeohashEncode() = %q", got)
+	}
+	if got := GeohashEncode(p, 0); got != "u" {
+		t.Errorf("GeohashEncode(p, 0) = %q", got)
+	}
+	cell, err := GeohashDecode("u4pruydqqvj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cell.Contains(p) {
+		t.Errorf("cell %+v does not contain %+v", cell, p)
+	}
+	if c := cell.Center(); math.Abs(c.Lat-p.Lat) > 1e-5 || math.Abs(c.Lon-p.Lon) > 1e-5 {
+		t.Errorf("Center() = %+v", c)
+	}
+	for _, bad := range []string{"", "u4pa"} {
+		if _, err := GeohashDecode(bad); !errors.Is(err, ErrInvalidGeohash) {
+			t.Errorf("GeohashDecode(%q) err = %v", bad, err)
+		}
+	}
+
+	neighbors, err := GeohashNeighbors("ezs42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ezs48", "ezs49", "ezs43", "ezs41", "ezs40", "ezefp", "ezefr", "ezefx"}
+	if !reflect.DeepEqual(neighbors, want) {
+		t.Errorf("GeohashNeighbors() = %q, want %q", neighbors, want)
+	}
+	if polar, _ := GeohashNeighbors("z"); len(polar) != 5 {
+		t.Errorf("polar neighbors = %q", polar)
+	}
+}
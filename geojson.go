@@ -0,0 +1,306 @@
+package faas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Geometry is a GeoJSON geometry. Coordinates are kept as sent, in the
+// longitude, latitude order of GeoJSON, and are read with Point, Points
+// and Polygons.
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	// Geometries holds the members of a GeometryCollection.
+	Geometries []Geometry `json:"geometries,omitempty"`
+}
+
+// Feature is a GeoJSON feature. Geometry is nil for an unlocated feature.
+type Feature struct {
+	Type       string    `json:"type"`
+	ID         any       `json:"id,omitempty"`
+	Geometry   *Geometry `json:"geometry"`
+	Properties Map       `json:"properties"`
+}
+
+// FeatureCollection is a GeoJSON feature collection.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// PointGeometry returns a Point geometry.
+func PointGeometry(p Point) *Geometry {
+	return newGeometry("Point", position(p))
+}
+
+// LineStringGeometry returns a LineString geometry.
+func LineStringGeometry(points []Point) *Geometry {
+	return newGeometry("LineString", positions(points))
+}
+
+// PolygonGeometry returns a Polygon geometry. Rings are closed by
+// repeating their first point when they do not already end with it, as
+// GeoJSON requires.
+func PolygonGeometry(poly Polygon) *Geometry {
+	rings := make([][][]float64, len(poly))
+	for i, ring := range poly {
+		if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+			ring = append(ring[:len(ring):len(ring)], ring[0])
+		}
+		rings[i] = positions(ring)
+	}
+	return newGeometry("Polygon", rings)
+}
+
+// NewFeature returns a feature with geometry g and properties.
+func NewFeature(g *Geometry, properties Map) Feature {
+	return Feature{Type: "Feature", Geometry: g, Properties: properties}
+}
+
+// NewFeatureCollection returns a collection of features, which encodes an
+// empty list rather than null when there are none.
+func NewFeatureCollection(features ...Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+func newGeometry(typ string, coordinates any) *Geometry {
+	// slices of floats always encode
+	data, _ := json.Marshal(coordinates)
+	return &Geometry{Type: typ, Coordinates: data}
+}
+
+func position(p Point) []float64 { return []float64{p.Lon, p.Lat} }
+
+func positions(points []Point) [][]float64 {
+	out := make([][]float64, len(points))
+	for i, p := range points {
+		out[i] = position(p)
+	}
+	return out
+}
+
+// Point returns the location of a Point geometry.
+func (g *Geometry) Point() (Point, error) {
+	if g.Type != "Point" {
+		return Point{}, fmt.Errorf("geometry is a %s, not a Point", g.Type)
+	}
+	var pos []float64
+	if err := json.Unmarshal(g.Coordinates, &pos); err != nil {
+		return Point{}, fmt.Errorf("invalid Point coordinates: %w", err)
+	}
+	return toPoint(pos)
+}
+
+// Points returns the locations of a MultiPoint or LineString geometry.
+func (g *Geometry) Points() ([]Point, error) {
+	if g.Type != "MultiPoint" && g.Type != "LineString" {
+		return nil, fmt.Errorf("geometry is a %s, not a MultiPoint or LineString", g.Type)
+	}
+	var pos [][]float64
+	if err := json.Unmarshal(g.Coordinates, &pos); err != nil {
+		return nil, fmt.Errorf("invalid %s coordinates: %w", g.Type, err)
+	}
+	return toPoints(pos)
+}
+
+// Polygons returns the polygons of a Polygon or MultiPolygon geometry.
+func (g *Geometry) Polygons() ([]Polygon, error) {
+	var polys [][][][]float64
+	switch g.Type {
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &rings); err != nil {
+			return nil, fmt.Errorf("invalid Polygon coordinates: %w", err)
+		}
+		polys = [][][][]float64{rings}
+	case "MultiPolygon":
+		if err := json.Unmarshal(g.Coordinates, &polys); err != nil {
+			return nil, fmt.Errorf("invalid MultiPolygon coordinates: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("geometry is a %s, not a Polygon or MultiPolygon", g.Type)
+	}
+	out := make([]Polygon, len(polys))
+	for i, rings := range polys {
+		out[i] = make(Polygon, len(rings))
+		for j, ring := range rings {
+			if len(ring) < 4 {
+				return nil, fmt.Errorf("polygon ring must have at least 4 positions, got %d", len(ring))
+			}
+			points, err := toPoints(ring)
+			if err != nil {
+				return nil, err
+			}
+			if points[0] != points[len(points)-1] {
+				return nil, errors.New("polygon ring must end with its first position")
+			}
+			out[i][j] = points
+		}
+	}
+	return out, nil
+}
+
+// Contains reports whether p is inside a Polygon or MultiPolygon geometry,
+// or a member of a GeometryCollection. It is false for other geometries
+// and for invalid coordinates.
+func (g *Geometry) Contains(p Point) bool {
+	if g.Type == "GeometryCollection" {
+		for i := range g.Geometries {
+			if g.Geometries[i].Contains(p) {
+				return true
+			}
+		}
+		return false
+	}
+	polys, err := g.Polygons()
+	if err != nil {
+		return false
+	}
+	for _, poly := range polys {
+		if poly.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the geometry has a known type and coordinates of valid
+// positions nested as its type requires.
+func (g *Geometry) Validate() error {
+	if g.Type == "GeometryCollection" {
+		for i := range g.Geometries {
+			if err := g.Geometries[i].Validate(); err != nil {
+				return fmt.Errorf("geometry %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+	var err error
+	switch g.Type {
+	case "Point":
+		_, err = g.Point()
+	case "MultiPoint", "LineString":
+		_, err = g.Points()
+	case "Polygon", "MultiPolygon":
+		_, err = g.Polygons()
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &lines); err != nil {
+			return fmt.Errorf("invalid MultiLineString coordinates: %w", err)
+		}
+		for _, line := range lines {
+			if _, err := toPoints(line); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown geometry type %q", g.Type)
+	}
+	return err
+}
+
+func toPoint(pos []float64) (Point, error) {
+	if len(pos) < 2 || len(pos) > 3 {
+		return Point{}, fmt.Errorf("position must have 2 or 3 numbers, got %d", len(pos))
+	}
+	p := Point{Lat: pos[1], Lon: pos[0]}
+	if !p.Valid() {
+		return Point{}, fmt.Errorf("position [%g, %g] is out of range", pos[0], pos[1])
+	}
+	return p, nil
+}
+
+func toPoints(pos [][]float64) ([]Point, error) {
+	points := make([]Point, len(pos))
+	for i := range pos {
+		p, err := toPoint(pos[i])
+		if err != nil {
+			return nil, err
+		}
+		points[i] = p
+	}
+	return points, nil
+}
+
+// ParseGeoJSON decodes and validates a GeoJSON document. A single Feature
+// or bare geometry is returned as a collection of one feature, so callers
+// handle a single shape.
+func ParseGeoJSON(data []byte) (FeatureCollection, error) {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return FeatureCollection{}, err
+	}
+	var fc FeatureCollection
+	switch head.Type {
+	case "FeatureCollection":
+		if err := json.Unmarshal(data, &fc); err != nil {
+			return FeatureCollection{}, err
+		}
+	case "Feature":
+		var f Feature
+		if err := json.Unmarshal(data, &f); err != nil {
+			return FeatureCollection{}, err
+		}
+		fc = NewFeatureCollection(f)
+	case "":
+		return FeatureCollection{}, errors.New("missing GeoJSON type")
+	default:
+		var g Geometry
+		if err := json.Unmarshal(data, &g); err != nil {
+			return FeatureCollection{}, err
+		}
+		fc = NewFeatureCollection(NewFeature(&g, nil))
+	}
+	for i, f := range fc.Features {
+		if f.Type != "Feature" {
+			return FeatureCollection{}, fmt.Errorf("feature %d: type must be Feature, got %q", i, f.Type)
+		}
+		if f.Geometry == nil {
+			continue
+		}
+		if err := f.Geometry.Validate(); err != nil {
+			return FeatureCollection{}, fmt.Errorf("feature %d: %w", i, err)
+		}
+	}
+	if fc.Features == nil {
+		fc.Features = []Feature{}
+	}
+	return fc, nil
+}
+
+// ReadGeoJSON reads a GeoJSON request body with ParseGeoJSON, returning a
+// CodeInvalidArgument AppError for an invalid document.
+func ReadGeoJSON(w http.ResponseWriter, r *http.Request, opts ...BodyOptions) (FeatureCollection, error) {
+	data, err := ReadBody(w, r, opts...)
+	if err != nil {
+		return FeatureCollection{}, err
+	}
+	fc, err := ParseGeoJSON(data)
+	if err != nil {
+		return FeatureCollection{}, E(CodeInvalidArgument, "invalid GeoJSON: "+err.Error(), err)
+	}
+	return fc, nil
+}
+
+// WriteGeoJSON is WriteJSON with the application/geo+json content type.
+func WriteGeoJSON(w http.ResponseWriter, status int, data any, headers http.Header) error {
+	body, err := jsonCodec().Marshal(data)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+	return nil
+}
@@ -0,0 +1,97 @@
+package faas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseGeoJSON(t *testing.T) {
+	doc := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "id": "sq", "properties": {"name": "square"},
+		 "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]]]}},
+		{"type": "Feature", "properties": null,
+		 "geometry": {"type": "Point", "coordinates": [13.4, 52.5, 34]}},
+		{"type": "Feature", "properties": {}, "geometry": null}
+	]}`
+	fc, err := ParseGeoJSON([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 3 || fc.Features[0].Properties["name"] != "square" {
+		t.Fatalf("features = %+v", fc.Features)
+	}
+	if !fc.Features[0].Geometry.Contains(Point{Lat: 5, Lon: 5}) || fc.Features[0].Geometry.Contains(Point{Lat: 5, Lon: 15}) {
+		t.Error("polygon Contains() is wrong")
+	}
+	p, err := fc.Features[1].Geometry.Point()
+	if err != nil || p != (Point{Lat: 52.5, Lon: 13.4}) {
+		t.Errorf("Point() = %+v, %v", p, err)
+	}
+	if _, err := fc.Features[1].Geometry.Polygons(); err == nil {
+		t.Error("Polygons() of a Point succeeded")
+	}
+
+	single, err := ParseGeoJSON([]byte(`{"type": "LineString", "coordinates": [[0, 0], [1, 1]]}`))
+	if err != nil || len(single.Features) != 1 || single.Features[0].Geometry.Type != "LineString" {
+		t.Errorf("bare geometry = %+v, %v", single, err)
+	}
+
+	for _, bad := range []string{
+		`{"features": []}`,
+		`{"type": "Circle", "coordinates": [0, 0]}`,
+		`{"type": "Point", "coordinates": [200, 0]}`,
+		`{"type": "Point", "coordinates": [1]}`,
+		`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1]]]}`,
+		`{"type": "MultiLineString", "coordinates": [[[0, 0], [0, 91]]]}`,
+		`{"type": "GeometryCollection", "geometries": [{"type": "Point", "coordinates": "x"}]}`,
+		`{"type": "FeatureCollection", "features": [{"type": "Point"}]}`,
+	} {
+		if _, err := ParseGeoJSON([]byte(bad)); err == nil {
+			t.Errorf("ParseGeoJSON(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestGeoJSONRoundTrip(t *testing.T) {
+	poly := Polygon{{{0, 0}, {0, 2}, {2, 2}, {2, 0}}}
+	fc := NewFeatureCollection(
+		NewFeature(PolygonGeometry(poly), Map{"zone": "a"}),
+		NewFeature(PointGeometry(Point{Lat: 1, Lon: 2}), nil),
+		NewFeature(LineStringGeometry([]Point{{0, 0}, {1, 1}}), nil),
+	)
+	rec := httptest.NewRecorder()
+	if err := WriteGeoJSON(rec, http.StatusOK, fc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/geo+json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `"coordinates":[[[0,0],[2,0],[2,2],[0,2],[0,0]]]`) {
+		t.Errorf("body = %s", rec.Body)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", rec.Body)
+	got, err := ReadGeoJSON(httptest.NewRecorder(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Features[0].Geometry.Contains(Point{Lat: 1, Lon: 1}) {
+		t.Error("decoded polygon does not contain its center")
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"type": "Point", "coordinates": []}`))
+	_, err = ReadGeoJSON(httptest.NewRecorder(), r)
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != CodeInvalidArgument {
+		t.Errorf("err = %v, want a CodeInvalidArgument AppError", err)
+	}
+
+	empty, _ := json.Marshal(NewFeatureCollection())
+	if string(empty) != `{"type":"FeatureCollection","features":[]}` {
+		t.Errorf("empty collection = %s", empty)
+	}
+}
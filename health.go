@@ -0,0 +1,75 @@
+package faas
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthCheck reports whether a dependency is usable.
+type HealthCheck func(ctx context.Context) error
+
+var (
+	healthMu     sync.RWMutex
+	healthChecks = map[string]HealthCheck{}
+)
+
+// RegisterHealthCheck adds a check run by HealthHandler. Registering an
+// existing name replaces it.
+func RegisterHealthCheck(name string, check HealthCheck) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	healthChecks[name] = check
+}
+
+// HealthHandler runs every registered check concurrently and responds 200
+// when all pass or 503 listing the failures, for use as a readiness probe.
+// Each check is given timeout, 2s when zero.
+func HealthHandler(timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		healthMu.RLock()
+		names := make([]string, 0, len(healthChecks))
+		for name := range healthChecks {
+			names = append(names, name)
+		}
+		checks := make([]HealthCheck, len(names))
+		sort.Strings(names)
+		for i, name := range names {
+			checks[i] = healthChecks[name]
+		}
+		healthMu.RUnlock()
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		results := make(map[string]string, len(names))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for i := range checks {
+			wg.Add(1)
+			go func(name string, check HealthCheck) {
+				defer wg.Done()
+				result := "ok"
+				if err := check(ctx); err != nil {
+					result = err.Error()
+				}
+				mu.Lock()
+				results[name] = result
+				mu.Unlock()
+			}(names[i], checks[i])
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		for _, result := range results {
+			if result != "ok" {
+				status = http.StatusServiceUnavailable
+			}
+		}
+		_ = writeJSON(w, status, Map{"status": http.StatusText(status), "checks": results}, nil)
+	})
+}
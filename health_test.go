@@ -0,0 +1,33 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	t.Cleanup(func() {
+		healthMu.Lock()
+		defer healthMu.Unlock()
+		delete(healthChecks, "db")
+		delete(healthChecks, "cache")
+	})
+	RegisterHealthCheck("db", func(context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	HealthHandler(0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body)
+	}
+
+	RegisterHealthCheck("cache", func(context.Context) error { return errors.New("connection refused") })
+	rec = httptest.NewRecorder()
+	HealthHandler(0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"cache":"connection refused"`) {
+		t.Errorf("expected 503 listing the failed check, got %d %s", rec.Code, rec.Body)
+	}
+}
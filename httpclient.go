@@ -0,0 +1,243 @@
+package faas
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPClientOptions configures NewHTTPClient. The zero value gives sane
+// defaults.
+type HTTPClientOptions struct {
+	// Timeout caps each request, including reading the body. Requests
+	// whose context has an earlier deadline use that instead, so outbound
+	// calls never outlive the function invocation. Defaults to 30 seconds.
+	Timeout time.Duration
+	// MaxIdleConns and MaxIdleConnsPerHost bound the connection pool. They
+	// default to 100 and 10.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes pooled connections after this long unused.
+	// Defaults to 90 seconds.
+	IdleConnTimeout time.Duration
+	// ProxyFromEnv routes requests through the proxy named by the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	ProxyFromEnv bool
+	// Proxy selects a proxy per destination and takes precedence over
+	// ProxyFromEnv.
+	Proxy *ProxySelector
+	// PreferIP is PreferIPv4 or PreferIPv6 to try that family first on dual
+	// stack hosts. Defaults to the DIAL_PREFER_IP environment variable, or
+	// the resolver's order when unset.
+	PreferIP string
+	// FallbackDelay is how long to wait for the preferred family before
+	// racing the other, see net.Dialer.FallbackDelay. Negative only tries
+	// the other family once the preferred one fails. Defaults to the
+	// DIAL_FALLBACK_DELAY environment variable, or 300ms.
+	FallbackDelay time.Duration
+	// DNSCache optionally caches lookups for the client's connections.
+	DNSCache *DNSCache
+	// Metrics receives the client's per host metrics. Defaults to
+	// DefaultOutboundMetrics.
+	Metrics *OutboundMetrics
+	// MaxResponseBytes limits the size of response bodies. Gzip responses
+	// are decompressed by the transport and the limit applies to the
+	// decompressed size, which guards against decompression bombs. It can
+	// be changed per call with WithMaxResponseBytes. Defaults to 10MB,
+	// negative disables the limit.
+	MaxResponseBytes int64
+}
+
+// NewHTTPClient returns an instrumented http.Client with a connection pool
+// and deadline aware timeouts, for use instead of http.DefaultClient which
+// never times out.
+func NewHTTPClient(opts HTTPClientOptions) *http.Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = 100
+	}
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = 10
+	}
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = 90 * time.Second
+	}
+	if opts.MaxResponseBytes == 0 {
+		opts.MaxResponseBytes = 10 << 20
+	}
+
+	prefer, delay, err := dialOptionsFromEnv()
+	if err != nil {
+		slog.Warn("ignoring dial settings", "error", err)
+	}
+	if opts.PreferIP == "" {
+		opts.PreferIP = prefer
+	}
+	if opts.FallbackDelay == 0 {
+		opts.FallbackDelay = delay
+	}
+
+	dial := dualStackDialer{
+		dialer:        &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second},
+		prefer:        opts.PreferIP,
+		fallbackDelay: opts.FallbackDelay,
+	}
+	if opts.DNSCache != nil {
+		dial.lookup = opts.DNSCache.LookupIPAddr
+	}
+	transport := &http.Transport{
+		DialContext:           dial.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	switch {
+	case opts.Proxy != nil:
+		transport.Proxy = opts.Proxy.Proxy
+	case opts.ProxyFromEnv:
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	return &http.Client{
+		Transport: deadlineTransport{
+			base: limitTransport{
+				base:     InstrumentedTransport{Base: transport, Metrics: opts.Metrics},
+				maxBytes: opts.MaxResponseBytes,
+			},
+			timeout: opts.Timeout,
+		},
+	}
+}
+
+var (
+	sharedClientOnce sync.Once
+	sharedClient     *http.Client
+)
+
+// SharedHTTPClient returns a process wide client created by NewHTTPClient
+// with the proxy and dial settings from the environment, so every outbound
+// call shares one connection pool.
+func SharedHTTPClient() *http.Client {
+	sharedClientOnce.Do(func() {
+		sharedClient = NewHTTPClient(HTTPClientOptions{ProxyFromEnv: true})
+	})
+	return sharedClient
+}
+
+// deadlineTransport bounds each request by the earlier of its context
+// deadline and timeout.
+type deadlineTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t deadlineTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if d, ok := r.Context().Deadline(); ok && time.Until(d) <= t.timeout {
+		return t.base.RoundTrip(r)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the timeout covers reading the body so cancel once it is closed
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (t deadlineTransport) CloseIdleConnections() {
+	closeIdleConnections(t.base)
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// ResponseTooLargeError is returned when reading a response body larger
+// than the client's MaxResponseBytes.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body must not be larger than %d bytes", e.Limit)
+}
+
+type maxResponseBytesKey struct{}
+
+// WithMaxResponseBytes overrides the response size limit of clients created
+// by NewHTTPClient for requests made with the returned context. A negative
+// n disables the limit.
+func WithMaxResponseBytes(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, maxResponseBytesKey{}, n)
+}
+
+// limitTransport rejects response bodies larger than maxBytes.
+type limitTransport struct {
+	base     http.RoundTripper
+	maxBytes int64
+}
+
+func (t limitTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	limit := t.maxBytes
+	if n, ok := r.Context().Value(maxResponseBytesKey{}).(int64); ok {
+		limit = n
+	}
+	resp, err := t.base.RoundTrip(r)
+	if err != nil || limit < 0 {
+		return resp, err
+	}
+	// fail early when the declared size is already too large, unless the
+	// body is transparently decompressed and its length unknown
+	if resp.ContentLength > limit {
+		resp.Body.Close()
+		return nil, &ResponseTooLargeError{Limit: limit}
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: limit, limit: limit}
+	return resp, nil
+}
+
+func (t limitTransport) CloseIdleConnections() {
+	closeIdleConnections(t.base)
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, &ResponseTooLargeError{Limit: b.limit}
+	}
+	// read one byte more than allowed to detect bodies over the limit
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = -1
+		return n, &ResponseTooLargeError{Limit: b.limit}
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
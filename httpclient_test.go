@@ -0,0 +1,131 @@
+package faas
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d, err := time.ParseDuration(r.URL.Query().Get("sleep")); err == nil {
+			select {
+			case <-time.After(d):
+			case <-r.Context().Done():
+			}
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	metrics := NewOutboundMetrics()
+	client := NewHTTPClient(HTTPClientOptions{Timeout: 50 * time.Millisecond, Metrics: metrics})
+
+	tests := []struct {
+		name     string
+		sleep    string
+		deadline time.Duration
+		timeout  bool
+	}{
+		{name: "fast", sleep: "0s"},
+		{name: "client timeout", sleep: "1s", timeout: true},
+		{name: "earlier context deadline", sleep: "30ms", deadline: 10 * time.Millisecond, timeout: true},
+		{name: "later context deadline capped", sleep: "1s", deadline: time.Minute, timeout: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.deadline)
+				defer cancel()
+			}
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?sleep="+tc.sleep, nil)
+			resp, err := client.Do(req)
+			if tc.timeout {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("expected deadline exceeded, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil || string(body) != "ok" {
+				t.Errorf("unexpected body %q: %v", body, err)
+			}
+		})
+	}
+
+	if stats := metrics.Snapshot(); len(stats) != 1 || stats[0].Requests != 4 {
+		t.Errorf("expected the client to be instrumented, got %+v", stats)
+	}
+}
+
+func TestHTTPClientMaxResponseBytes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bomb":
+			// a megabyte of zeros compresses to about a kilobyte
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			_, _ = gz.Write(make([]byte, 1<<20))
+			_ = gz.Close()
+		case "/chunked":
+			for i := 0; i < 10; i++ {
+				_, _ = w.Write(bytes.Repeat([]byte("x"), 100))
+				w.(http.Flusher).Flush()
+			}
+		default:
+			_, _ = w.Write(bytes.Repeat([]byte("x"), 1000))
+		}
+	}))
+	defer srv.Close()
+	client := NewHTTPClient(HTTPClientOptions{MaxResponseBytes: 500})
+
+	read := func(ctx context.Context, path string) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, err = io.ReadAll(resp.Body)
+		return err
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		limit int64
+		ok    bool
+	}{
+		{name: "content length over limit", path: "/"},
+		{name: "streamed body over limit", path: "/chunked"},
+		{name: "decompression bomb", path: "/bomb"},
+		{name: "raised per call", path: "/", limit: 2000, ok: true},
+		{name: "disabled per call", path: "/bomb", limit: -1, ok: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.limit != 0 {
+				ctx = WithMaxResponseBytes(ctx, tc.limit)
+			}
+			err := read(ctx, tc.path)
+			var tooLarge *ResponseTooLargeError
+			if tc.ok != (err == nil) || (!tc.ok && !errors.As(err, &tooLarge)) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
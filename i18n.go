@@ -0,0 +1,405 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language
+// header, such as "fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5", in order of
+// preference. Tags with q=0 are left out and invalid entries ignored.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || n < 0 || n > 1 {
+				continue
+			}
+			q = n
+		}
+		if q > 0 {
+			ranges = append(ranges, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
+
+// NegotiateLanguage returns the supported language best matching an
+// Accept-Language header, or "" when none does. A requested tag matches a
+// supported one exactly, ignoring case, or by its base language, so
+// "pt-BR" is served "pt" and "en" is served "en-GB". "*" matches the
+// first supported language, so list the preferred one first.
+func NegotiateLanguage(header string, supported ...string) string {
+	for _, tag := range ParseAcceptLanguage(header) {
+		if tag == "*" {
+			if len(supported) > 0 {
+				return supported[0]
+			}
+			return ""
+		}
+		for _, s := range supported {
+			if strings.EqualFold(s, tag) {
+				return s
+			}
+		}
+		for _, s := range supported {
+			if strings.EqualFold(baseLanguage(s), baseLanguage(tag)) {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return base
+}
+
+// Catalog holds the translated messages of each language, keyed by dotted
+// names such as "errors.not_found". Messages are fmt format strings, so
+// translations can reorder arguments with explicit indexes like %[2]s.
+type Catalog struct {
+	// Fallback is the language used for messages missing from the
+	// requested language, typically the one the messages are written in.
+	Fallback string
+
+	messages map[string]map[string]string
+}
+
+// NewCatalog returns a catalog of messages by language and key.
+func NewCatalog(fallback string, messages map[string]map[string]string) *Catalog {
+	return &Catalog{Fallback: fallback, messages: messages}
+}
+
+// LoadCatalog loads a catalog from the files in dir of fsys, typically an
+// embed.FS, named after their language, such as "en.json" or "fr-CA.toml".
+// JSON files hold an object of messages, where nested objects give dotted
+// keys. TOML files hold string keys and tables, the subset of TOML
+// message catalogs need.
+//
+//	//go:embed locales
+//	var locales embed.FS
+//
+//	func init() {
+//		catalog, err := faas.LoadCatalog(locales, "locales", "en")
+//		if err != nil {
+//			panic(err)
+//		}
+//		faas.DefaultCatalog = catalog
+//	}
+func LoadCatalog(fsys fs.FS, dir, fallback string) (*Catalog, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	messages := make(map[string]map[string]string)
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if e.IsDir() || (ext != ".json" && ext != ".toml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		msgs := make(map[string]string)
+		if ext == ".json" {
+			err = parseJSONMessages(data, msgs)
+		} else {
+			err = parseTOMLMessages(data, msgs)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid message catalog %s: %w", e.Name(), err)
+		}
+		messages[strings.TrimSuffix(e.Name(), ext)] = msgs
+	}
+	if _, ok := messages[fallback]; !ok {
+		return nil, fmt.Errorf("no messages for fallback language %q in %s", fallback, dir)
+	}
+	return NewCatalog(fallback, messages), nil
+}
+
+func parseJSONMessages(data []byte, msgs map[string]string) error {
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return triageJSONError(err, len(data))
+	}
+	return flattenMessages("", tree, msgs)
+}
+
+func flattenMessages(prefix string, tree map[string]any, msgs map[string]string) error {
+	for k, v := range tree {
+		switch v := v.(type) {
+		case string:
+			msgs[prefix+k] = v
+		case map[string]any:
+			if err := flattenMessages(prefix+k+".", v, msgs); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("message %s must be a string", prefix+k)
+		}
+	}
+	return nil
+}
+
+// parseTOMLMessages reads comments, [table] headers and key = "string"
+// lines, with bare, quoted or dotted keys and basic or literal strings.
+func parseTOMLMessages(data []byte, msgs map[string]string) error {
+	table := ""
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			end := strings.LastIndex(line, "]")
+			if end < 0 || !isTOMLComment(line[end+1:]) {
+				return fmt.Errorf("line %d: invalid table header", n+1)
+			}
+			key, err := parseTOMLKey(line[1:end])
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n+1, err)
+			}
+			table = key + "."
+			continue
+		}
+		rawKey, rawValue, ok := cutTOMLAssignment(line)
+		if !ok {
+			return fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		key, err := parseTOMLKey(rawKey)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n+1, err)
+		}
+		value, rest, err := parseTOMLString(strings.TrimSpace(rawValue))
+		if err != nil {
+			return fmt.Errorf("line %d: %s: %w", n+1, key, err)
+		}
+		if !isTOMLComment(rest) {
+			return fmt.Errorf("line %d: unexpected %q after value", n+1, strings.TrimSpace(rest))
+		}
+		msgs[table+key] = value
+	}
+	return nil
+}
+
+// cutTOMLAssignment splits a line at the first = outside a quoted key.
+func cutTOMLAssignment(line string) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return line[:i], line[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// parseTOMLKey returns a key with its dotted parts joined by dots.
+func parseTOMLKey(s string) (string, error) {
+	var parts []string
+	s = strings.TrimSpace(s)
+	for s != "" {
+		var part string
+		switch s[0] {
+		case '"', '\'':
+			v, rest, err := parseTOMLString(s)
+			if err != nil {
+				return "", err
+			}
+			part, s = v, strings.TrimSpace(rest)
+		default:
+			end := strings.IndexAny(s, ". \t")
+			if end < 0 {
+				end = len(s)
+			}
+			part, s = s[:end], strings.TrimSpace(s[end:])
+			for _, r := range part {
+				if !(r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+					return "", fmt.Errorf("invalid key %q", part)
+				}
+			}
+		}
+		if part == "" {
+			return "", errors.New("empty key")
+		}
+		parts = append(parts, part)
+		if s == "" {
+			break
+		}
+		if s[0] != '.' {
+			return "", fmt.Errorf("invalid key near %q", s)
+		}
+		s = strings.TrimSpace(s[1:])
+		if s == "" {
+			return "", errors.New("key must not end with a dot")
+		}
+	}
+	if len(parts) == 0 {
+		return "", errors.New("empty key")
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// parseTOMLString parses the basic or literal string s starts with,
+// returning the rest of s.
+func parseTOMLString(s string) (string, string, error) {
+	if strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''") {
+		return "", "", errors.New("multi-line strings are not supported")
+	}
+	if strings.HasPrefix(s, "'") {
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", errors.New("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", errors.New("value must be a string")
+	}
+	// find the closing quote, skipping escapes, and let strconv decode
+	// the escapes TOML shares with Go
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return v, s[i+1:], nil
+		}
+	}
+	return "", "", errors.New("unterminated string")
+}
+
+func isTOMLComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || s[0] == '#'
+}
+
+// Languages returns the languages of the catalog, sorted.
+func (c *Catalog) Languages() []string {
+	langs := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Message returns the message key in lang, falling back to its base
+// language, such as "fr" for "fr-CA", then to the Fallback language.
+func (c *Catalog) Message(lang, key string) (string, bool) {
+	for _, l := range []string{lang, baseLanguage(lang), c.Fallback} {
+		if msg, ok := c.messages[l][key]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// Translate returns the message key in lang formatted with args. A
+// missing message returns the key itself, so gaps in a catalog show up
+// without breaking responses.
+func (c *Catalog) Translate(lang, key string, args ...any) string {
+	msg, ok := c.Message(lang, key)
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// DefaultCatalog is used by T and Localize. Set it at startup, see
+// LoadCatalog.
+var DefaultCatalog = NewCatalog("en", nil)
+
+// catalogKey holds the catalog of Localize.
+var catalogKey = NewContextKey[*Catalog]("catalog")
+
+// T returns the message key in the language of ctx, formatted with args,
+// from the catalog of Localize or else DefaultCatalog:
+//
+//	return faas.E(faas.CodeNotFound, faas.T(ctx, "errors.order_not_found", id), nil)
+func T(ctx context.Context, key string, args ...any) string {
+	catalog, ok := FromContext(ctx, catalogKey)
+	if !ok {
+		catalog = DefaultCatalog
+	}
+	return catalog.Translate(Language(ctx), key, args...)
+}
+
+// Language returns the language negotiated by Localize, or the fallback
+// language of DefaultCatalog.
+func Language(ctx context.Context) string {
+	if lang, ok := FromContext(ctx, LanguageKey); ok {
+		return lang
+	}
+	return DefaultCatalog.Fallback
+}
+
+// Localize is middleware negotiating the response language from the
+// Accept-Language header among the languages of c, DefaultCatalog when
+// nil, falling back to its Fallback, which is also what "*" is served. The
+// language is available to the handler through Language and T, and sent as
+// Content-Language.
+func Localize(c *Catalog) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			catalog := c
+			if catalog == nil {
+				catalog = DefaultCatalog
+			}
+			// the fallback goes first so "*" is served it
+			langs := catalog.Languages()
+			if i := slices.Index(langs, catalog.Fallback); i > 0 {
+				langs = slices.Insert(slices.Delete(langs, i, i+1), 0, catalog.Fallback)
+			}
+			lang := NegotiateLanguage(r.Header.Get("Accept-Language"), langs...)
+			if lang == "" {
+				lang = catalog.Fallback
+			}
+			w.Header().Set("Content-Language", lang)
+			w.Header().Add("Vary", "Accept-Language")
+			ctx := WithValue(r.Context(), LanguageKey, lang)
+			next.ServeHTTP(w, r.WithContext(WithValue(ctx, catalogKey, catalog)))
+		})
+	}
+}
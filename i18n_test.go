@@ -0,0 +1,121 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := ParseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0, *;q=0.5, ;q=1, es;q=x")
+	want := []string{"fr-CH", "fr", "en", "*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	supported := []string{"en-GB", "fr", "pt"}
+	tests := []struct {
+		header, want string
+	}{
+		{"fr-CA, en;q=0.5", "fr"},
+		{"en", "en-GB"},
+		{"EN-gb", "en-GB"},
+		{"de, pt-BR;q=0.8", "pt"},
+		{"de, *;q=0.1", "en-GB"},
+		{"de", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NegotiateLanguage(tt.header, supported...); got != tt.want {
+			t.Errorf("NegotiateLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+var catalogFS = fstest.MapFS{
+	"locales/en.json": {Data: []byte(`{"greeting": "Hello", "errors": {"not_found": "Order %s not found"}}`)},
+	"locales/fr.toml": {Data: []byte(`# French
+greeting = "Bonjour" # inline comment
+
+[errors]
+not_found = 'Commande %s introuvable'
+"quoted.key" = "tab\there é"
+`)},
+	"locales/README.md": {Data: []byte("ignored")},
+}
+
+func TestLoadCatalog(t *testing.T) {
+	c, err := LoadCatalog(catalogFS, "locales", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Languages(); !reflect.DeepEqual(got, []string{"en", "fr"}) {
+		t.Errorf("languages = %q", got)
+	}
+	tests := []struct {
+		lang, key string
+		args      []any
+		want      string
+	}{
+		{"fr", "greeting", nil, "Bonjour"},
+		{"fr-CA", "errors.not_found", []any{"42"}, "Commande 42 introuvable"},
+		{"fr", "errors.quoted.key", nil, "tab\there é"},
+		{"de", "greeting", nil, "Hello"},
+		{"en", "missing.key", nil, "missing.key"},
+	}
+	for _, tt := range tests {
+		if got := c.Translate(tt.lang, tt.key, tt.args...); got != tt.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", tt.lang, tt.key, got, tt.want)
+		}
+	}
+
+	for name, data := range map[string]string{
+		"en.json": `{"count": 3}`,
+		"en.toml": `count = 3`,
+		"de.toml": "[errors\nname = \"x\"",
+	} {
+		fsys := fstest.MapFS{"l/en.json": {Data: []byte(`{}`)}, "l/" + name: {Data: []byte(data)}}
+		if _, err := LoadCatalog(fsys, "l", "en"); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: err = %v, want an error naming the file", name, err)
+		}
+	}
+	if _, err := LoadCatalog(catalogFS, "locales", "de"); err == nil {
+		t.Error("expected an error for a missing fallback language")
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	c, err := LoadCatalog(catalogFS, "locales", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Localize(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(T(r.Context(), "errors.not_found", "7")))
+	}))
+	for header, want := range map[string]string{"fr-FR,en;q=0.5": "fr", "ja": "en"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Language", header)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got := w.Header().Get("Content-Language"); got != want {
+			t.Errorf("%s: Content-Language = %q, want %q", header, got, want)
+		}
+		if want == "fr" && w.Body.String() != "Commande 7 introuvable" {
+			t.Errorf("body = %q", w.Body)
+		}
+	}
+
+	c = NewCatalog("fr", map[string]map[string]string{"de": {}, "en": {}, "fr": {}})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "ja, *;q=0.5")
+	w := httptest.NewRecorder()
+	Localize(c)(http.NotFoundHandler()).ServeHTTP(w, r)
+	if got := w.Header().Get("Content-Language"); got != "fr" {
+		t.Errorf("*: Content-Language = %q, want the fallback fr", got)
+	}
+}
@@ -0,0 +1,124 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrDuplicate is returned by Inbox.Process when the event was already
+// processed, or is being processed, within the inbox TTL.
+var ErrDuplicate = errors.New("event already processed")
+
+// errInboxHandlerFailed marks a non-2xx response written by a handler behind
+// Inbox.Middleware.
+var errInboxHandlerFailed = errors.New("handler failed")
+
+// Inbox records the IDs of processed webhook events so redelivered events
+// are skipped and their side effects happen once. Events are keyed by
+// provider and ID as IDs are only unique per provider.
+type Inbox struct {
+	// Store must implement AtomicKV, as MemoryKV and the redis and sqlstore
+	// clients do, so two deliveries arriving at once cannot both claim an
+	// event.
+	Store KV
+	// TTL is how long event IDs are remembered. It should exceed the
+	// provider's redelivery window. Defaults to 72 hours.
+	TTL time.Duration
+}
+
+// Process records the event and then calls fn. Events seen before return
+// ErrDuplicate without calling fn. When fn fails the record is removed so the
+// provider's redelivery is processed again. Stores which are not an
+// AtomicKV fail with ErrNotAtomic rather than risk running fn twice.
+func (i *Inbox) Process(ctx context.Context, provider, id string, fn func(context.Context) error) error {
+	store, ok := i.Store.(AtomicKV)
+	if !ok {
+		return fmt.Errorf("inbox: %w", ErrNotAtomic)
+	}
+	key := "faas:inbox:" + provider + ":" + id
+	ttl := i.TTL
+	if ttl <= 0 {
+		ttl = 72 * time.Hour
+	}
+	claimed, err := store.SetNX(ctx, key, []byte(time.Now().UTC().Format(time.RFC3339)), ttl)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrDuplicate
+	}
+	completed := false
+	defer func() {
+		if !completed {
+			_ = i.Store.Delete(context.WithoutCancel(ctx), key)
+		}
+	}()
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	completed = true
+	return nil
+}
+
+// WebhookEventID returns the provider and delivery ID of a webhook from the
+// headers set by common providers: GitHub, Shopify, Standard Webhooks (as
+// used by Svix) and this package's WebhookSender. Empty strings are returned
+// for unknown providers.
+func WebhookEventID(r *http.Request) (provider, id string) {
+	h := r.Header
+	switch {
+	case h.Get("X-GitHub-Delivery") != "":
+		return "github", h.Get("X-GitHub-Delivery")
+	case h.Get("X-Shopify-Webhook-Id") != "":
+		return "shopify", h.Get("X-Shopify-Webhook-Id")
+	case h.Get("Webhook-Id") != "":
+		return "standard", h.Get("Webhook-Id")
+	case h.Get("Svix-Id") != "":
+		return "svix", h.Get("Svix-Id")
+	case h.Get("X-Webhook-Id") != "":
+		return "webhook", h.Get("X-Webhook-Id")
+	}
+	return "", ""
+}
+
+// Middleware processes each request through the inbox using idFunc, or
+// WebhookEventID when nil. Duplicates are answered 200 so the provider stops
+// redelivering, and responses with a non-2xx status count as failures.
+// Requests without an ID are rejected with 400.
+func (i *Inbox) Middleware(idFunc func(*http.Request) (provider, id string)) func(http.Handler) http.Handler {
+	if idFunc == nil {
+		idFunc = WebhookEventID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provider, id := idFunc(r)
+			if id == "" {
+				_ = writeJSONError(w, Error{
+					Status: http.StatusText(http.StatusBadRequest),
+					Reason: "webhook event id is missing",
+					Code:   http.StatusBadRequest,
+				})
+				return
+			}
+			sw := &statusWriter{ResponseWriter: w}
+			err := i.Process(r.Context(), provider, id, func(ctx context.Context) error {
+				next.ServeHTTP(sw, r)
+				if sw.status != 0 && (sw.status < 200 || sw.status >= 300) {
+					return errInboxHandlerFailed
+				}
+				return nil
+			})
+			switch {
+			case errors.Is(err, ErrDuplicate):
+				_ = writeJSON(w, http.StatusOK, Map{"status": "duplicate"}, nil)
+			case errors.Is(err, errInboxHandlerFailed):
+				// the handler has already responded
+			case err != nil:
+				_ = writeError(w, err)
+			}
+		})
+	}
+}
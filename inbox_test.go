@@ -0,0 +1,108 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestInboxProcess(t *testing.T) {
+	inbox := &Inbox{Store: NewMemoryKV()}
+	ctx := context.Background()
+	calls := 0
+	ok := func(context.Context) error { calls++; return nil }
+	fail := func(context.Context) error { calls++; return errors.New("boom") }
+
+	if err := inbox.Process(ctx, "github", "1", fail); err == nil {
+		t.Fatal("expected the handler error")
+	}
+	if err := inbox.Process(ctx, "github", "1", ok); err != nil {
+		t.Fatalf("retry after failure: %v", err)
+	}
+	if err := inbox.Process(ctx, "github", "1", ok); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("duplicate error = %v", err)
+	}
+	if err := inbox.Process(ctx, "shopify", "1", ok); err != nil {
+		t.Fatalf("same id from another provider: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+}
+
+func TestInboxConcurrentDeliveries(t *testing.T) {
+	inbox := &Inbox{Store: NewMemoryKV()}
+	release := make(chan struct{})
+	var calls atomic.Int32
+	errs := make(chan error, 2)
+	for n := 0; n < 2; n++ {
+		go func() {
+			errs <- inbox.Process(context.Background(), "github", "1", func(context.Context) error {
+				calls.Add(1)
+				<-release
+				return nil
+			})
+		}()
+	}
+	// one delivery is rejected while the other is still running
+	if err := <-errs; !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("err = %v, want ErrDuplicate", err)
+	}
+	close(release)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+}
+
+func TestInboxRequiresAtomicStore(t *testing.T) {
+	inbox := &Inbox{Store: struct{ KV }{NewMemoryKV()}}
+	err := inbox.Process(context.Background(), "github", "1", func(context.Context) error {
+		t.Error("handler called without an atomic store")
+		return nil
+	})
+	if !errors.Is(err, ErrNotAtomic) {
+		t.Errorf("err = %v, want ErrNotAtomic", err)
+	}
+}
+
+func TestInboxMiddleware(t *testing.T) {
+	calls := 0
+	status := http.StatusOK
+	h := (&Inbox{Store: NewMemoryKV()}).Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+
+	tests := []struct {
+		name      string
+		delivery  string
+		status    int
+		want      int
+		wantCalls int
+	}{
+		{name: "missing id", want: http.StatusBadRequest},
+		{name: "failure", delivery: "a", status: http.StatusInternalServerError, want: http.StatusInternalServerError, wantCalls: 1},
+		{name: "redelivery", delivery: "a", status: http.StatusOK, want: http.StatusOK, wantCalls: 2},
+		{name: "duplicate", delivery: "a", status: http.StatusOK, want: http.StatusOK, wantCalls: 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			status = tc.status
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tc.delivery != "" {
+				req.Header.Set("X-GitHub-Delivery", tc.delivery)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.want || calls != tc.wantCalls {
+				t.Errorf("status = %d with %d calls, want %d with %d", rec.Code, calls, tc.want, tc.wantCalls)
+			}
+		})
+	}
+}
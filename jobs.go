@@ -0,0 +1,251 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobState is the lifecycle state of a Job.
+type JobState string
+
+// Job states.
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+)
+
+// Job is the status of work submitted to Jobs.
+type Job struct {
+	ID    string   `json:"id"`
+	State JobState `json:"state"`
+	// Progress is the fraction of the work done, from 0 to 1.
+	Progress float64 `json:"progress"`
+	Message  string  `json:"message,omitempty"`
+	// Result is the JSON encoded value returned by a succeeded job.
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Created time.Time       `json:"created"`
+	Updated time.Time       `json:"updated"`
+}
+
+// Done reports whether the job has finished.
+func (j Job) Done() bool {
+	return j.State == JobSucceeded || j.State == JobFailed
+}
+
+// JobFunc is the work of a job. It reports progress, a fraction from 0 to
+// 1 and a message, and returns a result which is stored as JSON.
+type JobFunc func(ctx context.Context, progress func(done float64, message string)) (any, error)
+
+// Jobs runs long tasks in the background so the caller gets a 202 and a job
+// ID straight away instead of holding a gateway connection open, then polls
+// the job's status:
+//
+//	jobs := &faas.Jobs{Store: redisClient}
+//	http.Handle("/exports/", jobs.Handler(func(r *http.Request) (faas.JobFunc, error) {
+//		return func(ctx context.Context, progress func(float64, string)) (any, error) {
+//			return runExport(ctx, progress)
+//		}, nil
+//	}))
+//
+// Statuses are kept in Store, so any replica can serve them when it is
+// shared. Jobs still running when the function shuts down are waited for by
+// Shutdown.
+type Jobs struct {
+	// Store holds the job statuses. Defaults to a MemoryKV.
+	Store KV
+	// Workers is how many jobs run at once, others wait as pending.
+	// Defaults to 4.
+	Workers int
+	// Timeout bounds the duration of each job. Zero means no limit.
+	Timeout time.Duration
+	// TTL is how long statuses are kept. Defaults to 24 hours.
+	TTL time.Duration
+
+	once sync.Once
+	sem  chan struct{}
+	wg   sync.WaitGroup
+}
+
+// ErrJobNotFound is returned by Jobs.Status for unknown or expired jobs.
+var ErrJobNotFound = errors.New("job not found")
+
+func (j *Jobs) init() {
+	j.once.Do(func() {
+		if j.Store == nil {
+			j.Store = NewMemoryKV()
+		}
+		j.sem = make(chan struct{}, defaultInt(j.Workers, 4))
+		OnShutdown(j.wait)
+	})
+}
+
+func (j *Jobs) key(id string) string {
+	return "faas:job:" + id
+}
+
+func (j *Jobs) save(ctx context.Context, job *Job) error {
+	job.Updated = time.Now()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return j.Store.Set(ctx, j.key(job.ID), data, defaultDuration(j.TTL, 24*time.Hour))
+}
+
+// Submit stores a pending job and runs fn in the background once a worker
+// is free. The job keeps the values of ctx, such as the request logger, but
+// is not cancelled with it.
+func (j *Jobs) Submit(ctx context.Context, fn JobFunc) (Job, error) {
+	j.init()
+	job := Job{ID: randomHex(16), State: JobPending, Created: time.Now()}
+	if err := j.save(ctx, &job); err != nil {
+		return Job{}, fmt.Errorf("saving job: %w", err)
+	}
+	ctx = context.WithoutCancel(ctx)
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		j.sem <- struct{}{}
+		defer func() { <-j.sem }()
+		j.run(ctx, job, fn)
+	}()
+	return job, nil
+}
+
+func (j *Jobs) run(ctx context.Context, job Job, fn JobFunc) {
+	logger := LoggerFromContext(ctx).With("job_id", job.ID)
+	var mu sync.Mutex
+	update := func(change func(*Job)) {
+		mu.Lock()
+		defer mu.Unlock()
+		change(&job)
+		if err := j.save(ctx, &job); err != nil {
+			logger.Error("saving job status", "error", err)
+		}
+	}
+	update(func(job *Job) { job.State = JobRunning })
+
+	if j.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.Timeout)
+		defer cancel()
+	}
+	result, err := runJob(ctx, fn, func(done float64, message string) {
+		update(func(job *Job) {
+			job.Progress = min(max(done, 0), 1)
+			job.Message = message
+		})
+	})
+	var data []byte
+	if err == nil && result != nil {
+		data, err = json.Marshal(result)
+	}
+	if err != nil {
+		logger.Error("job failed", "error", err)
+		update(func(job *Job) {
+			job.State = JobFailed
+			job.Error = err.Error()
+		})
+		return
+	}
+	update(func(job *Job) {
+		job.State = JobSucceeded
+		job.Progress = 1
+		job.Result = data
+	})
+}
+
+func runJob(ctx context.Context, fn JobFunc, progress func(float64, string)) (result any, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = &PanicError{Value: rec, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx, progress)
+}
+
+// Status returns the job with the given ID, or ErrJobNotFound.
+func (j *Jobs) Status(ctx context.Context, id string) (Job, error) {
+	j.init()
+	data, err := j.Store.Get(ctx, j.key(id))
+	if errors.Is(err, ErrNotFound) {
+		return Job{}, ErrJobNotFound
+	}
+	if err != nil {
+		return Job{}, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return Job{}, fmt.Errorf("decoding job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// wait blocks until the running jobs finish or ctx is done.
+func (j *Jobs) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for jobs: %w", ctx.Err())
+	}
+}
+
+// Handler returns a handler submitting a job on POST and reporting its
+// status on GET. A POST calls start with the request, submits the JobFunc
+// it returns and responds as Accepted does with the status URL, the request
+// path plus "/<job id>". A GET of that URL responds with the Job, with a
+// Retry-After of one second while it is not done. Errors returned by start
+// are written with WriteError.
+func (j *Jobs) Handler(start func(*http.Request) (JobFunc, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			fn, err := start(r)
+			if err != nil {
+				_ = writeError(w, err)
+				return
+			}
+			job, err := j.Submit(r.Context(), fn)
+			if err != nil {
+				_ = writeError(w, err)
+				return
+			}
+			_ = Accepted(w, strings.TrimSuffix(r.URL.Path, "/")+"/"+job.ID)
+		case http.MethodGet, http.MethodHead:
+			job, err := j.Status(r.Context(), path.Base(r.URL.Path))
+			if errors.Is(err, ErrJobNotFound) {
+				_ = writeError(w, E(CodeNotFound, "job not found", err))
+				return
+			}
+			if err != nil {
+				_ = writeError(w, err)
+				return
+			}
+			var headers http.Header
+			if !job.Done() {
+				headers = http.Header{"Retry-After": {"1"}, "Cache-Control": {"no-store"}}
+			}
+			_ = writeJSON(w, http.StatusOK, job, headers)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			_ = writeError(w, E(CodeInvalidArgument, "method not allowed", nil).WithStatus(http.StatusMethodNotAllowed))
+		}
+	})
+}
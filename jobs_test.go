@@ -0,0 +1,171 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitForJob(t *testing.T, jobs *Jobs, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := jobs.Status(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Done() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestJobs(t *testing.T) {
+	tests := []struct {
+		name       string
+		fn         JobFunc
+		wantState  JobState
+		wantResult string
+		wantError  string
+	}{
+		{
+			name: "succeeded",
+			fn: func(_ context.Context, progress func(float64, string)) (any, error) {
+				progress(0.5, "halfway")
+				return Map{"rows": 10}, nil
+			},
+			wantState:  JobSucceeded,
+			wantResult: `{"rows":10}`,
+		},
+		{
+			name: "failed",
+			fn: func(context.Context, func(float64, string)) (any, error) {
+				return nil, errors.New("export failed")
+			},
+			wantState: JobFailed,
+			wantError: "export failed",
+		},
+		{
+			name: "panicked",
+			fn: func(context.Context, func(float64, string)) (any, error) {
+				panic("boom")
+			},
+			wantState: JobFailed,
+			wantError: "panic: boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := &Jobs{}
+			job, err := jobs.Submit(context.Background(), tt.fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.State != JobPending || job.ID == "" {
+				t.Errorf("Submit() = %+v", job)
+			}
+			job = waitForJob(t, jobs, job.ID)
+			if job.State != tt.wantState || string(job.Result) != tt.wantResult || job.Error != tt.wantError {
+				t.Errorf("job = %+v", job)
+			}
+		})
+	}
+}
+
+func TestJobsProgressAndWorkers(t *testing.T) {
+	jobs := &Jobs{Workers: 1}
+	release := make(chan struct{})
+	reported := make(chan struct{})
+	first, _ := jobs.Submit(context.Background(), func(_ context.Context, progress func(float64, string)) (any, error) {
+		progress(2, "almost")
+		close(reported)
+		<-release
+		return nil, nil
+	})
+	<-reported
+	second, _ := jobs.Submit(context.Background(), func(context.Context, func(float64, string)) (any, error) {
+		return nil, nil
+	})
+
+	job, _ := jobs.Status(context.Background(), first.ID)
+	if job.State != JobRunning || job.Progress != 1 || job.Message != "almost" {
+		t.Errorf("first job = %+v", job)
+	}
+	if job, _ := jobs.Status(context.Background(), second.ID); job.State != JobPending {
+		t.Errorf("second job state = %s, want pending while the only worker is busy", job.State)
+	}
+	close(release)
+	waitForJob(t, jobs, second.ID)
+
+	if err := jobs.wait(context.Background()); err != nil {
+		t.Errorf("wait() = %v", err)
+	}
+	if _, err := jobs.Status(context.Background(), "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Status(missing) = %v", err)
+	}
+}
+
+func TestJobsTimeout(t *testing.T) {
+	jobs := &Jobs{Timeout: 10 * time.Millisecond}
+	job, _ := jobs.Submit(context.Background(), func(ctx context.Context, _ func(float64, string)) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if job = waitForJob(t, jobs, job.ID); job.State != JobFailed || !strings.Contains(job.Error, "deadline") {
+		t.Errorf("job = %+v", job)
+	}
+}
+
+func TestJobsHandler(t *testing.T) {
+	jobs := &Jobs{}
+	h := jobs.Handler(func(r *http.Request) (JobFunc, error) {
+		if r.URL.Query().Get("fail") != "" {
+			return nil, E(CodeInvalidArgument, "bad export", nil)
+		}
+		return func(context.Context, func(float64, string)) (any, error) { return "done", nil }, nil
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/exports", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("POST status = %d", w.Code)
+	}
+	var accepted struct {
+		StatusURL string `json:"status_url"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&accepted)
+	if !strings.HasPrefix(accepted.StatusURL, "/exports/") || w.Header().Get("Location") != accepted.StatusURL {
+		t.Fatalf("accepted = %+v, Location %s", accepted, w.Header().Get("Location"))
+	}
+	waitForJob(t, jobs, path.Base(accepted.StatusURL))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{name: "status", method: http.MethodGet, target: accepted.StatusURL, wantStatus: http.StatusOK},
+		{name: "unknown job", method: http.MethodGet, target: "/exports/nope", wantStatus: http.StatusNotFound},
+		{name: "start error", method: http.MethodPost, target: "/exports?fail=1", wantStatus: http.StatusBadRequest},
+		{name: "method not allowed", method: http.MethodDelete, target: accepted.StatusURL, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// JournalEntry is the envelope of a request stored by JournalRequests.
+type JournalEntry struct {
+	ID        string            `json:"id"`
+	Time      time.Time         `json:"time"`
+	Function  string            `json:"function,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Query     string            `json:"query,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	// Body is left empty when the body could not be redacted, see
+	// JournalOptions.KeepOtherBodies.
+	Body string `json:"body,omitempty"`
+	// BodyEncoding is "base64" when Body holds a base64 encoded body
+	// which is not UTF-8, such as an image.
+	BodyEncoding string `json:"body_encoding,omitempty"`
+	// BodyBytes is how much of the body was seen: what the handler read,
+	// and up to MaxBytes of the rest read after it returned. It is larger
+	// than Body when the body was truncated.
+	BodyBytes int      `json:"body_bytes"`
+	Status    int      `json:"status"`
+	Duration  Duration `json:"duration"`
+}
+
+// Request rebuilds the journaled request against baseURL, such as a staging
+// deployment of the function, to replay it. Redacted headers are left out
+// and redacted body and query values are sent as "[REDACTED]", so callers
+// usually set their own credentials before sending it.
+func (e JournalEntry) Request(ctx context.Context, baseURL string) (*http.Request, error) {
+	u := strings.TrimSuffix(baseURL, "/") + e.Path
+	if e.Query != "" {
+		u += "?" + e.Query
+	}
+	body := []byte(e.Body)
+	if e.BodyEncoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(e.Body); err != nil {
+			return nil, fmt.Errorf("decoding journaled body: %w", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, e.Method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, v := range e.Headers {
+		if v != redacted {
+			req.Header.Set(name, v)
+		}
+	}
+	req.Header.Del("Content-Length")
+	return req, nil
+}
+
+// JournalOptions configure JournalRequests.
+type JournalOptions struct {
+	// Store and Bucket receive the entries. Store is required.
+	Store  ObjectStore
+	Bucket string
+	// Prefix is prepended to the object keys, such as "journal".
+	Prefix string
+	// Function names the partition of the entries. Defaults to the
+	// function_name environment variable set by OpenFaaS.
+	Function string
+	// SampleRate is the fraction of requests journaled, between 0 and 1.
+	// Defaults to 1, every request.
+	SampleRate float64
+	// MaxBytes caps how much of each request body is kept. Defaults to
+	// 64KB. JSON and form bodies over the cap are not kept as they cannot
+	// be redacted.
+	MaxBytes int
+	// RedactHeaders and RedactPaths are redacted as by LogBodies. Query
+	// parameters named like a redacted header, or token, access_token,
+	// api_key, password or secret, are redacted too.
+	RedactHeaders []string
+	RedactPaths   []string
+	// KeepOtherBodies keeps bodies which are neither JSON nor forms, such
+	// as text or images, as they are. By default they are dropped, as
+	// secrets in them cannot be redacted.
+	KeepOtherBodies bool
+	// QueueSize is how many entries may wait to be stored. Entries are
+	// dropped with a warning when it is full rather than slowing down
+	// requests. Defaults to 256.
+	QueueSize int
+}
+
+// JournalRequests is middleware storing an envelope of each sampled
+// request, its headers and body with secrets redacted, in object storage
+// for audits and for replaying production traffic with
+// JournalEntry.Request. Entries are written by a background worker to
+// keys partitioned by function and date:
+//
+//	<prefix>/<function>/2006/01/02/150405.000000000-<id>.json
+//
+// Entries still queued when the function shuts down are stored by
+// Shutdown.
+func JournalRequests(opts JournalOptions) func(http.Handler) http.Handler {
+	if opts.Store == nil {
+		slog.Warn("request journal has no object store, requests are not journaled")
+		return func(next http.Handler) http.Handler { return next }
+	}
+	if opts.Function == "" {
+		opts.Function = os.Getenv("function_name")
+	}
+	if opts.SampleRate <= 0 || opts.SampleRate > 1 {
+		opts.SampleRate = 1
+	}
+	opts.MaxBytes = defaultInt(opts.MaxBytes, 64<<10)
+	j := &journal{
+		opts:    opts,
+		headers: append(append([]string(nil), defaultRedactHeaders...), opts.RedactHeaders...),
+		query:   map[string]bool{"token": true, "access_token": true, "api_key": true, "password": true, "secret": true},
+		queue:   make(chan JournalEntry, defaultInt(opts.QueueSize, 256)),
+	}
+	for _, h := range j.headers {
+		j.query[strings.ToLower(h)] = true
+	}
+	for _, p := range opts.RedactPaths {
+		j.paths = append(j.paths, parseRedactPath(p))
+	}
+	go j.run()
+	OnShutdown(j.wait)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.SampleRate < 1 && rand.Float64() >= opts.SampleRate {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			body := &cappedBuffer{max: opts.MaxBytes}
+			var tee io.Reader
+			if r.Body != nil {
+				tee = io.TeeReader(r.Body, body)
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{tee, r.Body}
+			}
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			if tee != nil && body.total <= opts.MaxBytes {
+				// capture what the handler left unread, such as the body of
+				// a request it rejected, so the entry can be replayed. One
+				// byte past the cap is enough to tell it was truncated
+				_, _ = io.Copy(io.Discard, io.LimitReader(tee, int64(opts.MaxBytes-body.total+1)))
+			}
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			e := JournalEntry{
+				ID:        randomHex(16),
+				Time:      start.UTC(),
+				Function:  opts.Function,
+				RequestID: RequestID(r.Context()),
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Query:     redactQuery(r.URL.RawQuery, j.query),
+				Headers:   redactHeaders(r.Header, j.headers),
+				BodyBytes: body.total,
+				Status:    status,
+				Duration:  Duration(time.Since(start)),
+			}
+			if body.total > 0 {
+				s, kind := redactStructured(body, r.Header.Get("Content-Type"), j.paths)
+				switch {
+				case kind == bodyRedacted:
+					e.Body = s
+				case kind == bodyOther && opts.KeepOtherBodies:
+					if utf8.Valid(body.Bytes()) {
+						e.Body = body.String()
+					} else {
+						e.Body, e.BodyEncoding = base64.StdEncoding.EncodeToString(body.Bytes()), "base64"
+					}
+				}
+			}
+			j.enqueue(r.Context(), e)
+		})
+	}
+}
+
+type journal struct {
+	opts    JournalOptions
+	headers []string
+	query   map[string]bool
+	paths   [][]string
+	queue   chan JournalEntry
+	pending sync.WaitGroup
+}
+
+// redactQuery replaces the values of the query parameters whose lower
+// case names are in names. Queries which cannot be parsed are dropped.
+func redactQuery(raw string, names map[string]bool) string {
+	if raw == "" {
+		return ""
+	}
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return ""
+	}
+	changed := false
+	for name, values := range q {
+		if names[strings.ToLower(name)] {
+			for i := range values {
+				values[i] = redacted
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return raw
+	}
+	return q.Encode()
+}
+
+func (j *journal) enqueue(ctx context.Context, e JournalEntry) {
+	j.pending.Add(1)
+	select {
+	case j.queue <- e:
+	default:
+		j.pending.Done()
+		LoggerFromContext(ctx).Warn("request journal queue is full, dropping entry", "path", e.Path)
+	}
+}
+
+func (j *journal) run() {
+	for e := range j.queue {
+		if err := j.store(e); err != nil {
+			slog.Error("storing request journal entry", "key", j.key(e), "error", err)
+		}
+		j.pending.Done()
+	}
+}
+
+func (j *journal) store(e JournalEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return j.opts.Store.Put(ctx, j.opts.Bucket, j.key(e), bytes.NewReader(data), "application/json")
+}
+
+func (j *journal) key(e JournalEntry) string {
+	fn := j.opts.Function
+	if fn == "" {
+		fn = "unknown"
+	}
+	key := fmt.Sprintf("%s/%s-%s.json", fn, e.Time.Format("2006/01/02/150405.000000000"), e.ID)
+	if p := strings.Trim(j.opts.Prefix, "/"); p != "" {
+		key = p + "/" + key
+	}
+	return key
+}
+
+func (j *journal) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		j.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for request journal: %w", ctx.Err())
+	}
+}
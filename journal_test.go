@@ -0,0 +1,177 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournalRequests(t *testing.T) {
+	store := &memoryObjectStore{objects: map[string][]byte{}}
+	mw := JournalRequests(JournalOptions{
+		Store:       store,
+		Bucket:      "audit",
+		Prefix:      "journal/",
+		Function:    "orders",
+		RedactPaths: []string{"$.card.number"},
+	})
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/orders?dry_run=1&token=abc", strings.NewReader(`{"card":{"number":"4242"},"qty":2}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	key, e := waitJournalEntry(t, store)
+	if want := "audit/journal/orders/" + time.Now().UTC().Format("2006/01/02") + "/"; !strings.HasPrefix(key, want) {
+		t.Errorf("key = %q, want prefix %q", key, want)
+	}
+	if e.Status != http.StatusCreated || e.Query != "dry_run=1&token=%5BREDACTED%5D" || e.Function != "orders" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Headers["Authorization"] != redacted {
+		t.Errorf("authorization not redacted: %q", e.Headers["Authorization"])
+	}
+	if want := `{"card":{"number":"[REDACTED]"},"qty":2}`; e.Body != want {
+		t.Errorf("body = %s, want %s", e.Body, want)
+	}
+
+	replay, err := e.Request(context.Background(), "http://staging:8080/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.URL.String() != "http://staging:8080/orders?dry_run=1&token=%5BREDACTED%5D" || replay.Header.Get("Authorization") != "" {
+		t.Errorf("replay = %s %v", replay.URL, replay.Header)
+	}
+	if replay.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("replay content type = %q", replay.Header.Get("Content-Type"))
+	}
+}
+
+func TestJournalBodies(t *testing.T) {
+	binary := []byte{0x89, 'P', 'N', 'G', 0xff, 0x00}
+	tests := []struct {
+		name         string
+		contentType  string
+		body         []byte
+		keep         bool
+		wantBody     string
+		wantEncoding string
+	}{
+		{name: "text dropped", contentType: "text/plain", body: []byte("password: hunter2")},
+		{name: "multipart dropped", contentType: "multipart/form-data; boundary=x", body: []byte("--x--")},
+		{name: "invalid json dropped", contentType: "application/json", body: []byte(`{"password":`)},
+		{name: "untyped json", body: []byte(`{"password":"hunter2"}`), wantBody: `{"password":"[REDACTED]"}`},
+		{name: "text kept", contentType: "text/plain", body: []byte("hello"), keep: true, wantBody: "hello"},
+		{name: "binary kept", contentType: "image/png", body: binary, keep: true, wantBody: "iVBOR/8A", wantEncoding: "base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryObjectStore{objects: map[string][]byte{}}
+			mw := JournalRequests(JournalOptions{Store: store, RedactPaths: []string{"$.password"}, KeepOtherBodies: tt.keep})
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.ReadAll(r.Body)
+			}))
+			r := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			_, e := waitJournalEntry(t, store)
+			if e.Body != tt.wantBody || e.BodyEncoding != tt.wantEncoding || e.BodyBytes != len(tt.body) {
+				t.Fatalf("body = %q (%q, %d bytes), want %q (%q)", e.Body, e.BodyEncoding, e.BodyBytes, tt.wantBody, tt.wantEncoding)
+			}
+			if !tt.keep {
+				return
+			}
+			replay, err := e.Request(context.Background(), "http://staging:8080")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := io.ReadAll(replay.Body); !bytes.Equal(got, tt.body) {
+				t.Errorf("replayed body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestJournalUnreadBody(t *testing.T) {
+	store := &memoryObjectStore{objects: map[string][]byte{}}
+	mw := JournalRequests(JournalOptions{Store: store, MaxBytes: 16})
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// rejected before the body is read
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"qty":2}`))
+	r.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	key, e := waitJournalEntry(t, store)
+	if e.Status != http.StatusUnauthorized || e.Body != `{"qty":2}` || e.BodyBytes != 9 {
+		t.Errorf("entry = %+v, want the unread body captured", e)
+	}
+	store.mu.Lock()
+	raw := string(store.objects[key])
+	store.mu.Unlock()
+	if !strings.Contains(raw, `"duration":"`) {
+		t.Errorf("entry %s, want the duration encoded as a Duration", raw)
+	}
+
+	// bodies past the cap are seen up to one byte over it and dropped
+	store.mu.Lock()
+	store.objects = map[string][]byte{}
+	store.mu.Unlock()
+	r = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"qty":2,"note":"a long note"}`))
+	r.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if _, e := waitJournalEntry(t, store); e.Body != "" || e.BodyBytes != 17 {
+		t.Errorf("entry = %+v, want a truncated body dropped", e)
+	}
+}
+
+func waitJournalEntry(t *testing.T, store *memoryObjectStore) (string, JournalEntry) {
+	t.Helper()
+	var key string
+	var data []byte
+	for deadline := time.Now().Add(time.Second); key == "" && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		store.mu.Lock()
+		for k, v := range store.objects {
+			key, data = k, v
+		}
+		store.mu.Unlock()
+	}
+	if key == "" {
+		t.Fatal("no entry stored")
+	}
+	var e JournalEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatal(err)
+	}
+	return key, e
+}
+
+func TestJournalQueueFull(t *testing.T) {
+	j := &journal{queue: make(chan JournalEntry, 1)}
+	j.enqueue(context.Background(), JournalEntry{Path: "/a"})
+	j.enqueue(context.Background(), JournalEntry{Path: "/b"})
+	if len(j.queue) != 1 {
+		t.Errorf("queued %d entries, want 1", len(j.queue))
+	}
+	<-j.queue
+	j.pending.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := j.wait(ctx); err != nil {
+		t.Errorf("wait: %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+package faas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONSchema is a compiled JSON Schema. The commonly used validation
+// keywords are supported: type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, format, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// allOf, anyOf and oneOf. The formats checked are email, date-time and uri,
+// which only accepts http and https URLs. Other keywords, including $ref,
+// are ignored.
+type JSONSchema struct {
+	Type jsonTypes `json:"type"`
+	Enum []any     `json:"enum"`
+	// Const is nil without the keyword, and points to nil for
+	// "const": null.
+	Const                *any                   `json:"const"`
+	Properties           map[string]*JSONSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties"`
+	Items                *JSONSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	Format               string                 `json:"format"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum"`
+	AllOf                []*JSONSchema          `json:"allOf"`
+	AnyOf                []*JSONSchema          `json:"anyOf"`
+	OneOf                []*JSONSchema          `json:"oneOf"`
+
+	// reject is set for the boolean schema false, as used by
+	// "additionalProperties": false.
+	reject  bool
+	pattern *regexp.Regexp
+}
+
+// jsonTypes accepts both "type": "string" and "type": ["string", "null"].
+type jsonTypes []string
+
+func (t *jsonTypes) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*t = jsonTypes{s}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting boolean schemas.
+func (s *JSONSchema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = JSONSchema{}
+		return nil
+	case "false":
+		*s = JSONSchema{reject: true}
+		return nil
+	}
+	type plain JSONSchema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	// "const": null decodes to a nil pointer, so its presence is recorded
+	// as a pointer to nil
+	if s.Const == nil && bytes.Contains(data, []byte(`"const"`)) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err == nil {
+			if _, ok := fields["const"]; ok {
+				s.Const = new(any)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseJSONSchema parses and compiles a JSON Schema document.
+func ParseJSONSchema(data []byte) (*JSONSchema, error) {
+	var s JSONSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *JSONSchema) compile() error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid JSON schema pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	children := []*JSONSchema{s.AdditionalProperties, s.Items}
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	children = append(children, s.AllOf...)
+	children = append(children, s.AnyOf...)
+	children = append(children, s.OneOf...)
+	for _, c := range children {
+		if err := c.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the JSON document data against the schema. Violations are
+// returned as ValidationErrors with dotted paths such as "items.0.sku", or
+// "(root)" for the document itself.
+func (s *JSONSchema) Validate(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return ValidationErrors{{Field: "(root)", Message: "must be valid JSON"}}
+	}
+	var errs ValidationErrors
+	s.validate(v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *JSONSchema) validate(v any, path string, errs *ValidationErrors) {
+	fail := func(format string, args ...any) {
+		field := path
+		if field == "" {
+			field = "(root)"
+		}
+		*errs = append(*errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.reject {
+		fail("is not allowed")
+		return
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return jsonTypeMatches(t, v) }) {
+		fail("must be of type %s", strings.Join(s.Type, " or "))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return jsonEqual(e, v) }) {
+		fail("must be one of %s", formatJSONValues(s.Enum))
+	}
+	if s.Const != nil && !jsonEqual(*s.Const, v) {
+		fail("must be %s", formatJSONValues([]any{*s.Const}))
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, FieldError{Field: joinPath(path, name), Message: "is required"})
+			}
+		}
+		for name, val := range v {
+			if prop, ok := s.Properties[name]; ok {
+				prop.validate(val, joinPath(path, name), errs)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(val, joinPath(path, name), errs)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, joinPath(path, strconv.Itoa(i)), errs)
+			}
+		}
+	case string:
+		n := len([]rune(v))
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match %s", s.Pattern)
+		}
+		if err := checkJSONFormat(s.Format, v); err != nil {
+			fail("%s", err)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+			fail("must be greater than %v", *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum {
+			fail("must be less than %v", *s.ExclusiveMaximum)
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		sub.validate(v, path, errs)
+	}
+	if len(s.AnyOf) > 0 {
+		if matchingSchemas(s.AnyOf, v) == 0 {
+			fail("must match at least one allowed schema")
+		}
+	}
+	if len(s.OneOf) > 0 {
+		if matchingSchemas(s.OneOf, v) != 1 {
+			fail("must match exactly one allowed schema")
+		}
+	}
+}
+
+func matchingSchemas(schemas []*JSONSchema, v any) int {
+	n := 0
+	for _, sub := range schemas {
+		var errs ValidationErrors
+		sub.validate(v, "", &errs)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func jsonTypeMatches(t string, v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+func jsonEqual(a, b any) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func formatJSONValues(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		js, _ := json.Marshal(v)
+		parts[i] = string(js)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func checkJSONFormat(format, s string) error {
+	switch format {
+	case "email":
+		return validateEmail(s)
+	case "uri":
+		return validateURL(s)
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			return errors.New("must be an RFC 3339 date-time")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package faas
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"required": ["id", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "pattern": "^o_"},
+		"email": {"type": "string", "format": "email"},
+		"status": {"enum": ["new", "paid"]},
+		"total": {"type": "number", "minimum": 0},
+		"placed": {"type": "string", "format": "date-time"},
+		"note": {"type": ["string", "null"], "maxLength": 5},
+		"items": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"required": ["sku"],
+				"properties": {"sku": {"type": "string"}, "qty": {"type": "integer", "exclusiveMinimum": 0}}
+			}
+		}
+	}
+}`
+
+func TestJSONSchema(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(orderSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// layouts accepted by Time do not make a string an RFC 3339 date-time
+	defer SetTimeConfig(TimeConfig{})
+	if err := SetTimeConfig(TimeConfig{Layouts: []string{time.DateTime}, AssumeLocation: time.UTC}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want ValidationErrors
+	}{
+		{
+			name: "valid",
+			doc:  `{"id": "o_1", "email": "jane@example.com", "status": "paid", "total": 9.5, "placed": "2024-05-01T10:00:00.5+02:00", "note": null, "items": [{"sku": "a", "qty": 2}]}`,
+		},
+		{
+			name: "missing required",
+			doc:  `{"id": "o_1"}`,
+			want: ValidationErrors{{Field: "items", Message: "is required"}},
+		},
+		{
+			name: "nested item",
+			doc:  `{"id": "o_1", "items": [{"sku": "a", "qty": 1.5}]}`,
+			want: ValidationErrors{{Field: "items.0.qty", Message: "must be of type integer"}},
+		},
+		{
+			name: "additional property",
+			doc:  `{"id": "o_1", "items": [{"sku": "a"}], "extra": 1}`,
+			want: ValidationErrors{{Field: "extra", Message: "is not allowed"}},
+		},
+		{
+			name: "pattern",
+			doc:  `{"id": "x", "items": [{"sku": "a"}]}`,
+			want: ValidationErrors{{Field: "id", Message: "must match ^o_"}},
+		},
+		{
+			name: "max length",
+			doc:  `{"id": "o_1", "note": "too long", "items": [{"sku": "a"}]}`,
+			want: ValidationErrors{{Field: "note", Message: "must be at most 5 characters"}},
+		},
+		{
+			name: "date-time",
+			doc:  `{"id": "o_1", "placed": "2024-05-01 10:00:00", "items": [{"sku": "a"}]}`,
+			want: ValidationErrors{{Field: "placed", Message: "must be an RFC 3339 date-time"}},
+		},
+		{
+			name: "enum",
+			doc:  `{"id": "o_1", "status": "lost", "items": [{"sku": "a"}]}`,
+			want: ValidationErrors{{Field: "status", Message: `must be one of "new", "paid"`}},
+		},
+		{
+			name: "root type",
+			doc:  `[]`,
+			want: ValidationErrors{{Field: "(root)", Message: "must be of type object"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tc.doc))
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			var got ValidationErrors
+			if !errors.As(err, &got) {
+				t.Fatalf("expected ValidationErrors, got %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaComposition(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(`{"oneOf": [{"type": "string"}, {"type": "integer", "maximum": 10}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for doc, valid := range map[string]bool{`"a"`: true, `5`: true, `50`: false, `true`: false} {
+		if err := schema.Validate([]byte(doc)); (err == nil) != valid {
+			t.Errorf("Validate(%s) = %v, want valid %v", doc, err, valid)
+		}
+	}
+	null, err := ParseJSONSchema([]byte(`{"properties": {"deleted_at": {"const": null}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for doc, valid := range map[string]bool{`{"deleted_at": null}`: true, `{}`: true, `{"deleted_at": "2024-03-01"}`: false} {
+		if err := null.Validate([]byte(doc)); (err == nil) != valid {
+			t.Errorf("const null: Validate(%s) = %v, want valid %v", doc, err, valid)
+		}
+	}
+	if _, err := ParseJSONSchema([]byte(`{"pattern": "("}`)); err == nil {
+		t.Error("expected invalid patterns to be rejected")
+	}
+}
@@ -0,0 +1,133 @@
+package faas
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KafkaMessage is a record delivered to a function by the OpenFaaS Kafka
+// connector.
+type KafkaMessage struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       string
+	Timestamp time.Time
+	Value     []byte
+	// SchemaID is set when the value uses the Confluent wire format, in
+	// which case Value holds the payload with the 5 byte header removed.
+	SchemaID int32
+}
+
+// ReadKafkaMessage parses the headers set by the Kafka connector (X-Topic,
+// X-Kafka-Partition, X-Kafka-Offset, X-Kafka-Key and X-Kafka-Timestamp) and
+// reads the record value from the body. Headers other than X-Topic are
+// optional as older connector versions do not send them. The body is limited
+// as by ReadBody.
+func ReadKafkaMessage(r *http.Request, opts ...BodyOptions) (KafkaMessage, error) {
+	return readKafkaMessage(r, opts...)
+}
+func readKafkaMessage(r *http.Request, opts ...BodyOptions) (KafkaMessage, error) {
+	msg := KafkaMessage{
+		Topic: r.Header.Get("X-Topic"),
+		Key:   r.Header.Get("X-Kafka-Key"),
+	}
+	if msg.Topic == "" {
+		return KafkaMessage{}, errors.New("request is missing the X-Topic header")
+	}
+
+	if v := r.Header.Get("X-Kafka-Partition"); v != "" {
+		p, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return KafkaMessage{}, fmt.Errorf("invalid X-Kafka-Partition header %q", v)
+		}
+		msg.Partition = int32(p)
+	}
+	if v := r.Header.Get("X-Kafka-Offset"); v != "" {
+		o, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return KafkaMessage{}, fmt.Errorf("invalid X-Kafka-Offset header %q", v)
+		}
+		msg.Offset = o
+	}
+	if v := r.Header.Get("X-Kafka-Timestamp"); v != "" {
+		// the connector sends the record timestamp in unix milliseconds
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return KafkaMessage{}, fmt.Errorf("invalid X-Kafka-Timestamp header %q", v)
+		}
+		msg.Timestamp = time.UnixMilli(ms).UTC()
+	}
+
+	value, err := ReadBody(nil, r, opts...)
+	if err != nil {
+		return KafkaMessage{}, err
+	}
+	// Confluent serialisers prefix the payload with a zero magic byte and a
+	// big endian schema id
+	if len(value) > 5 && value[0] == 0 {
+		msg.SchemaID = int32(binary.BigEndian.Uint32(value[1:5]))
+		value = value[5:]
+	}
+	msg.Value = value
+	return msg, nil
+}
+
+// DecodeJSON unmarshals the record value into dst.
+func (m KafkaMessage) DecodeJSON(dst any) error {
+	if err := json.Unmarshal(m.Value, dst); err != nil {
+		return fmt.Errorf("topic %s offset %d: %w", m.Topic, m.Offset, triageJSONError(err, maxRecordBytes))
+	}
+	return nil
+}
+
+// DecodeAvroJSON unmarshals a value produced with the Avro JSON encoding,
+// where nullable fields are wrapped in their union type, e.g.
+// {"name": {"string": "Jane"}}. Primitive union wrappers are removed before
+// decoding into dst.
+func (m KafkaMessage) DecodeAvroJSON(dst any) error {
+	var raw any
+	if err := json.Unmarshal(m.Value, &raw); err != nil {
+		return fmt.Errorf("topic %s offset %d: %w", m.Topic, m.Offset, triageJSONError(err, maxRecordBytes))
+	}
+	js, err := json.Marshal(unwrapAvroUnions(raw))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(js, dst)
+}
+
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+	"array": true, "map": true,
+}
+
+func unwrapAvroUnions(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		if len(t) == 1 {
+			for k, inner := range t {
+				if avroPrimitives[k] {
+					return unwrapAvroUnions(inner)
+				}
+			}
+		}
+		for k, inner := range t {
+			t[k] = unwrapAvroUnions(inner)
+		}
+		return t
+	case []any:
+		for i, inner := range t {
+			t[i] = unwrapAvroUnions(inner)
+		}
+		return t
+	default:
+		return v
+	}
+}
@@ -0,0 +1,64 @@
+package faas
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadKafkaMessage(t *testing.T) {
+	body := append([]byte{0, 0, 0, 0, 42}, []byte(`{"name":{"string":"Jane"},"tags":{"array":["a"]},"age":null}`)...)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("X-Topic", "users")
+	req.Header.Set("X-Kafka-Partition", "3")
+	req.Header.Set("X-Kafka-Offset", "1024")
+	req.Header.Set("X-Kafka-Key", "user-1")
+	req.Header.Set("X-Kafka-Timestamp", "1700000000000")
+
+	msg, err := readKafkaMessage(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Topic != "users" || msg.Partition != 3 || msg.Offset != 1024 || msg.Key != "user-1" {
+		t.Errorf("unexpected message metadata %+v", msg)
+	}
+	if msg.SchemaID != 42 || msg.Timestamp.Unix() != 1700000000 {
+		t.Errorf("unexpected schema id %d or timestamp %v", msg.SchemaID, msg.Timestamp)
+	}
+
+	var user struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+		Age  *int     `json:"age"`
+	}
+	if err := msg.DecodeAvroJSON(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "Jane" || len(user.Tags) != 1 || user.Age != nil {
+		t.Errorf("unexpected decoded value %+v", user)
+	}
+}
+
+func TestReadKafkaMessageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{name: "missing topic", headers: map[string]string{}},
+		{name: "bad partition", headers: map[string]string{"X-Topic": "t", "X-Kafka-Partition": "x"}},
+		{name: "bad offset", headers: map[string]string{"X-Topic": "t", "X-Kafka-Offset": "x"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			if _, err := readKafkaMessage(req); err == nil {
+				t.Fatal("expected an error but didn't get one")
+			}
+		})
+	}
+}
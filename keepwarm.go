@@ -0,0 +1,133 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// KeepWarmOptions configures KeepWarm. Unset fields are read from the
+// environment.
+type KeepWarmOptions struct {
+	// Function is the name of the function to keep warm, usually the one
+	// running KeepWarm. Defaults to KEEP_WARM_FUNCTION.
+	Function string
+	// Path of its health route. Defaults to /healthz.
+	Path string
+	// Interval between pings, which must be shorter than the idle time
+	// after which the function is scaled to zero. Defaults to
+	// KEEP_WARM_INTERVAL, such as "5m", or 5 minutes.
+	Interval time.Duration
+	// Calendar restricts pings to its business hours, so the function can
+	// still scale to zero at night. Defaults to LoadBusinessCalendarFromEnv.
+	Calendar *BusinessCalendar
+	// Client defaults to GatewayClient.
+	Client *http.Client
+}
+
+// KeepWarm pings the health route of a function through the gateway during
+// business hours, so a latency sensitive function with little traffic is
+// not scaled to zero and does not pay for cold starts. It only runs when
+// the KEEP_WARM environment variable is true, returning nil straight away
+// otherwise, so it can be turned on per deployment:
+//
+//	faas.Background(func() { _ = faas.KeepWarm(ctx, faas.KeepWarmOptions{Function: "quote"}) })
+//
+// It runs until ctx is done. Failed pings are logged and retried at the
+// next interval.
+func KeepWarm(ctx context.Context, opts KeepWarmOptions) error {
+	enabled, _ := strconv.ParseBool(os.Getenv("KEEP_WARM"))
+	if !enabled {
+		return nil
+	}
+	if err := opts.defaults(); err != nil {
+		return err
+	}
+	url := FunctionURL(opts.Function) + opts.Path
+	slog.Info("keeping function warm", "function", opts.Function, "interval", opts.Interval)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+		now := time.Now()
+		if opts.Calendar.IsOpen(now) {
+			if err := keepWarmPing(ctx, opts.Client, url); err != nil && ctx.Err() == nil {
+				slog.Warn("keep warm ping failed", "function", opts.Function, "error", err)
+			}
+		}
+		timer.Reset(keepWarmDelay(opts.Calendar, time.Now(), opts.Interval))
+	}
+}
+
+func (o *KeepWarmOptions) defaults() error {
+	if o.Function == "" {
+		o.Function = os.Getenv("KEEP_WARM_FUNCTION")
+	}
+	if o.Function == "" {
+		return errors.New("keep warm needs a function name, set KEEP_WARM_FUNCTION")
+	}
+	if o.Path == "" {
+		o.Path = "/healthz"
+	}
+	if o.Interval <= 0 {
+		o.Interval = 5 * time.Minute
+		if v := os.Getenv("KEEP_WARM_INTERVAL"); v != "" {
+			d, err := parseDuration(v)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("invalid KEEP_WARM_INTERVAL %q, expected a duration such as 5m", v)
+			}
+			o.Interval = d
+		}
+	}
+	if o.Calendar == nil {
+		c, err := LoadBusinessCalendarFromEnv()
+		if err != nil {
+			return err
+		}
+		o.Calendar = c
+	}
+	if o.Client == nil {
+		o.Client = GatewayClient()
+	}
+	return nil
+}
+
+// keepWarmDelay returns how long to wait before the next ping: the interval
+// while open, or until the next opening when that is later.
+func keepWarmDelay(c *BusinessCalendar, now time.Time, interval time.Duration) time.Duration {
+	next := c.NextOpen(now.Add(interval))
+	if next.IsZero() {
+		// never open, check again tomorrow in case the calendar is fixed
+		return 24 * time.Hour
+	}
+	return next.Sub(now)
+}
+
+func keepWarmPing(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "go-faas-keepwarm")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("GET %s: %d %s", url, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
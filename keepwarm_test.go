@@ -0,0 +1,73 @@
+package faas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeepWarm(t *testing.T) {
+	var pings atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/function/quote/healthz" {
+			pings.Add(1)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	always := &BusinessCalendar{Location: time.UTC, Hours: map[time.Weekday][]ClockRange{}}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		always.Hours[d] = []ClockRange{{Start: 0, End: 24 * time.Hour}}
+	}
+	opts := KeepWarmOptions{Function: "quote", Interval: 10 * time.Millisecond, Calendar: always, Client: srv.Client()}
+
+	t.Setenv("KEEP_WARM", "")
+	if err := KeepWarm(context.Background(), opts); err != nil {
+		t.Fatalf("disabled: %v", err)
+	}
+
+	t.Setenv("KEEP_WARM", "true")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := KeepWarm(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := pings.Load(); n < 2 {
+		t.Errorf("pings = %d, want at least 2", n)
+	}
+
+	t.Setenv("KEEP_WARM_FUNCTION", "")
+	if err := KeepWarm(ctx, KeepWarmOptions{Calendar: always}); err == nil {
+		t.Error("expected an error without a function name")
+	}
+}
+
+func TestKeepWarmDelay(t *testing.T) {
+	t.Setenv("BUSINESS_CALENDAR_FILE", "")
+	c, err := LoadBusinessCalendarFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Duration
+	}{
+		{name: "open", now: time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC), want: 5 * time.Minute},
+		{name: "before opening", now: time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC), want: time.Hour},
+		{name: "closing for the weekend", now: time.Date(2026, 10, 16, 16, 58, 0, 0, time.UTC), want: 64*time.Hour + 2*time.Minute},
+	}
+	for _, tt := range tests {
+		if got := keepWarmDelay(c, tt.now, 5*time.Minute); got != tt.want {
+			t.Errorf("%s: delay = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	never := &BusinessCalendar{Location: time.UTC}
+	if got := keepWarmDelay(never, time.Now(), time.Minute); got != 24*time.Hour {
+		t.Errorf("never open: delay = %v", got)
+	}
+}
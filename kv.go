@@ -0,0 +1,189 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a KV when the requested key does not exist or
+// has expired.
+var ErrNotFound = errors.New("key not found")
+
+// KV is a minimal key/value store used by helpers that need to keep state
+// between invocations. A ttl of zero means the value never expires.
+type KV interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// ErrNotAtomic is returned by helpers which guarantee a single execution,
+// such as Inbox, when their store does not implement AtomicKV.
+var ErrNotAtomic = errors.New("store does not support atomic inserts")
+
+// AtomicKV is implemented by stores which can insert a key only when it is
+// absent in a single step. Helpers which need mutual exclusion, such as
+// CronGuard, use it when available and fall back to a Get followed by a Set
+// otherwise.
+type AtomicKV interface {
+	KV
+	// SetNX stores value at key unless the key exists and reports whether
+	// it was stored.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+}
+
+// CounterKV is implemented by stores with atomic counters, as used by
+// RateLimit to share limits between replicas.
+type CounterKV interface {
+	KV
+	// Incr adds one to the counter at key and returns the new count. A
+	// missing key is created expiring after ttl, incrementing an existing
+	// key keeps its expiry.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// CompareDeleteKV is implemented by stores which can delete a key only while
+// it holds a given value in a single step. CronGuard uses it to release its
+// lock without removing one another run took after the lock expired.
+type CompareDeleteKV interface {
+	KV
+	// DeleteIfEqual removes key if it holds value and reports whether it
+	// was removed.
+	DeleteIfEqual(ctx context.Context, key string, value []byte) (bool, error)
+}
+
+// setNX inserts key using AtomicKV when store implements it.
+func setNX(ctx context.Context, store KV, key string, value []byte, ttl time.Duration) (bool, error) {
+	if a, ok := store.(AtomicKV); ok {
+		return a.SetNX(ctx, key, value, ttl)
+	}
+	_, err := store.Get(ctx, key)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	return true, store.Set(ctx, key, value, ttl)
+}
+
+// deleteIfEqual removes key while it holds value, using CompareDeleteKV when
+// store implements it and a Get followed by a Delete otherwise.
+func deleteIfEqual(ctx context.Context, store KV, key string, value []byte) (bool, error) {
+	if c, ok := store.(CompareDeleteKV); ok {
+		return c.DeleteIfEqual(ctx, key, value)
+	}
+	current, err := store.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil || !bytes.Equal(current, value) {
+		return false, err
+	}
+	return true, store.Delete(ctx, key)
+}
+
+// MemoryKV is an in-process KV. It is useful for tests and single replica
+// functions but state is lost when the function scales to zero.
+type MemoryKV struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+type memoryItem struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryKV returns an empty MemoryKV.
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{items: make(map[string]memoryItem)}
+}
+
+// Get returns the value stored at key or ErrNotFound.
+func (m *MemoryKV) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !item.expires.IsZero() && time.Now().After(item.expires) {
+		delete(m.items, key)
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), item.value...), nil
+}
+
+// Set stores value at key, replacing any existing value.
+func (m *MemoryKV) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item := memoryItem{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		item.expires = time.Now().Add(ttl)
+	}
+	m.items[key] = item
+	return nil
+}
+
+// SetNX implements AtomicKV.
+func (m *MemoryKV) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if item, ok := m.items[key]; ok && (item.expires.IsZero() || time.Now().Before(item.expires)) {
+		return false, nil
+	}
+	item := memoryItem{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		item.expires = time.Now().Add(ttl)
+	}
+	m.items[key] = item
+	return true, nil
+}
+
+// Incr implements CounterKV. Counters are stored as decimal strings.
+func (m *MemoryKV) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || (!item.expires.IsZero() && time.Now().After(item.expires)) {
+		item = memoryItem{}
+		if ttl > 0 {
+			item.expires = time.Now().Add(ttl)
+		}
+	}
+	n, err := strconv.ParseInt(string(item.value), 10, 64)
+	if err != nil && len(item.value) > 0 {
+		return 0, fmt.Errorf("value of %s is not a counter", key)
+	}
+	n++
+	item.value = strconv.AppendInt(nil, n, 10)
+	m.items[key] = item
+	return n, nil
+}
+
+// DeleteIfEqual implements CompareDeleteKV.
+func (m *MemoryKV) DeleteIfEqual(_ context.Context, key string, value []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || (!item.expires.IsZero() && time.Now().After(item.expires)) || !bytes.Equal(item.value, value) {
+		return false, nil
+	}
+	delete(m.items, key)
+	return true, nil
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func (m *MemoryKV) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
@@ -0,0 +1,177 @@
+package faas
+
+import (
+	"strings"
+	"unicode"
+)
+
+// languageStopwords are the most frequent words of the languages written in
+// the Latin script which DetectLanguage tells apart.
+var languageStopwords = map[string]string{
+	"cs": "a v se na je že to s z do o jsem ale jak pro by tak které jako podle",
+	"da": "og i at det er en til på de med for af den ikke som har et var men jeg",
+	"de": "der die und in den von zu das mit sich des auf für ist im dem nicht ein eine als auch es an werden aus er hat dass",
+	"en": "the and of to in is that it for was on are with as be this have from not by at or but which you",
+	"es": "de la que el en y los del se las por un una para con no es al lo como más pero sus le ya este",
+	"fi": "ja on ei se että oli hän mutta kun niin ovat myös joka tämä ole kuin",
+	"fr": "de la le et les des en un du une que est pour qui dans par sur pas au avec ce il sont plus ne",
+	"hu": "a az és hogy nem is egy van meg de ez ha csak már volt mint",
+	"id": "yang dan di ini itu dengan untuk tidak dari dalam akan pada ke juga ada adalah",
+	"it": "di e il la che in a per un del non è della sono si le con una dei gli ma come anche",
+	"nl": "de en van het een in is dat op te zijn met voor niet aan er die als ook maar om bij",
+	"no": "og i det som er på en til for av med at ikke har den de et var men jeg seg",
+	"pl": "i w na z że do nie się to jest jak o od po co ale przez dla tak są",
+	"pt": "de a o que e do da em um para é com não uma os no se na por mais as dos como mas ao",
+	"ro": "și de în a la cu nu că o pe un din se este mai pentru care sau",
+	"sv": "och i att det som en på är av för med till den de inte om ett har jag men",
+	"tr": "ve bir bu da de için ile çok ne olan gibi daha ben o en ama mi var",
+	"vi": "và của là có không những được trong cho người một các với này đã",
+}
+
+// languageLetters are letters which, although shared by a few languages,
+// are strong evidence for them.
+var languageLetters = map[rune][]string{
+	'ñ': {"es"}, 'ã': {"pt"}, 'õ': {"pt"}, 'ç': {"fr", "pt", "tr"}, 'ß': {"de"},
+	'ä': {"de", "sv", "fi"}, 'ö': {"de", "sv", "fi", "hu", "tr"}, 'ü': {"de", "tr", "hu"},
+	'å': {"sv", "da", "no"}, 'ø': {"da", "no"}, 'æ': {"da", "no"},
+	'ł': {"pl"}, 'ą': {"pl"}, 'ę': {"pl"}, 'ś': {"pl"}, 'ź': {"pl"}, 'ż': {"pl"}, 'ń': {"pl"},
+	'ğ': {"tr"}, 'ş': {"tr"}, 'ı': {"tr"}, 'ș': {"ro"}, 'ț': {"ro"}, 'ă': {"ro", "vi"},
+	'ě': {"cs"}, 'ř': {"cs"}, 'ů': {"cs"}, 'ő': {"hu"}, 'ű': {"hu"},
+	'đ': {"vi"}, 'ơ': {"vi"}, 'ư': {"vi"},
+}
+
+var stopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(languageStopwords))
+	for lang, words := range languageStopwords {
+		set := make(map[string]bool)
+		for _, w := range strings.Fields(words) {
+			set[w] = true
+		}
+		sets[lang] = set
+	}
+	return sets
+}()
+
+// scriptLanguages are the scripts detected before looking at words, with
+// the language they imply.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Georgian, "ka"},
+	{unicode.Armenian, "hy"},
+	{unicode.Latin, ""},
+}
+
+// DetectLanguage guesses the language of text, returning its ISO 639-1
+// code and a confidence from 0 to 1, or "" and 0 when there is too little
+// to go on. Languages are told apart by their script, then by their most
+// frequent words and distinctive letters, which is quick and good enough
+// to route content of a paragraph or more, but unreliable for a few words.
+func DetectLanguage(text string) (string, float64) {
+	counts := make([]int, len(scriptLanguages))
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for i, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return "", 0
+	}
+	best := 0
+	for i := range counts {
+		if counts[i] > counts[best] {
+			best = i
+		}
+	}
+	share := float64(counts[best]) / float64(letters)
+
+	switch lang := scriptLanguages[best].lang; lang {
+	case "":
+		lang, confidence := detectLatinLanguage(text)
+		return lang, confidence * share
+	case "zh":
+		// Japanese mixes kanji with kana
+		if counts[1]+counts[2] > 0 {
+			return "ja", share
+		}
+		return lang, share
+	case "ja":
+		return lang, float64(counts[1]+counts[2]+counts[3]) / float64(letters)
+	case "ru":
+		switch {
+		case strings.ContainsAny(text, "іїєґІЇЄҐ"):
+			return "uk", share
+		case strings.ContainsAny(text, "ђћџЂЋЏ"):
+			return "sr", share
+		}
+		return lang, share
+	case "ar":
+		if strings.ContainsAny(text, "پچژگ") {
+			return "fa", share
+		}
+		return lang, share
+	default:
+		return lang, share
+	}
+}
+
+func detectLatinLanguage(text string) (string, float64) {
+	scores := make(map[string]float64)
+	words := 0
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		words++
+		for lang, set := range stopwordSets {
+			if set[w] {
+				scores[lang]++
+			}
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		for _, lang := range languageLetters[r] {
+			scores[lang] += 0.5
+		}
+	}
+
+	best, second := "", 0.0
+	for lang, score := range scores {
+		if score > scores[best] || (score == scores[best] && lang < best) {
+			second = max(second, scores[best])
+			best = lang
+		} else {
+			second = max(second, score)
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+	// how far the best stands out from the runner up, scaled down for
+	// short texts
+	confidence := 1 - second/scores[best]
+	if words < 20 {
+		confidence *= float64(words) / 20
+	}
+	return best, confidence
+}
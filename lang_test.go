@@ -0,0 +1,46 @@
+package faas
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The quick brown fox jumps over the lazy dog and runs away from the farmer, who is not happy with it at all.", "en"},
+		{"El rápido zorro marrón salta sobre el perro perezoso y se va corriendo, pero el granjero no está contento con eso.", "es"},
+		{"Le renard brun rapide saute par-dessus le chien paresseux et il s'enfuit dans la forêt avec les autres.", "fr"},
+		{"Der schnelle braune Fuchs springt über den faulen Hund und läuft mit dem Huhn in den Wald, das ist nicht gut.", "de"},
+		{"La volpe marrone salta sopra il cane pigro e scappa con la gallina, ma il contadino non è felice della cosa.", "it"},
+		{"A raposa marrom rápida pula sobre o cão preguiçoso e foge com a galinha, mas o fazendeiro não está feliz.", "pt"},
+		{"De snelle bruine vos springt over de luie hond en rent met de kip naar het bos, maar dat is niet leuk voor de boer.", "nl"},
+		{"Szybki brązowy lis przeskakuje nad leniwym psem i ucieka do lasu, ale to nie jest dla rolnika dobre.", "pl"},
+		{"Быстрая коричневая лиса прыгает через ленивую собаку.", "ru"},
+		{"Швидка бура лисиця перестрибує через ледачого пса, і їй це подобається.", "uk"},
+		{"敏捷的棕色狐狸跳过了懒狗。", "zh"},
+		{"素早い茶色の狐はのろまな犬を飛び越える。", "ja"},
+		{"빠른 갈색 여우가 게으른 개를 뛰어넘는다.", "ko"},
+		{"Η γρήγορη καφέ αλεπού πηδάει πάνω από το τεμπέλικο σκυλί.", "el"},
+		{"الثعلب البني السريع يقفز فوق الكلب الكسول.", "ar"},
+		{"روباه قهوه‌ای چابک از روی سگ تنبل می‌پرد.", "fa"},
+		{"", ""},
+		{"12345 !!!", ""},
+	}
+	for _, tt := range tests {
+		got, confidence := DetectLanguage(tt.text)
+		if got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+		if confidence < 0 || confidence > 1 || (tt.want != "" && confidence == 0) {
+			t.Errorf("DetectLanguage(%q) confidence = %v", tt.text, confidence)
+		}
+	}
+}
+
+func TestDetectLanguageShortText(t *testing.T) {
+	_, short := DetectLanguage("the dog")
+	_, long := DetectLanguage("The dog is in the garden and it is barking at the cat, which is on the wall of the house.")
+	if short >= long {
+		t.Errorf("short text confidence %v is not below long text confidence %v", short, long)
+	}
+}
@@ -0,0 +1,167 @@
+package faas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocaleFormat is how numbers, amounts of money and dates are written in a
+// locale.
+type LocaleFormat struct {
+	Decimal string
+	Group   string
+	// CurrencyAfter writes the currency symbol after the amount, as in
+	// "1.234,56 €".
+	CurrencyAfter bool
+	// CurrencySpace separates a symbol written before the amount with a
+	// space, as in "R$ 1.234,56". A symbol written after always is.
+	CurrencySpace bool
+	// Date and DateTime are time layouts.
+	Date     string
+	DateTime string
+}
+
+const (
+	nbsp       = "\u00a0"
+	narrowNbsp = "\u202f"
+)
+
+var (
+	localesMu sync.RWMutex
+	locales   = map[string]LocaleFormat{
+		"en":    {Decimal: ".", Group: ",", Date: "01/02/2006", DateTime: "01/02/2006 3:04 PM"},
+		"en-GB": {Decimal: ".", Group: ",", Date: "02/01/2006", DateTime: "02/01/2006 15:04"},
+		"en-AU": {Decimal: ".", Group: ",", Date: "02/01/2006", DateTime: "02/01/2006 3:04 PM"},
+		"de":    {Decimal: ",", Group: ".", CurrencyAfter: true, Date: "02.01.2006", DateTime: "02.01.2006 15:04"},
+		"fr":    {Decimal: ",", Group: narrowNbsp, CurrencyAfter: true, Date: "02/01/2006", DateTime: "02/01/2006 15:04"},
+		"es":    {Decimal: ",", Group: ".", CurrencyAfter: true, Date: "02/01/2006", DateTime: "02/01/2006 15:04"},
+		"it":    {Decimal: ",", Group: ".", CurrencyAfter: true, Date: "02/01/2006", DateTime: "02/01/2006 15:04"},
+		"nl":    {Decimal: ",", Group: ".", CurrencySpace: true, Date: "02-01-2006", DateTime: "02-01-2006 15:04"},
+		"pt":    {Decimal: ",", Group: nbsp, CurrencyAfter: true, Date: "02/01/2006", DateTime: "02/01/2006 15:04"},
+		"pt-BR": {Decimal: ",", Group: ".", CurrencySpace: true, Date: "02/01/2006", DateTime: "02/01/2006 15:04"},
+		"sv":    {Decimal: ",", Group: nbsp, CurrencyAfter: true, Date: "2006-01-02", DateTime: "2006-01-02 15:04"},
+		"ja":    {Decimal: ".", Group: ",", Date: "2006/01/02", DateTime: "2006/01/02 15:04"},
+		"zh":    {Decimal: ".", Group: ",", Date: "2006/01/02", DateTime: "2006/01/02 15:04"},
+	}
+)
+
+// RegisterLocale adds or replaces the format of a language tag.
+func RegisterLocale(lang string, f LocaleFormat) {
+	localesMu.Lock()
+	defer localesMu.Unlock()
+	locales[lang] = f
+}
+
+// Locale returns the format of lang, falling back to its base language,
+// such as "de" for "de-AT", then to "en". lang is typically the result of
+// Language for the request.
+func Locale(lang string) LocaleFormat {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+	for _, l := range []string{lang, baseLanguage(lang)} {
+		if f, ok := locales[l]; ok {
+			return f
+		}
+	}
+	return locales["en"]
+}
+
+// FormatNumber writes d with the separators of lang, keeping its scale,
+// e.g. "1.234,50" for MustParseDecimal("1234.50") in "de".
+func FormatNumber(lang string, d Decimal) string {
+	f := Locale(lang)
+	s, sign := d.String(), ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, ok := strings.Cut(s, ".")
+	out := sign + groupDigits(whole, f.Group)
+	if ok {
+		out += f.Decimal + frac
+	}
+	return out
+}
+
+// FormatInteger writes n with the group separator of lang.
+func FormatInteger(lang string, n int64) string {
+	return FormatNumber(lang, NewDecimal(n, 0))
+}
+
+// FormatMoney writes m in lang with its currency's minor units and symbol,
+// e.g. "€1,234.50" in "en" and "1.234,50 €" in "de". Currencies without a
+// known symbol use their code.
+func FormatMoney(lang string, m Money) string {
+	minor, err := m.Minor()
+	if err != nil {
+		return m.Currency + " " + FormatNumber(lang, m.Amount)
+	}
+	s, err := formatCurrencyIn(minor, m.Currency, Locale(lang))
+	if err != nil {
+		return m.Currency + " " + FormatNumber(lang, m.Amount)
+	}
+	return s
+}
+
+// FormatDate writes the date of t in lang, in t's location.
+func FormatDate(lang string, t time.Time) string {
+	return t.Format(Locale(lang).Date)
+}
+
+// FormatDateTime writes the date and time of t in lang, in t's location.
+func FormatDateTime(lang string, t time.Time) string {
+	return t.Format(Locale(lang).DateTime)
+}
+
+// groupDigits inserts sep between every three digits from the right.
+func groupDigits(digits, sep string) string {
+	var b strings.Builder
+	for i, r := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// formatCurrencyIn formats an amount in minor units of code in locale f.
+func formatCurrencyIn(minor int64, code string, f LocaleFormat) (string, error) {
+	cur, ok := lookupCurrency(code)
+	if !ok {
+		return "", fmt.Errorf("unknown currency %q", code)
+	}
+	units := cur.MinorUnits
+	if units < 0 {
+		units = 0
+	}
+
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	digits := strconv.FormatInt(minor, 10)
+	if len(digits) <= units {
+		digits = strings.Repeat("0", units-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-units], digits[len(digits)-units:]
+	amount := groupDigits(whole, f.Group)
+	if units > 0 {
+		amount += f.Decimal + frac
+	}
+
+	symbol, space := cur.Symbol, f.CurrencySpace
+	if symbol == "" {
+		symbol, space = cur.Code, true
+	}
+	if f.CurrencyAfter {
+		return sign + amount + nbsp + symbol, nil
+	}
+	if space {
+		return sign + symbol + " " + amount, nil
+	}
+	return sign + symbol + amount, nil
+}
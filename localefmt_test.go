@@ -0,0 +1,51 @@
+package faas
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocaleFormatting(t *testing.T) {
+	d := MustParseDecimal("-1234567.50")
+	eur, err := NewMoney(MustParseDecimal("1234.5"), "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	brl, _ := NewMoney(MustParseDecimal("1234.5"), "BRL")
+	chf, _ := NewMoney(MustParseDecimal("99"), "CHF")
+	jpy, _ := NewMoney(MustParseDecimal("1500"), "JPY")
+	at := time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{"number en", FormatNumber("en", d), "-1,234,567.50"},
+		{"number de-AT", FormatNumber("de-AT", d), "-1.234.567,50"},
+		{"number fr", FormatNumber("fr", d), "-1 234 567,50"},
+		{"integer unknown locale", FormatInteger("xx", 1000), "1,000"},
+		{"money en", FormatMoney("en", eur), "€1,234.50"},
+		{"money de", FormatMoney("de", eur), "1.234,50 €"},
+		{"money pt-BR", FormatMoney("pt-BR", brl), "R$ 1.234,50"},
+		{"money without symbol", FormatMoney("de", chf), "99,00 CHF"},
+		{"money without minor units", FormatMoney("ja", jpy), "¥1,500"},
+		{"date en", FormatDate("en", at), "03/09/2024"},
+		{"date en-GB", FormatDate("en-GB", at), "09/03/2024"},
+		{"date time de", FormatDateTime("de", at), "09.03.2024 14:05"},
+		{"date time en", FormatDateTime("en-US", at), "03/09/2024 2:05 PM"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	RegisterLocale("xx", LocaleFormat{Decimal: "'", Group: "_", Date: "2006.01.02"})
+	defer func() {
+		localesMu.Lock()
+		delete(locales, "xx")
+		localesMu.Unlock()
+	}()
+	if got := FormatNumber("xx-YY", d); got != "-1_234_567'50" {
+		t.Errorf("registered locale: got %q", got)
+	}
+}
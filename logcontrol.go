@@ -0,0 +1,170 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+)
+
+// DefaultLogControl controls the logger installed by ConfigureLogging.
+var DefaultLogControl = NewLogControl()
+
+// LogSettings are the runtime adjustable logging settings.
+type LogSettings struct {
+	Level slog.Level `json:"level"`
+	// SampleRate is the fraction of debug and info records which are
+	// written, between 0 and 1. Warnings and errors are never sampled.
+	SampleRate float64 `json:"sample_rate"`
+}
+
+// LogControl holds a log level and sampling rate which can be changed while
+// the function is running, e.g. to turn on debug logging for a misbehaving
+// function without a redeploy.
+type LogControl struct {
+	// KV optionally persists changes made through Apply. Other replicas and
+	// restarted instances pick them up by calling Load.
+	KV KV
+	// Key is the KV key used for persistence. Defaults to "faas:log-control".
+	Key string
+
+	level  slog.LevelVar
+	sample atomic.Uint64 // math.Float64bits of the sample rate
+}
+
+// NewLogControl returns a LogControl at info level with no sampling.
+func NewLogControl() *LogControl {
+	c := &LogControl{}
+	c.sample.Store(math.Float64bits(1))
+	return c
+}
+
+// Settings returns the current settings.
+func (c *LogControl) Settings() LogSettings {
+	return LogSettings{Level: c.level.Level(), SampleRate: math.Float64frombits(c.sample.Load())}
+}
+
+// Apply replaces the current settings and persists them when KV is set.
+func (c *LogControl) Apply(ctx context.Context, s LogSettings) error {
+	if s.SampleRate < 0 || s.SampleRate > 1 || math.IsNaN(s.SampleRate) {
+		return fmt.Errorf("sample rate must be between 0 and 1, got %v", s.SampleRate)
+	}
+	c.level.Set(s.Level)
+	c.sample.Store(math.Float64bits(s.SampleRate))
+	if c.KV == nil {
+		return nil
+	}
+	js, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return c.KV.Set(ctx, c.key(), js, 0)
+}
+
+// Load restores settings persisted in KV. Missing settings are not an error.
+func (c *LogControl) Load(ctx context.Context) error {
+	if c.KV == nil {
+		return nil
+	}
+	js, err := c.KV.Get(ctx, c.key())
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var s LogSettings
+	if err := json.Unmarshal(js, &s); err != nil {
+		return fmt.Errorf("invalid persisted log settings: %w", err)
+	}
+	c.level.Set(s.Level)
+	c.sample.Store(math.Float64bits(s.SampleRate))
+	return nil
+}
+
+func (c *LogControl) key() string {
+	if c.Key == "" {
+		return "faas:log-control"
+	}
+	return c.Key
+}
+
+// Handler wraps h so records are filtered by the control's level and sample
+// rate. The level of h itself is bypassed. Records logged with the context
+// of a request being debugged, see Debug, are never filtered.
+func (c *LogControl) Handler(h slog.Handler) slog.Handler {
+	return controlledHandler{Handler: h, control: c}
+}
+
+type controlledHandler struct {
+	slog.Handler
+	control *LogControl
+}
+
+func (h controlledHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.control.level.Level() || IsDebug(ctx)
+}
+
+func (h controlledHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && !IsDebug(ctx) {
+		if rate := math.Float64frombits(h.control.sample.Load()); rate < 1 && rand.Float64() >= rate {
+			return nil
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h controlledHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return controlledHandler{Handler: h.Handler.WithAttrs(attrs), control: h.control}
+}
+
+func (h controlledHandler) WithGroup(name string) slog.Handler {
+	return controlledHandler{Handler: h.Handler.WithGroup(name), control: h.control}
+}
+
+// AdminHandler returns an endpoint for viewing and changing the settings.
+// Callers must send "Authorization: Bearer <token>" where the token is the
+// OpenFaaS secret named tokenSecret. GET returns the current settings and
+// POST accepts any of {"level": "debug", "sample_rate": 0.5}.
+func (c *LogControl) AdminHandler(tokenSecret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeBearer(w, r, tokenSecret, "log control") {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var input struct {
+				Level      *slog.Level `json:"level"`
+				SampleRate *float64    `json:"sample_rate"`
+			}
+			if err := readJSON(w, r, &input); err != nil {
+				_ = writeJSONError(w, Error{Status: http.StatusText(http.StatusBadRequest), Reason: err.Error(), Code: http.StatusBadRequest})
+				return
+			}
+			s := c.Settings()
+			if input.Level != nil {
+				s.Level = *input.Level
+			}
+			if input.SampleRate != nil {
+				s.SampleRate = *input.SampleRate
+			}
+			if err := c.Apply(r.Context(), s); err != nil {
+				_ = writeJSONError(w, Error{Status: http.StatusText(http.StatusBadRequest), Reason: err.Error(), Code: http.StatusBadRequest})
+				return
+			}
+			slog.Warn("log settings changed", "level", s.Level, "sample_rate", s.SampleRate)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			_ = writeJSONError(w, Error{Status: http.StatusText(http.StatusMethodNotAllowed), Code: http.StatusMethodNotAllowed})
+			return
+		}
+		_ = writeJSON(w, http.StatusOK, c.Settings(), nil)
+	})
+}
@@ -0,0 +1,97 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogControlHandler(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewLogControl()
+	logger := slog.New(c.Handler(slog.NewTextHandler(&buf, nil)))
+
+	logger.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug to be filtered at info level, got %q", buf.String())
+	}
+
+	if err := c.Apply(context.Background(), LogSettings{Level: slog.LevelDebug, SampleRate: 0}); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("sampled out")
+	logger.Error("always kept")
+	if got := buf.String(); strings.Contains(got, "sampled out") || !strings.Contains(got, "always kept") {
+		t.Errorf("unexpected output %q", got)
+	}
+
+	if err := c.Apply(context.Background(), LogSettings{SampleRate: 2}); err == nil {
+		t.Error("expected a sample rate above 1 to be rejected")
+	}
+}
+
+func TestLogControlLoad(t *testing.T) {
+	kv := NewMemoryKV()
+	c := NewLogControl()
+	c.KV = kv
+	if err := c.Apply(context.Background(), LogSettings{Level: slog.LevelDebug, SampleRate: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewLogControl()
+	restarted.KV = kv
+	if err := restarted.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := restarted.Settings(); got != (LogSettings{Level: slog.LevelDebug, SampleRate: 0.5}) {
+		t.Errorf("unexpected settings %+v", got)
+	}
+}
+
+func TestLogControlAdminHandler(t *testing.T) {
+	withSecrets(t, map[string]string{"log-token": "t0ken"})
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		body   string
+		status int
+		want   LogSettings
+	}{
+		{name: "get", method: http.MethodGet, token: "t0ken", status: http.StatusOK, want: LogSettings{SampleRate: 1}},
+		{name: "set level", method: http.MethodPost, token: "t0ken", body: `{"level": "debug"}`, status: http.StatusOK, want: LogSettings{Level: slog.LevelDebug, SampleRate: 1}},
+		{name: "invalid rate", method: http.MethodPost, token: "t0ken", body: `{"sample_rate": -1}`, status: http.StatusBadRequest},
+		{name: "bad token", method: http.MethodGet, token: "guess", status: http.StatusUnauthorized},
+		{name: "bad method", method: http.MethodDelete, token: "t0ken", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewLogControl().AdminHandler("log-token")
+			r := httptest.NewRequest(tc.method, "/", strings.NewReader(tc.body))
+			r.Header.Set("Authorization", "Bearer "+tc.token)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, r)
+
+			if rr.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, rr.Code, rr.Body)
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			var got LogSettings
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
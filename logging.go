@@ -0,0 +1,315 @@
+package faas
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Log formats accepted by NewLogHandler and the LOG_FORMAT environment
+// variable.
+const (
+	// LogFormatPlain writes logfmt lines without a timestamp since the
+	// OpenFaaS watchdog prefixes every line it collects with its own.
+	LogFormatPlain = "plain"
+	// LogFormatJSON writes the standard slog JSON format.
+	LogFormatJSON = "json"
+	// LogFormatGCP writes JSON using the field names recognised by Cloud
+	// Logging, as used by Cloud Run and Knative on GKE.
+	LogFormatGCP = "gcp"
+)
+
+// ConfigureLogging installs a default slog logger using the format named
+// by the LOG_FORMAT environment variable. When it is unset the format is
+// GCP on Cloud Run or Knative, detected by K_SERVICE, and plain otherwise.
+// The initial level is read from LOG_LEVEL and can be changed at runtime
+// through DefaultLogControl or with SIGHUP, for turning on debug logs in
+// production without a redeploy. When DefaultLogControl has a KV the signal
+// reloads the persisted settings, which AdminHandler writes, otherwise it
+// toggles between debug and the level from LOG_LEVEL.
+func ConfigureLogging() (*slog.Logger, error) {
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+		DefaultLogControl.level.Set(l)
+	}
+	format := os.Getenv("LOG_FORMAT")
+	if format == "" {
+		format = LogFormatPlain
+		if os.Getenv("K_SERVICE") != "" {
+			format = LogFormatGCP
+		}
+	}
+	h, err := NewLogHandler(os.Stderr, format, nil)
+	if err != nil {
+		return nil, err
+	}
+	logger := slog.New(DefaultLogControl.Handler(h))
+	slog.SetDefault(logger)
+
+	sighupOnce.Do(func() {
+		initial := DefaultLogControl.level.Level()
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGHUP)
+		go func() {
+			for range ch {
+				reloadLogLevel(initial)
+			}
+		}()
+	})
+	return logger, nil
+}
+
+var sighupOnce sync.Once
+
+func reloadLogLevel(initial slog.Level) {
+	c := DefaultLogControl
+	if c.KV != nil {
+		if err := c.Load(context.Background()); err != nil {
+			slog.Error("reloading log settings", "error", err)
+			return
+		}
+	} else if c.level.Level() == slog.LevelDebug {
+		c.level.Set(initial)
+	} else {
+		c.level.Set(slog.LevelDebug)
+	}
+	slog.Info("log level changed", "level", c.level.Level())
+}
+
+// NewLogHandler returns a slog.Handler writing to w in the given format.
+func NewLogHandler(w io.Writer, format string, opts *slog.HandlerOptions) (slog.Handler, error) {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	switch strings.ToLower(format) {
+	case LogFormatPlain, "openfaas", "text":
+		o := *opts
+		o.ReplaceAttr = chainReplaceAttr(dropTime, opts.ReplaceAttr)
+		return slog.NewTextHandler(w, &o), nil
+	case LogFormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	case LogFormatGCP, "knative", "cloudrun":
+		o := *opts
+		o.ReplaceAttr = chainReplaceAttr(gcpReplaceAttr, opts.ReplaceAttr)
+		return gcpHandler{Handler: slog.NewJSONHandler(w, &o), project: os.Getenv("GOOGLE_CLOUD_PROJECT")}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected plain, json or gcp", format)
+	}
+}
+
+func chainReplaceAttr(first, second func([]string, slog.Attr) slog.Attr) func([]string, slog.Attr) slog.Attr {
+	if second == nil {
+		return first
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		return second(groups, first(groups, a))
+	}
+}
+
+func dropTime(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func gcpReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "timestamp"
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.LevelKey:
+		a.Key = "severity"
+		level, _ := a.Value.Any().(slog.Level)
+		a.Value = slog.StringValue(gcpSeverity(level))
+	case slog.SourceKey:
+		a.Key = "logging.googleapis.com/sourceLocation"
+	}
+	return a
+}
+
+func gcpSeverity(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError+4:
+		return "CRITICAL"
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARNING"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// gcpHandler adds the trace of the request stored in the context by
+// LogContext so Cloud Logging groups log lines under their request.
+type gcpHandler struct {
+	slog.Handler
+	project string
+}
+
+func (h gcpHandler) Handle(ctx context.Context, r slog.Record) error {
+	if tc, ok := ctx.Value(traceKey{}).(traceContext); ok {
+		trace := tc.traceID
+		if h.project != "" {
+			trace = "projects/" + h.project + "/traces/" + tc.traceID
+		}
+		r.AddAttrs(slog.String("logging.googleapis.com/trace", trace))
+		if tc.spanID != "" {
+			r.AddAttrs(slog.String("logging.googleapis.com/spanId", tc.spanID))
+		}
+		r.AddAttrs(slog.Bool("logging.googleapis.com/trace_sampled", tc.sampled))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h gcpHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return gcpHandler{Handler: h.Handler.WithAttrs(attrs), project: h.project}
+}
+
+func (h gcpHandler) WithGroup(name string) slog.Handler {
+	return gcpHandler{Handler: h.Handler.WithGroup(name), project: h.project}
+}
+
+type traceKey struct{}
+
+type traceContext struct {
+	traceID string
+	spanID  string
+	sampled bool
+}
+
+// LogContext returns the request context carrying the trace from the
+// traceparent or X-Cloud-Trace-Context headers. Pass it to slog's Context
+// functions, e.g. slog.InfoContext(faas.LogContext(r), "done").
+func LogContext(r *http.Request) context.Context {
+	if _, ok := r.Context().Value(traceKey{}).(traceContext); ok {
+		return r.Context()
+	}
+	if tc, ok := parseTraceHeaders(r.Header); ok {
+		return context.WithValue(r.Context(), traceKey{}, tc)
+	}
+	return r.Context()
+}
+
+// Tracing is middleware adding the incoming trace to the request context,
+// starting a new sampled trace when the caller did not send one, so logs
+// and outbound calls made with the request context belong to it.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc, ok := parseTraceHeaders(r.Header)
+		if !ok {
+			tc = traceContext{traceID: randomHex(16), spanID: randomHex(8), sampled: true}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), traceKey{}, tc)))
+	})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func parseTraceHeaders(h http.Header) (traceContext, bool) {
+	// traceparent: 00-<32 hex trace id>-<16 hex span id>-<flags>
+	if parts := strings.Split(h.Get("traceparent"), "-"); len(parts) == 4 && len(parts[1]) == 32 {
+		return traceContext{traceID: parts[1], spanID: parts[2], sampled: strings.HasSuffix(parts[3], "1")}, true
+	}
+	// X-Cloud-Trace-Context: <trace id>/<decimal span id>;o=1
+	v := h.Get("X-Cloud-Trace-Context")
+	if v == "" {
+		return traceContext{}, false
+	}
+	v, opts, _ := strings.Cut(v, ";")
+	traceID, span, _ := strings.Cut(v, "/")
+	if traceID == "" {
+		return traceContext{}, false
+	}
+	tc := traceContext{traceID: traceID, sampled: opts == "o=1"}
+	if id, err := strconv.ParseUint(span, 10, 64); err == nil {
+		tc.spanID = fmt.Sprintf("%016x", id)
+	}
+	return tc, true
+}
+
+// Logger returns the default logger with the function name, namespace and
+// version attached, as read from the function_name, function_namespace and
+// function_version environment variables. Unset values are omitted.
+func Logger() *slog.Logger {
+	var attrs []any
+	for _, f := range []struct{ key, env string }{
+		{"function", "function_name"},
+		{"namespace", "function_namespace"},
+		{"version", "function_version"},
+	} {
+		if v := os.Getenv(f.env); v != "" {
+			attrs = append(attrs, slog.String(f.key, v))
+		}
+	}
+	return slog.Default().With(attrs...)
+}
+
+// WithLogger returns a copy of ctx carrying logger for LoggerFromContext.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return WithValue(ctx, LoggerKey, logger)
+}
+
+// LoggerFromContext returns the logger stored by WithLogger or the
+// RequestLogger middleware, falling back to Logger.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := FromContext(ctx, LoggerKey); ok {
+		return logger
+	}
+	return Logger()
+}
+
+// RequestID returns the ID of the request set by RequestLogger.
+func RequestID(ctx context.Context) string {
+	id, _ := FromContext(ctx, RequestIDKey)
+	return id
+}
+
+// RequestLogger is middleware storing a logger for LoggerFromContext with
+// the function metadata of Logger plus the request ID, method and path. The
+// ID is taken from the X-Call-Id header set by the OpenFaaS gateway or
+// X-Request-Id, and generated when neither is present. It is echoed in the
+// X-Request-Id response header. The ID and client address are also recorded
+// in audit events, see Audit.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Call-Id")
+		if id == "" {
+			id = r.Header.Get("X-Request-Id")
+		}
+		if id == "" {
+			id = randomHex(8)
+		}
+		w.Header().Set("X-Request-Id", id)
+
+		ctx := LogContext(r)
+		logger := Logger().With("request_id", id, "method", r.Method, "path", r.URL.Path)
+		ctx = WithValue(ctx, RequestIDKey, id)
+		ctx = WithValue(ctx, RealIPKey, ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(WithLogger(ctx, logger)))
+	})
+}
@@ -0,0 +1,188 @@
+package faas
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "my-project")
+
+	tests := []struct {
+		name   string
+		format string
+		header [2]string
+		want   map[string]any
+	}{
+		{
+			name:   "gcp with cloud trace header",
+			format: "gcp",
+			header: [2]string{"X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1"},
+			want: map[string]any{
+				"severity":                             "WARNING",
+				"message":                              "slow",
+				"logging.googleapis.com/trace":         "projects/my-project/traces/105445aa7843bc8bf206b12000100000",
+				"logging.googleapis.com/spanId":        "0000000000000001",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+		},
+		{
+			name:   "gcp with traceparent",
+			format: "knative",
+			header: [2]string{"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+			want: map[string]any{
+				"logging.googleapis.com/trace":         "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+				"logging.googleapis.com/spanId":        "00f067aa0ba902b7",
+				"logging.googleapis.com/trace_sampled": false,
+			},
+		},
+		{
+			name:   "json",
+			format: "json",
+			want:   map[string]any{"level": "WARN", "msg": "slow"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h, err := NewLogHandler(&buf, tc.format, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest("GET", "/", nil)
+			if tc.header[0] != "" {
+				r.Header.Set(tc.header[0], tc.header[1])
+			}
+			slog.New(h).WarnContext(LogContext(r), "slow", "ms", 1200)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			for k, v := range tc.want {
+				if got[k] != v {
+					t.Errorf("expected %s=%v, got %v", k, v, got[k])
+				}
+			}
+		})
+	}
+}
+
+func TestNewLogHandlerPlain(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewLogHandler(&buf, "plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Info("started", "port", 8080)
+	if got := strings.TrimSpace(buf.String()); got != "level=INFO msg=started port=8080" {
+		t.Errorf("unexpected log line %q", got)
+	}
+
+	if _, err := NewLogHandler(&buf, "xml", nil); err == nil {
+		t.Error("expected unknown format to be rejected")
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	t.Setenv("function_name", "orders")
+	t.Setenv("function_namespace", "openfaas-fn")
+
+	var buf bytes.Buffer
+	restoreDefaultLogger(t)
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	tests := []struct {
+		name   string
+		header [2]string
+		wantID string
+	}{
+		{name: "call id", header: [2]string{"X-Call-Id", "call-1"}, wantID: "call-1"},
+		{name: "request id", header: [2]string{"X-Request-Id", "req-1"}, wantID: "req-1"},
+		{name: "generated"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			var gotID string
+			h := RequestLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID = RequestID(r.Context())
+				LoggerFromContext(r.Context()).Info("handled")
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			if tc.header[0] != "" {
+				req.Header.Set(tc.header[0], tc.header[1])
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if tc.wantID != "" && gotID != tc.wantID {
+				t.Errorf("request id = %q, want %q", gotID, tc.wantID)
+			}
+			if gotID == "" || rec.Header().Get("X-Request-Id") != gotID {
+				t.Errorf("expected X-Request-Id %q, got %q", gotID, rec.Header().Get("X-Request-Id"))
+			}
+			var line map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range map[string]any{"function": "orders", "namespace": "openfaas-fn", "request_id": gotID, "path": "/orders"} {
+				if line[k] != v {
+					t.Errorf("%s = %v, want %v", k, line[k], v)
+				}
+			}
+			if _, ok := line["version"]; ok {
+				t.Error("unset metadata should be omitted")
+			}
+		})
+	}
+}
+
+func TestConfigureLoggingSIGHUP(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_FORMAT", "json")
+	restoreDefaultLogger(t)
+	t.Cleanup(func() { DefaultLogControl.level.Set(slog.LevelInfo) })
+
+	if _, err := ConfigureLogging(); err != nil {
+		t.Fatal(err)
+	}
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []slog.Level{slog.LevelDebug, slog.LevelWarn} {
+		if err := self.Signal(syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for DefaultLogControl.Settings().Level != want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := DefaultLogControl.Settings().Level; got != want {
+			t.Fatalf("level after SIGHUP = %v, want %v", got, want)
+		}
+	}
+}
+
+// restoreDefaultLogger puts back the default slog logger and the output of
+// the log package, which slog.SetDefault redirects, when the test ends.
+func restoreDefaultLogger(t *testing.T) {
+	t.Helper()
+	prev, out, flags := slog.Default(), log.Writer(), log.Flags()
+	t.Cleanup(func() {
+		slog.SetDefault(prev)
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	})
+}
@@ -0,0 +1,410 @@
+// Package mail sends email over SMTP, for notification functions:
+//
+//	mailer, err := mail.NewFromEnv()
+//	if err != nil {
+//		return err
+//	}
+//	err = mailer.Send(ctx, &mail.Message{
+//		To:      []string{"jane@example.com"},
+//		Subject: "Your order has shipped",
+//		Text:    "...",
+//		HTML:    "<p>...</p>",
+//	})
+//
+// Messages with both Text and HTML are sent as multipart/alternative so
+// every client can display them. Render builds messages from templates.
+// Transient failures, such as greylisting or a dropped connection, are
+// retried.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// Config describes the SMTP server.
+type Config struct {
+	Host string
+	// Port defaults to 587. Port 465 uses implicit TLS, other ports
+	// upgrade with STARTTLS when the server offers it.
+	Port     int
+	Username string
+	Password string
+	// From is the default sender address.
+	From string
+	// RequireTLS fails instead of sending in plain text when the server
+	// does not offer STARTTLS.
+	RequireTLS bool
+	// Timeout bounds each delivery attempt. Defaults to 30s.
+	Timeout time.Duration
+}
+
+// ConfigFromEnv reads the server from SMTP_HOST, SMTP_PORT and SMTP_FROM,
+// and the credentials from the OpenFaaS secrets smtp-username and
+// smtp-password when they exist. SMTP_REQUIRE_TLS=true sets RequireTLS.
+func ConfigFromEnv() (Config, error) {
+	c := Config{
+		Host:       os.Getenv("SMTP_HOST"),
+		From:       os.Getenv("SMTP_FROM"),
+		RequireTLS: os.Getenv("SMTP_REQUIRE_TLS") == "true",
+	}
+	if c.Host == "" {
+		return Config{}, errors.New("SMTP_HOST is not set")
+	}
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		var err error
+		if c.Port, err = strconv.Atoi(port); err != nil {
+			return Config{}, fmt.Errorf("invalid SMTP_PORT %q", port)
+		}
+	}
+	for secret, dst := range map[string]*string{"smtp-username": &c.Username, "smtp-password": &c.Password} {
+		v, err := faas.GetSecretString(secret)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return Config{}, fmt.Errorf("reading %s: %w", secret, err)
+		}
+		*dst = v
+	}
+	return c, nil
+}
+
+// Message is an email to send. From defaults to the Config's From.
+type Message struct {
+	From    string
+	To      []string
+	Cc      []string
+	Bcc     []string
+	ReplyTo string
+	Subject string
+	Text    string
+	HTML    string
+	// Headers are added to the message, e.g. List-Unsubscribe.
+	Headers map[string]string
+}
+
+// Render builds a message from the templates "<name>.subject.tmpl",
+// "<name>.txt.tmpl" and "<name>.html.tmpl" in fsys, typically an embed.FS.
+// The subject template is required, at least one of the bodies must exist.
+// The HTML template is escaped with html/template.
+func Render(fsys fs.FS, name string, data any) (*Message, error) {
+	subject, err := renderText(fsys, name+".subject.tmpl", data)
+	if err != nil {
+		return nil, err
+	}
+	msg := &Message{Subject: strings.TrimSpace(subject)}
+	if msg.Text, err = renderText(fsys, name+".txt.tmpl", data); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if msg.HTML, err = renderHTML(fsys, name+".html.tmpl", data); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if msg.Text == "" && msg.HTML == "" {
+		return nil, fmt.Errorf("no body template for %s", name)
+	}
+	return msg, nil
+}
+
+func renderText(fsys fs.FS, file string, data any) (string, error) {
+	src, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return "", err
+	}
+	t, err := template.New(file).Parse(string(src))
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func renderHTML(fsys fs.FS, file string, data any) (string, error) {
+	src, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return "", err
+	}
+	t, err := htmltemplate.New(file).Parse(string(src))
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Mailer sends messages. It is safe for concurrent use.
+type Mailer struct {
+	Config Config
+	// Retry defaults to 3 attempts with a 2s backoff.
+	Retry *faas.RetryPolicy
+}
+
+// NewFromEnv returns a Mailer configured by ConfigFromEnv.
+func NewFromEnv() (*Mailer, error) {
+	c, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &Mailer{Config: c}, nil
+}
+
+// Send delivers msg, retrying transient failures. SMTP 4xx replies and
+// network errors are transient, 5xx replies are not.
+func (m *Mailer) Send(ctx context.Context, msg *Message) error {
+	from := msg.From
+	if from == "" {
+		from = m.Config.From
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	var rcpts []string
+	for _, list := range [][]string{msg.To, msg.Cc, msg.Bcc} {
+		for _, a := range list {
+			addr, err := mail.ParseAddress(a)
+			if err != nil {
+				return fmt.Errorf("invalid recipient %q: %w", a, err)
+			}
+			rcpts = append(rcpts, addr.Address)
+		}
+	}
+	if len(rcpts) == 0 {
+		return errors.New("message has no recipients")
+	}
+	data, err := msg.build(sender)
+	if err != nil {
+		return err
+	}
+
+	policy := faas.RetryPolicy{Attempts: 3, Backoff: faas.Duration(2 * time.Second), Jitter: true}
+	if m.Retry != nil {
+		policy = *m.Retry
+	}
+	return faas.Retry(ctx, policy, func(ctx context.Context) error {
+		err := m.send(ctx, sender.Address, rcpts, data)
+		var tpErr *textproto.Error
+		if errors.As(err, &tpErr) && tpErr.Code >= 500 {
+			return faas.Permanent(err)
+		}
+		return err
+	})
+}
+
+func (m *Mailer) send(ctx context.Context, from string, rcpts []string, data []byte) error {
+	c := m.Config
+	port := c.Port
+	if port == 0 {
+		port = 587
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(port))
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: c.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+				return err
+			}
+		} else if c.RequireTLS {
+			return faas.Permanent(errors.New("smtp server does not support STARTTLS"))
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range rcpts {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// validHeaderKey reports whether k is a non-empty string of header token
+// characters, so it cannot inject other headers.
+func validHeaderKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, c := range k {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// build renders the message in RFC 5322 format.
+func (msg *Message) build(from *mail.Address) ([]byte, error) {
+	if msg.Text == "" && msg.HTML == "" {
+		return nil, errors.New("message has no body")
+	}
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	header("From", from.String())
+	if to := formatList(msg.To); to != "" {
+		header("To", to)
+	}
+	if cc := formatList(msg.Cc); cc != "" {
+		header("Cc", cc)
+	}
+	if msg.ReplyTo != "" {
+		replyTo, err := mail.ParseAddress(msg.ReplyTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reply-to address %q: %w", msg.ReplyTo, err)
+		}
+		header("Reply-To", replyTo.String())
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+messageID()+"@"+domain(from.Address)+">")
+	header("MIME-Version", "1.0")
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		if !validHeaderKey(k) {
+			return nil, fmt.Errorf("invalid header name %q", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		header(textproto.CanonicalMIMEHeaderKey(k), mime.QEncoding.Encode("utf-8", msg.Headers[k]))
+	}
+
+	if msg.Text == "" || msg.HTML == "" {
+		contentType, body := "text/plain; charset=utf-8", msg.Text
+		if msg.HTML != "" {
+			contentType, body = "text/html; charset=utf-8", msg.HTML
+		}
+		header("Content-Type", contentType)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(pw, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func formatList(list []string) string {
+	out := make([]string, 0, len(list))
+	for _, a := range list {
+		if addr, err := mail.ParseAddress(a); err == nil {
+			out = append(out, addr.String())
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+func messageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func domain(addr string) string {
+	if _, d, ok := strings.Cut(addr, "@"); ok {
+		return d
+	}
+	return "localhost"
+}
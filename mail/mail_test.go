@@ -0,0 +1,338 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// smtpServer is a minimal SMTP server replying to MAIL FROM with the codes
+// in mailReplies, one per connection, then 250.
+type smtpServer struct {
+	ln          net.Listener
+	mailReplies []string
+
+	mu    sync.Mutex
+	conns int
+	rcpts []string
+	data  string
+}
+
+func newSMTPServer(t *testing.T, mailReplies ...string) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &smtpServer{ln: ln, mailReplies: mailReplies}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) config() Config {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return Config{Host: host, Port: p, From: "Shop <shop@example.com>"}
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	s.mu.Lock()
+	n := s.conns
+	s.conns++
+	s.mu.Unlock()
+
+	rd := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			if n < len(s.mailReplies) {
+				reply(s.mailReplies[n])
+				continue
+			}
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mu.Unlock()
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := rd.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func noWait() *faas.RetryPolicy {
+	return &faas.RetryPolicy{Attempts: 3, Backoff: faas.Duration(time.Millisecond)}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name      string
+		replies   []string
+		wantErr   bool
+		wantConns int
+	}{
+		{name: "delivered", wantConns: 1},
+		{name: "transient error is retried", replies: []string{"451 try again later"}, wantConns: 2},
+		{name: "permanent error is not retried", replies: []string{"550 mailbox unavailable"}, wantErr: true, wantConns: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSMTPServer(t, tt.replies...)
+			m := &Mailer{Config: srv.config(), Retry: noWait()}
+			err := m.Send(context.Background(), &Message{
+				To:      []string{"jane@example.com"},
+				Bcc:     []string{"audit@example.com"},
+				Subject: "Your order",
+				Text:    "Hello",
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			if srv.conns != tt.wantConns {
+				t.Errorf("connections = %d, want %d", srv.conns, tt.wantConns)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := strings.Join(srv.rcpts, ","); got != "jane@example.com,audit@example.com" {
+				t.Errorf("recipients = %s", got)
+			}
+			if strings.Contains(srv.data, "audit@example.com") {
+				t.Error("Bcc recipient is in the message headers")
+			}
+		})
+	}
+}
+
+func TestSendValidation(t *testing.T) {
+	m := &Mailer{Config: Config{Host: "localhost", From: "shop@example.com"}}
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{name: "no recipients", msg: Message{Subject: "x", Text: "x"}},
+		{name: "invalid recipient", msg: Message{To: []string{"not an address"}, Text: "x"}},
+		{name: "no body", msg: Message{To: []string{"jane@example.com"}}},
+		{name: "invalid sender", msg: Message{From: "@", To: []string{"jane@example.com"}, Text: "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.Send(context.Background(), &tt.msg); err == nil {
+				t.Error("Send() returned no error")
+			}
+		})
+	}
+}
+
+func TestBuildRejectsHeaderInjection(t *testing.T) {
+	from := &mail.Address{Address: "shop@example.com"}
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{name: "reply-to with CRLF", msg: Message{ReplyTo: "a@example.com\r\nBcc: victim@example.com", Text: "x"}},
+		{name: "invalid reply-to", msg: Message{ReplyTo: "not an address", Text: "x"}},
+		{name: "header key with CRLF", msg: Message{Headers: map[string]string{"X-A\r\nBcc": "victim@example.com"}, Text: "x"}},
+		{name: "header key with colon", msg: Message{Headers: map[string]string{"Bcc: victim@example.com\r\nX-A": "x"}, Text: "x"}},
+		{name: "empty header key", msg: Message{Headers: map[string]string{"": "x"}, Text: "x"}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.msg.build(from); err == nil {
+			t.Errorf("%s: build() returned no error", tt.name)
+		}
+	}
+
+	msg := &Message{ReplyTo: "Support <support@example.com>", Headers: map[string]string{"X-Tag": "a\r\nBcc: victim@example.com"}, Text: "x"}
+	data, err := msg.build(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Header.Get("Reply-To"); got != `"Support" <support@example.com>` {
+		t.Errorf("Reply-To = %q", got)
+	}
+	if got := parsed.Header.Get("Bcc"); got != "" {
+		t.Errorf("Bcc smuggled through a header value: %q", got)
+	}
+}
+
+func TestBuildSinglePart(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      Message
+		wantType string
+		wantBody string
+	}{
+		{name: "text", msg: Message{Text: "plain body = ünïcode"}, wantType: "text/plain", wantBody: "plain body = ünïcode"},
+		{name: "html", msg: Message{HTML: "<p>html body</p>"}, wantType: "text/html", wantBody: "<p>html body</p>"},
+	}
+	for _, tt := range tests {
+		data, err := tt.msg.build(&mail.Address{Address: "shop@example.com"})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got, _, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type")); got != tt.wantType {
+			t.Errorf("%s: Content-Type = %s, want %s", tt.name, got, tt.wantType)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(body) != tt.wantBody {
+			t.Errorf("%s: body = %q, want %q", tt.name, body, tt.wantBody)
+		}
+	}
+}
+
+func TestBuildMultipart(t *testing.T) {
+	msg := &Message{
+		To:      []string{"Jane <jane@example.com>"},
+		Subject: "Grüße",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+		Headers: map[string]string{"list-unsubscribe": "<https://example.com/unsub>"},
+	}
+	data, err := msg.build(&mail.Address{Address: "shop@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); got != "Grüße" {
+		t.Errorf("Subject = %q", got)
+	}
+	if got := parsed.Header.Get("List-Unsubscribe"); got != "<https://example.com/unsub>" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %s", mediaType)
+	}
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []string{"text/plain", "text/html"} {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); got != want {
+			t.Errorf("part Content-Type = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"welcome.subject.tmpl": {Data: []byte("Welcome {{.Name}}\n")},
+		"welcome.txt.tmpl":     {Data: []byte("Hi {{.Name}}")},
+		"welcome.html.tmpl":    {Data: []byte("<p>Hi {{.Name}}</p>")},
+		"reset.subject.tmpl":   {Data: []byte("Reset")},
+		"reset.txt.tmpl":       {Data: []byte("Code {{.Code}}")},
+		"empty.subject.tmpl":   {Data: []byte("Empty")},
+	}
+	data := map[string]string{"Name": "<Jane>", "Code": "123"}
+
+	msg, err := Render(fsys, "welcome", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Welcome <Jane>" || msg.Text != "Hi <Jane>" || msg.HTML != "<p>Hi &lt;Jane&gt;</p>" {
+		t.Errorf("Render() = %+v", msg)
+	}
+
+	msg, err = Render(fsys, "reset", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Text != "Code 123" || msg.HTML != "" {
+		t.Errorf("Render() = %+v", msg)
+	}
+
+	for _, name := range []string{"empty", "missing"} {
+		if _, err := Render(fsys, name, data); err == nil {
+			t.Errorf("Render(%s) returned no error", name)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_PORT", "465")
+	t.Setenv("SMTP_FROM", "shop@example.com")
+	c, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Host != "smtp.example.com" || c.Port != 465 || c.From != "shop@example.com" {
+		t.Errorf("ConfigFromEnv() = %+v", c)
+	}
+
+	t.Setenv("SMTP_PORT", "smtp")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("invalid port returned no error")
+	}
+	t.Setenv("SMTP_HOST", "")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("missing host returned no error")
+	}
+}
@@ -0,0 +1,124 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Iterator yields items one at a time in the style of bufio.Scanner. Next
+// advances to the next item and returns false when there are no more items
+// or an error occurred, which is then reported by Err.
+type Iterator[T any] interface {
+	Next() bool
+	Value() T
+	Err() error
+}
+
+// SliceIterator returns an Iterator over the items of s.
+func SliceIterator[T any](s []T) Iterator[T] {
+	return &sliceIterator[T]{items: s, pos: -1}
+}
+
+type sliceIterator[T any] struct {
+	items []T
+	pos   int
+}
+
+func (s *sliceIterator[T]) Next() bool {
+	s.pos++
+	return s.pos < len(s.items)
+}
+
+func (s *sliceIterator[T]) Value() T {
+	return s.items[s.pos]
+}
+
+func (s *sliceIterator[T]) Err() error {
+	return nil
+}
+
+// MapReduce calls mapFn for every item of it using up to concurrency
+// goroutines and folds the results with reduceFn. reduceFn is only ever
+// called from a single goroutine so it needs no locking. Errors returned by
+// mapFn, panics and the iterator error are joined together and do not stop
+// the remaining items from being processed; cancelling ctx does.
+func MapReduce[T, M, R any](
+	ctx context.Context,
+	it Iterator[T],
+	mapFn func(context.Context, T) (M, error),
+	reduceFn func(R, M) R,
+	concurrency int,
+) (R, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type result struct {
+		value M
+		err   error
+	}
+
+	items := make(chan T)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				value, err := mapItem(ctx, item, mapFn)
+				results <- result{value: value, err: err}
+			}
+		}()
+	}
+
+	var iterErr error
+	go func() {
+		defer close(items)
+		for it.Next() {
+			select {
+			case items <- it.Value():
+			case <-ctx.Done():
+				return
+			}
+		}
+		iterErr = it.Err()
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var acc R
+	var errs []error
+	for res := range results {
+		if res.err != nil {
+			errs = append(errs, res.err)
+			continue
+		}
+		acc = reduceFn(acc, res.value)
+	}
+
+	// iterErr is safe to read once results is closed as the producer has
+	// finished by then
+	if iterErr != nil {
+		errs = append(errs, iterErr)
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return acc, errors.Join(errs...)
+}
+
+func mapItem[T, M any](ctx context.Context, item T, mapFn func(context.Context, T) (M, error)) (value M, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("map panic: %v", rec)
+		}
+	}()
+	return mapFn(ctx, item)
+}
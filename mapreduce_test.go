@@ -0,0 +1,45 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMapReduce(t *testing.T) {
+	square := func(_ context.Context, n int) (int, error) {
+		if n == 3 {
+			return 0, errors.New("three is not allowed")
+		}
+		if n == 4 {
+			panic("four")
+		}
+		return n * n, nil
+	}
+	sum := func(acc, n int) int { return acc + n }
+
+	total, err := MapReduce(context.Background(), SliceIterator([]int{1, 2, 3, 4, 5}), square, sum, 3)
+	if total != 1+4+25 {
+		t.Errorf("expected total of 30, got %d", total)
+	}
+	if err == nil {
+		t.Fatal("expected an error but didn't get one")
+	}
+	if !strings.Contains(err.Error(), "three is not allowed") || !strings.Contains(err.Error(), "map panic: four") {
+		t.Errorf("expected joined errors, got %v", err)
+	}
+}
+
+func TestMapReduceCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	identity := func(_ context.Context, n int) (int, error) { return n, nil }
+	sum := func(acc, n int) int { return acc + n }
+
+	_, err := MapReduce(ctx, SliceIterator(make([]int, 1000)), identity, sum, 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package faas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Money is an exact amount in an ISO 4217 currency. It is encoded in JSON as
+// {"amount": "12.34", "currency": "USD"} with the amount as a string.
+type Money struct {
+	Amount   Decimal
+	Currency string
+}
+
+type moneyJSON struct {
+	Amount   Decimal `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// NewMoney returns amount in currency. It fails for unknown currencies and
+// amounts with more decimal places than the currency allows.
+func NewMoney(amount Decimal, currency string) (Money, error) {
+	cur, ok := lookupCurrency(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("unknown currency %q", currency)
+	}
+	units := int32(max(cur.MinorUnits, 0))
+	if amount.Scale() > units && amount.Round(units).Cmp(amount) != 0 {
+		return Money{}, fmt.Errorf("%s amounts must not have more than %d decimal places", cur.Code, units)
+	}
+	return Money{Amount: amount.Round(units), Currency: cur.Code}, nil
+}
+
+// MoneyFromMinor returns an amount given in minor units, e.g. cents.
+func MoneyFromMinor(minor int64, currency string) (Money, error) {
+	cur, ok := lookupCurrency(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("unknown currency %q", currency)
+	}
+	return Money{Amount: NewDecimal(minor, int32(max(cur.MinorUnits, 0))), Currency: cur.Code}, nil
+}
+
+// Minor returns the amount in minor units, e.g. cents, as used by most
+// payment APIs.
+func (m Money) Minor() (int64, error) {
+	return m.Amount.Int64(m.units())
+}
+
+func (m Money) units() int32 {
+	cur, _ := lookupCurrency(m.Currency)
+	return int32(max(cur.MinorUnits, 0))
+}
+
+// Add returns m + o. Both must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("cannot add %s to %s", o.Currency, m.Currency)
+	}
+	return Money{Amount: m.Amount.Add(o.Amount), Currency: m.Currency}, nil
+}
+
+// Sub returns m - o. Both must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("cannot subtract %s from %s", o.Currency, m.Currency)
+	}
+	return Money{Amount: m.Amount.Sub(o.Amount), Currency: m.Currency}, nil
+}
+
+// Mul returns m multiplied by factor, such as a tax rate, rounded half-even
+// to the currency's minor units.
+func (m Money) Mul(factor Decimal) Money {
+	return Money{Amount: m.Amount.Mul(factor).Round(m.units()), Currency: m.Currency}
+}
+
+// Allocate splits m by the given ratios without losing or creating minor
+// units. Any remainder is handed out one unit at a time from the first
+// share, so Allocate(1, 1, 1) of $1.00 gives $0.34, $0.33 and $0.33.
+func (m Money) Allocate(ratios ...int) ([]Money, error) {
+	total := 0
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, errors.New("allocation ratios must not be negative")
+		}
+		total += r
+	}
+	if total == 0 {
+		return nil, errors.New("allocation ratios must not all be zero")
+	}
+
+	units := m.units()
+	minor := m.Amount.Round(units).int()
+	remainder := new(big.Int).Set(minor)
+	shares := make([]*big.Int, len(ratios))
+	for i, r := range ratios {
+		shares[i] = new(big.Int).Quo(new(big.Int).Mul(minor, big.NewInt(int64(r))), big.NewInt(int64(total)))
+		remainder.Sub(remainder, shares[i])
+	}
+	step := big.NewInt(int64(remainder.Sign()))
+	for i := 0; remainder.Sign() != 0; i = (i + 1) % len(shares) {
+		if ratios[i] == 0 {
+			continue
+		}
+		shares[i].Add(shares[i], step)
+		remainder.Sub(remainder, step)
+	}
+
+	out := make([]Money, len(shares))
+	for i, s := range shares {
+		out[i] = Money{Amount: Decimal{coef: s, scale: units}, Currency: m.Currency}
+	}
+	return out, nil
+}
+
+// String formats m for display, e.g. "$12.34".
+func (m Money) String() string {
+	minor, err := m.Minor()
+	if err != nil {
+		return m.Currency + " " + m.Amount.String()
+	}
+	s, err := formatCurrency(minor, m.Currency)
+	if err != nil {
+		return m.Currency + " " + m.Amount.String()
+	}
+	return s
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Amount, Currency: m.Currency})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, rejecting unknown currencies
+// and amounts with too many decimal places.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	parsed, err := NewMoney(v.Amount, strings.ToUpper(v.Currency))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,73 @@
+package faas
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{name: "valid", input: `{"amount":"12.30","currency":"usd"}`, expected: "$12.30"},
+		{name: "number amount", input: `{"amount":1000,"currency":"JPY"}`, expected: "¥1,000"},
+		{name: "too precise", input: `{"amount":"1.234","currency":"USD"}`, expectErr: true},
+		{name: "unknown currency", input: `{"amount":"1","currency":"XYZ"}`, expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var m Money
+			err := json.Unmarshal([]byte(tc.input), &m)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if !tc.expectErr && m.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, m)
+			}
+		})
+	}
+
+	m, _ := MoneyFromMinor(1999, "EUR")
+	js, _ := json.Marshal(m)
+	if string(js) != `{"amount":"19.99","currency":"EUR"}` {
+		t.Errorf("unexpected json %s", js)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	price, _ := MoneyFromMinor(1999, "USD")
+	shipping, _ := MoneyFromMinor(500, "USD")
+	total, err := price.Add(shipping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minor, _ := total.Minor(); minor != 2499 {
+		t.Errorf("expected 2499, got %d", minor)
+	}
+
+	tax := total.Mul(MustParseDecimal("0.1"))
+	if tax.String() != "$2.50" {
+		t.Errorf("expected $2.50 tax, got %s", tax)
+	}
+
+	euros, _ := MoneyFromMinor(100, "EUR")
+	if _, err := total.Add(euros); err == nil {
+		t.Error("expected an error adding different currencies")
+	}
+
+	dollar, _ := MoneyFromMinor(100, "USD")
+	shares, err := dollar.Allocate(1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"$0.34", "$0.33", "$0.33"}
+	for i, s := range shares {
+		if s.String() != expected[i] {
+			t.Errorf("expected share %d to be %s, got %s", i, expected[i], s)
+		}
+	}
+}
@@ -0,0 +1,538 @@
+package faas
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessageKey signs or verifies HTTP message signatures (RFC 9421), with
+// either a shared HMAC-SHA256 secret or an Ed25519 key pair. Ed25519 lets
+// many callers verify a function's responses without being able to sign
+// them.
+type MessageKey struct {
+	// ID is sent as the keyid parameter so the verifier can pick the key.
+	ID string
+
+	secret  []byte
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// NewHMACMessageKey returns a key signing with HMAC-SHA256.
+func NewHMACMessageKey(id string, secret []byte) *MessageKey {
+	return &MessageKey{ID: id, secret: secret}
+}
+
+// NewEd25519MessageKey returns a key signing with priv, which also verifies
+// its signatures.
+func NewEd25519MessageKey(id string, priv ed25519.PrivateKey) *MessageKey {
+	return &MessageKey{ID: id, private: priv, public: priv.Public().(ed25519.PublicKey)}
+}
+
+// NewEd25519VerifyKey returns a key which only verifies signatures.
+func NewEd25519VerifyKey(id string, pub ed25519.PublicKey) *MessageKey {
+	return &MessageKey{ID: id, public: pub}
+}
+
+// LoadMessageKey reads a key from the OpenFaaS secret name, which is also
+// its ID. A PEM "PRIVATE KEY" or "PUBLIC KEY" holds an Ed25519 key, as
+// written by "openssl genpkey -algorithm ed25519" and "openssl pkey
+// -pubout", and anything else is an HMAC secret.
+func LoadMessageKey(name string) (*MessageKey, error) {
+	secret, err := getSecret(name)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(secret)
+	if block == nil {
+		secret = bytes.TrimSpace(secret)
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("message key %s is empty", name)
+		}
+		return NewHMACMessageKey(name, secret), nil
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing message key %s: %w", name, err)
+		}
+		priv, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("message key %s is not an Ed25519 key", name)
+		}
+		return NewEd25519MessageKey(name, priv), nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing message key %s: %w", name, err)
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("message key %s is not an Ed25519 key", name)
+		}
+		return NewEd25519VerifyKey(name, pub), nil
+	}
+	return nil, fmt.Errorf("message key %s has unsupported PEM type %q", name, block.Type)
+}
+
+// Alg returns the RFC 9421 algorithm name of the key.
+func (k *MessageKey) Alg() string {
+	if k.secret != nil {
+		return "hmac-sha256"
+	}
+	return "ed25519"
+}
+
+func (k *MessageKey) sign(base []byte) ([]byte, error) {
+	switch {
+	case k.secret != nil:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(base)
+		return mac.Sum(nil), nil
+	case k.private != nil:
+		return ed25519.Sign(k.private, base), nil
+	}
+	return nil, fmt.Errorf("message key %s can only verify", k.ID)
+}
+
+func (k *MessageKey) verify(base, sig []byte) bool {
+	if k.secret != nil {
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(base)
+		return hmac.Equal(sig, mac.Sum(nil))
+	}
+	return k.public != nil && ed25519.Verify(k.public, base, sig)
+}
+
+// signatureLabel names the signatures written by this package.
+const signatureLabel = "sig1"
+
+// SignRequest adds Content-Digest, Signature-Input and Signature headers to
+// r covering its method, path, query, content type and body, which the
+// caller has already read into body. The authority is not covered as the
+// gateway rewrites it, and the /function/<name> prefix is left out of the
+// path of gateway URLs, so the function verifies the path it is served.
+func SignRequest(r *http.Request, body []byte, key *MessageKey) error {
+	r.Header.Set("Content-Digest", contentDigest(body))
+	// @query is always covered, as "?" when empty, so a query cannot be
+	// added to a signed request
+	components := []string{"@method", "@path", "@query", "content-digest"}
+	if r.Header.Get("Content-Type") != "" {
+		components = append(components, "content-type")
+	}
+	return signMessage(r.Header, requestMessage(r, true), components, key)
+}
+
+// VerifyRequest checks r carries a message signature from one of keys
+// over at least its method, path, query and body, which the caller has
+// read into body, created within tolerance of now, DefaultWebhookTolerance
+// when zero. Errors wrap ErrInvalidSignature.
+func VerifyRequest(r *http.Request, body []byte, tolerance time.Duration, keys ...*MessageKey) error {
+	return verifyMessage(r.Header, requestMessage(r, false), body, tolerance, []string{"@method", "@path", "@query", "content-digest"}, keys)
+}
+
+// VerifyResponse checks resp carries a message signature from one of keys
+// over at least its status and body, as written by SignResponses.
+func VerifyResponse(resp *http.Response, body []byte, tolerance time.Duration, keys ...*MessageKey) error {
+	return verifyMessage(resp.Header, responseMessage(resp.StatusCode, resp.Header), body, tolerance, []string{"@status", "content-digest"}, keys)
+}
+
+// SignResponses is middleware signing every response with key, covering
+// its status, content type and body, so callers can check with
+// VerifyResponse that it came from this function. Responses are buffered
+// to compute their digest, which makes SignResponses unsuitable for
+// streaming handlers.
+func SignResponses(key *MessageKey) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bw := &bufferedResponse{header: make(http.Header)}
+			next.ServeHTTP(bw, r)
+
+			status := bw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			h := bw.header
+			h.Set("Content-Digest", contentDigest(bw.body.Bytes()))
+			components := []string{"@status", "content-digest"}
+			if h.Get("Content-Type") != "" {
+				components = append(components, "content-type")
+			}
+			if err := signMessage(h, responseMessage(status, h), components, key); err != nil {
+				LoggerFromContext(r.Context()).Error("signing response", "error", err)
+				_ = writeError(w, err)
+				return
+			}
+			for k, v := range h {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write(bw.body.Bytes())
+		})
+	}
+}
+
+// RequireSignature is middleware rejecting requests without a valid
+// message signature from one of keys with a 401, see VerifyRequest. The
+// body is read, up to the limit of ReadBody, and replaced for next.
+func RequireSignature(tolerance time.Duration, keys ...*MessageKey) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ReadBody(w, r)
+			if err != nil {
+				_ = writeError(w, err)
+				return
+			}
+			if err := VerifyRequest(r, body, tolerance, keys...); err != nil {
+				_ = writeError(w, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SigningTransport signs each request with Key before sending it through
+// Base, http.DefaultTransport when nil, for clients of functions behind
+// RequireSignature:
+//
+//	client := &http.Client{Transport: faas.SigningTransport{Key: key, Base: faas.GatewayClient().Transport}}
+type SigningTransport struct {
+	Key  *MessageKey
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t SigningTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	// a RoundTripper must not modify the caller's request
+	r = r.Clone(r.Context())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	if err := SignRequest(r, body, t.Key); err != nil {
+		return nil, err
+	}
+	return base.RoundTrip(r)
+}
+
+// CloseIdleConnections closes the idle connections of Base.
+func (t SigningTransport) CloseIdleConnections() {
+	closeIdleConnections(t.Base)
+}
+
+// message derives the values of the components of a signature.
+type message struct {
+	method string
+	path   string
+	query  string
+	status int
+	header http.Header
+}
+
+func requestMessage(r *http.Request, outgoing bool) message {
+	path := r.URL.EscapedPath()
+	if outgoing {
+		path = gatewayFunctionPath(r.URL)
+	}
+	if path == "" {
+		path = "/"
+	}
+	return message{method: r.Method, path: path, query: r.URL.RawQuery, header: r.Header}
+}
+
+func responseMessage(status int, h http.Header) message {
+	return message{status: status, header: h}
+}
+
+// gatewayFunctionPath strips /function/<name> from the path of a gateway
+// URL, which the gateway does before calling the function.
+func gatewayFunctionPath(u *url.URL) string {
+	path := u.EscapedPath()
+	gw, err := url.Parse(gatewayBaseURL())
+	if err != nil || u.Host != gw.Host {
+		return path
+	}
+	rest, ok := strings.CutPrefix(path, "/function/")
+	if !ok {
+		return path
+	}
+	if _, p, ok := strings.Cut(rest, "/"); ok {
+		return "/" + p
+	}
+	return "/"
+}
+
+func (m message) component(name string) (string, error) {
+	switch name {
+	case "@method":
+		if m.method != "" {
+			return m.method, nil
+		}
+	case "@path":
+		if m.path != "" {
+			return m.path, nil
+		}
+	case "@query":
+		if m.method != "" {
+			return "?" + m.query, nil
+		}
+	case "@status":
+		if m.status != 0 {
+			return strconv.Itoa(m.status), nil
+		}
+	default:
+		if strings.HasPrefix(name, "@") {
+			return "", fmt.Errorf("unsupported component %s", name)
+		}
+		if values, ok := m.header[http.CanonicalHeaderKey(name)]; ok {
+			trimmed := make([]string, len(values))
+			for i, v := range values {
+				trimmed[i] = strings.TrimSpace(v)
+			}
+			return strings.Join(trimmed, ", "), nil
+		}
+	}
+	return "", fmt.Errorf("missing component %s", name)
+}
+
+// signatureBase builds the signature base of RFC 9421 section 2.5.
+func (m message) signatureBase(components []string, params string) ([]byte, error) {
+	var b bytes.Buffer
+	for _, c := range components {
+		v, err := m.component(c)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%q: %s\n", c, v)
+	}
+	fmt.Fprintf(&b, "%q: %s", "@signature-params", params)
+	return b.Bytes(), nil
+}
+
+func signMessage(h http.Header, m message, components []string, key *MessageKey) error {
+	quoted := make([]string, len(components))
+	for i, c := range components {
+		quoted[i] = strconv.Quote(c)
+	}
+	params := fmt.Sprintf("(%s);created=%d;keyid=%s;alg=%q",
+		strings.Join(quoted, " "), time.Now().Unix(), strconv.Quote(key.ID), key.Alg())
+	base, err := m.signatureBase(components, params)
+	if err != nil {
+		return err
+	}
+	sig, err := key.sign(base)
+	if err != nil {
+		return err
+	}
+	h.Set("Signature-Input", signatureLabel+"="+params)
+	h.Set("Signature", signatureLabel+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+func verifyMessage(h http.Header, m message, body []byte, tolerance time.Duration, required []string, keys []*MessageKey) error {
+	if tolerance <= 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+	if got, ok := digestValue(h.Get("Content-Digest")); !ok || !hmac.Equal(got, sha256Sum(body)) {
+		return messageSignatureError("content digest does not match")
+	}
+	inputs := splitDictionary(h.Get("Signature-Input"))
+	sigs := splitDictionary(h.Get("Signature"))
+	if len(inputs) == 0 {
+		return messageSignatureError("missing signature")
+	}
+
+	reason := "signature does not match"
+	for label, params := range inputs {
+		sig, ok := byteSequence(sigs[label])
+		if !ok {
+			continue
+		}
+		components, keyID, created, err := parseSignatureParams(params)
+		if err != nil {
+			reason = err.Error()
+			continue
+		}
+		if missing := missingComponent(components, required); missing != "" {
+			reason = "signature does not cover " + missing
+			continue
+		}
+		if d := time.Since(time.Unix(created, 0)); d > tolerance || d < -tolerance {
+			reason = "signature created outside tolerance"
+			continue
+		}
+		base, err := m.signatureBase(components, params)
+		if err != nil {
+			reason = err.Error()
+			continue
+		}
+		for _, k := range keys {
+			if k.ID == keyID && k.verify(base, sig) {
+				return nil
+			}
+		}
+	}
+	return messageSignatureError(reason)
+}
+
+// parseSignatureParams reads the components and parameters of a
+// Signature-Input member such as
+// ("@method" "@path");created=1618884473;keyid="test-key".
+func parseSignatureParams(params string) (components []string, keyID string, created int64, err error) {
+	list, rest, ok := strings.Cut(params, ")")
+	list, found := strings.CutPrefix(list, "(")
+	if !ok || !found {
+		return nil, "", 0, errors.New("malformed signature input")
+	}
+	for _, c := range strings.Fields(list) {
+		name, err := strconv.Unquote(c)
+		if err != nil {
+			return nil, "", 0, errors.New("malformed signature input")
+		}
+		components = append(components, name)
+	}
+	for _, p := range strings.Split(rest, ";") {
+		k, v, _ := strings.Cut(p, "=")
+		switch k {
+		case "keyid":
+			keyID, _ = strconv.Unquote(v)
+		case "created":
+			created, _ = strconv.ParseInt(v, 10, 64)
+		case "expires":
+			if exp, _ := strconv.ParseInt(v, 10, 64); exp > 0 && time.Now().Unix() > exp {
+				return nil, "", 0, errors.New("signature expired")
+			}
+		}
+	}
+	if created == 0 {
+		return nil, "", 0, errors.New("signature has no created time")
+	}
+	return components, keyID, created, nil
+}
+
+func missingComponent(components, required []string) string {
+	for _, r := range required {
+		found := false
+		for _, c := range components {
+			found = found || c == r
+		}
+		if !found {
+			return r
+		}
+	}
+	return ""
+}
+
+// splitDictionary splits a structured field dictionary into its members,
+// keeping the value of each as sent. Commas within parentheses, quotes and
+// byte sequences do not separate members.
+func splitDictionary(field string) map[string]string {
+	members := make(map[string]string)
+	depth, quoted, start := 0, false, 0
+	add := func(end int) {
+		if k, v, ok := strings.Cut(strings.TrimSpace(field[start:end]), "="); ok {
+			members[k] = v
+		}
+	}
+	for i := 0; i < len(field); i++ {
+		switch c := field[i]; {
+		case c == '"':
+			quoted = !quoted
+		case c == '\\' && quoted:
+			i++
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			add(i)
+			start = i + 1
+		}
+	}
+	add(len(field))
+	return members
+}
+
+// byteSequence decodes a structured field byte sequence, :base64:.
+func byteSequence(v string) ([]byte, bool) {
+	v, ok := strings.CutPrefix(v, ":")
+	if !ok {
+		return nil, false
+	}
+	v, ok = strings.CutSuffix(v, ":")
+	if !ok {
+		return nil, false
+	}
+	b, err := base64.StdEncoding.DecodeString(v)
+	return b, err == nil
+}
+
+// contentDigest returns the Content-Digest (RFC 9530) of body.
+func contentDigest(body []byte) string {
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sha256Sum(body)) + ":"
+}
+
+func digestValue(field string) ([]byte, bool) {
+	return byteSequence(splitDictionary(field)["sha-256"])
+}
+
+func sha256Sum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
+
+func messageSignatureError(reason string) error {
+	return E(CodeUnauthenticated, "invalid message signature: "+reason, ErrInvalidSignature)
+}
+
+// bufferedResponse holds a response until it is complete.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
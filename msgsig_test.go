@@ -0,0 +1,175 @@
+package faas
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	t.Setenv("GATEWAY_URL", "http://gateway.openfaas:8080")
+	key := NewHMACMessageKey("orders", []byte("s3cret"))
+	body := []byte(`{"id":1}`)
+	r := httptest.NewRequest(http.MethodPost, "http://gateway.openfaas:8080/function/orders/refund?dry_run=1", nil)
+	r.Header.Set("Content-Type", "application/json")
+	if err := SignRequest(r, body, key); err != nil {
+		t.Fatal(err)
+	}
+	input := r.Header.Get("Signature-Input")
+	if !strings.HasPrefix(input, `sig1=("@method" "@path" "@query" "content-digest" "content-type");created=`) ||
+		!strings.HasSuffix(input, `;keyid="orders";alg="hmac-sha256"`) {
+		t.Errorf("Signature-Input = %s", input)
+	}
+
+	// the function is served the path without the gateway prefix
+	served := httptest.NewRequest(http.MethodPost, "/refund?dry_run=1", nil)
+	served.Header = r.Header.Clone()
+	if err := VerifyRequest(served, body, 0, key); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *http.Request) []byte
+	}{
+		{name: "tampered body", modify: func(*http.Request) []byte { return []byte(`{"id":2}`) }},
+		{name: "other path", modify: func(r *http.Request) []byte { r.URL.Path = "/charge"; return body }},
+		{name: "tampered query", modify: func(r *http.Request) []byte { r.URL.RawQuery = "dry_run=0&admin=true"; return body }},
+		{name: "other content type", modify: func(r *http.Request) []byte { r.Header.Set("Content-Type", "text/plain"); return body }},
+		{name: "unsigned", modify: func(r *http.Request) []byte { r.Header.Del("Signature"); return body }},
+		{name: "partial coverage", modify: func(r *http.Request) []byte {
+			r.Header.Set("Signature-Input", `sig1=("content-digest");created=`+strconv.FormatInt(time.Now().Unix(), 10)+`;keyid="orders"`)
+			return body
+		}},
+	}
+	for _, tt := range tests {
+		req := served.Clone(served.Context())
+		req.Header = served.Header.Clone()
+		err := VerifyRequest(req, tt.modify(req), 0, key)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: err = %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+	if err := VerifyRequest(served, body, 0, NewHMACMessageKey("orders", []byte("other"))); err == nil {
+		t.Error("expected an error with the wrong key")
+	}
+
+	// a query added to a request signed without one
+	plain := httptest.NewRequest(http.MethodPost, "/orders/1", nil)
+	if err := SignRequest(plain, body, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyRequest(plain, body, 0, key); err != nil {
+		t.Fatalf("verify without query: %v", err)
+	}
+	plain.URL.RawQuery = "admin=true&delete=all"
+	if err := VerifyRequest(plain, body, 0, key); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("added query: err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestRequireSignature(t *testing.T) {
+	key := NewHMACMessageKey("billing", []byte("s3cret"))
+	var got string
+	srv := httptest.NewServer(RequireSignature(0, key)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	})))
+	defer srv.Close()
+
+	signed := &http.Client{Transport: SigningTransport{Key: key}}
+	resp, err := signed.Post(srv.URL+"/invoices", "application/json", strings.NewReader(`{"total":5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || got != `{"total":5}` {
+		t.Errorf("signed: status %d, body %q", resp.StatusCode, got)
+	}
+
+	resp, err = http.Post(srv.URL+"/invoices", "application/json", strings.NewReader(`{"total":5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unsigned: status %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestSignResponses(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := SignResponses(NewEd25519MessageKey("quote", priv))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = WriteJSON(w, http.StatusCreated, map[string]int{"price": 42}, nil)
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+
+	verifier := NewEd25519VerifyKey("quote", pub)
+	if err := VerifyResponse(resp, body, time.Minute, verifier); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	resp.StatusCode = http.StatusOK
+	if err := VerifyResponse(resp, body, time.Minute, verifier); err == nil {
+		t.Error("expected an error when the status changes")
+	}
+
+	w = httptest.NewRecorder()
+	SignResponses(verifier)(h).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("verify only key: status %d, want 500", w.Code)
+	}
+}
+
+func TestLoadMessageKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDer, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	withSecrets(t, map[string]string{
+		"hmac-key":    "s3cret\n",
+		"signing-key": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"verify-key":  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer})),
+	})
+
+	for name, alg := range map[string]string{"hmac-key": "hmac-sha256", "signing-key": "ed25519", "verify-key": "ed25519"} {
+		k, err := LoadMessageKey(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if k.ID != name || k.Alg() != alg {
+			t.Errorf("%s: id %q alg %q", name, k.ID, k.Alg())
+		}
+	}
+	if _, err := LoadMessageKey("missing"); err == nil {
+		t.Error("expected an error for a missing secret")
+	}
+}
+
+func TestSplitDictionary(t *testing.T) {
+	got := splitDictionary(`sig1=("@method" "@path");created=1;keyid="a,b", sig2=:YWJj:`)
+	if got["sig1"] != `("@method" "@path");created=1;keyid="a,b"` || got["sig2"] != ":YWJj:" {
+		t.Errorf("got %v", got)
+	}
+}
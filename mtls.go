@@ -0,0 +1,150 @@
+package faas
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MTLSOptions names the OpenFaaS secrets holding PEM encoded credentials for
+// calling mTLS-only services.
+type MTLSOptions struct {
+	CertSecret string
+	KeySecret  string
+	// CASecret holds the CA bundle used to verify servers. When empty the
+	// system roots are used.
+	CASecret string
+	// ServerName overrides the name verified against server certificates.
+	ServerName string
+	// ReloadInterval is how often the secrets are re-read so rotated
+	// credentials are picked up without a restart. Defaults to one minute.
+	ReloadInterval time.Duration
+}
+
+// NewMTLSConfig returns a client *tls.Config presenting the certificate from
+// opts and verifying servers against its CA. Both are reloaded when the
+// secret files change.
+func NewMTLSConfig(opts MTLSOptions) (*tls.Config, error) {
+	if opts.CertSecret == "" || opts.KeySecret == "" {
+		return nil, errors.New("mtls cert and key secrets are required")
+	}
+	if opts.ReloadInterval <= 0 {
+		opts.ReloadInterval = time.Minute
+	}
+	c := &mtlsCredentials{opts: opts}
+	c.mu.Lock()
+	err := c.reload()
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := c.current()
+			return cert, nil
+		},
+	}
+	if opts.CASecret != "" {
+		// the CA pool cannot be swapped on a tls.Config, so verification is
+		// done against the current pool in VerifyConnection instead
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			_, pool := c.current()
+			return verifyServer(cs, pool)
+		}
+	}
+	return cfg, nil
+}
+
+// NewMTLSClient returns an instrumented http.Client using NewMTLSConfig.
+func NewMTLSClient(opts MTLSOptions, timeout time.Duration) (*http.Client, error) {
+	cfg, err := NewMTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	return &http.Client{Timeout: timeout, Transport: InstrumentedTransport{Base: transport}}, nil
+}
+
+func verifyServer(cs tls.ConnectionState, pool *x509.CertPool) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("mtls: server sent no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         pool,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+type mtlsCredentials struct {
+	opts MTLSOptions
+
+	mu      sync.Mutex
+	raw     [][]byte
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	checked time.Time
+}
+
+// current returns the credentials, reloading them once ReloadInterval has
+// passed. A failed reload keeps the previous credentials so a half written
+// rotation does not break calls.
+func (c *mtlsCredentials) current() (*tls.Certificate, *x509.CertPool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.checked) >= c.opts.ReloadInterval {
+		_ = c.reload()
+	}
+	return c.cert, c.pool
+}
+
+// reload reads the secrets and parses them if they changed. c.mu must be
+// held.
+func (c *mtlsCredentials) reload() error {
+	c.checked = time.Now()
+	var raw [][]byte
+	for _, name := range []string{c.opts.CertSecret, c.opts.KeySecret, c.opts.CASecret} {
+		if name == "" {
+			raw = append(raw, nil)
+			continue
+		}
+		byt, err := getSecret(name)
+		if err != nil {
+			return err
+		}
+		raw = append(raw, byt)
+	}
+	if c.cert != nil && slices.EqualFunc(raw, c.raw, bytes.Equal) {
+		return nil
+	}
+
+	cert, err := tls.X509KeyPair(raw[0], raw[1])
+	if err != nil {
+		return fmt.Errorf("mtls: invalid certificate or key: %w", err)
+	}
+	var pool *x509.CertPool
+	if raw[2] != nil {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(raw[2]) {
+			return errors.New("mtls: no certificates found in CA secret")
+		}
+	}
+	c.raw, c.cert, c.pool = raw, &cert, pool
+	return nil
+}
@@ -0,0 +1,130 @@
+package faas
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	pem    []byte
+	keyPEM []byte
+}
+
+// newTestCert issues a certificate signed by parent, or a self signed CA
+// when parent is nil.
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return &testCert{
+		cert:   cert,
+		key:    key,
+		pem:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func TestMTLSClient(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", ca)
+	client := newTestCert(t, "client-1", ca)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srvCert, _ := tls.X509KeyPair(server.pem, server.keyPEM)
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{srvCert}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	withSecrets(t, map[string]string{"tls.crt": string(client.pem), "tls.key": string(client.keyPEM), "ca.crt": string(ca.pem)})
+	c, err := NewMTLSClient(MTLSOptions{CertSecret: "tls.crt", KeySecret: "tls.key", CASecret: "ca.crt", ReloadInterval: time.Nanosecond}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() string {
+		t.Helper()
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byt, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		// force a new handshake on the next call
+		c.CloseIdleConnections()
+		return string(byt)
+	}
+	if got := get(); got != "client-1" {
+		t.Errorf("expected client-1, got %q", got)
+	}
+
+	// rotate the client certificate
+	rotated := newTestCert(t, "client-2", ca)
+	_ = os.WriteFile(filepath.Join(secretsPath, "tls.crt"), rotated.pem, 0o600)
+	_ = os.WriteFile(filepath.Join(secretsPath, "tls.key"), rotated.keyPEM, 0o600)
+	if got := get(); got != "client-2" {
+		t.Errorf("expected rotated client-2, got %q", got)
+	}
+}
+
+func TestMTLSRejectsUnknownServer(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	client := newTestCert(t, "client", ca)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	withSecrets(t, map[string]string{"tls.crt": string(client.pem), "tls.key": string(client.keyPEM), "ca.crt": string(ca.pem)})
+	c, err := NewMTLSClient(MTLSOptions{CertSecret: "tls.crt", KeySecret: "tls.key", CASecret: "ca.crt"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(srv.URL); err == nil {
+		t.Error("expected a server certificate from an unknown CA to be rejected")
+	}
+}
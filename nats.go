@@ -0,0 +1,228 @@
+package faas
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSMessage is a message delivered to a function by the OpenFaaS NATS
+// connector.
+type NATSMessage struct {
+	Topic string
+	Data  []byte
+}
+
+// Decode unmarshals the message data as JSON into dst.
+func (m NATSMessage) Decode(dst any) error {
+	if err := json.Unmarshal(m.Data, dst); err != nil {
+		return fmt.Errorf("topic %s: %w", m.Topic, triageJSONError(err, maxRecordBytes))
+	}
+	return nil
+}
+
+// ReadNATSMessage reads the topic from the X-Topic header set by the NATS
+// connector along with the published message body.
+func ReadNATSMessage(r *http.Request, opts ...BodyOptions) (NATSMessage, error) {
+	return readNATSMessage(r, opts...)
+}
+func readNATSMessage(r *http.Request, opts ...BodyOptions) (NATSMessage, error) {
+	topic := r.Header.Get("X-Topic")
+	if topic == "" {
+		return NATSMessage{}, errors.New("request is missing the X-Topic header")
+	}
+	data, err := ReadBody(nil, r, opts...)
+	if err != nil {
+		return NATSMessage{}, err
+	}
+	return NATSMessage{Topic: topic, Data: data}, nil
+}
+
+// NATSOptions configure a NATSPublisher. Only one of User/Password or Token
+// is normally set.
+type NATSOptions struct {
+	User     string
+	Password string
+	Token    string
+	// Timeout bounds connecting and each publish. Default 5s.
+	Timeout time.Duration
+}
+
+// NATSOptionsFromSecrets loads a user and password from OpenFaaS secrets.
+func NATSOptionsFromSecrets(userSecret, passSecret string) (NATSOptions, error) {
+	user, err := getSecretString(userSecret)
+	if err != nil {
+		return NATSOptions{}, err
+	}
+	pass, err := getSecretString(passSecret)
+	if err != nil {
+		return NATSOptions{}, err
+	}
+	return NATSOptions{User: user, Password: pass}, nil
+}
+
+// NATSPublisher is a minimal NATS client for publishing follow-up messages.
+// Each publish waits for the server to acknowledge it with a PONG so errors
+// are reported to the caller. It is safe for concurrent use.
+type NATSPublisher struct {
+	addr string
+	opts NATSOptions
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewNATSPublisher returns a publisher for the server at addr, which may be
+// a nats:// URL or host:port. The connection is opened on first use.
+func NewNATSPublisher(addr string, opts NATSOptions) *NATSPublisher {
+	if u, err := url.Parse(addr); err == nil && u.Scheme == "nats" {
+		addr = u.Host
+		if u.User != nil && opts.User == "" && opts.Token == "" {
+			opts.User = u.User.Username()
+			opts.Password, _ = u.User.Password()
+		}
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	return &NATSPublisher{addr: addr, opts: opts}
+}
+
+// Publish sends data to subject.
+func (p *NATSPublisher) Publish(ctx context.Context, subject string, data []byte) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("invalid subject %q", subject)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	reused := p.conn != nil
+	err := p.publish(ctx, subject, data)
+	if err != nil && reused {
+		// the connection may have gone stale between invocations, so retry
+		// once on a fresh one
+		p.closeConn()
+		err = p.publish(ctx, subject, data)
+	}
+	if err != nil {
+		p.closeConn()
+	}
+	return err
+}
+
+// PublishJSON marshals v and publishes it to subject.
+func (p *NATSPublisher) PublishJSON(ctx context.Context, subject string, v any) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return p.Publish(ctx, subject, js)
+}
+
+// Close closes the underlying connection.
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closeConn()
+}
+
+func (p *NATSPublisher) closeConn() error {
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn, p.rd = nil, nil
+	return err
+}
+
+func (p *NATSPublisher) publish(ctx context.Context, subject string, data []byte) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+	p.setDeadline(ctx)
+	if _, err := fmt.Fprintf(p.conn, "PUB %s %d\r\n%s\r\nPING\r\n", subject, len(data), data); err != nil {
+		return err
+	}
+	return p.waitPong()
+}
+
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: p.opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	p.conn, p.rd = conn, bufio.NewReader(conn)
+	p.setDeadline(ctx)
+
+	line, err := p.rd.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected nats greeting %q", strings.TrimSpace(line))
+	}
+
+	opts := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "go-faas",
+		"lang":     "go",
+	}
+	if p.opts.User != "" {
+		opts["user"], opts["pass"] = p.opts.User, p.opts.Password
+	}
+	if p.opts.Token != "" {
+		opts["auth_token"] = p.opts.Token
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(p.conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return err
+	}
+	return p.waitPong()
+}
+
+func (p *NATSPublisher) setDeadline(ctx context.Context) {
+	deadline := time.Now().Add(p.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = p.conn.SetDeadline(deadline)
+}
+
+func (p *NATSPublisher) waitPong() error {
+	for {
+		line, err := p.rd.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := io.WriteString(p.conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.Trim(strings.TrimPrefix(line, "-ERR"), " '"))
+		}
+		// +OK and INFO updates are ignored
+	}
+}
@@ -0,0 +1,117 @@
+package faas
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadNATSMessage(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":1}`))
+	req.Header.Set("X-Topic", "orders.created")
+
+	msg, err := readNATSMessage(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		ID int `json:"id"`
+	}
+	if err := msg.Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Topic != "orders.created" || body.ID != 1 {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	if _, err := readNATSMessage(req); err == nil {
+		t.Error("expected an error for a missing topic")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":1}`))
+	req.Header.Set("X-Topic", "orders.created")
+	var appErr *AppError
+	if _, err := ReadNATSMessage(req, BodyOptions{MaxBytes: 4}); !errors.As(err, &appErr) || appErr.Code != CodeTooLarge {
+		t.Errorf("expected CodeTooLarge over the configured limit, got %v", err)
+	}
+}
+
+// fakeNATS accepts a single connection and records the published messages.
+func fakeNATS(t *testing.T, published chan<- string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		rd := bufio.NewReader(conn)
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "CONNECT"):
+				if !strings.Contains(line, `"user":"fn"`) {
+					fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+					return
+				}
+			case strings.HasPrefix(line, "PUB"):
+				var subject string
+				var size int
+				fmt.Sscanf(line, "PUB %s %d", &subject, &size)
+				payload := make([]byte, size+2)
+				_, _ = rd.Read(payload)
+				published <- subject + " " + string(payload[:size])
+			case strings.HasPrefix(line, "PING"):
+				fmt.Fprint(conn, "PONG\r\n")
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestNATSPublisher(t *testing.T) {
+	published := make(chan string, 1)
+	addr := fakeNATS(t, published)
+
+	pub := NewNATSPublisher("nats://fn:pw@"+addr, NATSOptions{})
+	defer pub.Close()
+
+	if err := pub.PublishJSON(context.Background(), "orders.shipped", Map{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-published; got != `orders.shipped {"id":1}` {
+		t.Errorf("unexpected publish %q", got)
+	}
+	if err := pub.Publish(context.Background(), "bad subject", nil); err == nil {
+		t.Error("expected an error for an invalid subject")
+	}
+}
+
+func TestNATSPublisherAuthError(t *testing.T) {
+	addr := fakeNATS(t, make(chan string, 1))
+
+	pub := NewNATSPublisher(addr, NATSOptions{User: "other"})
+	defer pub.Close()
+
+	err := pub.Publish(context.Background(), "orders", []byte("x"))
+	if err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Fatalf("expected authorization error, got %v", err)
+	}
+}
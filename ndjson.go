@@ -0,0 +1,145 @@
+package faas
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxRecordBytes is the largest single element DecodeStream will accept.
+const maxRecordBytes = 1_048_576 // 1MB
+
+// ReadNDJSON streams newline-delimited JSON records from the request body,
+// decoding each into a T and passing it to fn. Blank lines are skipped.
+// Records are limited to 1MB, or the size set by the MAX_BODY_BYTES
+// environment variable, unless opts sets another limit; the body as a whole
+// is not. Processing stops at the first decode error or error returned by
+// fn, and the returned error includes the line number of the offending
+// record.
+func ReadNDJSON[T any](r *http.Request, fn func(T) error, opts ...BodyOptions) error {
+	return readNDJSON(r, fn, opts...)
+}
+func readNDJSON[T any](r *http.Request, fn func(T) error, opts ...BodyOptions) error {
+	maxBytes := int(bodyLimit(opts))
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, min(64*1024, maxBytes)), maxBytes)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		record := bytes.TrimSpace(scanner.Bytes())
+		if len(record) == 0 {
+			continue
+		}
+
+		var v T
+		dec := jsonCodec().NewDecoder(bytes.NewReader(record), true)
+		if err := dec.Decode(&v); err != nil {
+			return fmt.Errorf("line %d: %w", line, triageJSONError(err, maxBytes))
+		}
+		if err := dec.Decode(&struct{}{}); err != io.EOF {
+			return fmt.Errorf("line %d: record must only contain a single JSON value", line)
+		}
+		if err := fn(v); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("line %d: record must not be larger than %d bytes", line+1, maxBytes)
+		}
+		return err
+	}
+	return nil
+}
+
+// errElementTooLarge is returned by elementLimitReader once the current
+// element exceeds maxRecordBytes.
+var errElementTooLarge = errors.New("element too large")
+
+// elementLimitReader caps the bytes read while decoding a single array
+// element, so one huge element cannot exhaust memory.
+type elementLimitReader struct {
+	r io.Reader
+	n int
+}
+
+// reset allows the next element maxRecordBytes, less what dec has
+// already buffered of it.
+func (l *elementLimitReader) reset(dec *json.Decoder) {
+	l.n = maxRecordBytes
+	if b, ok := dec.Buffered().(interface{ Len() int }); ok {
+		l.n -= b.Len()
+	}
+}
+
+func (l *elementLimitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errElementTooLarge
+	}
+	if len(p) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= n
+	return n, err
+}
+
+// DecodeStream decodes a top-level JSON array read from r element by
+// element, passing each to fn, so arrays far larger than the ReadJSON limit
+// are processed in bounded memory. Each element, which must not be larger
+// than 1MB, is decoded into a T with unknown fields rejected. Processing
+// stops at the first decode error or error returned by fn, and the
+// returned error includes the index of the offending element.
+//
+//	err := faas.DecodeStream(r.Body, func(p Product) error {
+//		return batch.Add(ctx, p)
+//	})
+func DecodeStream[T any](r io.Reader, fn func(T) error) error {
+	lr := &elementLimitReader{r: r, n: maxRecordBytes}
+	// the array is tokenized by encoding/json, as Codec has no tokens, and
+	// each element is decoded by the codec set with SetCodec
+	dec := json.NewDecoder(lr)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return triageStreamError(err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return errors.New("body must be a JSON array")
+	}
+	for i := 0; dec.More(); i++ {
+		lr.reset(dec)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("element %d: %w", i, triageStreamError(err))
+		}
+		var v T
+		if err := jsonCodec().NewDecoder(bytes.NewReader(raw), true).Decode(&v); err != nil {
+			return fmt.Errorf("element %d: %w", i, triageStreamError(err))
+		}
+		if err := fn(v); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	lr.reset(dec)
+	if _, err := dec.Token(); err != nil {
+		return triageStreamError(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("body must only contain a single JSON array")
+	}
+	return nil
+}
+
+func triageStreamError(err error) error {
+	if errors.Is(err, errElementTooLarge) {
+		return fmt.Errorf("element must not be larger than %d bytes", maxRecordBytes)
+	}
+	return triageJSONError(err, maxRecordBytes)
+}
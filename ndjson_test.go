@@ -0,0 +1,178 @@
+package faas
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadNDJSON(t *testing.T) {
+	type record struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		count    int
+		expected string
+	}{
+		{
+			name:  "valid records with blank lines",
+			body:  "{\"name\":\"a\"}\n\n{\"name\":\"b\"}\r\n",
+			count: 2,
+		},
+		{
+			name:     "bad record",
+			body:     "{\"name\":\"a\"}\n{\"name\":1}\n",
+			count:    1,
+			expected: `line 2: body contains incorrect JSON type for field "name"`,
+		},
+		{
+			name:     "unknown field",
+			body:     "{\"other\":\"a\"}\n",
+			expected: `line 1: body contains unknown key "other"`,
+		},
+		{
+			name:     "record too large",
+			body:     "{\"name\":\"" + strings.Repeat("a", maxRecordBytes) + "\"}\n",
+			expected: "line 1: record must not be larger than 1048576 bytes",
+		},
+		{
+			name:     "callback error",
+			body:     "{\"name\":\"stop\"}\n",
+			expected: "line 1: stop requested",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			count := 0
+			err := readNDJSON(req, func(r record) error {
+				if r.Name == "stop" {
+					return errors.New("stop requested")
+				}
+				count++
+				return nil
+			})
+
+			if tc.expected == "" && err != nil {
+				t.Fatalf("didn't expect an error but got one: %v", err)
+			}
+			if tc.expected != "" && (err == nil || err.Error() != tc.expected) {
+				t.Fatalf("expected error %q, got %v", tc.expected, err)
+			}
+			if count != tc.count {
+				t.Errorf("expected %d records, got %d", tc.count, count)
+			}
+		})
+	}
+}
+
+func TestReadNDJSONLimit(t *testing.T) {
+	body := "{\"name\":\"" + strings.Repeat("a", 100) + "\"}\n"
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	err := ReadNDJSON(req, func(struct{ Name string }) error { return nil }, BodyOptions{MaxBytes: 64})
+	if err == nil || err.Error() != "line 1: record must not be larger than 64 bytes" {
+		t.Errorf("err = %v, want the record rejected over the 64 byte limit", err)
+	}
+
+	big := "{\"name\":\"" + strings.Repeat("a", 1_500_000) + "\"}\n"
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(big))
+	if err := ReadNDJSON(req, func(struct{ Name string }) error { return nil }, BodyOptions{MaxBytes: 2 << 20}); err != nil {
+		t.Errorf("err = %v, want records past 1MB accepted with a 2MB limit", err)
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	type record struct {
+		Name string `json:"name"`
+	}
+	large := "[" + strings.Repeat(`{"name":"aaaaaaaaaaaaaaaaaaaa"},`, 100_000) + `{"name":"last"}]`
+
+	tests := []struct {
+		name     string
+		body     string
+		count    int
+		expected string
+	}{
+		{
+			name:  "valid array",
+			body:  ` [{"name":"a"}, {"name":"b"}] `,
+			count: 2,
+		},
+		{
+			name:  "empty array",
+			body:  "[]",
+			count: 0,
+		},
+		{
+			name:  "larger than the ReadJSON limit",
+			body:  large,
+			count: 100_001,
+		},
+		{
+			name:     "not an array",
+			body:     `{"name":"a"}`,
+			expected: "body must be a JSON array",
+		},
+		{
+			name:     "empty body",
+			body:     "",
+			expected: "body must not be empty",
+		},
+		{
+			name:     "bad element",
+			body:     `[{"name":"a"},{"name":1}]`,
+			count:    1,
+			expected: `element 1: body contains incorrect JSON type for field "name"`,
+		},
+		{
+			name:     "unknown field",
+			body:     `[{"other":"a"}]`,
+			expected: `element 0: body contains unknown key "other"`,
+		},
+		{
+			name:     "element too large",
+			body:     `[{"name":"` + strings.Repeat("a", maxRecordBytes) + `"}]`,
+			expected: "element 0: element must not be larger than 1048576 bytes",
+		},
+		{
+			name:     "trailing data",
+			body:     `[{"name":"a"}] []`,
+			count:    1,
+			expected: "body must only contain a single JSON array",
+		},
+		{
+			name:     "callback error",
+			body:     `[{"name":"stop"}]`,
+			expected: "element 0: stop requested",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			count := 0
+			err := DecodeStream(strings.NewReader(tc.body), func(r record) error {
+				if r.Name == "stop" {
+					return errors.New("stop requested")
+				}
+				count++
+				return nil
+			})
+
+			if tc.expected == "" && err != nil {
+				t.Fatalf("didn't expect an error but got one: %v", err)
+			}
+			if tc.expected != "" && (err == nil || err.Error() != tc.expected) {
+				t.Fatalf("expected error %q, got %v", tc.expected, err)
+			}
+			if count != tc.count {
+				t.Errorf("expected %d records, got %d", tc.count, count)
+			}
+		})
+	}
+}
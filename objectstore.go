@@ -0,0 +1,238 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+// ObjectStore is the minimal object storage API needed by ObjectProcessor.
+// It is small enough to wrap the AWS, MinIO or GCS SDKs.
+type ObjectStore interface {
+	Get(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+	Put(ctx context.Context, bucket, key string, r io.Reader, contentType string) error
+}
+
+// S3Event is the bucket notification body sent by S3 and MinIO.
+type S3Event struct {
+	Records []S3EventRecord `json:"Records"`
+}
+
+// S3EventRecord is a single record in an S3Event.
+type S3EventRecord struct {
+	EventName string    `json:"eventName"`
+	EventTime time.Time `json:"eventTime"`
+	S3        struct {
+		Bucket struct {
+			Name string `json:"name"`
+		} `json:"bucket"`
+		Object struct {
+			Key  string `json:"key"`
+			Size int64  `json:"size"`
+			ETag string `json:"eTag"`
+		} `json:"object"`
+	} `json:"s3"`
+}
+
+// ObjectRef identifies an object in a bucket.
+type ObjectRef struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// Objects returns the objects referenced by the event. Keys are URL decoded
+// as S3 encodes them in notifications.
+func (e S3Event) Objects() ([]ObjectRef, error) {
+	refs := make([]ObjectRef, 0, len(e.Records))
+	for _, rec := range e.Records {
+		key, err := url.QueryUnescape(rec.S3.Object.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object key %q: %w", rec.S3.Object.Key, err)
+		}
+		refs = append(refs, ObjectRef{Bucket: rec.S3.Bucket.Name, Key: key, Size: rec.S3.Object.Size})
+	}
+	return refs, nil
+}
+
+// ObjectProcessor fetches the objects named in an S3Event, streams each one
+// through Process and writes the output to DestBucket under DestPrefix.
+type ObjectProcessor struct {
+	Store ObjectStore
+	// Process reads the source object from src and writes the result to dst.
+	Process func(ctx context.Context, obj ObjectRef, src io.Reader, dst io.Writer) error
+	// DestBucket defaults to the source bucket.
+	DestBucket string
+	// DestPrefix is prepended to the source key. It must be set when
+	// DestBucket is the source bucket, otherwise results would trigger new
+	// events for themselves.
+	DestPrefix string
+	// ContentType of the written results. Defaults to application/octet-stream.
+	ContentType string
+	// Retries is the number of extra attempts made for a failing object.
+	Retries int
+	// Backoff is the delay before the first retry, doubled on each attempt.
+	Backoff time.Duration
+	// DeadLetter is called with objects that still fail after all retries.
+	// If it is nil or returns an error the event is reported as failed.
+	DeadLetter func(ctx context.Context, obj ObjectRef, err error) error
+	// Body limits the event notifications read by ServeHTTP, as for
+	// ReadBody.
+	Body BodyOptions
+}
+
+// ObjectResult is the outcome for one object, as written by ServeHTTP.
+type ObjectResult struct {
+	ObjectRef
+	Output       string `json:"output,omitempty"`
+	Attempts     int    `json:"attempts"`
+	Error        string `json:"error,omitempty"`
+	DeadLettered bool   `json:"dead_lettered,omitempty"`
+}
+
+// ServeHTTP decodes an S3Event from the request body, processes it and
+// writes the per-object results. A 500 is returned when any object could not
+// be processed or dead-lettered so the event source can redeliver.
+func (p *ObjectProcessor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var evt S3Event
+	// notifications carry many fields we don't model, so unknown fields are
+	// deliberately allowed here
+	limit := bodyLimit([]BodyOptions{p.Body})
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit)).Decode(&evt); err != nil {
+		_ = writeJSONError(w, Error{
+			Status: http.StatusText(http.StatusBadRequest),
+			Reason: triageJSONError(err, int(limit)).Error(),
+			Code:   http.StatusBadRequest,
+		})
+		return
+	}
+
+	results, err := p.Handle(r.Context(), evt)
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusInternalServerError
+	}
+	_ = writeJSON(w, status, Map{"results": results}, nil)
+}
+
+// Handle processes every object in evt. The returned error is non-nil if any
+// object failed and was not dead-lettered.
+func (p *ObjectProcessor) Handle(ctx context.Context, evt S3Event) ([]ObjectResult, error) {
+	objects, err := evt.Objects()
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	results := make([]ObjectResult, 0, len(objects))
+	for _, obj := range objects {
+		if p.DestPrefix == "" && (p.DestBucket == "" || p.DestBucket == obj.Bucket) {
+			// writing over the source would trigger a new event for each result
+			err := errors.New("DestPrefix must be set when writing to the source bucket")
+			results = append(results, ObjectResult{ObjectRef: obj, Error: err.Error()})
+			errs = append(errs, fmt.Errorf("%s/%s: %w", obj.Bucket, obj.Key, err))
+			continue
+		}
+		res := p.processWithRetry(ctx, obj)
+		if res.Error != "" && !res.DeadLettered {
+			errs = append(errs, fmt.Errorf("%s/%s: %s", obj.Bucket, obj.Key, res.Error))
+		}
+		results = append(results, res)
+	}
+	return results, errors.Join(errs...)
+}
+
+func (p *ObjectProcessor) processWithRetry(ctx context.Context, obj ObjectRef) ObjectResult {
+	res := ObjectResult{ObjectRef: obj}
+	backoff := p.Backoff
+
+	var err error
+	for attempt := 0; attempt <= p.Retries; attempt++ {
+		res.Attempts++
+		res.Output, err = p.processObject(ctx, obj)
+		if err == nil {
+			return res
+		}
+		slog.Warn("object processing failed", "bucket", obj.Bucket, "key", obj.Key,
+			"attempt", res.Attempts, "error", err)
+		if attempt == p.Retries {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			err = ctx.Err()
+			attempt = p.Retries
+		}
+	}
+
+	res.Output = ""
+	res.Error = err.Error()
+	if p.DeadLetter != nil {
+		if dlqErr := p.DeadLetter(ctx, obj, err); dlqErr != nil {
+			res.Error = errors.Join(err, fmt.Errorf("dead letter: %w", dlqErr)).Error()
+		} else {
+			res.DeadLettered = true
+		}
+	}
+	return res
+}
+
+// errUploadFinished is returned to a processor still writing when Put has
+// returned.
+var errUploadFinished = errors.New("upload finished")
+
+func (p *ObjectProcessor) processObject(ctx context.Context, obj ObjectRef) (string, error) {
+	src, err := p.Store.Get(ctx, obj.Bucket, obj.Key)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	bucket := p.DestBucket
+	if bucket == "" {
+		bucket = obj.Bucket
+	}
+	key := path.Join(p.DestPrefix, obj.Key)
+	contentType := p.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// stream the processor output straight into the upload so large objects
+	// are never held in memory
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := mapItem(ctx, obj, func(ctx context.Context, obj ObjectRef) (struct{}, error) {
+			return struct{}{}, p.Process(ctx, obj, src, pw)
+		})
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	putErr := p.Store.Put(ctx, bucket, key, pr, contentType)
+	// unblock the processor if Put returned without consuming everything
+	pr.CloseWithError(errUploadFinished)
+	procErr := <-done
+	if putErr != nil {
+		// the processor then usually fails writing to the closed pipe, which
+		// is only a symptom
+		if procErr != nil && !errors.Is(procErr, errUploadFinished) {
+			return "", errors.Join(putErr, procErr)
+		}
+		return "", putErr
+	}
+	if procErr != nil {
+		return "", procErr
+	}
+	return bucket + "/" + key, nil
+}
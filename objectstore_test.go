@@ -0,0 +1,125 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memoryObjectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *memoryObjectStore) Get(_ context.Context, bucket, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	byt, ok := m.objects[bucket+"/"+key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return io.NopCloser(bytes.NewReader(byt)), nil
+}
+
+func (m *memoryObjectStore) Put(_ context.Context, bucket, key string, r io.Reader, _ string) error {
+	byt, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[bucket+"/"+key] = byt
+	return nil
+}
+
+func TestObjectProcessor(t *testing.T) {
+	store := &memoryObjectStore{objects: map[string][]byte{
+		"in/hello world.txt": []byte("hello"),
+		"in/bad.txt":         []byte("bad"),
+	}}
+
+	var deadLettered []string
+	p := &ObjectProcessor{
+		Store:      store,
+		DestPrefix: "processed",
+		Retries:    2,
+		Process: func(_ context.Context, obj ObjectRef, src io.Reader, dst io.Writer) error {
+			byt, _ := io.ReadAll(src)
+			if string(byt) == "bad" {
+				return errors.New("cannot process")
+			}
+			_, err := dst.Write(bytes.ToUpper(byt))
+			return err
+		},
+		DeadLetter: func(_ context.Context, obj ObjectRef, _ error) error {
+			deadLettered = append(deadLettered, obj.Key)
+			return nil
+		},
+	}
+
+	body := `{"Records":[
+		{"eventName":"s3:ObjectCreated:Put","s3":{"bucket":{"name":"in"},"object":{"key":"hello+world.txt","size":5}}},
+		{"eventName":"s3:ObjectCreated:Put","s3":{"bucket":{"name":"in"},"object":{"key":"bad.txt","size":3}}}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	resp := httptest.NewRecorder()
+	p.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if got := string(store.objects["in/processed/hello world.txt"]); got != "HELLO" {
+		t.Errorf("expected processed output HELLO, got %q", got)
+	}
+	if len(deadLettered) != 1 || deadLettered[0] != "bad.txt" {
+		t.Errorf("expected bad.txt to be dead-lettered, got %v", deadLettered)
+	}
+
+	p.DeadLetter = nil
+	results, err := p.Handle(context.Background(), S3Event{Records: []S3EventRecord{{}}})
+	if err == nil || results[0].Attempts != 3 {
+		t.Errorf("expected failure after 3 attempts, got %+v and %v", results, err)
+	}
+}
+
+type failingPutStore struct{ memoryObjectStore }
+
+func (s *failingPutStore) Put(_ context.Context, _, _ string, r io.Reader, _ string) error {
+	_, _ = io.ReadAll(io.LimitReader(r, 1))
+	return errors.New("bucket is read only")
+}
+
+func TestObjectProcessorErrors(t *testing.T) {
+	store := &failingPutStore{memoryObjectStore{objects: map[string][]byte{"in/a.txt": []byte("hello")}}}
+	p := &ObjectProcessor{
+		Store:      store,
+		DestPrefix: "out",
+		Process: func(_ context.Context, _ ObjectRef, src io.Reader, dst io.Writer) error {
+			_, err := io.Copy(dst, src)
+			return err
+		},
+	}
+	evt := S3Event{Records: []S3EventRecord{{}}}
+	evt.Records[0].S3.Bucket.Name = "in"
+	evt.Records[0].S3.Object.Key = "a.txt"
+	results, err := p.Handle(context.Background(), evt)
+	if err == nil || !strings.Contains(results[0].Error, "bucket is read only") {
+		t.Errorf("failed put: results %+v, err %v", results, err)
+	}
+
+	p.Store, p.DestPrefix = &memoryObjectStore{objects: map[string][]byte{"in/a.txt": []byte("hello")}}, ""
+	results, err = p.Handle(context.Background(), evt)
+	if err == nil || results[0].Attempts != 0 {
+		t.Errorf("same bucket without a prefix: results %+v, err %v", results, err)
+	}
+	p.DestBucket = "out"
+	if _, err := p.Handle(context.Background(), evt); err != nil {
+		t.Errorf("other bucket without a prefix: %v", err)
+	}
+}
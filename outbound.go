@@ -0,0 +1,181 @@
+package faas
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultOutboundMetrics is used by InstrumentedTransport when no Metrics
+// are set.
+var DefaultOutboundMetrics = NewOutboundMetrics()
+
+// HostStats are the outbound call metrics for a single upstream host.
+type HostStats struct {
+	Host     string           `json:"host"`
+	Requests int64            `json:"requests"`
+	Errors   int64            `json:"errors"`
+	Statuses map[string]int64 `json:"statuses"`
+	Mean     Duration         `json:"mean"`
+	Max      Duration         `json:"max"`
+}
+
+// OutboundMetrics aggregates latency and errors of outbound calls per host.
+// It is safe for concurrent use.
+type OutboundMetrics struct {
+	mu    sync.Mutex
+	hosts map[string]*hostMetrics
+}
+
+type hostMetrics struct {
+	requests int64
+	errors   int64
+	statuses map[string]int64
+	total    time.Duration
+	max      time.Duration
+}
+
+// NewOutboundMetrics returns empty metrics.
+func NewOutboundMetrics() *OutboundMetrics {
+	return &OutboundMetrics{hosts: make(map[string]*hostMetrics)}
+}
+
+// Record adds a call to host. A zero status means the call failed before a
+// response was received. Transport errors and 5xx responses count as
+// errors.
+func (m *OutboundMetrics) Record(host string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.hosts[host]
+	if !ok {
+		h = &hostMetrics{statuses: make(map[string]int64)}
+		m.hosts[host] = h
+	}
+	h.requests++
+	h.total += d
+	h.max = max(h.max, d)
+	if status == 0 || status >= 500 {
+		h.errors++
+	}
+	class := "error"
+	if status > 0 {
+		class = fmt.Sprintf("%dxx", status/100)
+	}
+	h.statuses[class]++
+}
+
+// Snapshot returns the current metrics for every host, sorted by host.
+func (m *OutboundMetrics) Snapshot() []HostStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]HostStats, 0, len(m.hosts))
+	for host, h := range m.hosts {
+		s := HostStats{Host: host, Requests: h.requests, Errors: h.errors, Max: Duration(h.max), Statuses: make(map[string]int64)}
+		if h.requests > 0 {
+			s.Mean = Duration(h.total / time.Duration(h.requests))
+		}
+		for k, v := range h.statuses {
+			s.Statuses[k] = v
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Host < stats[j].Host })
+	return stats
+}
+
+// ServeHTTP writes the snapshot as JSON.
+func (m *OutboundMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	_ = writeJSON(w, http.StatusOK, Map{"hosts": m.Snapshot()}, nil)
+}
+
+// InstrumentedTransport records per host metrics for every outbound call,
+// adds it to the request Timings and runs it in a child span of the trace
+// in the request context, see Tracing. The child span is propagated to the
+// upstream in a traceparent header and logged at debug level when the call
+// completes. The priority stored by RequestPriority is sent in a Priority
+// header unless the request sets one. A nil Base uses http.DefaultTransport.
+type InstrumentedTransport struct {
+	Base    http.RoundTripper
+	Metrics *OutboundMetrics
+}
+
+// NewInstrumentedClient returns an http.Client using InstrumentedTransport.
+func NewInstrumentedClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: InstrumentedTransport{}}
+}
+
+// CloseIdleConnections closes idle connections of Base, see
+// http.Client.CloseIdleConnections.
+func (t InstrumentedTransport) CloseIdleConnections() {
+	closeIdleConnections(t.Base)
+}
+
+func closeIdleConnections(rt http.RoundTripper) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if c, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t InstrumentedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	metrics := t.Metrics
+	if metrics == nil {
+		metrics = DefaultOutboundMetrics
+	}
+
+	ctx := r.Context()
+	parent, traced := ctx.Value(traceKey{}).(traceContext)
+	if traced {
+		span := traceContext{traceID: parent.traceID, spanID: randomHex(8), sampled: parent.sampled}
+		ctx = context.WithValue(ctx, traceKey{}, span)
+		flags := "00"
+		if span.sampled {
+			flags = "01"
+		}
+		// RoundTrippers must not modify the caller's request
+		r = r.Clone(ctx)
+		r.Header.Set("traceparent", "00-"+span.traceID+"-"+span.spanID+"-"+flags)
+	}
+	if p, ok := FromContext(ctx, PriorityKey); ok && r.Header.Get("Priority") == "" {
+		if v := p.String(); v != "" {
+			if !traced {
+				r = r.Clone(ctx)
+			}
+			r.Header.Set("Priority", v)
+		}
+	}
+
+	stop := StartTiming(ctx, "outbound:"+r.URL.Host)
+	start := time.Now()
+	resp, err := base.RoundTrip(r)
+	elapsed := time.Since(start)
+	stop()
+
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	metrics.Record(r.URL.Host, status, elapsed)
+
+	attrs := []any{"method", r.Method, "host", r.URL.Host, "path", r.URL.Path, "status", status, "duration", elapsed}
+	if traced {
+		attrs = append(attrs, "parent_span", parent.spanID)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "outbound request failed", append(attrs, "error", err)...)
+	} else {
+		slog.DebugContext(ctx, "outbound request", attrs...)
+	}
+	return resp, err
+}
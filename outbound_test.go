@@ -0,0 +1,63 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrumentedTransport(t *testing.T) {
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer upstream.Close()
+
+	metrics := NewOutboundMetrics()
+	client := &http.Client{Transport: InstrumentedTransport{Metrics: metrics}}
+	h := Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range []string{"/ok", "/fail"} {
+			req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL+path, nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || parts[1] != "4bf92f3577b34da6a3ce929d0e0e4736" || parts[2] == "00f067aa0ba902b7" || parts[3] != "01" {
+		t.Errorf("expected a child span of the incoming trace, got %q", traceparent)
+	}
+
+	stats := metrics.Snapshot()
+	if len(stats) != 1 {
+		t.Fatalf("expected stats for one host, got %+v", stats)
+	}
+	s := stats[0]
+	if s.Requests != 2 || s.Errors != 1 || s.Statuses["2xx"] != 1 || s.Statuses["5xx"] != 1 || s.Max < s.Mean {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestInstrumentedTransportUntraced(t *testing.T) {
+	metrics := NewOutboundMetrics()
+	client := &http.Client{Transport: InstrumentedTransport{Metrics: metrics}}
+	if _, err := client.Get("http://127.0.0.1:1/unreachable"); err == nil {
+		t.Fatal("expected connection error")
+	}
+	stats := metrics.Snapshot()
+	if len(stats) != 1 || stats[0].Errors != 1 || stats[0].Statuses["error"] != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
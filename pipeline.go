@@ -0,0 +1,257 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorPolicy controls how a Pipeline reacts to a failing item.
+type ErrorPolicy int
+
+const (
+	// FailFast cancels the whole pipeline on the first error.
+	FailFast ErrorPolicy = iota
+	// CollectErrors drops failing items, records the error and keeps going.
+	CollectErrors
+)
+
+// Pipeline runs items from a source through typed stages into a sink. Stages
+// are connected by bounded channels, so a slow stage applies backpressure to
+// everything upstream of it.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	policy ErrorPolicy
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	errs   []error
+	stages []*stageMetrics
+}
+
+// StageStats is a snapshot of the metrics recorded for a single stage.
+type StageStats struct {
+	Name   string
+	In     uint64
+	Out    uint64
+	Errors uint64
+	// Busy is the total time spent inside the stage function across all
+	// workers.
+	Busy time.Duration
+}
+
+type stageMetrics struct {
+	name   string
+	in     atomic.Uint64
+	out    atomic.Uint64
+	errors atomic.Uint64
+	busy   atomic.Int64
+}
+
+// Stage is a named step which turns an In into an Out. Concurrency is the
+// number of workers running Fn and Buffer is the capacity of the channel
+// feeding the next stage.
+//
+// When Key is set, items sharing a key are always handled by the same worker
+// so they are processed one at a time in the order they arrived, while items
+// with different keys are spread over the workers. This suits streams where
+// events for an account or aggregate must be applied in sequence. Keys are
+// hashed onto workers, so a slow item also delays other keys on its worker.
+type Stage[In, Out any] struct {
+	Name        string
+	Concurrency int
+	Buffer      int
+	Fn          func(context.Context, In) (Out, error)
+	Key         func(In) string
+}
+
+// Stream is the typed output of a pipeline source or stage.
+type Stream[T any] struct {
+	p  *Pipeline
+	ch <-chan T
+}
+
+// NewPipeline returns a Pipeline bound to ctx.
+func NewPipeline(ctx context.Context, policy ErrorPolicy) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel, policy: policy}
+}
+
+// Stats returns the metrics of every stage in the order they were added.
+func (p *Pipeline) Stats() []StageStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]StageStats, 0, len(p.stages))
+	for _, m := range p.stages {
+		stats = append(stats, StageStats{
+			Name:   m.name,
+			In:     m.in.Load(),
+			Out:    m.out.Load(),
+			Errors: m.errors.Load(),
+			Busy:   time.Duration(m.busy.Load()),
+		})
+	}
+	return stats
+}
+
+func (p *Pipeline) addStage(name string) *stageMetrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m := &stageMetrics{name: name}
+	p.stages = append(p.stages, m)
+	return m
+}
+
+func (p *Pipeline) fail(stage string, err error) {
+	p.mu.Lock()
+	p.errs = append(p.errs, fmt.Errorf("%s: %w", stage, err))
+	p.mu.Unlock()
+	if p.policy == FailFast {
+		p.cancel()
+	}
+}
+
+// From starts a pipeline by reading items from it.
+func From[T any](p *Pipeline, it Iterator[T], buffer int) *Stream[T] {
+	out := make(chan T, buffer)
+	m := p.addStage("source")
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(out)
+		for it.Next() {
+			select {
+			case out <- it.Value():
+				m.out.Add(1)
+			case <-p.ctx.Done():
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			m.errors.Add(1)
+			p.fail("source", err)
+		}
+	}()
+	return &Stream[T]{p: p, ch: out}
+}
+
+// Through connects stage to the end of s and returns its output stream.
+func Through[In, Out any](s *Stream[In], stage Stage[In, Out]) *Stream[Out] {
+	p := s.p
+	concurrency := stage.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	out := make(chan Out, stage.Buffer)
+	m := p.addStage(stage.Name)
+
+	inputs := make([]<-chan In, concurrency)
+	for i := range inputs {
+		inputs[i] = s.ch
+	}
+	if stage.Key != nil {
+		inputs = partition(p, s.ch, concurrency, stage.Key)
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		in := inputs[i]
+		go func() {
+			defer workers.Done()
+			for item := range in {
+				m.in.Add(1)
+				start := time.Now()
+				res, err := mapItem(p.ctx, item, stage.Fn)
+				m.busy.Add(int64(time.Since(start)))
+				if err != nil {
+					m.errors.Add(1)
+					p.fail(stage.Name, err)
+					continue
+				}
+				select {
+				case out <- res:
+					m.out.Add(1)
+				case <-p.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		workers.Wait()
+		close(out)
+	}()
+	return &Stream[Out]{p: p, ch: out}
+}
+
+// partition routes the items of in onto n channels by the hash of their key.
+func partition[T any](p *Pipeline, in <-chan T, n int, key func(T) string) []<-chan T {
+	shards := make([]chan T, n)
+	outs := make([]<-chan T, n)
+	for i := range shards {
+		shards[i] = make(chan T)
+		outs[i] = shards[i]
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() {
+			for _, ch := range shards {
+				close(ch)
+			}
+		}()
+		for item := range in {
+			h := fnv.New32a()
+			h.Write([]byte(key(item)))
+			select {
+			case shards[h.Sum32()%uint32(n)] <- item:
+			case <-p.ctx.Done():
+				// keep draining so upstream stages are not blocked forever
+			}
+		}
+	}()
+	return outs
+}
+
+// Drain consumes the stream with fn, waits for every stage to finish and
+// returns the errors recorded along the way.
+func (s *Stream[T]) Drain(fn func(context.Context, T) error) error {
+	p := s.p
+	m := p.addStage("sink")
+	for item := range s.ch {
+		m.in.Add(1)
+		if p.ctx.Err() != nil {
+			// keep draining so upstream stages are not blocked forever
+			continue
+		}
+		start := time.Now()
+		_, err := mapItem(p.ctx, item, func(ctx context.Context, v T) (struct{}, error) {
+			return struct{}{}, fn(ctx, v)
+		})
+		m.busy.Add(int64(time.Since(start)))
+		if err != nil {
+			m.errors.Add(1)
+			p.fail("sink", err)
+		}
+	}
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	errs := p.errs
+	// with no recorded errors a done context can only come from the parent
+	if err := p.ctx.Err(); err != nil && len(errs) == 0 {
+		errs = append(errs, err)
+	}
+	p.cancel()
+	return errors.Join(errs...)
+}
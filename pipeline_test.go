@@ -0,0 +1,117 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    ErrorPolicy
+		expectErr bool
+		minSunk   int
+	}{
+		{name: "collect errors", policy: CollectErrors, expectErr: true, minSunk: 98},
+		{name: "fail fast", policy: FailFast, expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			items := make([]int, 100)
+			for i := range items {
+				items[i] = i
+			}
+
+			p := NewPipeline(context.Background(), tc.policy)
+			src := From(p, SliceIterator(items), 4)
+			doubled := Through(src, Stage[int, int]{
+				Name:        "double",
+				Concurrency: 4,
+				Buffer:      4,
+				Fn: func(_ context.Context, n int) (int, error) {
+					if n == 10 || n == 20 {
+						return 0, errors.New("bad item " + strconv.Itoa(n))
+					}
+					return n * 2, nil
+				},
+			})
+			formatted := Through(doubled, Stage[int, string]{
+				Name: "format",
+				Fn: func(_ context.Context, n int) (string, error) {
+					return strconv.Itoa(n), nil
+				},
+			})
+
+			var mu sync.Mutex
+			var sunk []string
+			err := formatted.Drain(func(_ context.Context, s string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				sunk = append(sunk, s)
+				return nil
+			})
+
+			if tc.expectErr && err == nil {
+				t.Fatal("expected an error but didn't get one")
+			}
+			if err != nil && !strings.Contains(err.Error(), "double: bad item") {
+				t.Errorf("expected stage name in error, got %v", err)
+			}
+			if len(sunk) < tc.minSunk {
+				t.Errorf("expected at least %d items in sink, got %d", tc.minSunk, len(sunk))
+			}
+
+			stats := p.Stats()
+			if len(stats) != 4 || stats[1].Name != "double" {
+				t.Fatalf("unexpected stats %+v", stats)
+			}
+			if tc.policy == CollectErrors && (stats[1].Errors != 2 || stats[3].In != 98) {
+				t.Errorf("unexpected stage metrics %+v", stats)
+			}
+		})
+	}
+}
+
+func TestPipelineKeyedOrder(t *testing.T) {
+	type event struct {
+		account string
+		seq     int
+	}
+	var items []event
+	for seq := 0; seq < 50; seq++ {
+		for _, account := range []string{"a", "b", "c", "d"} {
+			items = append(items, event{account: account, seq: seq})
+		}
+	}
+
+	var mu sync.Mutex
+	last := map[string]int{}
+	p := NewPipeline(context.Background(), FailFast)
+	applied := Through(From(p, SliceIterator(items), 8), Stage[event, event]{
+		Name:        "apply",
+		Concurrency: 4,
+		Key:         func(e event) string { return e.account },
+		Fn: func(_ context.Context, e event) (event, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if prev, ok := last[e.account]; ok && prev != e.seq-1 {
+				return e, errors.New("out of order event for " + e.account)
+			}
+			last[e.account] = e.seq
+			return e, nil
+		},
+	})
+
+	n := 0
+	if err := applied.Drain(func(context.Context, event) error { n++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(items) {
+		t.Errorf("expected %d items, got %d", len(items), n)
+	}
+}
@@ -0,0 +1,178 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Policy is the resilience configuration for a single named dependency.
+// Any part left out is not applied.
+type Policy struct {
+	Retry     *RetryPolicy     `json:"retry,omitempty"`
+	RateLimit *RateLimitPolicy `json:"rate_limit,omitempty"`
+	Circuit   *CircuitPolicy   `json:"circuit,omitempty"`
+	// Concurrency adapts the number of concurrent calls to the upstream.
+	Concurrency *AdaptivePolicy `json:"concurrency,omitempty"`
+}
+
+// Policies maps dependency names to their Policy. The "default" entry is
+// used for dependencies without their own entry.
+//
+// A policy file is YAML or JSON, such as:
+//
+//	default:
+//	  retry:
+//	    attempts: 3
+//	    backoff: 100ms
+//	    jitter: true
+//	payments:
+//	  retry:
+//	    attempts: 5
+//	    backoff: 200ms
+//	    max_backoff: 2s
+//	  rate_limit:
+//	    rate: 50/s
+//	    burst: 10
+//
+// or:
+//
+//	{
+//	  "default": {"retry": {"attempts": 3, "backoff": "100ms", "jitter": true}},
+//	  "payments": {
+//	    "retry": {"attempts": 5, "backoff": "200ms", "max_backoff": "2s"},
+//	    "rate_limit": {"rate": "50/s", "burst": 10},
+//	    "circuit": {"failure_threshold": 5, "open_for": "30s"},
+//	    "concurrency": {"max_limit": 20, "latency_threshold": "500ms"}
+//	  }
+//	}
+type Policies struct {
+	mu       sync.Mutex
+	policies map[string]Policy
+	deps     map[string]*Dependency
+}
+
+// ParsePolicies decodes a YAML or JSON policy document. Documents starting
+// with "{" are read as JSON. Unknown keys are rejected so typos in ops
+// managed files are caught at startup.
+func ParsePolicies(data []byte) (*Policies, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		converted, err := yamlToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy file: %w", err)
+		}
+		data = converted
+	}
+	var policies map[string]Policy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&policies); err != nil {
+		return nil, fmt.Errorf("invalid policy file: %w", triageJSONError(err, len(data)))
+	}
+	return &Policies{policies: policies, deps: make(map[string]*Dependency)}, nil
+}
+
+// LoadPolicies reads a YAML or JSON policy file from path.
+func LoadPolicies(path string) (*Policies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicies(data)
+}
+
+// LoadPoliciesFromEnv reads the policy file named by the POLICY_FILE
+// environment variable. An empty set of policies is returned when it is not
+// set, so every dependency runs without resilience behaviours.
+func LoadPoliciesFromEnv() (*Policies, error) {
+	path, err := getEnvOrError("POLICY_FILE")
+	if err != nil {
+		return &Policies{policies: map[string]Policy{}, deps: make(map[string]*Dependency)}, nil
+	}
+	return LoadPolicies(path)
+}
+
+// Policy returns the policy for name, falling back to "default".
+func (p *Policies) Policy(name string) Policy {
+	if policy, ok := p.policies[name]; ok {
+		return policy
+	}
+	return p.policies["default"]
+}
+
+// Dependency returns the Dependency for name. The same Dependency, and so
+// the same breaker and limiter state, is returned for every call with name.
+func (p *Policies) Dependency(name string) *Dependency {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if dep, ok := p.deps[name]; ok {
+		return dep
+	}
+	dep := NewDependency(name, p.Policy(name))
+	p.deps[name] = dep
+	return dep
+}
+
+// Dependency applies a Policy to calls made to a single upstream.
+type Dependency struct {
+	Name     string
+	policy   Policy
+	limiter  *RateLimiter
+	breaker  *CircuitBreaker
+	adaptive *AdaptiveLimiter
+}
+
+// NewDependency builds the limiters and breaker described by policy.
+func NewDependency(name string, policy Policy) *Dependency {
+	dep := &Dependency{Name: name, policy: policy}
+	if policy.RateLimit != nil {
+		dep.limiter = NewRateLimiter(*policy.RateLimit)
+	}
+	if policy.Circuit != nil {
+		dep.breaker = NewCircuitBreaker(*policy.Circuit)
+	}
+	if policy.Concurrency != nil {
+		dep.adaptive = NewAdaptiveLimiter(*policy.Concurrency)
+	}
+	return dep
+}
+
+// Do calls fn with the dependency's policy applied. Each attempt waits for
+// the rate limiter and a concurrency slot, then passes through the circuit
+// breaker, while the retry policy wraps the whole attempt. Open circuits
+// and exhausted limits are not retried.
+func (d *Dependency) Do(ctx context.Context, fn func(context.Context) error) error {
+	call := fn
+	if d.breaker != nil {
+		call = func(ctx context.Context) error {
+			err := d.breaker.Do(ctx, fn)
+			if err == ErrCircuitOpen {
+				return Permanent(err)
+			}
+			return err
+		}
+	}
+	attempt := func(ctx context.Context) error {
+		if d.limiter != nil {
+			if err := d.limiter.Wait(ctx); err != nil {
+				return Permanent(err)
+			}
+		}
+		if d.adaptive != nil {
+			err := d.adaptive.Do(ctx, call)
+			if err == ErrConcurrencyLimited {
+				return Permanent(err)
+			}
+			return err
+		}
+		return call(ctx)
+	}
+
+	if d.policy.Retry == nil {
+		return attempt(ctx)
+	}
+	return retry(ctx, *d.policy.Retry, attempt)
+}
@@ -0,0 +1,84 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies([]byte(`{
+		"default": {"retry": {"attempts": 2, "backoff": "1ms"}},
+		"payments": {
+			"retry": {"attempts": 4, "backoff": "1ms", "max_backoff": "2ms"},
+			"rate_limit": {"rate": "1000/s"},
+			"circuit": {"failure_threshold": 3, "open_for": "1d"},
+			"concurrency": {"max_limit": 20, "latency_threshold": "500ms"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payments := policies.Policy("payments")
+	if payments.Retry.Attempts != 4 || payments.RateLimit.Rate.Count != 1000 ||
+		time.Duration(payments.Circuit.OpenFor) != 24*time.Hour || payments.Concurrency.MaxLimit != 20 {
+		t.Errorf("unexpected payments policy %+v", payments)
+	}
+	if policies.Policy("search").Retry.Attempts != 2 {
+		t.Error("expected search to use the default policy")
+	}
+	if policies.Dependency("payments") != policies.Dependency("payments") {
+		t.Error("expected the same dependency to be returned for a name")
+	}
+
+	if _, err := ParsePolicies([]byte(`{"default": {"retries": {}}}`)); err == nil {
+		t.Error("expected unknown keys to be rejected")
+	}
+
+	yaml, err := ParsePolicies([]byte(`
+# tuned by ops
+default:
+  retry:
+    attempts: 2
+    backoff: 1ms
+payments:
+  rate_limit: {"rate": "1000/s", "burst": 5}
+  circuit:
+    failure_threshold: 3
+    open_for: 1d  # a day
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payments = yaml.Policy("payments")
+	if payments.RateLimit.Rate.Count != 1000 || payments.RateLimit.Burst != 5 || time.Duration(payments.Circuit.OpenFor) != 24*time.Hour {
+		t.Errorf("unexpected YAML payments policy %+v", payments)
+	}
+	if yaml.Policy("search").Retry.Attempts != 2 {
+		t.Error("expected search to use the YAML default policy")
+	}
+	if _, err := ParsePolicies([]byte("default:\n  retries:\n    attempts: 2\n")); err == nil {
+		t.Error("expected unknown YAML keys to be rejected")
+	}
+}
+
+func TestDependencyDo(t *testing.T) {
+	dep := NewDependency("payments", Policy{
+		Retry:   &RetryPolicy{Attempts: 5, Backoff: Duration(time.Millisecond)},
+		Circuit: &CircuitPolicy{FailureThreshold: 2, OpenFor: Duration(time.Minute)},
+	})
+
+	calls := 0
+	err := dep.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errors.New("down")
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to open, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected retries to stop once the circuit opened after 2 calls, got %d", calls)
+	}
+}
@@ -0,0 +1,80 @@
+package faas
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultUrgency is the urgency of requests without a Priority header.
+const DefaultUrgency = 3
+
+// Priority is an RFC 9218 request priority. Lower urgencies are more
+// important, from 0 to 7.
+type Priority struct {
+	Urgency int
+	// Incremental marks responses which are useful as they arrive, such as
+	// streams.
+	Incremental bool
+}
+
+// ParsePriority parses a Priority header such as "u=1, i". Unknown and
+// invalid parameters are ignored as the RFC requires, so the result is
+// always usable.
+func ParsePriority(header string) Priority {
+	p := Priority{Urgency: DefaultUrgency}
+	for _, member := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(member), "=")
+		// drop parameters such as "u=1;foo"
+		value, _, _ = strings.Cut(value, ";")
+		switch strings.TrimSpace(key) {
+		case "u":
+			if u, err := strconv.Atoi(value); err == nil && u >= 0 && u <= 7 {
+				p.Urgency = u
+			}
+		case "i":
+			switch value {
+			case "", "?1":
+				p.Incremental = true
+			case "?0":
+				p.Incremental = false
+			}
+		}
+	}
+	return p
+}
+
+// String formats p as a Priority header value, omitting defaults. The
+// default priority is the empty string.
+func (p Priority) String() string {
+	var parts []string
+	if p.Urgency != DefaultUrgency {
+		parts = append(parts, "u="+strconv.Itoa(p.Urgency))
+	}
+	if p.Incremental {
+		parts = append(parts, "i")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// RequestPriority is middleware storing the priority of the Priority
+// request header for PriorityFromContext. AdaptiveLimiter serves waiting
+// calls by urgency and InstrumentedTransport forwards the priority on
+// outbound calls made with the request context, so backpressure favours
+// the callers which asked for it.
+func RequestPriority(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithValue(r.Context(), PriorityKey, ParsePriority(r.Header.Get("Priority")))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// PriorityFromContext returns the priority stored by RequestPriority, or the
+// default priority.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := FromContext(ctx, PriorityKey); ok {
+		return p
+	}
+	return Priority{Urgency: DefaultUrgency}
+}
@@ -0,0 +1,121 @@
+package faas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		header string
+		want   Priority
+		str    string
+	}{
+		{header: "", want: Priority{Urgency: 3}, str: ""},
+		{header: "u=1", want: Priority{Urgency: 1}, str: "u=1"},
+		{header: "u=5, i", want: Priority{Urgency: 5, Incremental: true}, str: "u=5, i"},
+		{header: "i=?1", want: Priority{Urgency: 3, Incremental: true}, str: "i"},
+		{header: "u=9, i=?0, foo=bar", want: Priority{Urgency: 3}, str: ""},
+		{header: "u=0;x=1", want: Priority{Urgency: 0}, str: "u=0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.header, func(t *testing.T) {
+			got := ParsePriority(tc.header)
+			if got != tc.want {
+				t.Errorf("ParsePriority(%q) = %+v, want %+v", tc.header, got, tc.want)
+			}
+			if got.String() != tc.str {
+				t.Errorf("String() = %q, want %q", got.String(), tc.str)
+			}
+		})
+	}
+}
+
+func TestRequestPriorityOutbound(t *testing.T) {
+	var priority string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priority = r.Header.Get("Priority")
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: InstrumentedTransport{Metrics: NewOutboundMetrics()}}
+	h := RequestPriority(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Priority", "u=1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if priority != "u=1" {
+		t.Errorf("expected the priority to be forwarded, got %q", priority)
+	}
+}
+
+func TestAdaptiveLimiterPriority(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptivePolicy{MinLimit: 1, MaxLimit: 1})
+	release := make(chan struct{})
+	held := make(chan struct{})
+	go func() {
+		_ = l.Do(context.Background(), func(context.Context) error {
+			close(held)
+			<-release
+			return nil
+		})
+	}()
+	<-held
+
+	order := make(chan int, 2)
+	for _, u := range []int{6, 0} {
+		ctx := WithValue(context.Background(), PriorityKey, Priority{Urgency: u})
+		go func() {
+			_ = l.Do(ctx, func(context.Context) error {
+				order <- PriorityFromContext(ctx).Urgency
+				return nil
+			})
+		}()
+		// let the waiter queue up before the next one
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+	if first := <-order; first != 0 {
+		t.Errorf("expected the urgent call to get the slot first, got urgency %d", first)
+	}
+	<-order
+}
+
+func TestAdaptiveLimiterMiddleware(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptivePolicy{MinLimit: 1, MaxLimit: 1})
+	release := make(chan struct{})
+	held := make(chan struct{})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(held)
+			<-release
+		}
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-held
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while at the limit, got %d", rec.Code)
+	}
+	close(release)
+	<-done
+}
@@ -0,0 +1,179 @@
+package faas
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProbeTarget is an endpoint checked by a Prober.
+type ProbeTarget struct {
+	Name string `json:"name"`
+	// URL is "tcp://host:port" to check that a port accepts connections,
+	// or an http or https URL to request.
+	URL string `json:"url"`
+	// Method defaults to GET.
+	Method string `json:"method,omitempty"`
+	// ExpectStatus lists the accepted HTTP statuses. Defaults to any 2xx
+	// or 3xx status.
+	ExpectStatus []int `json:"expect_status,omitempty"`
+	// ExpectBody is a regular expression the start of the response body,
+	// up to 64KB, must match.
+	ExpectBody string `json:"expect_body,omitempty"`
+	// Timeout overrides the Prober's timeout.
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// ProbeResult is the outcome of probing a ProbeTarget.
+type ProbeResult struct {
+	Name    string    `json:"name"`
+	URL     string    `json:"url"`
+	Up      bool      `json:"up"`
+	Status  int       `json:"status,omitempty"`
+	Latency Duration  `json:"latency"`
+	Error   string    `json:"error,omitempty"`
+	Checked time.Time `json:"checked"`
+}
+
+// Prober checks TCP and HTTP endpoints with bounded concurrency, for uptime
+// checking functions. HTTP probes use Client, so they egress through the
+// same proxy rules, DNS cache and metrics as the function's other outbound
+// calls, and TCP probes connect through Proxy.
+type Prober struct {
+	// Concurrency bounds how many targets are probed at once. Defaults to
+	// 10.
+	Concurrency int
+	// Timeout bounds each probe, including connecting. Defaults to 5
+	// seconds.
+	Timeout time.Duration
+	// Client defaults to SharedHTTPClient. Redirects are not followed so
+	// a 3xx status can be checked.
+	Client *http.Client
+	// Proxy picks the proxy for TCP probes, e.g. ProxySelector.Proxy.
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
+// Probe checks a single target. Failures are reported in the result, which
+// is always returned.
+func (p *Prober) Probe(ctx context.Context, target ProbeTarget) ProbeResult {
+	res := ProbeResult{Name: target.Name, URL: target.URL, Checked: time.Now()}
+	timeout := defaultDuration(time.Duration(target.Timeout), defaultDuration(p.Timeout, 5*time.Second))
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	if addr, ok := strings.CutPrefix(target.URL, "tcp://"); ok {
+		err = p.probeTCP(ctx, addr)
+	} else {
+		res.Status, err = p.probeHTTP(ctx, target)
+	}
+	res.Latency = Duration(time.Since(res.Checked))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Up = true
+	return res
+}
+
+func (p *Prober) probeTCP(ctx context.Context, addr string) error {
+	var proxy *url.URL
+	if p.Proxy != nil {
+		var err error
+		if proxy, err = p.Proxy(&http.Request{URL: &url.URL{Scheme: "tcp", Host: addr}}); err != nil {
+			return err
+		}
+	}
+	conn, err := DialProxy(ctx, nil, proxy, addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (p *Prober) probeHTTP(ctx context.Context, target ProbeTarget) (int, error) {
+	var bodyRe *regexp.Regexp
+	if target.ExpectBody != "" {
+		var err error
+		if bodyRe, err = regexp.Compile(target.ExpectBody); err != nil {
+			return 0, fmt.Errorf("invalid expect_body: %w", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, defaultString(target.Method, http.MethodGet), target.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	client := p.Client
+	if client == nil {
+		client = SharedHTTPClient()
+	}
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if len(target.ExpectStatus) > 0 {
+		if !slices.Contains(target.ExpectStatus, resp.StatusCode) {
+			return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode > 399 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if bodyRe != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if err != nil {
+			return resp.StatusCode, err
+		}
+		if !bodyRe.Match(body) {
+			return resp.StatusCode, fmt.Errorf("body does not match %s", target.ExpectBody)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// ProbeAll checks every target, at most Concurrency at once, returning
+// the results in the order of targets.
+func (p *Prober) ProbeAll(ctx context.Context, targets []ProbeTarget) []ProbeResult {
+	results := make([]ProbeResult, len(targets))
+	sem := make(chan struct{}, defaultInt(p.Concurrency, 10))
+	var wg sync.WaitGroup
+	for i := range targets {
+		i := i
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = p.Probe(ctx, targets[i])
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Handler probes targets on every request and responds with the results,
+// with a 503 when any target is down so the function itself can be
+// monitored.
+func (p *Prober) Handler(targets []ProbeTarget) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		results := p.ProbeAll(r.Context(), targets)
+		status := http.StatusOK
+		for _, res := range results {
+			if !res.Up {
+				status = http.StatusServiceUnavailable
+				break
+			}
+		}
+		_ = writeJSON(w, status, Map{"results": results}, nil)
+	})
+}
@@ -0,0 +1,119 @@
+package faas
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			_, _ = w.Write([]byte(`{"status":"ok"}`))
+		case "/moved":
+			http.Redirect(w, r, "/health", http.StatusMovedPermanently)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	openAddr := ln.Addr().String()
+	defer ln.Close()
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	tests := []struct {
+		name       string
+		target     ProbeTarget
+		wantUp     bool
+		wantStatus int
+		wantError  string
+	}{
+		{name: "http up", target: ProbeTarget{URL: srv.URL + "/health"}, wantUp: true, wantStatus: 200},
+		{name: "body match", target: ProbeTarget{URL: srv.URL + "/health", ExpectBody: `"status":\s*"ok"`}, wantUp: true, wantStatus: 200},
+		{name: "body mismatch", target: ProbeTarget{URL: srv.URL + "/health", ExpectBody: "degraded"}, wantStatus: 200, wantError: "body does not match"},
+		{name: "unexpected status", target: ProbeTarget{URL: srv.URL + "/missing"}, wantStatus: 404, wantError: "unexpected status 404"},
+		{name: "expected status", target: ProbeTarget{URL: srv.URL + "/missing", ExpectStatus: []int{404}}, wantUp: true, wantStatus: 404},
+		{name: "redirect not followed", target: ProbeTarget{URL: srv.URL + "/moved", ExpectStatus: []int{301}}, wantUp: true, wantStatus: 301},
+		{name: "timeout", target: ProbeTarget{URL: srv.URL + "/slow", Timeout: Duration(20 * time.Millisecond)}, wantError: "deadline"},
+		{name: "tcp open", target: ProbeTarget{URL: "tcp://" + openAddr}, wantUp: true},
+		{name: "tcp closed", target: ProbeTarget{URL: "tcp://" + closedAddr}, wantError: "refused"},
+	}
+	p := &Prober{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := p.Probe(context.Background(), tt.target)
+			if res.Up != tt.wantUp || res.Status != tt.wantStatus || !strings.Contains(res.Error, tt.wantError) {
+				t.Errorf("Probe() = %+v", res)
+			}
+			if tt.wantError == "" && res.Error != "" {
+				t.Errorf("unexpected error %s", res.Error)
+			}
+		})
+	}
+}
+
+func TestProbeAll(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	targets := make([]ProbeTarget, 6)
+	for i := range targets {
+		targets[i] = ProbeTarget{Name: string(rune('a' + i)), URL: srv.URL + "/up"}
+	}
+	p := &Prober{Concurrency: 2}
+	results := p.ProbeAll(context.Background(), targets)
+	for i, res := range results {
+		if res.Name != targets[i].Name || !res.Up {
+			t.Errorf("result %d = %+v", i, res)
+		}
+	}
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("%d probes ran at once, want at most 2", got)
+	}
+
+	tests := []struct {
+		name       string
+		targets    []ProbeTarget
+		wantStatus int
+	}{
+		{name: "all up", targets: targets[:1], wantStatus: http.StatusOK},
+		{name: "one down", targets: append(targets[:1:1], ProbeTarget{URL: srv.URL + "/down"}), wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			p.Handler(tt.targets).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package faas
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProxyRule routes requests for matching hosts through a proxy.
+type ProxyRule struct {
+	// Hosts to match: an exact name such as "api.example.com", a suffix
+	// such as ".example.com" or "*.example.com", a CIDR such as
+	// "10.0.0.0/8" or "*" for every host.
+	Hosts []string `json:"hosts"`
+	// Proxy is an http, https or socks5 URL. Empty connects directly, which
+	// is useful to exclude hosts from a later catch-all rule.
+	Proxy string `json:"proxy"`
+	// UserSecret and PasswordSecret optionally name OpenFaaS secrets with
+	// the proxy credentials.
+	UserSecret     string `json:"user_secret,omitempty"`
+	PasswordSecret string `json:"password_secret,omitempty"`
+}
+
+// ProxySelector picks the proxy for each outbound request from a list of
+// rules. The first matching rule wins and requests matching no rule connect
+// directly.
+type ProxySelector struct {
+	rules []proxyRule
+}
+
+type proxyRule struct {
+	hosts []string
+	nets  []*net.IPNet
+	proxy *url.URL
+}
+
+// NewProxySelector validates rules and loads their credentials.
+func NewProxySelector(rules []ProxyRule) (*ProxySelector, error) {
+	s := &ProxySelector{}
+	for i, r := range rules {
+		var pr proxyRule
+		for _, h := range r.Hosts {
+			if _, n, err := net.ParseCIDR(h); err == nil {
+				pr.nets = append(pr.nets, n)
+				continue
+			}
+			pr.hosts = append(pr.hosts, strings.ToLower(strings.TrimPrefix(h, "*")))
+		}
+		if r.Proxy != "" {
+			u, err := url.Parse(r.Proxy)
+			if err != nil {
+				return nil, fmt.Errorf("proxy rule %d: %w", i, err)
+			}
+			switch u.Scheme {
+			case "http", "https", "socks5":
+			default:
+				return nil, fmt.Errorf("proxy rule %d: unsupported proxy scheme %q", i, u.Scheme)
+			}
+			if r.UserSecret != "" {
+				user, err := getSecretString(r.UserSecret)
+				if err != nil {
+					return nil, fmt.Errorf("proxy rule %d: %w", i, err)
+				}
+				pass, err := getSecretString(r.PasswordSecret)
+				if err != nil {
+					return nil, fmt.Errorf("proxy rule %d: %w", i, err)
+				}
+				u.User = url.UserPassword(user, pass)
+			}
+			pr.proxy = u
+		}
+		s.rules = append(s.rules, pr)
+	}
+	return s, nil
+}
+
+// Proxy returns the proxy for r, for use as http.Transport.Proxy.
+func (s *ProxySelector) Proxy(r *http.Request) (*url.URL, error) {
+	host := strings.ToLower(r.URL.Hostname())
+	ip := net.ParseIP(host)
+	for _, rule := range s.rules {
+		if rule.matches(host, ip) {
+			return rule.proxy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r proxyRule) matches(host string, ip net.IP) bool {
+	for _, h := range r.hosts {
+		switch {
+		case h == "":
+			// a bare "*"
+			return true
+		case strings.HasPrefix(h, "."):
+			if host == h[1:] || strings.HasSuffix(host, h) {
+				return true
+			}
+		case host == h:
+			return true
+		}
+	}
+	if ip != nil {
+		for _, n := range r.nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DialProxy connects to addr through proxy, for connections which are not
+// made by an http.Transport such as TLS inspection or TCP probes. http and
+// https proxies are sent a CONNECT request, socks5 proxies a CONNECT
+// command with the credentials in the URL. A nil proxy connects directly.
+func DialProxy(ctx context.Context, dialer *net.Dialer, proxy *url.URL, addr string) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	if proxy == nil {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		port := map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxy.Scheme]
+		proxyAddr = net.JoinHostPort(proxy.Hostname(), port)
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	switch proxy.Scheme {
+	case "https":
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err == nil {
+			conn = tlsConn
+			conn, err = httpConnect(conn, proxy, addr)
+		}
+	case "http":
+		conn, err = httpConnect(conn, proxy, addr)
+	case "socks5":
+		err = socks5Connect(conn, proxy, addr)
+	default:
+		err = fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to %s through proxy %s: %w", addr, proxy.Redacted(), err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// httpConnect sends a CONNECT request for addr and returns the tunnel,
+// which starts with any bytes the target sent along with the response,
+// such as an SMTP or SSH banner.
+func httpConnect(conn net.Conn, proxy *url.URL, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxy.User != nil {
+		pass, _ := proxy.User.Password()
+		creds := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+creds)
+	}
+	if err := req.Write(conn); err != nil {
+		return conn, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return conn, err
+	}
+	// the body is not closed as that would read the tunnel until EOF
+	if resp.StatusCode != http.StatusOK {
+		return conn, fmt.Errorf("proxy responded %s", resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn reads what its reader buffered before reading the conn.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func socks5Connect(conn net.Conn, proxy *url.URL, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || len(host) > 255 {
+		return fmt.Errorf("invalid address %q", addr)
+	}
+
+	method := byte(0x00)
+	if proxy.User != nil {
+		method = 0x02
+	}
+	if _, err := conn.Write([]byte{5, 1, method}); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 5 || reply[1] != method {
+		return errors.New("socks5 proxy rejected the authentication method")
+	}
+	if method == 0x02 {
+		user := proxy.User.Username()
+		pass, _ := proxy.User.Password()
+		auth := append(append([]byte{1, byte(len(user))}, user...), byte(len(pass)))
+		if _, err := conn.Write(append(auth, pass...)); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0 {
+			return errors.New("socks5 proxy rejected the credentials")
+		}
+	}
+
+	req := append([]byte{5, 1, 0, 3, byte(len(host))}, host...)
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return err
+	}
+	if head[1] != 0 {
+		return fmt.Errorf("socks5 proxy connect failed with code %d", head[1])
+	}
+	// skip the bound address and port
+	var skip int
+	switch head[3] {
+	case 1:
+		skip = 4 + 2
+	case 4:
+		skip = 16 + 2
+	case 3:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return err
+		}
+		skip = int(n[0]) + 2
+	default:
+		return errors.New("invalid socks5 reply")
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip))
+	return err
+}
@@ -0,0 +1,43 @@
+package faas
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is middleware allowing rate.Count requests per rate.Period for
+// each key returned by keyFunc, which defaults to ClientIP. Counts
+// are kept in fixed windows in store so the limit is shared by every
+// replica of the function. Requests over the limit are rejected with 429 and
+// a Retry-After header; when the store fails requests are let through.
+func RateLimit(store CounterKV, rate Rate, keyFunc func(*http.Request) string) func(http.Handler) http.Handler {
+	if keyFunc == nil {
+		keyFunc = ClientIP
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rate.Period <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			now := time.Now()
+			window := now.UnixNano() / int64(rate.Period)
+			key := "faas:ratelimit:" + keyFunc(r) + ":" + strconv.FormatInt(window, 10)
+			n, err := store.Incr(ctx, key, rate.Period)
+			if err != nil {
+				LoggerFromContext(ctx).Warn("rate limit store unavailable", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if n > int64(rate.Count) {
+				reset := time.Unix(0, (window+1)*int64(rate.Period))
+				w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds())+1))
+				_ = writeError(w, E(CodeRateLimited, "rate limit exceeded", ErrRateLimited))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
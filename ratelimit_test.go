@@ -0,0 +1,85 @@
+package faas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	store := NewMemoryKV()
+	h := RateLimit(store, Rate{Count: 2, Period: time.Minute}, func(r *http.Request) string {
+		return r.Header.Get("X-Api-Key")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	call := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	for i := 0; i < 2; i++ {
+		if rec := call("a"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	rec := call("a")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := call("b"); rec.Code != http.StatusOK {
+		t.Errorf("expected keys to be limited independently, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitDefaultKey(t *testing.T) {
+	h := RateLimit(NewMemoryKV(), Rate{Count: 1, Period: time.Minute}, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	call := func(remoteAddr, xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if xff != "" {
+			req.Header.Set("X-Forwarded-For", xff)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := call("203.0.113.7:50001", ""); code != http.StatusOK {
+		t.Fatalf("first request: %d", code)
+	}
+	// a new connection from the same client has another ephemeral port
+	if code := call("203.0.113.7:50002", ""); code != http.StatusTooManyRequests {
+		t.Errorf("same IP, other port: %d, want 429", code)
+	}
+	// behind the gateway every request comes from its address
+	if code := call("10.0.0.2:8080", "198.51.100.1"); code != http.StatusOK {
+		t.Errorf("first forwarded client: %d", code)
+	}
+	if code := call("10.0.0.2:8081", "198.51.100.2, 10.0.0.9"); code != http.StatusOK {
+		t.Errorf("second forwarded client: %d, want 200", code)
+	}
+	if code := call("10.0.0.2:8082", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("repeated forwarded client: %d, want 429", code)
+	}
+}
+
+func TestMemoryKVIncr(t *testing.T) {
+	ctx := context.Background()
+	kv := NewMemoryKV()
+	for want := int64(1); want <= 3; want++ {
+		n, err := kv.Incr(ctx, "c", time.Minute)
+		if err != nil || n != want {
+			t.Fatalf("Incr() = %d, %v, want %d", n, err, want)
+		}
+	}
+	if v, _ := kv.Get(ctx, "c"); string(v) != "3" {
+		t.Errorf("expected the counter to be readable with Get, got %q", v)
+	}
+	_ = kv.Set(ctx, "s", []byte("text"), 0)
+	if _, err := kv.Incr(ctx, "s", 0); err == nil {
+		t.Error("expected an error incrementing a non-counter value")
+	}
+}
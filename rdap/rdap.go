@@ -0,0 +1,375 @@
+// Package rdap looks up domain registration data with RDAP, falling back to
+// WHOIS for registries without an RDAP service:
+//
+//	client := &rdap.Client{}
+//	d, err := client.Domain(ctx, "example.com")
+//	if errors.Is(err, rdap.ErrNotFound) {
+//		// the domain is not registered
+//	}
+//	fmt.Println(d.Registrar, d.Expires)
+//
+// The RDAP server of each TLD is found with the IANA bootstrap registry,
+// which is cached.
+package rdap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// ErrNotFound is returned for domains which are not registered.
+var ErrNotFound = errors.New("domain not found")
+
+// Domain is the normalised registration data of a domain.
+type Domain struct {
+	Name string `json:"name"`
+	// Handle is the registry's ID of the domain. WHOIS does not always
+	// return it.
+	Handle          string    `json:"handle,omitempty"`
+	Registrar       string    `json:"registrar,omitempty"`
+	RegistrarIANAID string    `json:"registrar_iana_id,omitempty"`
+	Status          []string  `json:"status,omitempty"`
+	Nameservers     []string  `json:"nameservers,omitempty"`
+	Created         time.Time `json:"created,omitempty"`
+	Updated         time.Time `json:"updated,omitempty"`
+	Expires         time.Time `json:"expires,omitempty"`
+	DNSSEC          bool      `json:"dnssec"`
+	// Source is "rdap" or "whois".
+	Source string `json:"source"`
+}
+
+// Client looks up domains. It is safe for concurrent use.
+type Client struct {
+	// HTTP defaults to faas.SharedHTTPClient.
+	HTTP *http.Client
+	// BootstrapURL defaults to https://data.iana.org/rdap/dns.json.
+	BootstrapURL string
+	// BootstrapTTL is how long the bootstrap registry is cached. Defaults
+	// to 24 hours.
+	BootstrapTTL time.Duration
+	// WHOISServer is queried for the WHOIS server of a TLD. Defaults to
+	// whois.iana.org:43.
+	WHOISServer string
+	// Timeout bounds each WHOIS query. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	mu        sync.Mutex
+	services  map[string]string
+	fetchedAt time.Time
+}
+
+// Domain returns the registration data of name from the RDAP server of its
+// TLD, or from WHOIS when the TLD has no RDAP service or it fails.
+func (c *Client) Domain(ctx context.Context, name string) (*Domain, error) {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	if !strings.Contains(name, ".") {
+		return nil, fmt.Errorf("invalid domain %q", name)
+	}
+	base, err := c.server(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if base != "" {
+		d, err := c.lookupRDAP(ctx, base, name)
+		if err == nil || errors.Is(err, ErrNotFound) {
+			return d, err
+		}
+		faas.LoggerFromContext(ctx).Warn("rdap lookup failed, falling back to whois", "domain", name, "error", err)
+	}
+	return c.lookupWHOIS(ctx, name)
+}
+
+// server returns the RDAP base URL for the TLD of name, or "" when there
+// is none.
+func (c *Client) server(ctx context.Context, name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ttl := c.BootstrapTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if c.services == nil || time.Since(c.fetchedAt) > ttl {
+		var bootstrap struct {
+			Services [][][]string `json:"services"`
+		}
+		u := c.BootstrapURL
+		if u == "" {
+			u = "https://data.iana.org/rdap/dns.json"
+		}
+		if err := faas.DoJSON(ctx, c.HTTP, http.MethodGet, u, nil, &bootstrap); err != nil {
+			if c.services == nil {
+				return "", fmt.Errorf("fetching rdap bootstrap: %w", err)
+			}
+			// keep using the stale registry rather than failing
+		} else {
+			c.services = make(map[string]string)
+			for _, s := range bootstrap.Services {
+				if len(s) < 2 || len(s[1]) == 0 {
+					continue
+				}
+				for _, tld := range s[0] {
+					c.services[strings.ToLower(tld)] = s[1][0]
+				}
+			}
+			c.fetchedAt = time.Now()
+		}
+	}
+	// the longest matching suffix wins, e.g. "co.uk" over "uk"
+	labels := strings.Split(name, ".")
+	for i := 1; i < len(labels); i++ {
+		if base, ok := c.services[strings.Join(labels[i:], ".")]; ok {
+			return base, nil
+		}
+	}
+	return "", nil
+}
+
+type rdapDomain struct {
+	Handle  string   `json:"handle"`
+	LDHName string   `json:"ldhName"`
+	Status  []string `json:"status"`
+	Events  []struct {
+		Action string    `json:"eventAction"`
+		Date   time.Time `json:"eventDate"`
+	} `json:"events"`
+	Nameservers []struct {
+		LDHName string `json:"ldhName"`
+	} `json:"nameservers"`
+	Entities []struct {
+		Roles     []string          `json:"roles"`
+		VCard     []json.RawMessage `json:"vcardArray"`
+		PublicIDs []struct {
+			Type       string `json:"type"`
+			Identifier string `json:"identifier"`
+		} `json:"publicIds"`
+	} `json:"entities"`
+	SecureDNS struct {
+		DelegationSigned bool `json:"delegationSigned"`
+	} `json:"secureDNS"`
+}
+
+func (c *Client) lookupRDAP(ctx context.Context, base, name string) (*Domain, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(base, "/")+"/domain/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	client := c.HTTP
+	if client == nil {
+		client = faas.SharedHTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &faas.StatusError{Method: req.Method, URL: req.URL.Redacted(), StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(snippet))}
+	}
+	var rd rdapDomain
+	if err := json.NewDecoder(resp.Body).Decode(&rd); err != nil {
+		return nil, fmt.Errorf("decoding rdap response: %w", err)
+	}
+
+	d := &Domain{
+		Name:   strings.ToLower(defaultString(rd.LDHName, name)),
+		Handle: rd.Handle,
+		Status: rd.Status,
+		DNSSEC: rd.SecureDNS.DelegationSigned,
+		Source: "rdap",
+	}
+	for _, e := range rd.Events {
+		switch e.Action {
+		case "registration":
+			d.Created = e.Date
+		case "expiration":
+			d.Expires = e.Date
+		case "last changed":
+			d.Updated = e.Date
+		}
+	}
+	for _, ns := range rd.Nameservers {
+		d.Nameservers = append(d.Nameservers, strings.ToLower(ns.LDHName))
+	}
+	for _, e := range rd.Entities {
+		if !slices.Contains(e.Roles, "registrar") {
+			continue
+		}
+		d.Registrar = vcardName(e.VCard)
+		for _, id := range e.PublicIDs {
+			if id.Type == "IANA Registrar ID" {
+				d.RegistrarIANAID = id.Identifier
+			}
+		}
+	}
+	return d, nil
+}
+
+// vcardName returns the "fn" property of a jCard, which is encoded as
+// ["vcard", [["fn", {}, "text", "Example Registrar"], ...]].
+func vcardName(vcard []json.RawMessage) string {
+	if len(vcard) < 2 {
+		return ""
+	}
+	var props [][]any
+	if err := json.Unmarshal(vcard[1], &props); err != nil {
+		return ""
+	}
+	for _, p := range props {
+		if len(p) >= 4 && p[0] == "fn" {
+			if s, ok := p[3].(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func (c *Client) lookupWHOIS(ctx context.Context, name string) (*Domain, error) {
+	tld := name[strings.LastIndex(name, ".")+1:]
+	iana, err := c.queryWHOIS(ctx, defaultString(c.WHOISServer, "whois.iana.org:43"), tld)
+	if err != nil {
+		return nil, err
+	}
+	server := whoisFields(iana)["refer"]
+	if len(server) == 0 {
+		return nil, fmt.Errorf("no whois server for .%s", tld)
+	}
+	raw, err := c.queryWHOIS(ctx, server[0], name)
+	if err != nil {
+		return nil, err
+	}
+	return parseWHOIS(name, raw)
+}
+
+func (c *Client) queryWHOIS(ctx context.Context, server, query string) (string, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "43")
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", server)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	if _, err := io.WriteString(conn, query+"\r\n"); err != nil {
+		return "", err
+	}
+	// responses are small, the cap guards against misbehaving servers
+	data, err := io.ReadAll(io.LimitReader(conn, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("querying whois %s: %w", server, err)
+	}
+	return string(data), nil
+}
+
+// whoisFields returns the "key: value" lines of a WHOIS response by lower
+// cased key.
+func whoisFields(raw string) map[string][]string {
+	fields := make(map[string][]string)
+	sc := bufio.NewScanner(strings.NewReader(raw))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ">>>") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		fields[key] = append(fields[key], value)
+	}
+	return fields
+}
+
+var whoisNotFound = []string{"no match for", "not found", "no data found", "no entries found", "status: free", "status: available"}
+
+func parseWHOIS(name, raw string) (*Domain, error) {
+	lower := strings.ToLower(raw)
+	for _, marker := range whoisNotFound {
+		if strings.Contains(lower, marker) {
+			return nil, ErrNotFound
+		}
+	}
+	f := whoisFields(raw)
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := f[k]; len(v) > 0 {
+				return v[0]
+			}
+		}
+		return ""
+	}
+	d := &Domain{
+		Name:            name,
+		Handle:          first("registry domain id"),
+		Registrar:       first("registrar", "sponsoring registrar"),
+		RegistrarIANAID: first("registrar iana id"),
+		Created:         parseWHOISTime(first("creation date", "created", "registered on", "registration time")),
+		Updated:         parseWHOISTime(first("updated date", "last updated", "last modified", "changed")),
+		Expires:         parseWHOISTime(first("registry expiry date", "registrar registration expiration date", "expiry date", "expiration date", "expires", "paid-till")),
+		Source:          "whois",
+	}
+	for _, s := range append(f["domain status"], f["status"]...) {
+		// "clientTransferProhibited https://icann.org/epp#clientTransferProhibited"
+		d.Status = append(d.Status, strings.Fields(s)[0])
+	}
+	for _, ns := range append(f["name server"], f["nserver"]...) {
+		d.Nameservers = append(d.Nameservers, strings.ToLower(strings.Fields(ns)[0]))
+	}
+	dnssec := strings.ToLower(first("dnssec"))
+	d.DNSSEC = dnssec != "" && dnssec != "unsigned" && !strings.HasPrefix(dnssec, "no")
+	return d, nil
+}
+
+var whoisTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02-Jan-2006",
+	"2006.01.02",
+	"02.01.2006",
+}
+
+func parseWHOISTime(s string) time.Time {
+	for _, layout := range whoisTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func defaultString(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
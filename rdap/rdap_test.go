@@ -0,0 +1,210 @@
+package rdap
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const rdapExample = `{
+	"objectClassName": "domain",
+	"handle": "2336799_DOMAIN_COM-VRSN",
+	"ldhName": "EXAMPLE.COM",
+	"status": ["client delete prohibited", "client transfer prohibited"],
+	"events": [
+		{"eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z"},
+		{"eventAction": "expiration", "eventDate": "2026-08-13T04:00:00Z"},
+		{"eventAction": "last changed", "eventDate": "2025-08-14T07:01:39Z"}
+	],
+	"nameservers": [{"ldhName": "A.IANA-SERVERS.NET"}, {"ldhName": "B.IANA-SERVERS.NET"}],
+	"entities": [{
+		"roles": ["registrar"],
+		"vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "RESERVED-Internet Assigned Numbers Authority"]]],
+		"publicIds": [{"type": "IANA Registrar ID", "identifier": "376"}]
+	}],
+	"secureDNS": {"delegationSigned": true}
+}`
+
+func newRDAPServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var bootstraps atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			bootstraps.Add(1)
+			w.Write([]byte(`{"services": [[["com", "net"], ["` + srv.URL + `/com/"]], [["broken"], ["` + srv.URL + `/broken/"]]]}`))
+		case "/com/domain/example.com":
+			if r.Header.Get("Accept") != "application/rdap+json" {
+				t.Errorf("Accept = %q", r.Header.Get("Accept"))
+			}
+			w.Header().Set("Content-Type", "application/rdap+json")
+			w.Write([]byte(rdapExample))
+		default:
+			if strings.HasPrefix(r.URL.Path, "/broken/") {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bootstraps
+}
+
+// newWHOISServer answers WHOIS queries with responses[query], or a "No
+// match" response.
+func newWHOISServer(t *testing.T, responses map[string]string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				query, _ := bufio.NewReader(conn).ReadString('\n')
+				resp, ok := responses[strings.TrimSpace(query)]
+				if !ok {
+					resp = "No match for \"" + strings.TrimSpace(query) + "\".\r\n"
+				}
+				conn.Write([]byte(resp))
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestDomainRDAP(t *testing.T) {
+	srv, bootstraps := newRDAPServer(t)
+	c := &Client{HTTP: srv.Client(), BootstrapURL: srv.URL + "/dns.json"}
+
+	d, err := c.Domain(context.Background(), "Example.COM.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Domain{
+		Name:            "example.com",
+		Handle:          "2336799_DOMAIN_COM-VRSN",
+		Registrar:       "RESERVED-Internet Assigned Numbers Authority",
+		RegistrarIANAID: "376",
+		Created:         time.Date(1995, 8, 14, 4, 0, 0, 0, time.UTC),
+		Updated:         time.Date(2025, 8, 14, 7, 1, 39, 0, time.UTC),
+		Expires:         time.Date(2026, 8, 13, 4, 0, 0, 0, time.UTC),
+		DNSSEC:          true,
+		Source:          "rdap",
+	}
+	if d.Name != want.Name || d.Handle != want.Handle || d.Registrar != want.Registrar || d.RegistrarIANAID != want.RegistrarIANAID ||
+		!d.Created.Equal(want.Created) || !d.Updated.Equal(want.Updated) || !d.Expires.Equal(want.Expires) ||
+		d.DNSSEC != want.DNSSEC || d.Source != want.Source {
+		t.Errorf("domain = %+v, want %+v", d, want)
+	}
+	if strings.Join(d.Nameservers, ",") != "a.iana-servers.net,b.iana-servers.net" {
+		t.Errorf("nameservers = %v", d.Nameservers)
+	}
+	if len(d.Status) != 2 {
+		t.Errorf("status = %v", d.Status)
+	}
+
+	if _, err := c.Domain(context.Background(), "unregistered.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unregistered err = %v, want ErrNotFound", err)
+	}
+	if n := bootstraps.Load(); n != 1 {
+		t.Errorf("bootstrap fetched %d times, want 1", n)
+	}
+}
+
+const whoisExample = `Domain Name: EXAMPLE.ORG
+Registry Domain ID: 2d6f3b3f-org
+Registrar WHOIS Server: whois.example-registrar.test
+Updated Date: 2024-01-08T16:01:24Z
+Creation Date: 1995-04-30T04:00:00Z
+Registry Expiry Date: 2026-04-29T04:00:00Z
+Registrar: Example Registrar, Inc.
+Registrar IANA ID: 9999
+Domain Status: clientTransferProhibited https://icann.org/epp#clientTransferProhibited
+Name Server: NS1.EXAMPLE.ORG
+Name Server: NS2.EXAMPLE.ORG
+DNSSEC: unsigned
+>>> Last update of WHOIS database: 2024-06-01T00:00:00Z <<<
+`
+
+func TestDomainWHOISFallback(t *testing.T) {
+	srv, _ := newRDAPServer(t)
+	registry := newWHOISServer(t, map[string]string{
+		"example.org":    whoisExample,
+		"example.broken": whoisExample,
+	})
+	iana := newWHOISServer(t, map[string]string{
+		"org":    "% IANA WHOIS server\nrefer:        " + registry + "\n",
+		"broken": "refer: " + registry + "\n",
+	})
+	c := &Client{HTTP: srv.Client(), BootstrapURL: srv.URL + "/dns.json", WHOISServer: iana}
+
+	tests := []struct {
+		name   string
+		domain string
+	}{
+		{"no rdap service", "example.org"},
+		{"rdap failing", "example.broken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := c.Domain(context.Background(), tt.domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Source != "whois" || d.Registrar != "Example Registrar, Inc." || d.RegistrarIANAID != "9999" || d.Handle != "2d6f3b3f-org" {
+				t.Errorf("domain = %+v", d)
+			}
+			if !d.Expires.Equal(time.Date(2026, 4, 29, 4, 0, 0, 0, time.UTC)) || !d.Created.Equal(time.Date(1995, 4, 30, 4, 0, 0, 0, time.UTC)) {
+				t.Errorf("dates = %v, %v", d.Created, d.Expires)
+			}
+			if strings.Join(d.Status, ",") != "clientTransferProhibited" {
+				t.Errorf("status = %v", d.Status)
+			}
+			if strings.Join(d.Nameservers, ",") != "ns1.example.org,ns2.example.org" {
+				t.Errorf("nameservers = %v", d.Nameservers)
+			}
+			if d.DNSSEC {
+				t.Error("dnssec = true for an unsigned domain")
+			}
+		})
+	}
+
+	if _, err := c.Domain(context.Background(), "missing.org"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestParseWHOISTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2026-04-29T04:00:00Z", time.Date(2026, 4, 29, 4, 0, 0, 0, time.UTC)},
+		{"2026-04-29", time.Date(2026, 4, 29, 0, 0, 0, 0, time.UTC)},
+		{"29-Apr-2026", time.Date(2026, 4, 29, 0, 0, 0, 0, time.UTC)},
+		{"2026-04-29 04:00:00", time.Date(2026, 4, 29, 4, 0, 0, 0, time.UTC)},
+		{"", time.Time{}},
+		{"never", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseWHOISTime(tt.in); !got.Equal(tt.want) {
+			t.Errorf("parseWHOISTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+// Package redis is a minimal Redis client implementing the stores used by
+// the faas helpers, so a function backed by Redis needs one line of setup:
+//
+//	client, err := redis.NewFromEnv()
+//	if err != nil {
+//		return err
+//	}
+//	handler = faas.Dedupe(keyFunc, client, time.Hour)(handler)
+//
+// Client implements faas.KV for caches, faas.AtomicKV for idempotency with
+// faas.Dedupe and faas.Inbox and locks such as faas.CronGuard,
+// faas.CompareDeleteKV to release those locks, and faas.CounterKV for
+// faas.RateLimit.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// Error is an error reply from the server, such as "WRONGTYPE ...".
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// Options configure a Client.
+type Options struct {
+	// Addr is the host:port of the server.
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	// TLS connects with TLS, as used by most managed Redis services.
+	TLS bool
+	// Timeout bounds connecting and each command. Default 5s.
+	Timeout time.Duration
+	// PoolSize is the number of idle connections kept open. Default 10.
+	PoolSize int
+}
+
+// ParseURL parses redis://[user:password@]host[:port][/db], or rediss://
+// for TLS, into Options.
+func ParseURL(rawURL string) (Options, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return Options{}, fmt.Errorf("invalid redis URL %q, expected redis://host:port/db", rawURL)
+	}
+	opts := Options{Addr: u.Host, TLS: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		opts.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if opts.DB, err = strconv.Atoi(db); err != nil {
+			return Options{}, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	return opts, nil
+}
+
+// OptionsFromEnv reads Options from the REDIS_URL environment variable,
+// defaulting to redis://localhost:6379. When the URL has no password it is
+// read from the OpenFaaS secret named by REDIS_PASSWORD_SECRET, if set.
+func OptionsFromEnv() (Options, error) {
+	rawURL := os.Getenv("REDIS_URL")
+	if rawURL == "" {
+		rawURL = "redis://localhost:6379"
+	}
+	opts, err := ParseURL(rawURL)
+	if err != nil {
+		return Options{}, err
+	}
+	if secret := os.Getenv("REDIS_PASSWORD_SECRET"); secret != "" && opts.Password == "" {
+		if opts.Password, err = faas.GetSecretString(secret); err != nil {
+			return Options{}, fmt.Errorf("reading redis password: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// Client is a Redis client with a small connection pool. It is safe for
+// concurrent use.
+type Client struct {
+	opts Options
+	idle chan *conn
+}
+
+// New returns a client for the server described by opts. Connections are
+// opened on first use.
+func New(opts Options) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	return &Client{opts: opts, idle: make(chan *conn, opts.PoolSize)}
+}
+
+// NewFromEnv returns a client configured by OptionsFromEnv and registers its
+// Ping as the "redis" faas health check.
+func NewFromEnv() (*Client, error) {
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	c := New(opts)
+	faas.RegisterHealthCheck("redis", c.Ping)
+	return c, nil
+}
+
+// Ping checks the server is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Get implements faas.KV.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := c.Do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, faas.ErrNotFound
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected GET reply %T", v)
+	}
+	return b, nil
+}
+
+// Set implements faas.KV.
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// SetNX implements faas.AtomicKV.
+func (c *Client) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	args := []string{"SET", key, string(value), "NX"}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	v, err := c.Do(ctx, args...)
+	return v != nil, err
+}
+
+// incrScript increments a counter and sets the expiry of a new one, in a
+// single atomic step so a counter never lives forever when the connection
+// drops between two commands.
+const incrScript = `local n = redis.call('INCR', KEYS[1])
+if n == 1 and tonumber(ARGV[1]) > 0 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return n`
+
+// Incr implements faas.CounterKV.
+func (c *Client) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	v, err := c.Do(ctx, "EVAL", incrScript, "1", key, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected INCR reply %T", v)
+	}
+	return n, nil
+}
+
+// deleteIfEqualScript deletes a key only while it holds the expected value.
+const deleteIfEqualScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`
+
+// DeleteIfEqual implements faas.CompareDeleteKV.
+func (c *Client) DeleteIfEqual(ctx context.Context, key string, value []byte) (bool, error) {
+	v, err := c.Do(ctx, "EVAL", deleteIfEqualScript, "1", key, string(value))
+	if err != nil {
+		return false, err
+	}
+	return v == int64(1), nil
+}
+
+// Delete implements faas.KV.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	_, err := c.Do(ctx, "DEL", key)
+	return err
+}
+
+// Do sends a command and returns its reply: nil, a string for status
+// replies, int64, []byte for bulk strings or []any for arrays. Error replies
+// are returned as Error. When a pooled connection turns out to be closed the
+// command is retried once on a new one, unless the server may have run it
+// and running it again is not idempotent.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, reused, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v, err := cn.do(ctx, c.opts.Timeout, args)
+	if err != nil && reused && (cn.stale || (cn.closed && idempotent(args))) {
+		// the server closed the idle connection between invocations, retry
+		// once on a fresh one
+		_ = cn.Close()
+		if cn, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+		v, err = cn.do(ctx, c.opts.Timeout, args)
+	}
+	c.put(cn, err)
+	return v, err
+}
+
+// Close closes the idle connections.
+func (c *Client) Close() error {
+	var errs []error
+	for {
+		select {
+		case cn := <-c.idle:
+			errs = append(errs, cn.Close())
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context) (*conn, bool, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, true, nil
+	default:
+	}
+	cn, err := c.dial(ctx)
+	return cn, false, err
+}
+
+func (c *Client) put(cn *conn, err error) {
+	var redisErr Error
+	if err != nil && !errors.As(err, &redisErr) {
+		_ = cn.Close()
+		return
+	}
+	select {
+	case c.idle <- cn:
+	default:
+		_ = cn.Close()
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	var nc net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: c.opts.Timeout}
+	if c.opts.TLS {
+		td := &tls.Dialer{NetDialer: dialer}
+		nc, err = td.DialContext(ctx, "tcp", c.opts.Addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, rd: bufio.NewReader(nc)}
+
+	var setup [][]string
+	if c.opts.Password != "" {
+		if c.opts.Username != "" {
+			setup = append(setup, []string{"AUTH", c.opts.Username, c.opts.Password})
+		} else {
+			setup = append(setup, []string{"AUTH", c.opts.Password})
+		}
+	}
+	if c.opts.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.opts.DB)})
+	}
+	for _, args := range setup {
+		if _, err := cn.do(ctx, c.opts.Timeout, args); err != nil {
+			_ = cn.Close()
+			return nil, fmt.Errorf("redis %s: %w", strings.ToLower(args[0]), err)
+		}
+	}
+	return cn, nil
+}
+
+type conn struct {
+	net.Conn
+	rd *bufio.Reader
+	// stale is set when the command could not be written, so the server
+	// cannot have run it and it is safe to retry.
+	stale bool
+	// closed is set when the server closed the connection before replying.
+	// It may have run the command, so only idempotent commands are retried.
+	closed bool
+}
+
+// idempotent reports whether running args twice has the same effect, and
+// reply, as running them once.
+func idempotent(args []string) bool {
+	switch strings.ToUpper(args[0]) {
+	case "GET", "DEL", "PING", "EXISTS", "PTTL", "TTL":
+		return true
+	case "SET":
+		// NX, XX and GET make the reply depend on the previous value
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX", "XX", "GET":
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (cn *conn) do(ctx context.Context, timeout time.Duration, args []string) (any, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = cn.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(cn, b.String()); err != nil {
+		cn.stale = true
+		return nil, err
+	}
+	v, err := readReply(cn.rd)
+	if errors.Is(err, io.EOF) {
+		cn.closed = true
+	}
+	return v, err
+}
+
+func readReply(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = readReply(rd); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,267 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// fakeServer speaks enough RESP to serve the commands used by Client.
+type fakeServer struct {
+	ln       net.Listener
+	password string
+
+	mu   sync.Mutex
+	data map[string]string
+	ttls map[string]string
+	cmds []string
+	// drop is a command after which the connection is closed unanswered
+	drop string
+}
+
+func newFakeServer(t *testing.T, password string) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, password: password, data: map[string]string{}, ttls: map[string]string{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	rd := bufio.NewReader(c)
+	authed := s.password == ""
+	for {
+		v, err := readReply(rd)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range v.([]any) {
+			args = append(args, string(a.([]byte)))
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			fmt.Fprint(c, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		s.mu.Lock()
+		s.cmds = append(s.cmds, cmd)
+		// a dropped command runs but the connection closes before the reply
+		var out io.Writer = c
+		drop := s.drop != "" && cmd == s.drop
+		if drop {
+			s.drop, out = "", io.Discard
+		}
+		switch cmd {
+		case "AUTH":
+			if args[len(args)-1] == s.password {
+				authed = true
+				fmt.Fprint(out, "+OK\r\n")
+			} else {
+				fmt.Fprint(out, "-WRONGPASS invalid password\r\n")
+			}
+		case "PING":
+			fmt.Fprint(out, "+PONG\r\n")
+		case "GET":
+			if v, ok := s.data[args[1]]; ok {
+				fmt.Fprintf(out, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				fmt.Fprint(out, "$-1\r\n")
+			}
+		case "SET":
+			_, exists := s.data[args[1]]
+			if len(args) > 3 && args[3] == "NX" && exists {
+				fmt.Fprint(out, "$-1\r\n")
+			} else {
+				s.data[args[1]] = args[2]
+				fmt.Fprint(out, "+OK\r\n")
+			}
+		case "INCR":
+			n, _ := strconv.Atoi(s.data[args[1]])
+			s.data[args[1]] = strconv.Itoa(n + 1)
+			fmt.Fprintf(out, ":%d\r\n", n+1)
+		case "EVAL":
+			key := args[3]
+			if args[1] == deleteIfEqualScript {
+				if v, ok := s.data[key]; ok && v == args[4] {
+					delete(s.data, key)
+					fmt.Fprint(out, ":1\r\n")
+				} else {
+					fmt.Fprint(out, ":0\r\n")
+				}
+				break
+			}
+			// otherwise the counter script of Incr
+			n, _ := strconv.Atoi(s.data[key])
+			s.data[key] = strconv.Itoa(n + 1)
+			if n == 0 && args[4] != "0" {
+				s.ttls[key] = args[4]
+			}
+			fmt.Fprintf(out, ":%d\r\n", n+1)
+		case "DEL":
+			delete(s.data, args[1])
+			fmt.Fprint(out, ":1\r\n")
+		default:
+			fmt.Fprintf(out, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		s.mu.Unlock()
+		if drop {
+			return
+		}
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Options
+		wantErr bool
+	}{
+		{url: "redis://localhost", want: Options{Addr: "localhost:6379"}},
+		{url: "rediss://user:pw@cache:6380/2", want: Options{Addr: "cache:6380", Username: "user", Password: "pw", DB: 2, TLS: true}},
+		{url: "http://localhost", wantErr: true},
+		{url: "redis://localhost/x", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			got, err := ParseURL(tc.url)
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Errorf("ParseURL() = %+v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := newFakeServer(t, "secret")
+	c := New(Options{Addr: srv.ln.Addr().String(), Password: "secret"})
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, faas.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := c.Set(ctx, "k", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "k"); err != nil || string(v) != "v" {
+		t.Errorf("Get() = %q, %v", v, err)
+	}
+	if ok, err := c.SetNX(ctx, "k", []byte("other"), time.Minute); ok || err != nil {
+		t.Errorf("SetNX() on an existing key = %v, %v", ok, err)
+	}
+	srv.mu.Lock()
+	srv.cmds = nil
+	srv.mu.Unlock()
+	for want := int64(1); want <= 2; want++ {
+		if n, err := c.Incr(ctx, "n", time.Minute); n != want || err != nil {
+			t.Errorf("Incr() = %d, %v, want %d", n, err, want)
+		}
+	}
+	srv.mu.Lock()
+	if got := strings.Join(srv.cmds, " "); got != "EVAL EVAL" || srv.ttls["n"] != "60000" {
+		t.Errorf("Incr sent %q, ttl %q, want one EVAL per call with a 60000ms ttl", got, srv.ttls["n"])
+	}
+	srv.mu.Unlock()
+	if ok, err := c.DeleteIfEqual(ctx, "k", []byte("other")); ok || err != nil {
+		t.Errorf("DeleteIfEqual() with another value = %v, %v", ok, err)
+	}
+	if ok, err := c.DeleteIfEqual(ctx, "k", []byte("v")); !ok || err != nil {
+		t.Errorf("DeleteIfEqual() = %v, %v", ok, err)
+	}
+	if ok, err := c.SetNX(ctx, "k", []byte("lock"), time.Minute); !ok || err != nil {
+		t.Errorf("SetNX() on a deleted key = %v, %v", ok, err)
+	}
+	var redisErr Error
+	if _, err := c.Do(ctx, "FLUSHALL"); !errors.As(err, &redisErr) {
+		t.Errorf("expected an error reply, got %v", err)
+	}
+	if err := c.Ping(ctx); err != nil {
+		t.Errorf("expected the connection to stay usable after an error reply, got %v", err)
+	}
+}
+
+func TestClientRetriesOnlyIdempotentCommands(t *testing.T) {
+	srv := newFakeServer(t, "")
+	c := New(Options{Addr: srv.ln.Addr().String()})
+	defer c.Close()
+	ctx := context.Background()
+
+	// the server runs the command and closes the pooled connection before
+	// replying, so retrying SetNX would report its own claim as taken
+	if err := c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv.mu.Lock()
+	srv.drop, srv.cmds = "SET", nil
+	srv.mu.Unlock()
+	if ok, err := c.SetNX(ctx, "lock", []byte("owner"), time.Minute); err == nil {
+		t.Errorf("SetNX() = %v, want the dropped connection reported", ok)
+	}
+	srv.mu.Lock()
+	if got := strings.Join(srv.cmds, " "); got != "SET" {
+		t.Errorf("server received %q, want SET once", got)
+	}
+	srv.drop, srv.cmds = "GET", nil
+	srv.mu.Unlock()
+
+	// reads are retried once on a fresh connection
+	if err := c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "lock"); err != nil || string(v) != "owner" {
+		t.Errorf("Get() = %q, %v, want a retried read", v, err)
+	}
+}
+
+func TestClientAuthError(t *testing.T) {
+	srv := newFakeServer(t, "secret")
+	c := New(Options{Addr: srv.ln.Addr().String(), Password: "wrong"})
+	if err := c.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("expected an auth error, got %v", err)
+	}
+}
+
+func TestNewFromEnvHealthCheck(t *testing.T) {
+	srv := newFakeServer(t, "")
+	t.Setenv("REDIS_URL", "redis://"+srv.ln.Addr().String())
+	c, err := NewFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	rec := httptest.NewRecorder()
+	faas.HealthHandler(time.Second).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"redis":"ok"`) {
+		t.Errorf("expected a passing redis health check, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,160 @@
+package faas
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The reference data is generated from the Debian iso-codes package.
+var (
+	//go:embed refdata/countries.csv
+	countriesCSV string
+	//go:embed refdata/currencies.csv
+	currenciesCSV string
+)
+
+// Country is an ISO 3166-1 country.
+type Country struct {
+	Alpha2  string `json:"alpha2"`
+	Alpha3  string `json:"alpha3"`
+	Numeric string `json:"numeric"`
+	Name    string `json:"name"`
+}
+
+// Currency is an ISO 4217 currency. MinorUnits is the number of decimal
+// places used by the currency, or -1 for codes such as gold (XAU) which have
+// none.
+type Currency struct {
+	Code       string `json:"code"`
+	Numeric    string `json:"numeric"`
+	MinorUnits int    `json:"minor_units"`
+	Name       string `json:"name"`
+	Symbol     string `json:"symbol,omitempty"`
+}
+
+// currencySymbols covers the currencies most commonly shown to users. Other
+// currencies are formatted with their code.
+var currencySymbols = map[string]string{
+	"AUD": "A$", "BRL": "R$", "CAD": "CA$", "CNY": "CN¥", "EUR": "€", "GBP": "£",
+	"HKD": "HK$", "ILS": "₪", "INR": "₹", "JPY": "¥", "KRW": "₩", "MXN": "MX$",
+	"NZD": "NZ$", "PHP": "₱", "THB": "฿", "TWD": "NT$", "USD": "$", "VND": "₫",
+}
+
+var (
+	refdataOnce  sync.Once
+	countries    []Country
+	countryIndex map[string]Country
+	currencies   map[string]Currency
+)
+
+func loadRefdata() {
+	refdataOnce.Do(func() {
+		countryIndex = make(map[string]Country)
+		for _, rec := range readRefdataCSV(countriesCSV) {
+			c := Country{Alpha2: rec[0], Alpha3: rec[1], Numeric: rec[2], Name: rec[3]}
+			countries = append(countries, c)
+			countryIndex[c.Alpha2] = c
+			countryIndex[c.Alpha3] = c
+			countryIndex[c.Numeric] = c
+		}
+
+		currencies = make(map[string]Currency)
+		for _, rec := range readRefdataCSV(currenciesCSV) {
+			units, _ := strconv.Atoi(rec[2])
+			currencies[rec[0]] = Currency{
+				Code:       rec[0],
+				Numeric:    rec[1],
+				MinorUnits: units,
+				Name:       rec[3],
+				Symbol:     currencySymbols[rec[0]],
+			}
+		}
+	})
+}
+
+func readRefdataCSV(data string) [][]string {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		// the data is embedded at build time so this is a programming error
+		panic(fmt.Sprintf("invalid embedded reference data: %v", err))
+	}
+	return records[1:]
+}
+
+// LookupCountry finds a country by its alpha-2, alpha-3 or numeric code. The
+// lookup is case insensitive.
+func LookupCountry(code string) (Country, bool) {
+	return lookupCountry(code)
+}
+func lookupCountry(code string) (Country, bool) {
+	loadRefdata()
+	c, ok := countryIndex[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// Countries returns every ISO 3166-1 country ordered by alpha-2 code.
+func Countries() []Country {
+	loadRefdata()
+	return append([]Country(nil), countries...)
+}
+
+// LookupCurrency finds a currency by its ISO 4217 code.
+func LookupCurrency(code string) (Currency, bool) {
+	return lookupCurrency(code)
+}
+func lookupCurrency(code string) (Currency, bool) {
+	loadRefdata()
+	c, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// FormatCurrency formats an amount given in the currency's minor units, e.g.
+// cents, such as FormatCurrency(123456, "USD") == "$1,234.56". Currencies
+// without a known symbol are prefixed with their code.
+func FormatCurrency(minor int64, code string) (string, error) {
+	return formatCurrency(minor, code)
+}
+func formatCurrency(minor int64, code string) (string, error) {
+	return formatCurrencyIn(minor, code, Locale("en"))
+}
+
+// ValidTimezone checks name is an IANA time zone such as "Australia/Sydney".
+// Functions built on scratch images should import time/tzdata so the zone
+// database is available.
+func ValidTimezone(name string) error {
+	return validTimezone(name)
+}
+func validTimezone(name string) error {
+	// LoadLocation accepts "" and "Local" which are not portable zone names
+	if name == "" || name == "Local" {
+		return fmt.Errorf("unknown time zone %q", name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown time zone %q", name)
+	}
+	return nil
+}
+
+func init() {
+	RegisterValidator("country", stringRule(func(s, _ string) error {
+		if _, ok := lookupCountry(s); !ok {
+			return errors.New("must be an ISO 3166-1 country code")
+		}
+		return nil
+	}))
+	RegisterValidator("currency", stringRule(func(s, _ string) error {
+		if _, ok := lookupCurrency(s); !ok {
+			return errors.New("must be an ISO 4217 currency code")
+		}
+		return nil
+	}))
+	RegisterValidator("timezone", stringRule(func(s, _ string) error {
+		return validTimezone(s)
+	}))
+}
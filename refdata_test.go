@@ -0,0 +1,56 @@
+package faas
+
+import "testing"
+
+func TestLookupCountry(t *testing.T) {
+	for _, code := range []string{"AU", "aus", "036"} {
+		c, ok := lookupCountry(code)
+		if !ok || c.Name != "Australia" {
+			t.Errorf("expected Australia for %s, got %+v", code, c)
+		}
+	}
+	if _, ok := lookupCountry("ZZ"); ok {
+		t.Error("expected ZZ to be unknown")
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		minor    int64
+		code     string
+		expected string
+	}{
+		{minor: 123456, code: "USD", expected: "$1,234.56"},
+		{minor: -5, code: "EUR", expected: "-€0.05"},
+		{minor: 1000, code: "JPY", expected: "¥1,000"},
+		{minor: 1234, code: "KWD", expected: "KWD 1.234"},
+		{minor: 1234567890, code: "CHF", expected: "CHF 12,345,678.90"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expected, func(t *testing.T) {
+			got, err := formatCurrency(tc.minor, tc.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+
+	if _, err := formatCurrency(1, "XYZ"); err == nil {
+		t.Error("expected an error for an unknown currency")
+	}
+}
+
+func TestValidTimezone(t *testing.T) {
+	if err := validTimezone("Australia/Sydney"); err != nil {
+		t.Errorf("didn't expected an error but got one: %v", err)
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus"} {
+		if err := validTimezone(name); err == nil {
+			t.Errorf("expected an error for %q", name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package faas
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTemplates is used by Render. Set its FS, typically an embed.FS, at
+// startup:
+//
+//	//go:embed templates
+//	var templates embed.FS
+//
+//	func init() {
+//		faas.DefaultTemplates.FS = templates
+//		faas.DefaultTemplates.Layout = "templates/layout.html"
+//	}
+var DefaultTemplates = &Templates{}
+
+// Templates loads and caches html/template pages from a file system.
+type Templates struct {
+	FS fs.FS
+	// Layout is an optional template wrapping every page. The pages define
+	// blocks which the layout renders, e.g. {{template "content" .}}.
+	Layout string
+	// Funcs are made available to every template.
+	Funcs template.FuncMap
+	// Reload parses the templates on every call instead of caching them,
+	// for editing them without restarts while developing.
+	Reload bool
+
+	mu    sync.Mutex
+	cache map[string]*template.Template
+}
+
+// Render writes the page tmplName of DefaultTemplates with data, see
+// Templates.Render.
+func Render(w http.ResponseWriter, status int, tmplName string, data any) error {
+	return DefaultTemplates.Render(w, status, tmplName, data)
+}
+
+// Render writes the page tmplName, a path in FS, executed with data. The
+// Content-Type is derived from the file extension, ignoring a ".tmpl"
+// suffix, and defaults to HTML. The page is rendered before anything is
+// written, so on error nothing is sent and the caller can respond with
+// WriteError instead.
+func (t *Templates) Render(w http.ResponseWriter, status int, tmplName string, data any) error {
+	start := time.Now()
+	tmpl, err := t.lookup(tmplName)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	// report rendering time when the response is wrapped by ServerTiming
+	if tw, ok := w.(interface{ timings() *Timings }); ok {
+		tw.timings().Add("render", time.Since(start))
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(strings.TrimSuffix(tmplName, ".tmpl")))
+	if contentType == "" {
+		contentType = "text/html; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+	return nil
+}
+
+func (t *Templates) lookup(name string) (*template.Template, error) {
+	if t.FS == nil {
+		return nil, errors.New("no template file system is set")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tmpl, ok := t.cache[name]; ok && !t.Reload {
+		return tmpl, nil
+	}
+
+	files := []string{name}
+	if t.Layout != "" {
+		files = []string{t.Layout, name}
+	}
+	tmpl, err := template.New(path.Base(files[0])).Funcs(t.Funcs).ParseFS(t.FS, files...)
+	if err != nil {
+		return nil, err
+	}
+	if t.cache == nil {
+		t.cache = make(map[string]*template.Template)
+	}
+	t.cache[name] = tmpl
+	return tmpl, nil
+}
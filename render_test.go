@@ -0,0 +1,107 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.html":      {Data: []byte(`<html><title>{{block "title" .}}Shop{{end}}</title><body>{{template "content" .}}</body></html>`)},
+		"unsubscribe.html": {Data: []byte(`{{define "title"}}Unsubscribe{{end}}{{define "content"}}<p>Bye {{.Name}}</p>{{end}}`)},
+		"form.html":        {Data: []byte(`{{define "content"}}<form>{{upper .Name}}</form>{{end}}`)},
+		"feed.json.tmpl":   {Data: []byte(`{{define "content"}}{}{{end}}`)},
+		"broken.html":      {Data: []byte(`{{define "content"}}{{.Missing.Field}}{{end}}`)},
+	}
+	tmpls := &Templates{FS: fsys, Layout: "layout.html", Funcs: map[string]any{"upper": strings.ToUpper}}
+
+	tests := []struct {
+		name            string
+		tmpl            string
+		data            any
+		wantErr         bool
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:            "page in layout",
+			tmpl:            "unsubscribe.html",
+			data:            map[string]string{"Name": "<Jane>"},
+			wantBody:        `<html><title>Unsubscribe</title><body><p>Bye &lt;Jane&gt;</p></body></html>`,
+			wantContentType: "text/html; charset=utf-8",
+		},
+		{
+			name:            "default block and funcs",
+			tmpl:            "form.html",
+			data:            map[string]string{"Name": "jane"},
+			wantBody:        `<html><title>Shop</title><body><form>JANE</form></body></html>`,
+			wantContentType: "text/html; charset=utf-8",
+		},
+		{
+			name:            "content type from extension",
+			tmpl:            "feed.json.tmpl",
+			wantContentType: "application/json",
+		},
+		{name: "missing template", tmpl: "missing.html", wantErr: true},
+		{name: "execution error", tmpl: "broken.html", data: map[string]any{"Missing": 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			err := tmpls.Render(w, http.StatusOK, tt.tmpl, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+					t.Error("response was written on error")
+				}
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %s, want %s", got, tt.wantContentType)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	fsys := fstest.MapFS{"page.html": {Data: []byte("v1")}}
+	tests := []struct {
+		name   string
+		reload bool
+		want   string
+	}{
+		{name: "cached", want: "v1"},
+		{name: "reload", reload: true, want: "v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys["page.html"] = &fstest.MapFile{Data: []byte("v1")}
+			tmpls := &Templates{FS: fsys, Reload: tt.reload}
+			if err := tmpls.Render(httptest.NewRecorder(), http.StatusOK, "page.html", nil); err != nil {
+				t.Fatal(err)
+			}
+			fsys["page.html"] = &fstest.MapFile{Data: []byte("v2")}
+			w := httptest.NewRecorder()
+			if err := tmpls.Render(w, http.StatusCreated, "page.html", nil); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != http.StatusCreated || w.Body.String() != tt.want {
+				t.Errorf("Render() = %d %s, want %s", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWithoutFS(t *testing.T) {
+	if err := (&Templates{}).Render(httptest.NewRecorder(), http.StatusOK, "page.html", nil); err == nil {
+		t.Error("Render() without FS returned no error")
+	}
+}
@@ -0,0 +1,110 @@
+package faas
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+)
+
+// ErrorReport describes a 5xx failure passed to an ErrorReporter.
+type ErrorReport struct {
+	Err    error
+	Status int
+	// Request is the failed request. It is nil when WriteError is called
+	// outside the Recover middleware.
+	Request *http.Request
+	// Stack holds the program counters where the AppError was created or
+	// the panic happened, for use with runtime.CallersFrames. It may be
+	// empty.
+	Stack []uintptr
+	Panic bool
+}
+
+// ErrorReporter sends failures to an error aggregation service such as
+// Sentry. Implementations should not block the response for long.
+type ErrorReporter interface {
+	Report(ctx context.Context, report ErrorReport)
+}
+
+// NoopReporter discards reports.
+type NoopReporter struct{}
+
+// Report implements ErrorReporter.
+func (NoopReporter) Report(context.Context, ErrorReport) {}
+
+// DefaultErrorReporter is called by WriteError and Recover for 5xx
+// failures. Replace it at startup, e.g. with the sentry sub-package.
+var DefaultErrorReporter ErrorReporter = NoopReporter{}
+
+// requestWriter carries the request to WriteError so reports include it.
+type requestWriter struct {
+	http.ResponseWriter
+	r *http.Request
+}
+
+func (w requestWriter) request() *http.Request { return w.r }
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w requestWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Recover is middleware which turns handler panics into a 500 JSON error,
+// logging and reporting them to DefaultErrorReporter. Errors written with
+// WriteError by its handlers are reported with the request attached.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			err, ok := p.(error)
+			if !ok {
+				err = fmt.Errorf("%v", p)
+			}
+			pcs := make([]uintptr, 32)
+			n := runtime.Callers(3, pcs)
+			slog.ErrorContext(r.Context(), "handler panic", "error", err, "path", r.URL.Path)
+			DefaultErrorReporter.Report(r.Context(), ErrorReport{
+				Err: err, Status: http.StatusInternalServerError, Request: r, Stack: pcs[:n], Panic: true,
+			})
+			if sw.status == 0 {
+				_ = writeJSONError(w, Error{
+					Status: http.StatusText(http.StatusInternalServerError),
+					Code:   http.StatusInternalServerError,
+				})
+			}
+		}()
+		next.ServeHTTP(requestWriter{ResponseWriter: sw, r: r}, r)
+	})
+}
+
+// reportError sends a failure written by WriteError to DefaultErrorReporter.
+func reportError(w http.ResponseWriter, status int, err error, appErr *AppError) {
+	report := ErrorReport{Err: err, Status: status}
+	ctx := context.Background()
+	// look through writers wrapped by middleware inside Recover
+	for w != nil {
+		if rw, ok := w.(interface{ request() *http.Request }); ok {
+			report.Request = rw.request()
+			ctx = report.Request.Context()
+			break
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	if appErr != nil {
+		report.Stack = appErr.stack
+	}
+	DefaultErrorReporter.Report(ctx, report)
+}
@@ -0,0 +1,84 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordingReporter struct {
+	reports []ErrorReport
+}
+
+func (r *recordingReporter) Report(_ context.Context, report ErrorReport) {
+	r.reports = append(r.reports, report)
+}
+
+func withReporter(t *testing.T) *recordingReporter {
+	t.Helper()
+	rec := &recordingReporter{}
+	prev := DefaultErrorReporter
+	DefaultErrorReporter = rec
+	t.Cleanup(func() { DefaultErrorReporter = prev })
+	return rec
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		status    int
+		reports   int
+		wantPanic bool
+	}{
+		{
+			name:    "panic",
+			handler: func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			status:  http.StatusInternalServerError, reports: 1, wantPanic: true,
+		},
+		{
+			name: "internal error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_ = WriteError(w, E(CodeInternal, "failed", errors.New("db down")))
+			},
+			status: http.StatusInternalServerError, reports: 1,
+		},
+		{
+			name: "client error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_ = WriteError(w, E(CodeNotFound, "missing", nil))
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name:    "success",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			status:  http.StatusOK,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter := withReporter(t)
+			rec := httptest.NewRecorder()
+			Recover(tc.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+			if rec.Code != tc.status {
+				t.Errorf("status = %d, want %d", rec.Code, tc.status)
+			}
+			if len(reporter.reports) != tc.reports {
+				t.Fatalf("got %d reports, want %d", len(reporter.reports), tc.reports)
+			}
+			if tc.reports == 0 {
+				return
+			}
+			report := reporter.reports[0]
+			if report.Request == nil || report.Request.URL.Path != "/orders" {
+				t.Error("expected the request to be attached to the report")
+			}
+			if report.Panic != tc.wantPanic || len(report.Stack) == 0 {
+				t.Errorf("unexpected report %+v", report)
+			}
+		})
+	}
+}
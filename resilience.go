@@ -0,0 +1,256 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen is returned when a call is rejected by an open
+	// CircuitBreaker.
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrRateLimited is returned when a call could not acquire a token from a
+	// RateLimiter before its context was done.
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
+
+type permanentError struct {
+	err error
+}
+
+func (p permanentError) Error() string { return p.err.Error() }
+func (p permanentError) Unwrap() error { return p.err }
+
+// Permanent marks err as not worth retrying. Retry returns it immediately.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// RetryPolicy configures Retry.
+type RetryPolicy struct {
+	// Attempts is the total number of calls made, including the first.
+	Attempts int `json:"attempts"`
+	// Backoff is the delay before the first retry. It doubles on every
+	// attempt up to MaxBackoff.
+	Backoff Duration `json:"backoff"`
+	// MaxBackoff caps the delay between attempts. Zero means no cap.
+	MaxBackoff Duration `json:"max_backoff"`
+	// Jitter randomises each delay between zero and its full value to avoid
+	// synchronised retries from many replicas.
+	Jitter bool `json:"jitter"`
+}
+
+// Retry calls fn until it succeeds, returns a Permanent error, the attempts
+// are used up or ctx is done. The last error is returned.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(context.Context) error) error {
+	return retry(ctx, policy, fn)
+}
+func retry(ctx context.Context, policy RetryPolicy, fn func(context.Context) error) error {
+	attempts := max(policy.Attempts, 1)
+	backoff := time.Duration(policy.Backoff)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || IsPermanent(err) || attempt >= attempts {
+			return err
+		}
+
+		delay := backoff
+		if policy.Jitter && delay > 0 {
+			delay = time.Duration(rand.Int63n(int64(delay) + 1))
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > time.Duration(policy.MaxBackoff) {
+			backoff = time.Duration(policy.MaxBackoff)
+		}
+	}
+}
+
+// CircuitPolicy configures a CircuitBreaker.
+type CircuitPolicy struct {
+	// FailureThreshold is the number of consecutive failures which opens
+	// the circuit.
+	FailureThreshold int `json:"failure_threshold"`
+	// OpenFor is how long the circuit stays open before a trial call is
+	// allowed through.
+	OpenFor Duration `json:"open_for"`
+}
+
+// CircuitBreaker stops calling a failing dependency for a while so it has a
+// chance to recover. It is safe for concurrent use.
+type CircuitBreaker struct {
+	policy CircuitPolicy
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+func NewCircuitBreaker(policy CircuitPolicy) *CircuitBreaker {
+	if policy.FailureThreshold <= 0 {
+		policy.FailureThreshold = 5
+	}
+	if policy.OpenFor <= 0 {
+		policy.OpenFor = Duration(30 * time.Second)
+	}
+	return &CircuitBreaker{policy: policy}
+}
+
+// Do calls fn unless the circuit is open, in which case ErrCircuitOpen is
+// returned without calling it. Permanent errors do not count as failures as
+// they indicate a bad request rather than an unhealthy dependency.
+func (c *CircuitBreaker) Do(ctx context.Context, fn func(context.Context) error) error {
+	if !c.allow() {
+		return ErrCircuitOpen
+	}
+	err := fn(ctx)
+	c.record(err == nil || IsPermanent(err))
+	return err
+}
+
+// Open reports whether calls are currently being rejected.
+func (c *CircuitBreaker) Open() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.openedAt.IsZero() && time.Since(c.openedAt) < time.Duration(c.policy.OpenFor)
+}
+
+func (c *CircuitBreaker) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.openedAt.IsZero() {
+		return true
+	}
+	if time.Since(c.openedAt) < time.Duration(c.policy.OpenFor) || c.trial {
+		return false
+	}
+	// half-open: let a single trial call through
+	c.trial = true
+	return true
+}
+
+func (c *CircuitBreaker) record(success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if success {
+		c.failures, c.openedAt, c.trial = 0, time.Time{}, false
+		return
+	}
+	c.failures++
+	if c.trial || c.failures >= c.policy.FailureThreshold {
+		c.openedAt, c.trial = time.Now(), false
+	}
+}
+
+// RateLimitPolicy configures a RateLimiter.
+type RateLimitPolicy struct {
+	Rate Rate `json:"rate"`
+	// Burst is the number of calls allowed at once. Defaults to Rate.Count.
+	Burst int `json:"burst"`
+}
+
+// RateLimiter is a token bucket limiter. It is safe for concurrent use.
+type RateLimiter struct {
+	interval time.Duration
+	burst    float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter with a full bucket.
+func NewRateLimiter(policy RateLimitPolicy) *RateLimiter {
+	burst := policy.Burst
+	if burst <= 0 {
+		burst = max(policy.Rate.Count, 1)
+	}
+	return &RateLimiter{
+		interval: policy.Rate.Interval(),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Allow takes a token if one is available without waiting.
+func (l *RateLimiter) Allow() bool {
+	return l.reserve(false) == 0
+}
+
+// Wait blocks until a token is available or ctx is done, in which case
+// ErrRateLimited is returned.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	delay := l.reserve(true)
+	if delay == 0 {
+		return nil
+	}
+	if d, ok := ctx.Deadline(); ok && time.Until(d) < delay {
+		l.cancel()
+		return ErrRateLimited
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.cancel()
+		return ErrRateLimited
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a token, going into debt when wait is true, and returns how
+// long the caller must wait before using it.
+func (l *RateLimiter) reserve(wait bool) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.interval <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	if !wait {
+		return -1
+	}
+	delay := time.Duration((1 - l.tokens) * float64(l.interval))
+	l.tokens--
+	return delay
+}
+
+// cancel returns a token taken by an abandoned Wait.
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(l.burst, l.tokens+1)
+}
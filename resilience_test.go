@@ -0,0 +1,95 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		err      error
+		calls    int
+		succeed  bool
+	}{
+		{name: "succeeds after retries", failures: 2, err: errors.New("boom"), calls: 3, succeed: true},
+		{name: "gives up", failures: 5, err: errors.New("boom"), calls: 3},
+		{name: "permanent error", failures: 5, err: Permanent(errors.New("bad request")), calls: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			err := retry(context.Background(), RetryPolicy{Attempts: 3, Backoff: Duration(time.Millisecond), Jitter: true},
+				func(context.Context) error {
+					calls++
+					if calls <= tc.failures {
+						return tc.err
+					}
+					return nil
+				})
+			if tc.succeed != (err == nil) {
+				t.Fatalf("expected success %v, got %v", tc.succeed, err)
+			}
+			if calls != tc.calls {
+				t.Errorf("expected %d calls, got %d", tc.calls, calls)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitPolicy{FailureThreshold: 2, OpenFor: Duration(20 * time.Millisecond)})
+	fail := func(context.Context) error { return errors.New("down") }
+	ok := func(context.Context) error { return nil }
+	ctx := context.Background()
+
+	_ = cb.Do(ctx, fail)
+	_ = cb.Do(ctx, fail)
+	if err := cb.Do(ctx, ok); err != ErrCircuitOpen {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if err := cb.Do(ctx, fail); err == ErrCircuitOpen {
+		t.Fatal("expected a trial call once half-open")
+	}
+	if err := cb.Do(ctx, ok); err != ErrCircuitOpen {
+		t.Fatalf("expected failed trial to reopen the circuit, got %v", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if err := cb.Do(ctx, ok); err != nil {
+		t.Fatalf("expected trial call to succeed, got %v", err)
+	}
+	if cb.Open() {
+		t.Error("expected circuit to be closed after a successful trial")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(RateLimitPolicy{Rate: Rate{Count: 100, Period: time.Second}, Burst: 2})
+	if !l.Allow() || !l.Allow() {
+		t.Fatal("expected burst of 2 to be allowed")
+	}
+	if l.Allow() {
+		t.Fatal("expected third call to be limited")
+	}
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 5*time.Millisecond {
+		t.Errorf("expected Wait to block for a token")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != ErrRateLimited {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package faas
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Redirect redirects the request to url with a 3xx code, defaulting to 302
+// Found for other codes. Callers accepting JSON get {"location": url}
+// instead of the HTML body written by http.Redirect.
+func Redirect(w http.ResponseWriter, r *http.Request, url string, code int) {
+	if code < 300 || code > 399 {
+		code = http.StatusFound
+	}
+	if !strings.Contains(r.Header.Get("Accept"), "application/json") {
+		http.Redirect(w, r, url, code)
+		return
+	}
+	_ = writeJSON(w, code, Map{"location": url}, http.Header{"Location": {url}})
+}
+
+// NoContent responds 204 No Content.
+func NoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Created responds 201 Created with the URL of the new resource in the
+// Location header, when not empty, and body as JSON, when not nil.
+func Created(w http.ResponseWriter, location string, body any) error {
+	if location != "" {
+		w.Header().Set("Location", location)
+	}
+	if body == nil {
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+	return writeJSON(w, http.StatusCreated, body, nil)
+}
+
+// Accepted responds 202 Accepted for work which completes later, with the
+// URL to poll for its status in the Location header and the body
+// {"status": "accepted", "status_url": statusURL}.
+func Accepted(w http.ResponseWriter, statusURL string) error {
+	body := Map{"status": "accepted"}
+	if statusURL != "" {
+		w.Header().Set("Location", statusURL)
+		body["status_url"] = statusURL
+	}
+	return writeJSON(w, http.StatusAccepted, body, nil)
+}
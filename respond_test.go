@@ -0,0 +1,86 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     string
+		code       int
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "browser", code: http.StatusSeeOther, wantStatus: http.StatusSeeOther, wantBody: "See Other"},
+		{name: "json client", accept: "application/json", code: http.StatusMovedPermanently, wantStatus: http.StatusMovedPermanently, wantBody: `{"location":"/new"}`},
+		{name: "invalid code", code: http.StatusOK, wantStatus: http.StatusFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/old", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			Redirect(w, r, "/new", tt.code)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != "/new" {
+				t.Errorf("Location = %q", got)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestStatusHelpers(t *testing.T) {
+	tests := []struct {
+		name         string
+		respond      func(http.ResponseWriter)
+		wantStatus   int
+		wantLocation string
+		wantBody     string
+	}{
+		{name: "no content", respond: NoContent, wantStatus: http.StatusNoContent},
+		{
+			name:         "created with body",
+			respond:      func(w http.ResponseWriter) { _ = Created(w, "/orders/1", Map{"id": "1"}) },
+			wantStatus:   http.StatusCreated,
+			wantLocation: "/orders/1",
+			wantBody:     `{"id":"1"}`,
+		},
+		{
+			name:         "created without body",
+			respond:      func(w http.ResponseWriter) { _ = Created(w, "/orders/1", nil) },
+			wantStatus:   http.StatusCreated,
+			wantLocation: "/orders/1",
+		},
+		{
+			name:         "accepted",
+			respond:      func(w http.ResponseWriter) { _ = Accepted(w, "/jobs/abc") },
+			wantStatus:   http.StatusAccepted,
+			wantLocation: "/jobs/abc",
+			wantBody:     `{"status":"accepted","status_url":"/jobs/abc"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.respond(w)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
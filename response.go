@@ -0,0 +1,183 @@
+package faas
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachePolicy is a Cache-Control header.
+type CachePolicy struct {
+	// MaxAge is how long any cache may reuse the response.
+	MaxAge time.Duration
+	// SharedMaxAge overrides MaxAge for shared caches such as CDNs.
+	SharedMaxAge time.Duration
+	// StaleWhileRevalidate is how long a stale response may still be served
+	// while it is revalidated in the background.
+	StaleWhileRevalidate time.Duration
+	Public               bool
+	Private              bool
+	NoCache              bool
+	NoStore              bool
+	MustRevalidate       bool
+	Immutable            bool
+}
+
+// String returns the Cache-Control value of the policy, such as
+// "public, max-age=60".
+func (p CachePolicy) String() string {
+	var directives []string
+	flag := func(set bool, name string) {
+		if set {
+			directives = append(directives, name)
+		}
+	}
+	seconds := func(d time.Duration, name string) {
+		if d > 0 {
+			directives = append(directives, name+"="+strconv.FormatInt(int64(d/time.Second), 10))
+		}
+	}
+	flag(p.Public, "public")
+	flag(p.Private, "private")
+	flag(p.NoCache, "no-cache")
+	flag(p.NoStore, "no-store")
+	seconds(p.MaxAge, "max-age")
+	seconds(p.SharedMaxAge, "s-maxage")
+	seconds(p.StaleWhileRevalidate, "stale-while-revalidate")
+	flag(p.MustRevalidate, "must-revalidate")
+	flag(p.Immutable, "immutable")
+	return strings.Join(directives, ", ")
+}
+
+// ResponseBuilder composes a response which is only sent by Write, so
+// headers, cookies and the cache policy can be given in any order without
+// being lost to an early WriteHeader:
+//
+//	return faas.Response().
+//		Status(http.StatusCreated).
+//		Header("Location", u).
+//		Cache(faas.CachePolicy{NoStore: true}).
+//		JSON(order).
+//		Write(w)
+//
+// The last body set wins.
+type ResponseBuilder struct {
+	status      int
+	header      http.Header
+	cookies     []*http.Cookie
+	cache       string
+	keys        []string
+	contentType string
+	body        []byte
+	json        any
+	isJSON      bool
+}
+
+// Response starts building a 200 OK response without a body.
+func Response() *ResponseBuilder {
+	return &ResponseBuilder{status: http.StatusOK, header: make(http.Header)}
+}
+
+// Status sets the status code.
+func (b *ResponseBuilder) Status(code int) *ResponseBuilder {
+	b.status = code
+	return b
+}
+
+// Header sets a header, replacing the values set earlier under the same
+// name. A Content-Type header overrides the one of the body, e.g. to send
+// JSON as application/problem+json.
+func (b *ResponseBuilder) Header(name, value string) *ResponseBuilder {
+	b.header.Set(name, value)
+	return b
+}
+
+// AddHeader adds a value to a header.
+func (b *ResponseBuilder) AddHeader(name, value string) *ResponseBuilder {
+	b.header.Add(name, value)
+	return b
+}
+
+// Cookie adds a Set-Cookie header. Invalid cookies are dropped, as by
+// http.SetCookie.
+func (b *ResponseBuilder) Cookie(c *http.Cookie) *ResponseBuilder {
+	b.cookies = append(b.cookies, c)
+	return b
+}
+
+// Cache sets the Cache-Control header.
+func (b *ResponseBuilder) Cache(p CachePolicy) *ResponseBuilder {
+	b.cache = p.String()
+	return b
+}
+
+// SurrogateKeys tags the response for CDN purges, see SurrogateKeys.
+func (b *ResponseBuilder) SurrogateKeys(keys ...string) *ResponseBuilder {
+	b.keys = append(b.keys, keys...)
+	return b
+}
+
+// JSON sets the body to v encoded as by WriteJSON.
+func (b *ResponseBuilder) JSON(v any) *ResponseBuilder {
+	b.json, b.isJSON, b.body = v, true, nil
+	b.contentType = "application/json"
+	return b
+}
+
+// Text sets a text/plain body.
+func (b *ResponseBuilder) Text(s string) *ResponseBuilder {
+	return b.Bytes("text/plain; charset=utf-8", []byte(s))
+}
+
+// Bytes sets the body to data of contentType, which is sniffed from data
+// when empty.
+func (b *ResponseBuilder) Bytes(contentType string, data []byte) *ResponseBuilder {
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	b.json, b.isJSON, b.body = nil, false, data
+	b.contentType = contentType
+	return b
+}
+
+// Write sends the response to w. JSON bodies are encoded first, so on
+// error nothing is written and the caller can respond with WriteError
+// instead.
+func (b *ResponseBuilder) Write(w http.ResponseWriter) error {
+	if b.status < 100 || b.status > 999 {
+		return fmt.Errorf("invalid response status %d", b.status)
+	}
+	body := b.body
+	if b.isJSON {
+		buf := jsonBufPool.Get().(*bytes.Buffer)
+		defer putJSONBuf(buf)
+		var err error
+		if body, err = encodeJSONBody(w, buf, b.json); err != nil {
+			return err
+		}
+	}
+
+	h := w.Header()
+	if b.contentType != "" {
+		h.Set("Content-Type", b.contentType)
+	}
+	for k, v := range b.header {
+		h[k] = v
+	}
+	for _, c := range b.cookies {
+		http.SetCookie(w, c)
+	}
+	if b.cache != "" {
+		h.Set("Cache-Control", b.cache)
+	}
+	SurrogateKeys(w, b.keys...)
+
+	w.WriteHeader(b.status)
+	if len(body) > 0 {
+		_, _ = w.Write(body)
+	}
+	return nil
+}
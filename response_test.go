@@ -0,0 +1,94 @@
+package faas
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponseBuilder(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := Response().
+		JSON(Map{"id": "1"}).
+		Cookie(&http.Cookie{Name: "session", Value: "abc", HttpOnly: true}).
+		Header("Location", "/orders/1").
+		Cache(CachePolicy{Private: true, MaxAge: time.Minute}).
+		SurrogateKeys("order-1").
+		Status(http.StatusCreated).
+		Write(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id":"1"}` {
+		t.Errorf("got %d %s", w.Code, w.Body)
+	}
+	want := map[string]string{
+		"Content-Type":  "application/json",
+		"Location":      "/orders/1",
+		"Set-Cookie":    "session=abc; HttpOnly",
+		"Cache-Control": "private, max-age=60",
+		"Surrogate-Key": "order-1",
+	}
+	for k, v := range want {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestResponseBuilderBodies(t *testing.T) {
+	tests := []struct {
+		name            string
+		build           *ResponseBuilder
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{name: "empty", build: Response().Status(http.StatusNoContent), wantStatus: http.StatusNoContent},
+		{name: "text", build: Response().Text("ok"), wantStatus: http.StatusOK, wantContentType: "text/plain; charset=utf-8", wantBody: "ok"},
+		{name: "sniffed bytes", build: Response().Bytes("", []byte("<html></html>")), wantStatus: http.StatusOK, wantContentType: "text/html; charset=utf-8", wantBody: "<html></html>"},
+		{
+			name:            "content type override",
+			build:           Response().Header("Content-Type", "application/problem+json").Status(http.StatusConflict).JSON(Map{"title": "conflict"}),
+			wantStatus:      http.StatusConflict,
+			wantContentType: "application/problem+json",
+			wantBody:        `{"title":"conflict"}`,
+		},
+		{name: "last body wins", build: Response().JSON(Map{"a": 1}).Text("b"), wantStatus: http.StatusOK, wantContentType: "text/plain; charset=utf-8", wantBody: "b"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		if err := tt.build.Write(w); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if w.Code != tt.wantStatus || w.Header().Get("Content-Type") != tt.wantContentType || w.Body.String() != tt.wantBody {
+			t.Errorf("%s: got %d %q %q", tt.name, w.Code, w.Header().Get("Content-Type"), w.Body)
+		}
+	}
+}
+
+func TestResponseBuilderErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := Response().Cookie(&http.Cookie{Name: "a", Value: "b"}).JSON(math.Inf(1)).Write(w)
+	if err == nil {
+		t.Fatal("expected an encoding error")
+	}
+	if len(w.Header()) != 0 || w.Body.Len() != 0 {
+		t.Errorf("wrote %v %q after an error", w.Header(), w.Body)
+	}
+	if err := Response().Status(42).Write(httptest.NewRecorder()); err == nil {
+		t.Error("expected an error for an invalid status")
+	}
+}
+
+func TestCachePolicy(t *testing.T) {
+	p := CachePolicy{Public: true, MaxAge: time.Hour, SharedMaxAge: 2 * time.Hour, StaleWhileRevalidate: 30 * time.Second, Immutable: true}
+	if want := "public, max-age=3600, s-maxage=7200, stale-while-revalidate=30, immutable"; p.String() != want {
+		t.Errorf("got %q, want %q", p, want)
+	}
+	if got := (CachePolicy{NoStore: true}).String(); got != "no-store" {
+		t.Errorf("no store = %q", got)
+	}
+}
@@ -0,0 +1,289 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSchemaNotFound is returned by a SchemaRegistry without a schema for the
+// subject.
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// SchemaRegistry looks up the JSON Schema of an event subject, such as a
+// topic name.
+type SchemaRegistry interface {
+	Schema(ctx context.Context, subject string) (*JSONSchema, error)
+}
+
+// SchemaDir is a SchemaRegistry reading "<subject>.json" files from a
+// directory, typically mounted from a ConfigMap. Schemas are cached after
+// the first read.
+type SchemaDir struct {
+	Dir string
+
+	mu    sync.Mutex
+	cache map[string]*JSONSchema
+}
+
+// Schema implements SchemaRegistry.
+func (d *SchemaDir) Schema(_ context.Context, subject string) (*JSONSchema, error) {
+	if subject == "" || strings.ContainsAny(subject, `/\`) || strings.Contains(subject, "..") {
+		return nil, E(CodeInvalidArgument, "invalid schema subject", nil).With("subject", subject)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if s, ok := d.cache[subject]; ok {
+		return s, nil
+	}
+	data, err := os.ReadFile(filepath.Join(d.Dir, subject+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSchemaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseJSONSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", subject, err)
+	}
+	if d.cache == nil {
+		d.cache = make(map[string]*JSONSchema)
+	}
+	d.cache[subject] = s
+	return s, nil
+}
+
+// ConfluentRegistry is a SchemaRegistry backed by a Confluent compatible
+// schema registry. The latest version of each subject is fetched and cached
+// for CacheTTL. Only subjects of schema type JSON are supported.
+type ConfluentRegistry struct {
+	URL string
+	// Client defaults to SharedHTTPClient.
+	Client *http.Client
+	// CacheTTL defaults to 5 minutes.
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedSchema
+}
+
+type cachedSchema struct {
+	schema  *JSONSchema
+	expires time.Time
+}
+
+// Schema implements SchemaRegistry.
+func (c *ConfluentRegistry) Schema(ctx context.Context, subject string) (*JSONSchema, error) {
+	c.mu.Lock()
+	cached, ok := c.cache[subject]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.schema, nil
+	}
+
+	var resp struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	u := strings.TrimSuffix(c.URL, "/") + "/subjects/" + url.PathEscape(subject) + "/versions/latest"
+	err := doJSON(ctx, c.Client, http.MethodGet, u, nil, &resp)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil, ErrSchemaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.SchemaType != "JSON" {
+		return nil, fmt.Errorf("%s: unsupported schema type %q", subject, resp.SchemaType)
+	}
+	s, err := ParseJSONSchema([]byte(resp.Schema))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", subject, err)
+	}
+
+	ttl := c.CacheTTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	c.mu.Lock()
+	if c.cache == nil {
+		c.cache = make(map[string]cachedSchema)
+	}
+	c.cache[subject] = cachedSchema{schema: s, expires: time.Now().Add(ttl)}
+	c.mu.Unlock()
+	return s, nil
+}
+
+// DefaultSchemaMetrics is used by SchemaValidator when no Metrics are set.
+var DefaultSchemaMetrics = NewSchemaMetrics()
+
+// SubjectStats are the schema validation counts for one subject.
+type SubjectStats struct {
+	Subject      string `json:"subject"`
+	Valid        int64  `json:"valid"`
+	Invalid      int64  `json:"invalid"`
+	DeadLettered int64  `json:"dead_lettered"`
+}
+
+// SchemaMetrics counts validation outcomes per subject. It is safe for
+// concurrent use.
+type SchemaMetrics struct {
+	mu       sync.Mutex
+	subjects map[string]*SubjectStats
+}
+
+// NewSchemaMetrics returns empty metrics.
+func NewSchemaMetrics() *SchemaMetrics {
+	return &SchemaMetrics{subjects: make(map[string]*SubjectStats)}
+}
+
+func (m *SchemaMetrics) record(subject string, fn func(*SubjectStats)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.subjects[subject]
+	if !ok {
+		s = &SubjectStats{Subject: subject}
+		m.subjects[subject] = s
+	}
+	fn(s)
+}
+
+// Snapshot returns the current counts for every subject, sorted by subject.
+func (m *SchemaMetrics) Snapshot() []SubjectStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]SubjectStats, 0, len(m.subjects))
+	for _, s := range m.subjects {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Subject < stats[j].Subject })
+	return stats
+}
+
+// ServeHTTP writes the snapshot as JSON.
+func (m *SchemaMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	_ = writeJSON(w, http.StatusOK, Map{"subjects": m.Snapshot()}, nil)
+}
+
+// SchemaValidator checks consumed and produced events against the schemas
+// in a registry.
+type SchemaValidator struct {
+	Registry SchemaRegistry
+	// DeadLetter receives consumed events which fail validation. When it is
+	// nil they are rejected with a 400 instead.
+	DeadLetter func(ctx context.Context, subject string, data []byte, err error) error
+	// AllowUnknown accepts events for subjects without a schema.
+	AllowUnknown bool
+	Metrics      *SchemaMetrics
+	// Body limits the events read by Middleware, as for ReadBody.
+	Body BodyOptions
+}
+
+// Validate checks data against the schema of subject. Invalid documents
+// return ValidationErrors. Use it before publishing events as well as when
+// consuming them.
+func (v *SchemaValidator) Validate(ctx context.Context, subject string, data []byte) error {
+	metrics := v.Metrics
+	if metrics == nil {
+		metrics = DefaultSchemaMetrics
+	}
+	s, err := v.Registry.Schema(ctx, subject)
+	if errors.Is(err, ErrSchemaNotFound) && v.AllowUnknown {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.Validate(data); err != nil {
+		metrics.record(subject, func(s *SubjectStats) { s.Invalid++ })
+		return err
+	}
+	metrics.record(subject, func(s *SubjectStats) { s.Valid++ })
+	return nil
+}
+
+// ValidateJSON marshals v and validates it against the schema of subject.
+func (v *SchemaValidator) ValidateJSON(ctx context.Context, subject string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return v.Validate(ctx, subject, data)
+}
+
+// EventSubject is the default subject function of SchemaValidator.Middleware.
+// It returns the X-Topic header set by the OpenFaaS connectors or the
+// CloudEvents type from Ce-Type.
+func EventSubject(r *http.Request) string {
+	if topic := r.Header.Get("X-Topic"); topic != "" {
+		return topic
+	}
+	return r.Header.Get("Ce-Type")
+}
+
+// Middleware validates request bodies before they reach the handler, using
+// subjectFunc, or EventSubject when nil, to pick the schema. Invalid events
+// are dead-lettered and acknowledged with 202 so the source does not
+// redeliver them, or rejected with 400 listing the invalid fields when no
+// DeadLetter is set. Bodies over the Body limit are rejected with 413.
+func (v *SchemaValidator) Middleware(subjectFunc func(*http.Request) string) func(http.Handler) http.Handler {
+	if subjectFunc == nil {
+		subjectFunc = EventSubject
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			data, err := ReadBody(w, r, v.Body)
+			var appErr *AppError
+			if err != nil && !errors.As(err, &appErr) {
+				err = E(CodeInvalidArgument, "reading event body", err)
+			}
+			if err != nil {
+				_ = writeError(w, err)
+				return
+			}
+			subject := subjectFunc(r)
+			if subject == "" {
+				_ = writeError(w, E(CodeInvalidArgument, "event has no subject", nil))
+				return
+			}
+			err = v.Validate(ctx, subject, data)
+			var invalid ValidationErrors
+			switch {
+			case err == nil:
+				r.Body = io.NopCloser(bytes.NewReader(data))
+				next.ServeHTTP(w, r)
+			case errors.As(err, &invalid) && v.DeadLetter != nil:
+				if dlErr := v.DeadLetter(ctx, subject, data, err); dlErr != nil {
+					_ = writeError(w, fmt.Errorf("dead-lettering %s event: %w", subject, dlErr))
+					return
+				}
+				metrics := v.Metrics
+				if metrics == nil {
+					metrics = DefaultSchemaMetrics
+				}
+				metrics.record(subject, func(s *SubjectStats) { s.DeadLettered++ })
+				_ = writeJSON(w, http.StatusAccepted, Map{"status": "dead_lettered"}, nil)
+			case errors.As(err, &invalid):
+				_ = writeError(w, err)
+			case errors.Is(err, ErrSchemaNotFound):
+				_ = writeError(w, E(CodeInvalidArgument, "no schema for event subject", err).With("subject", subject))
+			default:
+				_ = writeError(w, err)
+			}
+		})
+	}
+}
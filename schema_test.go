@@ -0,0 +1,106 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfluentRegistry(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/subjects/orders-value/versions/latest":
+			_ = writeJSON(w, http.StatusOK, Map{"subject": "orders-value", "version": 3, "schemaType": "JSON", "schema": `{"type": "object", "required": ["id"]}`}, nil)
+		case "/subjects/avro-value/versions/latest":
+			_ = writeJSON(w, http.StatusOK, Map{"schema": `{"type": "record"}`}, nil)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	reg := &ConfluentRegistry{URL: srv.URL, Client: srv.Client()}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		s, err := reg.Schema(ctx, "orders-value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Validate([]byte(`{}`)) == nil {
+			t.Error("expected the registry schema to be enforced")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the schema to be cached, got %d calls", calls)
+	}
+	if _, err := reg.Schema(ctx, "missing"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+	if _, err := reg.Schema(ctx, "avro-value"); err == nil {
+		t.Error("expected non JSON schemas to be rejected")
+	}
+}
+
+func TestSchemaValidatorMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "orders.json"), []byte(`{"type": "object", "required": ["id"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		topic      string
+		body       string
+		deadLetter bool
+		status     int
+		handled    bool
+	}{
+		{name: "valid", topic: "orders", body: `{"id": "1"}`, status: http.StatusOK, handled: true},
+		{name: "invalid", topic: "orders", body: `{}`, status: http.StatusBadRequest},
+		{name: "dead lettered", topic: "orders", body: `{}`, deadLetter: true, status: http.StatusAccepted},
+		{name: "unknown subject", topic: "refunds", body: `{}`, status: http.StatusBadRequest},
+		{name: "no subject", body: `{}`, status: http.StatusBadRequest},
+		{name: "invalid subject", topic: "../orders", body: `{}`, status: http.StatusBadRequest},
+		{name: "too large", topic: "orders", body: `{"id": "` + strings.Repeat("x", 1<<20) + `"}`, status: http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics := NewSchemaMetrics()
+			var dead []string
+			v := &SchemaValidator{Registry: &SchemaDir{Dir: dir}, Metrics: metrics}
+			if tc.deadLetter {
+				v.DeadLetter = func(_ context.Context, subject string, data []byte, _ error) error {
+					dead = append(dead, subject+":"+string(data))
+					return nil
+				}
+			}
+			handled := false
+			h := v.Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body Map
+				if err := ReadJSON(w, r, &body); err != nil {
+					t.Errorf("body not restored: %v", err)
+				}
+				handled = true
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req.Header.Set("X-Topic", tc.topic)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status || handled != tc.handled {
+				t.Fatalf("status = %d handled = %v, want %d %v: %s", rec.Code, handled, tc.status, tc.handled, rec.Body)
+			}
+			if tc.deadLetter && (len(dead) != 1 || metrics.Snapshot()[0].DeadLettered != 1) {
+				t.Errorf("expected one dead-lettered event, got %v %+v", dead, metrics.Snapshot())
+			}
+		})
+	}
+}
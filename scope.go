@@ -0,0 +1,115 @@
+package faas
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is returned by ScopeGroup.Wait when a goroutine panicked.
+type PanicError struct {
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// ScopeGroup runs goroutines bound to the lifetime of a handler, see Scope.
+type ScopeGroup[T any] struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	sem    chan struct{}
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	results []T
+	err     error
+}
+
+// Scope returns a group whose goroutines share a context derived from ctx,
+// for fanning out calls in a handler without leaking goroutines. The first
+// error or panic cancels the context of the others and is returned by Wait
+// along with the results, in the order the goroutines were started:
+//
+//	s := faas.Scope[*User](r.Context())
+//	for _, id := range ids {
+//		s.Go(func(ctx context.Context) (*User, error) { return fetchUser(ctx, id) })
+//	}
+//	users, err := s.Wait()
+func Scope[T any](ctx context.Context) *ScopeGroup[T] {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &ScopeGroup[T]{ctx: ctx, cancel: cancel}
+}
+
+// SetLimit bounds how many goroutines run at once, Go blocks while the limit
+// is reached. It must be called before Go.
+func (s *ScopeGroup[T]) SetLimit(n int) {
+	if n > 0 {
+		s.sem = make(chan struct{}, n)
+	}
+}
+
+// Go runs fn in a new goroutine with the scope's context. Once the scope is
+// cancelled fn is not started and its result is the zero value.
+func (s *ScopeGroup[T]) Go(fn func(ctx context.Context) (T, error)) {
+	s.mu.Lock()
+	i := len(s.results)
+	var zero T
+	s.results = append(s.results, zero)
+	s.mu.Unlock()
+
+	if s.sem != nil {
+		select {
+		case s.sem <- struct{}{}:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if s.sem != nil {
+			defer func() { <-s.sem }()
+		}
+		if s.ctx.Err() != nil {
+			return
+		}
+		v, err := s.run(fn)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err != nil {
+			if s.err == nil {
+				s.err = err
+				s.cancel(err)
+			}
+			return
+		}
+		s.results[i] = v
+	}()
+}
+
+func (s *ScopeGroup[T]) run(fn func(ctx context.Context) (T, error)) (v T, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = &PanicError{Value: rec, Stack: debug.Stack()}
+		}
+	}()
+	return fn(s.ctx)
+}
+
+// Wait blocks until every goroutine has returned and cancels the scope's
+// context. It returns the first error, or the parent context's error when it
+// was cancelled before all goroutines ran.
+func (s *ScopeGroup[T]) Wait() ([]T, error) {
+	s.wg.Wait()
+	err := s.err
+	if err == nil {
+		err = s.ctx.Err()
+	}
+	s.cancel(nil)
+	return s.results, err
+}
@@ -0,0 +1,66 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScope(t *testing.T) {
+	s := Scope[int](context.Background())
+	for i := 1; i <= 5; i++ {
+		n := i
+		s.Go(func(context.Context) (int, error) {
+			time.Sleep(time.Duration(5-n) * time.Millisecond)
+			return n * n, nil
+		})
+	}
+	got, err := s.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{1, 4, 9, 16, 25}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected results in start order %v, got %v", want, got)
+		}
+	}
+}
+
+func TestScopeCancelsOnError(t *testing.T) {
+	boom := errors.New("boom")
+	s := Scope[string](context.Background())
+	var cancelled atomic.Bool
+	started := make(chan struct{})
+	s.Go(func(ctx context.Context) (string, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			cancelled.Store(true)
+			return "", ctx.Err()
+		case <-time.After(time.Second):
+			return "slow", nil
+		}
+	})
+	<-started
+	s.Go(func(context.Context) (string, error) { return "", boom })
+	if _, err := s.Wait(); !errors.Is(err, boom) {
+		t.Errorf("expected the first error, got %v", err)
+	}
+	if !cancelled.Load() {
+		t.Error("expected the other goroutines to be cancelled")
+	}
+}
+
+func TestScopePanic(t *testing.T) {
+	s := Scope[int](context.Background())
+	s.SetLimit(1)
+	s.Go(func(context.Context) (int, error) { panic("oops") })
+	_, err := s.Wait()
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "oops" || len(panicErr.Stack) == 0 {
+		t.Errorf("expected a PanicError with a stack, got %v", err)
+	}
+}
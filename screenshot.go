@@ -0,0 +1,326 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScreenshotOptions configures a screenshot taken by a Screenshotter.
+type ScreenshotOptions struct {
+	// Width and Height are the viewport size in pixels. They default to
+	// 1280 by 800.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// FullPage captures the whole scrollable page instead of the viewport.
+	FullPage bool `json:"full_page,omitempty"`
+	// Format is "png", the default, "jpeg" or "webp".
+	Format string `json:"format,omitempty"`
+	// Quality, from 1 to 100, applies to jpeg and webp.
+	Quality int `json:"quality,omitempty"`
+	// WaitDelay waits after the page has loaded, for pages which render
+	// with JavaScript.
+	WaitDelay Duration `json:"wait_delay,omitempty"`
+}
+
+// Screenshotter renders web pages to images with a headless browser.
+type Screenshotter interface {
+	// ScreenshotURL captures the page at an http or https URL.
+	ScreenshotURL(ctx context.Context, pageURL string, opts ScreenshotOptions) ([]byte, error)
+	// ScreenshotHTML captures an HTML document.
+	ScreenshotHTML(ctx context.Context, html string, opts ScreenshotOptions) ([]byte, error)
+}
+
+// ScreenshotLimits are the guardrails of the Screenshotter implementations,
+// so a user supplied page cannot hold the function or the browser for long
+// or return an unbounded image.
+type ScreenshotLimits struct {
+	// Timeout bounds each screenshot, including loading the page. Defaults
+	// to 30 seconds.
+	Timeout time.Duration
+	// MaxBytes is the largest image accepted. Defaults to 10MB.
+	MaxBytes int64
+	// MaxWidth and MaxHeight bound the viewport. They default to 4096.
+	MaxWidth  int
+	MaxHeight int
+	// MaxWaitDelay bounds ScreenshotOptions.WaitDelay. Defaults to 10
+	// seconds.
+	MaxWaitDelay time.Duration
+	// AllowPrivateNetworks lets pages on loopback, private and link-local
+	// addresses be captured, which are otherwise rejected so a caller
+	// cannot make the in-cluster browser read internal services or cloud
+	// metadata.
+	AllowPrivateNetworks bool
+}
+
+// ErrScreenshotTooLarge is returned for images larger than
+// ScreenshotLimits.MaxBytes.
+var ErrScreenshotTooLarge = errors.New("screenshot is too large")
+
+// check validates opts against the limits, filling in the defaults.
+func (l ScreenshotLimits) check(opts *ScreenshotOptions) error {
+	opts.Width = defaultInt(opts.Width, 1280)
+	opts.Height = defaultInt(opts.Height, 800)
+	opts.Format = defaultString(opts.Format, "png")
+	if opts.Width < 0 || opts.Width > defaultInt(l.MaxWidth, 4096) || opts.Height < 0 || opts.Height > defaultInt(l.MaxHeight, 4096) {
+		return E(CodeInvalidArgument, "viewport is out of bounds", nil).With("width", opts.Width).With("height", opts.Height)
+	}
+	switch opts.Format {
+	case "png", "jpeg", "webp":
+	default:
+		return E(CodeInvalidArgument, "unsupported screenshot format", nil).With("format", opts.Format)
+	}
+	if opts.Quality < 0 || opts.Quality > 100 {
+		return E(CodeInvalidArgument, "quality must be between 1 and 100", nil)
+	}
+	if time.Duration(opts.WaitDelay) > defaultDuration(l.MaxWaitDelay, 10*time.Second) {
+		return E(CodeInvalidArgument, "wait delay is too long", nil)
+	}
+	return nil
+}
+
+// lookupPageHost resolves the hosts of pages, replaced in tests.
+var lookupPageHost = net.DefaultResolver.LookupIPAddr
+
+// checkPageURL only lets through http and https URLs, so a page cannot be
+// read from the browser's file system, whose host resolves to public
+// addresses unless private networks are allowed. The browser resolves the
+// host again, so this does not stop a DNS server answering differently
+// the second time.
+func (l ScreenshotLimits) checkPageURL(ctx context.Context, pageURL string) error {
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return E(CodeInvalidArgument, "url must be an absolute http or https URL", err)
+	}
+	if l.AllowPrivateNetworks {
+		return nil
+	}
+	host := u.Hostname()
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := lookupPageHost(ctx, host)
+		if err != nil {
+			return E(CodeInvalidArgument, "url host cannot be resolved", err).With("host", host)
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return E(CodeInvalidArgument, "url must not point to a private network", nil).With("host", host)
+		}
+	}
+	return nil
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address.
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() ||
+		// carrier-grade NAT, used by some clusters for pod networks
+		(ip.To4() != nil && ip.To4()[0] == 100 && ip.To4()[1]&0xc0 == 64))
+}
+
+// errorURL is the URL of req as written in errors, without credentials or
+// query parameters which may hold tokens.
+func errorURL(u *url.URL) string {
+	clean := *u
+	clean.User, clean.RawQuery, clean.ForceQuery = nil, "", false
+	return clean.String()
+}
+
+// do sends a screenshot request built by newReq within the limits and
+// returns the image.
+func (l ScreenshotLimits) do(ctx context.Context, client *http.Client, newReq func(context.Context) (*http.Request, error)) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultDuration(l.Timeout, 30*time.Second))
+	defer cancel()
+	req, err := newReq(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = SharedHTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = errorURL(req.URL)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySnippet))
+		return nil, &StatusError{Method: req.Method, URL: errorURL(req.URL), StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(snippet))}
+	}
+	maxBytes := l.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 10 << 20
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, ErrScreenshotTooLarge
+	}
+	img, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(img)) > maxBytes {
+		return nil, ErrScreenshotTooLarge
+	}
+	return img, nil
+}
+
+// GotenbergScreenshotter takes screenshots with the Chromium module of a
+// Gotenberg (https://gotenberg.dev) sidecar or service.
+type GotenbergScreenshotter struct {
+	// URL is the base URL of Gotenberg, e.g. http://localhost:3000.
+	URL string
+	// Client defaults to SharedHTTPClient.
+	Client *http.Client
+	Limits ScreenshotLimits
+}
+
+// ScreenshotURL implements Screenshotter.
+func (g *GotenbergScreenshotter) ScreenshotURL(ctx context.Context, pageURL string, opts ScreenshotOptions) ([]byte, error) {
+	if err := g.Limits.checkPageURL(ctx, pageURL); err != nil {
+		return nil, err
+	}
+	return g.screenshot(ctx, "url", opts, func(mw *multipart.Writer) error {
+		return mw.WriteField("url", pageURL)
+	})
+}
+
+// ScreenshotHTML implements Screenshotter.
+func (g *GotenbergScreenshotter) ScreenshotHTML(ctx context.Context, html string, opts ScreenshotOptions) ([]byte, error) {
+	return g.screenshot(ctx, "html", opts, func(mw *multipart.Writer) error {
+		fw, err := mw.CreateFormFile("files", "index.html")
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(fw, html)
+		return err
+	})
+}
+
+func (g *GotenbergScreenshotter) screenshot(ctx context.Context, route string, opts ScreenshotOptions, page func(*multipart.Writer) error) ([]byte, error) {
+	if err := g.Limits.check(&opts); err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := page(mw); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{
+		"width":  strconv.Itoa(opts.Width),
+		"height": strconv.Itoa(opts.Height),
+		"format": opts.Format,
+		// clipping to the viewport is what makes it not a full page
+		"clip": strconv.FormatBool(!opts.FullPage),
+	}
+	if opts.Quality > 0 && opts.Format != "png" {
+		fields["quality"] = strconv.Itoa(opts.Quality)
+	}
+	if opts.WaitDelay > 0 {
+		fields["waitDelay"] = time.Duration(opts.WaitDelay).String()
+	}
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	endpoint := strings.TrimSuffix(g.URL, "/") + "/forms/chromium/screenshot/" + route
+	return g.Limits.do(ctx, g.Client, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req, nil
+	})
+}
+
+// BrowserlessScreenshotter takes screenshots with the /screenshot API of a
+// browserless (https://browserless.io) sidecar or service.
+type BrowserlessScreenshotter struct {
+	// URL is the base URL of browserless, e.g. http://localhost:3000.
+	URL string
+	// TokenSecret is the name of the secret holding the API token, sent as
+	// a bearer token. Leave it empty for instances without a token.
+	TokenSecret string
+	// Client defaults to SharedHTTPClient.
+	Client *http.Client
+	Limits ScreenshotLimits
+}
+
+// ScreenshotURL implements Screenshotter.
+func (b *BrowserlessScreenshotter) ScreenshotURL(ctx context.Context, pageURL string, opts ScreenshotOptions) ([]byte, error) {
+	if err := b.Limits.checkPageURL(ctx, pageURL); err != nil {
+		return nil, err
+	}
+	return b.screenshot(ctx, Map{"url": pageURL}, opts)
+}
+
+// ScreenshotHTML implements Screenshotter.
+func (b *BrowserlessScreenshotter) ScreenshotHTML(ctx context.Context, html string, opts ScreenshotOptions) ([]byte, error) {
+	return b.screenshot(ctx, Map{"html": html}, opts)
+}
+
+func (b *BrowserlessScreenshotter) screenshot(ctx context.Context, payload Map, opts ScreenshotOptions) ([]byte, error) {
+	if err := b.Limits.check(&opts); err != nil {
+		return nil, err
+	}
+	options := Map{"type": opts.Format, "fullPage": opts.FullPage}
+	if opts.Quality > 0 && opts.Format != "png" {
+		options["quality"] = opts.Quality
+	}
+	payload["options"] = options
+	payload["viewport"] = Map{"width": opts.Width, "height": opts.Height}
+	// keep the browser's own timeout inside ours so it gives up first
+	payload["gotoOptions"] = Map{"timeout": defaultDuration(b.Limits.Timeout, 30*time.Second).Milliseconds()}
+	if opts.WaitDelay > 0 {
+		payload["waitForTimeout"] = time.Duration(opts.WaitDelay).Milliseconds()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := strings.TrimSuffix(b.URL, "/") + "/screenshot"
+	var token string
+	if b.TokenSecret != "" {
+		if token, err = getSecretString(b.TokenSecret); err != nil {
+			return nil, fmt.Errorf("reading browserless token: %w", err)
+		}
+	}
+	return b.Limits.do(ctx, b.Client, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		// a header rather than the ?token= parameter keeps the token out of
+		// URLs in errors and access logs
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req, nil
+	})
+}
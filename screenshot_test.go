@@ -0,0 +1,208 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fakePNG = []byte("\x89PNG\r\n\x1a\nimage")
+
+// fakePageDNS resolves example.com to a public address and internal.test
+// to a private one, so the tests need no network.
+func fakePageDNS(t *testing.T) {
+	t.Helper()
+	old := lookupPageHost
+	lookupPageHost = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}}, nil
+		case "internal.test":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}, {IP: net.ParseIP("10.0.0.7")}}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	t.Cleanup(func() { lookupPageHost = old })
+}
+
+func TestGotenbergScreenshotter(t *testing.T) {
+	fakePageDNS(t)
+	var fields map[string][]string
+	var html string
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+		}
+		fields = r.MultipartForm.Value
+		if fh := r.MultipartForm.File["files"]; len(fh) == 1 {
+			f, _ := fh[0].Open()
+			data, _ := io.ReadAll(f)
+			html = fh[0].Filename + ":" + string(data)
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(fakePNG)
+	}))
+	defer srv.Close()
+	g := &GotenbergScreenshotter{URL: srv.URL, Client: srv.Client()}
+
+	img, err := g.ScreenshotURL(context.Background(), "https://example.com", ScreenshotOptions{Format: "jpeg", Quality: 80, WaitDelay: Duration(time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(img) != string(fakePNG) {
+		t.Errorf("image = %q", img)
+	}
+	if path != "/forms/chromium/screenshot/url" {
+		t.Errorf("path = %q", path)
+	}
+	want := map[string]string{"url": "https://example.com", "width": "1280", "height": "800", "format": "jpeg", "quality": "80", "clip": "true", "waitDelay": "1s"}
+	for k, v := range want {
+		if got := fields[k]; len(got) != 1 || got[0] != v {
+			t.Errorf("field %s = %v, want %q", k, got, v)
+		}
+	}
+
+	if _, err := g.ScreenshotHTML(context.Background(), "<h1>Hi</h1>", ScreenshotOptions{FullPage: true}); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/forms/chromium/screenshot/html" || html != "index.html:<h1>Hi</h1>" {
+		t.Errorf("path = %q, html = %q", path, html)
+	}
+	if got := fields["clip"]; len(got) != 1 || got[0] != "false" {
+		t.Errorf("clip = %v for a full page", got)
+	}
+}
+
+func TestBrowserlessScreenshotter(t *testing.T) {
+	fakePageDNS(t)
+	withSecrets(t, map[string]string{"browserless-token": "s3cret"})
+	var payload map[string]any
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		if r.URL.RawQuery != "" {
+			t.Errorf("query = %q, want the token in a header", r.URL.RawQuery)
+		}
+		if r.URL.Path != "/screenshot" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write(fakePNG)
+	}))
+	defer srv.Close()
+	b := &BrowserlessScreenshotter{URL: srv.URL, TokenSecret: "browserless-token", Client: srv.Client(), Limits: ScreenshotLimits{Timeout: 5 * time.Second}}
+
+	if _, err := b.ScreenshotURL(context.Background(), "http://example.com/page", ScreenshotOptions{Width: 800, Height: 600, FullPage: true}); err != nil {
+		t.Fatal(err)
+	}
+	if token != "Bearer s3cret" {
+		t.Errorf("token = %q", token)
+	}
+	got, _ := json.Marshal(payload)
+	want := `{"gotoOptions":{"timeout":5000},"options":{"fullPage":true,"type":"png"},"url":"http://example.com/page","viewport":{"height":600,"width":800}}`
+	if string(got) != want {
+		t.Errorf("payload = %s, want %s", got, want)
+	}
+
+	if _, err := b.ScreenshotHTML(context.Background(), "<p>x</p>", ScreenshotOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if payload["html"] != "<p>x</p>" {
+		t.Errorf("html = %v", payload["html"])
+	}
+}
+
+func TestScreenshotLimits(t *testing.T) {
+	fakePageDNS(t)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		r.ParseMultipartForm(1 << 20)
+		switch r.FormValue("url") {
+		case "https://example.com/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		case "https://example.com/fail":
+			http.Error(w, "chromium crashed", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(strings.Repeat("x", 2048)))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		opts    ScreenshotOptions
+		limits  ScreenshotLimits
+		wantErr func(error) bool
+		noCall  bool
+	}{
+		{"file url", "file:///etc/passwd", ScreenshotOptions{}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"relative url", "/admin", ScreenshotOptions{}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"loopback", "http://127.0.0.1:8080/admin", ScreenshotOptions{}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"metadata", "http://169.254.169.254/latest/meta-data/", ScreenshotOptions{}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"ipv6 loopback", "http://[::1]/", ScreenshotOptions{}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"resolves to private", "http://internal.test/", ScreenshotOptions{}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"unresolvable", "http://nowhere.test/", ScreenshotOptions{}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"private allowed", "http://10.0.0.7/", ScreenshotOptions{}, ScreenshotLimits{AllowPrivateNetworks: true}, func(err error) bool { return err == nil }, false},
+		{"wide viewport", "https://example.com", ScreenshotOptions{Width: 10000}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"format", "https://example.com", ScreenshotOptions{Format: "gif"}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"wait delay", "https://example.com", ScreenshotOptions{WaitDelay: Duration(time.Minute)}, ScreenshotLimits{}, isInvalidArgument, true},
+		{"image too large", "https://example.com", ScreenshotOptions{}, ScreenshotLimits{MaxBytes: 1024}, func(err error) bool { return errors.Is(err, ErrScreenshotTooLarge) }, false},
+		{"timeout", "https://example.com/slow", ScreenshotOptions{}, ScreenshotLimits{Timeout: 50 * time.Millisecond}, func(err error) bool { return errors.Is(err, context.DeadlineExceeded) }, false},
+		{"service error", "https://example.com/fail", ScreenshotOptions{}, ScreenshotLimits{}, func(err error) bool {
+			var se *StatusError
+			return errors.As(err, &se) && se.StatusCode == http.StatusServiceUnavailable
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			g := &GotenbergScreenshotter{URL: srv.URL, Client: srv.Client(), Limits: tt.limits}
+			_, err := g.ScreenshotURL(context.Background(), tt.url, tt.opts)
+			if !tt.wantErr(err) {
+				t.Errorf("err = %v", err)
+			}
+			if n := calls.Load(); tt.noCall && n != 0 {
+				t.Errorf("service called %d times for a rejected request", n)
+			}
+		})
+	}
+}
+
+func isInvalidArgument(err error) bool {
+	var appErr *AppError
+	return errors.As(err, &appErr) && appErr.Code == CodeInvalidArgument
+}
+
+func TestScreenshotErrorsHideToken(t *testing.T) {
+	fakePageDNS(t)
+	withSecrets(t, map[string]string{"browserless-token": "s3cret"})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	b := &BrowserlessScreenshotter{URL: srv.URL, TokenSecret: "browserless-token", Client: srv.Client()}
+	_, err := b.ScreenshotURL(context.Background(), "https://example.com", ScreenshotOptions{})
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("err = %v", err)
+	}
+
+	if got := errorURL(&url.URL{Scheme: "http", Host: "b:3000", Path: "/screenshot", RawQuery: "token=s3cret"}); got != "http://b:3000/screenshot" {
+		t.Errorf("errorURL = %q", got)
+	}
+}
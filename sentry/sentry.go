@@ -0,0 +1,209 @@
+// Package sentry reports function failures to Sentry using its HTTP store
+// API. Install it with:
+//
+//	reporter, err := sentry.NewFromEnv()
+//	if err != nil {
+//		return err
+//	}
+//	faas.DefaultErrorReporter = reporter
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// sendTimeout bounds how long a report can delay a response.
+const sendTimeout = 2 * time.Second
+
+// Reporter is a faas.ErrorReporter sending events to a Sentry project.
+type Reporter struct {
+	// Environment and Release are attached to every event.
+	Environment string
+	Release     string
+	// Client defaults to faas.SharedHTTPClient.
+	Client *http.Client
+
+	endpoint string
+	auth     string
+}
+
+// New returns a Reporter for a DSN of the form
+// https://<key>@<host>/<project id>.
+func New(dsn string) (*Reporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry DSN: %w", err)
+	}
+	key := u.User.Username()
+	project := strings.TrimPrefix(u.Path, "/")
+	if key == "" || project == "" || u.Host == "" {
+		return nil, errors.New("invalid sentry DSN, expected https://<key>@<host>/<project id>")
+	}
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	return &Reporter{
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+		auth:     "Sentry sentry_version=7, sentry_client=go-faas/1.0, sentry_key=" + key,
+	}, nil
+}
+
+// NewFromEnv returns a Reporter configured by SENTRY_DSN, SENTRY_ENVIRONMENT
+// and SENTRY_RELEASE. When SENTRY_DSN is unset faas.NoopReporter is returned
+// so local runs need no configuration.
+func NewFromEnv() (faas.ErrorReporter, error) {
+	dsn := os.Getenv("SENTRY_DSN")
+	if dsn == "" {
+		return faas.NoopReporter{}, nil
+	}
+	r, err := New(dsn)
+	if err != nil {
+		return nil, err
+	}
+	r.Environment = os.Getenv("SENTRY_ENVIRONMENT")
+	r.Release = os.Getenv("SENTRY_RELEASE")
+	return r, nil
+}
+
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Exception   []exception       `json:"exception"`
+	Request     *request          `json:"request,omitempty"`
+}
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+}
+
+type request struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Report implements faas.ErrorReporter. Failures to send are logged.
+func (s *Reporter) Report(ctx context.Context, report faas.ErrorReport) {
+	ev := newEvent(report)
+	ev.Environment, ev.Release = s.Environment, s.Release
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	client := s.Client
+	if client == nil {
+		client = faas.SharedHTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		slog.WarnContext(ctx, "sentry report failed", "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.WarnContext(ctx, "sentry report rejected", "status", resp.StatusCode)
+	}
+}
+
+// headersSent are the request headers included in events. Others may carry
+// credentials.
+var headersSent = []string{"User-Agent", "Content-Type", "X-Call-Id", "X-Request-Id"}
+
+func newEvent(report faas.ErrorReport) event {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	ev := event{
+		EventID:   hex.EncodeToString(b),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Level:     "error",
+		Platform:  "go",
+		Tags:      map[string]string{"status": fmt.Sprint(report.Status)},
+	}
+	if name := os.Getenv("function_name"); name != "" {
+		ev.ServerName = name
+	} else {
+		ev.ServerName, _ = os.Hostname()
+	}
+	if report.Panic {
+		ev.Level = "fatal"
+		ev.Tags["panic"] = "true"
+	}
+
+	ex := exception{Type: "error", Value: http.StatusText(report.Status)}
+	if report.Err != nil {
+		ex.Type, ex.Value = reflect.TypeOf(report.Err).String(), report.Err.Error()
+	}
+	if len(report.Stack) > 0 {
+		ex.Stacktrace = &stacktrace{}
+		frames := runtime.CallersFrames(report.Stack)
+		for {
+			f, more := frames.Next()
+			ex.Stacktrace.Frames = append(ex.Stacktrace.Frames, frame{Function: f.Function, Filename: f.File, Lineno: f.Line})
+			if !more {
+				break
+			}
+		}
+		// Sentry expects the innermost frame last
+		for i, j := 0, len(ex.Stacktrace.Frames)-1; i < j; i, j = i+1, j-1 {
+			ex.Stacktrace.Frames[i], ex.Stacktrace.Frames[j] = ex.Stacktrace.Frames[j], ex.Stacktrace.Frames[i]
+		}
+	}
+	ev.Exception = []exception{ex}
+
+	if r := report.Request; r != nil {
+		// the query and user info may carry credentials
+		u := *r.URL
+		u.User, u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = nil, "", false, "", ""
+		ev.Request = &request{URL: u.String(), Method: r.Method, Headers: map[string]string{}}
+		for _, h := range headersSent {
+			if v := r.Header.Get(h); v != "" {
+				ev.Request.Headers[h] = v
+			}
+		}
+	}
+	return ev
+}
@@ -0,0 +1,88 @@
+package sentry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		dsn       string
+		endpoint  string
+		expectErr bool
+	}{
+		{name: "valid", dsn: "https://abc@o1.ingest.sentry.io/42", endpoint: "https://o1.ingest.sentry.io/api/42/store/"},
+		{name: "path prefix", dsn: "https://abc@sentry.example.com/sentry/7", endpoint: "https://sentry.example.com/sentry/api/7/store/"},
+		{name: "missing key", dsn: "https://sentry.io/42", expectErr: true},
+		{name: "missing project", dsn: "https://abc@sentry.io/", expectErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := New(tc.dsn)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("New() error = %v, expectErr %v", err, tc.expectErr)
+			}
+			if err == nil && r.endpoint != tc.endpoint {
+				t.Errorf("endpoint = %q, want %q", r.endpoint, tc.endpoint)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	var got event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("X-Sentry-Auth"), "sentry_key=abc") {
+			t.Errorf("missing auth header: %q", r.Header.Get("X-Sentry-Auth"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	r, err := New(strings.Replace(srv.URL, "://", "://abc@", 1) + "/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Client = srv.Client()
+	r.Environment = "test"
+
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(1, pcs)
+	req := httptest.NewRequest(http.MethodPost, "/orders?token=secret", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	r.Report(context.Background(), faas.ErrorReport{
+		Err: errors.New("db down"), Status: 500, Request: req, Stack: pcs[:n],
+	})
+
+	if got.Environment != "test" || len(got.Exception) != 1 || got.Exception[0].Value != "db down" {
+		t.Fatalf("unexpected event %+v", got)
+	}
+	frames := got.Exception[0].Stacktrace.Frames
+	if !strings.HasSuffix(frames[len(frames)-1].Function, "TestReport") {
+		t.Errorf("expected the innermost frame last, got %+v", frames)
+	}
+	if got.Request == nil || got.Request.Method != http.MethodPost || got.Request.URL != "/orders" {
+		t.Errorf("unexpected request %+v", got.Request)
+	}
+	if _, ok := got.Request.Headers["Authorization"]; ok {
+		t.Error("credentials must not be sent")
+	}
+}
+
+func TestReportNilError(t *testing.T) {
+	ev := newEvent(faas.ErrorReport{Status: http.StatusInternalServerError})
+	if ev.Exception[0].Value != "Internal Server Error" {
+		t.Errorf("unexpected exception %+v", ev.Exception[0])
+	}
+}
@@ -0,0 +1,37 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []func(context.Context) error
+)
+
+// OnShutdown registers fn to run when Shutdown is called, e.g. to close a
+// database pool. Hooks run in reverse order of registration so resources
+// are released before the ones they depend on.
+func OnShutdown(fn func(ctx context.Context) error) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, fn)
+}
+
+// Shutdown runs the hooks registered with OnShutdown, once each, and
+// returns their errors joined. Call it once the server has stopped serving,
+// typically after http.Server.Shutdown on SIGTERM.
+func Shutdown(ctx context.Context) error {
+	shutdownMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	shutdownMu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		errs = append(errs, hooks[i](ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,24 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestShutdown(t *testing.T) {
+	var order []int
+	boom := errors.New("boom")
+	OnShutdown(func(context.Context) error { order = append(order, 1); return nil })
+	OnShutdown(func(context.Context) error { order = append(order, 2); return boom })
+
+	if err := Shutdown(context.Background()); !errors.Is(err, boom) {
+		t.Errorf("expected the hook error, got %v", err)
+	}
+	if len(order) != 2 || order[0] != 2 {
+		t.Errorf("expected hooks to run in reverse order, got %v", order)
+	}
+	if err := Shutdown(context.Background()); err != nil || len(order) != 2 {
+		t.Errorf("expected hooks to run once, got %v", order)
+	}
+}
@@ -0,0 +1,236 @@
+// Package sqlstore implements the faas stores on Postgres or MySQL, for
+// teams which already run a database but not Redis:
+//
+//	db, err := faas.OpenDB("pgx", "db-dsn", faas.DBOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	store, err := sqlstore.New(db, sqlstore.Postgres, "")
+//	if err != nil {
+//		return err
+//	}
+//	if err := store.CreateTable(ctx); err != nil {
+//		return err
+//	}
+//	go store.RunExpiry(ctx, time.Minute)
+//
+// Store implements faas.KV for idempotency and dedupe, faas.AtomicKV and
+// faas.CompareDeleteKV for locks and faas.CounterKV for faas.RateLimit.
+// Expired rows are ignored as soon as they expire and deleted by RunExpiry
+// or DeleteExpired. The database driver is imported by the function as
+// usual.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// Dialect selects the SQL syntax of the database.
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	MySQL
+)
+
+// DefaultTable is the table used when New is given no name.
+const DefaultTable = "faas_kv"
+
+var validTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// Store is a key/value table. It is safe for concurrent use.
+type Store struct {
+	db *sql.DB
+	q  queries
+}
+
+type queries struct {
+	create []string
+
+	get, set, insert, incr, deleteKey, deleteIfEqual, deleteExpiredKey, deleteExpired string
+	// incrReturns is set when incr returns the new value, otherwise it is
+	// read back in the same transaction
+	incrReturns bool
+}
+
+// New returns a Store using table, or DefaultTable when empty.
+func New(db *sql.DB, dialect Dialect, table string) (*Store, error) {
+	if table == "" {
+		table = DefaultTable
+	}
+	if !validTable.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	q, err := buildQueries(dialect, table)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, q: q}, nil
+}
+
+func buildQueries(dialect Dialect, t string) (queries, error) {
+	switch dialect {
+	case Postgres:
+		return queries{
+			create: []string{
+				`CREATE TABLE IF NOT EXISTS ` + t + ` (k VARCHAR(255) PRIMARY KEY, v BYTEA NOT NULL, expires_at TIMESTAMPTZ NULL)`,
+				`CREATE INDEX IF NOT EXISTS ` + t + `_expires_at ON ` + t + ` (expires_at)`,
+			},
+			get: `SELECT v FROM ` + t + ` WHERE k = $1 AND (expires_at IS NULL OR expires_at > $2)`,
+			set: `INSERT INTO ` + t + ` (k, v, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (k) DO UPDATE SET v = EXCLUDED.v, expires_at = EXCLUDED.expires_at`,
+			insert: `INSERT INTO ` + t + ` (k, v, expires_at) VALUES ($1, $2, $3) ON CONFLICT (k) DO NOTHING`,
+			incr: `INSERT INTO ` + t + ` (k, v, expires_at) VALUES ($1, '1', $2)
+ON CONFLICT (k) DO UPDATE SET v = convert_to((convert_from(` + t + `.v, 'UTF8')::bigint + 1)::text, 'UTF8')
+RETURNING v`,
+			incrReturns:      true,
+			deleteKey:        `DELETE FROM ` + t + ` WHERE k = $1`,
+			deleteIfEqual:    `DELETE FROM ` + t + ` WHERE k = $1 AND v = $2 AND (expires_at IS NULL OR expires_at > $3)`,
+			deleteExpiredKey: `DELETE FROM ` + t + ` WHERE k = $1 AND expires_at <= $2`,
+			deleteExpired:    `DELETE FROM ` + t + ` WHERE expires_at <= $1`,
+		}, nil
+	case MySQL:
+		return queries{
+			create: []string{
+				`CREATE TABLE IF NOT EXISTS ` + t + ` (k VARCHAR(255) PRIMARY KEY, v LONGBLOB NOT NULL, expires_at DATETIME(6) NULL, INDEX ` + t + `_expires_at (expires_at))`,
+			},
+			get: `SELECT v FROM ` + t + ` WHERE k = ? AND (expires_at IS NULL OR expires_at > ?)`,
+			set: `INSERT INTO ` + t + ` (k, v, expires_at) VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE v = VALUES(v), expires_at = VALUES(expires_at)`,
+			insert: `INSERT IGNORE INTO ` + t + ` (k, v, expires_at) VALUES (?, ?, ?)`,
+			incr: `INSERT INTO ` + t + ` (k, v, expires_at) VALUES (?, '1', ?)
+ON DUPLICATE KEY UPDATE v = CAST(CAST(v AS SIGNED) + 1 AS CHAR)`,
+			deleteKey:        `DELETE FROM ` + t + ` WHERE k = ?`,
+			deleteIfEqual:    `DELETE FROM ` + t + ` WHERE k = ? AND v = ? AND (expires_at IS NULL OR expires_at > ?)`,
+			deleteExpiredKey: `DELETE FROM ` + t + ` WHERE k = ? AND expires_at <= ?`,
+			deleteExpired:    `DELETE FROM ` + t + ` WHERE expires_at <= ?`,
+		}, nil
+	}
+	return queries{}, fmt.Errorf("unknown dialect %d", dialect)
+}
+
+// CreateTable creates the table and its expiry index unless they exist.
+func (s *Store) CreateTable(ctx context.Context) error {
+	for _, q := range s.q.create {
+		if _, err := s.db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get implements faas.KV.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	var v []byte
+	err := s.db.QueryRowContext(ctx, s.q.get, key, time.Now().UTC()).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, faas.ErrNotFound
+	}
+	return v, err
+}
+
+// Set implements faas.KV.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx, s.q.set, key, value, expiresAt(ttl))
+	return err
+}
+
+// SetNX implements faas.AtomicKV, relying on the primary key so only one
+// caller can insert a key.
+func (s *Store) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if _, err := s.db.ExecContext(ctx, s.q.deleteExpiredKey, key, time.Now().UTC()); err != nil {
+		return false, err
+	}
+	res, err := s.db.ExecContext(ctx, s.q.insert, key, value, expiresAt(ttl))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Incr implements faas.CounterKV.
+func (s *Store) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, s.q.deleteExpiredKey, key, time.Now().UTC()); err != nil {
+		return 0, err
+	}
+	var v []byte
+	if s.q.incrReturns {
+		err = tx.QueryRowContext(ctx, s.q.incr, key, expiresAt(ttl)).Scan(&v)
+	} else {
+		if _, err = tx.ExecContext(ctx, s.q.incr, key, expiresAt(ttl)); err == nil {
+			err = tx.QueryRowContext(ctx, s.q.get, key, time.Now().UTC()).Scan(&v)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value of %s is not a counter", key)
+	}
+	return n, tx.Commit()
+}
+
+// Delete implements faas.KV.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.q.deleteKey, key)
+	return err
+}
+
+// DeleteIfEqual implements faas.CompareDeleteKV.
+func (s *Store) DeleteIfEqual(ctx context.Context, key string, value []byte) (bool, error) {
+	res, err := s.db.ExecContext(ctx, s.q.deleteIfEqual, key, value, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteExpired removes expired rows and returns how many were removed.
+func (s *Store) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.q.deleteExpired, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RunExpiry calls DeleteExpired every interval until ctx is done. Failures
+// are logged and retried at the next interval.
+func (s *Store) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeleteExpired(ctx); err != nil && ctx.Err() == nil {
+				faas.LoggerFromContext(ctx).Warn("deleting expired rows", "error", err)
+			}
+		}
+	}
+}
+
+func expiresAt(ttl time.Duration) any {
+	if ttl <= 0 {
+		return nil
+	}
+	return time.Now().Add(ttl).UTC()
+}
@@ -0,0 +1,165 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	faas "github.com/danielmichaels/go-faas"
+)
+
+// scriptDriver answers every statement with respond, recording what was
+// executed, so the store can be tested without a database.
+type scriptDriver struct {
+	mu      sync.Mutex
+	queries []string
+	respond func(query string, args []driver.Value) ([][]driver.Value, int64)
+}
+
+func (d *scriptDriver) Open(string) (driver.Conn, error) { return scriptConn{d}, nil }
+
+type scriptConn struct{ d *scriptDriver }
+
+func (c scriptConn) Prepare(query string) (driver.Stmt, error) { return scriptStmt{c.d, query}, nil }
+func (c scriptConn) Close() error                              { return nil }
+func (c scriptConn) Begin() (driver.Tx, error)                 { return scriptTx{}, nil }
+
+type scriptTx struct{}
+
+func (scriptTx) Commit() error   { return nil }
+func (scriptTx) Rollback() error { return nil }
+
+type scriptStmt struct {
+	d     *scriptDriver
+	query string
+}
+
+func (s scriptStmt) Close() error  { return nil }
+func (s scriptStmt) NumInput() int { return -1 }
+
+func (s scriptStmt) run(args []driver.Value) ([][]driver.Value, int64) {
+	s.d.mu.Lock()
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.mu.Unlock()
+	return s.d.respond(s.query, args)
+}
+
+func (s scriptStmt) Exec(args []driver.Value) (driver.Result, error) {
+	_, n := s.run(args)
+	return driver.RowsAffected(n), nil
+}
+
+func (s scriptStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, _ := s.run(args)
+	return &scriptRows{rows: rows}, nil
+}
+
+type scriptRows struct {
+	rows [][]driver.Value
+}
+
+func (r *scriptRows) Columns() []string { return []string{"v"} }
+func (r *scriptRows) Close() error      { return nil }
+
+func (r *scriptRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func openScript(t *testing.T, respond func(string, []driver.Value) ([][]driver.Value, int64)) (*sql.DB, *scriptDriver) {
+	t.Helper()
+	d := &scriptDriver{respond: respond}
+	name := "script-" + t.Name()
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestNewValidatesTable(t *testing.T) {
+	if _, err := New(nil, Postgres, "kv; DROP TABLE users"); err == nil {
+		t.Error("expected an invalid table name to be rejected")
+	}
+	if _, err := New(nil, Dialect(9), ""); err == nil {
+		t.Error("expected an unknown dialect to be rejected")
+	}
+}
+
+func TestStore(t *testing.T) {
+	for _, dialect := range []Dialect{Postgres, MySQL} {
+		dialect := dialect
+		t.Run(map[Dialect]string{Postgres: "postgres", MySQL: "mysql"}[dialect], func(t *testing.T) {
+			counter := int64(0)
+			exists := false
+			db, d := openScript(t, func(query string, args []driver.Value) ([][]driver.Value, int64) {
+				switch {
+				case strings.HasPrefix(query, "SELECT") && strings.Contains(query, "faas_kv") && counter > 0:
+					return [][]driver.Value{{[]byte("7")}}, 0
+				case strings.HasPrefix(query, "SELECT"):
+					return nil, 0
+				case strings.Contains(query, "'1'"):
+					counter++
+					return [][]driver.Value{{[]byte("7")}}, 1
+				case strings.Contains(query, "DO NOTHING") || strings.Contains(query, "INSERT IGNORE"):
+					if exists {
+						return nil, 0
+					}
+					exists = true
+					return nil, 1
+				case strings.Contains(query, "AND v ="):
+					if string(args[1].([]byte)) != "owner" {
+						return nil, 0
+					}
+					return nil, 1
+				}
+				return nil, 1
+			})
+			s, err := New(db, dialect, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+
+			if err := s.CreateTable(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Get(ctx, "missing"); !errors.Is(err, faas.ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+			if ok, err := s.SetNX(ctx, "lock", []byte("owner"), time.Minute); !ok || err != nil {
+				t.Errorf("SetNX() on a new key = %v, %v", ok, err)
+			}
+			if ok, err := s.SetNX(ctx, "lock", []byte("other"), time.Minute); ok || err != nil {
+				t.Errorf("SetNX() on an existing key = %v, %v", ok, err)
+			}
+			if ok, err := s.DeleteIfEqual(ctx, "lock", []byte("other")); ok || err != nil {
+				t.Errorf("DeleteIfEqual() with another value = %v, %v", ok, err)
+			}
+			if ok, err := s.DeleteIfEqual(ctx, "lock", []byte("owner")); !ok || err != nil {
+				t.Errorf("DeleteIfEqual() = %v, %v", ok, err)
+			}
+			if n, err := s.Incr(ctx, "hits", time.Minute); n != 7 || err != nil {
+				t.Errorf("Incr() = %d, %v", n, err)
+			}
+			if _, err := s.DeleteExpired(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if len(d.queries) == 0 || !strings.Contains(d.queries[0], "CREATE TABLE IF NOT EXISTS faas_kv") {
+				t.Errorf("unexpected queries %q", d.queries)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStreamClosed is returned when writing to a stream that has been closed
+// or whose client has gone away.
+var ErrStreamClosed = errors.New("stream closed")
+
+// SSEWriter streams Server-Sent Events to the client. It is safe for
+// concurrent use.
+type SSEWriter struct {
+	mu     sync.Mutex
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	ctx    context.Context
+	done   chan struct{}
+	closed bool
+}
+
+// NewSSEWriter sets the event-stream headers and returns an SSEWriter. When
+// heartbeat is greater than zero a comment line is sent on that interval to
+// keep proxies and the watchdog from timing out an idle connection.
+func NewSSEWriter(w http.ResponseWriter, r *http.Request, heartbeat time.Duration) *SSEWriter {
+	return newSSEWriter(w, r, heartbeat)
+}
+func newSSEWriter(w http.ResponseWriter, r *http.Request, heartbeat time.Duration) *SSEWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable response buffering in nginx based ingress controllers
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	s := &SSEWriter{
+		w:    w,
+		rc:   http.NewResponseController(w),
+		ctx:  r.Context(),
+		done: make(chan struct{}),
+	}
+	_ = s.rc.Flush()
+
+	if heartbeat > 0 {
+		go s.keepAlive(heartbeat)
+	}
+	return s
+}
+
+// Send writes an event. An empty event name sends an unnamed "message"
+// event. Strings and byte slices are sent as-is, everything else is encoded
+// as JSON.
+func (s *SSEWriter) Send(event string, data any) error {
+	return s.send("", event, data)
+}
+
+// SendWithID writes an event with an id so clients can resume with the
+// Last-Event-ID header after reconnecting.
+func (s *SSEWriter) SendWithID(id, event string, data any) error {
+	return s.send(id, event, data)
+}
+
+// Close stops the heartbeat and must be called before the handler returns.
+// It does not close the underlying connection, which happens when the
+// handler returns.
+func (s *SSEWriter) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+func (s *SSEWriter) send(id, event string, data any) error {
+	var payload string
+	switch v := data.(type) {
+	case string:
+		payload = v
+	case []byte:
+		payload = string(v)
+	default:
+		js, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		payload = string(js)
+	}
+
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	// each line of the payload needs its own data field
+	for _, line := range strings.Split(payload, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+func (s *SSEWriter) write(msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+	if _, err := s.w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	err := s.rc.Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+func (s *SSEWriter) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.write(": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEWriterSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		event    string
+		data     any
+		expected string
+	}{
+		{
+			name:     "unnamed string event",
+			data:     "hello",
+			expected: "data: hello\n\n",
+		},
+		{
+			name:     "named multiline event",
+			event:    "progress",
+			data:     "line1\nline2",
+			expected: "event: progress\ndata: line1\ndata: line2\n\n",
+		},
+		{
+			name:     "json event with id",
+			id:       "7",
+			event:    "done",
+			data:     Map{"ok": true},
+			expected: "id: 7\nevent: done\ndata: {\"ok\":true}\n\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			resp := httptest.NewRecorder()
+
+			s := newSSEWriter(resp, req, 0)
+			if err := s.SendWithID(tc.id, tc.event, tc.data); err != nil {
+				t.Fatal(err)
+			}
+			s.Close()
+
+			if resp.Header().Get("Content-Type") != "text/event-stream" {
+				t.Errorf("unexpected content type %s", resp.Header().Get("Content-Type"))
+			}
+			if resp.Body.String() != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, resp.Body.String())
+			}
+			if !resp.Flushed {
+				t.Error("expected response to be flushed")
+			}
+			if err := s.Send("", "late"); err != ErrStreamClosed {
+				t.Errorf("expected ErrStreamClosed after close, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSSEWriterHeartbeat(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	resp := httptest.NewRecorder()
+
+	s := newSSEWriter(resp, req, 5*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	s.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !strings.Contains(resp.Body.String(), ": keep-alive") {
+		t.Errorf("expected heartbeat comment, got %q", resp.Body.String())
+	}
+}
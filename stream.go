@@ -0,0 +1,88 @@
+package faas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// streamFlushInterval is how often buffered stream output is flushed to the
+// client.
+const streamFlushInterval = 100 * time.Millisecond
+
+// StreamJSON writes each value received on ch as an element of a JSON array,
+// flushing periodically so clients see results as they are produced. The
+// array is closed when ch is closed. Once streaming starts the status code
+// cannot change, so an encoding error ends the response early. The
+// TrailerWriter trailers are sent so clients can tell a complete stream
+// from a truncated one.
+func StreamJSON(w http.ResponseWriter, ch <-chan any) error {
+	return streamJSON(w, ch, false)
+}
+
+// StreamNDJSON is like StreamJSON but writes newline-delimited JSON, one
+// value per line.
+func StreamNDJSON(w http.ResponseWriter, ch <-chan any) error {
+	return streamJSON(w, ch, true)
+}
+
+func streamJSON(rw http.ResponseWriter, ch <-chan any, ndjson bool) (err error) {
+	w := NewTrailerWriter(rw)
+	defer func() { w.Finish(err != nil) }()
+	if ndjson {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	flush := func() error {
+		err := rc.Flush()
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil
+		}
+		return err
+	}
+
+	if !ndjson {
+		if _, err := w.Write([]byte("[")); err != nil {
+			return err
+		}
+	}
+
+	first := true
+	last := time.Now()
+	for v := range ch {
+		js, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		switch {
+		case ndjson:
+			js = append(js, '\n')
+		case !first:
+			js = append([]byte(","), js...)
+		}
+		first = false
+
+		if _, err := w.Write(js); err != nil {
+			return err
+		}
+		w.Item()
+		if time.Since(last) >= streamFlushInterval {
+			if err := flush(); err != nil {
+				return err
+			}
+			last = time.Now()
+		}
+	}
+
+	if !ndjson {
+		if _, err := w.Write([]byte("]")); err != nil {
+			return err
+		}
+	}
+	return flush()
+}
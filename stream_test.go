@@ -0,0 +1,58 @@
+package faas
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		ndjson      bool
+		values      []any
+		contentType string
+		expected    string
+	}{
+		{
+			name:        "json array",
+			values:      []any{1, "two", Map{"three": 3}},
+			contentType: "application/json",
+			expected:    `[1,"two",{"three":3}]`,
+		},
+		{
+			name:        "empty json array",
+			contentType: "application/json",
+			expected:    `[]`,
+		},
+		{
+			name:        "ndjson",
+			ndjson:      true,
+			values:      []any{1, Map{"two": 2}},
+			contentType: "application/x-ndjson",
+			expected:    "1\n{\"two\":2}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ch := make(chan any)
+			go func() {
+				defer close(ch)
+				for _, v := range tc.values {
+					ch <- v
+				}
+			}()
+
+			resp := httptest.NewRecorder()
+			if err := streamJSON(resp, ch, tc.ndjson); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Header().Get("Content-Type") != tc.contentType {
+				t.Errorf("expected content type %s, got %s", tc.contentType, resp.Header().Get("Content-Type"))
+			}
+			if resp.Body.String() != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, resp.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Change is a single entry in a ChangeLog.
+type Change struct {
+	Seq     uint64          `json:"seq"`
+	ID      string          `json:"id"`
+	Deleted bool            `json:"deleted,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Time    time.Time       `json:"time"`
+}
+
+// SyncResponse is the body written by WriteSync.
+type SyncResponse struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"has_more"`
+}
+
+// ChangeLog is an append only log of item changes stored in a KV. Sequence
+// numbers are allocated in-process, so a single ChangeLog should own writes
+// for a given name.
+type ChangeLog struct {
+	// CursorSecret, when set, signs the cursors handed to clients with
+	// EncodeCursor so they cannot be tampered with.
+	CursorSecret []byte
+	// CursorTTL is the lifetime of signed cursors. Zero means no expiry.
+	CursorTTL time.Duration
+
+	mu     sync.Mutex
+	kv     KV
+	prefix string
+}
+
+// NewChangeLog returns a ChangeLog that stores its entries in kv under name.
+func NewChangeLog(kv KV, name string) *ChangeLog {
+	return &ChangeLog{kv: kv, prefix: "changelog/" + name + "/"}
+}
+
+// Append records a change to the item with the given id and returns its
+// sequence number. data is marshalled to JSON and ignored when deleted is true.
+func (c *ChangeLog) Append(ctx context.Context, id string, data any, deleted bool) (uint64, error) {
+	change := Change{ID: id, Deleted: deleted, Time: time.Now().UTC()}
+	if !deleted && data != nil {
+		js, err := json.Marshal(data)
+		if err != nil {
+			return 0, err
+		}
+		change.Data = js
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	head, err := c.Head(ctx)
+	if err != nil {
+		return 0, err
+	}
+	change.Seq = head + 1
+
+	js, err := json.Marshal(change)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.kv.Set(ctx, c.key(change.Seq), js, 0); err != nil {
+		return 0, err
+	}
+	if err := c.kv.Set(ctx, c.prefix+"head", []byte(strconv.FormatUint(change.Seq, 10)), 0); err != nil {
+		return 0, err
+	}
+	return change.Seq, nil
+}
+
+// Head returns the sequence number of the latest change, or zero if the log
+// is empty.
+func (c *ChangeLog) Head(ctx context.Context) (uint64, error) {
+	byt, err := c.kv.Get(ctx, c.prefix+"head")
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(byt), 10, 64)
+}
+
+// Since returns up to limit changes after seq. When an item changed several
+// times only its latest change is returned. The returned sequence number is
+// the cursor the client should send next time.
+func (c *ChangeLog) Since(ctx context.Context, seq uint64, limit int) ([]Change, uint64, bool, error) {
+	head, err := c.Head(ctx)
+	if err != nil {
+		return nil, seq, false, err
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var changes []Change
+	index := make(map[string]int)
+	next := seq
+	for s := seq + 1; s <= head && len(changes) < limit; s++ {
+		byt, err := c.kv.Get(ctx, c.key(s))
+		if errors.Is(err, ErrNotFound) {
+			// expired or compacted entries are skipped
+			next = s
+			continue
+		}
+		if err != nil {
+			return nil, seq, false, err
+		}
+		var change Change
+		if err := json.Unmarshal(byt, &change); err != nil {
+			return nil, seq, false, err
+		}
+		if i, ok := index[change.ID]; ok {
+			changes[i] = change
+		} else {
+			index[change.ID] = len(changes)
+			changes = append(changes, change)
+		}
+		next = s
+	}
+	return changes, next, next < head, nil
+}
+
+func (c *ChangeLog) key(seq uint64) string {
+	return c.prefix + strconv.FormatUint(seq, 10)
+}
+
+// EncodeCursor returns the client facing cursor for seq.
+func (c *ChangeLog) EncodeCursor(seq uint64) (string, error) {
+	if len(c.CursorSecret) == 0 {
+		return strconv.FormatUint(seq, 10), nil
+	}
+	return encodeCursor(c.CursorSecret, seq, c.CursorTTL)
+}
+
+// DecodeCursor parses a cursor created by EncodeCursor. An empty cursor
+// decodes to zero.
+func (c *ChangeLog) DecodeCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	var seq uint64
+	if len(c.CursorSecret) != 0 {
+		if err := decodeCursor(c.CursorSecret, cursor, &seq); err != nil {
+			return 0, err
+		}
+		return seq, nil
+	}
+	seq, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sync cursor %q", cursor)
+	}
+	return seq, nil
+}
+
+// ReadSyncCursor returns the raw sync cursor sent by the client. The cursor
+// is read from the "cursor" query parameter and falls back to the
+// If-None-Match header. An empty cursor means the client wants a full sync.
+func ReadSyncCursor(r *http.Request) string {
+	return readSyncCursor(r)
+}
+func readSyncCursor(r *http.Request) string {
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		cursor = strings.Trim(r.Header.Get("If-None-Match"), `"`)
+	}
+	return cursor
+}
+
+// WriteSync reads the client cursor, fetches the changes since then and
+// writes them as a SyncResponse. If nothing has changed since the cursor a
+// 304 Not Modified is returned instead.
+func WriteSync(w http.ResponseWriter, r *http.Request, log *ChangeLog, limit int) error {
+	return writeSync(w, r, log, limit)
+}
+func writeSync(w http.ResponseWriter, r *http.Request, log *ChangeLog, limit int) error {
+	seq, err := log.DecodeCursor(readSyncCursor(r))
+	if err != nil {
+		return writeJSONError(w, Error{
+			Status: http.StatusText(http.StatusBadRequest),
+			Reason: err.Error(),
+			Code:   http.StatusBadRequest,
+		})
+	}
+
+	changes, next, more, err := log.Since(r.Context(), seq, limit)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := log.EncodeCursor(next)
+	if err != nil {
+		return err
+	}
+	headers := http.Header{}
+	headers.Set("ETag", strconv.Quote(cursor))
+	if len(changes) == 0 && seq != 0 {
+		for k, v := range headers {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	if changes == nil {
+		changes = []Change{}
+	}
+	return writeJSON(w, http.StatusOK, SyncResponse{Changes: changes, Cursor: cursor, HasMore: more}, headers)
+}
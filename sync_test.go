@@ -0,0 +1,116 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChangeLogSince(t *testing.T) {
+	ctx := context.Background()
+	log := NewChangeLog(NewMemoryKV(), "items")
+
+	for _, id := range []string{"a", "b", "a", "c"} {
+		if _, err := log.Append(ctx, id, Map{"id": id}, false); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	changes, next, more, err := log.Since(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 compacted changes, got %d", len(changes))
+	}
+	if changes[0].ID != "a" || changes[0].Seq != 3 {
+		t.Errorf("expected latest change for a to be seq 3, got %d", changes[0].Seq)
+	}
+	if next != 4 || more {
+		t.Errorf("expected next 4 and no more, got %d and %v", next, more)
+	}
+
+	changes, next, more, err = log.Since(ctx, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || next != 3 || !more {
+		t.Errorf("expected 2 changes, next 3 and more, got %d, %d, %v", len(changes), next, more)
+	}
+}
+
+func TestWriteSync(t *testing.T) {
+	ctx := context.Background()
+	log := NewChangeLog(NewMemoryKV(), "items")
+	_, _ = log.Append(ctx, "a", Map{"id": "a"}, false)
+	_, _ = log.Append(ctx, "b", nil, true)
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		count  int
+	}{
+		{name: "full sync", target: "/", status: http.StatusOK, count: 2},
+		{name: "partial sync", target: "/?cursor=1", status: http.StatusOK, count: 1},
+		{name: "up to date", target: "/?cursor=2", status: http.StatusNotModified},
+		{name: "bad cursor", target: "/?cursor=abc", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			resp := httptest.NewRecorder()
+
+			if err := writeSync(resp, req, log, 10); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, resp.Code)
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			var body SyncResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Changes) != tc.count || body.Cursor != "2" {
+				t.Errorf("expected %d changes and cursor 2, got %d and %s",
+					tc.count, len(body.Changes), body.Cursor)
+			}
+		})
+	}
+}
+
+func TestWriteSyncSignedCursor(t *testing.T) {
+	ctx := context.Background()
+	log := NewChangeLog(NewMemoryKV(), "items")
+	log.CursorSecret = []byte("s3cret")
+	_, _ = log.Append(ctx, "a", Map{"id": "a"}, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/?cursor=1", nil)
+	resp := httptest.NewRecorder()
+	if err := writeSync(resp, req, log, 10); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected unsigned cursor to be rejected, got %d", resp.Code)
+	}
+
+	cursor, err := log.EncodeCursor(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"`+cursor+`"`)
+	resp = httptest.NewRecorder()
+	if err := writeSync(resp, req, log, 10); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != http.StatusNotModified {
+		t.Fatalf("expected %d, got %d", http.StatusNotModified, resp.Code)
+	}
+}
@@ -0,0 +1,292 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrTempQuota is returned when a temporary file would take the storage
+// used by TempFiles over its quota.
+var ErrTempQuota = errors.New("temporary storage quota exceeded")
+
+// DefaultTempFiles is used by TempFile and TempDir.
+var DefaultTempFiles = &TempFiles{}
+
+// TempStats describe the temporary storage in use.
+type TempStats struct {
+	Files int   `json:"files"`
+	Dirs  int   `json:"dirs"`
+	Bytes int64 `json:"bytes"`
+	Quota int64 `json:"quota,omitempty"`
+}
+
+// TempFiles creates temporary files and directories which are removed when
+// the request that created them ends, or on Shutdown for those created with
+// a context that never ends, so functions do not fill their ephemeral
+// storage. It is safe for concurrent use.
+type TempFiles struct {
+	// Dir is where temporary files are created. Defaults to os.TempDir.
+	Dir string
+	// Quota caps the bytes held by temporary files and directories. Bytes
+	// written to a QuotaFile count against it as they grow the file, the
+	// contents of directories are measured when new entries are created.
+	// Zero means no quota.
+	Quota int64
+
+	// quotaMu is held from a quota check until the checked bytes or entry
+	// are tracked, so concurrent callers cannot both pass the check
+	quotaMu sync.Mutex
+	mu      sync.Mutex
+	// files and dirs hold the size of each entry, as tracked by writes for
+	// files and as last measured for dirs, and bytes the sum over files
+	files        map[string]int64
+	dirs         map[string]int64
+	bytes        int64
+	shutdownOnce sync.Once
+}
+
+// QuotaFile is a temporary file whose writes are limited by the quota of
+// the TempFiles which created it.
+type QuotaFile struct {
+	*os.File
+	t *TempFiles
+	// mu keeps the offset read by Write valid until the write is done
+	mu sync.Mutex
+}
+
+// TempFile creates a temporary file with DefaultTempFiles, see
+// TempFiles.File.
+func TempFile(ctx context.Context, pattern string) (*QuotaFile, error) {
+	return DefaultTempFiles.File(ctx, pattern)
+}
+
+// TempDir creates a temporary directory with DefaultTempFiles, see
+// TempFiles.MkDir.
+func TempDir(ctx context.Context, pattern string) (string, error) {
+	return DefaultTempFiles.MkDir(ctx, pattern)
+}
+
+// File creates a temporary file, named as by os.CreateTemp, which is closed
+// and removed once ctx is done.
+func (t *TempFiles) File(ctx context.Context, pattern string) (*QuotaFile, error) {
+	// refuse new entries once there is no room left for a single byte
+	var f *os.File
+	err := t.withQuota(1, func() error {
+		var err error
+		if f, err = os.CreateTemp(t.Dir, pattern); err != nil {
+			return err
+		}
+		t.track(func() { t.files[f.Name()] = 0 })
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	name := f.Name()
+	t.cleanupWith(ctx, func() {
+		_ = f.Close()
+		_ = os.Remove(name)
+		t.track(func() {
+			t.bytes -= t.files[name]
+			delete(t.files, name)
+		})
+	})
+	return &QuotaFile{File: f, t: t}, nil
+}
+
+// MkDir creates a temporary directory, named as by os.MkdirTemp, which is
+// removed with its contents once ctx is done.
+func (t *TempFiles) MkDir(ctx context.Context, pattern string) (string, error) {
+	// refuse new entries once there is no room left for a single byte
+	var dir string
+	err := t.withQuota(1, func() error {
+		var err error
+		if dir, err = os.MkdirTemp(t.Dir, pattern); err != nil {
+			return err
+		}
+		t.track(func() { t.dirs[dir] = 0 })
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	t.cleanupWith(ctx, func() {
+		_ = os.RemoveAll(dir)
+		t.track(func() { delete(t.dirs, dir) })
+	})
+	return dir, nil
+}
+
+func (t *TempFiles) track(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.files == nil {
+		t.files = make(map[string]int64)
+		t.dirs = make(map[string]int64)
+	}
+	fn()
+}
+
+// cleanupWith runs cleanup when ctx is done or on Shutdown, whichever is
+// first.
+func (t *TempFiles) cleanupWith(ctx context.Context, cleanup func()) {
+	once := sync.OnceFunc(cleanup)
+	context.AfterFunc(ctx, once)
+	t.shutdownOnce.Do(func() {
+		OnShutdown(func(context.Context) error {
+			t.RemoveAll()
+			return nil
+		})
+	})
+}
+
+// RemoveAll removes every temporary file and directory still present.
+func (t *TempFiles) RemoveAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name := range t.files {
+		_ = os.Remove(name)
+		delete(t.files, name)
+	}
+	for dir := range t.dirs {
+		_ = os.RemoveAll(dir)
+		delete(t.dirs, dir)
+	}
+	t.bytes = 0
+}
+
+// withQuota measures the directories and runs fn, which tracks a new entry,
+// unless n more bytes would exceed the quota, in which case it reports
+// ErrTempQuota.
+func (t *TempFiles) withQuota(n int64, fn func() error) error {
+	if t.Quota <= 0 {
+		return fn()
+	}
+	t.quotaMu.Lock()
+	defer t.quotaMu.Unlock()
+	t.measureDirs()
+	if t.used()+n > t.Quota {
+		return ErrTempQuota
+	}
+	return fn()
+}
+
+// used returns the bytes held by files and, as last measured, directories.
+func (t *TempFiles) used() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usedLocked()
+}
+
+// usedLocked is used for callers holding mu.
+func (t *TempFiles) usedLocked() int64 {
+	n := t.bytes
+	for _, size := range t.dirs {
+		n += size
+	}
+	return n
+}
+
+// measureDirs walks the directories and records the size of their
+// contents.
+func (t *TempFiles) measureDirs() {
+	t.mu.Lock()
+	dirs := make([]string, 0, len(t.dirs))
+	for dir := range t.dirs {
+		dirs = append(dirs, dir)
+	}
+	t.mu.Unlock()
+
+	for _, dir := range dirs {
+		var size int64
+		_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+			return nil
+		})
+		t.track(func() {
+			if _, ok := t.dirs[dir]; ok {
+				t.dirs[dir] = size
+			}
+		})
+	}
+}
+
+// Snapshot returns the temporary storage currently in use.
+func (t *TempFiles) Snapshot() TempStats {
+	t.measureDirs()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return TempStats{Files: len(t.files), Dirs: len(t.dirs), Bytes: t.usedLocked(), Quota: t.Quota}
+}
+
+// ServeHTTP writes the snapshot as JSON.
+func (t *TempFiles) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	_ = writeJSON(w, http.StatusOK, t.Snapshot(), nil)
+}
+
+// reserve grows the tracked size of the file name to end, counting the
+// growth against the quota. Writes within the current size are free.
+func (t *TempFiles) reserve(name string, end int64) error {
+	if t.Quota > 0 {
+		t.quotaMu.Lock()
+		defer t.quotaMu.Unlock()
+	}
+	var err error
+	t.track(func() {
+		size, ok := t.files[name]
+		if !ok || end <= size {
+			return
+		}
+		if t.Quota > 0 && t.usedLocked()+end-size > t.Quota {
+			err = ErrTempQuota
+			return
+		}
+		t.files[name] = end
+		t.bytes += end - size
+	})
+	return err
+}
+
+// Write implements io.Writer, failing with ErrTempQuota over the quota.
+func (f *QuotaFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	off, err := f.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.t.reserve(f.Name(), off+int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+// WriteAt implements io.WriterAt, failing with ErrTempQuota over the quota.
+func (f *QuotaFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.t.reserve(f.Name(), off+int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.WriteAt(p, off)
+}
+
+// WriteString is Write for strings.
+func (f *QuotaFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+// ReadFrom implements io.ReaderFrom so io.Copy goes through Write rather
+// than the unlimited *os.File implementation.
+func (f *QuotaFile) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
@@ -0,0 +1,125 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitRemoved(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("expected %s to be removed", path)
+}
+
+func TestTempFilesCleanup(t *testing.T) {
+	tf := &TempFiles{Dir: t.TempDir()}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	f, err := tf.File(ctx, "report-*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("a,b\n"); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := tf.MkDir(ctx, "work-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(dir, "part"), []byte("12345"), 0o600)
+
+	stats := tf.Snapshot()
+	if stats.Files != 1 || stats.Dirs != 1 || stats.Bytes != 9 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	cancel()
+	waitRemoved(t, f.Name())
+	waitRemoved(t, dir)
+}
+
+func TestTempFilesQuota(t *testing.T) {
+	tf := &TempFiles{Dir: t.TempDir(), Quota: 10}
+	f, err := tf.File(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tf.RemoveAll()
+
+	if _, err := f.Write([]byte("12345678")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(f, strings.NewReader("too much")); !errors.Is(err, ErrTempQuota) {
+		t.Errorf("expected ErrTempQuota from io.Copy, got %v", err)
+	}
+	if _, err := f.Write([]byte("12")); err != nil {
+		t.Errorf("expected writes within the quota to succeed, got %v", err)
+	}
+	if _, err := tf.File(context.Background(), ""); !errors.Is(err, ErrTempQuota) {
+		t.Errorf("expected new files to be refused at the quota, got %v", err)
+	}
+}
+
+func TestTempFilesQuotaConcurrent(t *testing.T) {
+	tf := &TempFiles{Dir: t.TempDir(), Quota: 100}
+	defer tf.RemoveAll()
+	f, err := tf.File(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = f.Write([]byte("0123456789"))
+		}()
+	}
+	wg.Wait()
+	if stats := tf.Snapshot(); stats.Bytes > tf.Quota {
+		t.Errorf("concurrent writes used %d bytes, over the %d quota", stats.Bytes, tf.Quota)
+	}
+}
+
+func TestTempFilesQuotaRewrite(t *testing.T) {
+	tf := &TempFiles{Dir: t.TempDir(), Quota: 10}
+	defer tf.RemoveAll()
+	f, err := tf.File(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("12345678")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := f.WriteAt([]byte("abcd"), 2); err != nil {
+			t.Fatalf("rewrite %d: %v", i, err)
+		}
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("0123456789")); err != nil {
+		t.Errorf("expected a rewrite up to the quota to succeed, got %v", err)
+	}
+	if _, err := f.WriteAt([]byte("x"), 10); !errors.Is(err, ErrTempQuota) {
+		t.Errorf("expected growth past the quota to fail, got %v", err)
+	}
+	if stats := tf.Snapshot(); stats.Bytes != 10 {
+		t.Errorf("Bytes = %d, want 10", stats.Bytes)
+	}
+}
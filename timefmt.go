@@ -0,0 +1,186 @@
+package faas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeConfig controls how Time values are parsed and formatted.
+type TimeConfig struct {
+	// Layouts are accepted in addition to RFC 3339, tried in order.
+	Layouts []string
+	// AssumeLocation interprets values without a zone offset in this
+	// location. When nil, layouts without zone information are rejected by
+	// SetTimeConfig since such values are ambiguous.
+	AssumeLocation *time.Location
+	// OutputLayout is used when marshalling. Defaults to time.RFC3339Nano.
+	OutputLayout string
+	// Output selects whether times are written as OutputLayout strings,
+	// the default, or as JSON numbers of Unix milliseconds. Unix
+	// milliseconds are then accepted as input too.
+	Output TimeOutput
+}
+
+// TimeOutput is how TimeConfig writes times.
+type TimeOutput int
+
+const (
+	// TimeOutputLayout writes strings in TimeConfig.OutputLayout.
+	TimeOutputLayout TimeOutput = iota
+	// TimeOutputUnixMillis writes numbers of milliseconds since the Unix
+	// epoch.
+	TimeOutputUnixMillis
+)
+
+var (
+	timeConfigMu sync.RWMutex
+	timeConfig   = TimeConfig{OutputLayout: time.RFC3339Nano}
+)
+
+// SetTimeConfig replaces the process-wide time handling used by Time and
+// ParseTime. It is normally called once at startup. The output applies to
+// the Time values a response is encoded from, while time.Time values keep
+// their standard encoding, so fields that must share the format across
+// functions should be declared as Time.
+func SetTimeConfig(cfg TimeConfig) error {
+	for _, layout := range cfg.Layouts {
+		if cfg.AssumeLocation == nil && !layoutHasZone(layout) {
+			return fmt.Errorf("time layout %q has no zone and no AssumeLocation is set", layout)
+		}
+	}
+	if cfg.OutputLayout == "" {
+		cfg.OutputLayout = time.RFC3339Nano
+	}
+	timeConfigMu.Lock()
+	defer timeConfigMu.Unlock()
+	timeConfig = cfg
+	return nil
+}
+
+func layoutHasZone(layout string) bool {
+	for _, zone := range []string{"Z07", "-07", "MST"} {
+		if strings.Contains(layout, zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTime parses s as RFC 3339 or one of the configured layouts and
+// returns it in UTC.
+func ParseTime(s string) (time.Time, error) {
+	return parseTime(s)
+}
+func parseTime(s string) (time.Time, error) {
+	timeConfigMu.RLock()
+	cfg := timeConfig
+	timeConfigMu.RUnlock()
+
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range cfg.Layouts {
+		var t time.Time
+		var err error
+		if layoutHasZone(layout) {
+			t, err = time.Parse(layout, s)
+		} else {
+			t, err = time.ParseInLocation(layout, s, cfg.AssumeLocation)
+		}
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 such as %q", s, time.RFC3339)
+}
+
+// FormatTime formats t in UTC with the configured output layout.
+func FormatTime(t time.Time) string {
+	timeConfigMu.RLock()
+	layout := timeConfig.OutputLayout
+	timeConfigMu.RUnlock()
+	return t.UTC().Format(layout)
+}
+
+// Time is a time.Time which is strictly parsed and always normalised to UTC
+// when used in JSON bodies decoded by ReadJSON, query parameters and form
+// values.
+type Time struct {
+	time.Time
+}
+
+// MarshalJSON implements json.Marshaler. The zero time is encoded as null.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return marshalTime(t.Time), nil
+}
+
+// marshalTime encodes t as configured by SetTimeConfig.
+func marshalTime(t time.Time) []byte {
+	timeConfigMu.RLock()
+	output := timeConfig.Output
+	timeConfigMu.RUnlock()
+	if output == TimeOutputUnixMillis {
+		return strconv.AppendInt(nil, t.UnixMilli(), 10)
+	}
+	// strings always encode
+	b, _ := json.Marshal(FormatTime(t))
+	return b
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Only JSON strings are
+// accepted, unless the output is TimeOutputUnixMillis; otherwise numeric
+// timestamps are rejected as their unit is ambiguous.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	timeConfigMu.RLock()
+	output := timeConfig.Output
+	timeConfigMu.RUnlock()
+	if output == TimeOutputUnixMillis && len(data) > 0 && data[0] != '"' {
+		ms, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return errors.New("time must be a string or Unix milliseconds")
+		}
+		t.Time = time.UnixMilli(ms).UTC()
+		return nil
+	}
+	if len(data) == 0 || data[0] != '"' {
+		return errors.New("time must be a string")
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := parseTime(s)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t Time) MarshalText() ([]byte, error) {
+	return []byte(FormatTime(t.Time)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *Time) UnmarshalText(text []byte) error {
+	parsed, err := parseTime(string(text))
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
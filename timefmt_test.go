@@ -0,0 +1,106 @@
+package faas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeJSON(t *testing.T) {
+	defer SetTimeConfig(TimeConfig{})
+
+	if err := SetTimeConfig(TimeConfig{Layouts: []string{"2006-01-02 15:04"}}); err == nil {
+		t.Fatal("expected layouts without a zone to be rejected")
+	}
+	if err := SetTimeConfig(TimeConfig{Layouts: []string{"2006-01-02 15:04"}, AssumeLocation: time.UTC}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{name: "rfc3339 with offset", input: `"2024-03-01T10:00:00+10:00"`, expected: "2024-03-01T00:00:00Z"},
+		{name: "custom layout", input: `"2024-03-01 10:00"`, expected: "2024-03-01T10:00:00Z"},
+		{name: "ambiguous date", input: `"03/01/2024"`, expectErr: true},
+		{name: "numeric timestamp", input: `1709251200`, expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var v struct {
+				At Time `json:"at"`
+			}
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"at":`+tc.input+`}`))
+			err := readJSON(httptest.NewRecorder(), req, &v)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if tc.expectErr {
+				return
+			}
+			if v.At.Location() != time.UTC {
+				t.Errorf("expected time in UTC, got %v", v.At.Location())
+			}
+			js, _ := json.Marshal(v)
+			if string(js) != `{"at":"`+tc.expected+`"}` {
+				t.Errorf("expected %s, got %s", tc.expected, js)
+			}
+		})
+	}
+}
+
+func TestWriteJSONTimeOutput(t *testing.T) {
+	defer SetTimeConfig(TimeConfig{})
+	at := time.Date(2024, 3, 1, 10, 0, 0, 500_000_000, time.FixedZone("AEST", 10*3600))
+	body := map[string]any{
+		"created": at,
+		"due":     Time{at},
+		"note":    "2024-03-01T00:00:00Z",
+	}
+
+	tests := []struct {
+		name string
+		cfg  TimeConfig
+		want string
+	}{
+		{name: "default", cfg: TimeConfig{},
+			want: `{"created":"2024-03-01T10:00:00.5+10:00","due":"2024-03-01T00:00:00.5Z","note":"2024-03-01T00:00:00Z"}`},
+		{name: "rfc3339 seconds", cfg: TimeConfig{OutputLayout: time.RFC3339},
+			want: `{"created":"2024-03-01T10:00:00.5+10:00","due":"2024-03-01T00:00:00Z","note":"2024-03-01T00:00:00Z"}`},
+		{name: "unix millis", cfg: TimeConfig{Output: TimeOutputUnixMillis},
+			want: `{"created":"2024-03-01T10:00:00.5+10:00","due":1709251200500,"note":"2024-03-01T00:00:00Z"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetTimeConfig(tc.cfg); err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			if err := WriteJSON(w, http.StatusOK, body, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Body.String(); got != tc.want {
+				t.Errorf("got  %s\nwant %s", got, tc.want)
+			}
+		})
+	}
+
+	if err := SetTimeConfig(TimeConfig{Output: TimeOutputUnixMillis}); err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		At Time `json:"at"`
+	}
+	if err := json.Unmarshal([]byte(`{"at":1709251200500}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if !v.At.Equal(at) || v.At.Location() != time.UTC {
+		t.Errorf("parsed %v, want %v in UTC", v.At, at)
+	}
+}
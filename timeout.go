@@ -0,0 +1,151 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Timeout is middleware giving each request a context deadline and writing
+// a 504 JSON error when the handler does not finish in time. A d of zero
+// derives the timeout from the exec_timeout, or failing that read_timeout,
+// environment variables set for the of-watchdog, less a small margin so the
+// function responds before the watchdog cuts the connection. When neither is
+// set, handlers are not wrapped.
+//
+// Handlers should pass the request context to downstream calls so they are
+// cancelled. The response is buffered so it can be replaced by the error,
+// which makes Timeout unsuitable for streaming handlers.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	if d <= 0 {
+		d = watchdogTimeout()
+	}
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, h: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				tw.mu.Lock()
+				tw.completed = !tw.timedOut && ctx.Err() == nil
+				tw.mu.Unlock()
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.flush()
+			case <-ctx.Done():
+				tw.mu.Lock()
+				if tw.completed {
+					// the handler finished as the deadline fired
+					tw.mu.Unlock()
+					tw.flush()
+					return
+				}
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					_ = writeJSONError(w, Error{
+						Status: http.StatusText(http.StatusGatewayTimeout),
+						Reason: "request exceeded the " + d.String() + " timeout",
+						Code:   http.StatusGatewayTimeout,
+					})
+				}
+			}
+		})
+	}
+}
+
+// watchdogTimeout reads of-watchdog timeouts which are either durations,
+// such as "10s", or a number of seconds.
+func watchdogTimeout() time.Duration {
+	for _, env := range []string{"exec_timeout", "read_timeout"} {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			secs, err := strconv.Atoi(v)
+			if err != nil {
+				continue
+			}
+			d = time.Duration(secs) * time.Second
+		}
+		if d > 0 {
+			return d - min(d/10, time.Second)
+		}
+	}
+	return 0
+}
+
+// timeoutWriter buffers a response until the handler completes.
+type timeoutWriter struct {
+	w http.ResponseWriter
+	h http.Header
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+	// completed is set when the handler returned before the deadline, so
+	// exactly one of the response and the timeout error is written
+	completed bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.code == 0 {
+		tw.code = code
+	}
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(b)
+}
+
+func (tw *timeoutWriter) flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	for k, v := range tw.h {
+		tw.w.Header()[k] = v
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	tw.w.WriteHeader(tw.code)
+	_, _ = tw.w.Write(tw.buf.Bytes())
+}
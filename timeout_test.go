@@ -0,0 +1,95 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		env     string
+		sleep   time.Duration
+		status  int
+	}{
+		{name: "completes", timeout: 50 * time.Millisecond, status: http.StatusCreated},
+		{name: "exceeded", timeout: 10 * time.Millisecond, sleep: time.Second, status: http.StatusGatewayTimeout},
+		{name: "from watchdog env", env: "20ms", sleep: time.Second, status: http.StatusGatewayTimeout},
+		{name: "no timeout configured", status: http.StatusCreated},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("exec_timeout", tc.env)
+			t.Setenv("read_timeout", "")
+			sleep := tc.sleep
+
+			h := Timeout(tc.timeout)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(sleep):
+				case <-r.Context().Done():
+					return
+				}
+				w.Header().Set("X-Done", "yes")
+				w.WriteHeader(http.StatusCreated)
+			}))
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rr.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, rr.Code)
+			}
+			if tc.status == http.StatusCreated && rr.Header().Get("X-Done") != "yes" {
+				t.Error("expected handler headers to be copied")
+			}
+		})
+	}
+}
+
+func TestWatchdogTimeout(t *testing.T) {
+	tests := []struct {
+		exec, read string
+		want       time.Duration
+	}{
+		{exec: "10s", want: 9 * time.Second},
+		{exec: "30", want: 29 * time.Second},
+		{read: "5s", want: 4500 * time.Millisecond},
+		{want: 0},
+	}
+	for _, tc := range tests {
+		t.Setenv("exec_timeout", tc.exec)
+		t.Setenv("read_timeout", tc.read)
+		if got := watchdogTimeout(); got != tc.want {
+			t.Errorf("exec_timeout=%q read_timeout=%q: expected %v, got %v", tc.exec, tc.read, tc.want, got)
+		}
+	}
+}
+
+func TestTimeoutAtDeadline(t *testing.T) {
+	// a handler finishing as the deadline fires gets either its response
+	// or the timeout error, never a mix of both
+	h := Timeout(time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		w.Header().Set("X-Done", "yes")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	for i := 0; i < 50; i++ {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		switch rr.Code {
+		case http.StatusCreated:
+			if rr.Header().Get("X-Done") != "yes" {
+				t.Fatal("expected handler headers with its status")
+			}
+		case http.StatusGatewayTimeout:
+			if rr.Header().Get("X-Done") != "" {
+				t.Fatal("expected no handler headers with the timeout error")
+			}
+		default:
+			t.Fatalf("unexpected status %d", rr.Code)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package faas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timing is a named duration recorded while handling a request.
+type Timing struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Timings collects the Timing entries for a single request. It is safe for
+// concurrent use so outbound calls made from goroutines can be recorded.
+type Timings struct {
+	mu      sync.Mutex
+	entries []Timing
+}
+
+type timingsKey struct{}
+
+// WithTimings returns a context which records timings.
+func WithTimings(ctx context.Context) (context.Context, *Timings) {
+	t := &Timings{}
+	return context.WithValue(ctx, timingsKey{}, t), t
+}
+
+// TimingsFromContext returns the Timings recording for ctx, if any.
+func TimingsFromContext(ctx context.Context) (*Timings, bool) {
+	t, ok := ctx.Value(timingsKey{}).(*Timings)
+	return t, ok
+}
+
+// StartTiming starts a timing called name and returns a function which
+// records it when called. It does nothing when ctx is not recording.
+func StartTiming(ctx context.Context, name string) func() {
+	t, ok := TimingsFromContext(ctx)
+	if !ok {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { t.Add(name, time.Since(start)) }
+}
+
+// Add records a timing.
+func (t *Timings) Add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(t.entries, Timing{Name: name, Duration: d})
+}
+
+// Entries returns the timings recorded so far in the order they finished.
+func (t *Timings) Entries() []Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Timing(nil), t.entries...)
+}
+
+// String formats the timings in the Server-Timing header format, e.g.
+// "handler;dur=12.5, outbound;desc=\"api.example.com\";dur=8.1".
+func (t *Timings) String() string {
+	entries := t.Entries()
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		name, desc, _ := strings.Cut(e.Name, ":")
+		part := name
+		if desc != "" {
+			part += fmt.Sprintf(";desc=%q", desc)
+		}
+		parts[i] = part + fmt.Sprintf(";dur=%.1f", float64(e.Duration.Microseconds())/1000)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Timed is middleware recording the time spent in next as name, e.g. to
+// separate the time taken by a handler from the middleware around it.
+func Timed(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer StartTiming(r.Context(), name)()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ServerTiming is middleware sending the timings of each request in a
+// Server-Timing header so they show up in browser devtools. It records the
+// handler duration up to the first write, the time WriteJSON spends
+// serialising and outbound calls made through TimingTransport. Timings
+// which finish after the response headers are sent are not included.
+func ServerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		timings, ok := TimingsFromContext(ctx)
+		if !ok {
+			ctx, timings = WithTimings(ctx)
+		}
+		tw := &timingWriter{ResponseWriter: w, t: timings, start: time.Now()}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+		tw.writeTimings()
+	})
+}
+
+// timingWriter adds the Server-Timing header before the response headers
+// are written.
+type timingWriter struct {
+	http.ResponseWriter
+	t       *Timings
+	start   time.Time
+	written bool
+}
+
+func (w *timingWriter) writeTimings() {
+	if w.written {
+		return
+	}
+	w.written = true
+	w.t.Add("handler", time.Since(w.start))
+	w.Header().Set("Server-Timing", w.t.String())
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	w.writeTimings()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(b []byte) (int, error) {
+	w.writeTimings()
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// timings returns the Timings for the response, used by writeJSON.
+func (w *timingWriter) timings() *Timings {
+	return w.t
+}
+
+// TimingTransport records every outbound request made with the request
+// context as "outbound:<host>". A nil Base uses http.DefaultTransport.
+type TimingTransport struct {
+	Base http.RoundTripper
+}
+
+// CloseIdleConnections closes idle connections of Base, see
+// http.Client.CloseIdleConnections.
+func (t TimingTransport) CloseIdleConnections() {
+	closeIdleConnections(t.Base)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t TimingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	defer StartTiming(r.Context(), "outbound:"+r.URL.Host)()
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,69 @@
+package faas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimingsString(t *testing.T) {
+	_, timings := WithTimings(context.Background())
+	timings.Add("handler", 12500*time.Microsecond)
+	timings.Add("outbound:api.example.com", 8*time.Millisecond)
+
+	want := `handler;dur=12.5, outbound;desc="api.example.com";dur=8.0`
+	if got := timings.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestTimingTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ctx, timings := WithTimings(context.Background())
+	client := &http.Client{Transport: TimingTransport{}}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	entries := timings.Entries()
+	if len(entries) != 1 || !strings.HasPrefix(entries[0].Name, "outbound:127.0.0.1") {
+		t.Errorf("unexpected timings %+v", entries)
+	}
+
+	// requests without a recording context are not affected
+	StartTiming(context.Background(), "ignored")()
+}
+
+func TestServerTiming(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	client := &http.Client{Transport: TimingTransport{}}
+
+	h := ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, srv.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+		_ = WriteJSON(w, http.StatusOK, Map{"ok": true}, nil)
+	}))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	got := rr.Header().Get("Server-Timing")
+	for _, name := range []string{"outbound;desc=", "serialize;dur=", "handler;dur="} {
+		if !strings.Contains(got, name) {
+			t.Errorf("expected %q in Server-Timing %q", name, got)
+		}
+	}
+}
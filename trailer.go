@@ -0,0 +1,83 @@
+package faas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Trailers written by TrailerWriter.
+const (
+	TrailerChecksum  = "X-Content-SHA256"
+	TrailerItemCount = "X-Item-Count"
+	TrailerTruncated = "X-Truncated"
+)
+
+// DeclareTrailers announces trailers which will be set after the body with
+// SetTrailer. It must be called before the status is written.
+func DeclareTrailers(w http.ResponseWriter, names ...string) {
+	for _, name := range names {
+		w.Header().Add("Trailer", http.CanonicalHeaderKey(name))
+	}
+}
+
+// SetTrailer sets a trailer once the body has been written. Trailers which
+// were not declared with DeclareTrailers are still sent, although some
+// proxies drop undeclared trailers.
+func SetTrailer(w http.ResponseWriter, name, value string) {
+	name = http.CanonicalHeaderKey(name)
+	for _, declared := range w.Header().Values("Trailer") {
+		for _, d := range strings.Split(declared, ",") {
+			if http.CanonicalHeaderKey(strings.TrimSpace(d)) == name {
+				w.Header().Set(name, value)
+				return
+			}
+		}
+	}
+	w.Header().Set(http.TrailerPrefix+name, value)
+}
+
+// TrailerWriter wraps a streaming response so clients can verify they
+// received all of it. It hashes everything written and, on Finish, sends the
+// SHA-256 of the body, the number of items counted with Item and whether
+// the stream was truncated as trailers.
+type TrailerWriter struct {
+	http.ResponseWriter
+	hash  hash.Hash
+	items int
+}
+
+// NewTrailerWriter declares the trailers on w. It must be called before the
+// status is written.
+func NewTrailerWriter(w http.ResponseWriter) *TrailerWriter {
+	DeclareTrailers(w, TrailerChecksum, TrailerItemCount, TrailerTruncated)
+	return &TrailerWriter{ResponseWriter: w, hash: sha256.New()}
+}
+
+func (t *TrailerWriter) Write(b []byte) (int, error) {
+	n, err := t.ResponseWriter.Write(b)
+	t.hash.Write(b[:n])
+	return n, err
+}
+
+// Item counts an item written to the stream.
+func (t *TrailerWriter) Item() {
+	t.items++
+}
+
+// Finish sets the trailers. Pass truncated when the stream ended early, e.g.
+// on an error or a size limit.
+func (t *TrailerWriter) Finish(truncated bool) {
+	w := t.ResponseWriter
+	w.Header().Set(TrailerChecksum, hex.EncodeToString(t.hash.Sum(nil)))
+	w.Header().Set(TrailerItemCount, strconv.Itoa(t.items))
+	w.Header().Set(TrailerTruncated, strconv.FormatBool(truncated))
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (t *TrailerWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
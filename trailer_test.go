@@ -0,0 +1,70 @@
+package faas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamTrailers(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []any
+		count     string
+		truncated string
+	}{
+		{name: "complete", values: []any{1, 2, 3}, count: "3", truncated: "false"},
+		{name: "encoding error", values: []any{1, func() {}}, count: "1", truncated: "true"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			values := tc.values
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ch := make(chan any, len(values))
+				for _, v := range values {
+					ch <- v
+				}
+				close(ch)
+				_ = StreamNDJSON(w, ch)
+			}))
+			defer srv.Close()
+
+			resp, err := srv.Client().Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256(body)
+			if got := resp.Trailer.Get(TrailerChecksum); got != hex.EncodeToString(sum[:]) {
+				t.Errorf("checksum trailer %q does not match the body", got)
+			}
+			if got := resp.Trailer.Get(TrailerItemCount); got != tc.count {
+				t.Errorf("item count = %q, want %q", got, tc.count)
+			}
+			if got := resp.Trailer.Get(TrailerTruncated); got != tc.truncated {
+				t.Errorf("truncated = %q, want %q", got, tc.truncated)
+			}
+		})
+	}
+}
+
+func TestSetTrailer(t *testing.T) {
+	rec := httptest.NewRecorder()
+	DeclareTrailers(rec, "x-rows")
+	rec.WriteHeader(http.StatusOK)
+	_, _ = rec.Write([]byte("data"))
+	SetTrailer(rec, "X-Rows", "10")
+	SetTrailer(rec, "X-Late", "yes")
+
+	trailer := rec.Result().Trailer
+	if trailer.Get("X-Rows") != "10" || trailer.Get("X-Late") != "yes" {
+		t.Errorf("unexpected trailers %v", trailer)
+	}
+}
@@ -0,0 +1,133 @@
+package faas
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// transliterations are the ASCII spellings of lower case letters, and of
+// the punctuation and symbols which have one. Upper case letters are
+// looked up by their lower case.
+var transliterations = map[rune]string{
+	// Latin
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a", 'ǎ': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'ĉ': "c", 'ċ': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ĕ': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ĝ': "g", 'ğ': "g", 'ġ': "g", 'ģ': "g", 'ĥ': "h", 'ħ': "h",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ĩ': "i", 'ī': "i", 'ĭ': "i", 'į': "i", 'ı': "i", 'ǐ': "i",
+	'ĵ': "j", 'ķ': "k", 'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ŀ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n", 'ŋ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ŏ': "o", 'ő': "o", 'ơ': "o", 'ǒ': "o",
+	'œ': "oe", 'ŕ': "r", 'ŗ': "r", 'ř': "r", 'ś': "s", 'ŝ': "s", 'ş': "s", 'ș': "s", 'š': "s", 'ß': "ss",
+	'ţ': "t", 'ț': "t", 'ť': "t", 'ŧ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ũ': "u", 'ū': "u", 'ŭ': "u", 'ů': "u", 'ű': "u", 'ų': "u", 'ư': "u", 'ǔ': "u",
+	'ŵ': "w", 'ý': "y", 'ÿ': "y", 'ŷ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+	// Vietnamese vowels with tone marks
+	'ả': "a", 'ạ': "a", 'ấ': "a", 'ầ': "a", 'ẩ': "a", 'ẫ': "a", 'ậ': "a", 'ắ': "a", 'ằ': "a", 'ẳ': "a", 'ẵ': "a", 'ặ': "a",
+	'ẻ': "e", 'ẽ': "e", 'ẹ': "e", 'ế': "e", 'ề': "e", 'ể': "e", 'ễ': "e", 'ệ': "e", 'ỉ': "i", 'ị': "i",
+	'ỏ': "o", 'ọ': "o", 'ố': "o", 'ồ': "o", 'ổ': "o", 'ỗ': "o", 'ộ': "o", 'ớ': "o", 'ờ': "o", 'ở': "o", 'ỡ': "o", 'ợ': "o",
+	'ủ': "u", 'ụ': "u", 'ứ': "u", 'ừ': "u", 'ử': "u", 'ữ': "u", 'ự': "u", 'ỳ': "y", 'ỷ': "y", 'ỹ': "y", 'ỵ': "y",
+	// Greek
+	'α': "a", 'ά': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'έ': "e", 'ζ': "z", 'η': "i", 'ή': "i",
+	'θ': "th", 'ι': "i", 'ί': "i", 'ϊ': "i", 'ΐ': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'ό': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'ύ': "y", 'ϋ': "y",
+	'ΰ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o", 'ώ': "o",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'ђ': "dj", 'е': "e", 'ё': "yo", 'є': "ye",
+	'ж': "zh", 'з': "z", 'ѕ': "dz", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'ј': "j", 'к': "k", 'л': "l",
+	'љ': "lj", 'м': "m", 'н': "n", 'њ': "nj", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'ћ': "c",
+	'у': "u", 'ў': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'џ': "dz", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// punctuation and symbols
+	'‘': "'", '’': "'", '‚': "'", '‛': "'", '“': "\"", '”': "\"", '„': "\"", '‟': "\"",
+	'«': "\"", '»': "\"", '‹': "'", '›': "'", '–': "-", '—': "-", '‐': "-", '‑': "-", '−': "-",
+	'…': "...", '•': "*", '·': ".", '\u00a0': " ", '×': "x", '÷': "/", '€': "EUR", '£': "GBP",
+	'¥': "JPY", '©': "(c)", '®': "(r)", '™': "(tm)", '°': "deg", '½': "1/2", '¼': "1/4", '¾': "3/4",
+}
+
+// Transliterate replaces the letters of the Latin, Greek and Cyrillic
+// scripts with their closest ASCII spelling, e.g. "Ærøskøbing" becomes
+// "Aeroskobing" and "Москва" becomes "Moskva". Common punctuation such as
+// curly quotes and dashes is replaced too. Other characters, including
+// those of scripts without a table such as Han, are kept.
+func Transliterate(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	runes := []rune(s)
+	for i, r := range runes {
+		if r < utf8.RuneSelf {
+			b.WriteRune(r)
+			continue
+		}
+		lower := unicode.ToLower(r)
+		t, ok := transliterations[lower]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		if lower != r && t != "" {
+			// "Ж" is "Zh" within a word, but "ZH" in an all caps word
+			next := i+1 < len(runes) && unicode.IsUpper(runes[i+1])
+			prev := i > 0 && unicode.IsUpper(runes[i-1])
+			if next || (prev && (i+1 == len(runes) || !unicode.IsLetter(runes[i+1]))) {
+				t = strings.ToUpper(t)
+			} else {
+				t = strings.ToUpper(t[:1]) + t[1:]
+			}
+		}
+		b.WriteString(t)
+	}
+	return b.String()
+}
+
+// SlugOptions configures SlugWith.
+type SlugOptions struct {
+	// Separator replaces the runs of characters which are not letters or
+	// digits. Defaults to "-".
+	Separator string
+	// MaxLength truncates the slug at a word boundary. Zero means no limit.
+	MaxLength int
+	// KeepCase keeps upper case letters instead of lower casing them.
+	KeepCase bool
+}
+
+// Slug returns a URL friendly version of s, transliterated to lower case
+// ASCII with words joined by dashes, e.g. "Crème Brûlée: 10 Tips!" becomes
+// "creme-brulee-10-tips". Characters which cannot be transliterated are
+// dropped, so the slug may be empty.
+func Slug(s string) string {
+	return SlugWith(s, SlugOptions{})
+}
+
+// SlugWith is Slug with options.
+func SlugWith(s string, opts SlugOptions) string {
+	sep := defaultString(opts.Separator, "-")
+	// apostrophes join, so "don't" is "dont" rather than "don-t"
+	s = strings.ReplaceAll(Transliterate(s), "'", "")
+	if !opts.KeepCase {
+		s = strings.ToLower(s)
+	}
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return r >= utf8.RuneSelf || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	var b strings.Builder
+	for _, w := range words {
+		need := len(w)
+		if b.Len() > 0 {
+			need += len(sep)
+		}
+		if opts.MaxLength > 0 && b.Len()+need > opts.MaxLength {
+			if b.Len() == 0 {
+				// a single word longer than the limit is cut
+				b.WriteString(w[:opts.MaxLength])
+			}
+			break
+		}
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(w)
+	}
+	return b.String()
+}
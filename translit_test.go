@@ -0,0 +1,50 @@
+package faas
+
+import "testing"
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Ærøskøbing", "Aeroskobing"},
+		{"Crème brûlée", "Creme brulee"},
+		{"Straße", "Strasse"},
+		{"Łódź", "Lodz"},
+		{"Москва", "Moskva"},
+		{"Щука ЖУК Жук", "Shchuka ZHUK Zhuk"},
+		{"Київ", "Kiyiv"},
+		{"Αθήνα", "Athina"},
+		{"Tiếng Việt", "Tieng Viet"},
+		{"“Quotes” – and…", "\"Quotes\" - and..."},
+		{"东京 stays", "东京 stays"},
+	}
+	for _, tt := range tests {
+		if got := Transliterate(tt.in); got != tt.want {
+			t.Errorf("Transliterate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		in   string
+		opts SlugOptions
+		want string
+	}{
+		{"Crème Brûlée: 10 Tips!", SlugOptions{}, "creme-brulee-10-tips"},
+		{"  Don't   stop__believin' ", SlugOptions{}, "dont-stop-believin"},
+		{"Привет, мир", SlugOptions{}, "privet-mir"},
+		{"东京", SlugOptions{}, ""},
+		{"Hello World", SlugOptions{Separator: "_", KeepCase: true}, "Hello_World"},
+		{"the quick brown fox", SlugOptions{MaxLength: 12}, "the-quick"},
+		{"supercalifragilistic", SlugOptions{MaxLength: 5}, "super"},
+	}
+	for _, tt := range tests {
+		if got := SlugWith(tt.in, tt.opts); got != tt.want {
+			t.Errorf("SlugWith(%q, %+v) = %q, want %q", tt.in, tt.opts, got, tt.want)
+		}
+	}
+	if got := Slug("A B"); got != "a-b" {
+		t.Errorf("Slug() = %q", got)
+	}
+}
@@ -0,0 +1,226 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypedHandler returns a handler for the route method and path, such as
+// "PATCH" and "/orders/{id}", which binds and validates a request model In
+// before calling fn, and writes the Out it returns as JSON with a 200:
+//
+//	type UpdateOrder struct {
+//		ID     string `path:"id" json:"-"`
+//		DryRun bool   `query:"dry_run" json:"-"`
+//		Status string `json:"status" validate:"required"`
+//	}
+//
+//	faas.TypedHandler("PATCH", "/orders/{id}", func(ctx context.Context, in *UpdateOrder) (*Order, error) { ... })
+//
+// Fields tagged path, query or header are read from the path parameter,
+// query parameter or header named by the tag; they may be strings, bools,
+// numbers, types implementing encoding.TextUnmarshaler, or slices of them
+// for repeated query parameters. A JSON body, when sent, is decoded into
+// the rest of In with unknown keys rejected. In is then checked with
+// Validate. Every problem found becomes one 422 response listing the
+// invalid fields, so fn only sees valid input. An empty method accepts
+// any method.
+//
+// Out is checked with Validate too, and a response failing it is a 500, so
+// the handler cannot break its contract unnoticed. Errors returned by fn
+// are written with WriteError.
+func TypedHandler[In, Out any](method, path string, fn func(ctx context.Context, in *In) (Out, error), opts ...BodyOptions) http.Handler {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if method != "" && !strings.EqualFold(r.Method, method) {
+			w.Header().Set("Allow", strings.ToUpper(method))
+			_ = writeJSONError(w, Error{Status: http.StatusText(http.StatusMethodNotAllowed), Code: http.StatusMethodNotAllowed})
+			return
+		}
+		params, ok := matchPath(segments, r.URL.Path)
+		if !ok {
+			_ = writeError(w, E(CodeNotFound, "no route for "+r.URL.Path, nil))
+			return
+		}
+
+		in := new(In)
+		if err := bindRequest(w, r, in, params, opts); err != nil {
+			_ = writeError(w, err)
+			return
+		}
+		out, err := fn(r.Context(), in)
+		if err != nil {
+			_ = writeError(w, err)
+			return
+		}
+		if err := validate(out); err != nil {
+			_ = writeError(w, E(CodeInternal, "response failed validation", err))
+			return
+		}
+		_ = writeJSON(w, http.StatusOK, out, nil)
+	})
+}
+
+// matchPath returns the parameters of path matched against the segments of
+// a route path, where {name} segments match any value.
+func matchPath(segments []string, path string) (map[string]string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != len(segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, s := range segments {
+		if name, ok := strings.CutPrefix(s, "{"); ok && strings.HasSuffix(name, "}") {
+			params[strings.TrimSuffix(name, "}")] = parts[i]
+			continue
+		}
+		if s != parts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// bindRequest fills in from the request, returning a 422 AppError listing
+// every invalid field.
+func bindRequest(w http.ResponseWriter, r *http.Request, in any, params map[string]string, opts []BodyOptions) error {
+	data, err := ReadBody(w, r, opts...)
+	if err != nil {
+		return err
+	}
+	var errs ValidationErrors
+	if len(bytes.TrimSpace(data)) > 0 {
+		dec := jsonCodec().NewDecoder(bytes.NewReader(data), true)
+		if err := dec.Decode(in); err != nil {
+			errs = append(errs, FieldError{Field: "body", Message: triageJSONError(err, len(data)).Error()})
+		} else if dec.Decode(&struct{}{}) != io.EOF {
+			errs = append(errs, FieldError{Field: "body", Message: "body must only contain a single JSON value"})
+		}
+	}
+
+	rv := reflect.ValueOf(in).Elem()
+	if rv.Kind() == reflect.Struct {
+		query := r.URL.Query()
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			sf := rt.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			var values []string
+			name, source := "", ""
+			switch {
+			case sf.Tag.Get("path") != "":
+				name, source = sf.Tag.Get("path"), "path"
+				if v, ok := params[name]; ok {
+					values = []string{v}
+				}
+			case sf.Tag.Get("query") != "":
+				name, source = sf.Tag.Get("query"), "query"
+				values = query[name]
+			case sf.Tag.Get("header") != "":
+				name, source = sf.Tag.Get("header"), "header"
+				values = r.Header.Values(name)
+			default:
+				continue
+			}
+			if len(values) == 0 {
+				continue
+			}
+			if err := setParam(rv.Field(i), values); err != nil {
+				errs = append(errs, FieldError{Field: name, Message: fmt.Sprintf("%s parameter %s", source, err)})
+			}
+		}
+	}
+
+	var invalid ValidationErrors
+	if err := validate(in); errors.As(err, &invalid) {
+		errs = append(errs, invalid...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	fields := make(map[string]any, len(errs))
+	for _, fe := range errs {
+		if _, ok := fields[fe.Field]; !ok {
+			fields[fe.Field] = fe.Message
+		}
+	}
+	appErr := E(CodeInvalidArgument, "validation failed", errs).WithStatus(http.StatusUnprocessableEntity)
+	appErr.Fields = fields
+	return appErr
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setParam converts the values of a path, query or header parameter into
+// field.
+func setParam(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !field.Type().Implements(textUnmarshalerType) && !reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setScalar(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+		field.Set(s)
+		return nil
+	}
+	return setScalar(field, values[0])
+}
+
+func setScalar(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setScalar(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("is invalid: %v", err)
+		}
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("has unsupported type %s", v.Type())
+	}
+	return nil
+}
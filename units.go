@@ -0,0 +1,303 @@
+package faas
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var sizeUnits = map[string]int64{
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// formatSizeUnits is ordered largest first for FormatSize.
+var formatSizeUnits = []struct {
+	name string
+	size int64
+}{
+	{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+}
+
+// ParseSize parses a human readable size such as "512", "10MB" or "1.5GiB"
+// into bytes. KB, MB, GB and TB are powers of 1000 while KiB, MiB, GiB and
+// TiB are powers of 1024. Units are case insensitive.
+func ParseSize(s string) (int64, error) {
+	return parseSize(s)
+}
+func parseSize(s string) (int64, error) {
+	num, unit := splitNumber(strings.TrimSpace(s))
+	if num == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if unit == "" {
+		unit = "b"
+	}
+	mult, ok := sizeUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, unit)
+	}
+
+	d, err := parseDecimal(num)
+	if err != nil || d.Sign() < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	bytes, err := d.Mul(NewDecimal(mult, 0)).Int64(0)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: not a whole number of bytes", s)
+	}
+	return bytes, nil
+}
+
+// FormatSize formats n bytes using the largest unit which represents it
+// exactly, so ParseSize(FormatSize(n)) == n.
+func FormatSize(n int64) string {
+	return formatSize(n)
+}
+func formatSize(n int64) string {
+	if n == 0 {
+		return "0B"
+	}
+	for _, u := range formatSizeUnits {
+		if n%u.size == 0 {
+			return strconv.FormatInt(n/u.size, 10) + u.name
+		}
+	}
+	// fall back to at most three decimal places of a decimal unit, counting
+	// thousandths of the unit so n is never multiplied past int64
+	for _, u := range formatSizeUnits[4:] {
+		milli := u.size / 1000
+		if n >= u.size && n%milli == 0 {
+			return NewDecimal(n/milli, 3).trimZeros().String() + u.name
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
+
+// trimZeros removes trailing fractional zeros, e.g. 1.500 becomes 1.5.
+func (d Decimal) trimZeros() Decimal {
+	for d.scale > 0 && d.Round(d.scale-1).Cmp(d) == 0 {
+		d = d.Round(d.scale - 1)
+	}
+	return d
+}
+
+// ParseDuration extends time.ParseDuration with days ("d") and weeks ("w"),
+// so values such as "2d6h" or "1w" are accepted. A day is always 24 hours.
+func ParseDuration(s string) (time.Duration, error) {
+	return parseDuration(s)
+}
+func parseDuration(s string) (time.Duration, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+
+	var total time.Duration
+	var rest strings.Builder
+	for s != "" {
+		num, after := splitNumber(s)
+		unitLen := strings.IndexFunc(after, unicode.IsDigit)
+		if unitLen < 0 {
+			unitLen = len(after)
+		}
+		unit := after[:unitLen]
+		if num == "" || unit == "" {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		s = after[unitLen:]
+
+		var mult time.Duration
+		switch unit {
+		case "d":
+			mult = 24 * time.Hour
+		case "w":
+			mult = 7 * 24 * time.Hour
+		default:
+			rest.WriteString(num + unit)
+			continue
+		}
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		total += time.Duration(n * float64(mult))
+	}
+
+	if rest.Len() > 0 {
+		d, err := time.ParseDuration(rest.String())
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		total += d
+	}
+	if neg {
+		total = -total
+	}
+	return total, nil
+}
+
+// FormatDuration formats d using days for anything of 24 hours or more, e.g.
+// "2d6h" or "1d30m", so ParseDuration(FormatDuration(d)) == d.
+func FormatDuration(d time.Duration) string {
+	return formatDuration(d)
+}
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	var b strings.Builder
+	for _, u := range []struct {
+		name string
+		size time.Duration
+	}{
+		{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second},
+		{"ms", time.Millisecond}, {"us", time.Microsecond}, {"ns", time.Nanosecond},
+	} {
+		if n := d / u.size; n > 0 {
+			b.WriteString(strconv.FormatInt(int64(n), 10) + u.name)
+			d -= n * u.size
+		}
+	}
+	return sign + b.String()
+}
+
+// Rate is a number of events per period, such as 100 per second.
+type Rate struct {
+	Count  int
+	Period time.Duration
+}
+
+// ParseRate parses rates such as "100/s", "5/min", "1000/1h" or "10/2m".
+// The period accepts the units of ParseDuration plus "sec", "min", "hour"
+// and "day", and defaults to a count of one when no number is given.
+func ParseRate(s string) (Rate, error) {
+	return parseRate(s)
+}
+func parseRate(s string) (Rate, error) {
+	count, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q, expected a value like 100/s", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n < 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q", s)
+	}
+
+	period = strings.TrimSpace(period)
+	switch period {
+	case "sec", "second":
+		period = "s"
+	case "min", "minute":
+		period = "m"
+	case "hour":
+		period = "h"
+	case "day":
+		period = "d"
+	}
+	if period != "" && !unicode.IsDigit(rune(period[0])) {
+		period = "1" + period
+	}
+	d, err := parseDuration(period)
+	if err != nil || d <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q", s)
+	}
+	return Rate{Count: n, Period: d}, nil
+}
+
+// PerSecond returns the rate as events per second.
+func (r Rate) PerSecond() float64 {
+	if r.Period <= 0 {
+		return math.Inf(1)
+	}
+	return float64(r.Count) / r.Period.Seconds()
+}
+
+// Interval returns the average time between events.
+func (r Rate) Interval() time.Duration {
+	if r.Count == 0 {
+		return 0
+	}
+	return r.Period / time.Duration(r.Count)
+}
+
+// String formats r so that ParseRate(r.String()) == r, e.g. "100/s".
+func (r Rate) String() string {
+	period := formatDuration(r.Period)
+	if strings.HasPrefix(period, "1") && len(period) > 1 && !unicode.IsDigit(rune(period[1])) &&
+		strings.IndexFunc(period[1:], unicode.IsDigit) < 0 {
+		period = period[1:]
+	}
+	return strconv.Itoa(r.Count) + "/" + period
+}
+
+// splitNumber splits a leading decimal number from the rest of s.
+func splitNumber(s string) (string, string) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler so rates can be used in
+// JSON config and query parameters.
+func (r *Rate) UnmarshalText(text []byte) error {
+	parsed, err := parseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// Duration is a time.Duration which is encoded as text using ParseDuration
+// and FormatDuration, e.g. "2d6h" in JSON config files.
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := parseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(formatDuration(time.Duration(d))), nil
+}
+
+// String formats d with FormatDuration.
+func (d Duration) String() string {
+	return formatDuration(time.Duration(d))
+}
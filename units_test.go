@@ -0,0 +1,128 @@
+package faas
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  int64
+		expectErr bool
+	}{
+		{input: "512", expected: 512},
+		{input: "10MB", expected: 10_000_000},
+		{input: "10mib", expected: 10 << 20},
+		{input: "1.5 GiB", expected: 3 << 29},
+		{input: "0.5B", expectErr: true},
+		{input: "10 parsecs", expectErr: true},
+		{input: "MB", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseSize(tc.input)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatSizeRoundTrip(t *testing.T) {
+	tests := map[int64]string{
+		0:         "0B",
+		1 << 20:   "1MiB",
+		5000:      "5KB",
+		1500:      "1.5KB",
+		1_234_567: "1234.567KB",
+		7:         "7B",
+		// n*1000 would overflow int64 for these
+		1<<62 + 1000:  "4611686018427388.904KB",
+		math.MaxInt64: "9223372036854775.807KB",
+	}
+	for n, expected := range tests {
+		got := formatSize(n)
+		if got != expected {
+			t.Errorf("expected %d to format as %s, got %s", n, expected, got)
+		}
+		back, err := parseSize(got)
+		if err != nil || back != n {
+			t.Errorf("expected %s to parse back to %d, got %d (%v)", got, n, back, err)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{input: "2d6h", expected: 54 * time.Hour},
+		{input: "1w", expected: 7 * 24 * time.Hour},
+		{input: "1d30m15s", expected: 24*time.Hour + 30*time.Minute + 15*time.Second},
+		{input: "-1.5d", expected: -36 * time.Hour},
+		{input: "250ms", expected: 250 * time.Millisecond},
+		{input: "2 days", expectErr: true},
+		{input: "d", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseDuration(tc.input)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+			if !tc.expectErr {
+				back, _ := parseDuration(formatDuration(got))
+				if back != got {
+					t.Errorf("expected %s to round trip, got %v", formatDuration(got), back)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  Rate
+		str       string
+		expectErr bool
+	}{
+		{input: "100/s", expected: Rate{Count: 100, Period: time.Second}, str: "100/s"},
+		{input: "5/min", expected: Rate{Count: 5, Period: time.Minute}, str: "5/m"},
+		{input: "1000/1h", expected: Rate{Count: 1000, Period: time.Hour}, str: "1000/h"},
+		{input: "10/2m", expected: Rate{Count: 10, Period: 2 * time.Minute}, str: "10/2m"},
+		{input: "100", expectErr: true},
+		{input: "x/s", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseRate(tc.input)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if tc.expectErr {
+				return
+			}
+			if got != tc.expected || got.String() != tc.str {
+				t.Errorf("expected %+v (%s), got %+v (%s)", tc.expected, tc.str, got, got)
+			}
+		})
+	}
+
+	if r := (Rate{Count: 100, Period: time.Second}); r.Interval() != 10*time.Millisecond || r.PerSecond() != 100 {
+		t.Errorf("unexpected interval %v or per second %v", r.Interval(), r.PerSecond())
+	}
+}
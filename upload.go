@@ -0,0 +1,190 @@
+package faas
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UploadOptions are the limits enforced by ReadUpload. Zero values fall back
+// to the defaults noted on each field.
+type UploadOptions struct {
+	// Field restricts the upload to a single form field. All file fields are
+	// read when empty.
+	Field string
+	// MaxFiles is the maximum number of files accepted. Default 10.
+	MaxFiles int
+	// MaxFileSize is the maximum size of a single file. Default 10MB.
+	MaxFileSize int64
+	// MaxTotalSize is the maximum size of the whole request body. Default 32MB.
+	MaxTotalSize int64
+	// AllowedTypes lists the accepted MIME types as detected from the file
+	// content, e.g. "image/png". Any type is accepted when empty.
+	AllowedTypes []string
+	// AllowedExtensions lists the accepted file extensions including the
+	// dot, e.g. ".png". Any extension is accepted when empty.
+	AllowedExtensions []string
+}
+
+// Upload is a single file received by ReadUpload.
+type Upload struct {
+	Field    string
+	Filename string
+	Size     int64
+	// ContentType is sniffed from the file content rather than trusted from
+	// the client.
+	ContentType string
+
+	header *multipart.FileHeader
+}
+
+// Open returns a reader for the uploaded file.
+func (u *Upload) Open() (multipart.File, error) {
+	return u.header.Open()
+}
+
+// CopyTo writes the uploaded file to dst.
+func (u *Upload) CopyTo(dst io.Writer) (int64, error) {
+	f, err := u.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(dst, f)
+}
+
+// SaveTo writes the uploaded file into dir and returns its path. The client
+// supplied filename is only used for its extension so it cannot be used to
+// escape dir. An empty dir uses os.TempDir.
+func (u *Upload) SaveTo(dir string) (string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	f, err := os.CreateTemp(dir, "upload-*"+filepath.Ext(u.Filename))
+	if err != nil {
+		return "", err
+	}
+	if _, err := u.CopyTo(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// ReadUpload parses a multipart/form-data request and returns the uploaded
+// files after enforcing opts. Call CleanupUpload once the files are no longer
+// needed to remove any temporary files created while parsing.
+func ReadUpload(r *http.Request, opts UploadOptions) ([]*Upload, error) {
+	return readUpload(r, opts)
+}
+func readUpload(r *http.Request, opts UploadOptions) (uploads []*Upload, err error) {
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = 10
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = 10 << 20
+	}
+	if opts.MaxTotalSize <= 0 {
+		opts.MaxTotalSize = 32 << 20
+	}
+
+	r.Body = http.MaxBytesReader(nil, r.Body, opts.MaxTotalSize)
+	// files larger than 1MB are spooled to disk rather than held in memory
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			return nil, fmt.Errorf("body must not be larger than %d bytes", opts.MaxTotalSize)
+		case errors.Is(err, http.ErrNotMultipart):
+			return nil, errors.New("body must be multipart/form-data")
+		default:
+			return nil, err
+		}
+	}
+
+	// the caller only cleans up after a successful read
+	defer func() {
+		if err != nil {
+			_ = CleanupUpload(r)
+		}
+	}()
+
+	for field, headers := range r.MultipartForm.File {
+		if opts.Field != "" && field != opts.Field {
+			continue
+		}
+		for _, fh := range headers {
+			if len(uploads) == opts.MaxFiles {
+				return nil, fmt.Errorf("body must not contain more than %d files", opts.MaxFiles)
+			}
+			upload, err := checkUpload(field, fh, opts)
+			if err != nil {
+				return nil, err
+			}
+			uploads = append(uploads, upload)
+		}
+	}
+	if len(uploads) == 0 {
+		return nil, errors.New("body must contain a file")
+	}
+	return uploads, nil
+}
+
+// CleanupUpload removes the temporary files created by ReadUpload.
+func CleanupUpload(r *http.Request) error {
+	if r.MultipartForm == nil {
+		return nil
+	}
+	return r.MultipartForm.RemoveAll()
+}
+
+func checkUpload(field string, fh *multipart.FileHeader, opts UploadOptions) (*Upload, error) {
+	if fh.Size > opts.MaxFileSize {
+		return nil, fmt.Errorf("file %q must not be larger than %d bytes", fh.Filename, opts.MaxFileSize)
+	}
+
+	ext := strings.ToLower(filepath.Ext(fh.Filename))
+	if len(opts.AllowedExtensions) > 0 && !containsFold(opts.AllowedExtensions, ext) {
+		return nil, fmt.Errorf("file %q has a disallowed extension", fh.Filename)
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(f, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	contentType := http.DetectContentType(sniff[:n])
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	if len(opts.AllowedTypes) > 0 && !containsFold(opts.AllowedTypes, mediaType) {
+		return nil, fmt.Errorf("file %q has a disallowed content type %s", fh.Filename, mediaType)
+	}
+
+	return &Upload{
+		Field:       field,
+		Filename:    filepath.Base(fh.Filename),
+		Size:        fh.Size,
+		ContentType: contentType,
+		header:      fh,
+	}, nil
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
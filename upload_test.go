@@ -0,0 +1,122 @@
+package faas
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func newUploadRequest(t *testing.T, files map[string][]byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, content := range files {
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write(content)
+	}
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestReadUpload(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+
+	tests := []struct {
+		name      string
+		files     map[string][]byte
+		opts      UploadOptions
+		expectErr bool
+	}{
+		{
+			name:  "allowed png",
+			files: map[string][]byte{"a.png": png},
+			opts:  UploadOptions{AllowedTypes: []string{"image/png"}, AllowedExtensions: []string{".png"}},
+		},
+		{
+			name:      "disguised text file",
+			files:     map[string][]byte{"a.png": []byte("just text")},
+			opts:      UploadOptions{AllowedTypes: []string{"image/png"}},
+			expectErr: true,
+		},
+		{
+			name:      "disallowed extension",
+			files:     map[string][]byte{"a.exe": png},
+			opts:      UploadOptions{AllowedExtensions: []string{".png"}},
+			expectErr: true,
+		},
+		{
+			name:      "file too large",
+			files:     map[string][]byte{"a.png": bytes.Repeat([]byte("a"), 100)},
+			opts:      UploadOptions{MaxFileSize: 10},
+			expectErr: true,
+		},
+		{
+			name:      "body too large",
+			files:     map[string][]byte{"a.png": bytes.Repeat([]byte("a"), 1000)},
+			opts:      UploadOptions{MaxTotalSize: 100},
+			expectErr: true,
+		},
+		{
+			name:      "too many files",
+			files:     map[string][]byte{"a.png": png, "b.png": png},
+			opts:      UploadOptions{MaxFiles: 1},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newUploadRequest(t, tc.files)
+			defer CleanupUpload(req)
+
+			uploads, err := readUpload(req, tc.opts)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error but didn't get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("didn't expected an error but got one: %v", err)
+			}
+			if len(uploads) != 1 || uploads[0].ContentType != "image/png" {
+				t.Fatalf("unexpected uploads %+v", uploads)
+			}
+
+			path, err := uploads[0].SaveTo(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			saved, _ := os.ReadFile(path)
+			if !bytes.Equal(saved, png) {
+				t.Errorf("saved file does not match upload")
+			}
+		})
+	}
+}
+
+func TestReadUploadRemovesTempFilesOnError(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	// over the 1MB in memory limit, so the file is spooled to disk
+	req := newUploadRequest(t, map[string][]byte{"a.txt": bytes.Repeat([]byte("a"), 2<<20)})
+	if _, err := readUpload(req, UploadOptions{AllowedExtensions: []string{".png"}}); err == nil {
+		t.Fatal("expected an error")
+	}
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
@@ -0,0 +1,74 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+)
+
+// UpstreamRule maps a failed upstream call to the error returned to the
+// function's caller.
+type UpstreamRule struct {
+	// Status matches the upstream status code. Zero matches any status.
+	Status int
+	// Body optionally matches the start of the upstream response body kept
+	// in StatusError.Body, e.g. regexp.MustCompile(`"code":"card_declined"`).
+	Body *regexp.Regexp
+	// Code is the status returned to the caller. Zero passes the upstream
+	// status through.
+	Code int
+	// Reason replaces the default reason, which names the upstream status
+	// but never includes the upstream body.
+	Reason string
+}
+
+// UpstreamErrors translates errors from outbound calls, such as the
+// *StatusError returned by DoJSON, into consistent responses for proxy
+// style functions. Rules are tried in order. Without a matching rule
+// upstream failures become 502 Bad Gateway and timeouts 504 Gateway
+// Timeout, so callers can tell them apart from their own mistakes.
+type UpstreamErrors struct {
+	Rules []UpstreamRule
+}
+
+// Translate returns the response for err.
+func (u *UpstreamErrors) Translate(err error) Error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		for _, rule := range u.Rules {
+			if rule.Status != 0 && rule.Status != statusErr.StatusCode {
+				continue
+			}
+			if rule.Body != nil && !rule.Body.MatchString(statusErr.Body) {
+				continue
+			}
+			code := rule.Code
+			if code == 0 {
+				code = statusErr.StatusCode
+			}
+			reason := rule.Reason
+			if reason == "" {
+				reason = upstreamReason(statusErr)
+			}
+			return Error{Status: http.StatusText(code), Reason: reason, Code: code}
+		}
+		return Error{Status: http.StatusText(http.StatusBadGateway), Reason: upstreamReason(statusErr), Code: http.StatusBadGateway}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return Error{Status: http.StatusText(http.StatusGatewayTimeout), Reason: "upstream request timed out", Code: http.StatusGatewayTimeout}
+	}
+	return Error{Status: http.StatusText(http.StatusBadGateway), Reason: "upstream request failed", Code: http.StatusBadGateway}
+}
+
+// Write writes the translated err as a JSON error response.
+func (u *UpstreamErrors) Write(w http.ResponseWriter, err error) error {
+	return writeJSONError(w, u.Translate(err))
+}
+
+func upstreamReason(e *StatusError) string {
+	return "upstream responded " + http.StatusText(e.StatusCode)
+}
@@ -0,0 +1,41 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestUpstreamErrorsTranslate(t *testing.T) {
+	u := &UpstreamErrors{Rules: []UpstreamRule{
+		{Status: http.StatusNotFound, Code: http.StatusNotFound, Reason: "customer not found"},
+		{Status: http.StatusPaymentRequired, Body: regexp.MustCompile(`"code":"card_declined"`), Code: http.StatusUnprocessableEntity, Reason: "card declined"},
+		{Status: http.StatusTooManyRequests},
+	}}
+
+	tests := []struct {
+		name   string
+		err    error
+		code   int
+		reason string
+	}{
+		{name: "mapped 404", err: &StatusError{StatusCode: 404}, code: 404, reason: "customer not found"},
+		{name: "body pattern", err: fmt.Errorf("charge: %w", &StatusError{StatusCode: 402, Body: `{"code":"card_declined"}`}), code: 422, reason: "card declined"},
+		{name: "body pattern not matched", err: &StatusError{StatusCode: 402, Body: `{"code":"expired"}`}, code: 502, reason: "upstream responded Payment Required"},
+		{name: "status passed through", err: &StatusError{StatusCode: 429}, code: 429, reason: "upstream responded Too Many Requests"},
+		{name: "unmapped 500", err: &StatusError{StatusCode: 500, Body: "stack trace"}, code: 502, reason: "upstream responded Internal Server Error"},
+		{name: "timeout", err: fmt.Errorf("get: %w", context.DeadlineExceeded), code: 504, reason: "upstream request timed out"},
+		{name: "connection error", err: errors.New("connection refused"), code: 502, reason: "upstream request failed"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := u.Translate(tc.err)
+			if got.Code != tc.code || got.Reason != tc.reason || got.Status != http.StatusText(tc.code) {
+				t.Errorf("expected %d %q, got %+v", tc.code, tc.reason, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package faas
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ValidatorFunc checks a single field value. param is the text after "=" in
+// the tag, e.g. "5" for `validate:"max=5"`, and is empty when not given.
+type ValidatorFunc func(v reflect.Value, param string) error
+
+// FieldError describes a single field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is returned by Validate when one or more fields are
+// invalid.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, fe := range v {
+		msgs = append(msgs, fmt.Sprintf("%s %s", fe.Field, fe.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidatorFunc{}
+)
+
+// RegisterValidator makes fn available as a `validate` struct tag rule.
+// Registering an existing name replaces it.
+func RegisterValidator(name string, fn ValidatorFunc) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = fn
+}
+
+// Validate checks the fields of the struct pointed to by v against their
+// `validate` tags, e.g. `validate:"required,email"`. Nested structs are
+// validated too and field names are taken from the json tag when present,
+// else from a path, query or header tag. Empty fields skip their rules unless
+// required, except numbers whose zero is checked too, so optional numbers
+// should be pointers.
+func Validate(v any) error {
+	return validate(v)
+}
+func validate(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	validateStruct(rv, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix string, errs *ValidationErrors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := prefix + fieldName(sf)
+		fv := rv.Field(i)
+
+		tag := sf.Tag.Get("validate")
+		if tag != "" && tag != "-" {
+			required := false
+			rules := strings.Split(tag, ",")
+			for _, rule := range rules {
+				if rule == "required" {
+					required = true
+				}
+			}
+			if fv.IsZero() && (required || !isNumberKind(fv.Kind())) {
+				if required {
+					*errs = append(*errs, FieldError{Field: name, Message: "is required"})
+				}
+				// optional empty fields skip the remaining rules, numbers
+				// are always present so zero is checked against them
+				continue
+			}
+			// rules apply to the value a pointer field is set to
+			rv := fv
+			for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+				rv = rv.Elem()
+			}
+			for _, rule := range rules {
+				if rule == "required" || rule == "" {
+					continue
+				}
+				ruleName, param, _ := strings.Cut(rule, "=")
+				validatorsMu.RLock()
+				fn, ok := validators[ruleName]
+				validatorsMu.RUnlock()
+				if !ok {
+					*errs = append(*errs, FieldError{Field: name, Message: fmt.Sprintf("has unknown rule %q", ruleName)})
+					continue
+				}
+				if err := fn(rv, param); err != nil {
+					*errs = append(*errs, FieldError{Field: name, Message: err.Error()})
+				}
+			}
+		}
+
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && fv.Type().PkgPath() != "time" {
+			validateStruct(fv, name+".", errs)
+		}
+	}
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func fieldName(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("json"); tag != "" {
+		if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	// parameters bound by TypedHandler are named as in the request
+	for _, key := range []string{"path", "query", "header"} {
+		if name := sf.Tag.Get(key); name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
@@ -0,0 +1,137 @@
+package faas
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type address struct {
+		Website string `json:"website" validate:"url"`
+	}
+	type contact struct {
+		Email   string   `json:"email" validate:"required,email"`
+		Phone   string   `json:"phone" validate:"e164"`
+		Name    string   `json:"name" validate:"max=5"`
+		Tags    []string `json:"tags" validate:"min=2"`
+		Age     int      `json:"age" validate:"min=18,max=130"`
+		Address *address `json:"address"`
+	}
+
+	tests := []struct {
+		name   string
+		input  contact
+		fields []string
+	}{
+		{
+			name:  "valid contact",
+			input: contact{Email: "jane@example.com", Phone: "+61412345678", Name: "Zoë", Tags: []string{"a", "b"}, Age: 30, Address: &address{Website: "https://example.com"}},
+		},
+		{
+			name:   "missing email",
+			input:  contact{},
+			fields: []string{"email", "age"},
+		},
+		{
+			name:   "invalid fields",
+			input:  contact{Email: "Jane <jane@example.com>", Phone: "0412345678", Age: 30, Address: &address{Website: "example.com"}},
+			fields: []string{"email", "phone", "address.website"},
+		},
+		{
+			name:   "out of bounds",
+			input:  contact{Email: "jane@example.com", Name: "Janette", Tags: []string{"a"}, Age: 12},
+			fields: []string{"name", "tags", "age"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate(&tc.input)
+			if len(tc.fields) == 0 {
+				if err != nil {
+					t.Fatalf("didn't expected an error but got one: %v", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("expected ValidationErrors, got %v", err)
+			}
+			if len(verrs) != len(tc.fields) {
+				t.Fatalf("expected %d field errors, got %v", len(tc.fields), verrs)
+			}
+			for i, field := range tc.fields {
+				if verrs[i].Field != field {
+					t.Errorf("expected error for %s, got %s", field, verrs[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateBounds(t *testing.T) {
+	type order struct {
+		Quantity int  `json:"quantity" validate:"min=1"`
+		Discount *int `json:"discount" validate:"max=50"`
+	}
+	zero, half, most := 0, 50, 80
+	tests := []struct {
+		name   string
+		input  order
+		fields []string
+	}{
+		{name: "within bounds", input: order{Quantity: 1, Discount: &half}},
+		{name: "min=1 with 0", input: order{Quantity: 0}, fields: []string{"quantity"}},
+		{name: "unset pointer", input: order{Quantity: 2}},
+		{name: "pointer to zero", input: order{Quantity: 2, Discount: &zero}},
+		{name: "pointer over max", input: order{Quantity: 2, Discount: &most}, fields: []string{"discount"}},
+	}
+	for _, tc := range tests {
+		err := validate(&tc.input)
+		var verrs ValidationErrors
+		errors.As(err, &verrs)
+		got := make([]string, 0, len(verrs))
+		for _, fe := range verrs {
+			got = append(got, fe.Field)
+		}
+		if len(got) != len(tc.fields) || (len(got) > 0 && got[0] != tc.fields[0]) {
+			t.Errorf("%s: invalid fields %v, want %v", tc.name, got, tc.fields)
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{input: "+61 412 345 678", expected: "+61412345678"},
+		{input: "0044 (20) 7946-0958", expected: "+442079460958"},
+		{input: "0412 345 678", expectErr: true},
+		{input: "+1 555 CALL NOW", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := normalizePhone(tc.input)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	got, err := normalizeEmail("  Jane.Doe@Example.COM ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Jane.Doe@example.com" {
+		t.Errorf("expected Jane.Doe@example.com, got %s", got)
+	}
+}
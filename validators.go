@@ -0,0 +1,188 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func init() {
+	RegisterValidator("email", stringRule(func(s, param string) error {
+		if err := validateEmail(s); err != nil {
+			return err
+		}
+		if param == "mx" {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return checkMX(ctx, s)
+		}
+		return nil
+	}))
+	RegisterValidator("e164", stringRule(func(s, _ string) error {
+		if !isE164(s) {
+			return errors.New("must be an E.164 phone number")
+		}
+		return nil
+	}))
+	RegisterValidator("url", stringRule(func(s, _ string) error {
+		return validateURL(s)
+	}))
+	RegisterValidator("cron", stringRule(func(s, _ string) error {
+		if _, err := ParseCron(s); err != nil {
+			return fmt.Errorf("must be a valid cron expression: %v", err)
+		}
+		return nil
+	}))
+	RegisterValidator("min", boundRule("least", func(n, bound float64) bool { return n >= bound }))
+	RegisterValidator("max", boundRule("most", func(n, bound float64) bool { return n <= bound }))
+}
+
+// boundRule checks the value of numbers, and the length of strings, in
+// characters, and of slices and maps, against the rule's parameter.
+func boundRule(word string, ok func(n, bound float64) bool) ValidatorFunc {
+	return func(v reflect.Value, param string) error {
+		bound, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return fmt.Errorf("has an invalid bound %q", param)
+		}
+		var n float64
+		unit := ""
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+		case reflect.String:
+			n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			n, unit = float64(v.Len()), " items"
+		default:
+			return errors.New("must be a number, string, slice or map")
+		}
+		if !ok(n, bound) {
+			return fmt.Errorf("must be at %s %s%s", word, param, unit)
+		}
+		return nil
+	}
+}
+
+// stringRule adapts a string check to a ValidatorFunc.
+func stringRule(fn func(s, param string) error) ValidatorFunc {
+	return func(v reflect.Value, param string) error {
+		if v.Kind() != reflect.String {
+			return errors.New("must be a string")
+		}
+		return fn(v.String(), param)
+	}
+}
+
+// ValidateEmail checks s is a bare email address such as "jane@example.com".
+// Display names like "Jane <jane@example.com>" are rejected.
+func ValidateEmail(s string) error {
+	return validateEmail(s)
+}
+func validateEmail(s string) error {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s || addr.Name != "" {
+		return errors.New("must be a valid email address")
+	}
+	_, domain, _ := strings.Cut(s, "@")
+	if !strings.Contains(domain, ".") {
+		return errors.New("must be a valid email address")
+	}
+	return nil
+}
+
+// NormalizeEmail trims s and lower cases the domain. The local part is left
+// alone as it is case sensitive in principle.
+func NormalizeEmail(s string) (string, error) {
+	return normalizeEmail(s)
+}
+func normalizeEmail(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if err := validateEmail(s); err != nil {
+		return "", err
+	}
+	local, domain, _ := strings.Cut(s, "@")
+	return local + "@" + strings.ToLower(domain), nil
+}
+
+// CheckMX verifies the domain of email has at least one MX record.
+func CheckMX(ctx context.Context, email string) error {
+	return checkMX(ctx, email)
+}
+func checkMX(ctx context.Context, email string) error {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return errors.New("must be a valid email address")
+	}
+	records, err := net.DefaultResolver.LookupMX(ctx, domain)
+	if err != nil || len(records) == 0 {
+		return fmt.Errorf("domain %s does not accept email", domain)
+	}
+	return nil
+}
+
+// NormalizePhone converts a phone number written with spaces, dashes, dots
+// or brackets, and an optional "00" international prefix, into E.164 form
+// such as "+61412345678". Numbers without a country code are rejected.
+func NormalizePhone(s string) (string, error) {
+	return normalizePhone(s)
+}
+func normalizePhone(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "00") {
+		s = "+" + s[2:]
+	}
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", errors.New("must be a valid phone number")
+		}
+	}
+	phone := b.String()
+	if !isE164(phone) {
+		return "", errors.New("must be a phone number with a country code")
+	}
+	return phone, nil
+}
+
+func isE164(s string) bool {
+	if len(s) < 8 || len(s) > 16 || s[0] != '+' || s[1] == '0' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL checks s is an absolute http or https URL with a host.
+func ValidateURL(s string) error {
+	return validateURL(s)
+}
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	return nil
+}
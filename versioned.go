@@ -0,0 +1,279 @@
+package faas
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// VersionedType returns the media type of a version of a vendor payload,
+// e.g. VersionedType("myfn", 2) is "application/vnd.myfn.v2+json".
+func VersionedType(vendor string, version int) string {
+	return "application/vnd." + vendor + ".v" + strconv.Itoa(version) + "+json"
+}
+
+// ParseVersionedType returns the vendor and version of a media type made by
+// VersionedType. Parameters such as charset are ignored. The version is 0
+// for "application/vnd.<vendor>+json" and ok is false for other types.
+func ParseVersionedType(contentType string) (vendor string, version int, ok bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", 0, false
+	}
+	name, ok := strings.CutPrefix(mediaType, "application/vnd.")
+	if !ok {
+		return "", 0, false
+	}
+	if name, ok = strings.CutSuffix(name, "+json"); !ok || name == "" {
+		return "", 0, false
+	}
+	if i := strings.LastIndex(name, ".v"); i > 0 {
+		if v, err := strconv.Atoi(name[i+2:]); err == nil && v > 0 {
+			return name[:i], v, true
+		}
+	}
+	return name, 0, true
+}
+
+// VersionCodec converts one version of a payload to and from the current
+// type T.
+type VersionCodec[T any] struct {
+	Decode func(data []byte) (T, error)
+	Encode func(v T) ([]byte, error)
+}
+
+// JSONVersionCodec is the VersionCodec of a version with the same JSON
+// shape as T. Unknown fields are ignored so producers can add fields
+// without a new version.
+func JSONVersionCodec[T any]() VersionCodec[T] {
+	return VersionCodec[T]{
+		Decode: func(data []byte) (T, error) {
+			var v T
+			err := jsonCodec().Unmarshal(data, &v)
+			return v, err
+		},
+		Encode: func(v T) ([]byte, error) {
+			return jsonCodec().Marshal(v)
+		},
+	}
+}
+
+// Versions is the registry of the versions of a payload shared between
+// functions, so producers and consumers can move to a new version
+// independently. Every version has a VersionCodec converting it to and
+// from T, the type the function works with:
+//
+//	var orders = faas.NewVersions[OrderV2]("orders")
+//
+//	func init() {
+//		orders.Register(1, faas.VersionCodec[OrderV2]{Decode: upgradeV1, Encode: downgradeV1})
+//		orders.Register(2, faas.JSONVersionCodec[OrderV2]())
+//	}
+//
+// It is safe for concurrent use.
+type Versions[T any] struct {
+	Vendor string
+	// Default is the version of unversioned payloads, sent as
+	// application/json, and the version returned to callers which do not
+	// ask for one. Defaults to the lowest registered version, so callers
+	// written before versioning keep working.
+	Default int
+
+	mu     sync.RWMutex
+	codecs map[int]VersionCodec[T]
+}
+
+// NewVersions returns an empty registry for the vendor's payloads.
+func NewVersions[T any](vendor string) *Versions[T] {
+	return &Versions[T]{Vendor: vendor, codecs: make(map[int]VersionCodec[T])}
+}
+
+// Register adds the codec of a version. Registering an existing version
+// replaces it.
+func (v *Versions[T]) Register(version int, codec VersionCodec[T]) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.codecs == nil {
+		v.codecs = make(map[int]VersionCodec[T])
+	}
+	v.codecs[version] = codec
+}
+
+// versions returns the registered versions, highest first.
+func (v *Versions[T]) versions() []int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	versions := make([]int, 0, len(v.codecs))
+	for n := range v.codecs {
+		versions = append(versions, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	return versions
+}
+
+func (v *Versions[T]) codec(version int) (VersionCodec[T], bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	c, ok := v.codecs[version]
+	return c, ok
+}
+
+func (v *Versions[T]) defaultVersion() int {
+	if v.Default != 0 {
+		return v.Default
+	}
+	versions := v.versions()
+	if len(versions) == 0 {
+		return 0
+	}
+	return versions[len(versions)-1]
+}
+
+// Unmarshal decodes data sent with contentType into T. Plain JSON and an
+// empty content type are read as the Default version and the vendor type
+// without a version as the latest one.
+func (v *Versions[T]) Unmarshal(contentType string, data []byte) (T, error) {
+	out, _, err := v.unmarshal(contentType, data)
+	return out, err
+}
+
+func (v *Versions[T]) unmarshal(contentType string, data []byte) (T, int, error) {
+	var zero T
+	version, err := v.contentVersion(contentType)
+	if err != nil {
+		return zero, 0, err
+	}
+	c, ok := v.codec(version)
+	if !ok {
+		return zero, 0, E(CodeInvalidArgument, "unsupported "+v.Vendor+" version", nil).
+			With("version", version).WithStatus(http.StatusUnsupportedMediaType)
+	}
+	out, err := c.Decode(data)
+	if err != nil {
+		return zero, 0, E(CodeInvalidArgument, fmt.Sprintf("invalid %s v%d payload", v.Vendor, version), err)
+	}
+	return out, version, nil
+}
+
+func (v *Versions[T]) contentVersion(contentType string) (int, error) {
+	if contentType == "" {
+		return v.defaultVersion(), nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/json" {
+		return v.defaultVersion(), nil
+	}
+	vendor, version, ok := ParseVersionedType(contentType)
+	if !ok || vendor != v.Vendor {
+		return 0, E(CodeInvalidArgument, "unsupported content type", nil).
+			With("content_type", contentType).WithStatus(http.StatusUnsupportedMediaType)
+	}
+	if version == 0 {
+		if versions := v.versions(); len(versions) > 0 {
+			version = versions[0]
+		}
+	}
+	return version, nil
+}
+
+// Marshal encodes value as the given version, returning the content type to
+// send it with. Producers use it when publishing events or calling other
+// functions.
+func (v *Versions[T]) Marshal(version int, value T) (string, []byte, error) {
+	c, ok := v.codec(version)
+	if !ok {
+		return "", nil, fmt.Errorf("%s v%d is not registered", v.Vendor, version)
+	}
+	data, err := c.Encode(value)
+	if err != nil {
+		return "", nil, err
+	}
+	return VersionedType(v.Vendor, version), data, nil
+}
+
+// Negotiate returns the version to respond with for an Accept header: the
+// acceptable registered version with the highest quality, preferring newer
+// versions on ties. Plain JSON, wildcards and an empty header select the
+// Default version. ok is false when no registered version is acceptable.
+func (v *Versions[T]) Negotiate(accept string) (version int, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return v.defaultVersion(), len(v.versions()) > 0
+	}
+	versions := v.versions()
+	best, bestQ := 0, 0.0
+	for _, r := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		candidate := 0
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			candidate = v.defaultVersion()
+		default:
+			vendor, n, ok := ParseVersionedType(mediaType)
+			if !ok || vendor != v.Vendor {
+				continue
+			}
+			candidate = n
+			if n == 0 && len(versions) > 0 {
+				candidate = versions[0]
+			}
+		}
+		if _, ok := v.codec(candidate); !ok {
+			continue
+		}
+		if q > bestQ || (q == bestQ && candidate > best) {
+			best, bestQ = candidate, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// Read decodes the request body according to its Content-Type, returning
+// the version it was sent as. Bodies are limited as by ReadBody.
+func (v *Versions[T]) Read(w http.ResponseWriter, r *http.Request, opts ...BodyOptions) (T, int, error) {
+	var zero T
+	data, err := ReadBody(w, r, opts...)
+	if err != nil {
+		return zero, 0, err
+	}
+	return v.unmarshal(r.Header.Get("Content-Type"), data)
+}
+
+// Write responds with value encoded as the version negotiated from the
+// request's Accept header, setting Content-Type and Vary. When no
+// registered version is acceptable nothing is written and a 406 AppError
+// listing the supported types is returned.
+func (v *Versions[T]) Write(w http.ResponseWriter, r *http.Request, status int, value T) error {
+	w.Header().Add("Vary", "Accept")
+	version, ok := v.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		var supported []string
+		for _, n := range v.versions() {
+			supported = append(supported, VersionedType(v.Vendor, n))
+		}
+		return E(CodeInvalidArgument, "no acceptable "+v.Vendor+" version", nil).
+			With("supported", supported).WithStatus(http.StatusNotAcceptable)
+	}
+	contentType, data, err := v.Marshal(version, value)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+	return nil
+}
@@ -0,0 +1,189 @@
+package faas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type orderV2 struct {
+	ID    string `json:"id"`
+	Total int64  `json:"total_cents"`
+}
+
+func orderVersions() *Versions[orderV2] {
+	v := NewVersions[orderV2]("shop.order")
+	v.Register(1, VersionCodec[orderV2]{
+		Decode: func(data []byte) (orderV2, error) {
+			var v1 struct {
+				ID    string  `json:"id"`
+				Total float64 `json:"total"`
+			}
+			err := json.Unmarshal(data, &v1)
+			return orderV2{ID: v1.ID, Total: int64(v1.Total * 100)}, err
+		},
+		Encode: func(o orderV2) ([]byte, error) {
+			return json.Marshal(map[string]any{"id": o.ID, "total": float64(o.Total) / 100})
+		},
+	})
+	v.Register(2, JSONVersionCodec[orderV2]())
+	return v
+}
+
+func TestParseVersionedType(t *testing.T) {
+	tests := []struct {
+		in          string
+		wantVendor  string
+		wantVersion int
+		wantOK      bool
+	}{
+		{in: "application/vnd.myfn.v2+json", wantVendor: "myfn", wantVersion: 2, wantOK: true},
+		{in: "application/vnd.shop.order.v10+json; charset=utf-8", wantVendor: "shop.order", wantVersion: 10, wantOK: true},
+		{in: "application/vnd.myfn+json", wantVendor: "myfn", wantOK: true},
+		{in: "application/json"},
+		{in: "application/vnd.myfn.v2+xml"},
+		{in: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			vendor, version, ok := ParseVersionedType(tt.in)
+			if vendor != tt.wantVendor || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("ParseVersionedType() = %q, %d, %v", vendor, version, ok)
+			}
+		})
+	}
+	if got := VersionedType("myfn", 2); got != "application/vnd.myfn.v2+json" {
+		t.Errorf("VersionedType() = %s", got)
+	}
+}
+
+func TestVersionsNegotiate(t *testing.T) {
+	v := orderVersions()
+	tests := []struct {
+		accept      string
+		wantVersion int
+		wantOK      bool
+	}{
+		{accept: "", wantVersion: 1, wantOK: true},
+		{accept: "application/json", wantVersion: 1, wantOK: true},
+		{accept: "*/*", wantVersion: 1, wantOK: true},
+		{accept: "application/vnd.shop.order.v2+json", wantVersion: 2, wantOK: true},
+		{accept: "application/vnd.shop.order+json", wantVersion: 2, wantOK: true},
+		{accept: "application/vnd.shop.order.v1+json, application/vnd.shop.order.v2+json", wantVersion: 2, wantOK: true},
+		{accept: "application/vnd.shop.order.v2+json;q=0.5, application/vnd.shop.order.v1+json", wantVersion: 1, wantOK: true},
+		{accept: "application/vnd.shop.order.v3+json, application/json;q=0.1", wantVersion: 1, wantOK: true},
+		{accept: "application/vnd.shop.order.v3+json"},
+		{accept: "application/vnd.other.v2+json"},
+		{accept: "application/vnd.shop.order.v2+json;q=0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			version, ok := v.Negotiate(tt.accept)
+			if version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("Negotiate() = %d, %v, want %d, %v", version, ok, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+
+	v.Default = 2
+	if version, _ := v.Negotiate("application/json"); version != 2 {
+		t.Errorf("Negotiate() with Default 2 = %d", version)
+	}
+}
+
+func TestVersionsRead(t *testing.T) {
+	v := orderVersions()
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        orderV2
+		wantVersion int
+		wantStatus  int
+	}{
+		{name: "v1 is upgraded", contentType: "application/vnd.shop.order.v1+json", body: `{"id":"o1","total":12.5}`, want: orderV2{ID: "o1", Total: 1250}, wantVersion: 1},
+		{name: "v2", contentType: "application/vnd.shop.order.v2+json", body: `{"id":"o1","total_cents":1250,"new":true}`, want: orderV2{ID: "o1", Total: 1250}, wantVersion: 2},
+		{name: "plain json is the default version", contentType: "application/json", body: `{"id":"o1","total":1}`, want: orderV2{ID: "o1", Total: 100}, wantVersion: 1},
+		{name: "unknown version", contentType: "application/vnd.shop.order.v9+json", body: `{}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "other vendor", contentType: "application/vnd.other.v1+json", body: `{}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "invalid payload", contentType: "application/vnd.shop.order.v2+json", body: `{`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			got, version, err := v.Read(httptest.NewRecorder(), r)
+			if tt.wantStatus != 0 {
+				var appErr *AppError
+				if !errors.As(err, &appErr) || appErr.Status() != tt.wantStatus {
+					t.Fatalf("Read() error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || version != tt.wantVersion {
+				t.Errorf("Read() = %+v, v%d", got, version)
+			}
+		})
+	}
+}
+
+func TestVersionsWrite(t *testing.T) {
+	v := orderVersions()
+	order := orderV2{ID: "o1", Total: 1250}
+	tests := []struct {
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{accept: "application/vnd.shop.order.v2+json", wantContentType: "application/vnd.shop.order.v2+json", wantBody: `{"id":"o1","total_cents":1250}`},
+		{accept: "application/json", wantContentType: "application/vnd.shop.order.v1+json", wantBody: `{"id":"o1","total":12.5}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			if err := v.Write(w, r, http.StatusOK, order); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %s", got)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %s", got)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %s", got)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/vnd.shop.order.v3+json")
+	err := v.Write(httptest.NewRecorder(), r, http.StatusOK, order)
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Status() != http.StatusNotAcceptable {
+		t.Fatalf("Write() error = %v, want 406", err)
+	}
+}
+
+func TestVersionsMarshal(t *testing.T) {
+	v := orderVersions()
+	contentType, data, err := v.Marshal(1, orderV2{ID: "o1", Total: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := v.Unmarshal(contentType, data)
+	if err != nil || got.Total != 100 {
+		t.Errorf("round trip = %+v, %v", got, err)
+	}
+	if _, _, err := v.Marshal(3, orderV2{}); err == nil {
+		t.Error("Marshal() of an unregistered version returned no error")
+	}
+}
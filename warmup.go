@@ -0,0 +1,100 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WarmupOptions configures WarmConnections.
+type WarmupOptions struct {
+	// Client whose connection pool is filled. Defaults to http.DefaultClient.
+	// Its transport must keep at least Connections idle connections per
+	// host, http.Transport only keeps 2 unless MaxIdleConnsPerHost is set.
+	Client *http.Client
+	// URLs of the upstreams to connect to. A cheap endpoint such as a health
+	// check should be used as a HEAD request is sent to each. Defaults to the
+	// comma separated WARMUP_URLS environment variable.
+	URLs []string
+	// Connections opened to each upstream. Defaults to 2.
+	Connections int
+	// Timeout bounds the whole warmup. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// WarmConnections pre-opens connections, including their TLS handshakes, so
+// the first requests after a cold start do not pay for them. It is meant to
+// be run once at startup, e.g. from an init function with Background:
+//
+//	faas.Background(func() { _ = faas.WarmConnections(context.Background(), opts) })
+//
+// Requests are made concurrently so each needs its own connection. Failures
+// are logged and returned joined but are otherwise harmless.
+func WarmConnections(ctx context.Context, opts WarmupOptions) error {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.URLs == nil {
+		for _, u := range strings.Split(os.Getenv("WARMUP_URLS"), ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				opts.URLs = append(opts.URLs, u)
+			}
+		}
+	}
+	if opts.Connections <= 0 {
+		opts.Connections = 2
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, u := range opts.URLs {
+		for i := 0; i < opts.Connections; i++ {
+			wg.Add(1)
+			go func(u string) {
+				defer wg.Done()
+				if err := warmConnection(ctx, opts.Client, u); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}(u)
+		}
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if err != nil {
+		slog.Warn("connection warmup failed", "error", err)
+	} else {
+		slog.Debug("connections warmed", "upstreams", len(opts.URLs), "connections", opts.Connections, "duration", time.Since(start))
+	}
+	return err
+}
+
+func warmConnection(ctx context.Context, client *http.Client, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return fmt.Errorf("warmup %s: %w", u, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("warmup %s: %w", u, err)
+	}
+	// drain so the connection is returned to the pool
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
@@ -0,0 +1,61 @@
+package faas
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWarmConnections(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			// hold warmup requests so they cannot share a connection
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := srv.Client()
+	client.Transport.(*http.Transport).MaxIdleConnsPerHost = 3
+	if err := WarmConnections(context.Background(), WarmupOptions{Client: client, URLs: []string{srv.URL}, Connections: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if got := conns.Load(); got != 3 {
+		t.Fatalf("expected 3 connections to be opened, got %d", got)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := conns.Load(); got != 3 {
+		t.Errorf("expected requests to reuse warm connections, got %d connections", got)
+	}
+}
+
+func TestWarmConnectionsFromEnv(t *testing.T) {
+	t.Setenv("WARMUP_URLS", "http://127.0.0.1:1/health")
+	if err := WarmConnections(context.Background(), WarmupOptions{Timeout: time.Second}); err == nil {
+		t.Error("expected unreachable upstream to be reported")
+	}
+}
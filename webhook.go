@@ -0,0 +1,205 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Webhook delivery states.
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// WebhookAttempt is a single delivery attempt.
+type WebhookAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Duration   Duration  `json:"duration"`
+}
+
+// WebhookDelivery is the status and attempt history of one webhook.
+type WebhookDelivery struct {
+	ID        string           `json:"id"`
+	URL       string           `json:"url"`
+	Event     string           `json:"event"`
+	Status    string           `json:"status"`
+	Attempts  []WebhookAttempt `json:"attempts"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// WebhookSender delivers signed webhooks with retries. Each request carries
+// X-Webhook-Id, X-Webhook-Event, X-Webhook-Timestamp and, when Secret is
+// set, X-Webhook-Signature: "sha256=" followed by the hex HMAC-SHA256 of
+// the timestamp, a dot and the body.
+type WebhookSender struct {
+	// Client defaults to SharedHTTPClient.
+	Client *http.Client
+	Secret []byte
+	// Retry defaults to 3 attempts with a one second backoff. Responses
+	// other than 408, 429 and 5xx are not retried.
+	Retry *RetryPolicy
+	// Store optionally records every delivery so it can be looked up with
+	// Delivery and StatusHandler.
+	Store KV
+	// TTL is how long delivery records are kept. Defaults to 7 days.
+	TTL time.Duration
+}
+
+// Send delivers payload as JSON to url and returns the delivery record. The
+// error is that of the last attempt when every attempt failed.
+func (s *WebhookSender) Send(ctx context.Context, url, event string, payload any) (*WebhookDelivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	d := &WebhookDelivery{ID: randomHex(16), URL: url, Event: event, Status: WebhookPending, CreatedAt: now, UpdatedAt: now}
+	s.save(ctx, d)
+
+	policy := RetryPolicy{Attempts: 3, Backoff: Duration(time.Second), Jitter: true}
+	if s.Retry != nil {
+		policy = *s.Retry
+	}
+	err = retry(ctx, policy, func(ctx context.Context) error {
+		attempt, err := s.attempt(ctx, d, body)
+		d.Attempts = append(d.Attempts, attempt)
+		d.UpdatedAt = time.Now().UTC()
+		s.save(ctx, d)
+		return err
+	})
+
+	d.Status = WebhookDelivered
+	if err != nil {
+		d.Status = WebhookFailed
+	}
+	d.UpdatedAt = time.Now().UTC()
+	s.save(ctx, d)
+	return d, err
+}
+
+func (s *WebhookSender) attempt(ctx context.Context, d *WebhookDelivery, body []byte) (WebhookAttempt, error) {
+	client := s.Client
+	if client == nil {
+		client = SharedHTTPClient()
+	}
+	start := time.Now()
+	attempt := WebhookAttempt{At: start.UTC()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, Permanent(err)
+	}
+	ts := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", d.ID)
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Timestamp", ts)
+	if len(s.Secret) > 0 {
+		req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(s.Secret, ts, body))
+	}
+
+	resp, err := client.Do(req)
+	attempt.Duration = Duration(time.Since(start))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySnippet))
+	resp.Body.Close()
+	attempt.StatusCode = resp.StatusCode
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return attempt, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		err = fmt.Errorf("webhook %s responded %d", d.URL, resp.StatusCode)
+	default:
+		err = Permanent(fmt.Errorf("webhook %s responded %d", d.URL, resp.StatusCode))
+	}
+	attempt.Error = err.Error()
+	return attempt, err
+}
+
+// SignWebhook returns the hex HMAC-SHA256 signature of a webhook, for
+// receivers verifying X-Webhook-Signature.
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookSender) key(id string) string {
+	return "faas:webhook:" + id
+}
+
+// save records d when a Store is configured. Failing to record status does
+// not fail the delivery.
+func (s *WebhookSender) save(ctx context.Context, d *WebhookDelivery) {
+	if s.Store == nil {
+		return
+	}
+	js, err := json.Marshal(d)
+	if err != nil {
+		return
+	}
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	_ = s.Store.Set(context.WithoutCancel(ctx), s.key(d.ID), js, ttl)
+}
+
+// Delivery returns the recorded delivery with id, or ErrNotFound.
+func (s *WebhookSender) Delivery(ctx context.Context, id string) (*WebhookDelivery, error) {
+	if s.Store == nil {
+		return nil, ErrNotFound
+	}
+	js, err := s.Store.Get(ctx, s.key(id))
+	if err != nil {
+		return nil, err
+	}
+	var d WebhookDelivery
+	if err := json.Unmarshal(js, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// StatusHandler returns an endpoint serving the delivery named by the id
+// query parameter so webhook consumers can debug deliveries themselves. It
+// should be protected with middleware such as APIKeyAuth.
+func (s *WebhookSender) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			_ = writeError(w, E(CodeInvalidArgument, "the id query parameter is required", nil))
+			return
+		}
+		d, err := s.Delivery(r.Context(), id)
+		if errors.Is(err, ErrNotFound) {
+			_ = writeError(w, E(CodeNotFound, "delivery not found", nil).With("id", id))
+			return
+		}
+		if err != nil {
+			_ = writeError(w, err)
+			return
+		}
+		_ = writeJSON(w, http.StatusOK, d, nil)
+	})
+}
@@ -0,0 +1,115 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWebhookSender(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		status   string
+		attempts int
+		wantErr  bool
+	}{
+		{name: "delivered", statuses: []int{200}, status: WebhookDelivered, attempts: 1},
+		{name: "retried", statuses: []int{503, 200}, status: WebhookDelivered, attempts: 2},
+		{name: "exhausted", statuses: []int{500, 500, 500}, status: WebhookFailed, attempts: 3, wantErr: true},
+		{name: "client error", statuses: []int{400}, status: WebhookFailed, attempts: 1, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			statuses := tc.statuses
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1)) - 1
+				if r.Header.Get("X-Webhook-Event") != "order.created" || r.Header.Get("X-Webhook-Id") == "" {
+					t.Errorf("missing webhook headers: %v", r.Header)
+				}
+				w.WriteHeader(statuses[min(n, len(statuses)-1)])
+			}))
+			defer srv.Close()
+
+			s := &WebhookSender{Client: srv.Client(), Retry: &RetryPolicy{Attempts: 3}, Store: NewMemoryKV()}
+			d, err := s.Send(context.Background(), srv.URL, "order.created", map[string]string{"id": "1"})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if d.Status != tc.status || len(d.Attempts) != tc.attempts {
+				t.Fatalf("got status %q with %d attempts, want %q with %d", d.Status, len(d.Attempts), tc.status, tc.attempts)
+			}
+
+			stored, err := s.Delivery(context.Background(), d.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != tc.status || len(stored.Attempts) != tc.attempts {
+				t.Errorf("stored delivery = %+v", stored)
+			}
+			if last := stored.Attempts[len(stored.Attempts)-1]; last.StatusCode != statuses[len(statuses)-1] {
+				t.Errorf("last attempt status = %d", last.StatusCode)
+			}
+		})
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	secret := []byte("s3cret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		want := "sha256=" + SignWebhook(secret, r.Header.Get("X-Webhook-Timestamp"), body)
+		if got := r.Header.Get("X-Webhook-Signature"); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+	}))
+	defer srv.Close()
+
+	s := &WebhookSender{Client: srv.Client(), Secret: secret}
+	if _, err := s.Send(context.Background(), srv.URL, "ping", map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookStatusHandler(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	s := &WebhookSender{Client: srv.Client(), Store: NewMemoryKV()}
+	d, err := s.Send(context.Background(), srv.URL, "ping", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{name: "found", query: "?id=" + d.ID, status: http.StatusOK},
+		{name: "not found", query: "?id=missing", status: http.StatusNotFound},
+		{name: "missing id", query: "", status: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/deliveries"+tc.query, nil))
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.status, rec.Body)
+			}
+			if tc.status == http.StatusOK {
+				var got WebhookDelivery
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.ID != d.ID || got.Status != WebhookDelivered || len(got.Attempts) != 1 {
+					t.Errorf("delivery = %+v", got)
+				}
+			}
+		})
+	}
+}
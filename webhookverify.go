@@ -0,0 +1,165 @@
+package faas
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is wrapped by the errors of the Verify functions when
+// a webhook or message is not signed with any of the accepted secrets or
+// keys, or its timestamp is outside the tolerance.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// DefaultWebhookTolerance is how far the timestamp of a webhook may be from
+// the current time when no tolerance is given, limiting replays.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// LoadWebhookSecrets reads the signing secrets of a webhook from the
+// OpenFaaS secret name, one per line. Secrets are rotated by adding the new
+// secret as the first line, switching the sender over and then removing
+// the old line, so no delivery is rejected in between.
+func LoadWebhookSecrets(name string) ([][]byte, error) {
+	secret, err := getSecretString(name)
+	if err != nil {
+		return nil, err
+	}
+	var secrets [][]byte
+	for _, line := range strings.Split(secret, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			secrets = append(secrets, []byte(line))
+		}
+	}
+	if len(secrets) == 0 {
+		return nil, errors.New("no webhook secrets configured")
+	}
+	return secrets, nil
+}
+
+// VerifyHMAC checks signature is the hex HMAC-SHA256 of body with one of
+// secrets, which are tried in order. A "sha256=" prefix, as sent by many
+// providers, is accepted.
+func VerifyHMAC(signature string, body []byte, secrets ...[]byte) error {
+	signature = strings.TrimPrefix(signature, "sha256=")
+	if matchSignature([]string{signature}, secrets, body) {
+		return nil
+	}
+	return invalidSignature("signature does not match")
+}
+
+// VerifyWebhook checks a webhook sent by WebhookSender: its
+// X-Webhook-Signature must be signed with one of secrets and its
+// X-Webhook-Timestamp within tolerance of now, DefaultWebhookTolerance when
+// zero.
+func VerifyWebhook(r *http.Request, body []byte, tolerance time.Duration, secrets ...[]byte) error {
+	ts := r.Header.Get("X-Webhook-Timestamp")
+	if err := checkWebhookTime(ts, tolerance); err != nil {
+		return err
+	}
+	sig, ok := strings.CutPrefix(r.Header.Get("X-Webhook-Signature"), "sha256=")
+	if !ok || !matchSignature([]string{sig}, secrets, []byte(ts+"."), body) {
+		return invalidSignature("signature does not match")
+	}
+	return nil
+}
+
+// VerifyGitHubWebhook checks the X-Hub-Signature-256 of a GitHub webhook
+// is signed with one of secrets. GitHub signatures carry no timestamp, so
+// replays are only detectable through X-GitHub-Delivery.
+func VerifyGitHubWebhook(r *http.Request, body []byte, secrets ...[]byte) error {
+	sig, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+	if !ok || !matchSignature([]string{sig}, secrets, body) {
+		return invalidSignature("signature does not match")
+	}
+	return nil
+}
+
+// VerifyStripeWebhook checks the Stripe-Signature of a Stripe webhook.
+// Stripe sends a v1 signature for each of the endpoint's active secrets
+// while one is being rolled, and any of them may match any of secrets.
+func VerifyStripeWebhook(r *http.Request, body []byte, tolerance time.Duration, secrets ...[]byte) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if err := checkWebhookTime(ts, tolerance); err != nil {
+		return err
+	}
+	if !matchSignature(sigs, secrets, []byte(ts+"."), body) {
+		return invalidSignature("signature does not match")
+	}
+	return nil
+}
+
+// VerifySlackWebhook checks the X-Slack-Signature of a Slack request is
+// signed with one of secrets and its X-Slack-Request-Timestamp within
+// tolerance of now.
+func VerifySlackWebhook(r *http.Request, body []byte, tolerance time.Duration, secrets ...[]byte) error {
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	if err := checkWebhookTime(ts, tolerance); err != nil {
+		return err
+	}
+	sig, ok := strings.CutPrefix(r.Header.Get("X-Slack-Signature"), "v0=")
+	if !ok || !matchSignature([]string{sig}, secrets, []byte("v0:"+ts+":"), body) {
+		return invalidSignature("signature does not match")
+	}
+	return nil
+}
+
+// matchSignature reports whether one of sigs is the hex HMAC-SHA256 of the
+// concatenated parts with one of secrets. Every secret is tried, so the
+// time taken does not reveal which one matched.
+func matchSignature(sigs []string, secrets [][]byte, parts ...[]byte) bool {
+	var decoded [][]byte
+	for _, s := range sigs {
+		if b, err := hex.DecodeString(s); err == nil && len(b) == sha256.Size {
+			decoded = append(decoded, b)
+		}
+	}
+	matched := false
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, secret)
+		for _, p := range parts {
+			mac.Write(p)
+		}
+		want := mac.Sum(nil)
+		for _, got := range decoded {
+			if hmac.Equal(got, want) {
+				matched = true
+			}
+		}
+	}
+	return matched
+}
+
+// checkWebhookTime checks a Unix timestamp in seconds is within tolerance
+// of now.
+func checkWebhookTime(ts string, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return invalidSignature("missing or invalid timestamp")
+	}
+	if d := time.Since(time.Unix(sec, 0)); d > tolerance || d < -tolerance {
+		return invalidSignature("timestamp outside tolerance")
+	}
+	return nil
+}
+
+func invalidSignature(reason string) error {
+	return E(CodeUnauthenticated, "invalid webhook signature: "+reason, ErrInvalidSignature)
+}
@@ -0,0 +1,98 @@
+package faas
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func hmacHex(secret string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, p := range parts {
+		mac.Write([]byte(p))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhookRotation(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	current, previous := []byte("new-secret"), []byte("old-secret")
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name   string
+		verify func(signedWith string) error
+	}{
+		{name: "hmac", verify: func(s string) error {
+			return VerifyHMAC("sha256="+hmacHex(s, string(body)), body, current, previous)
+		}},
+		{name: "sender", verify: func(s string) error {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("X-Webhook-Timestamp", now)
+			r.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook([]byte(s), now, body))
+			return VerifyWebhook(r, body, 0, current, previous)
+		}},
+		{name: "github", verify: func(s string) error {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("X-Hub-Signature-256", "sha256="+hmacHex(s, string(body)))
+			return VerifyGitHubWebhook(r, body, current, previous)
+		}},
+		{name: "stripe", verify: func(s string) error {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("Stripe-Signature", "t="+now+",v1="+hmacHex("unrelated", now, ".", string(body))+",v1="+hmacHex(s, now, ".", string(body)))
+			return VerifyStripeWebhook(r, body, 0, current, previous)
+		}},
+		{name: "slack", verify: func(s string) error {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("X-Slack-Request-Timestamp", now)
+			r.Header.Set("X-Slack-Signature", "v0="+hmacHex(s, "v0:", now, ":", string(body)))
+			return VerifySlackWebhook(r, body, 0, current, previous)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range []string{"new-secret", "old-secret"} {
+				if err := tt.verify(s); err != nil {
+					t.Errorf("signed with %s: %v", s, err)
+				}
+			}
+			err := tt.verify("retired-secret")
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("signed with retired secret: err = %v, want ErrInvalidSignature", err)
+			}
+			var appErr *AppError
+			if !errors.As(err, &appErr) || appErr.Code != CodeUnauthenticated {
+				t.Errorf("err = %#v, want CodeUnauthenticated", err)
+			}
+		})
+	}
+
+	t.Run("stale timestamp", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("X-Webhook-Timestamp", stale)
+		r.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(current, stale, body))
+		if err := VerifyWebhook(r, body, time.Minute, current); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("err = %v, want ErrInvalidSignature", err)
+		}
+	})
+}
+
+func TestLoadWebhookSecrets(t *testing.T) {
+	withSecrets(t, map[string]string{"hook": "new\n\n old \n", "empty": "\n"})
+	secrets, err := LoadWebhookSecrets("hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 2 || string(secrets[0]) != "new" || string(secrets[1]) != "old" {
+		t.Errorf("secrets = %q", secrets)
+	}
+	if _, err := LoadWebhookSecrets("empty"); err == nil {
+		t.Error("expected error for a secret without lines")
+	}
+}
@@ -0,0 +1,271 @@
+package faas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// yamlToJSON converts the block style subset of YAML used by configuration
+// files to JSON: nested mappings and sequences, plain, single and double
+// quoted scalars, and comments. Flow collections are accepted when they are
+// valid JSON. Anchors, tags, multi-line scalars and multiple documents are
+// not supported and are rejected rather than misread.
+func yamlToJSON(data []byte) ([]byte, error) {
+	lines, err := yamlLines(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return []byte("null"), nil
+	}
+	p := &yamlParser{lines: lines}
+	v, err := p.block(0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(lines) {
+		return nil, p.errorf(p.i, "unexpected indentation")
+	}
+	return json.Marshal(v)
+}
+
+type yamlLine struct {
+	n      int
+	indent int
+	text   string
+}
+
+// yamlLines splits data into its non-blank lines without comments.
+func yamlLines(data []byte) ([]yamlLine, error) {
+	var lines []yamlLine
+	for n, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("yaml line %d: tabs must not be used for indentation", n+1)
+		}
+		text = strings.TrimSpace(stripYAMLComment(text))
+		if text == "" || (len(lines) == 0 && text == "---") {
+			continue
+		}
+		if text == "---" || text == "..." {
+			return nil, fmt.Errorf("yaml line %d: multiple documents are not supported", n+1)
+		}
+		lines = append(lines, yamlLine{n: n + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text})
+	}
+	return lines, nil
+}
+
+// stripYAMLComment removes a comment, which starts with a # at the start of
+// the text or after a space, outside of quotes.
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+func (p *yamlParser) errorf(i int, format string, args ...any) error {
+	line := 0
+	if i < len(p.lines) {
+		line = p.lines[i].n
+	} else if len(p.lines) > 0 {
+		line = p.lines[len(p.lines)-1].n
+	}
+	return fmt.Errorf("yaml line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// block parses the mapping or sequence starting at the current line, whose
+// entries are indented by indent.
+func (p *yamlParser) block(start, indent int) (any, error) {
+	p.i = start
+	if isYAMLSequenceEntry(p.lines[p.i].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func isYAMLSequenceEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	m := make(map[string]any)
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent {
+		line := p.lines[p.i]
+		if isYAMLSequenceEntry(line.text) {
+			return nil, p.errorf(p.i, "expected a key, got a sequence entry")
+		}
+		key, rest, err := splitYAMLKey(line.text)
+		if err != nil {
+			return nil, p.errorf(p.i, "%v", err)
+		}
+		if _, ok := m[key]; ok {
+			return nil, p.errorf(p.i, "duplicate key %q", key)
+		}
+		if m[key], err = p.value(indent, rest, true); err != nil {
+			return nil, err
+		}
+	}
+	if p.i < len(p.lines) && p.lines[p.i].indent > indent {
+		return nil, p.errorf(p.i, "unexpected indentation")
+	}
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	s := []any{}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent && isYAMLSequenceEntry(p.lines[p.i].text) {
+		line := p.lines[p.i]
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if rest != "" {
+			if _, _, err := splitYAMLKey(rest); err == nil && !strings.HasPrefix(rest, "[") && !strings.HasPrefix(rest, "{") {
+				// "- key: value" starts a mapping indented past the dash
+				offset := len(line.text) - len(rest)
+				p.lines[p.i] = yamlLine{n: line.n, indent: indent + offset, text: rest}
+				v, err := p.mapping(indent + offset)
+				if err != nil {
+					return nil, err
+				}
+				s = append(s, v)
+				continue
+			}
+		}
+		v, err := p.value(indent, rest, false)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+	if p.i < len(p.lines) && p.lines[p.i].indent > indent {
+		return nil, p.errorf(p.i, "unexpected indentation")
+	}
+	return s, nil
+}
+
+// value parses the value of the entry on the current line, which is rest
+// or, when rest is empty, the block nested under the line. Mapping values
+// may be sequences at the indentation of their key.
+func (p *yamlParser) value(indent int, rest string, key bool) (any, error) {
+	p.i++
+	if rest != "" {
+		v, err := yamlScalar(rest)
+		if err != nil {
+			return nil, p.errorf(p.i-1, "%v", err)
+		}
+		return v, nil
+	}
+	if p.i < len(p.lines) {
+		next := p.lines[p.i]
+		if next.indent > indent || (key && next.indent == indent && isYAMLSequenceEntry(next.text)) {
+			return p.block(p.i, next.indent)
+		}
+	}
+	return nil, nil
+}
+
+// splitYAMLKey splits "key: value" into its key and value.
+func splitYAMLKey(text string) (string, string, error) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingYAMLQuote(text)
+		if end < 0 {
+			return "", "", errors.New("unterminated quoted key")
+		}
+		key, err := yamlScalar(text[:end+1])
+		if err != nil {
+			return "", "", err
+		}
+		rest := text[end+1:]
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", errors.New("expected a colon after the key")
+		}
+		return key.(string), strings.TrimSpace(rest[1:]), nil
+	}
+	if key, ok := strings.CutSuffix(text, ":"); ok && !strings.Contains(key, ": ") {
+		return strings.TrimSpace(key), "", nil
+	}
+	key, rest, ok := strings.Cut(text, ": ")
+	if !ok {
+		return "", "", fmt.Errorf("expected \"key: value\", got %q", text)
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(rest), nil
+}
+
+// closingYAMLQuote returns the index of the quote closing the string which
+// s starts with, or -1.
+func closingYAMLQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[0] == '"' && s[i] == '\\':
+			i++
+		case s[i] == s[0] && s[0] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == s[0]:
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlScalar parses a scalar as the YAML 1.2 core schema does: null, true,
+// false and numbers are typed, other plain scalars are strings.
+func yamlScalar(s string) (any, error) {
+	switch s[0] {
+	case '"':
+		if closingYAMLQuote(s) != len(s)-1 {
+			return nil, fmt.Errorf("invalid quoted string %s", s)
+		}
+		var v string
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", s)
+		}
+		return v, nil
+	case '\'':
+		if closingYAMLQuote(s) != len(s)-1 {
+			return nil, fmt.Errorf("invalid quoted string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case '[', '{':
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil || dec.More() {
+			return nil, fmt.Errorf("flow collections must be valid JSON: %s", s)
+		}
+		return v, nil
+	case '|', '>', '&', '*', '!':
+		return nil, fmt.Errorf("unsupported YAML syntax %q", s)
+	}
+	switch s {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	// the other JSON values were handled above, so this is a number
+	if json.Valid([]byte(s)) {
+		return json.Number(s), nil
+	}
+	return s, nil
+}
@@ -0,0 +1,46 @@
+package faas
+
+import "testing"
+
+func TestYAMLToJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", input: "# nothing\n", want: `null`},
+		{
+			name:  "nested mappings",
+			input: "---\na:\n  b: 1\n  c: two words # comment\n  d: 'it''s'\n  e: \"x # y\"\nf: ~\n",
+			want:  `{"a":{"b":1,"c":"two words","d":"it's","e":"x # y"},"f":null}`,
+		},
+		{
+			name:  "sequences",
+			input: "hosts:\n- a.example.com\n- b.example.com\nretries:\n  - attempts: 2\n    backoff: 1s\n  - true\n",
+			want:  `{"hosts":["a.example.com","b.example.com"],"retries":[{"attempts":2,"backoff":"1s"},true]}`,
+		},
+		{name: "flow json", input: "codes: [500, 502]\nlabels: {\"env\": \"prod\"}\n", want: `{"codes":[500,502],"labels":{"env":"prod"}}`},
+		{name: "typed scalars", input: "a: 1.5e3\nb: -2\nc: false\nd: 1.2.3\ne: 50/s\n", want: `{"a":1.5e3,"b":-2,"c":false,"d":"1.2.3","e":"50/s"}`},
+		{name: "quoted key", input: "\"a: b\": 1\n", want: `{"a: b":1}`},
+		{name: "bad indentation", input: "a:\n    b: 1\n  c: 2\n", wantErr: true},
+		{name: "tabs", input: "a:\n\tb: 1\n", wantErr: true},
+		{name: "duplicate key", input: "a: 1\na: 2\n", wantErr: true},
+		{name: "yaml flow mapping", input: "a: {b: 1}\n", wantErr: true},
+		{name: "block scalar", input: "a: |\n  text\n", wantErr: true},
+		{name: "anchor", input: "a: &x 1\n", wantErr: true},
+		{name: "multiple documents", input: "a: 1\n---\nb: 2\n", wantErr: true},
+		{name: "not a mapping", input: "a: 1\njust text\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yamlToJSON([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("yamlToJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && string(got) != tt.want {
+				t.Errorf("yamlToJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}